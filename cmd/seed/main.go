@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	postgres "chatservice/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	firstNames = []string{"Ada", "Aziz", "Bea", "Chen", "Dilnoza", "Emil", "Farah", "Gus", "Hana", "Ivan", "Jamal", "Kira", "Leo", "Mira", "Nodir", "Olga", "Pavel", "Rosa", "Sami", "Timur", "Uma", "Vera", "Wen", "Yusuf", "Zara"}
+	lastNames  = []string{"Abbasov", "Berg", "Costa", "Dahl", "Evans", "Fischer", "Garcia", "Hoffmann", "Ito", "Jensen", "Karimov", "Lund", "Moreau", "Novak", "Okafor", "Petrov", "Quinn", "Rahimov", "Silva", "Tanaka"}
+	groupNames = []string{"Design crit", "Backend guild", "Weekend hikes", "Book club", "Release train", "Coffee chat", "Incident review", "Frontend", "Mobile", "Random", "Data team", "Board games"}
+	openers    = []string{"hey", "morning", "quick question", "fyi", "heads up", "ok so", "lol", "nice", "agreed", "hmm"}
+	phrases    = []string{
+		"did anyone look at the latest build",
+		"I pushed a fix for the flaky test",
+		"can we move the sync to tomorrow",
+		"the deploy went out without issues",
+		"lunch at the usual place?",
+		"I'll take a look after standup",
+		"that dashboard is finally green",
+		"sharing the notes from yesterday",
+		"who has context on the billing job",
+		"running five minutes late",
+		"let's ship it behind a flag",
+		"thanks, that worked",
+		"the numbers look better this week",
+		"I left a few comments on the doc",
+		"anyone up for a walk later",
+	}
+	tags  = []string{"release", "bug", "design", "oncall", "weekend", "metrics"}
+	links = []string{"https://example.com/docs/roadmap", "https://example.com/dash/latency", "https://example.com/notes/retro"}
+)
+
+type seeder struct {
+	pool *pgxpool.Pool
+	rng  *rand.Rand
+	now  time.Time
+	span time.Duration
+}
+
+type seededRoom struct {
+	id      uuid.UUID
+	members []uuid.UUID
+}
+
+func main() {
+	dbURL := flag.String("database-url", os.Getenv("DATABASE_URL"), "Postgres connection string")
+	users := flag.Int("users", 50, "number of users to create")
+	friends := flag.Int("friends", 5, "accepted friendships per user, each with a private room")
+	groups := flag.Int("groups", 10, "number of group rooms")
+	groupSize := flag.Int("group-size", 8, "members per group room")
+	messages := flag.Int("messages", 200, "messages per room")
+	weeks := flag.Int("weeks", 4, "how many weeks of history to spread messages over")
+	unread := flag.Int("unread", 5, "most recent messages per room left unread for each member")
+	seed := flag.Uint64("seed", uint64(time.Now().UnixNano()), "random seed, reuse to reproduce a data set")
+	emailDomain := flag.String("email-domain", "seed.chatservice.local", "domain used for generated email addresses")
+	flag.Parse()
+
+	if *dbURL == "" {
+		log.Fatal("-database-url or DATABASE_URL is required")
+	}
+	if *users < 2 || *weeks < 1 || *messages < 0 {
+		log.Fatal("-users must be at least 2, -weeks at least 1 and -messages not negative")
+	}
+
+	ctx := context.Background()
+	pool, err := postgres.NewDBPool(*dbURL, 0)
+	if err != nil {
+		log.Fatalf("Could not connect to the database: %v", err)
+	}
+	defer pool.Close()
+	if err := postgres.ValidateSchema(ctx, pool); err != nil {
+		log.Fatalf("Refusing to seed: %v", err)
+	}
+
+	s := &seeder{
+		pool: pool,
+		rng:  rand.New(rand.NewPCG(*seed, *seed)),
+		now:  time.Now().UTC(),
+		span: time.Duration(*weeks) * 7 * 24 * time.Hour,
+	}
+	start := time.Now()
+	log.Printf("Seeding with seed %d", *seed)
+
+	userIDs, err := s.createUsers(ctx, *users, *emailDomain)
+	if err != nil {
+		log.Fatalf("Could not create users: %v", err)
+	}
+	rooms, err := s.createFriendships(ctx, userIDs, *friends)
+	if err != nil {
+		log.Fatalf("Could not create friendships: %v", err)
+	}
+	groupRooms, err := s.createGroups(ctx, userIDs, *groups, *groupSize)
+	if err != nil {
+		log.Fatalf("Could not create group rooms: %v", err)
+	}
+	rooms = append(rooms, groupRooms...)
+
+	total := 0
+	for _, room := range rooms {
+		n, err := s.createMessages(ctx, room, *messages)
+		if err != nil {
+			log.Fatalf("Could not create messages for room %s: %v", room.id, err)
+		}
+		total += n
+	}
+	if err := s.markRead(ctx, rooms, *unread); err != nil {
+		log.Fatalf("Could not mark messages as read: %v", err)
+	}
+	log.Printf("Seeded %d users, %d rooms and %d messages in %s", len(userIDs), len(rooms), total, time.Since(start).Round(time.Millisecond))
+}
+
+func (s *seeder) createUsers(ctx context.Context, count int, emailDomain string) ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, count)
+	rows := make([][]any, count)
+	for i := range ids {
+		ids[i] = uuid.New()
+		first := firstNames[s.rng.IntN(len(firstNames))]
+		last := lastNames[s.rng.IntN(len(lastNames))]
+		username := fmt.Sprintf("%s.%s.%s", strings.ToLower(first), strings.ToLower(last), ids[i].String()[:6])
+		createdAt := s.now.Add(-s.span - time.Duration(s.rng.Int64N(int64(s.span))))
+		rows[i] = []any{ids[i], username + "@" + emailDomain, username, first + " " + last, createdAt}
+	}
+	_, err := s.pool.CopyFrom(ctx, pgx.Identifier{"users"}, []string{"id", "email", "username", "nickname", "created_at"}, pgx.CopyFromRows(rows))
+	return ids, err
+}
+
+func (s *seeder) createFriendships(ctx context.Context, userIDs []uuid.UUID, perUser int) ([]seededRoom, error) {
+	seen := make(map[[2]uuid.UUID]bool)
+	var rooms []seededRoom
+	for _, userID := range userIDs {
+		for range perUser {
+			other := userIDs[s.rng.IntN(len(userIDs))]
+			if other == userID {
+				continue
+			}
+			pair := [2]uuid.UUID{userID, other}
+			if strings.Compare(other.String(), userID.String()) < 0 {
+				pair = [2]uuid.UUID{other, userID}
+			}
+			if seen[pair] {
+				continue
+			}
+			seen[pair] = true
+
+			createdAt := s.randomTime()
+			_, err := s.pool.Exec(ctx, `
+				INSERT INTO friendships (user_one_id, user_two_id, status, action_user_id, created_at, updated_at)
+				VALUES ($1, $2, 'accepted', $3, $4, $4)`, pair[0], pair[1], userID, createdAt)
+			if err != nil {
+				return nil, err
+			}
+			room, err := s.createRoom(ctx, "private", nil, nil, pair[:], createdAt)
+			if err != nil {
+				return nil, err
+			}
+			rooms = append(rooms, room)
+		}
+	}
+	log.Printf("Created %d friendships", len(rooms))
+	return rooms, nil
+}
+
+func (s *seeder) createGroups(ctx context.Context, userIDs []uuid.UUID, count, size int) ([]seededRoom, error) {
+	size = min(max(size, 2), len(userIDs))
+	rooms := make([]seededRoom, 0, count)
+	for i := range count {
+		members := slices.Clone(userIDs)
+		s.rng.Shuffle(len(members), func(a, b int) { members[a], members[b] = members[b], members[a] })
+		members = members[:size]
+		name := groupNames[i%len(groupNames)]
+		if i >= len(groupNames) {
+			name = fmt.Sprintf("%s %d", name, i/len(groupNames)+1)
+		}
+		room, err := s.createRoom(ctx, "group", &name, &members[0], members, s.now.Add(-s.span))
+		if err != nil {
+			return nil, err
+		}
+		rooms = append(rooms, room)
+	}
+	log.Printf("Created %d group rooms", len(rooms))
+	return rooms, nil
+}
+
+func (s *seeder) createRoom(ctx context.Context, roomType string, name *string, ownerID *uuid.UUID, members []uuid.UUID, createdAt time.Time) (seededRoom, error) {
+	room := seededRoom{id: uuid.New(), members: slices.Clone(members)}
+	_, err := s.pool.Exec(ctx, `INSERT INTO rooms (id, type, name, owner_id, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $5)`, room.id, roomType, name, ownerID, createdAt)
+	if err != nil {
+		return room, err
+	}
+	for _, memberID := range members {
+		role := "member"
+		if ownerID != nil && *ownerID == memberID {
+			role = "owner"
+		}
+		_, err := s.pool.Exec(ctx, `INSERT INTO room_participants (room_id, user_id, role, joined_at) VALUES ($1, $2, $3, $4)`, room.id, memberID, role, createdAt)
+		if err != nil {
+			return room, err
+		}
+	}
+	return room, nil
+}
+
+func (s *seeder) createMessages(ctx context.Context, room seededRoom, count int) (int, error) {
+	if count == 0 {
+		return 0, nil
+	}
+	times := make([]time.Time, count)
+	for i := range times {
+		times[i] = s.randomTime()
+	}
+	slices.SortFunc(times, func(a, b time.Time) int { return a.Compare(b) })
+
+	rows := make([][]any, count)
+	sender := room.members[s.rng.IntN(len(room.members))]
+	for i, at := range times {
+		if s.rng.IntN(3) == 0 {
+			sender = room.members[s.rng.IntN(len(room.members))]
+		}
+		content, hashtags, messageLinks := s.message()
+		rows[i] = []any{uuid.New(), room.id, sender, content, hashtags, messageLinks, at}
+	}
+	_, err := s.pool.CopyFrom(ctx, pgx.Identifier{"messages"}, []string{"message_uid", "room_id", "user_id", "content", "hashtags", "links", "created_at"}, pgx.CopyFromRows(rows))
+	if err != nil {
+		return 0, err
+	}
+	_, err = s.pool.Exec(ctx, `UPDATE rooms SET last_message_at = $2 WHERE id = $1`, room.id, times[len(times)-1])
+	return count, err
+}
+
+func (s *seeder) markRead(ctx context.Context, rooms []seededRoom, unread int) error {
+	for _, room := range rooms {
+		_, err := s.pool.Exec(ctx, `
+			INSERT INTO message_read_status (message_id, user_id, read_at)
+			SELECT m.id, rp.user_id, m.created_at + INTERVAL '5 minutes'
+			FROM messages m
+			JOIN room_participants rp ON rp.room_id = m.room_id AND rp.user_id <> m.user_id
+			WHERE m.room_id = $1
+				AND m.id NOT IN (SELECT id FROM messages WHERE room_id = $1 ORDER BY created_at DESC LIMIT $2)
+			ON CONFLICT DO NOTHING`, room.id, unread)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *seeder) message() (string, []string, []string) {
+	parts := []string{}
+	if s.rng.IntN(4) == 0 {
+		parts = append(parts, openers[s.rng.IntN(len(openers))])
+	}
+	parts = append(parts, phrases[s.rng.IntN(len(phrases))])
+	hashtags := []string{}
+	messageLinks := []string{}
+	if s.rng.IntN(8) == 0 {
+		tag := tags[s.rng.IntN(len(tags))]
+		parts = append(parts, "#"+tag)
+		hashtags = append(hashtags, tag)
+	}
+	if s.rng.IntN(15) == 0 {
+		link := links[s.rng.IntN(len(links))]
+		parts = append(parts, link)
+		messageLinks = append(messageLinks, link)
+	}
+	return strings.Join(parts, " "), hashtags, messageLinks
+}
+
+func (s *seeder) randomTime() time.Time {
+	day := s.now.Add(-time.Duration(s.rng.Int64N(int64(s.span)))).Truncate(24 * time.Hour)
+	hour := 8 + s.rng.IntN(12)
+	return day.Add(time.Duration(hour)*time.Hour + time.Duration(s.rng.IntN(3600))*time.Second)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	subsystemStartTimeout = 30 * time.Second
+	subsystemStopTimeout  = 10 * time.Second
+)
+
+const (
+	statePending  = "pending"
+	stateStarting = "starting"
+	stateRunning  = "running"
+	stateStopping = "stopping"
+	stateStopped  = "stopped"
+	stateFailed   = "failed"
+)
+
+type subsystemHooks struct {
+	start func(ctx context.Context) error
+	run   func(ctx context.Context)
+	stop  func(ctx context.Context) error
+}
+
+type subsystem struct {
+	name   string
+	hooks  subsystemHooks
+	state  string
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+type lifecycle struct {
+	mu         sync.Mutex
+	subsystems []*subsystem
+	started    []*subsystem
+}
+
+func (l *lifecycle) add(name string, hooks subsystemHooks) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.subsystems = append(l.subsystems, &subsystem{name: name, hooks: hooks, state: statePending})
+}
+
+func (l *lifecycle) goRun(name string, run func(ctx context.Context)) {
+	l.add(name, subsystemHooks{run: run})
+}
+
+func (l *lifecycle) Start() error {
+	l.mu.Lock()
+	subsystems := slices.Clone(l.subsystems)
+	l.mu.Unlock()
+
+	for _, s := range subsystems {
+		if err := l.start(s); err != nil {
+			l.setState(s, stateFailed)
+			return fmt.Errorf("could not start %s: %w", s.name, err)
+		}
+	}
+	return nil
+}
+
+func (l *lifecycle) start(s *subsystem) error {
+	l.setState(s, stateStarting)
+	ctx, cancel := context.WithCancel(context.Background())
+	l.mu.Lock()
+	s.cancel = cancel
+	l.started = append(l.started, s)
+	l.mu.Unlock()
+
+	if s.hooks.start != nil {
+		result := make(chan error, 1)
+		go func() { result <- s.hooks.start(ctx) }()
+		select {
+		case err := <-result:
+			if err != nil {
+				return err
+			}
+		case <-time.After(subsystemStartTimeout):
+			return fmt.Errorf("timed out after %s", subsystemStartTimeout)
+		}
+	}
+	if s.hooks.run != nil {
+		s.done = make(chan struct{})
+		go func() {
+			defer close(s.done)
+			s.hooks.run(ctx)
+		}()
+	}
+	l.setState(s, stateRunning)
+	log.Printf("Started %s", s.name)
+	return nil
+}
+
+func (l *lifecycle) Stop() error {
+	l.mu.Lock()
+	started := slices.Clone(l.started)
+	l.started = nil
+	l.mu.Unlock()
+
+	var errs []error
+	for _, s := range slices.Backward(started) {
+		if err := l.stop(s); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (l *lifecycle) stop(s *subsystem) error {
+	l.setState(s, stateStopping)
+	ctx, cancel := context.WithTimeout(context.Background(), subsystemStopTimeout)
+	defer cancel()
+
+	var err error
+	if s.hooks.stop != nil {
+		err = s.hooks.stop(ctx)
+	}
+	s.cancel()
+	if s.done != nil {
+		select {
+		case <-s.done:
+		case <-ctx.Done():
+			err = errors.Join(err, fmt.Errorf("did not stop within %s", subsystemStopTimeout))
+		}
+	}
+	if err != nil {
+		l.setState(s, stateFailed)
+		return err
+	}
+	l.setState(s, stateStopped)
+	log.Printf("Stopped %s", s.name)
+	return nil
+}
+
+func (l *lifecycle) setState(s *subsystem, state string) {
+	l.mu.Lock()
+	s.state = state
+	l.mu.Unlock()
+}
+
+func (l *lifecycle) Ready() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, s := range l.subsystems {
+		if s.state != stateRunning {
+			return false
+		}
+	}
+	return true
+}
+
+func (l *lifecycle) Subsystems() map[string]string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	states := make(map[string]string, len(l.subsystems))
+	for _, s := range l.subsystems {
+		states[s.name] = s.state
+	}
+	return states
+}
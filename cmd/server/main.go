@@ -1,14 +1,41 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"chatservice/config"
+	"chatservice/internal/access"
+	"chatservice/internal/attachments"
+	"chatservice/internal/cluster"
+	"chatservice/internal/compliance"
+	"chatservice/internal/ephemeral"
+	"chatservice/internal/events"
+	"chatservice/internal/experiments"
+	"chatservice/internal/firehose"
+	"chatservice/internal/insights"
+	"chatservice/internal/integrations"
+	"chatservice/internal/janitor"
+	"chatservice/internal/logbuf"
+	"chatservice/internal/logging"
+	"chatservice/internal/outbox"
+	"chatservice/internal/overload"
 	postgres "chatservice/internal/repository"
+	"chatservice/internal/scheduler"
+	"chatservice/internal/search"
 	
 	http_delivery "chatservice/internal/delivery/http"
 	ws_delivery "chatservice/internal/delivery/websocket"
 	"chatservice/internal/middleware"
+	"chatservice/internal/notify"
+	"chatservice/internal/sfu"
 	"chatservice/internal/usecase"
 
 	"github.com/gin-gonic/gin"
@@ -18,8 +45,9 @@ func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "http://localhost:3000")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, Upload-Offset, Upload-Checksum, Tus-Resumable, Idempotency-Key")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, PATCH, HEAD, DELETE")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "Location, Upload-Offset, Upload-Length, Upload-Expires, Tus-Resumable, ETag, Accept-Ranges, Content-Range, Idempotent-Replayed")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -31,41 +59,331 @@ func CORSMiddleware() gin.HandlerFunc {
 }
 
 func main() {
+	recentErrors := logbuf.NewRecent(200)
+	log.SetOutput(logging.NewScrubber(io.MultiWriter(os.Stderr, recentErrors)))
+	gin.DefaultWriter = logging.NewScrubber(os.Stdout)
+	gin.DefaultErrorWriter = logging.NewScrubber(io.MultiWriter(os.Stderr, recentErrors))
+
 	cfg := config.Load()
+	if err := logging.Configure(cfg.LogLevel, cfg.LogModules); err != nil {
+		log.Fatalf("Invalid logging configuration: %v", err)
+	}
+	logging.SetRedaction(cfg.LogRedact)
+	if !cfg.LogRedact {
+		log.Printf("Log scrubbing is disabled, logs and error reports may contain personal data")
+	}
 
-	dbPool, err := postgres.NewDBPool(cfg.DatabaseURL)
+	dbPool, err := postgres.NewDBPool(cfg.DatabaseURL, cfg.StatementCacheCapacity)
 	if err != nil {
 		log.Fatalf("Could not connect to the database: %v", err)
 	}
 	defer dbPool.Close()
 
-	appRepo := postgres.NewAppRepository(dbPool)
+	resolver, err := postgres.NewClusterResolver(dbPool, cfg.StatementCacheCapacity, cfg.RegionDatabaseURLs, cfg.TenantRegions)
+	if err != nil {
+		log.Fatalf("Could not set up database regions: %v", err)
+	}
+	resolver.SetRetryPolicy(cfg.DBRetryAttempts, cfg.DBRetryBaseDelay)
+	app := &lifecycle{}
+	dbHealth := postgres.NewHealthProbe(resolver.Pools(), cfg.DBHealthInterval)
+	app.goRun("database health probe", dbHealth.Run)
+	defer resolver.Close()
+
+	if cfg.SchemaCheck {
+		if err := resolver.Validate(context.Background()); err != nil {
+			log.Fatalf("Schema validation failed: %v", err)
+		}
+		log.Printf("Database schema matches version %d", postgres.ExpectedSchemaVersion)
+	}
+
+	appRepo := postgres.NewAppRepository(resolver)
+	if len(cfg.ShadowModes) > 0 {
+		if appRepo, err = postgres.NewShadowRepository(appRepo, resolver, cfg.ShadowModes); err != nil {
+			log.Fatalf("Could not configure shadow writes: %v", err)
+		}
+	}
+	var userCache *postgres.UserCache
+	if cfg.UserCacheSize > 0 {
+		userCache = postgres.NewUserCache(appRepo, cfg.UserCacheSize, cfg.UserCacheTTL)
+		appRepo = userCache
+	}
+
+	var storage *attachments.DiskStorage
+	janitorCfg := janitor.Config{Interval: cfg.JanitorInterval, DraftTTL: cfg.DraftTTL, PushTTL: cfg.SentPushTTL, IdempotencyTTL: cfg.IdempotencyTTL, SummaryTTL: cfg.SummaryTTL}
+	if cfg.UploadDir != "" {
+		if storage, err = attachments.NewDiskStorage(cfg.UploadDir); err != nil {
+			log.Fatalf("Could not set up upload storage: %v", err)
+		}
+		janitorCfg.RemoveBlob = storage.Remove
+		var keys attachments.KeyWrapper
+		switch {
+		case cfg.AttachmentKMSURL != "":
+			if keys, err = attachments.NewKMSWrapper(cfg.AttachmentKMSURL, cfg.AttachmentKMSToken, cfg.AttachmentActiveKey); err != nil {
+				log.Fatalf("Could not configure attachment KMS: %v", err)
+			}
+		case len(cfg.AttachmentKeys) > 0:
+			if keys, err = attachments.NewKeyring(cfg.AttachmentKeys, cfg.AttachmentActiveKey); err != nil {
+				log.Fatalf("Could not load attachment encryption keys: %v", err)
+			}
+		}
+		if keys != nil {
+			storage.SetEncryption(keys)
+			log.Printf("Attachment encryption enabled with key %s", keys.ActiveKeyID())
+		}
+	}
+
+	maintenance := make(map[string]postgres.MaintenanceRepository)
+	activity := make(map[string]postgres.InsightsRepository)
+	for name, pool := range resolver.Pools() {
+		maintenance[name] = postgres.NewMaintenanceRepository(pool)
+		activity[name] = postgres.NewInsightsRepository(pool)
+	}
 
 	hub := ws_delivery.NewHub(appRepo)
-	go hub.Run()
+	hub.SetDoNotTrack(cfg.DoNotTrack)
+	hub.SetRecording(cfg.WSRecordDir)
+	hub.SetSnapshotPath(cfg.WSSnapshotPath)
+	hub.SetDeliverCoalescing(cfg.WSDeliverCoalesceWindow)
+	hub.SetProtocolErrorLimit(cfg.WSMaxProtocolErrors, cfg.WSProtocolErrorWindow)
+	hub.SetAdmission(cfg.AdmissionConcurrency, cfg.AdmissionWait)
+	hub.SetPacketConcurrency(cfg.WSPacketConcurrency, cfg.WSPacketQueue)
+	hub.SetChunking(cfg.ChunkThreshold, cfg.MaxChunkedPayload)
+
+	var node *cluster.Node
+	if cfg.ClusterEnabled {
+		node = cluster.NewNode(dbPool, postgres.NewClusterRepository(dbPool), cfg.InstanceID, cfg.InstanceURL)
+		hub.SetCluster(node)
+		app.goRun("cluster node", func(ctx context.Context) { node.Run(ctx, hub.ConnectionCount) })
+		log.Printf("Cluster mode enabled, instance ID %s", node.ID())
+	}
+
+	schedulerOwner := cfg.InstanceID
+	if node != nil {
+		schedulerOwner = node.ID()
+	}
+	jobs := scheduler.New(postgres.NewJobRepository(dbPool), schedulerOwner, cfg.SchedulerPollInterval)
+	for _, job := range janitor.New(janitorCfg, maintenance).Jobs() {
+		jobs.Register(job)
+	}
+	if storage != nil && storage.Encrypted() {
+		jobs.Register(scheduler.Job{Name: "rewrap-attachment-keys", Interval: cfg.JanitorInterval, Run: storage.RewrapKeys})
+	}
+	if cfg.InsightsRollupInterval > 0 {
+		jobs.Register(insights.NewPipeline(activity).Job(cfg.InsightsRollupInterval))
+	}
 
-	appUsecase := usecase.NewAppUsecase(appRepo, hub, dbPool)
+	hub.SetConnectionLimit(cfg.MaxConnections, func() []string {
+		if node != nil {
+			if urls := node.PeerURLs(); len(urls) > 0 {
+				return urls
+			}
+		}
+		return cfg.AlternativeInstanceURLs
+	})
+	app.add("websocket hub", subsystemHooks{
+		start: func(context.Context) error {
+			if err := hub.RestoreSnapshot(); err != nil {
+				log.Printf("Could not restore hub snapshot: %v", err)
+			}
+			return nil
+		},
+		run:  hub.Run,
+		stop: hub.SaveSnapshot,
+	})
+
+	if cfg.SMTPAddr != "" && cfg.EmailLinkSecret == "" {
+		log.Fatal("EMAIL_LINK_SECRET is required when SMTP_ADDR is set")
+	}
+	mailer := notify.NewMailer(notify.SMTPConfig{
+		Addr:     cfg.SMTPAddr,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+		From:     cfg.SMTPFrom,
+	})
+	emailSender := notify.NewEmailSender(mailer, cfg.EmailLinkSecret, cfg.PublicBaseURL)
+	notifier := notify.NewDispatcher(notify.NewPusher(cfg.PushGatewayURL), emailSender, cfg.PushBatchWindow)
+	notifier.SetMasker(notify.NewMasker(cfg.PushMaskedWords))
+	notifier.SetTracker(appRepo)
+	notifier.SetRateLimit(cfg.PushRateLimit)
+
+	failedDeliveries := outbox.NewService(postgres.NewDeliveryRepository(dbPool), cfg.DeliveryMaxAttempts)
+	failedDeliveries.Handle(outbox.KindPush, notifier.RetryPush)
+	notifier.SetOutbox(failedDeliveries)
+	jobs.Register(failedDeliveries.Job(cfg.DeliveryRetryInterval))
+
+	bus := events.NewBus()
+	bus.Subscribe(hub.HandleEvent)
+	if userCache != nil {
+		bus.Subscribe(userCache.HandleEvent)
+	}
+	pushSubscriber := notify.NewSubscriber(notifier, appRepo, hub)
+	app.add("push fan-out", subsystemHooks{start: func(ctx context.Context) error {
+		pushSubscriber.StartFanOut(ctx, cfg.PushFanOutWorkers, cfg.PushFanOutQueue, cfg.PushFanOutBatch)
+		return nil
+	}})
+	bus.Subscribe(pushSubscriber.HandleEvent)
+
+	appUsecase := usecase.NewAppUsecase(appRepo, hub, resolver, notifier, bus)
 
 	concreteUsecase, ok := appUsecase.(*usecase.AppUsecase)
 	if !ok {
 		log.Fatal("Could not assert AppUsecase interface to concrete type *usecase.AppUsecase")
 	}
 	hub.SetUsecase(concreteUsecase)
+	bus.Subscribe(concreteUsecase.HandleEvent)
+	concreteUsecase.SetPresence(hub)
+	concreteUsecase.SetSFU(sfu.NewClient(sfu.Config{
+		URL:           cfg.SFUURL,
+		APIKey:        cfg.SFUAPIKey,
+		APISecret:     cfg.SFUAPISecret,
+		WebhookSecret: cfg.SFUWebhookSecret,
+		TokenTTL:      cfg.SFUTokenTTL,
+		APIURL:        cfg.SFUAPIURL,
+	}))
+	concreteUsecase.SetAuthSync(integrations.NewAuthSync(cfg.AuthWebhookSecret))
+	concreteUsecase.SetCallRingTimeout(cfg.CallRingTimeout)
+	concreteUsecase.SetPageLimits(cfg.MessagePageDefault, cfg.MessagePageMax)
+	concreteUsecase.SetAwayReplyCooldown(cfg.AwayReplyCooldown)
+	concreteUsecase.SetShareBaseURL(cfg.PublicBaseURL)
+	jobs.Register(scheduler.Job{Name: "unsnooze-rooms", Interval: cfg.SnoozeSweepInterval, Run: concreteUsecase.ExpireRoomSnoozes})
+	concreteUsecase.SetSupportSLA(cfg.SupportFirstResponseSLA, cfg.SupportResolutionSLA)
+	jobs.Register(scheduler.Job{Name: "support-sla", Interval: cfg.SupportSLASweepInterval, Run: concreteUsecase.CheckSupportSLAs})
+	concreteUsecase.SetDailyQuotas(usecase.DailyQuotas{
+		Messages:       cfg.QuotaDailyMessages,
+		FriendRequests: cfg.QuotaDailyFriendReqs,
+		RoomsCreated:   cfg.QuotaDailyRooms,
+		UrgentMessages: cfg.QuotaDailyUrgent,
+	})
+	experimentDefs, err := experiments.Parse(cfg.Experiments)
+	if err != nil {
+		log.Fatalf("Could not load experiments: %v", err)
+	}
+	concreteUsecase.SetExperiments(experiments.NewService(experimentDefs, postgres.NewExperimentRepository(resolver)))
+	if storage != nil {
+		concreteUsecase.SetUploads(storage, int64(cfg.MaxUploadSize), cfg.UploadTTL)
+	}
+	actionDispatcher := integrations.NewActionDispatcher(cfg.ActionWebhookSecret)
+	failedDeliveries.Handle(outbox.KindActionWebhook, actionDispatcher.Retry)
+	concreteUsecase.SetActionDispatcher(actionDispatcher)
+	concreteUsecase.SetOutbox(failedDeliveries)
+	concreteUsecase.SetTranslator(integrations.NewTranslator(cfg.TranslateHookURL, cfg.TranslateHookToken))
+	concreteUsecase.SetSummarizer(integrations.NewSummaryHook(cfg.SummaryHookURL, cfg.SummaryHookToken))
+	concreteUsecase.SetSuggestionProvider(integrations.NewSuggestionHook(cfg.SuggestionHookURL, cfg.SuggestionHookToken))
+	if openSearch := search.NewOpenSearch(cfg.OpenSearchURL, cfg.OpenSearchIndex, cfg.OpenSearchUsername, cfg.OpenSearchPassword); openSearch != nil {
+		indexer := search.NewIndexer(openSearch, cfg.SearchIndexQueue)
+		app.add("search indexer", subsystemHooks{start: func(ctx context.Context) error {
+			if err := openSearch.EnsureIndex(ctx); err != nil {
+				log.Printf("Could not prepare OpenSearch index, relying on Postgres fallback until it recovers: %v", err)
+			}
+			indexer.Start(ctx, cfg.SearchIndexWorkers)
+			return nil
+		}})
+		bus.Subscribe(indexer.HandleEvent)
+		concreteUsecase.SetSearch(openSearch)
+		if cfg.SearchBackfillInterval > 0 {
+			tenants := []string{""}
+			for tenantID := range cfg.TenantRegions {
+				tenants = append(tenants, tenantID)
+			}
+			backfill := search.NewBackfill(indexer, appRepo, postgres.NewSearchBackfillRepository(dbPool), tenants)
+			jobs.Register(backfill.Job(cfg.SearchBackfillInterval))
+		}
+	}
+	app.goRun("scheduler", jobs.Run)
+	if node != nil {
+		shared := ephemeral.NewSharedStore(postgres.NewEphemeralRepository(dbPool))
+		app.goRun("ephemeral store", shared.Run)
+		concreteUsecase.SetEphemeralStore(shared)
+	}
 
 	router := gin.Default()
 
 	router.Use(CORSMiddleware())
 
+	http_delivery.RegisterHealthRoutes(&router.RouterGroup, dbHealth, app)
+
+	if cfg.OverloadCheckInterval > 0 {
+		loadMonitor := overload.NewMonitor(overload.Config{
+			Interval:       cfg.OverloadCheckInterval,
+			MaxLoopLag:     cfg.OverloadMaxLoopLag,
+			MaxAcquireWait: cfg.OverloadMaxAcquireWait,
+			RetryAfter:     cfg.OverloadRetryAfter,
+		}, func(ctx context.Context) error {
+			_, err := hub.Stats(ctx)
+			return err
+		}, resolver.Stats)
+		app.goRun("overload monitor", loadMonitor.Run)
+		router.Use(middleware.ShedHeavyRoutes(loadMonitor,
+			"/rooms/:id/messages",
+			"/rooms/:id/highlights",
+			"/labels/:id/messages",
+			"/messages/search",
+			"/quick-search",
+			"/users/search",
+			"/shared/:token",
+			"/widget/messages",
+			"/admin/compliance/export",
+			"/admin/firehose/messages",
+		))
+	}
+
+	http_delivery.RegisterPublicRoutes(&router.RouterGroup, appUsecase)
+
 	authMiddleware := middleware.AuthMiddleware(cfg.AuthServiceURL)
 	router.Use(authMiddleware)
 
+	allowlist := access.NewAllowlist(postgres.NewAccessRepository(resolver))
+	if cfg.InviteOnly {
+		router.Use(middleware.InviteOnlyMiddleware(allowlist, cfg.AdminUserIDs))
+		log.Printf("Invite-only mode enabled")
+	}
+
+	if cfg.IdempotencyTTL > 0 {
+		router.Use(middleware.IdempotencyMiddleware(postgres.NewIdempotencyRepository(resolver), cfg.IdempotencyTTL))
+	}
+
 	http_delivery.RegisterRoutes(&router.RouterGroup, appUsecase)
+	complianceService := compliance.NewService(postgres.NewComplianceRepository(resolver))
+	complianceService.SetSigningKey(cfg.ExportSigningKey)
+	if storage != nil {
+		complianceService.SetStorage(storage)
+	}
+	http_delivery.RegisterAccessRoutes(&router.RouterGroup, middleware.AdminMiddleware(cfg.AdminUserIDs), allowlist)
+	http_delivery.RegisterAdminConsole(&router.RouterGroup, middleware.AdminMiddleware(cfg.AdminUserIDs), hub, appUsecase, recentErrors)
+	firehoseService := firehose.NewService(postgres.NewFirehoseRepository(resolver), cfg.FirehoseSettleDelay)
+	http_delivery.RegisterAdminRoutes(&router.RouterGroup, middleware.AdminMiddleware(cfg.AdminUserIDs), node, complianceService, resolver, jobs, failedDeliveries, firehoseService)
 
 	wsGroup := router.Group("/ws")
 	wsGroup.GET("", ws_delivery.ServeWs(hub))
 
-	log.Printf("Server starting on port %s", cfg.ServerPort)
-	if err := router.Run(cfg.ServerPort); err != nil {
-		log.Fatalf("Failed to run server: %v", err)
+	server := &http.Server{Handler: router}
+	app.add("http server", subsystemHooks{
+		start: func(context.Context) error {
+			listener, err := listen(cfg.ListenAddr, cfg.SocketMode)
+			if err != nil {
+				return fmt.Errorf("could not listen on %s: %w", cfg.ListenAddr, err)
+			}
+			log.Printf("Server starting on %s", listener.Addr())
+			go func() {
+				if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					log.Fatalf("Failed to run server: %v", err)
+				}
+			}()
+			return nil
+		},
+		stop: server.Shutdown,
+	})
+
+	if err := app.Start(); err != nil {
+		app.Stop()
+		log.Fatalf("Startup failed: %v", err)
 	}
-}
\ No newline at end of file
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	log.Printf("Shutting down")
+	if err := app.Stop(); err != nil {
+		log.Printf("Errors during shutdown: %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"chatservice/internal/middleware"
+)
+
+type stubAuth struct {
+	mu    sync.RWMutex
+	users map[string]middleware.UserData
+}
+
+func newStubAuth() *stubAuth {
+	return &stubAuth{users: make(map[string]middleware.UserData)}
+}
+
+func (a *stubAuth) add(u *simUser) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.users[u.token] = middleware.UserData{ID: u.id, Email: u.email, Nickname: u.nickname}
+}
+
+func (a *stubAuth) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/auth/me" {
+		http.NotFound(w, r)
+		return
+	}
+	cookie, err := r.Cookie(middleware.AuthCookieName)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	a.mu.RLock()
+	user, ok := a.users[cookie.Value]
+	a.mu.RUnlock()
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(middleware.AuthResponse{Success: true, User: user})
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type membership struct {
+	roomID uuid.UUID
+	userID uuid.UUID
+}
+
+type checker struct {
+	pool    *pgxpool.Pool
+	userIDs []uuid.UUID
+	since   time.Time
+
+	members map[membership]bool
+	reads   map[membership]int64
+}
+
+func newChecker(pool *pgxpool.Pool, userIDs []uuid.UUID, since time.Time) *checker {
+	return &checker{
+		pool:    pool,
+		userIDs: userIDs,
+		since:   since,
+		members: make(map[membership]bool),
+		reads:   make(map[membership]int64),
+	}
+}
+
+func (c *checker) roomsOf(ctx context.Context, userID uuid.UUID) (map[uuid.UUID]bool, error) {
+	rows, err := c.pool.Query(ctx, `SELECT room_id FROM room_participants WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error loading rooms of %s: %w", userID, err)
+	}
+	defer rows.Close()
+	rooms := make(map[uuid.UUID]bool)
+	for rows.Next() {
+		var roomID uuid.UUID
+		if err := rows.Scan(&roomID); err != nil {
+			return nil, err
+		}
+		rooms[roomID] = true
+	}
+	return rooms, rows.Err()
+}
+
+func (c *checker) run(ctx context.Context) ([]string, error) {
+	var violations []string
+	for _, check := range []func(context.Context) ([]string, error){
+		c.orphanRooms,
+		c.privateRoomSizes,
+		c.friendshipRooms,
+		c.membershipMonotonic,
+		c.readStateMonotonic,
+		c.nonMemberActivity,
+	} {
+		found, err := check(ctx)
+		if err != nil {
+			return violations, err
+		}
+		violations = append(violations, found...)
+	}
+	return violations, nil
+}
+
+func (c *checker) orphanRooms(ctx context.Context) ([]string, error) {
+	rows, err := c.pool.Query(ctx, `
+		SELECT r.id, r.type FROM rooms r
+		WHERE r.created_at >= $1
+			AND NOT EXISTS (SELECT 1 FROM room_participants p WHERE p.room_id = r.id)`, c.since)
+	if err != nil {
+		return nil, fmt.Errorf("error checking orphan rooms: %w", err)
+	}
+	defer rows.Close()
+	var violations []string
+	for rows.Next() {
+		var roomID uuid.UUID
+		var roomType string
+		if err := rows.Scan(&roomID, &roomType); err != nil {
+			return nil, err
+		}
+		violations = append(violations, fmt.Sprintf("%s room %s has no participants", roomType, roomID))
+	}
+	return violations, rows.Err()
+}
+
+func (c *checker) privateRoomSizes(ctx context.Context) ([]string, error) {
+	rows, err := c.pool.Query(ctx, `
+		SELECT r.id, COUNT(p.user_id) FROM rooms r
+		JOIN room_participants p ON p.room_id = r.id
+		WHERE r.type = 'private'
+			AND EXISTS (SELECT 1 FROM room_participants s WHERE s.room_id = r.id AND s.user_id = ANY($1))
+		GROUP BY r.id
+		HAVING COUNT(p.user_id) <> 2`, c.userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error checking private rooms: %w", err)
+	}
+	defer rows.Close()
+	var violations []string
+	for rows.Next() {
+		var roomID uuid.UUID
+		var count int
+		if err := rows.Scan(&roomID, &count); err != nil {
+			return nil, err
+		}
+		violations = append(violations, fmt.Sprintf("private room %s has %d participants", roomID, count))
+	}
+	return violations, rows.Err()
+}
+
+func (c *checker) friendshipRooms(ctx context.Context) ([]string, error) {
+	rows, err := c.pool.Query(ctx, `
+		SELECT f.user_one_id, f.user_two_id FROM friendships f
+		WHERE f.status = 'accepted' AND f.user_one_id = ANY($1) AND f.user_two_id = ANY($1)
+			AND NOT EXISTS (
+				SELECT 1 FROM rooms r
+				JOIN room_participants a ON a.room_id = r.id AND a.user_id = f.user_one_id
+				JOIN room_participants b ON b.room_id = r.id AND b.user_id = f.user_two_id
+				WHERE r.type = 'private'
+			)`, c.userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error checking friendship rooms: %w", err)
+	}
+	defer rows.Close()
+	var violations []string
+	for rows.Next() {
+		var one, two uuid.UUID
+		if err := rows.Scan(&one, &two); err != nil {
+			return nil, err
+		}
+		violations = append(violations, fmt.Sprintf("friends %s and %s share no private room", one, two))
+	}
+	return violations, rows.Err()
+}
+
+func (c *checker) membershipMonotonic(ctx context.Context) ([]string, error) {
+	rows, err := c.pool.Query(ctx, `SELECT room_id, user_id FROM room_participants WHERE user_id = ANY($1)`, c.userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error loading memberships: %w", err)
+	}
+	defer rows.Close()
+	current := make(map[membership]bool)
+	for rows.Next() {
+		var m membership
+		if err := rows.Scan(&m.roomID, &m.userID); err != nil {
+			return nil, err
+		}
+		current[m] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	var violations []string
+	for m := range c.members {
+		if !current[m] {
+			violations = append(violations, fmt.Sprintf("user %s is no longer a member of room %s", m.userID, m.roomID))
+		}
+	}
+	c.members = current
+	return violations, nil
+}
+
+func (c *checker) readStateMonotonic(ctx context.Context) ([]string, error) {
+	rows, err := c.pool.Query(ctx, `
+		SELECT m.room_id, s.user_id, MAX(s.message_id) FROM message_read_status s
+		JOIN messages m ON m.id = s.message_id
+		WHERE s.user_id = ANY($1)
+		GROUP BY m.room_id, s.user_id`, c.userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error loading read state: %w", err)
+	}
+	defer rows.Close()
+	current := make(map[membership]int64)
+	for rows.Next() {
+		var m membership
+		var last int64
+		if err := rows.Scan(&m.roomID, &m.userID, &last); err != nil {
+			return nil, err
+		}
+		current[m] = last
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	var violations []string
+	for m, last := range c.reads {
+		if current[m] < last {
+			violations = append(violations, fmt.Sprintf("read state of %s in room %s went back from message %d to %d", m.userID, m.roomID, last, current[m]))
+		}
+	}
+	c.reads = current
+	return violations, nil
+}
+
+func (c *checker) nonMemberActivity(ctx context.Context) ([]string, error) {
+	rows, err := c.pool.Query(ctx, `
+		SELECT 'sent', m.user_id, m.room_id, COUNT(*) FROM messages m
+		WHERE m.user_id = ANY($1) AND m.kind = 'text' AND m.created_at >= $2
+			AND NOT EXISTS (SELECT 1 FROM room_participants p WHERE p.room_id = m.room_id AND p.user_id = m.user_id)
+		GROUP BY m.user_id, m.room_id
+		UNION ALL
+		SELECT 'read', s.user_id, m.room_id, COUNT(*) FROM message_read_status s
+		JOIN messages m ON m.id = s.message_id
+		WHERE s.user_id = ANY($1)
+			AND NOT EXISTS (SELECT 1 FROM room_participants p WHERE p.room_id = m.room_id AND p.user_id = s.user_id)
+		GROUP BY s.user_id, m.room_id`, c.userIDs, c.since)
+	if err != nil {
+		return nil, fmt.Errorf("error checking non-member activity: %w", err)
+	}
+	defer rows.Close()
+	var violations []string
+	for rows.Next() {
+		var action string
+		var userID, roomID uuid.UUID
+		var count int
+		if err := rows.Scan(&action, &userID, &roomID, &count); err != nil {
+			return nil, err
+		}
+		violations = append(violations, fmt.Sprintf("user %s %s %d messages in room %s without being a member", userID, action, count, roomID))
+	}
+	return violations, rows.Err()
+}
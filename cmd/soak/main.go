@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"time"
+
+	postgres "chatservice/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+func main() {
+	baseURL := flag.String("url", "http://localhost:8080", "HTTP base URL of the test server")
+	wsURL := flag.String("ws-url", "ws://localhost:8080/ws?v=2", "WebSocket URL of the test server")
+	dbURL := flag.String("database-url", os.Getenv("DATABASE_URL"), "Postgres connection string of the test server's primary")
+	authAddr := flag.String("auth-listen", "127.0.0.1:9099", "address of the stub auth service; start the test server with AUTH_SERVICE_URL pointing here")
+	users := flag.Int("users", 20, "number of simulated users")
+	workers := flag.Int("workers", 8, "concurrent operation drivers")
+	duration := flag.Duration("duration", 10*time.Minute, "how long to run")
+	checkEvery := flag.Duration("check-interval", 5*time.Second, "how often invariants are checked")
+	failFast := flag.Bool("fail-fast", false, "stop at the first invariant violation")
+	seed := flag.Uint64("seed", uint64(time.Now().UnixNano()), "random seed for the operation mix")
+	flag.Parse()
+
+	if *dbURL == "" {
+		log.Fatal("-database-url or DATABASE_URL is required")
+	}
+	if *users < 2 || *workers < 1 {
+		log.Fatal("-users must be at least 2 and -workers at least 1")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	pool, err := postgres.NewDBPool(*dbURL, 0)
+	if err != nil {
+		log.Fatalf("Could not connect to the database: %v", err)
+	}
+	defer pool.Close()
+
+	auth := newStubAuth()
+	listener, err := net.Listen("tcp", *authAddr)
+	if err != nil {
+		log.Fatalf("Could not listen on %s: %v", *authAddr, err)
+	}
+	go http.Serve(listener, auth)
+
+	var since time.Time
+	if err := pool.QueryRow(ctx, `SELECT now()`).Scan(&since); err != nil {
+		log.Fatalf("Could not read database time: %v", err)
+	}
+
+	report := &report{}
+	w := &world{base: *baseURL, ws: *wsURL, report: report, pending: make(map[[2]int]bool), friends: make(map[[2]int]bool)}
+	for i := range *users {
+		u := newSimUser(uuid.New(), fmt.Sprintf("soak-%d-%d@soak.chatservice.local", *seed, i), w)
+		auth.add(u)
+		if err := u.register(ctx); err != nil {
+			log.Fatalf("Could not register simulated user %d: %v", i, err)
+		}
+		if err := u.connect(); err != nil {
+			log.Fatalf("Could not connect simulated user %d: %v", i, err)
+		}
+		w.users = append(w.users, u)
+	}
+	log.Printf("Soaking %d users with %d workers for %s (seed %d)", *users, *workers, *duration, *seed)
+
+	runCtx, cancel := context.WithTimeout(ctx, *duration)
+	defer cancel()
+	check := newChecker(pool, w.userIDs(), since)
+	w.check = check
+
+	var wg sync.WaitGroup
+	for i := range *workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rng := rand.New(rand.NewPCG(*seed, uint64(i)))
+			for runCtx.Err() == nil {
+				w.step(runCtx, rng)
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(*checkEvery)
+	defer ticker.Stop()
+	runCheck := func() {
+		violations, err := check.run(context.Background())
+		if err != nil {
+			log.Printf("Invariant check failed to run: %v", err)
+			return
+		}
+		for _, v := range violations {
+			report.violation("%s", v)
+		}
+		if *failFast && report.violationCount() > 0 {
+			cancel()
+		}
+	}
+loop:
+	for {
+		select {
+		case <-runCtx.Done():
+			break loop
+		case <-ticker.C:
+			runCheck()
+		}
+	}
+	wg.Wait()
+	for _, u := range w.users {
+		u.close()
+	}
+	runCheck()
+
+	report.print()
+	if report.violationCount() > 0 {
+		os.Exit(1)
+	}
+}
+
+type report struct {
+	mu         sync.Mutex
+	ops        map[string]int
+	rejected   map[string]int
+	violations []string
+}
+
+func (r *report) op(name string, status int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.ops == nil {
+		r.ops = make(map[string]int)
+		r.rejected = make(map[string]int)
+	}
+	r.ops[name]++
+	if status >= 400 {
+		r.rejected[name]++
+	}
+}
+
+func (r *report) violation(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	log.Printf("VIOLATION: %s", msg)
+	r.mu.Lock()
+	r.violations = append(r.violations, msg)
+	r.mu.Unlock()
+}
+
+func (r *report) violationCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.violations)
+}
+
+func (r *report) print() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := make([]string, 0, len(r.ops))
+	for name := range r.ops {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("%-16s %8d ops %8d rejected\n", name, r.ops[name], r.rejected[name])
+	}
+	fmt.Printf("%d invariant violations\n", len(r.violations))
+}
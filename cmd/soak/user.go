@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"chatservice/internal/middleware"
+	"chatservice/pkg/wprotocol"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+type simUser struct {
+	id       uuid.UUID
+	email    string
+	nickname string
+	token    string
+	world    *world
+	client   *http.Client
+
+	mu       sync.Mutex
+	conn     *websocket.Conn
+	rooms    map[uuid.UUID]string
+	lastSeen map[uuid.UUID]int64
+}
+
+type roomListResponse struct {
+	Rooms   []roomRef `json:"rooms"`
+	Snoozed []roomRef `json:"snoozed"`
+}
+
+type roomRef struct {
+	ID   uuid.UUID `json:"id"`
+	Type string    `json:"type"`
+}
+
+func newSimUser(id uuid.UUID, email string, w *world) *simUser {
+	return &simUser{
+		id:       id,
+		email:    email,
+		nickname: "soak " + id.String()[:8],
+		token:    id.String(),
+		world:    w,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		rooms:    make(map[uuid.UUID]string),
+		lastSeen: make(map[uuid.UUID]int64),
+	}
+}
+
+func (u *simUser) register(ctx context.Context) error {
+	status, err := u.do(ctx, "user.register", http.MethodPost, "/users/me", map[string]string{"email": u.email, "nickname": u.nickname}, nil)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("registration returned status %d", status)
+	}
+	return nil
+}
+
+func (u *simUser) connect() error {
+	header := http.Header{}
+	header.Set("Cookie", (&http.Cookie{Name: middleware.AuthCookieName, Value: u.token}).String())
+	conn, _, err := websocket.DefaultDialer.Dial(u.world.ws, header)
+	if err != nil {
+		return err
+	}
+	u.mu.Lock()
+	u.conn = conn
+	u.mu.Unlock()
+	go u.read(conn)
+	return nil
+}
+
+func (u *simUser) reconnect() error {
+	u.close()
+	return u.connect()
+}
+
+func (u *simUser) close() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.conn != nil {
+		u.conn.Close()
+		u.conn = nil
+	}
+}
+
+func (u *simUser) read(conn *websocket.Conn) {
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		for _, frame := range bytes.Split(data, []byte{'\n'}) {
+			packet, err := wprotocol.Parse(frame)
+			if err != nil {
+				continue
+			}
+			switch packet.Op {
+			case wprotocol.OpMsgDeliver:
+				if len(packet.Payload) < 3 {
+					continue
+				}
+				msgID, err := strconv.ParseInt(packet.Payload[0], 10, 64)
+				if err != nil {
+					continue
+				}
+				roomID, err := uuid.Parse(packet.Payload[2])
+				if err != nil {
+					continue
+				}
+				u.mu.Lock()
+				if msgID > u.lastSeen[roomID] {
+					u.lastSeen[roomID] = msgID
+				}
+				u.mu.Unlock()
+			case wprotocol.OpError:
+				u.world.report.op("ws.error", http.StatusBadRequest)
+			}
+		}
+	}
+}
+
+func (u *simUser) send(op wprotocol.OpCode, params ...string) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.conn == nil {
+		return fmt.Errorf("not connected")
+	}
+	return u.conn.WriteMessage(websocket.BinaryMessage, wprotocol.Build(op, params...))
+}
+
+func (u *simUser) do(ctx context.Context, name, method, path string, body, out any) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.world.base+path, reader)
+	if err != nil {
+		return 0, err
+	}
+	req.AddCookie(&http.Cookie{Name: middleware.AuthCookieName, Value: u.token})
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	u.world.report.op(name, resp.StatusCode)
+	if resp.StatusCode >= 500 {
+		u.world.report.violation("server error on %s %s for %s: %d %s", method, path, u.id, resp.StatusCode, bytes.TrimSpace(data))
+	}
+	if out != nil && resp.StatusCode == http.StatusOK {
+		if err := json.Unmarshal(data, out); err != nil {
+			return resp.StatusCode, fmt.Errorf("decoding %s: %w", path, err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+func (u *simUser) refreshRooms(ctx context.Context) (map[uuid.UUID]bool, error) {
+	var list roomListResponse
+	status, err := u.do(ctx, "rooms.list", http.MethodGet, "/rooms", nil, &list)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("room list returned status %d", status)
+	}
+	seen := make(map[uuid.UUID]bool)
+	u.mu.Lock()
+	for _, room := range append(list.Rooms, list.Snoozed...) {
+		u.rooms[room.ID] = room.Type
+		seen[room.ID] = true
+	}
+	u.mu.Unlock()
+	return seen, nil
+}
+
+func (u *simUser) pickRoom(pick func(int) int, roomType string) (uuid.UUID, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	var candidates []uuid.UUID
+	for id, t := range u.rooms {
+		if roomType == "" || t == roomType {
+			candidates = append(candidates, id)
+		}
+	}
+	if len(candidates) == 0 {
+		return uuid.Nil, false
+	}
+	return candidates[pick(len(candidates))], true
+}
+
+func (u *simUser) lastSeenIn(roomID uuid.UUID) int64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.lastSeen[roomID]
+}
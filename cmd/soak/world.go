@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"chatservice/pkg/wprotocol"
+
+	"github.com/google/uuid"
+)
+
+type world struct {
+	base   string
+	ws     string
+	report *report
+	check  *checker
+	users  []*simUser
+
+	mu      sync.Mutex
+	pending map[[2]int]bool
+	friends map[[2]int]bool
+}
+
+type operation struct {
+	name   string
+	weight int
+	run    func(w *world, ctx context.Context, rng *rand.Rand, i int) error
+}
+
+var operations = []operation{
+	{"friend.request", 4, (*world).requestFriend},
+	{"friend.accept", 4, (*world).acceptFriend},
+	{"message.send", 30, (*world).sendMessage},
+	{"message.read", 15, (*world).readMessage},
+	{"rooms.read", 5, (*world).markRoomsRead},
+	{"rooms.list", 8, (*world).listRooms},
+	{"history", 10, (*world).fetchHistory},
+	{"group.create", 2, (*world).createGroup},
+	{"group.add", 3, (*world).addMembers},
+	{"ws.reconnect", 2, (*world).reconnect},
+}
+
+func (w *world) userIDs() []uuid.UUID {
+	ids := make([]uuid.UUID, len(w.users))
+	for i, u := range w.users {
+		ids[i] = u.id
+	}
+	return ids
+}
+
+func (w *world) step(ctx context.Context, rng *rand.Rand) {
+	total := 0
+	for _, op := range operations {
+		total += op.weight
+	}
+	n := rng.IntN(total)
+	for _, op := range operations {
+		if n >= op.weight {
+			n -= op.weight
+			continue
+		}
+		if err := op.run(w, ctx, rng, rng.IntN(len(w.users))); err != nil && ctx.Err() == nil {
+			log.Printf("%s: %v", op.name, err)
+		}
+		return
+	}
+}
+
+func pair(a, b int) [2]int {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]int{a, b}
+}
+
+func (w *world) requestFriend(ctx context.Context, rng *rand.Rand, i int) error {
+	j := rng.IntN(len(w.users))
+	w.mu.Lock()
+	taken := i == j || w.friends[pair(i, j)] || w.pending[[2]int{i, j}] || w.pending[[2]int{j, i}]
+	w.mu.Unlock()
+	if taken {
+		return nil
+	}
+	status, err := w.users[i].do(ctx, "friend.request", http.MethodPost, "/friends/requests", map[string]string{"email": w.users[j].email}, nil)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusOK {
+		w.mu.Lock()
+		w.pending[[2]int{i, j}] = true
+		w.mu.Unlock()
+	}
+	return nil
+}
+
+func (w *world) acceptFriend(ctx context.Context, rng *rand.Rand, i int) error {
+	requester := -1
+	w.mu.Lock()
+	for p := range w.pending {
+		if p[1] == i {
+			requester = p[0]
+			delete(w.pending, p)
+			break
+		}
+	}
+	w.mu.Unlock()
+	if requester < 0 {
+		return nil
+	}
+	status, err := w.users[i].do(ctx, "friend.accept", http.MethodPut, "/friends/requests/"+w.users[requester].id.String()+"/accept", nil, nil)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusOK {
+		w.mu.Lock()
+		w.friends[pair(i, requester)] = true
+		w.mu.Unlock()
+		_, err = w.users[i].refreshRooms(ctx)
+	}
+	return err
+}
+
+func (w *world) sendMessage(ctx context.Context, rng *rand.Rand, i int) error {
+	u := w.users[i]
+	roomID, ok := u.pickRoom(rng.IntN, "")
+	if !ok {
+		_, err := u.refreshRooms(ctx)
+		return err
+	}
+	w.report.op("message.send", 0)
+	if err := u.send(wprotocol.OpMsgSend, roomID.String(), uuid.NewString(), fmt.Sprintf("soak %d from %s", rng.Uint32(), u.nickname)); err != nil {
+		return u.reconnect()
+	}
+	return nil
+}
+
+func (w *world) readMessage(ctx context.Context, rng *rand.Rand, i int) error {
+	u := w.users[i]
+	roomID, ok := u.pickRoom(rng.IntN, "")
+	if !ok {
+		return nil
+	}
+	msgID := u.lastSeenIn(roomID)
+	if msgID == 0 {
+		return nil
+	}
+	w.report.op("message.read", 0)
+	if err := u.send(wprotocol.OpMsgRead, strconv.FormatInt(msgID, 10), roomID.String()); err != nil {
+		return u.reconnect()
+	}
+	return nil
+}
+
+func (w *world) markRoomsRead(ctx context.Context, rng *rand.Rand, i int) error {
+	u := w.users[i]
+	var markers []map[string]any
+	for range 3 {
+		roomID, ok := u.pickRoom(rng.IntN, "")
+		if !ok {
+			break
+		}
+		if msgID := u.lastSeenIn(roomID); msgID > 0 {
+			markers = append(markers, map[string]any{"roomId": roomID, "lastMessageId": msgID})
+		}
+	}
+	if len(markers) == 0 {
+		return nil
+	}
+	_, err := u.do(ctx, "rooms.read", http.MethodPost, "/rooms/read", map[string]any{"rooms": markers}, nil)
+	return err
+}
+
+func (w *world) listRooms(ctx context.Context, rng *rand.Rand, i int) error {
+	u := w.users[i]
+	expected, err := w.check.roomsOf(ctx, u.id)
+	if err != nil {
+		return err
+	}
+	for attempt := 0; ; attempt++ {
+		listed, err := u.refreshRooms(ctx)
+		if err != nil {
+			return err
+		}
+		var missing []uuid.UUID
+		for roomID := range expected {
+			if !listed[roomID] {
+				missing = append(missing, roomID)
+			}
+		}
+		if len(missing) == 0 {
+			return nil
+		}
+		if attempt > 0 {
+			w.report.violation("room list for %s is missing rooms it is a member of: %v", u.id, missing)
+			return nil
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+func (w *world) fetchHistory(ctx context.Context, rng *rand.Rand, i int) error {
+	u := w.users[i]
+	roomID, ok := u.pickRoom(rng.IntN, "")
+	if !ok {
+		return nil
+	}
+	_, err := u.do(ctx, "history", http.MethodGet, "/rooms/"+roomID.String()+"/messages?limit=20&offset="+strconv.Itoa(rng.IntN(3)*20), nil, nil)
+	return err
+}
+
+func (w *world) friendsOf(i int, rng *rand.Rand, limit int) []uuid.UUID {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var ids []uuid.UUID
+	for _, j := range rng.Perm(len(w.users)) {
+		if len(ids) == limit {
+			break
+		}
+		if w.friends[pair(i, j)] {
+			ids = append(ids, w.users[j].id)
+		}
+	}
+	return ids
+}
+
+func (w *world) createGroup(ctx context.Context, rng *rand.Rand, i int) error {
+	u := w.users[i]
+	roomID, ok := u.pickRoom(rng.IntN, "private")
+	if !ok {
+		return nil
+	}
+	payload := map[string]any{"name": fmt.Sprintf("soak group %d", rng.Uint32()), "userIds": w.friendsOf(i, rng, 3)}
+	status, err := u.do(ctx, "group.create", http.MethodPost, "/rooms/from-private/"+roomID.String(), payload, nil)
+	if err != nil || status != http.StatusCreated {
+		return err
+	}
+	_, err = u.refreshRooms(ctx)
+	return err
+}
+
+func (w *world) addMembers(ctx context.Context, rng *rand.Rand, i int) error {
+	u := w.users[i]
+	roomID, ok := u.pickRoom(rng.IntN, "group")
+	if !ok {
+		return nil
+	}
+	userIDs := w.friendsOf(i, rng, 2)
+	if len(userIDs) == 0 {
+		return nil
+	}
+	_, err := u.do(ctx, "group.add", http.MethodPost, "/rooms/"+roomID.String()+"/members/bulk", map[string]any{"userIds": userIDs}, nil)
+	return err
+}
+
+func (w *world) reconnect(ctx context.Context, rng *rand.Rand, i int) error {
+	w.report.op("ws.reconnect", 0)
+	return w.users[i].reconnect()
+}
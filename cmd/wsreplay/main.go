@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"chatservice/internal/middleware"
+	"chatservice/pkg/wprotocol"
+	"chatservice/pkg/wprotocol/record"
+
+	"github.com/gorilla/websocket"
+)
+
+func main() {
+	file := flag.String("file", "", "recording to replay (JSON lines)")
+	target := flag.String("url", "ws://localhost:8080/ws?v=2", "WebSocket URL of the test server")
+	token := flag.String("token", "", "session token sent as the auth cookie")
+	speed := flag.Float64("speed", 1, "replay speed multiplier; 0 sends frames back to back")
+	settle := flag.Duration("settle", 2*time.Second, "how long to wait for responses after the last frame")
+	rewrite := flag.String("rewrite", "", "comma separated old=new substitutions applied to payload values")
+	flag.Parse()
+
+	if *file == "" {
+		log.Fatal("-file is required")
+	}
+	in, err := os.Open(*file)
+	if err != nil {
+		log.Fatalf("Could not open recording: %v", err)
+	}
+	frames, err := record.ReadFrames(in)
+	in.Close()
+	if err != nil {
+		log.Fatalf("Could not read recording: %v", err)
+	}
+	substitutions := parseRewrites(*rewrite)
+
+	header := http.Header{}
+	if *token != "" {
+		header.Set("Cookie", (&http.Cookie{Name: middleware.AuthCookieName, Value: *token}).String())
+	}
+	conn, _, err := websocket.DefaultDialer.Dial(*target, header)
+	if err != nil {
+		log.Fatalf("Could not connect to %s: %v", *target, err)
+	}
+	defer conn.Close()
+
+	received := make(chan wprotocol.OpCode, 1024)
+	go func() {
+		defer close(received)
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			for _, frame := range bytes.Split(data, []byte{'\n'}) {
+				if op, ok := wprotocol.PeekOp(frame); ok {
+					log.Printf("<- %s", op)
+					received <- op
+				}
+			}
+		}
+	}()
+
+	var expected []wprotocol.OpCode
+	start := time.Now()
+	for _, frame := range frames {
+		if frame.Direction == record.DirectionOut {
+			expected = append(expected, wprotocol.OpCode(frame.Op))
+			continue
+		}
+		if *speed > 0 {
+			due := time.Duration(float64(frame.OffsetMs)/(*speed)) * time.Millisecond
+			time.Sleep(time.Until(start.Add(due)))
+		}
+		for i, value := range frame.Payload {
+			if replacement, ok := substitutions[value]; ok {
+				frame.Payload[i] = replacement
+			}
+		}
+		log.Printf("-> %s", wprotocol.OpCode(frame.Op))
+		if err := conn.WriteMessage(websocket.BinaryMessage, frame.Bytes()); err != nil {
+			log.Fatalf("Could not send frame: %v", err)
+		}
+	}
+
+	var got []wprotocol.OpCode
+	timeout := time.After(*settle)
+collect:
+	for {
+		select {
+		case op, ok := <-received:
+			if !ok {
+				break collect
+			}
+			got = append(got, op)
+		case <-timeout:
+			break collect
+		}
+	}
+
+	if diff := compare(expected, got); diff != "" {
+		fmt.Println(diff)
+		os.Exit(1)
+	}
+	fmt.Printf("Replayed %d frames, received %d matching server frames\n", len(frames)-len(expected), len(got))
+}
+
+func parseRewrites(spec string) map[string]string {
+	substitutions := make(map[string]string)
+	for _, pair := range strings.Split(spec, ",") {
+		from, to, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if ok && from != "" {
+			substitutions[from] = to
+		}
+	}
+	return substitutions
+}
+
+func compare(expected, got []wprotocol.OpCode) string {
+	for i := 0; i < len(expected) || i < len(got); i++ {
+		switch {
+		case i >= len(got):
+			return fmt.Sprintf("server frame %d missing: expected %s, got nothing (%d of %d received)", i, expected[i], len(got), len(expected))
+		case i >= len(expected):
+			return fmt.Sprintf("unexpected extra server frame %d: %s", i, got[i])
+		case expected[i] != got[i]:
+			return fmt.Sprintf("server frame %d differs: expected %s, got %s", i, expected[i], got[i])
+		}
+	}
+	return ""
+}
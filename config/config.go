@@ -3,6 +3,9 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -10,7 +13,110 @@ import (
 type Config struct {
 	DatabaseURL string
 	ServerPort  string
+	ListenAddr  string
+	SocketMode  os.FileMode
 	AuthServiceURL string 
+	MaxConnections          int
+	AlternativeInstanceURLs []string
+	ClusterEnabled          bool
+	InstanceID              string
+	InstanceURL             string
+	AdminUserIDs            []string
+	InviteOnly              bool
+	Experiments             string
+	AwayReplyCooldown       time.Duration
+	SnoozeSweepInterval     time.Duration
+	SupportFirstResponseSLA time.Duration
+	SupportResolutionSLA    time.Duration
+	SupportSLASweepInterval time.Duration
+	QuotaDailyMessages      int
+	QuotaDailyFriendReqs    int
+	QuotaDailyRooms         int
+	QuotaDailyUrgent        int
+	PushGatewayURL          string
+	PushBatchWindow         time.Duration
+	PushFanOutWorkers       int
+	PushFanOutQueue         int
+	PushFanOutBatch         int
+	PushRateLimit           int
+	PublicBaseURL           string
+	SMTPAddr                string
+	SMTPUsername            string
+	SMTPPassword            string
+	SMTPFrom                string
+	EmailLinkSecret         string
+	ChunkThreshold          int
+	MaxChunkedPayload       int
+	SFUURL                  string
+	SFUAPIKey               string
+	SFUAPISecret            string
+	SFUWebhookSecret        string
+	AuthWebhookSecret       string
+	SFUTokenTTL             time.Duration
+	SFUAPIURL               string
+	CallRingTimeout         time.Duration
+	DoNotTrack              bool
+	LogLevel                string
+	LogModules              map[string]string
+	LogRedact               bool
+	WSRecordDir             string
+	WSSnapshotPath          string
+	WSDeliverCoalesceWindow time.Duration
+	FirehoseSettleDelay     time.Duration
+	WSMaxProtocolErrors     int
+	WSProtocolErrorWindow   time.Duration
+	RegionDatabaseURLs      map[string]string
+	TenantRegions           map[string]string
+	SchemaCheck             bool
+	StatementCacheCapacity  int
+	UserCacheSize           int
+	UserCacheTTL            time.Duration
+	DBRetryAttempts         int
+	DBRetryBaseDelay        time.Duration
+	DBHealthInterval        time.Duration
+	AdmissionConcurrency    int
+	AdmissionWait           time.Duration
+	OverloadCheckInterval   time.Duration
+	OverloadMaxLoopLag      time.Duration
+	OverloadMaxAcquireWait  time.Duration
+	OverloadRetryAfter      time.Duration
+	WSPacketConcurrency     int
+	WSPacketQueue           int
+	ActionWebhookSecret     string
+	ExportSigningKey        string
+	MessagePageDefault      int
+	MessagePageMax          int
+	JanitorInterval         time.Duration
+	DraftTTL                time.Duration
+	UploadDir               string
+	AttachmentKeys          map[string]string
+	AttachmentActiveKey     string
+	AttachmentKMSURL        string
+	AttachmentKMSToken      string
+	MaxUploadSize           int
+	UploadTTL               time.Duration
+	PushMaskedWords         []string
+	SentPushTTL             time.Duration
+	IdempotencyTTL          time.Duration
+	SchedulerPollInterval   time.Duration
+	DeliveryRetryInterval   time.Duration
+	DeliveryMaxAttempts     int
+	TranslateHookURL        string
+	TranslateHookToken      string
+	SummaryHookURL          string
+	SummaryHookToken        string
+	SummaryTTL              time.Duration
+	SuggestionHookURL       string
+	SuggestionHookToken     string
+	ShadowModes             map[string]string
+	OpenSearchURL           string
+	OpenSearchIndex         string
+	OpenSearchUsername      string
+	OpenSearchPassword      string
+	SearchIndexWorkers      int
+	SearchIndexQueue        int
+	SearchBackfillInterval  time.Duration
+	InsightsRollupInterval  time.Duration
 }
 
 func Load() *Config {
@@ -28,6 +134,16 @@ func Load() *Config {
 		log.Fatal("DATABASE_URL environment variable is required")
 	}
 
+	regionURLs := make(map[string]string)
+	for _, region := range getEnvList("DB_REGIONS") {
+		key := "DATABASE_URL_" + strings.ToUpper(region)
+		url := os.Getenv(key)
+		if url == "" {
+			log.Fatalf("%s is required for database region %q", key, region)
+		}
+		regionURLs[region] = url
+	}
+
 	port := os.Getenv("SERVER_PORT")
 	if port == "" {
 		port = "8080"
@@ -36,6 +152,186 @@ func Load() *Config {
 	return &Config{
 		DatabaseURL: dbURL,
 		ServerPort:  ":" + port,
+		ListenAddr:  getEnv("LISTEN_ADDR", ":"+port),
+		SocketMode:  getEnvFileMode("LISTEN_SOCKET_MODE", 0o660),
 		AuthServiceURL: authURL,
+		MaxConnections:          getEnvInt("MAX_CONNECTIONS", 0),
+		AlternativeInstanceURLs: getEnvList("ALTERNATIVE_INSTANCE_URLS"),
+		ClusterEnabled:          getEnvBool("CLUSTER_ENABLED", false),
+		InstanceID:              os.Getenv("INSTANCE_ID"),
+		InstanceURL:             os.Getenv("INSTANCE_URL"),
+		AdminUserIDs:            getEnvList("ADMIN_USER_IDS"),
+		InviteOnly:              getEnvBool("INVITE_ONLY", false),
+		Experiments:             os.Getenv("EXPERIMENTS"),
+		AwayReplyCooldown:       getEnvDuration("AWAY_REPLY_COOLDOWN", 12*time.Hour),
+		SnoozeSweepInterval:     getEnvDuration("SNOOZE_SWEEP_INTERVAL", time.Minute),
+		SupportFirstResponseSLA: getEnvDuration("SUPPORT_FIRST_RESPONSE_SLA", 15*time.Minute),
+		SupportResolutionSLA:    getEnvDuration("SUPPORT_RESOLUTION_SLA", 24*time.Hour),
+		SupportSLASweepInterval: getEnvDuration("SUPPORT_SLA_SWEEP_INTERVAL", time.Minute),
+		QuotaDailyMessages:      getEnvInt("QUOTA_DAILY_MESSAGES", 0),
+		QuotaDailyFriendReqs:    getEnvInt("QUOTA_DAILY_FRIEND_REQUESTS", 0),
+		QuotaDailyRooms:         getEnvInt("QUOTA_DAILY_ROOMS", 0),
+		QuotaDailyUrgent:        getEnvInt("QUOTA_DAILY_URGENT_MESSAGES", 10),
+		PushGatewayURL:          os.Getenv("PUSH_GATEWAY_URL"),
+		PushBatchWindow:         getEnvDuration("PUSH_BATCH_WINDOW", 5*time.Second),
+		PushFanOutWorkers:       getEnvInt("PUSH_FANOUT_WORKERS", 8),
+		PushFanOutQueue:         getEnvInt("PUSH_FANOUT_QUEUE", 1024),
+		PushFanOutBatch:         getEnvInt("PUSH_FANOUT_BATCH", 500),
+		PushRateLimit:           getEnvInt("PUSH_RATE_LIMIT", 200),
+		PublicBaseURL:           getEnv("PUBLIC_BASE_URL", "http://localhost:"+port),
+		SMTPAddr:                os.Getenv("SMTP_ADDR"),
+		SMTPUsername:            os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:            os.Getenv("SMTP_PASSWORD"),
+		SMTPFrom:                getEnv("SMTP_FROM", "no-reply@chatservice.local"),
+		EmailLinkSecret:         os.Getenv("EMAIL_LINK_SECRET"),
+		ChunkThreshold:          getEnvInt("WS_CHUNK_THRESHOLD", 3*1024),
+		MaxChunkedPayload:       getEnvInt("WS_MAX_CHUNKED_PAYLOAD", 256*1024),
+		SFUURL:                  os.Getenv("SFU_URL"),
+		SFUAPIKey:               os.Getenv("SFU_API_KEY"),
+		SFUAPISecret:            os.Getenv("SFU_API_SECRET"),
+		SFUWebhookSecret:        os.Getenv("SFU_WEBHOOK_SECRET"),
+		AuthWebhookSecret:       os.Getenv("AUTH_WEBHOOK_SECRET"),
+		SFUTokenTTL:             getEnvDuration("SFU_TOKEN_TTL", time.Hour),
+		SFUAPIURL:               os.Getenv("SFU_API_URL"),
+		CallRingTimeout:         getEnvDuration("CALL_RING_TIMEOUT", 45*time.Second),
+		DoNotTrack:              getEnvBool("DO_NOT_TRACK", false),
+		LogLevel:                getEnv("LOG_LEVEL", "info"),
+		LogModules:              getEnvMap("LOG_MODULES"),
+		LogRedact:               getEnvBool("LOG_REDACT", true),
+		RegionDatabaseURLs:      regionURLs,
+		TenantRegions:           getEnvMap("TENANT_REGIONS"),
+		SchemaCheck:             getEnvBool("SCHEMA_CHECK", true),
+		WSRecordDir:             os.Getenv("WS_RECORD_DIR"),
+		WSSnapshotPath:          os.Getenv("WS_SNAPSHOT_PATH"),
+		WSDeliverCoalesceWindow: getEnvDuration("WS_DELIVER_COALESCE_WINDOW", 25*time.Millisecond),
+		FirehoseSettleDelay:     getEnvDuration("FIREHOSE_SETTLE_DELAY", 5*time.Second),
+		WSMaxProtocolErrors:     getEnvInt("WS_MAX_PROTOCOL_ERRORS", 10),
+		WSProtocolErrorWindow:   getEnvDuration("WS_PROTOCOL_ERROR_WINDOW", time.Minute),
+		StatementCacheCapacity:  getEnvInt("DB_STATEMENT_CACHE_CAPACITY", 512),
+		UserCacheSize:           getEnvInt("USER_CACHE_SIZE", 10000),
+		UserCacheTTL:            getEnvDuration("USER_CACHE_TTL", time.Minute),
+		DBRetryAttempts:         getEnvInt("DB_RETRY_ATTEMPTS", 3),
+		DBRetryBaseDelay:        getEnvDuration("DB_RETRY_BASE_DELAY", 25*time.Millisecond),
+		DBHealthInterval:        getEnvDuration("DB_HEALTH_INTERVAL", 5*time.Second),
+		AdmissionConcurrency:    getEnvInt("WS_ADMISSION_CONCURRENCY", 32),
+		AdmissionWait:           getEnvDuration("WS_ADMISSION_WAIT", 10*time.Second),
+		OverloadCheckInterval:   getEnvDuration("OVERLOAD_CHECK_INTERVAL", time.Second),
+		OverloadMaxLoopLag:      getEnvDuration("OVERLOAD_MAX_LOOP_LAG", 250*time.Millisecond),
+		OverloadMaxAcquireWait:  getEnvDuration("OVERLOAD_MAX_ACQUIRE_WAIT", 100*time.Millisecond),
+		OverloadRetryAfter:      getEnvDuration("OVERLOAD_RETRY_AFTER", 5*time.Second),
+		WSPacketConcurrency:     getEnvInt("WS_PACKET_CONCURRENCY", 16),
+		WSPacketQueue:           getEnvInt("WS_PACKET_QUEUE", 64),
+		ActionWebhookSecret:     os.Getenv("ACTION_WEBHOOK_SECRET"),
+		ExportSigningKey:        os.Getenv("EXPORT_SIGNING_KEY"),
+		MessagePageDefault:      getEnvInt("MESSAGE_PAGE_DEFAULT", 50),
+		MessagePageMax:          getEnvInt("MESSAGE_PAGE_MAX", 200),
+		JanitorInterval:         getEnvDuration("JANITOR_INTERVAL", time.Hour),
+		DraftTTL:                getEnvDuration("DRAFT_TTL", 30*24*time.Hour),
+		UploadDir:               os.Getenv("UPLOAD_DIR"),
+		AttachmentKeys:          getEnvMap("ATTACHMENT_ENCRYPTION_KEYS"),
+		AttachmentActiveKey:     os.Getenv("ATTACHMENT_ENCRYPTION_ACTIVE_KEY"),
+		AttachmentKMSURL:        os.Getenv("ATTACHMENT_KMS_URL"),
+		AttachmentKMSToken:      os.Getenv("ATTACHMENT_KMS_TOKEN"),
+		MaxUploadSize:           getEnvInt("MAX_UPLOAD_SIZE", 100*1024*1024),
+		UploadTTL:               getEnvDuration("UPLOAD_TTL", 24*time.Hour),
+		PushMaskedWords:         getEnvList("PUSH_MASKED_WORDS"),
+		SentPushTTL:             getEnvDuration("SENT_PUSH_TTL", 7*24*time.Hour),
+		IdempotencyTTL:          getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+		SchedulerPollInterval:   getEnvDuration("SCHEDULER_POLL_INTERVAL", 10*time.Second),
+		DeliveryRetryInterval:   getEnvDuration("DELIVERY_RETRY_INTERVAL", 30*time.Second),
+		DeliveryMaxAttempts:     getEnvInt("DELIVERY_MAX_ATTEMPTS", 8),
+		TranslateHookURL:        os.Getenv("TRANSLATE_HOOK_URL"),
+		TranslateHookToken:      os.Getenv("TRANSLATE_HOOK_TOKEN"),
+		SummaryHookURL:          os.Getenv("SUMMARY_HOOK_URL"),
+		SummaryHookToken:        os.Getenv("SUMMARY_HOOK_TOKEN"),
+		SummaryTTL:              getEnvDuration("ROOM_SUMMARY_TTL", 7*24*time.Hour),
+		SuggestionHookURL:       os.Getenv("SUGGESTION_HOOK_URL"),
+		SuggestionHookToken:     os.Getenv("SUGGESTION_HOOK_TOKEN"),
+		ShadowModes:             getEnvMap("SHADOW_MODES"),
+		OpenSearchURL:           os.Getenv("OPENSEARCH_URL"),
+		OpenSearchIndex:         getEnv("OPENSEARCH_INDEX", "chat-messages"),
+		OpenSearchUsername:      os.Getenv("OPENSEARCH_USERNAME"),
+		OpenSearchPassword:      os.Getenv("OPENSEARCH_PASSWORD"),
+		SearchIndexWorkers:      getEnvInt("SEARCH_INDEX_WORKERS", 2),
+		SearchIndexQueue:        getEnvInt("SEARCH_INDEX_QUEUE", 4096),
+		SearchBackfillInterval:  getEnvDuration("SEARCH_BACKFILL_INTERVAL", time.Minute),
+		InsightsRollupInterval:  getEnvDuration("INSIGHTS_ROLLUP_INTERVAL", 15*time.Minute),
+	}
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func getEnvInt(key string, fallback int) int {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		log.Fatalf("%s must be an integer, got %q", key, raw)
+	}
+	return value
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		log.Fatalf("%s must be a boolean, got %q", key, raw)
+	}
+	return value
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	value, err := time.ParseDuration(raw)
+	if err != nil {
+		log.Fatalf("%s must be a duration, got %q", key, raw)
+	}
+	return value
+}
+
+func getEnvFileMode(key string, fallback os.FileMode) os.FileMode {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	value, err := strconv.ParseUint(raw, 8, 32)
+	if err != nil || value > 0o777 {
+		log.Fatalf("%s must be an octal permission mode, got %q", key, raw)
 	}
-}
\ No newline at end of file
+	return os.FileMode(value)
+}
+
+func getEnvList(key string) []string {
+	var values []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	return values
+}
+
+func getEnvMap(key string) map[string]string {
+	values := make(map[string]string)
+	for _, item := range getEnvList(key) {
+		k, v, ok := strings.Cut(item, "=")
+		if !ok || strings.TrimSpace(k) == "" || strings.TrimSpace(v) == "" {
+			log.Fatalf("%s entries must look like key=value, got %q", key, item)
+		}
+		values[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return values
+}
@@ -41,6 +41,7 @@ CREATE TABLE messages (
     room_id UUID NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
     user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
     content TEXT NOT NULL,
+    kind VARCHAR(32) NOT NULL DEFAULT 'text' CHECK (kind IN ('text', 'missed_call')),
     reply_to_message_id BIGINT REFERENCES messages(id) ON DELETE SET NULL,
     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     updated_at TIMESTAMPTZ,
@@ -62,4 +63,627 @@ CREATE INDEX ON friendships(user_two_id, status);
 CREATE INDEX ON rooms(type);
 CREATE INDEX ON room_participants(user_id);
 CREATE INDEX ON messages(room_id, created_at DESC);
-CREATE INDEX ON message_read_status(user_id);
\ No newline at end of file
+CREATE INDEX ON message_read_status(user_id);
+
+-- Per-user preferences; missing rows mean defaults
+CREATE TABLE user_settings (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    email_notifications BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Chatservice instances participating in the cluster
+CREATE TABLE chat_instances (
+    id TEXT PRIMARY KEY,
+    url TEXT NOT NULL DEFAULT '',
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_heartbeat_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    connections INTEGER NOT NULL DEFAULT 0
+);
+
+-- Which instance currently holds a user's websocket connection
+CREATE TABLE user_connections (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    instance_id TEXT NOT NULL REFERENCES chat_instances(id) ON DELETE CASCADE,
+    connected_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, instance_id)
+);
+
+CREATE INDEX ON chat_instances(last_heartbeat_at);
+
+-- Files attached to a room, e.g. call recordings
+CREATE TABLE room_attachments (
+    id UUID PRIMARY KEY,
+    room_id UUID NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+    uploader_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    kind VARCHAR(32) NOT NULL CHECK (kind IN ('call_recording')),
+    storage_url TEXT NOT NULL,
+    content_type VARCHAR(255) NOT NULL DEFAULT '',
+    size_bytes BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Users allowed to access a restricted attachment
+CREATE TABLE attachment_access (
+    attachment_id UUID NOT NULL REFERENCES room_attachments(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (attachment_id, user_id)
+);
+
+CREATE INDEX ON room_attachments(room_id, created_at DESC);
+CREATE INDEX ON attachment_access(user_id);
+
+-- Legal holds exempt users or rooms from erasure and retention jobs
+CREATE TABLE legal_holds (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    subject_type VARCHAR(16) NOT NULL CHECK (subject_type IN ('user', 'room')),
+    subject_id UUID NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    placed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    released_at TIMESTAMPTZ
+);
+
+CREATE INDEX ON legal_holds(subject_type, subject_id) WHERE released_at IS NULL;
+
+-- Applied schema version, checked by the service on startup
+CREATE TABLE schema_migrations (
+    version INTEGER PRIMARY KEY,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO schema_migrations (version) VALUES (1);
+
+-- Version 2: last interaction per room, maintained on message insert
+ALTER TABLE rooms ADD COLUMN last_message_at TIMESTAMPTZ;
+
+UPDATE rooms r SET last_message_at = m.last_at
+FROM (SELECT room_id, MAX(created_at) AS last_at FROM messages GROUP BY room_id) m
+WHERE m.room_id = r.id;
+
+INSERT INTO schema_migrations (version) VALUES (2);
+
+-- Version 3: per-room ephemeral state shared between instances
+CREATE UNLOGGED TABLE room_ephemeral_state (
+    room_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    value TEXT NOT NULL DEFAULT '',
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (room_id, user_id, kind)
+);
+
+CREATE INDEX ON room_ephemeral_state(expires_at);
+
+INSERT INTO schema_migrations (version) VALUES (3);
+
+-- Version 4: free-form room metadata for integrations
+ALTER TABLE rooms ADD COLUMN metadata JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+INSERT INTO schema_migrations (version) VALUES (4);
+
+-- Version 5: optional integration metadata on messages
+ALTER TABLE messages ADD COLUMN metadata JSONB;
+
+INSERT INTO schema_migrations (version) VALUES (5);
+
+-- Version 6: per-room message drafts, expired by the janitor
+CREATE TABLE message_drafts (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    room_id UUID NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+    content TEXT NOT NULL DEFAULT '',
+    attachment_ids UUID[] NOT NULL DEFAULT '{}',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, room_id)
+);
+
+CREATE INDEX ON message_drafts(updated_at);
+
+INSERT INTO schema_migrations (version) VALUES (6);
+
+-- Version 7: resumable uploads finalized into attachment messages
+ALTER TABLE messages DROP CONSTRAINT messages_kind_check;
+ALTER TABLE messages ADD CONSTRAINT messages_kind_check CHECK (kind IN ('text', 'missed_call', 'attachment'));
+ALTER TABLE room_attachments DROP CONSTRAINT room_attachments_kind_check;
+ALTER TABLE room_attachments ADD CONSTRAINT room_attachments_kind_check CHECK (kind IN ('call_recording', 'upload'));
+ALTER TABLE messages ADD COLUMN attachment_id UUID REFERENCES room_attachments(id) ON DELETE SET NULL;
+
+CREATE TABLE uploads (
+    id UUID PRIMARY KEY,
+    room_id UUID NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+    uploader_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    filename TEXT NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size_bytes BIGINT NOT NULL CHECK (size_bytes > 0),
+    offset_bytes BIGINT NOT NULL DEFAULT 0,
+    checksum_sha256 VARCHAR(64) NOT NULL DEFAULT '',
+    storage_key TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ
+);
+
+CREATE INDEX ON uploads(expires_at) WHERE completed_at IS NULL;
+
+INSERT INTO schema_migrations (version) VALUES (7);
+
+-- Version 8: opt out of message previews in push notifications
+ALTER TABLE user_settings ADD COLUMN push_previews BOOLEAN NOT NULL DEFAULT TRUE;
+
+INSERT INTO schema_migrations (version) VALUES (8);
+
+-- Version 9: track delivered message pushes so they can be retracted or updated
+CREATE TABLE sent_pushes (
+    notification_id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    room_id UUID NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+    message_id BIGINT NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    preview TEXT NOT NULL DEFAULT '',
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX ON sent_pushes(message_id);
+CREATE INDEX ON sent_pushes(sent_at);
+
+INSERT INTO schema_migrations (version) VALUES (9);
+
+-- Version 10: unread mentions tracked separately from unread messages
+CREATE TABLE message_mentions (
+    message_id BIGINT NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (message_id, user_id)
+);
+
+CREATE INDEX ON message_mentions(user_id);
+
+INSERT INTO schema_migrations (version) VALUES (10);
+
+-- Version 11: scheduled job leases and run history
+CREATE TABLE scheduled_jobs (
+    name VARCHAR(100) PRIMARY KEY,
+    interval_seconds INTEGER NOT NULL CHECK (interval_seconds > 0),
+    next_run_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    locked_by VARCHAR(255),
+    locked_until TIMESTAMPTZ,
+    last_started_at TIMESTAMPTZ,
+    last_finished_at TIMESTAMPTZ,
+    last_status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    last_error TEXT NOT NULL DEFAULT '',
+    run_count BIGINT NOT NULL DEFAULT 0
+);
+
+INSERT INTO schema_migrations (version) VALUES (11);
+
+-- Version 12: failed push and webhook deliveries with retry backoff and dead-lettering
+CREATE TABLE failed_deliveries (
+    id UUID PRIMARY KEY,
+    kind VARCHAR(50) NOT NULL,
+    target TEXT NOT NULL DEFAULT '',
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'retrying' CHECK (status IN ('retrying', 'dead')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX ON failed_deliveries(status, next_attempt_at);
+
+INSERT INTO schema_migrations (version) VALUES (12);
+
+-- Version 13: preferred languages and stored message translations
+ALTER TABLE user_settings ADD COLUMN language VARCHAR(35) NOT NULL DEFAULT '';
+
+CREATE TABLE message_translations (
+    message_id BIGINT NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    language VARCHAR(35) NOT NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (message_id, language)
+);
+
+INSERT INTO schema_migrations (version) VALUES (13);
+
+-- Version 14: message content types with sanitized markdown and rich-text documents
+ALTER TABLE messages ADD COLUMN content_type VARCHAR(20) NOT NULL DEFAULT 'plain' CHECK (content_type IN ('plain', 'markdown', 'rich'));
+ALTER TABLE messages ADD COLUMN rich_content JSONB;
+
+INSERT INTO schema_migrations (version) VALUES (14);
+
+-- Version 15: links and hashtags extracted from message content
+ALTER TABLE messages ADD COLUMN links TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE messages ADD COLUMN hashtags TEXT[] NOT NULL DEFAULT '{}';
+
+INSERT INTO schema_migrations (version) VALUES (15);
+
+-- Version 16: hashtag index for browsing rooms by topic
+CREATE INDEX ON messages USING GIN (hashtags);
+
+INSERT INTO schema_migrations (version) VALUES (16);
+
+-- Version 17: daily per-user activity aggregates for insights
+CREATE TABLE user_activity_daily (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    room_id UUID NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    messages_sent INT NOT NULL DEFAULT 0,
+    responses INT NOT NULL DEFAULT 0,
+    response_seconds BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, day, room_id)
+);
+
+CREATE INDEX ON user_activity_daily(day);
+
+INSERT INTO schema_migrations (version) VALUES (17);
+
+-- Version 18: symbolic group mentions (@everyone, @admins)
+ALTER TABLE messages ADD COLUMN group_mentions TEXT[] NOT NULL DEFAULT '{}';
+
+INSERT INTO schema_migrations (version) VALUES (18);
+
+-- Version 19: full-text message search
+ALTER TABLE messages ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (to_tsvector('simple', content)) STORED;
+
+CREATE INDEX ON messages USING GIN (search_vector);
+
+INSERT INTO schema_migrations (version) VALUES (19);
+
+-- Version 20: invite-only access allowlist
+CREATE TABLE access_allowlist (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID UNIQUE,
+    email VARCHAR(255) UNIQUE,
+    note TEXT NOT NULL DEFAULT '',
+    added_by UUID,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (user_id IS NOT NULL OR email IS NOT NULL)
+);
+
+INSERT INTO schema_migrations (version) VALUES (20);
+
+-- Version 21: experiment exposure tracking
+CREATE TABLE experiment_exposures (
+    experiment VARCHAR(64) NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    variant VARCHAR(64) NOT NULL,
+    first_exposed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_exposed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    exposures INT NOT NULL DEFAULT 1,
+    PRIMARY KEY (experiment, user_id)
+);
+
+CREATE INDEX ON experiment_exposures(experiment, variant);
+
+INSERT INTO schema_migrations (version) VALUES (21);
+
+-- Version 22: away auto-replies
+CREATE TABLE user_away (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    message TEXT NOT NULL,
+    starts_at TIMESTAMPTZ,
+    ends_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE away_replies (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    sender_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, sender_id)
+);
+
+ALTER TABLE messages DROP CONSTRAINT messages_kind_check;
+ALTER TABLE messages ADD CONSTRAINT messages_kind_check CHECK (kind IN ('text', 'missed_call', 'attachment', 'auto_reply'));
+
+INSERT INTO schema_migrations (version) VALUES (22);
+
+-- Version 23: room snooze
+ALTER TABLE room_participants ADD COLUMN snoozed_until TIMESTAMPTZ;
+
+CREATE INDEX ON room_participants(snoozed_until) WHERE snoozed_until IS NOT NULL;
+
+INSERT INTO schema_migrations (version) VALUES (23);
+
+-- Version 24: read-only history share links
+CREATE TABLE room_share_links (
+    id UUID PRIMARY KEY,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    room_id UUID NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+    created_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    title VARCHAR(200),
+    first_message_id BIGINT NOT NULL,
+    last_message_id BIGINT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (first_message_id <= last_message_id)
+);
+
+CREATE INDEX ON room_share_links(room_id);
+
+CREATE TABLE share_link_access (
+    id BIGSERIAL PRIMARY KEY,
+    link_id UUID NOT NULL REFERENCES room_share_links(id) ON DELETE CASCADE,
+    ip_address TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    accessed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX ON share_link_access(link_id, accessed_at);
+
+INSERT INTO schema_migrations (version) VALUES (24);
+
+-- Version 25: room-scoped widget keys and guests
+ALTER TABLE room_participants DROP CONSTRAINT room_participants_role_check;
+ALTER TABLE room_participants ADD CONSTRAINT room_participants_role_check CHECK (role IN ('owner', 'admin', 'member', 'guest'));
+
+CREATE TABLE room_widget_keys (
+    id UUID PRIMARY KEY,
+    room_id UUID NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+    key_hash VARCHAR(64) NOT NULL UNIQUE,
+    key_prefix VARCHAR(16) NOT NULL,
+    label VARCHAR(100) NOT NULL DEFAULT '',
+    created_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX ON room_widget_keys(room_id);
+
+CREATE TABLE widget_guests (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    key_id UUID NOT NULL REFERENCES room_widget_keys(id) ON DELETE CASCADE,
+    room_id UUID NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    display_name VARCHAR(64) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX ON widget_guests(key_id);
+
+INSERT INTO schema_migrations (version) VALUES (25);
+
+-- Version 26: customer-support inbox
+ALTER TABLE rooms DROP CONSTRAINT rooms_type_check;
+ALTER TABLE rooms ADD CONSTRAINT rooms_type_check CHECK (type IN ('private', 'group', 'support'));
+
+CREATE TABLE support_agents (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    available BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE support_conversations (
+    room_id UUID PRIMARY KEY REFERENCES rooms(id) ON DELETE CASCADE,
+    customer_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    agent_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    subject VARCHAR(200) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    assigned_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX ON support_conversations(agent_id) WHERE agent_id IS NOT NULL;
+CREATE INDEX ON support_conversations(created_at) WHERE agent_id IS NULL;
+
+CREATE TABLE support_notes (
+    id BIGSERIAL PRIMARY KEY,
+    room_id UUID NOT NULL REFERENCES support_conversations(room_id) ON DELETE CASCADE,
+    author_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX ON support_notes(room_id, id);
+
+INSERT INTO schema_migrations (version) VALUES (26);
+
+-- Version 27: canned responses
+CREATE TABLE canned_responses (
+    id UUID PRIMARY KEY,
+    scope VARCHAR(16) NOT NULL CHECK (scope IN ('user', 'room', 'support')),
+    owner_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    room_id UUID REFERENCES rooms(id) ON DELETE CASCADE,
+    shortcut VARCHAR(32) NOT NULL,
+    title VARCHAR(100) NOT NULL DEFAULT '',
+    body TEXT NOT NULL,
+    created_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK ((scope = 'user') = (owner_id IS NOT NULL) AND (scope = 'room') = (room_id IS NOT NULL))
+);
+
+CREATE UNIQUE INDEX ON canned_responses(owner_id, shortcut) WHERE scope = 'user';
+CREATE UNIQUE INDEX ON canned_responses(room_id, shortcut) WHERE scope = 'room';
+CREATE UNIQUE INDEX ON canned_responses(shortcut) WHERE scope = 'support';
+
+INSERT INTO schema_migrations (version) VALUES (27);
+
+-- Version 28: support SLA tracking
+ALTER TABLE support_conversations ADD COLUMN first_response_at TIMESTAMPTZ;
+ALTER TABLE support_conversations ADD COLUMN resolved_at TIMESTAMPTZ;
+ALTER TABLE support_conversations ADD COLUMN first_response_breached_at TIMESTAMPTZ;
+ALTER TABLE support_conversations ADD COLUMN resolution_breached_at TIMESTAMPTZ;
+
+CREATE INDEX ON support_conversations(resolved_at);
+
+INSERT INTO schema_migrations (version) VALUES (28);
+
+-- Version 29: room resolution and closing workflow
+ALTER TABLE rooms ADD COLUMN state VARCHAR(16) NOT NULL DEFAULT 'open' CHECK (state IN ('open', 'resolved', 'closed'));
+ALTER TABLE rooms ADD COLUMN state_changed_at TIMESTAMPTZ;
+ALTER TABLE rooms ADD COLUMN state_changed_by UUID REFERENCES users(id) ON DELETE SET NULL;
+
+ALTER TABLE messages DROP CONSTRAINT messages_kind_check;
+ALTER TABLE messages ADD CONSTRAINT messages_kind_check CHECK (kind IN ('text', 'missed_call', 'attachment', 'auto_reply', 'system'));
+
+INSERT INTO schema_migrations (version) VALUES (29);
+
+-- Version 30: private per-user labels for messages and conversations
+CREATE TABLE labels (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(64) NOT NULL,
+    color VARCHAR(7) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, name)
+);
+
+CREATE TABLE message_labels (
+    label_id UUID NOT NULL REFERENCES labels(id) ON DELETE CASCADE,
+    message_id BIGINT NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (label_id, message_id)
+);
+
+CREATE INDEX ON message_labels(message_id);
+
+CREATE TABLE room_labels (
+    label_id UUID NOT NULL REFERENCES labels(id) ON DELETE CASCADE,
+    room_id UUID NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (label_id, room_id)
+);
+
+CREATE INDEX ON room_labels(room_id);
+
+INSERT INTO schema_migrations (version) VALUES (30);
+
+-- Version 31: admin-managed trust badges on users
+ALTER TABLE users ADD COLUMN badges TEXT[] NOT NULL DEFAULT '{}' CHECK (badges <@ ARRAY['verified', 'bot', 'staff']::TEXT[]);
+
+INSERT INTO schema_migrations (version) VALUES (31);
+
+-- Version 32: identity providers linked through the auth service
+CREATE TABLE user_identities (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(32) NOT NULL,
+    login VARCHAR(255) NOT NULL DEFAULT '',
+    linked_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, provider)
+);
+
+INSERT INTO schema_migrations (version) VALUES (32);
+
+-- Version 33: per-user daily quotas
+CREATE TABLE user_quota_usage (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(32) NOT NULL,
+    day DATE NOT NULL,
+    used INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, kind, day)
+);
+
+CREATE INDEX ON user_quota_usage(day);
+
+INSERT INTO schema_migrations (version) VALUES (33);
+
+-- Version 34: per-user bootstrap state version
+ALTER TABLE users ADD COLUMN state_version BIGINT NOT NULL DEFAULT 0;
+
+INSERT INTO schema_migrations (version) VALUES (34);
+
+-- Version 35: idempotency keys for retried REST requests
+CREATE TABLE idempotency_keys (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    key VARCHAR(255) NOT NULL,
+    fingerprint CHAR(64) NOT NULL,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    content_type VARCHAR(255) NOT NULL DEFAULT '',
+    body BYTEA,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, key)
+);
+
+CREATE INDEX ON idempotency_keys(created_at);
+
+INSERT INTO schema_migrations (version) VALUES (35);
+
+-- Version 36: audit trail of widget guests merged into registered accounts
+CREATE TABLE guest_merges (
+    id UUID PRIMARY KEY,
+    guest_id UUID NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    room_id UUID NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+    guest_display_name VARCHAR(64) NOT NULL,
+    messages_moved INTEGER NOT NULL DEFAULT 0,
+    already_member BOOLEAN NOT NULL DEFAULT FALSE,
+    merged_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX ON guest_merges(user_id);
+CREATE UNIQUE INDEX ON guest_merges(guest_id);
+
+INSERT INTO schema_migrations (version) VALUES (36);
+
+-- Version 37: cached room summaries for returning members
+CREATE TABLE room_summaries (
+    room_id UUID NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+    language VARCHAR(35) NOT NULL DEFAULT '',
+    first_message_id BIGINT NOT NULL,
+    last_message_id BIGINT NOT NULL,
+    message_count INTEGER NOT NULL,
+    summary TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (room_id, language, first_message_id, last_message_id)
+);
+
+CREATE INDEX ON room_summaries(created_at);
+
+INSERT INTO schema_migrations (version) VALUES (37);
+
+-- Version 38: opt-in smart reply suggestions
+ALTER TABLE user_settings ADD COLUMN smart_replies BOOLEAN NOT NULL DEFAULT FALSE;
+
+INSERT INTO schema_migrations (version) VALUES (38);
+
+-- Version 39: denormalized per-room message counters, shadow-written until cutover
+CREATE TABLE room_message_counters (
+    room_id UUID PRIMARY KEY REFERENCES rooms(id) ON DELETE CASCADE,
+    message_count BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO schema_migrations (version) VALUES (39);
+
+-- Version 40: search index backfill progress per tenant
+CREATE TABLE search_backfill_cursors (
+    tenant TEXT PRIMARY KEY,
+    last_message_id BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO schema_migrations (version) VALUES (40);
+
+-- Version 41: cluster envelopes too large for a NOTIFY payload
+CREATE TABLE backplane_envelopes (
+    id BIGSERIAL PRIMARY KEY,
+    payload TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX ON backplane_envelopes(created_at);
+
+INSERT INTO schema_migrations (version) VALUES (41);
+
+-- Version 42: newest auth service event applied per user, to drop stale and replayed events
+CREATE TABLE auth_event_cursors (
+    user_id UUID PRIMARY KEY,
+    applied_at TIMESTAMPTZ NOT NULL
+);
+
+INSERT INTO schema_migrations (version) VALUES (42);
+
+-- Version 43: message reactions, ranked for room highlights
+CREATE TABLE message_reactions (
+    message_id BIGINT NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    emoji TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (message_id, user_id, emoji)
+);
+
+INSERT INTO schema_migrations (version) VALUES (43);
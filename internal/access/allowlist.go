@@ -0,0 +1,109 @@
+package access
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/repository"
+	"chatservice/internal/tenant"
+
+	"github.com/google/uuid"
+)
+
+const cacheTTL = 30 * time.Second
+
+var (
+	ErrInvalidEntry  = errors.New("an allowlist entry needs a user ID or a valid email")
+	ErrEntryNotFound = errors.New("allowlist entry not found")
+)
+
+type cachedDecision struct {
+	allowed bool
+	expires time.Time
+}
+
+type Allowlist struct {
+	repo repository.AccessRepository
+
+	mu    sync.Mutex
+	cache map[string]cachedDecision
+}
+
+func NewAllowlist(repo repository.AccessRepository) *Allowlist {
+	return &Allowlist{repo: repo, cache: make(map[string]cachedDecision)}
+}
+
+func (a *Allowlist) IsAllowed(ctx context.Context, userID uuid.UUID, email string) (bool, error) {
+	key := tenant.FromContext(ctx) + "/" + userID.String()
+	now := time.Now()
+
+	a.mu.Lock()
+	decision, ok := a.cache[key]
+	a.mu.Unlock()
+	if ok && now.Before(decision.expires) {
+		return decision.allowed, nil
+	}
+
+	allowed, err := a.repo.IsAllowlisted(ctx, userID, normalizeEmail(email))
+	if err != nil {
+		return false, err
+	}
+	a.mu.Lock()
+	a.cache[key] = cachedDecision{allowed: allowed, expires: now.Add(cacheTTL)}
+	a.mu.Unlock()
+	return allowed, nil
+}
+
+func (a *Allowlist) Entries(ctx context.Context) ([]domain.AllowlistEntry, error) {
+	return a.repo.ListAllowlist(ctx)
+}
+
+func (a *Allowlist) Add(ctx context.Context, adminID uuid.UUID, userID *uuid.UUID, email, note string) (*domain.AllowlistEntry, error) {
+	entry := &domain.AllowlistEntry{UserID: userID, Note: note, AddedBy: &adminID}
+	if email != "" {
+		parsed, err := mail.ParseAddress(email)
+		if err != nil {
+			return nil, ErrInvalidEntry
+		}
+		normalized := normalizeEmail(parsed.Address)
+		entry.Email = &normalized
+	}
+	if entry.UserID == nil && entry.Email == nil {
+		return nil, ErrInvalidEntry
+	}
+	if err := a.repo.AddAllowlistEntry(ctx, entry); err != nil {
+		return nil, err
+	}
+	a.flush()
+	log.Printf("Admin %s added allowlist entry %s", adminID, entry.ID)
+	return entry, nil
+}
+
+func (a *Allowlist) Remove(ctx context.Context, adminID, id uuid.UUID) error {
+	removed, err := a.repo.RemoveAllowlistEntry(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrEntryNotFound
+	}
+	a.flush()
+	log.Printf("Admin %s removed allowlist entry %s", adminID, id)
+	return nil
+}
+
+func (a *Allowlist) flush() {
+	a.mu.Lock()
+	clear(a.cache)
+	a.mu.Unlock()
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
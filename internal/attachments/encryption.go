@@ -0,0 +1,441 @@
+package attachments
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const dataKeySuffix = ".dek"
+
+var (
+	ErrUnknownKey      = errors.New("unknown attachment encryption key")
+	ErrEncryptionUnset = errors.New("blob is encrypted but no attachment encryption keys are configured")
+)
+
+type KeyWrapper interface {
+	ActiveKeyID() string
+	Wrap(keyID string, dataKey []byte) ([]byte, error)
+	Unwrap(keyID string, wrapped []byte) ([]byte, error)
+}
+
+type Keyring struct {
+	active string
+	keys   map[string]cipher.AEAD
+}
+
+func NewKeyring(encoded map[string]string, active string) (*Keyring, error) {
+	keys := make(map[string]cipher.AEAD, len(encoded))
+	for id, value := range encoded {
+		raw, err := base64.StdEncoding.DecodeString(value)
+		if err != nil || len(raw) != 32 {
+			return nil, fmt.Errorf("attachment key %q must be 32 base64-encoded bytes", id)
+		}
+		block, err := aes.NewCipher(raw)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		keys[id] = aead
+	}
+	if active == "" && len(keys) == 1 {
+		for id := range keys {
+			active = id
+		}
+	}
+	if _, ok := keys[active]; !ok {
+		return nil, fmt.Errorf("%w: active key %q", ErrUnknownKey, active)
+	}
+	return &Keyring{active: active, keys: keys}, nil
+}
+
+func (k *Keyring) ActiveKeyID() string { return k.active }
+
+func (k *Keyring) Wrap(keyID string, dataKey []byte) ([]byte, error) {
+	aead, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, dataKey, []byte(keyID)), nil
+}
+
+func (k *Keyring) Unwrap(keyID string, wrapped []byte) ([]byte, error) {
+	aead, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, errors.New("wrapped data key is truncated")
+	}
+	nonce, sealed := wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():]
+	return aead.Open(nil, nonce, sealed, []byte(keyID))
+}
+
+type blobKey struct {
+	KeyID      string `json:"keyId"`
+	WrappedKey []byte `json:"wrappedKey"`
+}
+
+// Encrypted blobs are a sequence of independently sealed AES-GCM chunks, each
+// stored as nonce || ciphertext || tag. Every chunk gets a fresh random nonce,
+// so rewriting a chunk on a resumed upload never reuses one. The chunk index
+// and a final-chunk flag are authenticated, so chunks cannot be reordered and
+// a blob cut short at a chunk boundary is rejected. A blob always holds at
+// least one chunk; an empty blob is a single empty final chunk.
+const (
+	chunkSize       = 64 * 1024
+	chunkOverhead   = 12 + 16
+	sealedChunkSize = chunkSize + chunkOverhead
+)
+
+var ErrBlobCorrupt = errors.New("encrypted blob failed authentication")
+
+type blobCipher struct {
+	aead cipher.AEAD
+}
+
+func (s *DiskStorage) SetEncryption(keys KeyWrapper) { s.keys = keys }
+
+func (s *DiskStorage) Encrypted() bool { return s.keys != nil }
+
+func (s *DiskStorage) createDataKey(path string) (*blobCipher, error) {
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	keyID := s.keys.ActiveKeyID()
+	wrapped, err := s.keys.Wrap(keyID, dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("could not wrap data key: %w", err)
+	}
+	data, err := json.Marshal(blobKey{KeyID: keyID, WrappedKey: wrapped})
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path+dataKeySuffix, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("could not create data key: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("could not write data key: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	return newBlobCipher(dataKey)
+}
+
+func (s *DiskStorage) loadCipher(path string) (*blobCipher, error) {
+	data, err := os.ReadFile(path + dataKeySuffix)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read data key: %w", err)
+	}
+	if s.keys == nil {
+		return nil, ErrEncryptionUnset
+	}
+	var key blobKey
+	if err := json.Unmarshal(data, &key); err != nil {
+		return nil, fmt.Errorf("could not decode data key: %w", err)
+	}
+	dataKey, err := s.keys.Unwrap(key.KeyID, key.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("could not unwrap data key: %w", err)
+	}
+	return newBlobCipher(dataKey)
+}
+
+func newBlobCipher(dataKey []byte) (*blobCipher, error) {
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &blobCipher{aead: aead}, nil
+}
+
+func chunkAAD(index int64, final bool) []byte {
+	aad := binary.BigEndian.AppendUint64(nil, uint64(index))
+	if final {
+		return append(aad, 1)
+	}
+	return append(aad, 0)
+}
+
+func (c *blobCipher) seal(index int64, final bool, plain []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize(), sealedChunkSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return c.aead.Seal(nonce, nonce, plain, chunkAAD(index, final)), nil
+}
+
+func (c *blobCipher) open(index int64, final bool, sealed []byte) ([]byte, error) {
+	if len(sealed) < chunkOverhead {
+		return nil, ErrBlobCorrupt
+	}
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plain, err := c.aead.Open(nil, nonce, ciphertext, chunkAAD(index, final))
+	if err != nil {
+		return nil, ErrBlobCorrupt
+	}
+	return plain, nil
+}
+
+// readChunk opens chunk index of a blob holding chunks chunks; only the
+// last one may carry the final flag.
+func (c *blobCipher) readChunk(f *os.File, index, chunks int64) ([]byte, error) {
+	sealed := make([]byte, sealedChunkSize)
+	n, err := f.ReadAt(sealed, index*sealedChunkSize)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return c.open(index, index == chunks-1, sealed[:n])
+}
+
+// blobLayout returns the plaintext size and chunk count of a sealed blob. A
+// file that was never written to holds no chunks.
+func blobLayout(f *os.File) (size, chunks int64, err error) {
+	info, err := f.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+	full, rest := info.Size()/sealedChunkSize, info.Size()%sealedChunkSize
+	if rest > 0 && rest < chunkOverhead {
+		return 0, 0, ErrBlobCorrupt
+	}
+	size, chunks = full*chunkSize, full
+	if rest > 0 {
+		size += rest - chunkOverhead
+		chunks++
+	}
+	return size, chunks, nil
+}
+
+// appendEncrypted writes r at plaintext offset, replacing anything after it.
+// An offset inside a chunk re-seals that chunk with the bytes kept before the
+// offset. It returns the number of bytes read from r.
+func (c *blobCipher) appendEncrypted(path string, offset int64, r io.Reader) (int64, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return 0, fmt.Errorf("could not open blob: %w", err)
+	}
+	defer f.Close()
+
+	size, chunks, err := blobLayout(f)
+	if err != nil {
+		return 0, err
+	}
+	if offset > size {
+		return 0, fmt.Errorf("could not reset blob to offset %d: blob holds %d bytes", offset, size)
+	}
+	index, filled := offset/chunkSize, int(offset%chunkSize)
+	// The chunk ending at offset may have been sealed as the final one, so
+	// it is re-sealed along with whatever follows.
+	if filled == 0 && index > 0 {
+		index, filled = index-1, chunkSize
+	}
+	buf := make([]byte, chunkSize)
+	if filled > 0 {
+		kept, err := c.readChunk(f, index, chunks)
+		if err != nil {
+			return 0, err
+		}
+		copy(buf, kept[:filled])
+	}
+	if err := f.Truncate(index * sealedChunkSize); err != nil {
+		return 0, fmt.Errorf("could not reset blob to offset %d: %w", offset, err)
+	}
+	if _, err := f.Seek(index*sealedChunkSize, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	flush := func(final bool) error {
+		sealed, err := c.seal(index, final, buf[:filled])
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(sealed); err != nil {
+			return fmt.Errorf("could not write blob: %w", err)
+		}
+		index++
+		filled = 0
+		return nil
+	}
+	var written int64
+	for {
+		if filled == chunkSize {
+			// A full chunk is only sealed as non-final once more data follows.
+			var next [1]byte
+			if _, err := io.ReadFull(r, next[:]); errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return written, fmt.Errorf("could not write blob: %w", err)
+			}
+			if err := flush(false); err != nil {
+				return written, err
+			}
+			buf[0], filled = next[0], 1
+			written++
+			continue
+		}
+		n, err := io.ReadFull(r, buf[filled:])
+		filled += n
+		written += int64(n)
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return written, fmt.Errorf("could not write blob: %w", err)
+		}
+	}
+	if err := flush(true); err != nil {
+		return written, err
+	}
+	return written, f.Sync()
+}
+
+type decryptingBlob struct {
+	file   *os.File
+	cipher *blobCipher
+	size   int64
+	chunks int64
+	offset int64
+
+	chunk      []byte
+	chunkIndex int64
+}
+
+func newDecryptingBlob(f *os.File, c *blobCipher) (*decryptingBlob, error) {
+	size, chunks, err := blobLayout(f)
+	if err != nil {
+		return nil, err
+	}
+	if chunks == 0 {
+		return nil, ErrBlobCorrupt
+	}
+	// Opening the last chunk up front rejects a truncated blob before any
+	// plaintext is served from it.
+	last, err := c.readChunk(f, chunks-1, chunks)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptingBlob{file: f, cipher: c, size: size, chunks: chunks, chunk: last, chunkIndex: chunks - 1}, nil
+}
+
+func (b *decryptingBlob) Read(p []byte) (int, error) {
+	if b.offset >= b.size {
+		return 0, io.EOF
+	}
+	index := b.offset / chunkSize
+	if index != b.chunkIndex {
+		chunk, err := b.cipher.readChunk(b.file, index, b.chunks)
+		if err != nil {
+			return 0, err
+		}
+		b.chunk, b.chunkIndex = chunk, index
+	}
+	n := copy(p, b.chunk[b.offset-index*chunkSize:])
+	b.offset += int64(n)
+	return n, nil
+}
+
+func (b *decryptingBlob) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += b.offset
+	case io.SeekEnd:
+		offset += b.size
+	}
+	if offset < 0 {
+		return 0, errors.New("seek before start of blob")
+	}
+	b.offset = offset
+	return offset, nil
+}
+
+func (b *decryptingBlob) Close() error { return b.file.Close() }
+
+func (s *DiskStorage) RewrapKeys(ctx context.Context) error {
+	if s.keys == nil {
+		return nil
+	}
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("could not list upload directory: %w", err)
+	}
+	active := s.keys.ActiveKeyID()
+	var rewrapped int
+	var errs []error
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			break
+		}
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), dataKeySuffix) {
+			continue
+		}
+		changed, err := s.rewrap(filepath.Join(s.dir, entry.Name()), active)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", entry.Name(), err))
+		} else if changed {
+			rewrapped++
+		}
+	}
+	if rewrapped > 0 {
+		attachmentsLog.Infof("Rewrapped %d attachment data keys with key %s", rewrapped, active)
+	}
+	return errors.Join(errs...)
+}
+
+func (s *DiskStorage) rewrap(path, active string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	var key blobKey
+	if err := json.Unmarshal(data, &key); err != nil {
+		return false, err
+	}
+	if key.KeyID == active {
+		return false, nil
+	}
+	dataKey, err := s.keys.Unwrap(key.KeyID, key.WrappedKey)
+	if err != nil {
+		return false, err
+	}
+	if key.WrappedKey, err = s.keys.Wrap(active, dataKey); err != nil {
+		return false, err
+	}
+	key.KeyID = active
+	if data, err = json.Marshal(key); err != nil {
+		return false, err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o640); err != nil {
+		return false, err
+	}
+	return true, os.Rename(tmp, path)
+}
@@ -0,0 +1,170 @@
+package attachments
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func encryptedStorage(t *testing.T, keys KeyWrapper) *DiskStorage {
+	t.Helper()
+	s, err := NewDiskStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetEncryption(keys)
+	return s
+}
+
+func testKeyring(t *testing.T) *Keyring {
+	t.Helper()
+	raw := make([]byte, 32)
+	rand.Read(raw)
+	keys, err := NewKeyring(map[string]string{"k1": base64.StdEncoding.EncodeToString(raw)}, "k1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func readBlob(t *testing.T, s *DiskStorage, key string) []byte {
+	t.Helper()
+	f, err := s.Open(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestEncryptedAppendResumesMidChunk(t *testing.T) {
+	s := encryptedStorage(t, testKeyring(t))
+	if err := s.Create("blob"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readBlob(t, s, "blob"); len(got) != 0 {
+		t.Fatalf("new blob holds %d bytes, want none", len(got))
+	}
+	want := make([]byte, 3*chunkSize+1234)
+	rand.Read(want)
+
+	offsets := []int64{0, 1000, chunkSize + 17, 2 * chunkSize, int64(len(want))}
+	for i := 0; i+1 < len(offsets); i++ {
+		part := want[offsets[i]:offsets[i+1]]
+		// A retried PATCH rewrites the same offset before the real attempt.
+		if _, err := s.Append("blob", offsets[i], bytes.NewReader(bytes.Repeat([]byte{0xff}, len(part)))); err != nil {
+			t.Fatal(err)
+		}
+		written, err := s.Append("blob", offsets[i], bytes.NewReader(part))
+		if err != nil || written != int64(len(part)) {
+			t.Fatalf("Append at %d = %d, %v", offsets[i], written, err)
+		}
+	}
+	if got := readBlob(t, s, "blob"); !bytes.Equal(got, want) {
+		t.Fatalf("decrypted %d bytes that differ from the %d written", len(got), len(want))
+	}
+
+	f, err := s.Open("blob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if size, err := f.Seek(0, io.SeekEnd); err != nil || size != int64(len(want)) {
+		t.Errorf("Seek(0, SeekEnd) = %d, %v; want plaintext size %d", size, err, len(want))
+	}
+
+	if err := s.Truncate("blob", chunkSize+5); err != nil {
+		t.Fatal(err)
+	}
+	if got := readBlob(t, s, "blob"); !bytes.Equal(got, want[:chunkSize+5]) {
+		t.Errorf("truncated blob holds %d bytes, want %d", len(got), chunkSize+5)
+	}
+}
+
+func TestEncryptedBlobDetectsTampering(t *testing.T) {
+	s := encryptedStorage(t, testKeyring(t))
+	if err := s.Create("blob"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Append("blob", 0, bytes.NewReader(make([]byte, 2*chunkSize))); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(s.dir, "blob")
+	sealed, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flipped := bytes.Clone(sealed)
+	flipped[sealedChunkSize+100] ^= 1
+	swapped := append(bytes.Clone(sealed[sealedChunkSize:]), sealed[:sealedChunkSize]...)
+	truncated := bytes.Clone(sealed[:sealedChunkSize])
+	for name, data := range map[string][]byte{"flipped bit": flipped, "swapped chunks": swapped, "truncated at chunk": truncated, "emptied": nil} {
+		t.Run(name, func(t *testing.T) {
+			if err := os.WriteFile(path, data, 0o640); err != nil {
+				t.Fatal(err)
+			}
+			f, err := s.Open("blob")
+			if err == nil {
+				defer f.Close()
+				_, err = io.ReadAll(f)
+			}
+			if !errors.Is(err, ErrBlobCorrupt) {
+				t.Errorf("reading tampered blob returned %v, want ErrBlobCorrupt", err)
+			}
+		})
+	}
+}
+
+func TestKMSWrapperRoundTrip(t *testing.T) {
+	var seen []string
+	kms := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.URL.Path)
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		var resp kmsResponse
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/encrypt/"):
+			resp.Data.Ciphertext = "vault:v1:" + body["plaintext"]
+		case strings.HasPrefix(r.URL.Path, "/decrypt/"):
+			resp.Data.Plaintext = strings.TrimPrefix(body["ciphertext"], "vault:v1:")
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer kms.Close()
+
+	keys, err := NewKMSWrapper(kms.URL+"/", "token", "attachments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := encryptedStorage(t, keys)
+	if err := s.Create("blob"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Append("blob", 0, strings.NewReader("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if got := readBlob(t, s, "blob"); string(got) != "hello" {
+		t.Errorf("decrypted %q, want %q", got, "hello")
+	}
+	if len(seen) == 0 || seen[0] != "/encrypt/attachments" {
+		t.Errorf("KMS requests = %v", seen)
+	}
+}
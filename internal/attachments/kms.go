@@ -0,0 +1,98 @@
+package attachments
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// KMSWrapper wraps data keys with a key held by an external KMS speaking the
+// Vault transit API, so master keys never leave the KMS. Key IDs are transit
+// key names; rotating to a new name and running RewrapKeys migrates blobs.
+type KMSWrapper struct {
+	url    string
+	token  string
+	active string
+	client *http.Client
+}
+
+type kmsResponse struct {
+	Data struct {
+		Ciphertext string `json:"ciphertext"`
+		Plaintext  string `json:"plaintext"`
+	} `json:"data"`
+}
+
+func NewKMSWrapper(url, token, activeKey string) (*KMSWrapper, error) {
+	if activeKey == "" {
+		return nil, fmt.Errorf("%w: no active KMS key configured", ErrUnknownKey)
+	}
+	return &KMSWrapper{
+		url:    strings.TrimSuffix(url, "/"),
+		token:  token,
+		active: activeKey,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}, nil
+}
+
+func (k *KMSWrapper) ActiveKeyID() string { return k.active }
+
+func (k *KMSWrapper) Wrap(keyID string, dataKey []byte) ([]byte, error) {
+	resp, err := k.call("encrypt", keyID, map[string]string{"plaintext": base64.StdEncoding.EncodeToString(dataKey)})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Data.Ciphertext == "" {
+		return nil, fmt.Errorf("KMS returned no ciphertext for key %q", keyID)
+	}
+	return []byte(resp.Data.Ciphertext), nil
+}
+
+func (k *KMSWrapper) Unwrap(keyID string, wrapped []byte) ([]byte, error) {
+	resp, err := k.call("decrypt", keyID, map[string]string{"ciphertext": string(wrapped)})
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := base64.StdEncoding.DecodeString(resp.Data.Plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("malformed KMS plaintext for key %q: %w", keyID, err)
+	}
+	return dataKey, nil
+}
+
+func (k *KMSWrapper) call(operation, keyID string, payload map[string]string) (*kmsResponse, error) {
+	if keyID == "" || strings.ContainsAny(keyID, "/?#") {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, k.url+"/"+operation+"/"+keyID, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid KMS URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if k.token != "" {
+		req.Header.Set("X-Vault-Token", k.token)
+	}
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error contacting KMS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("KMS %s with key %q returned status %d", operation, keyID, resp.StatusCode)
+	}
+	var decoded kmsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("malformed KMS response: %w", err)
+	}
+	return &decoded, nil
+}
@@ -0,0 +1,169 @@
+package attachments
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"chatservice/internal/logging"
+)
+
+var attachmentsLog = logging.For("attachments")
+
+const diskScheme = "disk://"
+
+var ErrInvalidKey = errors.New("invalid storage key")
+
+type DiskStorage struct {
+	dir  string
+	keys KeyWrapper
+}
+
+func NewDiskStorage(dir string) (*DiskStorage, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("could not create upload directory: %w", err)
+	}
+	return &DiskStorage{dir: dir}, nil
+}
+
+func (s *DiskStorage) path(key string) (string, error) {
+	if key == "" || strings.ContainsAny(key, `/\`) || strings.Contains(key, "..") {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(s.dir, key), nil
+}
+
+func (s *DiskStorage) Create(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o640)
+	if err != nil {
+		return fmt.Errorf("could not create blob: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if s.keys != nil {
+		blobCipher, err := s.createDataKey(path)
+		if err == nil {
+			_, err = blobCipher.appendEncrypted(path, 0, strings.NewReader(""))
+		}
+		if err != nil {
+			os.Remove(path)
+			os.Remove(path + dataKeySuffix)
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *DiskStorage) Append(key string, offset int64, r io.Reader) (int64, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return 0, err
+	}
+	blobCipher, err := s.loadCipher(path)
+	if err != nil {
+		return 0, err
+	}
+	if blobCipher != nil {
+		return blobCipher.appendEncrypted(path, offset, r)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return 0, fmt.Errorf("could not open blob: %w", err)
+	}
+	defer f.Close()
+
+	if err := f.Truncate(offset); err != nil {
+		return 0, fmt.Errorf("could not reset blob to offset %d: %w", offset, err)
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	written, err := io.Copy(f, r)
+	if err != nil {
+		return written, fmt.Errorf("could not write blob: %w", err)
+	}
+	return written, f.Sync()
+}
+
+func (s *DiskStorage) Truncate(key string, size int64) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	blobCipher, err := s.loadCipher(path)
+	if err != nil {
+		return err
+	}
+	if blobCipher != nil {
+		_, err := blobCipher.appendEncrypted(path, size, strings.NewReader(""))
+		return err
+	}
+	return os.Truncate(path, size)
+}
+
+func (s *DiskStorage) Open(key string) (io.ReadSeekCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	blobCipher, err := s.loadCipher(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if blobCipher != nil {
+		blob, err := newDecryptingBlob(f, blobCipher)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return blob, nil
+	}
+	return f, nil
+}
+
+func (s *DiskStorage) Remove(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := os.Remove(path + dataKeySuffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *DiskStorage) Checksum(key string) (string, error) {
+	f, err := s.Open(key)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (s *DiskStorage) URL(key string) string { return diskScheme + key }
+
+func KeyFromURL(url string) (string, bool) {
+	return strings.CutPrefix(url, diskScheme)
+}
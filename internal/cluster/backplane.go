@@ -0,0 +1,131 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	backplaneChannel = "chat_backplane"
+	maxNotifyPayload = 7900
+	envelopeTTL      = time.Minute
+)
+
+const (
+	KindRoom        = "room"
+	KindUser        = "user"
+	KindSubscribe   = "subscribe"
+	KindUnsubscribe = "unsubscribe"
+	KindResume      = "resume"
+	KindState       = "state"
+	KindTyping      = "typing"
+)
+
+type Envelope struct {
+	Origin   string    `json:"o"`
+	Instance string    `json:"i,omitempty"`
+	Kind     string    `json:"k"`
+	Target   uuid.UUID `json:"t"`
+	Data     []byte    `json:"d"`
+	Ref      int64     `json:"r,omitempty"`
+}
+
+func (n *Node) Publish(ctx context.Context, env Envelope) error {
+	env.Origin = n.id
+	payload, err := n.notification(ctx, env)
+	if err != nil {
+		return err
+	}
+	_, err = n.db.Exec(ctx, `SELECT pg_notify($1, $2)`, backplaneChannel, payload)
+	return err
+}
+
+// notification encodes env for NOTIFY. Envelopes over the payload limit are
+// stored in backplane_envelopes and only a reference carrying the routing
+// fields is sent, so receivers can skip envelopes meant for another instance
+// without loading them.
+func (n *Node) notification(ctx context.Context, env Envelope) (string, error) {
+	payload, err := json.Marshal(env)
+	if err != nil {
+		return "", err
+	}
+	if len(payload) <= maxNotifyPayload {
+		return string(payload), nil
+	}
+	id, err := n.repo.StoreEnvelope(ctx, payload)
+	if err != nil {
+		return "", err
+	}
+	ref, err := json.Marshal(Envelope{Origin: env.Origin, Instance: env.Instance, Kind: env.Kind, Target: env.Target, Ref: id})
+	if err != nil {
+		return "", err
+	}
+	return string(ref), nil
+}
+
+func (n *Node) resolve(ctx context.Context, env Envelope) (Envelope, error) {
+	if env.Ref == 0 {
+		return env, nil
+	}
+	payload, err := n.repo.LoadEnvelope(ctx, env.Ref)
+	if err != nil {
+		return Envelope{}, err
+	}
+	var stored Envelope
+	if err := json.Unmarshal(payload, &stored); err != nil {
+		return Envelope{}, fmt.Errorf("malformed stored envelope %d: %w", env.Ref, err)
+	}
+	return stored, nil
+}
+
+func (n *Node) listen(ctx context.Context) {
+	for ctx.Err() == nil {
+		if err := n.listenOnce(ctx); err != nil && ctx.Err() == nil {
+			clusterLog.Warnf("Backplane listener error, reconnecting: %v", err)
+			time.Sleep(time.Second)
+		}
+	}
+}
+
+func (n *Node) listenOnce(ctx context.Context) error {
+	conn, err := n.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+backplaneChannel); err != nil {
+		return err
+	}
+	clusterLog.Infof("Instance %s listening on backplane", n.id)
+
+	for {
+		notification, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		var env Envelope
+		if err := json.Unmarshal([]byte(notification.Payload), &env); err != nil {
+			clusterLog.Warnf("Dropping malformed backplane envelope: %v", err)
+			continue
+		}
+		if env.Origin == n.id || (env.Instance != "" && env.Instance != n.id) {
+			continue
+		}
+		env, err = n.resolve(ctx, env)
+		if err != nil {
+			clusterLog.Warnf("Dropping backplane envelope: %v", err)
+			continue
+		}
+		n.mu.RLock()
+		handler := n.handler
+		n.mu.RUnlock()
+		if handler != nil {
+			handler(env)
+		}
+	}
+}
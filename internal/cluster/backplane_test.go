@@ -0,0 +1,74 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"chatservice/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type envelopeStore struct {
+	repository.ClusterRepository
+	envelopes map[int64][]byte
+}
+
+func (s *envelopeStore) StoreEnvelope(_ context.Context, payload []byte) (int64, error) {
+	id := int64(len(s.envelopes) + 1)
+	s.envelopes[id] = payload
+	return id, nil
+}
+
+func (s *envelopeStore) LoadEnvelope(_ context.Context, id int64) ([]byte, error) {
+	return s.envelopes[id], nil
+}
+
+func TestOversizedEnvelopeTravelsByReference(t *testing.T) {
+	store := &envelopeStore{envelopes: make(map[int64][]byte)}
+	n := &Node{id: "a", repo: store}
+	ctx := context.Background()
+	target := uuid.New()
+
+	tests := []struct {
+		name   string
+		data   []byte
+		stored bool
+	}{
+		{"small", []byte("hello"), false},
+		{"over the limit", bytes.Repeat([]byte("x"), 4*maxNotifyPayload), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clear(store.envelopes)
+			sent := Envelope{Origin: n.id, Instance: "b", Kind: KindRoom, Target: target, Data: tt.data}
+			payload, err := n.notification(ctx, sent)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(payload) > maxNotifyPayload {
+				t.Fatalf("notification is %d bytes, limit is %d", len(payload), maxNotifyPayload)
+			}
+			if got := len(store.envelopes) > 0; got != tt.stored {
+				t.Fatalf("stored = %v, want %v", got, tt.stored)
+			}
+
+			var env Envelope
+			if err := json.Unmarshal([]byte(payload), &env); err != nil {
+				t.Fatal(err)
+			}
+			if env.Instance != sent.Instance || env.Kind != sent.Kind || env.Target != sent.Target {
+				t.Errorf("routing fields lost: %+v", env)
+			}
+			received, err := n.resolve(ctx, env)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(received.Data, tt.data) || received.Ref != 0 {
+				t.Errorf("resolved %d bytes (ref %d), want %d", len(received.Data), received.Ref, len(tt.data))
+			}
+		})
+	}
+}
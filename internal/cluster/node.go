@@ -0,0 +1,139 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/logging"
+	"chatservice/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var clusterLog = logging.For("cluster")
+
+const (
+	heartbeatInterval = 10 * time.Second
+	instanceTTL       = 3 * heartbeatInterval
+	pruneAfter        = 10 * instanceTTL
+)
+
+type Node struct {
+	id        string
+	url       string
+	startedAt time.Time
+	repo      repository.ClusterRepository
+	db        *pgxpool.Pool
+
+	mu      sync.RWMutex
+	peers   []domain.Instance
+	handler func(Envelope)
+}
+
+func NewNode(db *pgxpool.Pool, repo repository.ClusterRepository, instanceID, url string) *Node {
+	if instanceID == "" {
+		host, _ := os.Hostname()
+		instanceID = fmt.Sprintf("%s-%s", host, uuid.NewString()[:8])
+	}
+	return &Node{
+		id:        instanceID,
+		url:       url,
+		startedAt: time.Now(),
+		repo:      repo,
+		db:        db,
+	}
+}
+
+func (n *Node) ID() string { return n.id }
+
+func (n *Node) OnEnvelope(fn func(Envelope)) {
+	n.mu.Lock()
+	n.handler = fn
+	n.mu.Unlock()
+}
+
+func (n *Node) Run(ctx context.Context, connections func() int) {
+	go n.listen(ctx)
+
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		n.heartbeat(ctx, connections())
+		select {
+		case <-ctx.Done():
+			if err := n.repo.RemoveInstance(context.Background(), n.id); err != nil {
+				clusterLog.Errorf("Error deregistering instance %s: %v", n.id, err)
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (n *Node) heartbeat(ctx context.Context, connections int) {
+	self := &domain.Instance{ID: n.id, URL: n.url, StartedAt: n.startedAt, Connections: connections}
+	if err := n.repo.Heartbeat(ctx, self); err != nil {
+		clusterLog.Errorf("Cluster heartbeat failed for %s: %v", n.id, err)
+		return
+	}
+	if err := n.repo.PruneInstances(ctx, time.Now().Add(-pruneAfter)); err != nil {
+		clusterLog.Errorf("Error pruning dead instances: %v", err)
+	}
+	if err := n.repo.PruneEnvelopes(ctx, time.Now().Add(-envelopeTTL)); err != nil {
+		clusterLog.Errorf("Error pruning backplane envelopes: %v", err)
+	}
+	instances, err := n.repo.GetLiveInstances(ctx, time.Now().Add(-instanceTTL))
+	if err != nil {
+		clusterLog.Errorf("Error refreshing cluster topology: %v", err)
+		return
+	}
+	n.mu.Lock()
+	n.peers = instances
+	n.mu.Unlock()
+}
+
+func (n *Node) Instances(ctx context.Context) ([]domain.Instance, error) {
+	return n.repo.GetLiveInstances(ctx, time.Now().Add(-instanceTTL))
+}
+
+func (n *Node) PeerURLs() []string {
+	n.mu.RLock()
+	peers := append([]domain.Instance(nil), n.peers...)
+	n.mu.RUnlock()
+
+	sort.Slice(peers, func(i, j int) bool { return peers[i].Connections < peers[j].Connections })
+	urls := []string{}
+	for _, peer := range peers {
+		if peer.ID != n.id && peer.URL != "" {
+			urls = append(urls, peer.URL)
+		}
+	}
+	return urls
+}
+
+func (n *Node) TrackConnect(ctx context.Context, userID uuid.UUID) {
+	if err := n.repo.TrackConnection(ctx, userID, n.id); err != nil {
+		clusterLog.Errorf("Error tracking connection of %s on %s: %v", userID, n.id, err)
+	}
+}
+
+func (n *Node) TrackDisconnect(ctx context.Context, userID uuid.UUID) {
+	if err := n.repo.UntrackConnection(ctx, userID, n.id); err != nil {
+		clusterLog.Errorf("Error untracking connection of %s on %s: %v", userID, n.id, err)
+	}
+}
+
+func (n *Node) LocateUser(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	return n.repo.GetInstancesForUser(ctx, userID, time.Now().Add(-instanceTTL))
+}
+
+func (n *Node) IsOnline(ctx context.Context, userID uuid.UUID) (bool, error) {
+	instances, err := n.LocateUser(ctx, userID)
+	return len(instances) > 0, err
+}
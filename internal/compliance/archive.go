@@ -0,0 +1,118 @@
+package compliance
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"chatservice/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+const (
+	HashAlgorithm = "sha256-chain"
+	genesisHash   = "0000000000000000000000000000000000000000000000000000000000000000"
+)
+
+type Record struct {
+	Seq        int        `json:"seq"`
+	MessageID  int64      `json:"messageId"`
+	MessageUID uuid.UUID  `json:"messageUid"`
+	RoomID     uuid.UUID  `json:"roomId"`
+	UserID     uuid.UUID  `json:"userId"`
+	Kind       string     `json:"kind"`
+	Content    string     `json:"content"`
+	Attachment *uuid.UUID `json:"attachmentId,omitempty"`
+	ReplyTo    *int64     `json:"replyTo,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  *time.Time `json:"updatedAt,omitempty"`
+	DeletedAt  *time.Time `json:"deletedAt,omitempty"`
+	PrevHash   string     `json:"prevHash"`
+	Hash       string     `json:"hash"`
+}
+
+type Archive struct {
+	Algorithm   string      `json:"algorithm"`
+	GeneratedAt time.Time   `json:"generatedAt"`
+	GeneratedBy uuid.UUID   `json:"generatedBy"`
+	UserIDs     []uuid.UUID `json:"userIds"`
+	RoomIDs     []uuid.UUID `json:"roomIds,omitempty"`
+	From        time.Time   `json:"from"`
+	To          time.Time   `json:"to"`
+	Records     []Record    `json:"records"`
+	HeadHash    string      `json:"headHash"`
+}
+
+func NewArchive(generatedBy uuid.UUID, userIDs, roomIDs []uuid.UUID, from, to time.Time, messages []domain.Message) (*Archive, error) {
+	archive := &Archive{
+		Algorithm:   HashAlgorithm,
+		GeneratedAt: time.Now().UTC(),
+		GeneratedBy: generatedBy,
+		UserIDs:     userIDs,
+		RoomIDs:     roomIDs,
+		From:        from,
+		To:          to,
+		Records:     make([]Record, 0, len(messages)),
+		HeadHash:    genesisHash,
+	}
+
+	for i, msg := range messages {
+		record := Record{
+			Seq:        i + 1,
+			MessageID:  msg.ID,
+			MessageUID: msg.MessageUID,
+			RoomID:     msg.RoomID,
+			UserID:     msg.UserID,
+			Kind:       msg.Kind,
+			Content:    msg.Content,
+			Attachment: msg.AttachmentID,
+			ReplyTo:    msg.ReplyToMessageID,
+			CreatedAt:  msg.CreatedAt,
+			UpdatedAt:  msg.UpdatedAt,
+			DeletedAt:  msg.DeletedAt,
+			PrevHash:   archive.HeadHash,
+		}
+		hash, err := record.digest()
+		if err != nil {
+			return nil, err
+		}
+		record.Hash = hash
+		archive.Records = append(archive.Records, record)
+		archive.HeadHash = hash
+	}
+	return archive, nil
+}
+
+func (a *Archive) Verify() error {
+	prev := genesisHash
+	for _, record := range a.Records {
+		if record.PrevHash != prev {
+			return fmt.Errorf("record %d breaks the chain", record.Seq)
+		}
+		hash, err := record.digest()
+		if err != nil {
+			return err
+		}
+		if hash != record.Hash {
+			return fmt.Errorf("record %d has been altered", record.Seq)
+		}
+		prev = record.Hash
+	}
+	if prev != a.HeadHash {
+		return fmt.Errorf("head hash does not match the last record")
+	}
+	return nil
+}
+
+func (r Record) digest() (string, error) {
+	r.Hash = ""
+	payload, err := json.Marshal(r)
+	if err != nil {
+		return "", fmt.Errorf("error encoding record %d: %w", r.Seq, err)
+	}
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:]), nil
+}
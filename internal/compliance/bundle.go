@@ -0,0 +1,161 @@
+package compliance
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"chatservice/internal/attachments"
+	"chatservice/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+const (
+	AttachmentsManifest = "manifest"
+	AttachmentsFiles    = "files"
+)
+
+type ManifestEntry struct {
+	AttachmentID uuid.UUID `json:"attachmentId"`
+	RoomID       uuid.UUID `json:"roomId"`
+	Kind         string    `json:"kind"`
+	ContentType  string    `json:"contentType"`
+	SizeBytes    int64     `json:"sizeBytes"`
+	CreatedAt    time.Time `json:"createdAt"`
+	Path         string    `json:"path,omitempty"`
+	URL          string    `json:"url,omitempty"`
+	SHA256       string    `json:"sha256,omitempty"`
+	Error        string    `json:"error,omitempty"`
+}
+
+type Manifest struct {
+	GeneratedAt     time.Time       `json:"generatedAt"`
+	ArchiveHeadHash string          `json:"archiveHeadHash"`
+	IncludesFiles   bool            `json:"includesFiles"`
+	Attachments     []ManifestEntry `json:"attachments"`
+}
+
+type Bundle struct {
+	Archive      *Archive
+	attachments  []domain.Attachment
+	includeFiles bool
+}
+
+func (s *Service) ExportBundle(ctx context.Context, adminID uuid.UUID, userIDs, roomIDs []uuid.UUID, from, to time.Time, mode string) (*Bundle, error) {
+	if mode != AttachmentsManifest && mode != AttachmentsFiles {
+		return nil, fmt.Errorf("attachments must be %q or %q", AttachmentsManifest, AttachmentsFiles)
+	}
+	archive, err := s.Export(ctx, adminID, userIDs, roomIDs, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var attachmentIDs []uuid.UUID
+	for _, record := range archive.Records {
+		if record.Attachment != nil {
+			attachmentIDs = append(attachmentIDs, *record.Attachment)
+		}
+	}
+	found, err := s.repo.GetAttachmentsForExport(ctx, attachmentIDs, roomIDs, from, to)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Admin %s exported %d attachments (%s)", adminID, len(found), mode)
+	return &Bundle{Archive: archive, attachments: found, includeFiles: mode == AttachmentsFiles}, nil
+}
+
+func (s *Service) WriteBundle(ctx context.Context, w io.Writer, bundle *Bundle) error {
+	zw := zip.NewWriter(w)
+
+	entry, err := zw.Create("messages.json")
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(entry).Encode(bundle.Archive); err != nil {
+		return fmt.Errorf("error writing message archive: %w", err)
+	}
+
+	manifest := Manifest{
+		GeneratedAt:     bundle.Archive.GeneratedAt,
+		ArchiveHeadHash: bundle.Archive.HeadHash,
+		IncludesFiles:   bundle.includeFiles,
+		Attachments:     make([]ManifestEntry, 0, len(bundle.attachments)),
+	}
+	for _, att := range bundle.attachments {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		manifest.Attachments = append(manifest.Attachments, s.writeAttachment(zw, att, bundle.includeFiles))
+	}
+
+	encoded, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if entry, err = zw.Create("manifest.json"); err != nil {
+		return err
+	}
+	if _, err := entry.Write(encoded); err != nil {
+		return err
+	}
+	if len(s.signingKey) > 0 {
+		mac := hmac.New(sha256.New, s.signingKey)
+		mac.Write(encoded)
+		if entry, err = zw.Create("manifest.sig"); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(entry, "hmac-sha256="+hex.EncodeToString(mac.Sum(nil))+"\n"); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func (s *Service) writeAttachment(zw *zip.Writer, att domain.Attachment, includeFile bool) ManifestEntry {
+	entry := ManifestEntry{
+		AttachmentID: att.ID,
+		RoomID:       att.RoomID,
+		Kind:         att.Kind,
+		ContentType:  att.ContentType,
+		SizeBytes:    att.SizeBytes,
+		CreatedAt:    att.CreatedAt,
+	}
+	key, local := attachments.KeyFromURL(att.StorageURL)
+	if !local || s.storage == nil {
+		entry.URL = att.StorageURL
+		return entry
+	}
+
+	blob, err := s.storage.Open(key)
+	if err != nil {
+		entry.Error = "blob unavailable"
+		return entry
+	}
+	defer blob.Close()
+
+	hash := sha256.New()
+	dst := io.Writer(hash)
+	if includeFile {
+		entry.Path = "attachments/" + att.ID.String()
+		file, err := zw.CreateHeader(&zip.FileHeader{Name: entry.Path, Method: zip.Store, Modified: att.CreatedAt})
+		if err != nil {
+			entry.Error = err.Error()
+			return entry
+		}
+		dst = io.MultiWriter(file, hash)
+	}
+	if entry.SizeBytes, err = io.Copy(dst, blob); err != nil {
+		entry.Error = "blob read failed"
+		return entry
+	}
+	entry.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return entry
+}
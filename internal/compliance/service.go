@@ -0,0 +1,86 @@
+package compliance
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"chatservice/internal/attachments"
+	"chatservice/internal/domain"
+	"chatservice/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+const maxExportRange = 366 * 24 * time.Hour
+
+type Service struct {
+	repo       repository.ComplianceRepository
+	storage    *attachments.DiskStorage
+	signingKey []byte
+}
+
+func NewService(repo repository.ComplianceRepository) *Service {
+	return &Service{repo: repo}
+}
+
+func (s *Service) PlaceHold(ctx context.Context, adminID uuid.UUID, subjectType string, subjectID uuid.UUID, reason string) (*domain.LegalHold, error) {
+	if subjectType != domain.LegalHoldSubjectUser && subjectType != domain.LegalHoldSubjectRoom {
+		return nil, fmt.Errorf("subject type must be %q or %q", domain.LegalHoldSubjectUser, domain.LegalHoldSubjectRoom)
+	}
+	hold := &domain.LegalHold{
+		SubjectType: subjectType,
+		SubjectID:   subjectID,
+		Reason:      reason,
+		PlacedBy:    &adminID,
+	}
+	if err := s.repo.CreateLegalHold(ctx, hold); err != nil {
+		return nil, err
+	}
+	log.Printf("Admin %s placed legal hold %s on %s %s", adminID, hold.ID, subjectType, subjectID)
+	return hold, nil
+}
+
+func (s *Service) ReleaseHold(ctx context.Context, adminID, holdID uuid.UUID) error {
+	if err := s.repo.ReleaseLegalHold(ctx, holdID); err != nil {
+		return err
+	}
+	log.Printf("Admin %s released legal hold %s", adminID, holdID)
+	return nil
+}
+
+func (s *Service) ActiveHolds(ctx context.Context) ([]domain.LegalHold, error) {
+	return s.repo.GetActiveLegalHolds(ctx)
+}
+
+func (s *Service) IsHeld(ctx context.Context, subjectType string, subjectID uuid.UUID) (bool, error) {
+	return s.repo.IsUnderLegalHold(ctx, subjectType, subjectID)
+}
+
+func (s *Service) SetStorage(storage *attachments.DiskStorage) { s.storage = storage }
+
+func (s *Service) SetSigningKey(key string) { s.signingKey = []byte(key) }
+
+func (s *Service) Export(ctx context.Context, adminID uuid.UUID, userIDs, roomIDs []uuid.UUID, from, to time.Time) (*Archive, error) {
+	if len(userIDs) == 0 && len(roomIDs) == 0 {
+		return nil, fmt.Errorf("at least one user or room ID is required")
+	}
+	if !to.After(from) {
+		return nil, fmt.Errorf("export range end must be after its start")
+	}
+	if to.Sub(from) > maxExportRange {
+		return nil, fmt.Errorf("export range cannot exceed %d days", int(maxExportRange.Hours()/24))
+	}
+
+	messages, err := s.repo.GetMessagesForExport(ctx, userIDs, roomIDs, from, to)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := NewArchive(adminID, userIDs, roomIDs, from, to, messages)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Admin %s exported %d messages for %d users and %d rooms (%s to %s)", adminID, len(archive.Records), len(userIDs), len(roomIDs), from.Format(time.RFC3339), to.Format(time.RFC3339))
+	return archive, nil
+}
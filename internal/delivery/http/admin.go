@@ -0,0 +1,252 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"chatservice/internal/cluster"
+	"chatservice/internal/compliance"
+	"chatservice/internal/firehose"
+	"chatservice/internal/middleware"
+	"chatservice/internal/outbox"
+	"chatservice/internal/repository"
+	"chatservice/internal/scheduler"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type AdminHandler struct {
+	cluster    *cluster.Node
+	compliance *compliance.Service
+	databases  *repository.ClusterResolver
+	jobs       *scheduler.Scheduler
+	deliveries *outbox.Service
+	firehose   *firehose.Service
+}
+
+func RegisterAdminRoutes(api *gin.RouterGroup, adminOnly gin.HandlerFunc, node *cluster.Node, complianceService *compliance.Service, databases *repository.ClusterResolver, jobs *scheduler.Scheduler, deliveries *outbox.Service, firehoseService *firehose.Service) {
+	h := &AdminHandler{cluster: node, compliance: complianceService, databases: databases, jobs: jobs, deliveries: deliveries, firehose: firehoseService}
+
+	admin := api.Group("/admin", adminOnly)
+	{
+		admin.GET("/cluster", h.getClusterTopology)
+		admin.GET("/db", h.getDatabaseStats)
+		admin.GET("/jobs", h.getJobs)
+		admin.GET("/deliveries", h.getFailedDeliveries)
+		admin.GET("/deliveries/:id", h.getFailedDelivery)
+		admin.POST("/deliveries/:id/requeue", h.requeueDelivery)
+		admin.POST("/deliveries/:id/dead-letter", h.deadLetterDelivery)
+		admin.GET("/legal-holds", h.getLegalHolds)
+		admin.POST("/legal-holds", h.placeLegalHold)
+		admin.DELETE("/legal-holds/:id", h.releaseLegalHold)
+		admin.POST("/compliance/export", h.exportCompliance)
+		admin.GET("/firehose/messages", h.getFirehoseMessages)
+	}
+}
+
+type PlaceLegalHoldPayload struct {
+	SubjectType string    `json:"subjectType" binding:"required"`
+	SubjectID   uuid.UUID `json:"subjectId" binding:"required"`
+	Reason      string    `json:"reason"`
+}
+
+type ComplianceExportPayload struct {
+	UserIDs     []uuid.UUID `json:"userIds"`
+	RoomIDs     []uuid.UUID `json:"roomIds"`
+	From        time.Time   `json:"from" binding:"required"`
+	To          time.Time   `json:"to" binding:"required"`
+	Attachments string      `json:"attachments"`
+}
+
+func (h *AdminHandler) getClusterTopology(c *gin.Context) {
+	if h.cluster == nil {
+		c.JSON(http.StatusOK, gin.H{"enabled": false, "instances": []any{}})
+		return
+	}
+
+	instances, err := h.cluster.Instances(c.Request.Context())
+	if err != nil {
+		httpLog.Errorf("Error fetching cluster topology: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch cluster topology"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"enabled": true, "self": h.cluster.ID(), "instances": instances})
+}
+
+func (h *AdminHandler) getJobs(c *gin.Context) {
+	jobs, err := h.jobs.Jobs(c.Request.Context())
+	if err != nil {
+		httpLog.Errorf("Error listing scheduled jobs: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch scheduled jobs"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"jobs": jobs})
+}
+
+func (h *AdminHandler) getFailedDeliveries(c *gin.Context) {
+	deliveries, err := h.deliveries.List(c.Request.Context(), c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+}
+
+func (h *AdminHandler) getFailedDelivery(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delivery ID"})
+		return
+	}
+	delivery, err := h.deliveries.Get(c.Request.Context(), id)
+	if err != nil {
+		h.deliveryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, delivery)
+}
+
+func (h *AdminHandler) requeueDelivery(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delivery ID"})
+		return
+	}
+	if err := h.deliveries.Requeue(c.Request.Context(), id); err != nil {
+		h.deliveryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "requeued"})
+}
+
+func (h *AdminHandler) deadLetterDelivery(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delivery ID"})
+		return
+	}
+	if err := h.deliveries.DeadLetter(c.Request.Context(), id); err != nil {
+		h.deliveryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "dead"})
+}
+
+func (h *AdminHandler) deliveryError(c *gin.Context, err error) {
+	if errors.Is(err, outbox.ErrDeliveryNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Delivery not found"})
+		return
+	}
+	httpLog.Errorf("Error managing failed delivery: %v", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update delivery"})
+}
+
+func (h *AdminHandler) getDatabaseStats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"statements": repository.StatementStats(),
+		"retries":    repository.TransientRetryStats(),
+		"userCache":  repository.UserLookupStats(),
+		"shadow":     repository.ShadowStats(),
+		"pools":      h.databases.Stats(),
+	})
+}
+
+func (h *AdminHandler) getLegalHolds(c *gin.Context) {
+	holds, err := h.compliance.ActiveHolds(c.Request.Context())
+	if err != nil {
+		httpLog.Errorf("Error fetching legal holds: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch legal holds"})
+		return
+	}
+	c.JSON(http.StatusOK, holds)
+}
+
+func (h *AdminHandler) placeLegalHold(c *gin.Context) {
+	adminID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	var payload PlaceLegalHoldPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	hold, err := h.compliance.PlaceHold(c.Request.Context(), adminID, payload.SubjectType, payload.SubjectID, payload.Reason)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, hold)
+}
+
+func (h *AdminHandler) releaseLegalHold(c *gin.Context) {
+	adminID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	holdID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid legal hold ID"})
+		return
+	}
+	if err := h.compliance.ReleaseHold(c.Request.Context(), adminID, holdID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "legal hold released"})
+}
+
+func (h *AdminHandler) exportCompliance(c *gin.Context) {
+	adminID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	var payload ComplianceExportPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if payload.Attachments != "" {
+		h.exportComplianceBundle(c, adminID, payload)
+		return
+	}
+	archive, err := h.compliance.Export(c.Request.Context(), adminID, payload.UserIDs, payload.RoomIDs, payload.From, payload.To)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", "attachment; filename=compliance-export-"+archive.GeneratedAt.Format("20060102T150405Z")+".json")
+	c.JSON(http.StatusOK, archive)
+}
+
+func (h *AdminHandler) exportComplianceBundle(c *gin.Context, adminID uuid.UUID, payload ComplianceExportPayload) {
+	bundle, err := h.compliance.ExportBundle(c.Request.Context(), adminID, payload.UserIDs, payload.RoomIDs, payload.From, payload.To, payload.Attachments)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", "attachment; filename=compliance-export-"+bundle.Archive.GeneratedAt.Format("20060102T150405Z")+".zip")
+	c.Status(http.StatusOK)
+	if err := h.compliance.WriteBundle(c.Request.Context(), c.Writer, bundle); err != nil {
+		httpLog.Errorf("Error streaming compliance export for admin %s: %v", adminID, err)
+	}
+}
+
+func (h *AdminHandler) getFirehoseMessages(c *gin.Context) {
+	after, err := strconv.ParseInt(c.DefaultQuery("after", "0"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid after cursor"})
+		return
+	}
+	until, err := strconv.ParseInt(c.DefaultQuery("until", "0"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid until cursor"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(firehose.DefaultPageSize)))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+	page, err := h.firehose.Messages(c.Request.Context(), after, until, limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
@@ -0,0 +1,85 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"chatservice/internal/access"
+	"chatservice/internal/middleware"
+	"chatservice/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type AllowlistPayload struct {
+	UserID *uuid.UUID `json:"userId"`
+	Email  string     `json:"email"`
+	Note   string     `json:"note"`
+}
+
+type AccessHandler struct {
+	allowlist *access.Allowlist
+}
+
+func RegisterAccessRoutes(api *gin.RouterGroup, adminOnly gin.HandlerFunc, allowlist *access.Allowlist) {
+	h := &AccessHandler{allowlist: allowlist}
+
+	admin := api.Group("/admin", adminOnly)
+	{
+		admin.GET("/allowlist", h.getAllowlist)
+		admin.POST("/allowlist", h.addAllowlistEntry)
+		admin.DELETE("/allowlist/:id", h.removeAllowlistEntry)
+	}
+}
+
+func (h *AccessHandler) getAllowlist(c *gin.Context) {
+	entries, err := h.allowlist.Entries(c.Request.Context())
+	if err != nil {
+		httpLog.Errorf("Error listing allowlist: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch allowlist"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"entries": entries})
+}
+
+func (h *AccessHandler) addAllowlistEntry(c *gin.Context) {
+	adminID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	var payload AllowlistPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	entry, err := h.allowlist.Add(c.Request.Context(), adminID, payload.UserID, payload.Email, payload.Note)
+	switch {
+	case errors.Is(err, access.ErrInvalidEntry):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, repository.ErrAllowlistDuplicate):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		httpLog.Errorf("Error adding allowlist entry: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not add allowlist entry"})
+	default:
+		c.JSON(http.StatusCreated, entry)
+	}
+}
+
+func (h *AccessHandler) removeAllowlistEntry(c *gin.Context) {
+	adminID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid allowlist entry ID"})
+		return
+	}
+	err = h.allowlist.Remove(c.Request.Context(), adminID, id)
+	if errors.Is(err, access.ErrEntryNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpLog.Errorf("Error removing allowlist entry %s: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not remove allowlist entry"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "removed"})
+}
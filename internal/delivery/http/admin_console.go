@@ -0,0 +1,203 @@
+package http
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	ws_delivery "chatservice/internal/delivery/websocket"
+	"chatservice/internal/logbuf"
+	"chatservice/internal/middleware"
+	"chatservice/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+//go:embed adminui/index.html
+var adminConsolePage []byte
+
+type LiveHub interface {
+	Stats(ctx context.Context) (ws_delivery.HubStats, error)
+	DisconnectUser(userID uuid.UUID)
+}
+
+type AdminConsoleHandler struct {
+	hub    LiveHub
+	uc     usecase.AppUsecaseInterface
+	recent *logbuf.Recent
+}
+
+func RegisterAdminConsole(api *gin.RouterGroup, adminOnly gin.HandlerFunc, hub LiveHub, uc usecase.AppUsecaseInterface, recent *logbuf.Recent) {
+	h := &AdminConsoleHandler{hub: hub, uc: uc, recent: recent}
+
+	admin := api.Group("/admin", adminOnly)
+	{
+		admin.GET("/ui", h.getConsole)
+		admin.GET("/hub", h.getHubStats)
+		admin.GET("/errors", h.getRecentErrors)
+		admin.POST("/users/:id/disconnect", h.disconnectUser)
+		admin.DELETE("/messages/:id", h.removeMessage)
+		admin.GET("/support/agents", h.getSupportAgents)
+		admin.GET("/support/sla", h.getSupportSLA)
+		admin.PUT("/support/agents/:id", h.putSupportAgent)
+		admin.DELETE("/support/agents/:id", h.deleteSupportAgent)
+		admin.PUT("/users/:id/badges", h.putUserBadges)
+	}
+}
+
+func (h *AdminConsoleHandler) getConsole(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Content-Security-Policy", "default-src 'self'; script-src 'unsafe-inline'; style-src 'unsafe-inline'")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", adminConsolePage)
+}
+
+func (h *AdminConsoleHandler) getHubStats(c *gin.Context) {
+	stats, err := h.hub.Stats(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Hub did not respond"})
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+func (h *AdminConsoleHandler) getRecentErrors(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"errors": h.recent.Entries()})
+}
+
+func (h *AdminConsoleHandler) disconnectUser(c *gin.Context) {
+	adminID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	h.hub.DisconnectUser(userID)
+	httpLog.Infof("Admin %s disconnected user %s", adminID, userID)
+	c.JSON(http.StatusOK, gin.H{"status": "disconnected"})
+}
+
+func (h *AdminConsoleHandler) removeMessage(c *gin.Context) {
+	adminID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	messageID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+	err = h.uc.ModerateDeleteMessage(c.Request.Context(), adminID, messageID)
+	if errors.Is(err, usecase.ErrMessageNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpLog.Errorf("Error removing message %d: %v", messageID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not remove message"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "removed"})
+}
+
+type SupportAgentPayload struct {
+	Available *bool `json:"available"`
+}
+
+func (h *AdminConsoleHandler) getSupportAgents(c *gin.Context) {
+	agents, err := h.uc.ListSupportAgents(c.Request.Context())
+	if err != nil {
+		httpLog.Errorf("Error listing support agents: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch support agents"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"agents": agents})
+}
+
+func (h *AdminConsoleHandler) getSupportSLA(c *gin.Context) {
+	days, _ := strconv.Atoi(c.Query("days"))
+	summary, err := h.uc.GetSupportSLASummary(c.Request.Context(), days)
+	if err != nil {
+		httpLog.Errorf("Error summarizing support SLAs: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch support SLAs"})
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
+
+func (h *AdminConsoleHandler) putSupportAgent(c *gin.Context) {
+	adminID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	var payload SupportAgentPayload
+	if err := c.ShouldBindJSON(&payload); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	available := payload.Available == nil || *payload.Available
+	if err := h.uc.AddSupportAgent(c.Request.Context(), userID, available); err != nil {
+		httpLog.Errorf("Error saving support agent %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not save support agent"})
+		return
+	}
+	httpLog.Infof("Admin %s made %s a support agent", adminID, userID)
+	c.JSON(http.StatusOK, gin.H{"status": "saved"})
+}
+
+func (h *AdminConsoleHandler) deleteSupportAgent(c *gin.Context) {
+	adminID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	err = h.uc.RemoveSupportAgent(c.Request.Context(), userID)
+	if errors.Is(err, usecase.ErrSupportAgentNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpLog.Errorf("Error removing support agent %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not remove support agent"})
+		return
+	}
+	httpLog.Infof("Admin %s removed support agent %s", adminID, userID)
+	c.Status(http.StatusNoContent)
+}
+
+type UserBadgesPayload struct {
+	Badges []string `json:"badges"`
+}
+
+func (h *AdminConsoleHandler) putUserBadges(c *gin.Context) {
+	adminID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	var payload UserBadgesPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	user, err := h.uc.SetUserBadges(c.Request.Context(), userID, payload.Badges)
+	if errors.Is(err, usecase.ErrInvalidBadge) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpLog.Errorf("Error setting badges for %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update badges"})
+		return
+	}
+	httpLog.Infof("Admin %s set badges of %s to %v", adminID, userID, user.Badges)
+	c.JSON(http.StatusOK, gin.H{"id": user.ID, "badges": user.Badges})
+}
@@ -0,0 +1,189 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"chatservice/internal/middleware"
+	"chatservice/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type CannedResponsePayload struct {
+	Scope    string     `json:"scope"`
+	RoomID   *uuid.UUID `json:"roomId"`
+	Shortcut string     `json:"shortcut" binding:"required"`
+	Title    string     `json:"title"`
+	Body     string     `json:"body" binding:"required"`
+}
+
+type RenderCannedPayload struct {
+	RoomID    *uuid.UUID        `json:"roomId"`
+	Variables map[string]string `json:"variables"`
+}
+
+type CannedReplyPayload struct {
+	CannedResponseID uuid.UUID         `json:"cannedResponseId" binding:"required"`
+	Variables        map[string]string `json:"variables"`
+}
+
+func (p CannedResponsePayload) input() usecase.CannedResponseInput {
+	return usecase.CannedResponseInput{Scope: p.Scope, RoomID: p.RoomID, Shortcut: p.Shortcut, Title: p.Title, Body: p.Body}
+}
+
+func (h *AppHandler) lookupCannedResponses(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	var roomID *uuid.UUID
+	if raw := c.Query("roomId"); raw != "" {
+		parsed, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+			return
+		}
+		roomID = &parsed
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	responses, err := h.uc.LookupCannedResponses(c.Request.Context(), userID, roomID, c.Query("q"), limit)
+	if errors.Is(err, usecase.ErrNotRoomMember) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpLog.Errorf("Error from LookupCannedResponses: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch canned responses"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"responses": responses})
+}
+
+func (h *AppHandler) createCannedResponse(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	var payload CannedResponsePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	resp, err := h.uc.CreateCannedResponse(c.Request.Context(), userID, payload.input())
+	if err != nil {
+		respondCannedError(c, "CreateCannedResponse", err)
+		return
+	}
+	c.JSON(http.StatusCreated, resp)
+}
+
+func (h *AppHandler) updateCannedResponse(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid canned response ID"})
+		return
+	}
+	var payload CannedResponsePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	resp, err := h.uc.UpdateCannedResponse(c.Request.Context(), userID, id, payload.input())
+	if err != nil {
+		respondCannedError(c, "UpdateCannedResponse", err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *AppHandler) deleteCannedResponse(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid canned response ID"})
+		return
+	}
+	if err := h.uc.DeleteCannedResponse(c.Request.Context(), userID, id); err != nil {
+		respondCannedError(c, "DeleteCannedResponse", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AppHandler) renderCannedResponse(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid canned response ID"})
+		return
+	}
+	var payload RenderCannedPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	content, err := h.uc.RenderCannedResponse(c.Request.Context(), userID, id, payload.RoomID, payload.Variables)
+	if err != nil {
+		respondCannedError(c, "RenderCannedResponse", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"content": content})
+}
+
+func (h *AppHandler) sendCannedSupportReply(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	roomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+		return
+	}
+	var payload CannedReplyPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	msg, err := h.uc.SendCannedSupportReply(c.Request.Context(), userID, roomID, payload.CannedResponseID, payload.Variables)
+	if errors.Is(err, usecase.ErrSupportConversationNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrNotSupportAgent) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrInvalidContent) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrRoomPostingRestricted) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrRoomClosed) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrQuotaExceeded) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		respondCannedError(c, "SendCannedSupportReply", err)
+		return
+	}
+	c.JSON(http.StatusCreated, msg)
+}
+
+func respondCannedError(c *gin.Context, op string, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrInvalidCannedResponse), errors.Is(err, usecase.ErrMissingCannedVariable):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrCannedResponseNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrNotRoomMember), errors.Is(err, usecase.ErrCannedResponseForbidden), errors.Is(err, usecase.ErrNotSupportAgent):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrCannedShortcutTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		httpLog.Errorf("Error from %s: %v", op, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not process canned response"})
+	}
+}
@@ -1,17 +1,28 @@
 package http
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
 	"log"
 	"net/http"
 	"strconv"
+	"time"
 
+	"chatservice/internal/experiments"
+	"chatservice/internal/integrations"
+	"chatservice/internal/logging"
 	"chatservice/internal/middleware"
+	"chatservice/internal/notify"
+	"chatservice/internal/sfu"
 	"chatservice/internal/usecase"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
+var httpLog = logging.For("http")
+
 type AppHandler struct {
 	uc usecase.AppUsecaseInterface
 }
@@ -32,7 +43,18 @@ func RegisterRoutes(api *gin.RouterGroup, uc usecase.AppUsecaseInterface) {
 	users := api.Group("/users")
 	{
 		users.POST("/me", h.updateUser)
+		users.POST("/me/email", h.requestEmailChange)
+		users.GET("/me/settings", h.getSettings)
+		users.PUT("/me/settings", h.updateSettings)
+		users.GET("/me/badge", h.getBadge)
+		users.GET("/me/insights", h.getInsights)
+		users.GET("/me/drafts", h.getDrafts)
+		users.POST("/me/guest-merge", h.mergeGuestIdentity)
+		users.GET("/me/away", h.getAway)
+		users.PUT("/me/away", h.setAway)
+		users.DELETE("/me/away", h.clearAway)
 		users.GET("/search", h.searchUsers)
+		users.GET("/:id/identities", h.getUserIdentities)
 	}
 
 	friends := api.Group("/friends")
@@ -40,18 +62,118 @@ func RegisterRoutes(api *gin.RouterGroup, uc usecase.AppUsecaseInterface) {
 		friends.GET("", h.getFriends)
 		friends.POST("/requests", h.sendFriendRequest)
 		friends.PUT("/requests/:requester_id/accept", h.acceptFriendRequest)
+		friends.PUT("/requests/:requester_id/decline", h.declineFriendRequest)
+		friends.POST("/requests/bulk", h.bulkRespondToFriendRequests)
 	}
 
 	rooms := api.Group("/rooms")
 	{
 		rooms.GET("", h.getRooms)
+		rooms.POST("/read", h.markRoomsRead)
+		rooms.POST("/from-private/:room_id", h.createGroupFromPrivate)
+		rooms.HEAD("", h.headRooms)
+		rooms.POST("/:id/snooze", h.snoozeRoom)
+		rooms.POST("/:id/clone", h.cloneRoom)
+		rooms.DELETE("/:id/snooze", h.unsnoozeRoom)
+		rooms.PUT("/:id/state", h.setRoomState)
+		rooms.GET("/:id/share-links", h.listShareLinks)
+		rooms.POST("/:id/share-links", h.createShareLink)
+		rooms.DELETE("/:id/share-links/:linkId", h.revokeShareLink)
+		rooms.GET("/:id/widget-keys", h.listWidgetKeys)
+		rooms.POST("/:id/widget-keys", h.createWidgetKey)
+		rooms.DELETE("/:id/widget-keys/:keyId", h.revokeWidgetKey)
 		rooms.GET("/:id/messages", h.getMessages)
+		rooms.GET("/:id/tags", h.getRoomTags)
+		rooms.GET("/:id/stats", h.getRoomStats)
+		rooms.GET("/:id/summary", h.getRoomSummary)
+		rooms.GET("/:id/highlights", h.getRoomHighlights)
+		rooms.PUT("/:id/messages/:messageId/reactions/:emoji", h.addReaction)
+		rooms.DELETE("/:id/messages/:messageId/reactions/:emoji", h.removeReaction)
+		rooms.POST("/:id/call/token", h.createCallToken)
+		rooms.GET("/:id/recordings", h.getRecordings)
+		rooms.GET("/:id/recordings/:recordingId", h.getRecording)
+		rooms.POST("/:id/members/bulk", h.addRoomMembers)
+		rooms.GET("/:id/metadata", h.getRoomMetadata)
+		rooms.PUT("/:id/metadata", h.updateRoomMetadata)
+		rooms.GET("/:id/draft", h.getDraft)
+		rooms.PUT("/:id/draft", h.saveDraft)
+		rooms.DELETE("/:id/draft", h.deleteDraft)
+		rooms.POST("/:id/uploads", h.createUpload)
+	}
+
+	support := api.Group("/support")
+	{
+		support.POST("/conversations", h.openSupportConversation)
+		support.GET("/conversations", h.listAgentConversations)
+		support.GET("/queue", h.listSupportQueue)
+		support.PUT("/availability", h.setSupportAvailability)
+		support.POST("/conversations/:id/claim", h.claimSupportConversation)
+		support.POST("/conversations/:id/release", h.releaseSupportConversation)
+		support.POST("/conversations/:id/transfer", h.transferSupportConversation)
+		support.POST("/conversations/:id/resolve", h.resolveSupportConversation)
+		support.GET("/conversations/:id/notes", h.listSupportNotes)
+		support.POST("/conversations/:id/notes", h.addSupportNote)
+		support.POST("/conversations/:id/reply", h.sendCannedSupportReply)
+	}
+
+	canned := api.Group("/canned-responses")
+	{
+		canned.GET("", h.lookupCannedResponses)
+		canned.POST("", h.createCannedResponse)
+		canned.PUT("/:id", h.updateCannedResponse)
+		canned.DELETE("/:id", h.deleteCannedResponse)
+		canned.POST("/:id/render", h.renderCannedResponse)
+	}
+
+	labels := api.Group("/labels")
+	{
+		labels.GET("", h.listLabels)
+		labels.POST("", h.createLabel)
+		labels.PUT("/:id", h.updateLabel)
+		labels.DELETE("/:id", h.deleteLabel)
+		labels.GET("/:id/messages", h.getLabeledMessages)
+		labels.PUT("/:id/messages/:messageId", h.labelMessage)
+		labels.DELETE("/:id/messages/:messageId", h.unlabelMessage)
+		labels.GET("/:id/rooms", h.getLabeledRooms)
+		labels.PUT("/:id/rooms/:roomId", h.labelRoom)
+		labels.DELETE("/:id/rooms/:roomId", h.unlabelRoom)
+	}
+
+	api.GET("/bootstrap", h.getBootstrap)
+	api.POST("/experiments/:name/exposures", h.recordExposure)
+	api.GET("/messages/search", h.searchMessages)
+	api.GET("/quick-search", h.quickSearch)
+	api.GET("/attachments/:attachmentId/content", h.getAttachmentContent)
+
+	uploads := api.Group("/uploads")
+	{
+		uploads.HEAD("/:uploadId", h.headUpload)
+		uploads.PATCH("/:uploadId", h.patchUpload)
+		uploads.DELETE("/:uploadId", h.deleteUpload)
+	}
+}
+
+func RegisterPublicRoutes(api *gin.RouterGroup, uc usecase.AppUsecaseInterface) {
+	h := NewAppHandler(uc)
+
+	api.GET("/unsubscribe", h.unsubscribe)
+	api.GET("/email/verify", h.confirmEmailChange)
+	api.POST("/integrations/sfu/webhook", h.sfuWebhook)
+	api.POST("/integrations/auth/events", h.authEvents)
+	api.GET("/shared/:token", h.viewSharedHistory)
+
+	widget := api.Group("/widget")
+	{
+		widget.POST("/sessions", h.startGuestSession)
+		widget.GET("/messages", h.getGuestMessages)
+		widget.POST("/messages", h.sendGuestMessage)
 	}
 }
 
 type UpdateUserPayload struct {
 	Email    *string `json:"email,omitempty"`
 	Username *string `json:"username,omitempty"`
+	Nickname *string `json:"nickname,omitempty"`
 }
 
 func (h *AppHandler) searchUsers(c *gin.Context) {
@@ -65,7 +187,7 @@ func (h *AppHandler) searchUsers(c *gin.Context) {
 
 	users, err := h.uc.SearchUsers(c.Request.Context(), query, selfID)
 	if err != nil {
-		log.Printf("Error from SearchUsers usecase: %v", err)
+		httpLog.Errorf("Error from SearchUsers usecase: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search for users"})
 		return
 	}
@@ -81,13 +203,124 @@ func (h *AppHandler) updateUser(c *gin.Context) {
 		return
 	}
 
-	if err := h.uc.UpdateUser(c.Request.Context(), userID, payload.Email, payload.Username); err != nil {
+	if payload.Email != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "email changes must be confirmed through POST /users/me/email"})
+		return
+	}
+	if err := h.uc.UpdateUser(c.Request.Context(), userID, payload.Username, payload.Nickname); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update user"})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"status": "user updated"})
 }
 
+type UpdateSettingsPayload struct {
+	EmailNotifications *bool `json:"emailNotifications,omitempty"`
+	PushPreviews       *bool   `json:"pushPreviews,omitempty"`
+	SmartReplies       *bool   `json:"smartReplies,omitempty"`
+	Language           *string `json:"language,omitempty"`
+}
+
+func (h *AppHandler) getSettings(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	settings, err := h.uc.GetUserSettings(c.Request.Context(), userID)
+	if err != nil {
+		httpLog.Errorf("Error from GetUserSettings: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch settings"})
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+func (h *AppHandler) updateSettings(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	var payload UpdateSettingsPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	settings, err := h.uc.UpdateUserSettings(c.Request.Context(), userID, payload.EmailNotifications, payload.PushPreviews, payload.SmartReplies, payload.Language)
+	if errors.Is(err, usecase.ErrInvalidLanguage) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpLog.Errorf("Error from UpdateUserSettings: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update settings"})
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+func (h *AppHandler) getBadge(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	counts, err := h.uc.GetBadgeCounts(c.Request.Context(), userID)
+	if err != nil {
+		httpLog.Errorf("Error from GetBadgeCounts: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch badge counts"})
+		return
+	}
+	c.JSON(http.StatusOK, counts)
+}
+
+func (h *AppHandler) getInsights(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	days, _ := strconv.Atoi(c.Query("days"))
+	insights, err := h.uc.GetUserInsights(c.Request.Context(), userID, days)
+	if err != nil {
+		httpLog.Errorf("Error from GetUserInsights: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch insights"})
+		return
+	}
+	c.JSON(http.StatusOK, insights)
+}
+
+type EmailChangePayload struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+func (h *AppHandler) requestEmailChange(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	var payload EmailChangePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	err := h.uc.RequestEmailChange(c.Request.Context(), userID, payload.Email)
+	switch {
+	case errors.Is(err, usecase.ErrEmailTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, notify.ErrEmailLinksDisabled), errors.Is(err, notify.ErrMailerDisabled):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Email changes are not available"})
+	case err != nil:
+		httpLog.Errorf("Error from RequestEmailChange: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not send confirmation email"})
+	default:
+		c.JSON(http.StatusAccepted, gin.H{"status": "confirmation email sent"})
+	}
+}
+
+func (h *AppHandler) confirmEmailChange(c *gin.Context) {
+	err := h.uc.ConfirmEmailChange(c.Request.Context(), c.Query("token"))
+	switch {
+	case errors.Is(err, usecase.ErrInvalidEmailToken):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		httpLog.Errorf("Error from ConfirmEmailChange: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update email"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"status": "email updated"})
+	}
+}
+
+func (h *AppHandler) unsubscribe(c *gin.Context) {
+	if err := h.uc.UnsubscribeEmail(c.Request.Context(), c.Query("token")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "unsubscribed from email notifications"})
+}
+
 type SendFriendRequestPayload struct {
 	Email string `json:"email" binding:"required,email"`
 }
@@ -95,9 +328,19 @@ type SendFriendRequestPayload struct {
 func (h *AppHandler) getFriends(c *gin.Context) {
 	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
 
-	friendsList, err := h.uc.GetFriendsAndRequests(c.Request.Context(), userID)
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	onlineFirst, _ := strconv.ParseBool(c.DefaultQuery("online_first", "false"))
+	opts := usecase.FriendListOptions{
+		Sort:        c.DefaultQuery("sort", "nickname"),
+		OnlineFirst: onlineFirst,
+		Limit:       limit,
+		Offset:      offset,
+	}
+
+	friendsList, err := h.uc.GetFriendsAndRequests(c.Request.Context(), userID, opts)
 	if err != nil {
-		log.Printf("Error from GetFriendsAndRequests: %v", err)
+		httpLog.Errorf("Error from GetFriendsAndRequests: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch friends list"})
 		return
 	}
@@ -112,7 +355,10 @@ func (h *AppHandler) sendFriendRequest(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	if err := h.uc.SendFriendRequest(c.Request.Context(), senderID, payload.Email); err != nil {
+	if err := h.uc.SendFriendRequest(c.Request.Context(), senderID, payload.Email); errors.Is(err, usecase.ErrQuotaExceeded) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
@@ -134,17 +380,205 @@ func (h *AppHandler) acceptFriendRequest(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "friend request accepted"})
 }
 
+func (h *AppHandler) declineFriendRequest(c *gin.Context) {
+	declinerID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	requesterID, err := uuid.Parse(c.Param("requester_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid requester ID"})
+		return
+	}
+	if err := h.uc.DeclineFriendRequest(c.Request.Context(), declinerID, requesterID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "friend request declined"})
+}
+
+type BulkFriendRequestsPayload struct {
+	Action       string      `json:"action" binding:"required,oneof=accept decline"`
+	RequesterIDs []uuid.UUID `json:"requesterIds" binding:"required,min=1"`
+}
+
+func (h *AppHandler) bulkRespondToFriendRequests(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	var payload BulkFriendRequestsPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	results, err := h.uc.BulkRespondToFriendRequests(c.Request.Context(), userID, payload.Action, payload.RequesterIDs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+type MarkRoomsReadPayload struct {
+	Rooms []usecase.RoomReadMarker `json:"rooms" binding:"required,min=1,dive"`
+}
+
+func (h *AppHandler) markRoomsRead(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	var payload MarkRoomsReadPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	results, err := h.uc.MarkRoomsRead(c.Request.Context(), userID, payload.Rooms)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+func (h *AppHandler) roomsChangeToken(c *gin.Context, userID uuid.UUID) (string, bool) {
+	token, err := h.uc.GetRoomsChangeToken(c.Request.Context(), userID)
+	if err != nil {
+		httpLog.Errorf("Error from GetRoomsChangeToken: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch rooms"})
+		return "", false
+	}
+	c.Header("ETag", `"`+token+`"`)
+	c.Header("X-Rooms-Change-Token", token)
+	return token, true
+}
+
+func (h *AppHandler) headRooms(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	token, ok := h.roomsChangeToken(c, userID)
+	if !ok {
+		return
+	}
+	if c.GetHeader("If-None-Match") == `"`+token+`"` {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
 func (h *AppHandler) getRooms(c *gin.Context) {
 	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
-	rooms, err := h.uc.GetRoomsForUser(c.Request.Context(), userID)
+	token, ok := h.roomsChangeToken(c, userID)
+	if !ok {
+		return
+	}
+	if c.GetHeader("If-None-Match") == `"`+token+`"` {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	rooms, err := h.uc.GetRoomList(c.Request.Context(), userID, c.Query("state"))
+	if errors.Is(err, usecase.ErrInvalidRoomState) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
-		log.Printf("Error from GetRoomsForUser: %v", err)
+		httpLog.Errorf("Error from GetRoomList: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch rooms"})
 		return
 	}
 	c.JSON(http.StatusOK, rooms)
 }
 
+type SnoozePayload struct {
+	Duration string `json:"duration" binding:"required"`
+}
+
+func (h *AppHandler) snoozeRoom(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	roomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+	var payload SnoozePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	duration, err := time.ParseDuration(payload.Duration)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "duration must look like 30m or 8h"})
+		return
+	}
+	until, err := h.uc.SnoozeRoom(c.Request.Context(), userID, roomID, duration)
+	if errors.Is(err, usecase.ErrInvalidSnooze) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrNotRoomMember) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpLog.Errorf("Error from SnoozeRoom: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not snooze room"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"roomId": roomID, "snoozedUntil": until})
+}
+
+func (h *AppHandler) unsnoozeRoom(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	roomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+	err = h.uc.UnsnoozeRoom(c.Request.Context(), userID, roomID)
+	if errors.Is(err, usecase.ErrNotRoomMember) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpLog.Errorf("Error from UnsnoozeRoom: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not unsnooze room"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+type RoomStatePayload struct {
+	State string `json:"state" binding:"required"`
+}
+
+func (h *AppHandler) setRoomState(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	roomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+	var payload RoomStatePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	change, err := h.uc.ChangeRoomState(c.Request.Context(), userID, roomID, payload.State)
+	switch {
+	case errors.Is(err, usecase.ErrInvalidRoomState), errors.Is(err, usecase.ErrRoomStateUnsupported):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, usecase.ErrNotRoomMember), errors.Is(err, usecase.ErrRoomStateForbidden), errors.Is(err, usecase.ErrNotSupportAgent):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, usecase.ErrRoomNotFound), errors.Is(err, usecase.ErrSupportConversationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		httpLog.Errorf("Error from ChangeRoomState: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not change room state"})
+		return
+	}
+	if change == nil {
+		c.JSON(http.StatusOK, gin.H{"roomId": roomID, "state": payload.State})
+		return
+	}
+	c.JSON(http.StatusOK, change)
+}
+
 func (h *AppHandler) getMessages(c *gin.Context) {
 	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
 	roomID, err := uuid.Parse(c.Param("id"))
@@ -152,12 +586,615 @@ func (h *AppHandler) getMessages(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
 		return
 	}
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
-	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
-	messages, err := h.uc.GetMessagesForRoom(c.Request.Context(), userID, roomID, limit, offset)
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	offset, _ := strconv.Atoi(c.Query("offset"))
+	page, err := h.uc.GetMessagesForRoom(c.Request.Context(), userID, roomID, c.Query("tag"), limit, offset)
+	if errors.Is(err, usecase.ErrInvalidTag) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+func (h *AppHandler) getRoomTags(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	roomID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	tags, err := h.uc.GetRoomTags(c.Request.Context(), userID, roomID, limit)
+	if errors.Is(err, usecase.ErrNotRoomMember) {
 		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, messages)
-}
\ No newline at end of file
+	if err != nil {
+		httpLog.Errorf("Error from GetRoomTags: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch room tags"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+type AwayPayload struct {
+	Message  string     `json:"message" binding:"required"`
+	StartsAt *time.Time `json:"startsAt"`
+	EndsAt   *time.Time `json:"endsAt"`
+}
+
+func (h *AppHandler) getAway(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	status, err := h.uc.GetAwayStatus(c.Request.Context(), userID)
+	if err != nil {
+		httpLog.Errorf("Error from GetAwayStatus: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch away status"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"away": status})
+}
+
+func (h *AppHandler) setAway(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	var payload AwayPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	status, err := h.uc.SetAwayStatus(c.Request.Context(), userID, payload.Message, payload.StartsAt, payload.EndsAt)
+	if errors.Is(err, usecase.ErrInvalidAwayStatus) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpLog.Errorf("Error from SetAwayStatus: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not save away status"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"away": status})
+}
+
+func (h *AppHandler) clearAway(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	if err := h.uc.ClearAwayStatus(c.Request.Context(), userID); err != nil {
+		httpLog.Errorf("Error from ClearAwayStatus: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not clear away status"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+type ShareLinkPayload struct {
+	FirstMessageID int64  `json:"firstMessageId" binding:"required"`
+	LastMessageID  int64  `json:"lastMessageId" binding:"required"`
+	Title          string `json:"title"`
+	ExpiresIn      string `json:"expiresIn"`
+}
+
+func (h *AppHandler) createShareLink(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	roomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+	var payload ShareLinkPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var ttl time.Duration
+	if payload.ExpiresIn != "" {
+		if ttl, err = time.ParseDuration(payload.ExpiresIn); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expiresIn must look like 24h or 168h"})
+			return
+		}
+	}
+	link, err := h.uc.CreateShareLink(c.Request.Context(), userID, roomID, payload.FirstMessageID, payload.LastMessageID, payload.Title, ttl)
+	if errors.Is(err, usecase.ErrInvalidShareRange) || errors.Is(err, usecase.ErrInvalidShareLink) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrNotRoomMember) || errors.Is(err, usecase.ErrShareLinkForbidden) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpLog.Errorf("Error from CreateShareLink: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create share link"})
+		return
+	}
+	c.JSON(http.StatusCreated, link)
+}
+
+func (h *AppHandler) listShareLinks(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	roomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+	links, err := h.uc.ListShareLinks(c.Request.Context(), userID, roomID)
+	if errors.Is(err, usecase.ErrNotRoomMember) || errors.Is(err, usecase.ErrShareLinkForbidden) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpLog.Errorf("Error from ListShareLinks: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch share links"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"links": links})
+}
+
+func (h *AppHandler) revokeShareLink(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	roomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+	linkID, err := uuid.Parse(c.Param("linkId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid share link ID"})
+		return
+	}
+	err = h.uc.RevokeShareLink(c.Request.Context(), userID, roomID, linkID)
+	if errors.Is(err, usecase.ErrNotRoomMember) || errors.Is(err, usecase.ErrShareLinkForbidden) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrShareLinkNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpLog.Errorf("Error from RevokeShareLink: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not revoke share link"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AppHandler) viewSharedHistory(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Robots-Tag", "noindex")
+	history, err := h.uc.ViewSharedHistory(c.Request.Context(), c.Param("token"), c.ClientIP(), c.Request.UserAgent())
+	if errors.Is(err, usecase.ErrShareLinkNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrShareLinkExpired) {
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpLog.Errorf("Error from ViewSharedHistory: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not load shared history"})
+		return
+	}
+	c.JSON(http.StatusOK, history)
+}
+
+func (h *AppHandler) getBootstrap(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	bootstrap, err := h.uc.GetBootstrap(c.Request.Context(), userID)
+	if err != nil {
+		httpLog.Errorf("Error from GetBootstrap: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not load bootstrap data"})
+		return
+	}
+	c.JSON(http.StatusOK, bootstrap)
+}
+
+func (h *AppHandler) recordExposure(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	variant, err := h.uc.RecordExperimentExposure(c.Request.Context(), userID, c.Param("name"))
+	if errors.Is(err, experiments.ErrUnknownExperiment) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpLog.Errorf("Error recording experiment exposure: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not record exposure"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"experiment": c.Param("name"), "variant": variant})
+}
+
+func (h *AppHandler) searchMessages(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	var roomID uuid.UUID
+	if raw := c.Query("roomId"); raw != "" {
+		parsed, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+			return
+		}
+		roomID = parsed
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	messages, err := h.uc.SearchMessages(c.Request.Context(), userID, roomID, c.Query("q"), limit)
+	if errors.Is(err, usecase.ErrInvalidSearchQuery) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrNotRoomMember) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpLog.Errorf("Error from SearchMessages: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search messages"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"messages": messages})
+}
+
+func (h *AppHandler) quickSearch(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	perType, _ := strconv.Atoi(c.Query("limit"))
+	results, err := h.uc.QuickSearch(c.Request.Context(), userID, c.Query("q"), perType)
+	if errors.Is(err, usecase.ErrInvalidSearchQuery) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpLog.Errorf("Error from QuickSearch: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+func (h *AppHandler) createCallToken(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	roomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+	token, err := h.uc.CreateCallToken(c.Request.Context(), userID, roomID)
+	if errors.Is(err, sfu.ErrNotConfigured) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, token)
+}
+
+func (h *AppHandler) getRecordings(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	roomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+	recordings, err := h.uc.ListCallRecordings(c.Request.Context(), userID, roomID)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, recordings)
+}
+
+func (h *AppHandler) getRecording(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	roomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+	recordingID, err := uuid.Parse(c.Param("recordingId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid recording ID"})
+		return
+	}
+	recording, err := h.uc.GetCallRecording(c.Request.Context(), userID, roomID, recordingID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, recording)
+}
+
+func (h *AppHandler) getRoomMetadata(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	roomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+	metadata, err := h.uc.GetRoomMetadata(c.Request.Context(), userID, roomID)
+	if errors.Is(err, usecase.ErrNotRoomMember) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpLog.Errorf("Error from GetRoomMetadata: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch room metadata"})
+		return
+	}
+	c.JSON(http.StatusOK, metadata)
+}
+
+func (h *AppHandler) updateRoomMetadata(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	roomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, 16*1024)
+	var changes map[string]json.RawMessage
+	if err := c.ShouldBindJSON(&changes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Metadata must be a JSON object of at most 16KB"})
+		return
+	}
+	metadata, err := h.uc.UpdateRoomMetadata(c.Request.Context(), userID, roomID, changes)
+	switch {
+	case errors.Is(err, usecase.ErrNotRoomMember), errors.Is(err, usecase.ErrRoomMetadataForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrInvalidRoomMetadata):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrRoomMetadataTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case err != nil:
+		httpLog.Errorf("Error from UpdateRoomMetadata: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update room metadata"})
+	default:
+		c.JSON(http.StatusOK, metadata)
+	}
+}
+
+type AddRoomMembersPayload struct {
+	UserIDs []uuid.UUID `json:"userIds" binding:"required,min=1,max=50"`
+}
+
+func (h *AppHandler) addRoomMembers(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	roomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+	var payload AddRoomMembersPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	results, err := h.uc.AddRoomMembers(c.Request.Context(), userID, roomID, payload.UserIDs)
+	switch {
+	case errors.Is(err, usecase.ErrNotRoomMember), errors.Is(err, usecase.ErrRoomMembersForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrRoomNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrRoomNotGroup):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		httpLog.Errorf("Error from AddRoomMembers: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not add room members"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"results": results})
+	}
+}
+
+type GroupFromPrivatePayload struct {
+	Name          string      `json:"name" binding:"required"`
+	UserIDs       []uuid.UUID `json:"userIds"`
+	ImportHistory int         `json:"importHistory"`
+}
+
+func (h *AppHandler) createGroupFromPrivate(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	roomID, err := uuid.Parse(c.Param("room_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+	var payload GroupFromPrivatePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	upgrade, err := h.uc.CreateGroupFromPrivate(c.Request.Context(), userID, roomID, payload.Name, payload.UserIDs, payload.ImportHistory)
+	switch {
+	case errors.Is(err, usecase.ErrNotRoomMember):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrRoomNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrRoomNotPrivate):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrInvalidGroupUpgrade):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrQuotaExceeded):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case err != nil:
+		httpLog.Errorf("Error from CreateGroupFromPrivate: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create group room"})
+	default:
+		c.JSON(http.StatusCreated, upgrade)
+	}
+}
+
+type CloneRoomPayload struct {
+	Name           string `json:"name"`
+	IncludeMembers bool   `json:"includeMembers"`
+}
+
+func (h *AppHandler) cloneRoom(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	roomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+	var payload CloneRoomPayload
+	if err := c.ShouldBindJSON(&payload); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	clone, err := h.uc.CloneRoom(c.Request.Context(), userID, roomID, payload.Name, payload.IncludeMembers)
+	switch {
+	case errors.Is(err, usecase.ErrNotRoomMember), errors.Is(err, usecase.ErrRoomCloneForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrRoomNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrRoomNotCloneable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrInvalidRoomClone):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrQuotaExceeded):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case err != nil:
+		httpLog.Errorf("Error from CloneRoom: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not clone room"})
+	default:
+		c.JSON(http.StatusCreated, clone)
+	}
+}
+
+type SaveDraftPayload struct {
+	Content       string      `json:"content"`
+	AttachmentIDs []uuid.UUID `json:"attachmentIds"`
+}
+
+func (h *AppHandler) getDrafts(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	drafts, err := h.uc.GetDrafts(c.Request.Context(), userID)
+	if err != nil {
+		httpLog.Errorf("Error from GetDrafts: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch drafts"})
+		return
+	}
+	c.JSON(http.StatusOK, drafts)
+}
+
+func (h *AppHandler) getDraft(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	roomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+	draft, err := h.uc.GetDraft(c.Request.Context(), userID, roomID)
+	switch {
+	case errors.Is(err, usecase.ErrNotRoomMember):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case err != nil:
+		httpLog.Errorf("Error from GetDraft: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch draft"})
+	case draft == nil:
+		c.Status(http.StatusNoContent)
+	default:
+		c.JSON(http.StatusOK, draft)
+	}
+}
+
+func (h *AppHandler) saveDraft(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	roomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+	var payload SaveDraftPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	draft, err := h.uc.SaveDraft(c.Request.Context(), userID, roomID, payload.Content, payload.AttachmentIDs)
+	switch {
+	case errors.Is(err, usecase.ErrNotRoomMember):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrInvalidDraft):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		httpLog.Errorf("Error from SaveDraft: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not save draft"})
+	case draft == nil:
+		c.Status(http.StatusNoContent)
+	default:
+		c.JSON(http.StatusOK, draft)
+	}
+}
+
+func (h *AppHandler) deleteDraft(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	roomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+	if err := h.uc.DeleteDraft(c.Request.Context(), userID, roomID); err != nil {
+		httpLog.Errorf("Error from DeleteDraft: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete draft"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AppHandler) sfuWebhook(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, 64*1024))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not read webhook body"})
+		return
+	}
+	err = h.uc.HandleSFUWebhook(c.Request.Context(), body, c.GetHeader("X-SFU-Signature"))
+	switch {
+	case errors.Is(err, sfu.ErrNotConfigured):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case errors.Is(err, sfu.ErrInvalidSignature):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func (h *AppHandler) authEvents(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, 64*1024))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not read event body"})
+		return
+	}
+	err = h.uc.HandleAuthEvent(c.Request.Context(), body, c.GetHeader(integrations.AuthSignatureHeader))
+	switch {
+	case errors.Is(err, integrations.ErrAuthSyncNotConfigured):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case errors.Is(err, integrations.ErrInvalidAuthSignature):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func (h *AppHandler) getUserIdentities(c *gin.Context) {
+	viewerID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	userID := viewerID
+	if raw := c.Param("id"); raw != "me" {
+		parsed, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+		userID = parsed
+	}
+	identities, err := h.uc.GetUserIdentities(c.Request.Context(), viewerID, userID)
+	if err != nil {
+		httpLog.Errorf("Error from GetUserIdentities: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch linked accounts"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"identities": identities})
+}
@@ -0,0 +1,32 @@
+package http
+
+import (
+	"net/http"
+
+	"chatservice/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Readiness interface {
+	Ready() bool
+	Status() map[string]repository.PoolHealth
+}
+
+type SubsystemReadiness interface {
+	Ready() bool
+	Subsystems() map[string]string
+}
+
+func RegisterHealthRoutes(api *gin.RouterGroup, readiness Readiness, subsystems SubsystemReadiness) {
+	api.GET("/healthz", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+	api.GET("/readyz", func(c *gin.Context) {
+		status := http.StatusOK
+		if !readiness.Ready() || !subsystems.Ready() {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, gin.H{"ready": status == http.StatusOK, "databases": readiness.Status(), "subsystems": subsystems.Subsystems()})
+	})
+}
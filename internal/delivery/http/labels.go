@@ -0,0 +1,195 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"chatservice/internal/middleware"
+	"chatservice/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type LabelPayload struct {
+	Name  string `json:"name" binding:"required"`
+	Color string `json:"color"`
+}
+
+func (p LabelPayload) input() usecase.LabelInput {
+	return usecase.LabelInput{Name: p.Name, Color: p.Color}
+}
+
+func (h *AppHandler) listLabels(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	labels, err := h.uc.ListLabels(c.Request.Context(), userID)
+	if err != nil {
+		respondLabelError(c, "ListLabels", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"labels": labels})
+}
+
+func (h *AppHandler) createLabel(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	var payload LabelPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	label, err := h.uc.CreateLabel(c.Request.Context(), userID, payload.input())
+	if err != nil {
+		respondLabelError(c, "CreateLabel", err)
+		return
+	}
+	c.JSON(http.StatusCreated, label)
+}
+
+func (h *AppHandler) updateLabel(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	labelID, ok := parseLabelID(c)
+	if !ok {
+		return
+	}
+	var payload LabelPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	label, err := h.uc.UpdateLabel(c.Request.Context(), userID, labelID, payload.input())
+	if err != nil {
+		respondLabelError(c, "UpdateLabel", err)
+		return
+	}
+	c.JSON(http.StatusOK, label)
+}
+
+func (h *AppHandler) deleteLabel(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	labelID, ok := parseLabelID(c)
+	if !ok {
+		return
+	}
+	if err := h.uc.DeleteLabel(c.Request.Context(), userID, labelID); err != nil {
+		respondLabelError(c, "DeleteLabel", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AppHandler) getLabeledMessages(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	labelID, ok := parseLabelID(c)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	messages, err := h.uc.GetLabeledMessages(c.Request.Context(), userID, labelID, limit)
+	if err != nil {
+		respondLabelError(c, "GetLabeledMessages", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"messages": messages})
+}
+
+func (h *AppHandler) labelMessage(c *gin.Context) {
+	h.setMessageLabel(c, true)
+}
+
+func (h *AppHandler) unlabelMessage(c *gin.Context) {
+	h.setMessageLabel(c, false)
+}
+
+func (h *AppHandler) setMessageLabel(c *gin.Context, add bool) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	labelID, ok := parseLabelID(c)
+	if !ok {
+		return
+	}
+	messageID, err := strconv.ParseInt(c.Param("messageId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+	if add {
+		err = h.uc.LabelMessage(c.Request.Context(), userID, labelID, messageID)
+	} else {
+		err = h.uc.UnlabelMessage(c.Request.Context(), userID, labelID, messageID)
+	}
+	if err != nil {
+		respondLabelError(c, "SetMessageLabel", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AppHandler) getLabeledRooms(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	labelID, ok := parseLabelID(c)
+	if !ok {
+		return
+	}
+	rooms, err := h.uc.GetLabeledRooms(c.Request.Context(), userID, labelID)
+	if err != nil {
+		respondLabelError(c, "GetLabeledRooms", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"rooms": rooms})
+}
+
+func (h *AppHandler) labelRoom(c *gin.Context) {
+	h.setRoomLabel(c, true)
+}
+
+func (h *AppHandler) unlabelRoom(c *gin.Context) {
+	h.setRoomLabel(c, false)
+}
+
+func (h *AppHandler) setRoomLabel(c *gin.Context, add bool) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	labelID, ok := parseLabelID(c)
+	if !ok {
+		return
+	}
+	roomID, err := uuid.Parse(c.Param("roomId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+	if add {
+		err = h.uc.LabelRoom(c.Request.Context(), userID, labelID, roomID)
+	} else {
+		err = h.uc.UnlabelRoom(c.Request.Context(), userID, labelID, roomID)
+	}
+	if err != nil {
+		respondLabelError(c, "SetRoomLabel", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func parseLabelID(c *gin.Context) (uuid.UUID, bool) {
+	labelID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid label ID"})
+		return uuid.Nil, false
+	}
+	return labelID, true
+}
+
+func respondLabelError(c *gin.Context, op string, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrInvalidLabel), errors.Is(err, usecase.ErrTooManyLabels):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrLabelNotFound), errors.Is(err, usecase.ErrMessageNotFound), errors.Is(err, usecase.ErrRoomNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrLabelTargetAccess):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrLabelNameTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		httpLog.Errorf("Error from %s: %v", op, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not process label"})
+	}
+}
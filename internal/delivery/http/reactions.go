@@ -0,0 +1,69 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"chatservice/internal/middleware"
+	"chatservice/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func (h *AppHandler) addReaction(c *gin.Context) {
+	h.setReaction(c, true)
+}
+
+func (h *AppHandler) removeReaction(c *gin.Context) {
+	h.setReaction(c, false)
+}
+
+func (h *AppHandler) setReaction(c *gin.Context, add bool) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	roomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+	messageID, err := strconv.ParseInt(c.Param("messageId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+	err = h.uc.ReactToMessage(c.Request.Context(), userID, roomID, messageID, c.Param("emoji"), add)
+	switch {
+	case errors.Is(err, usecase.ErrInvalidReaction):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrNotRoomMember):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrMessageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		httpLog.Errorf("Error from ReactToMessage: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update reaction"})
+	default:
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func (h *AppHandler) getRoomHighlights(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	roomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+	days, _ := strconv.Atoi(c.Query("days"))
+	highlights, err := h.uc.GetRoomHighlights(c.Request.Context(), userID, roomID, days)
+	switch {
+	case errors.Is(err, usecase.ErrNotRoomMember):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case err != nil:
+		httpLog.Errorf("Error from GetRoomHighlights: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch room highlights"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"highlights": highlights})
+	}
+}
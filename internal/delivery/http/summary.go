@@ -0,0 +1,51 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"chatservice/internal/integrations"
+	"chatservice/internal/middleware"
+	"chatservice/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func (h *AppHandler) getRoomSummary(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	roomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+	var since *time.Time
+	if raw := c.Query("since"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC 3339 timestamp"})
+			return
+		}
+		since = &parsed
+	}
+	summary, err := h.uc.GetRoomSummary(c.Request.Context(), userID, roomID, since)
+	switch {
+	case errors.Is(err, usecase.ErrNotRoomMember), errors.Is(err, usecase.ErrSummariesDisabled):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrRoomNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrInvalidSummarySince):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, integrations.ErrSummarizerNotConfigured):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrSummaryUnavailable):
+		httpLog.Errorf("Error from GetRoomSummary: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Could not generate room summary"})
+	case err != nil:
+		httpLog.Errorf("Error from GetRoomSummary: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch room summary"})
+	default:
+		c.JSON(http.StatusOK, summary)
+	}
+}
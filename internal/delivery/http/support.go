@@ -0,0 +1,250 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"chatservice/internal/middleware"
+	"chatservice/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type SupportConversationPayload struct {
+	Subject string `json:"subject"`
+}
+
+type SupportAvailabilityPayload struct {
+	Available *bool `json:"available" binding:"required"`
+}
+
+type SupportTransferPayload struct {
+	AgentID uuid.UUID `json:"agentId" binding:"required"`
+}
+
+type SupportNotePayload struct {
+	Content string `json:"content" binding:"required"`
+}
+
+func (h *AppHandler) openSupportConversation(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	var payload SupportConversationPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	conv, err := h.uc.OpenSupportConversation(c.Request.Context(), userID, payload.Subject)
+	if errors.Is(err, usecase.ErrInvalidSupportSubject) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrQuotaExceeded) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpLog.Errorf("Error from OpenSupportConversation: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not open support conversation"})
+		return
+	}
+	c.JSON(http.StatusCreated, conv)
+}
+
+func (h *AppHandler) listSupportQueue(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	conversations, err := h.uc.ListSupportQueue(c.Request.Context(), userID)
+	if errors.Is(err, usecase.ErrNotSupportAgent) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpLog.Errorf("Error from ListSupportQueue: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch support queue"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"conversations": conversations})
+}
+
+func (h *AppHandler) listAgentConversations(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	conversations, err := h.uc.ListAgentConversations(c.Request.Context(), userID)
+	if errors.Is(err, usecase.ErrNotSupportAgent) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpLog.Errorf("Error from ListAgentConversations: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch support conversations"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"conversations": conversations})
+}
+
+func (h *AppHandler) setSupportAvailability(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	var payload SupportAvailabilityPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	err := h.uc.SetSupportAvailability(c.Request.Context(), userID, *payload.Available)
+	if errors.Is(err, usecase.ErrNotSupportAgent) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpLog.Errorf("Error from SetSupportAvailability: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update availability"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"available": *payload.Available})
+}
+
+func (h *AppHandler) claimSupportConversation(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	roomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+		return
+	}
+	conv, err := h.uc.ClaimSupportConversation(c.Request.Context(), userID, roomID)
+	h.respondSupportAssignment(c, "ClaimSupportConversation", conv, err)
+}
+
+func (h *AppHandler) releaseSupportConversation(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	roomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+		return
+	}
+	conv, err := h.uc.ReleaseSupportConversation(c.Request.Context(), userID, roomID)
+	h.respondSupportAssignment(c, "ReleaseSupportConversation", conv, err)
+}
+
+func (h *AppHandler) transferSupportConversation(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	roomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+		return
+	}
+	var payload SupportTransferPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	conv, err := h.uc.TransferSupportConversation(c.Request.Context(), userID, roomID, payload.AgentID)
+	h.respondSupportAssignment(c, "TransferSupportConversation", conv, err)
+}
+
+func (h *AppHandler) resolveSupportConversation(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	roomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+		return
+	}
+	conv, err := h.uc.ResolveSupportConversation(c.Request.Context(), userID, roomID)
+	h.respondSupportAssignment(c, "ResolveSupportConversation", conv, err)
+}
+
+func (h *AppHandler) getRoomStats(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	roomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+	stats, err := h.uc.GetRoomStats(c.Request.Context(), userID, roomID)
+	if errors.Is(err, usecase.ErrNotRoomMember) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrRoomNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpLog.Errorf("Error from GetRoomStats: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch room stats"})
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+func (h *AppHandler) respondSupportAssignment(c *gin.Context, op string, conv any, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrNotSupportAgent):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrSupportConversationNotFound), errors.Is(err, usecase.ErrSupportAgentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrSupportConversationAssigned), errors.Is(err, usecase.ErrNotAssignedAgent):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrInvalidSupportTransferTarget):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		httpLog.Errorf("Error from %s: %v", op, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update support conversation"})
+	default:
+		c.JSON(http.StatusOK, conv)
+	}
+}
+
+func (h *AppHandler) listSupportNotes(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	roomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+		return
+	}
+	notes, err := h.uc.ListSupportNotes(c.Request.Context(), userID, roomID)
+	if errors.Is(err, usecase.ErrNotSupportAgent) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrSupportConversationNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpLog.Errorf("Error from ListSupportNotes: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch notes"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"notes": notes})
+}
+
+func (h *AppHandler) addSupportNote(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	roomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+		return
+	}
+	var payload SupportNotePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	note, err := h.uc.AddSupportNote(c.Request.Context(), userID, roomID, payload.Content)
+	if errors.Is(err, usecase.ErrNotSupportAgent) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrSupportConversationNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrInvalidSupportNote) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpLog.Errorf("Error from AddSupportNote: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not add note"})
+		return
+	}
+	c.JSON(http.StatusCreated, note)
+}
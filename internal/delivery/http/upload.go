@@ -0,0 +1,219 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/middleware"
+	"chatservice/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	tusVersion           = "1.0.0"
+	uploadChunkMediaType = "application/offset+octet-stream"
+	statusChecksumFailed = 460
+)
+
+type CreateUploadPayload struct {
+	Filename    string `json:"filename" binding:"required"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size" binding:"required"`
+	Checksum    string `json:"checksumSha256"`
+}
+
+func setUploadHeaders(c *gin.Context, upload *domain.Upload) {
+	c.Header("Tus-Resumable", tusVersion)
+	c.Header("Upload-Offset", strconv.FormatInt(upload.OffsetBytes, 10))
+	c.Header("Upload-Length", strconv.FormatInt(upload.SizeBytes, 10))
+	c.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	c.Header("Cache-Control", "no-store")
+}
+
+func uploadError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, usecase.ErrUploadsDisabled):
+		status = http.StatusServiceUnavailable
+	case errors.Is(err, usecase.ErrNotRoomMember), errors.Is(err, usecase.ErrRoomPostingRestricted):
+		status = http.StatusForbidden
+	case errors.Is(err, usecase.ErrInvalidUpload):
+		status = http.StatusBadRequest
+	case errors.Is(err, usecase.ErrQuotaExceeded):
+		status = http.StatusTooManyRequests
+	case errors.Is(err, usecase.ErrUploadNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, usecase.ErrUploadExpired):
+		status = http.StatusGone
+	case errors.Is(err, usecase.ErrUploadTooLarge):
+		status = http.StatusRequestEntityTooLarge
+	case errors.Is(err, usecase.ErrUploadOffsetMismatch):
+		status = http.StatusConflict
+	case errors.Is(err, usecase.ErrUploadChecksumMismatch):
+		status = statusChecksumFailed
+	default:
+		httpLog.Errorf("Upload error: %v", err)
+		c.Header("Tus-Resumable", tusVersion)
+		c.JSON(status, gin.H{"error": "Upload failed"})
+		return
+	}
+	c.Header("Tus-Resumable", tusVersion)
+	c.JSON(status, gin.H{"error": err.Error()})
+}
+
+func (h *AppHandler) createUpload(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	roomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+	var payload CreateUploadPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	upload, err := h.uc.CreateUpload(c.Request.Context(), userID, roomID, payload.Filename, payload.ContentType, payload.Size, payload.Checksum)
+	if err != nil {
+		uploadError(c, err)
+		return
+	}
+	setUploadHeaders(c, upload)
+	c.Header("Location", "/uploads/"+upload.ID.String())
+	c.JSON(http.StatusCreated, upload)
+}
+
+func (h *AppHandler) headUpload(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	uploadID, err := uuid.Parse(c.Param("uploadId"))
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	upload, err := h.uc.GetUpload(c.Request.Context(), userID, uploadID)
+	if err != nil {
+		uploadError(c, err)
+		return
+	}
+	setUploadHeaders(c, upload)
+	c.Status(http.StatusOK)
+}
+
+func (h *AppHandler) patchUpload(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	uploadID, err := uuid.Parse(c.Param("uploadId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invalid upload ID"})
+		return
+	}
+	if c.ContentType() != uploadChunkMediaType {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be " + uploadChunkMediaType})
+		return
+	}
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload-Offset header is required"})
+		return
+	}
+	progress, err := h.uc.AppendUpload(c.Request.Context(), userID, uploadID, offset, c.GetHeader("Upload-Checksum"), c.Request.Body)
+	if err != nil {
+		uploadError(c, err)
+		return
+	}
+	setUploadHeaders(c, progress.Upload)
+	if progress.Message != nil {
+		c.JSON(http.StatusOK, gin.H{"upload": progress.Upload, "attachment": progress.Attachment, "message": progress.Message})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AppHandler) deleteUpload(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	uploadID, err := uuid.Parse(c.Param("uploadId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invalid upload ID"})
+		return
+	}
+	if err := h.uc.DeleteUpload(c.Request.Context(), userID, uploadID); err != nil {
+		uploadError(c, err)
+		return
+	}
+	c.Header("Tus-Resumable", tusVersion)
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AppHandler) getAttachmentContent(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	attachmentID, err := uuid.Parse(c.Param("attachmentId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invalid attachment ID"})
+		return
+	}
+	att, f, err := h.uc.OpenAttachment(c.Request.Context(), userID, attachmentID)
+	switch {
+	case errors.Is(err, usecase.ErrAttachmentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, usecase.ErrUploadsDisabled):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		httpLog.Errorf("Error from OpenAttachment: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not open attachment"})
+		return
+	}
+	c.Header("Cache-Control", "private, max-age=86400")
+	if f == nil {
+		c.Redirect(http.StatusFound, att.StorageURL)
+		return
+	}
+	defer f.Close()
+
+	contentType, err := sniffAttachmentType(f)
+	if err != nil {
+		httpLog.Errorf("Error reading attachment %s: %v", att.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not open attachment"})
+		return
+	}
+	c.Header("ETag", `"`+att.ID.String()+`"`)
+	c.Header("Content-Type", contentType)
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Content-Disposition", `attachment; filename="`+att.ID.String()+`"`)
+	c.Header("Content-Security-Policy", "default-src 'none'; sandbox")
+	http.ServeContent(c.Writer, c.Request, "", att.CreatedAt, f)
+}
+
+var servableAttachmentTypes = map[string]bool{
+	"image/png":                 true,
+	"image/jpeg":                true,
+	"image/gif":                 true,
+	"image/webp":                true,
+	"audio/mpeg":                true,
+	"audio/ogg":                 true,
+	"audio/wave":                true,
+	"video/mp4":                 true,
+	"video/webm":                true,
+	"application/pdf":           true,
+	"text/plain; charset=utf-8": true,
+}
+
+func sniffAttachmentType(f io.ReadSeeker) (string, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	if sniffed := http.DetectContentType(head[:n]); servableAttachmentTypes[sniffed] {
+		return sniffed, nil
+	}
+	return "application/octet-stream", nil
+}
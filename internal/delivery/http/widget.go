@@ -0,0 +1,221 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"chatservice/internal/middleware"
+	"chatservice/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type WidgetKeyPayload struct {
+	Label string `json:"label"`
+}
+
+type GuestSessionPayload struct {
+	DisplayName string `json:"displayName" binding:"required"`
+}
+
+type GuestMergePayload struct {
+	GuestToken string `json:"guestToken" binding:"required"`
+}
+
+type GuestMessagePayload struct {
+	Content string `json:"content" binding:"required"`
+}
+
+func (h *AppHandler) createWidgetKey(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	roomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+	var payload WidgetKeyPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	key, err := h.uc.CreateWidgetKey(c.Request.Context(), userID, roomID, payload.Label)
+	if errors.Is(err, usecase.ErrInvalidWidgetLabel) || errors.Is(err, usecase.ErrWidgetRoomNotGroup) || errors.Is(err, usecase.ErrWidgetRoomNotPublic) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrNotRoomMember) || errors.Is(err, usecase.ErrWidgetKeyForbidden) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrRoomNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpLog.Errorf("Error from CreateWidgetKey: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create widget key"})
+		return
+	}
+	c.JSON(http.StatusCreated, key)
+}
+
+func (h *AppHandler) listWidgetKeys(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	roomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+	keys, err := h.uc.ListWidgetKeys(c.Request.Context(), userID, roomID)
+	if errors.Is(err, usecase.ErrNotRoomMember) || errors.Is(err, usecase.ErrWidgetKeyForbidden) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpLog.Errorf("Error from ListWidgetKeys: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch widget keys"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"keys": keys})
+}
+
+func (h *AppHandler) revokeWidgetKey(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	roomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+	keyID, err := uuid.Parse(c.Param("keyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid widget key ID"})
+		return
+	}
+	err = h.uc.RevokeWidgetKey(c.Request.Context(), userID, roomID, keyID)
+	if errors.Is(err, usecase.ErrNotRoomMember) || errors.Is(err, usecase.ErrWidgetKeyForbidden) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrWidgetKeyNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpLog.Errorf("Error from RevokeWidgetKey: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not revoke widget key"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AppHandler) startGuestSession(c *gin.Context) {
+	var payload GuestSessionPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	session, err := h.uc.StartGuestSession(c.Request.Context(), c.GetHeader("X-Widget-Key"), payload.DisplayName, c.ClientIP())
+	if errors.Is(err, usecase.ErrInvalidWidgetKey) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrInvalidGuestName) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrGuestSessionsLimited) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpLog.Errorf("Error from StartGuestSession: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not start guest session"})
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, session)
+}
+
+func (h *AppHandler) getGuestMessages(c *gin.Context) {
+	afterID, _ := strconv.ParseInt(c.Query("after"), 10, 64)
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	messages, err := h.uc.GetGuestMessages(c.Request.Context(), guestToken(c), afterID, limit)
+	if errors.Is(err, usecase.ErrInvalidGuestToken) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpLog.Errorf("Error from GetGuestMessages: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch messages"})
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{"messages": messages})
+}
+
+func (h *AppHandler) sendGuestMessage(c *gin.Context) {
+	var payload GuestMessagePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	msg, err := h.uc.SendGuestMessage(c.Request.Context(), guestToken(c), payload.Content)
+	if errors.Is(err, usecase.ErrInvalidGuestToken) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrInvalidContent) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrRoomPostingRestricted) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrRoomClosed) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrQuotaExceeded) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpLog.Errorf("Error from SendGuestMessage: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not send message"})
+		return
+	}
+	c.JSON(http.StatusCreated, msg)
+}
+
+func (h *AppHandler) mergeGuestIdentity(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	var payload GuestMergePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	merge, err := h.uc.MergeGuestIdentity(c.Request.Context(), userID, strings.TrimSpace(payload.GuestToken))
+	if errors.Is(err, usecase.ErrInvalidGuestToken) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrGuestTenantMismatch) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpLog.Errorf("Error from MergeGuestIdentity: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not merge guest history"})
+		return
+	}
+	c.JSON(http.StatusOK, merge)
+}
+
+func guestToken(c *gin.Context) string {
+	token, _ := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	return strings.TrimSpace(token)
+}
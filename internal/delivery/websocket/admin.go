@@ -0,0 +1,73 @@
+package websocket
+
+import (
+	"context"
+
+	"chatservice/internal/encode"
+
+	"github.com/google/uuid"
+)
+
+const adminDisconnectCode = "disconnected_by_admin"
+
+type HubStats struct {
+	Connections       int `json:"connections"`
+	Users             int `json:"users"`
+	Rooms             int `json:"rooms"`
+	ParkedSessions    int `json:"parkedSessions"`
+	PendingDeliveries int `json:"pendingDeliveries"`
+	QueuedBroadcasts  int `json:"queuedBroadcasts"`
+	QueuedPackets     int `json:"queuedPackets"`
+
+	Packets PacketStats `json:"packets"`
+}
+
+func (h *Hub) Stats(ctx context.Context) (HubStats, error) {
+	reply := make(chan HubStats, 1)
+	select {
+	case h.statsRequests <- reply:
+	case <-ctx.Done():
+		return HubStats{}, ctx.Err()
+	}
+	select {
+	case stats := <-reply:
+		return stats, nil
+	case <-ctx.Done():
+		return HubStats{}, ctx.Err()
+	}
+}
+
+func (h *Hub) DisconnectUser(userID uuid.UUID) { h.disconnects <- userID }
+
+func (h *Hub) stats() HubStats {
+	stats := HubStats{
+		Connections:      len(h.clients),
+		Users:            len(h.userClients),
+		Rooms:            len(h.rooms),
+		ParkedSessions:   len(h.parked),
+		QueuedBroadcasts: len(h.broadcast),
+		QueuedPackets:    len(h.process),
+		Packets:          h.packetStatsSnapshot(),
+	}
+	for _, pending := range h.pendingDeliveries {
+		stats.PendingDeliveries += len(pending.msgs)
+	}
+	return stats
+}
+
+func (h *Hub) disconnectUser(userID uuid.UUID) {
+	for client := range h.clients {
+		if client.userID != userID {
+			continue
+		}
+		hubLog.Infof("Disconnecting %s at admin request", userID)
+		select {
+		case client.send <- encode.EncodeErrorCode(adminDisconnectCode, "Disconnected by an administrator"):
+		default:
+		}
+		select {
+		case client.kick <- adminDisconnectCode:
+		default:
+		}
+	}
+}
@@ -2,9 +2,15 @@ package websocket
 
 import (
 	"bytes"
-	"log"
+	"context"
+	"strconv"
 	"time"
 
+	"chatservice/internal/encode"
+	"chatservice/internal/tenant"
+	"chatservice/pkg/wprotocol"
+	"chatservice/pkg/wprotocol/record"
+
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
@@ -14,6 +20,9 @@ const (
 	pongWait       = 60 * time.Second
 	pingPeriod     = (pongWait * 9) / 10
 	maxMessageSize = 1024 * 4
+
+	compressionThreshold = 1024
+	maxChunkStreams      = 4
 )
 
 type Client struct {
@@ -21,17 +30,79 @@ type Client struct {
 	conn   *websocket.Conn
 	send   chan []byte
 	userID uuid.UUID
+	tenant string
 	rooms  map[uuid.UUID]bool
+
+	// cursors holds the last message ID delivered per room, carried into
+	// the parked session so a resync can tell the client where to start.
+	cursors map[uuid.UUID]int64
+
+	initialRooms []uuid.UUID
+
+	sessionID   string
+	resumeToken string
+
+	protocolVersion int
+	warnedOps       map[wprotocol.OpCode]bool
+
+	assembler   *wprotocol.Assembler
+	nextChunkID int
+
+	recorder *record.Recorder
+
+	violations violationCounter
+	kick       chan string
+
+	packets chan *wprotocol.Packet
+	closed  bool
+
+	// closeReason is set by the hub before it closes send, so the write
+	// pump can read it once the channel is drained.
+	closeReason string
+}
+
+func (c *Client) context() context.Context {
+	return tenant.WithTenant(context.Background(), c.tenant)
 }
 
 func (c *Client) sendMessage(message []byte) {
+	if op, ok := wprotocol.PeekOp(message); ok {
+		if info, known := wprotocol.Lookup(op); known && !info.SupportedBy(c.protocolVersion) {
+			if op == wprotocol.OpMsgDeliverBatch {
+				for _, frame := range encode.ExpandMsgDeliverBatch(message) {
+					c.sendMessage(frame)
+					if c.closed {
+						break
+					}
+				}
+			}
+			return
+		}
+	}
+	advanceCursor(c.cursors, message)
+	c.recorder.Record(record.DirectionOut, message)
+	if threshold := c.hub.chunkThreshold; threshold > 0 && len(message) > threshold && c.protocolVersion >= 2 {
+		c.nextChunkID++
+		for _, part := range wprotocol.Split(message, threshold*3/4, strconv.Itoa(c.nextChunkID)) {
+			c.enqueue(part)
+			if c.closed {
+				break
+			}
+		}
+		return
+	}
+	c.enqueue(message)
+}
+
+func (c *Client) enqueue(message []byte) {
+	if c.closed {
+		return
+	}
 	select {
 	case c.send <- message:
 	default:
-		log.Printf("Client %s send buffer full. Closing connection.", c.userID)
-		close(c.send)
-		delete(c.hub.clients, c)
-		delete(c.hub.userClients, c.userID)
+		hubLog.Warnf("Client %s send buffer full. Closing connection.", c.userID)
+		c.hub.dropClient(c)
 	}
 }
 
@@ -39,6 +110,8 @@ func (c *Client) readPump() {
 	defer func() {
 		c.hub.unregister <- c
 		c.conn.Close()
+		c.hub.releaseSlot()
+		c.recorder.Close()
 	}()
 	c.conn.SetReadLimit(maxMessageSize)
 	c.conn.SetReadDeadline(time.Now().Add(pongWait))
@@ -48,15 +121,43 @@ func (c *Client) readPump() {
 		_, message, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("error: %v", err)
+				hubLog.Warnf("Unexpected close from %s: %v", c.userID, err)
 			}
 			break
 		}
 		message = bytes.TrimSpace(message)
+		if op, ok := wprotocol.PeekOp(message); ok && wprotocol.IsChunkOp(op) {
+			frame, done := c.reassemble(message)
+			if !done {
+				continue
+			}
+			message = frame
+		}
+		c.recorder.Record(record.DirectionIn, message)
 		c.hub.process <- &PacketRequest{client: c, data: message}
 	}
 }
 
+func (c *Client) reassemble(message []byte) ([]byte, bool) {
+	packet, err := wprotocol.Parse(message)
+	if err != nil {
+		hubLog.Warnf("Error parsing chunk frame from %s: %v", c.userID, err)
+		if c.protocolError(err) {
+			c.hub.violations <- c
+		}
+		return nil, false
+	}
+	frame, done, err := c.assembler.Feed(packet)
+	if err != nil {
+		hubLog.Warnf("Dropping chunked payload from %s: %v", c.userID, err)
+		if c.protocolError(err) {
+			c.hub.violations <- c
+		}
+		return nil, false
+	}
+	return frame, done
+}
+
 func (c *Client) writePump() {
 	ticker := time.NewTicker(pingPeriod)
 	defer func() {
@@ -68,9 +169,14 @@ func (c *Client) writePump() {
 		case message, ok := <-c.send:
 			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if !ok {
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				closing := []byte{}
+				if c.closeReason != "" {
+					closing = websocket.FormatCloseMessage(websocket.ClosePolicyViolation, c.closeReason)
+				}
+				c.conn.WriteMessage(websocket.CloseMessage, closing)
 				return
 			}
+			c.conn.EnableWriteCompression(len(message) >= compressionThreshold)
 			w, err := c.conn.NextWriter(websocket.BinaryMessage)
 			if err != nil {
 				return
@@ -84,6 +190,17 @@ func (c *Client) writePump() {
 			if err := w.Close(); err != nil {
 				return
 			}
+		case reason := <-c.kick:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			for pending := len(c.send); pending > 0; pending-- {
+				message, ok := <-c.send
+				if !ok {
+					break
+				}
+				c.conn.WriteMessage(websocket.BinaryMessage, message)
+			}
+			c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, reason))
+			return
 		case <-ticker.C:
 			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
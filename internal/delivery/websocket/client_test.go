@@ -0,0 +1,162 @@
+package websocket
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/encode"
+
+	"github.com/google/uuid"
+)
+
+func newTestClient(h *Hub, buffer, protocolVersion int) *Client {
+	c := &Client{
+		hub:             h,
+		send:            make(chan []byte, buffer),
+		userID:          uuid.New(),
+		rooms:           make(map[uuid.UUID]bool),
+		cursors:         make(map[uuid.UUID]int64),
+		protocolVersion: protocolVersion,
+	}
+	h.clients[c] = true
+	h.addUserClient(c)
+	return c
+}
+
+func TestChunkedFrameOverflowDropsClient(t *testing.T) {
+	h := NewHub(nil)
+	h.SetChunking(64, 1<<20)
+	roomID := uuid.New()
+	c := newTestClient(h, 2, 2)
+	h.doSubscribe(c, roomID)
+
+	c.sendMessage(bytes.Repeat([]byte("x"), 1024))
+
+	if !c.closed {
+		t.Fatal("client not marked closed after its send buffer overflowed")
+	}
+	if h.clients[c] || len(h.userClients[c.userID]) != 0 {
+		t.Error("client still registered with the hub")
+	}
+	if len(h.rooms[roomID]) != 0 || len(c.rooms) != 0 {
+		t.Error("client still subscribed to its rooms")
+	}
+	if got := len(c.send); got != 2 {
+		t.Errorf("queued %d frames, want the 2 that fit", got)
+	}
+
+	h.doBroadcast(&BroadcastMessage{RoomID: roomID, Message: []byte("late")})
+	c.sendMessage([]byte("late"))
+
+	h.dropClient(c)
+}
+
+func TestExpandedBatchOverflowDropsClient(t *testing.T) {
+	h := NewHub(nil)
+	c := newTestClient(h, 1, 1)
+	roomID := uuid.New()
+	msgs := make([]domain.Message, 4)
+	for i := range msgs {
+		msgs[i] = domain.Message{ID: int64(i + 1), RoomID: roomID, UserID: c.userID, Content: "hi", Kind: "text"}
+	}
+
+	c.sendMessage(encode.EncodeMsgDeliverBatch(roomID, msgs))
+
+	if !c.closed {
+		t.Fatal("client not marked closed after its send buffer overflowed")
+	}
+	if got := len(c.send); got != 1 {
+		t.Errorf("queued %d frames, want the 1 that fits", got)
+	}
+}
+
+func TestProtocolViolationDisconnectsOnHub(t *testing.T) {
+	h := NewHub(nil)
+	h.SetProtocolErrorLimit(2, time.Minute)
+	c := newTestClient(h, 1, 2)
+	c.send <- []byte("pending")
+
+	for range 3 {
+		c.reassemble([]byte("not a packet"))
+	}
+	if len(h.violations) != 1 {
+		t.Fatalf("queued %d violation requests, want 1", len(h.violations))
+	}
+	h.disconnectViolator(<-h.violations)
+
+	if !c.closed || h.clients[c] {
+		t.Fatal("violating client still connected")
+	}
+	if c.closeReason != protocolViolationCode {
+		t.Errorf("close reason = %q, want %q", c.closeReason, protocolViolationCode)
+	}
+	h.disconnectViolator(c)
+}
+
+func TestOverflowedSessionResumesWithResync(t *testing.T) {
+	h := NewHub(nil)
+	roomID := uuid.New()
+	session := &parkedSession{userID: uuid.New(), rooms: map[uuid.UUID]bool{roomID: true}, expiresAt: time.Now().Add(resumeWindow)}
+	h.parked["s1"] = session
+	for range maxParkedFrames + 1 {
+		h.bufferRoomFrame(roomID, []byte("frame"))
+	}
+	if !session.overflowed || len(session.frames) != 0 {
+		t.Fatalf("overflowed = %v with %d frames, want an overflowed empty buffer", session.overflowed, len(session.frames))
+	}
+
+	c := newTestClient(h, 4, 2)
+	c.userID = session.userID
+	c.resumeToken = h.resumeToken("s1")
+	h.resume(c)
+
+	if got := len(c.send); got != 1 {
+		t.Fatalf("sent %d frames, want only the resync", got)
+	}
+	if want := encode.EncodeSessionResync(resyncOverflow, map[uuid.UUID]int64{roomID: 0}); !bytes.Equal(<-c.send, want) {
+		t.Error("resume of an overflowed session did not request a resync")
+	}
+}
+
+func TestRestoredSessionResumesWithCursors(t *testing.T) {
+	dir := t.TempDir()
+	roomID := uuid.New()
+	msg := func(id int64) []byte {
+		return encode.EncodeMsgDeliver(domain.Message{ID: id, RoomID: roomID, Content: "hi", Kind: "text"})
+	}
+
+	before := NewHub(nil)
+	before.SetSnapshotPath(dir + "/hub.json")
+	live := newTestClient(before, 4, 2)
+	live.sessionID = "s1"
+	before.doSubscribe(live, roomID)
+	live.sendMessage(msg(7))
+	before.park(live)
+	before.bufferRoomFrame(roomID, msg(9))
+	go func() { reply := <-before.snapshotRequests; reply <- before.snapshot() }()
+	if err := before.SaveSnapshot(t.Context()); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+
+	after := NewHub(nil)
+	after.SetSnapshotPath(dir + "/hub.json")
+	if err := after.RestoreSnapshot(); err != nil {
+		t.Fatalf("RestoreSnapshot failed: %v", err)
+	}
+	c := newTestClient(after, 4, 2)
+	c.userID = live.userID
+	c.resumeToken = after.resumeToken(live.sessionID)
+	after.resume(c)
+
+	if got := len(c.send); got != 2 {
+		t.Fatalf("sent %d frames, want the buffered message and a resync", got)
+	}
+	if !bytes.Equal(<-c.send, msg(9)) {
+		t.Error("buffered message was not replayed")
+	}
+	if want := encode.EncodeSessionResync(resyncRestart, map[uuid.UUID]int64{roomID: 9}); !bytes.Equal(<-c.send, want) {
+		t.Error("restored session did not request a resync from its cursor")
+	}
+}
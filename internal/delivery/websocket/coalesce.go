@@ -0,0 +1,74 @@
+package websocket
+
+import (
+	"time"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/encode"
+	"chatservice/pkg/wprotocol"
+
+	"github.com/google/uuid"
+)
+
+type pendingDeliveries struct {
+	msgs   []domain.Message
+	fields int
+}
+
+func (h *Hub) SetDeliverCoalescing(window time.Duration) { h.deliverWindow = window }
+
+func (h *Hub) deliverMessage(msg domain.Message) {
+	if h.deliverWindow <= 0 {
+		h.BroadcastToRoom(msg.RoomID, encode.EncodeMsgDeliver(msg))
+		return
+	}
+	h.deliveries <- msg
+}
+
+func (h *Hub) coalesceDelivery(msg domain.Message) {
+	pending, ok := h.pendingDeliveries[msg.RoomID]
+	if !ok {
+		h.pendingDeliveries[msg.RoomID] = &pendingDeliveries{}
+		h.armDeliveryWindow(msg.RoomID)
+		h.doBroadcast(&BroadcastMessage{RoomID: msg.RoomID, Message: encode.EncodeMsgDeliver(msg)})
+		return
+	}
+	fields := encode.MsgDeliverBatchFields(msg)
+	if len(pending.msgs) > 0 && 1+pending.fields+fields > wprotocol.MaxPacketFields {
+		h.flushDeliveries(msg.RoomID)
+	}
+	pending.msgs = append(pending.msgs, msg)
+	pending.fields += fields
+}
+
+func (h *Hub) armDeliveryWindow(roomID uuid.UUID) {
+	time.AfterFunc(h.deliverWindow, func() { h.deliveryFlushes <- roomID })
+}
+
+func (h *Hub) endDeliveryWindow(roomID uuid.UUID) {
+	pending, ok := h.pendingDeliveries[roomID]
+	if !ok {
+		return
+	}
+	if len(pending.msgs) == 0 {
+		delete(h.pendingDeliveries, roomID)
+		return
+	}
+	h.flushDeliveries(roomID)
+	h.armDeliveryWindow(roomID)
+}
+
+func (h *Hub) flushDeliveries(roomID uuid.UUID) {
+	pending, ok := h.pendingDeliveries[roomID]
+	if !ok || len(pending.msgs) == 0 {
+		return
+	}
+	msgs := pending.msgs
+	pending.msgs, pending.fields = nil, 0
+
+	frame := encode.EncodeMsgDeliverBatch(roomID, msgs)
+	if len(msgs) == 1 {
+		frame = encode.EncodeMsgDeliver(msgs[0])
+	}
+	h.doBroadcast(&BroadcastMessage{RoomID: roomID, Message: frame})
+}
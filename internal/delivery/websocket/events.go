@@ -0,0 +1,124 @@
+package websocket
+
+import (
+	"context"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/encode"
+	"chatservice/internal/events"
+	"chatservice/pkg/wprotocol"
+
+	"github.com/google/uuid"
+)
+
+func (h *Hub) HandleEvent(ctx context.Context, event events.Event) {
+	switch e := event.(type) {
+	case events.MessageCreated:
+		h.deliverMessage(e.Message)
+
+	case events.MessageEdited:
+		h.BroadcastToRoom(e.RoomID, encode.EncodeMsgEdited(e.MessageID, e.RoomID, e.Content, e.RichContent))
+
+	case events.MessageDeleted:
+		h.BroadcastToRoom(e.RoomID, encode.EncodeMsgDeleted(e.MessageID, e.RoomID))
+
+	case events.MessageRead:
+		h.BroadcastToRoom(e.RoomID, encode.EncodeMsgStatusUpdate(e.MessageID, e.RoomID, e.UserID, "read", e.ReadAt))
+
+	case events.FriendRequestSent:
+		h.SendToUser(e.Receiver.ID, encode.EncodeFriendRequestReceived(e.Sender))
+
+	case events.FriendRequestDeclined:
+		h.SendToUser(e.RequesterID, encode.EncodeFriendRemoved(e.DeclinerID))
+
+	case events.FriendshipAccepted:
+		h.SendToUser(e.RequesterID, encode.EncodeFriendRequestAccepted(e.Accepter, e.Room.ID))
+		h.joinRoom(e.RequesterID, e.Room)
+		h.joinRoom(e.Accepter.ID, e.Room)
+
+	case events.RoomMembersAdded:
+		for _, userID := range e.UserIDs {
+			h.joinRoom(userID, e.Room)
+		}
+		h.BroadcastToRoom(e.Room.ID, encode.EncodeRoomMembersAdded(e.Room.ID, e.AddedBy, e.UserIDs))
+
+	case events.RoomMembersRemoved:
+		for _, userID := range e.UserIDs {
+			h.leaveRoom(userID, e.RoomID)
+		}
+
+	case events.RoomUpdated:
+		h.SendToUser(e.UserID, encode.EncodeRoomUpdated(e.RoomID, e.SnoozedUntil))
+
+	case events.SupportAssignmentChanged:
+		h.BroadcastToRoom(e.Conversation.RoomID, encode.EncodeSupportAssignment(e.Conversation))
+		if e.PreviousAgentID != nil {
+			h.SendToUser(*e.PreviousAgentID, encode.EncodeSupportAssignment(e.Conversation))
+		}
+
+	case events.UserProfileUpdated:
+		frame := encode.EncodeUserProfileUpdated(e.User)
+		h.SendToUser(e.User.ID, frame)
+		for _, userID := range e.RecipientIDs {
+			h.SendToUser(userID, frame)
+		}
+
+	case events.RoomStateChanged:
+		h.BroadcastToRoom(e.Change.RoomID, encode.EncodeRoomStateChanged(e.Change))
+
+	case events.MessageReactionChanged:
+		h.BroadcastToRoom(e.Reaction.RoomID, encode.EncodeMsgReaction(e.Reaction))
+
+	case events.SupportSLABreached:
+		if e.Breach.AgentID != nil {
+			h.SendToUser(*e.Breach.AgentID, encode.EncodeSupportSLABreached(e.Breach))
+		}
+
+	case events.SupportNoteCreated:
+		for _, userID := range e.RecipientIDs {
+			h.SendToUser(userID, encode.EncodeSupportNote(e.Note))
+		}
+
+	case events.CallParticipantJoined:
+		h.BroadcastToRoom(e.RoomID, encode.EncodeCallParticipant(wprotocol.OpCallParticipantJoined, e.RoomID, e.UserID, e.At))
+
+	case events.CallParticipantLeft:
+		h.BroadcastToRoom(e.RoomID, encode.EncodeCallParticipant(wprotocol.OpCallParticipantLeft, e.RoomID, e.UserID, e.At))
+
+	case events.CallEnded:
+		h.BroadcastToRoom(e.RoomID, encode.EncodeCallEnded(e.RoomID, e.At))
+
+	case events.CallStateChanged:
+		h.BroadcastToRoom(e.RoomID, encode.EncodeCallState(e.RoomID, e.State))
+
+	case events.CallRecordingRequested:
+		h.BroadcastToRoom(e.RoomID, encode.EncodeCallRecordingPrompt(e.RoomID, e.RecordingID, e.RequesterID))
+
+	case events.CallRecordingStarted:
+		h.BroadcastToRoom(e.RoomID, encode.EncodeCallRecordingStarted(e.RoomID, e.RecordingID, e.At))
+
+	case events.CallRecordingStopped:
+		h.BroadcastToRoom(e.RoomID, encode.EncodeCallRecordingStopped(e.RoomID, e.RecordingID, e.Reason, e.AttachmentID))
+
+	case events.UserTyping:
+		h.broadcastTyping(e.RoomID, e.UserID, encode.EncodeTyping(e.RoomID, e.UserID, e.Typing))
+
+	case events.RoomStateSnapshot:
+		h.SendToUser(e.RecipientID, encode.EncodeRoomState(e.RoomID, e.States))
+
+	case events.CallStateSnapshot:
+		for _, state := range e.States {
+			h.SendToUser(e.RecipientID, encode.EncodeCallState(e.RoomID, state))
+		}
+	}
+}
+
+func (h *Hub) joinRoom(userID uuid.UUID, room domain.Room) {
+	h.SendToUser(userID, encode.EncodeNotifyRoomAdded(room))
+	h.Subscribe(userID, room.ID)
+}
+
+func (h *Hub) leaveRoom(userID, roomID uuid.UUID) {
+	h.Unsubscribe(userID, roomID)
+	h.SendToUser(userID, encode.EncodeNotifyRoomRemoved(roomID))
+}
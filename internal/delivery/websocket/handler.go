@@ -1,18 +1,21 @@
 package websocket
 
 import (
-	"log"
 	"net/http"
+	"strconv"
 
 	"chatservice/internal/middleware"
+	"chatservice/internal/tenant"
+	"chatservice/pkg/wprotocol"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
 var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
+	ReadBufferSize:    1024,
+	WriteBufferSize:   1024,
+	EnableCompression: true,
 	CheckOrigin: func(r *http.Request) bool {
 		return true
 	},
@@ -22,9 +25,50 @@ func ServeWs(hub *Hub) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
 
+		version, err := strconv.Atoi(c.DefaultQuery("v", strconv.Itoa(wprotocol.MinProtocolVersion)))
+		if err != nil || version < wprotocol.MinProtocolVersion || version > wprotocol.ProtocolVersion {
+			c.JSON(http.StatusUpgradeRequired, gin.H{
+				"error":      "unsupported protocol version",
+				"minVersion": wprotocol.MinProtocolVersion,
+				"maxVersion": wprotocol.ProtocolVersion,
+			})
+			return
+		}
+
+		if !hub.acquireSlot() {
+			hubLog.Warnf("Connection limit reached, rejecting user %s", userID)
+			c.Header("Retry-After", "5")
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error":        "server is at connection capacity",
+				"alternatives": hub.alternativeInstances(),
+			})
+			return
+		}
+
+		if !hub.admit(c.Request.Context()) {
+			hub.releaseSlot()
+			hubLog.Warnf("Admission queue saturated, deferring user %s", userID)
+			c.Header("Retry-After", "2")
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error":        "server is busy admitting connections",
+				"alternatives": hub.alternativeInstances(),
+			})
+			return
+		}
+		var initialRooms []uuid.UUID
+		userRooms, err := hub.repo.GetRoomsForUser(c.Request.Context(), userID)
+		hub.leave()
+		if err != nil {
+			hubLog.Errorf("Error fetching rooms for user %s: %v", userID, err)
+		}
+		for _, room := range userRooms {
+			initialRooms = append(initialRooms, room.ID)
+		}
+
 		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 		if err != nil {
-			log.Println(err)
+			hub.releaseSlot()
+			hubLog.Errorf("Error upgrading connection for %s: %v", userID, err)
 			return
 		}
 
@@ -33,11 +77,28 @@ func ServeWs(hub *Hub) gin.HandlerFunc {
 			conn:   conn,
 			send:   make(chan []byte, 256),
 			userID: userID,
+			tenant: tenant.FromContext(c.Request.Context()),
 			rooms:  make(map[uuid.UUID]bool),
+
+			cursors: make(map[uuid.UUID]int64),
+
+			initialRooms: initialRooms,
+
+			sessionID:   uuid.NewString(),
+			resumeToken: c.Query("resume"),
+
+			protocolVersion: version,
+			warnedOps:       make(map[wprotocol.OpCode]bool),
+
+			assembler: wprotocol.NewAssembler(hub.maxChunkedPayload, maxChunkStreams),
+			kick:      make(chan string, 1),
+		}
+		if hub.recordDir != "" && c.Query("record") == "1" {
+			client.recorder = hub.newRecorder(userID, client.sessionID)
 		}
 		client.hub.register <- client
 
 		go client.writePump()
 		go client.readPump()
 	}
-}
\ No newline at end of file
+}
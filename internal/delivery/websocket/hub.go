@@ -2,22 +2,31 @@ package websocket
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"chatservice/internal/cluster"
+	"chatservice/internal/domain"
+	"chatservice/internal/encode"
+	"chatservice/internal/logging"
 	"chatservice/internal/repository"
 	"chatservice/internal/usecase"
 	"chatservice/pkg/wprotocol"
 	"github.com/google/uuid"
 )
 
+var hubLog = logging.For("hub")
+
 type PacketRequest struct { client *Client; data []byte }
-type BroadcastMessage struct { RoomID uuid.UUID; Message []byte }
-type DirectMessage struct { UserID uuid.UUID; Message []byte }
-type SubscriptionRequest struct { ClientUserID uuid.UUID; RoomID uuid.UUID }
+type BroadcastMessage struct { RoomID uuid.UUID; Message []byte; except uuid.UUID; transient bool; remote bool }
+type DirectMessage struct { UserID uuid.UUID; Message []byte; remote bool }
+type SubscriptionRequest struct { ClientUserID uuid.UUID; RoomID uuid.UUID; Unsubscribe bool; remote bool }
 
 type Hub struct {
 	clients     map[*Client]bool
-	userClients map[uuid.UUID]*Client
+	userClients map[uuid.UUID]map[*Client]bool
 	rooms       map[uuid.UUID]map[*Client]bool
 	broadcast   chan *BroadcastMessage
 	direct      chan *DirectMessage
@@ -25,14 +34,53 @@ type Hub struct {
 	process     chan *PacketRequest
 	register    chan *Client
 	unregister  chan *Client
+	violations  chan *Client
+	resumes     chan *resumeRequest
+	parked      map[string]*parkedSession
+	online      sync.Map
 	usecase     *usecase.AppUsecase
 	repo        repository.AppRepository
+	cluster     *cluster.Node
+
+	connections    atomic.Int64
+	maxConnections int64
+	alternatives   func() []string
+
+	chunkThreshold    int
+	maxChunkedPayload int
+
+	doNotTrack bool
+
+	recordDir    string
+	snapshotPath string
+
+	maxProtocolErrors   int
+	protocolErrorWindow time.Duration
+
+	deliverWindow     time.Duration
+	deliveries        chan domain.Message
+	deliveryFlushes   chan uuid.UUID
+	pendingDeliveries map[uuid.UUID]*pendingDeliveries
+
+	admission     chan struct{}
+	admissionWait time.Duration
+
+	packetSlots chan struct{}
+	packetQueue int
+	packetStats packetCounters
+
+	statsRequests    chan chan HubStats
+	snapshotRequests chan chan hubSnapshot
+	disconnects      chan uuid.UUID
+
+	stateVersions map[uuid.UUID]int64
+	stateUpdates  chan stateVersionUpdate
 }
 
 func NewHub(repo repository.AppRepository) *Hub {
 	return &Hub{
 		clients:     make(map[*Client]bool),
-		userClients: make(map[uuid.UUID]*Client),
+		userClients: make(map[uuid.UUID]map[*Client]bool),
 		rooms:       make(map[uuid.UUID]map[*Client]bool),
 		broadcast:   make(chan *BroadcastMessage, 256),
 		direct:      make(chan *DirectMessage, 256),
@@ -40,61 +88,282 @@ func NewHub(repo repository.AppRepository) *Hub {
 		process:     make(chan *PacketRequest, 256),
 		register:    make(chan *Client),
 		unregister:  make(chan *Client),
+		violations:  make(chan *Client, 16),
+		resumes:     make(chan *resumeRequest, 64),
+		parked:      make(map[string]*parkedSession),
 		repo:        repo,
+
+		deliveries:        make(chan domain.Message, 256),
+		deliveryFlushes:   make(chan uuid.UUID, 64),
+		pendingDeliveries: make(map[uuid.UUID]*pendingDeliveries),
+
+		statsRequests:    make(chan chan HubStats),
+		snapshotRequests: make(chan chan hubSnapshot),
+		disconnects:      make(chan uuid.UUID, 16),
+
+		stateVersions: make(map[uuid.UUID]int64),
+		stateUpdates:  make(chan stateVersionUpdate, 256),
 	}
 }
 
 func (h *Hub) SetUsecase(uc *usecase.AppUsecase) { h.usecase = uc }
 
-func (h *Hub) Run() {
+func (h *Hub) SetConnectionLimit(limit int, alternatives func() []string) {
+	h.maxConnections = int64(limit)
+	h.alternatives = alternatives
+}
+
+func (h *Hub) SetChunking(threshold, maxPayload int) {
+	h.chunkThreshold = threshold
+	h.maxChunkedPayload = maxPayload
+}
+
+func (h *Hub) SetAdmission(concurrency int, wait time.Duration) {
+	if concurrency > 0 {
+		h.admission = make(chan struct{}, concurrency)
+	}
+	h.admissionWait = wait
+}
+
+func (h *Hub) SetDoNotTrack(enabled bool) { h.doNotTrack = enabled }
+
+func (h *Hub) SetRecording(dir string) { h.recordDir = dir }
+
+func (h *Hub) SetCluster(node *cluster.Node) {
+	h.cluster = node
+	node.OnEnvelope(h.deliverRemote)
+}
+
+func (h *Hub) ConnectionCount() int { return int(h.connections.Load()) }
+
+func (h *Hub) IsOnline(ctx context.Context, userID uuid.UUID) bool {
+	if _, ok := h.online.Load(userID); ok {
+		return true
+	}
+	if h.cluster == nil {
+		return false
+	}
+	online, err := h.cluster.IsOnline(ctx, userID)
+	if err != nil {
+		hubLog.Errorf("Error checking cluster presence for %s: %v", userID, err)
+	}
+	return online
+}
+
+func (h *Hub) acquireSlot() bool {
+	if n := h.connections.Add(1); h.maxConnections > 0 && n > h.maxConnections {
+		h.connections.Add(-1)
+		return false
+	}
+	return true
+}
+
+func (h *Hub) releaseSlot() { h.connections.Add(-1) }
+
+func (h *Hub) admit(ctx context.Context) bool {
+	if h.admission == nil {
+		return true
+	}
+	select {
+	case h.admission <- struct{}{}:
+		return true
+	default:
+	}
+	timer := time.NewTimer(h.admissionWait)
+	defer timer.Stop()
+	select {
+	case h.admission <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (h *Hub) leave() {
+	if h.admission != nil {
+		<-h.admission
+	}
+}
+
+func (h *Hub) alternativeInstances() []string {
+	var urls []string
+	if h.alternatives != nil {
+		urls = h.alternatives()
+	}
+	if urls == nil {
+		urls = []string{}
+	}
+	return urls
+}
+
+func (h *Hub) Run(ctx context.Context) {
+	evictTicker := time.NewTicker(resumeWindow / 4)
+	defer evictTicker.Stop()
+	stateTicker := time.NewTicker(stateHeartbeatInterval)
+	defer stateTicker.Stop()
+
 	for {
 		select {
 		case client := <-h.register:
 			h.clients[client] = true
-			h.userClients[client.userID] = client
-			log.Printf("Client connected: %s", client.userID)
-			userRooms, err := h.repo.GetRoomsForUser(context.Background(), client.userID)
-			if err != nil { log.Printf("Error fetching rooms for user %s: %v", client.userID, err) } else {
-				for _, room := range userRooms { h.doSubscribe(client, room.ID) }
-			}
+			h.addUserClient(client)
+			h.online.Store(client.userID, client)
+			hubLog.Debugf("Client connected: %s", client.userID)
+			if h.cluster != nil { go h.trackConnect(client.userID) }
+			for _, roomID := range client.initialRooms { h.doSubscribe(client, roomID) }
+			client.initialRooms = nil
+			client.sendMessage(encode.EncodeSessionToken(h.resumeToken(client.sessionID)))
+			if client.resumeToken != "" { h.resume(client) }
+			h.sendStateVersion(client)
 
 		case client := <-h.unregister:
 			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				delete(h.userClients, client.userID)
-				for roomID := range client.rooms { h.doUnsubscribe(client, roomID) }
-				close(client.send)
-				log.Printf("Client disconnected: %s", client.userID)
+				h.park(client)
+				h.dropClient(client)
+				hubLog.Debugf("Client disconnected: %s", client.userID)
 			}
 
+		case client := <-h.violations:
+			if h.clients[client] { h.disconnectViolator(client) }
+
 		case req := <-h.process:
+			if !h.clients[req.client] { continue }
 			packet, err := wprotocol.Parse(req.data)
-			if err != nil { log.Printf("Error parsing packet from %s: %v", req.client.userID, err); continue }
-			h.usecase.ProcessIncomingPacket(context.Background(), req.client.userID, packet)
+			if err != nil {
+				hubLog.Warnf("Error parsing packet from %s: %v", req.client.userID, err)
+				if req.client.protocolError(err) { h.disconnectViolator(req.client) }
+				continue
+			}
+			if !h.admitPacket(req.client, packet) { continue }
+			h.dispatchPacket(req.client, packet)
 
 		case broadcastMsg := <-h.broadcast:
-			if roomClients, ok := h.rooms[broadcastMsg.RoomID]; ok {
-				for client := range roomClients { client.sendMessage(broadcastMsg.Message) }
-			}
+			h.flushDeliveries(broadcastMsg.RoomID)
+			h.doBroadcast(broadcastMsg)
+
+		case msg := <-h.deliveries:
+			h.coalesceDelivery(msg)
+
+		case roomID := <-h.deliveryFlushes:
+			h.endDeliveryWindow(roomID)
 
 		case directMsg := <-h.direct:
-			if client, ok := h.userClients[directMsg.UserID]; ok {
-				client.sendMessage(directMsg.Message)
+			for client := range h.userClients[directMsg.UserID] { client.sendMessage(directMsg.Message) }
+			h.bufferUserFrame(directMsg.UserID, directMsg.Message)
+			if h.cluster != nil && !directMsg.remote {
+				go h.forwardToUser(cluster.KindUser, directMsg.UserID, directMsg.Message)
 			}
 
 		case sub := <-h.subscribe:
-			if client, ok := h.userClients[sub.ClientUserID]; ok {
-				h.doSubscribe(client, sub.RoomID)
-			}
+			h.applySubscription(sub)
+
+		case req := <-h.resumes:
+			h.transferParked(req)
+
+		case reply := <-h.statsRequests:
+			reply <- h.stats()
+
+		case reply := <-h.snapshotRequests:
+			reply <- h.snapshot()
+
+		case userID := <-h.disconnects:
+			h.disconnectUser(userID)
+
+		case update := <-h.stateUpdates:
+			h.applyStateVersion(update)
+
+		case now := <-evictTicker.C:
+			h.evictParked(now)
+
+		case <-stateTicker.C:
+			h.stateHeartbeat()
+
+		case <-ctx.Done():
+			return
 		}
 	}
 }
 
+func (h *Hub) admitPacket(client *Client, packet *wprotocol.Packet) bool {
+	info, ok := wprotocol.Lookup(packet.Op)
+	if !ok {
+		return true
+	}
+	if info.Direction&wprotocol.ClientToServer == 0 || !info.SupportedBy(client.protocolVersion) {
+		hubLog.Warnf("Client %s (v%d) sent unsupported opcode %s", client.userID, client.protocolVersion, packet.Op)
+		client.sendMessage(encode.EncodeError("Unsupported opcode " + packet.Op.String()))
+		if client.protocolError(fmt.Errorf("unsupported opcode %s", packet.Op)) { h.disconnectViolator(client) }
+		return false
+	}
+	if h.doNotTrack && info.Tracking {
+		return false
+	}
+	if info.Deprecated && !client.warnedOps[packet.Op] {
+		client.warnedOps[packet.Op] = true
+		client.sendMessage(encode.EncodeDeprecated(packet.Op, info))
+	}
+	return true
+}
+
+// dropClient detaches client from every hub index and closes its queues. It
+// marks the client closed so frames still in flight for it (the rest of a
+// chunked payload, a room broadcast) are discarded rather than sent on the
+// closed channel.
+func (h *Hub) dropClient(client *Client) {
+	if client.closed { return }
+	client.closed = true
+	delete(h.clients, client)
+	if h.removeUserClient(client) {
+		if h.cluster != nil { go h.cluster.TrackDisconnect(context.Background(), client.userID) }
+	}
+	for roomID := range client.rooms { h.doUnsubscribe(client, roomID) }
+	close(client.send)
+	if client.packets != nil {
+		close(client.packets)
+		client.packets = nil
+	}
+}
+
+func (h *Hub) addUserClient(client *Client) {
+	if _, ok := h.userClients[client.userID]; !ok { h.userClients[client.userID] = make(map[*Client]bool) }
+	h.userClients[client.userID][client] = true
+}
+
+func (h *Hub) removeUserClient(client *Client) bool {
+	clients, ok := h.userClients[client.userID]
+	if !ok || !clients[client] { return false }
+	delete(clients, client)
+	if len(clients) > 0 { return false }
+	delete(h.userClients, client.userID)
+	delete(h.stateVersions, client.userID)
+	h.online.Delete(client.userID)
+	return true
+}
+
+func (h *Hub) applySubscription(sub *SubscriptionRequest) {
+	for client := range h.userClients[sub.ClientUserID] {
+		if sub.Unsubscribe {
+			h.doUnsubscribe(client, sub.RoomID)
+		} else {
+			h.doSubscribe(client, sub.RoomID)
+		}
+	}
+	h.updateParkedRooms(sub.ClientUserID, sub.RoomID, !sub.Unsubscribe)
+	if h.cluster != nil && !sub.remote {
+		kind := cluster.KindSubscribe
+		if sub.Unsubscribe { kind = cluster.KindUnsubscribe }
+		go h.forwardToUser(kind, sub.ClientUserID, []byte(sub.RoomID.String()))
+	}
+}
+
 func (h *Hub) doSubscribe(client *Client, roomID uuid.UUID) {
+	if client.closed { return }
 	if _, ok := h.rooms[roomID]; !ok { h.rooms[roomID] = make(map[*Client]bool) }
 	h.rooms[roomID][client] = true
 	client.rooms[roomID] = true
-	log.Printf("Client %s subscribed to room %s", client.userID, roomID)
+	hubLog.Debugf("Client %s subscribed to room %s", client.userID, roomID)
 }
 
 func (h *Hub) doUnsubscribe(client *Client, roomID uuid.UUID) {
@@ -103,9 +372,96 @@ func (h *Hub) doUnsubscribe(client *Client, roomID uuid.UUID) {
 		if len(room) == 0 { delete(h.rooms, roomID) }
 	}
 	delete(client.rooms, roomID)
-	log.Printf("Client %s unsubscribed from room %s", client.userID, roomID)
+	hubLog.Debugf("Client %s unsubscribed from room %s", client.userID, roomID)
 }
 
-func (h *Hub) BroadcastToRoom(roomID uuid.UUID, message []byte) { h.broadcast <- &BroadcastMessage{RoomID: roomID, Message: message} }
-func (h *Hub) SendToUser(userID uuid.UUID, message []byte) { h.direct <- &DirectMessage{UserID: userID, Message: message} }
-func (h *Hub) Subscribe(clientUserID uuid.UUID, roomID uuid.UUID) { h.subscribe <- &SubscriptionRequest{ClientUserID: clientUserID, RoomID: roomID} }
\ No newline at end of file
+func (h *Hub) trackConnect(userID uuid.UUID) {
+	ctx := context.Background()
+	if !h.admit(ctx) {
+		hubLog.Warnf("Admission queue full, tracking connection for %s without waiting", userID)
+		h.cluster.TrackConnect(ctx, userID)
+		return
+	}
+	defer h.leave()
+	h.cluster.TrackConnect(ctx, userID)
+}
+
+func (h *Hub) publish(env cluster.Envelope) {
+	if err := h.cluster.Publish(context.Background(), env); err != nil {
+		hubLog.Errorf("Error publishing %s envelope for %s: %v", env.Kind, env.Target, err)
+	}
+}
+
+func (h *Hub) forwardToUser(kind string, userID uuid.UUID, data []byte) {
+	instances, err := h.cluster.LocateUser(context.Background(), userID)
+	if err != nil {
+		hubLog.Errorf("Error locating user %s in cluster: %v", userID, err)
+		return
+	}
+	for _, instanceID := range instances {
+		if instanceID == h.cluster.ID() { continue }
+		h.publish(cluster.Envelope{Instance: instanceID, Kind: kind, Target: userID, Data: data})
+	}
+}
+
+func (h *Hub) deliverRemote(env cluster.Envelope) {
+	switch env.Kind {
+	case cluster.KindRoom:
+		h.broadcast <- &BroadcastMessage{RoomID: env.Target, Message: env.Data, remote: true}
+	case cluster.KindUser:
+		h.direct <- &DirectMessage{UserID: env.Target, Message: env.Data, remote: true}
+	case cluster.KindSubscribe, cluster.KindUnsubscribe:
+		roomID, err := uuid.Parse(string(env.Data))
+		if err != nil {
+			hubLog.Warnf("Invalid room ID in %s envelope from %s: %v", env.Kind, env.Origin, err)
+			return
+		}
+		h.subscribe <- &SubscriptionRequest{ClientUserID: env.Target, RoomID: roomID, Unsubscribe: env.Kind == cluster.KindUnsubscribe, remote: true}
+	case cluster.KindResume:
+		h.resumes <- &resumeRequest{sessionID: string(env.Data), userID: env.Target, requester: env.Origin}
+	case cluster.KindState:
+		h.remoteStateVersion(env)
+	case cluster.KindTyping:
+		h.remoteTyping(env)
+	}
+}
+
+func (h *Hub) suppressed(frame []byte) bool {
+	if !h.doNotTrack {
+		return false
+	}
+	op, ok := wprotocol.PeekOp(frame)
+	if !ok {
+		return false
+	}
+	info, ok := wprotocol.Lookup(op)
+	return ok && info.Tracking
+}
+
+func (h *Hub) doBroadcast(broadcastMsg *BroadcastMessage) {
+	if roomClients, ok := h.rooms[broadcastMsg.RoomID]; ok {
+		for client := range roomClients {
+			if client.userID != broadcastMsg.except { client.sendMessage(broadcastMsg.Message) }
+		}
+	}
+	if broadcastMsg.transient {
+		h.relayTransient(broadcastMsg)
+		return
+	}
+	h.bufferRoomFrame(broadcastMsg.RoomID, broadcastMsg.Message)
+	if h.cluster != nil && !broadcastMsg.remote {
+		go h.publish(cluster.Envelope{Kind: cluster.KindRoom, Target: broadcastMsg.RoomID, Data: broadcastMsg.Message})
+	}
+}
+
+func (h *Hub) BroadcastToRoom(roomID uuid.UUID, message []byte) {
+	if h.suppressed(message) { return }
+	h.broadcast <- &BroadcastMessage{RoomID: roomID, Message: message}
+}
+
+func (h *Hub) SendToUser(userID uuid.UUID, message []byte) {
+	if h.suppressed(message) { return }
+	h.direct <- &DirectMessage{UserID: userID, Message: message}
+}
+func (h *Hub) Subscribe(clientUserID uuid.UUID, roomID uuid.UUID) { h.subscribe <- &SubscriptionRequest{ClientUserID: clientUserID, RoomID: roomID} }
+func (h *Hub) Unsubscribe(clientUserID uuid.UUID, roomID uuid.UUID) { h.subscribe <- &SubscriptionRequest{ClientUserID: clientUserID, RoomID: roomID, Unsubscribe: true} }
\ No newline at end of file
@@ -0,0 +1,109 @@
+package websocket
+
+import (
+	"sync/atomic"
+	"time"
+
+	"chatservice/internal/encode"
+	"chatservice/pkg/wprotocol"
+)
+
+const (
+	defaultPacketQueue = 64
+	serverBusyCode     = "server_busy"
+)
+
+type packetCounters struct {
+	inFlight  atomic.Int64
+	waiting   atomic.Int64
+	waits     atomic.Int64
+	waitNanos atomic.Int64
+	maxWait   atomic.Int64
+	dropped   atomic.Int64
+}
+
+type PacketStats struct {
+	Limit     int     `json:"limit"`
+	InFlight  int64   `json:"inFlight"`
+	Waiting   int64   `json:"waiting"`
+	Queued    int64   `json:"queued"`
+	AvgWaitMs float64 `json:"avgWaitMs"`
+	MaxWaitMs float64 `json:"maxWaitMs"`
+	Dropped   int64   `json:"dropped"`
+}
+
+func (h *Hub) SetPacketConcurrency(limit, queue int) {
+	if limit > 0 {
+		h.packetSlots = make(chan struct{}, limit)
+	}
+	h.packetQueue = defaultPacketQueue
+	if queue > 0 {
+		h.packetQueue = queue
+	}
+}
+
+func (h *Hub) dispatchPacket(client *Client, packet *wprotocol.Packet) {
+	if h.packetSlots == nil {
+		h.usecase.ProcessIncomingPacket(client.context(), client.userID, packet)
+		return
+	}
+	if client.packets == nil {
+		client.packets = make(chan *wprotocol.Packet, h.packetQueue)
+		go h.runPackets(client, client.packets)
+	}
+	select {
+	case client.packets <- packet:
+	default:
+		h.packetStats.dropped.Add(1)
+		hubLog.Warnf("Packet queue full for %s, dropping %s", client.userID, packet.Op)
+		client.sendMessage(encode.EncodeErrorCode(serverBusyCode, "Too many pending requests, slow down"))
+	}
+}
+
+func (h *Hub) runPackets(client *Client, packets <-chan *wprotocol.Packet) {
+	for packet := range packets {
+		h.acquirePacketSlot()
+		h.usecase.ProcessIncomingPacket(client.context(), client.userID, packet)
+		h.packetStats.inFlight.Add(-1)
+		<-h.packetSlots
+	}
+}
+
+func (h *Hub) acquirePacketSlot() {
+	select {
+	case h.packetSlots <- struct{}{}:
+	default:
+		start := time.Now()
+		h.packetStats.waiting.Add(1)
+		h.packetSlots <- struct{}{}
+		h.packetStats.waiting.Add(-1)
+
+		wait := int64(time.Since(start))
+		h.packetStats.waits.Add(1)
+		h.packetStats.waitNanos.Add(wait)
+		for {
+			longest := h.packetStats.maxWait.Load()
+			if wait <= longest || h.packetStats.maxWait.CompareAndSwap(longest, wait) {
+				break
+			}
+		}
+	}
+	h.packetStats.inFlight.Add(1)
+}
+
+func (h *Hub) packetStatsSnapshot() PacketStats {
+	stats := PacketStats{
+		Limit:     cap(h.packetSlots),
+		InFlight:  h.packetStats.inFlight.Load(),
+		Waiting:   h.packetStats.waiting.Load(),
+		MaxWaitMs: float64(h.packetStats.maxWait.Load()) / float64(time.Millisecond),
+		Dropped:   h.packetStats.dropped.Load(),
+	}
+	if waits := h.packetStats.waits.Load(); waits > 0 {
+		stats.AvgWaitMs = float64(h.packetStats.waitNanos.Load()) / float64(waits) / float64(time.Millisecond)
+	}
+	for client := range h.clients {
+		stats.Queued += int64(len(client.packets))
+	}
+	return stats
+}
@@ -0,0 +1,22 @@
+package websocket
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"chatservice/pkg/wprotocol/record"
+
+	"github.com/google/uuid"
+)
+
+func (h *Hub) newRecorder(userID uuid.UUID, sessionID string) *record.Recorder {
+	path := filepath.Join(h.recordDir, fmt.Sprintf("%s-%s.jsonl", userID, sessionID))
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		hubLog.Errorf("Could not start recording session %s: %v", sessionID, err)
+		return nil
+	}
+	hubLog.Infof("Recording redacted protocol traffic of session %s to %s", sessionID, path)
+	return record.NewRecorder(out)
+}
@@ -0,0 +1,211 @@
+package websocket
+
+import (
+	"context"
+	"maps"
+	"strings"
+	"time"
+
+	"chatservice/internal/cluster"
+	"chatservice/internal/encode"
+
+	"github.com/google/uuid"
+)
+
+const (
+	resumeWindow    = 2 * time.Minute
+	maxParkedFrames = 256
+	localInstanceID = "local"
+
+	resyncOverflow = "overflow"
+	resyncRestart  = "restart"
+)
+
+type parkedSession struct {
+	userID    uuid.UUID
+	rooms     map[uuid.UUID]bool
+	cursors   map[uuid.UUID]int64
+	frames    [][]byte
+	expiresAt time.Time
+	// overflowed is set once more frames arrived than fit the buffer. The
+	// replay would have a gap, so the client is told to resync instead.
+	overflowed bool
+	// restored sessions come from a snapshot; frames published while the
+	// instance was down were never buffered, so they always resync.
+	restored bool
+}
+
+type resumeRequest struct {
+	sessionID string
+	userID    uuid.UUID
+	requester string
+}
+
+func (p *parkedSession) push(frame []byte) {
+	if p.overflowed {
+		return
+	}
+	if len(p.frames) >= maxParkedFrames {
+		p.overflowed = true
+		p.frames = nil
+		return
+	}
+	p.frames = append(p.frames, frame)
+}
+
+// replay returns the frames to deliver on resume, ending with the frame
+// that tells the client whether its view is complete.
+func (p *parkedSession) replay() [][]byte {
+	if p.overflowed {
+		return [][]byte{encode.EncodeSessionResync(resyncOverflow, p.resumeCursors(nil))}
+	}
+	if p.restored {
+		return append(p.frames, encode.EncodeSessionResync(resyncRestart, p.resumeCursors(p.frames)))
+	}
+	return append(p.frames, encode.EncodeSessionResumed(len(p.frames)))
+}
+
+// resumeCursors returns the cursor of every parked room once the given
+// frames have been replayed on top of those the client already had.
+func (p *parkedSession) resumeCursors(frames [][]byte) map[uuid.UUID]int64 {
+	cursors := make(map[uuid.UUID]int64, len(p.rooms))
+	for roomID := range p.rooms {
+		cursors[roomID] = p.cursors[roomID]
+	}
+	for _, frame := range frames {
+		advanceCursor(cursors, frame)
+	}
+	return cursors
+}
+
+func advanceCursor(cursors map[uuid.UUID]int64, frame []byte) {
+	if roomID, messageID, ok := encode.DeliveredMessage(frame); ok && messageID > cursors[roomID] {
+		cursors[roomID] = messageID
+	}
+}
+
+func (h *Hub) instanceID() string {
+	if h.cluster != nil {
+		return h.cluster.ID()
+	}
+	return localInstanceID
+}
+
+func (h *Hub) resumeToken(sessionID string) string {
+	return sessionID + "@" + h.instanceID()
+}
+
+func parseResumeToken(token string) (sessionID, instanceID string, ok bool) {
+	sessionID, instanceID, ok = strings.Cut(token, "@")
+	return sessionID, instanceID, ok && sessionID != "" && instanceID != ""
+}
+
+func (h *Hub) park(client *Client) {
+	rooms := make(map[uuid.UUID]bool, len(client.rooms))
+	for roomID := range client.rooms {
+		rooms[roomID] = true
+	}
+	h.parked[client.sessionID] = &parkedSession{
+		userID:    client.userID,
+		rooms:     rooms,
+		cursors:   maps.Clone(client.cursors),
+		expiresAt: time.Now().Add(resumeWindow),
+	}
+}
+
+func (h *Hub) bufferRoomFrame(roomID uuid.UUID, frame []byte) {
+	for _, session := range h.parked {
+		if session.rooms[roomID] {
+			session.push(frame)
+		}
+	}
+}
+
+func (h *Hub) bufferUserFrame(userID uuid.UUID, frame []byte) {
+	for _, session := range h.parked {
+		if session.userID == userID {
+			session.push(frame)
+		}
+	}
+}
+
+func (h *Hub) updateParkedRooms(userID, roomID uuid.UUID, subscribed bool) {
+	for _, session := range h.parked {
+		if session.userID != userID {
+			continue
+		}
+		if subscribed {
+			session.rooms[roomID] = true
+		} else {
+			delete(session.rooms, roomID)
+		}
+	}
+}
+
+func (h *Hub) evictParked(now time.Time) {
+	for sessionID, session := range h.parked {
+		if now.After(session.expiresAt) {
+			delete(h.parked, sessionID)
+		}
+	}
+}
+
+func (h *Hub) takeParked(sessionID string, userID uuid.UUID) *parkedSession {
+	session, ok := h.parked[sessionID]
+	if !ok || session.userID != userID || time.Now().After(session.expiresAt) {
+		return nil
+	}
+	delete(h.parked, sessionID)
+	return session
+}
+
+func (h *Hub) resume(client *Client) {
+	sessionID, instanceID, ok := parseResumeToken(client.resumeToken)
+	if !ok {
+		hubLog.Warnf("Client %s sent malformed resume token", client.userID)
+		return
+	}
+
+	if instanceID == h.instanceID() {
+		session := h.takeParked(sessionID, client.userID)
+		if session == nil {
+			hubLog.Debugf("No resumable session %s for user %s", sessionID, client.userID)
+			return
+		}
+		for _, frame := range session.replay() {
+			client.sendMessage(frame)
+		}
+		if session.overflowed || session.restored {
+			hubLog.Infof("Session %s for user %s has a gap in its buffer, requesting resync", sessionID, client.userID)
+			return
+		}
+		hubLog.Debugf("Resumed session %s for user %s with %d buffered frames", sessionID, client.userID, len(session.frames))
+		return
+	}
+
+	if h.cluster == nil {
+		return
+	}
+	go h.publish(cluster.Envelope{Instance: instanceID, Kind: cluster.KindResume, Target: client.userID, Data: []byte(sessionID)})
+}
+
+func (h *Hub) transferParked(req *resumeRequest) {
+	session := h.takeParked(req.sessionID, req.userID)
+	if session == nil {
+		hubLog.Warnf("Remote resume from %s for unknown session %s", req.requester, req.sessionID)
+		return
+	}
+	frames := session.replay()
+	go func() {
+		if h.admit(context.Background()) {
+			defer h.leave()
+		}
+		for _, frame := range frames {
+			env := cluster.Envelope{Instance: req.requester, Kind: cluster.KindUser, Target: req.userID, Data: frame}
+			if err := h.cluster.Publish(context.Background(), env); err != nil {
+				hubLog.Errorf("Error transferring buffered frame for session %s: %v", req.sessionID, err)
+			}
+		}
+		hubLog.Debugf("Transferred session %s for user %s to instance %s", req.sessionID, req.userID, req.requester)
+	}()
+}
@@ -0,0 +1,138 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type hubSnapshot struct {
+	Instance string            `json:"instance"`
+	SavedAt  time.Time         `json:"savedAt"`
+	Sessions []snapshotSession `json:"sessions"`
+}
+
+type snapshotSession struct {
+	SessionID  string              `json:"sessionId"`
+	UserID     uuid.UUID           `json:"userId"`
+	Rooms      []uuid.UUID         `json:"rooms"`
+	Cursors    map[uuid.UUID]int64 `json:"cursors,omitempty"`
+	Frames     [][]byte            `json:"frames,omitempty"`
+	Overflowed bool                `json:"overflowed,omitempty"`
+}
+
+func (h *Hub) SetSnapshotPath(path string) { h.snapshotPath = path }
+
+func (h *Hub) SaveSnapshot(ctx context.Context) error {
+	if h.snapshotPath == "" {
+		return nil
+	}
+	reply := make(chan hubSnapshot, 1)
+	select {
+	case h.snapshotRequests <- reply:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	var snapshot hubSnapshot
+	select {
+	case snapshot = <-reply:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	tmp := h.snapshotPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, h.snapshotPath); err != nil {
+		return err
+	}
+	hubLog.Infof("Saved %d sessions to hub snapshot %s", len(snapshot.Sessions), h.snapshotPath)
+	return nil
+}
+
+func (h *Hub) snapshot() hubSnapshot {
+	snapshot := hubSnapshot{Instance: h.instanceID(), SavedAt: time.Now()}
+	for client := range h.clients {
+		snapshot.Sessions = append(snapshot.Sessions, snapshotSession{
+			SessionID: client.sessionID,
+			UserID:    client.userID,
+			Rooms:     roomIDs(client.rooms),
+			Cursors:   maps.Clone(client.cursors),
+		})
+	}
+	for sessionID, session := range h.parked {
+		snapshot.Sessions = append(snapshot.Sessions, snapshotSession{
+			SessionID:  sessionID,
+			UserID:     session.userID,
+			Rooms:      roomIDs(session.rooms),
+			Cursors:    maps.Clone(session.cursors),
+			Frames:     session.frames,
+			Overflowed: session.overflowed,
+		})
+	}
+	return snapshot
+}
+
+func (h *Hub) RestoreSnapshot() error {
+	if h.snapshotPath == "" {
+		return nil
+	}
+	data, err := os.ReadFile(h.snapshotPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer os.Remove(h.snapshotPath)
+
+	var snapshot hubSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return err
+	}
+	if snapshot.Instance != h.instanceID() {
+		hubLog.Warnf("Ignoring hub snapshot of instance %s, this is %s", snapshot.Instance, h.instanceID())
+		return nil
+	}
+	if time.Since(snapshot.SavedAt) > resumeWindow {
+		hubLog.Infof("Ignoring hub snapshot saved at %s, sessions are past the resume window", snapshot.SavedAt.Format(time.RFC3339))
+		return nil
+	}
+
+	expiresAt := time.Now().Add(resumeWindow)
+	for _, session := range snapshot.Sessions {
+		rooms := make(map[uuid.UUID]bool, len(session.Rooms))
+		for _, roomID := range session.Rooms {
+			rooms[roomID] = true
+		}
+		h.parked[session.SessionID] = &parkedSession{
+			userID:     session.UserID,
+			rooms:      rooms,
+			cursors:    session.Cursors,
+			frames:     session.Frames,
+			expiresAt:  expiresAt,
+			overflowed: session.Overflowed,
+			restored:   true,
+		}
+	}
+	hubLog.Infof("Restored %d resumable sessions from hub snapshot", len(snapshot.Sessions))
+	return nil
+}
+
+func roomIDs(rooms map[uuid.UUID]bool) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(rooms))
+	for roomID := range rooms {
+		ids = append(ids, roomID)
+	}
+	return ids
+}
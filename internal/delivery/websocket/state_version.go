@@ -0,0 +1,79 @@
+package websocket
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"chatservice/internal/cluster"
+	"chatservice/internal/encode"
+
+	"github.com/google/uuid"
+)
+
+const stateHeartbeatInterval = 30 * time.Second
+
+type stateVersionUpdate struct {
+	userID  uuid.UUID
+	version int64
+	remote  bool
+}
+
+func (h *Hub) SetStateVersion(userID uuid.UUID, version int64) {
+	h.stateUpdates <- stateVersionUpdate{userID: userID, version: version}
+}
+
+func (h *Hub) applyStateVersion(update stateVersionUpdate) {
+	if h.cluster != nil && !update.remote {
+		go h.forwardToUser(cluster.KindState, update.userID, []byte(strconv.FormatInt(update.version, 10)))
+	}
+	clients, ok := h.userClients[update.userID]
+	if !ok {
+		return
+	}
+	if known, seen := h.stateVersions[update.userID]; seen && update.version <= known {
+		return
+	}
+	h.stateVersions[update.userID] = update.version
+	frame := encode.EncodeStateVersion(update.version)
+	for client := range clients {
+		client.sendMessage(frame)
+	}
+}
+
+func (h *Hub) sendStateVersion(client *Client) {
+	if version, ok := h.stateVersions[client.userID]; ok {
+		client.sendMessage(encode.EncodeStateVersion(version))
+		return
+	}
+	go h.loadStateVersion(client.userID)
+}
+
+func (h *Hub) loadStateVersion(userID uuid.UUID) {
+	versions, err := h.repo.GetStateVersions(context.Background(), []uuid.UUID{userID})
+	if err != nil {
+		hubLog.Errorf("Error loading state version for %s: %v", userID, err)
+		return
+	}
+	if version, ok := versions[userID]; ok {
+		h.stateUpdates <- stateVersionUpdate{userID: userID, version: version, remote: true}
+	}
+}
+
+func (h *Hub) stateHeartbeat() {
+	for userID, version := range h.stateVersions {
+		frame := encode.EncodeStateVersion(version)
+		for client := range h.userClients[userID] {
+			client.sendMessage(frame)
+		}
+	}
+}
+
+func (h *Hub) remoteStateVersion(env cluster.Envelope) {
+	version, err := strconv.ParseInt(string(env.Data), 10, 64)
+	if err != nil {
+		hubLog.Warnf("Invalid state version in envelope from %s: %v", env.Origin, err)
+		return
+	}
+	h.stateUpdates <- stateVersionUpdate{userID: env.Target, version: version, remote: true}
+}
@@ -0,0 +1,35 @@
+package websocket
+
+import (
+	"chatservice/internal/cluster"
+	"chatservice/pkg/wprotocol"
+
+	"github.com/google/uuid"
+)
+
+func (h *Hub) broadcastTyping(roomID, userID uuid.UUID, frame []byte) {
+	if h.suppressed(frame) {
+		return
+	}
+	h.broadcast <- &BroadcastMessage{RoomID: roomID, Message: frame, except: userID, transient: true}
+}
+
+func (h *Hub) relayTransient(msg *BroadcastMessage) {
+	if h.cluster != nil && !msg.remote {
+		go h.publish(cluster.Envelope{Kind: cluster.KindTyping, Target: msg.RoomID, Data: msg.Message})
+	}
+}
+
+func (h *Hub) remoteTyping(env cluster.Envelope) {
+	packet, err := wprotocol.Parse(env.Data)
+	if err != nil || len(packet.Payload) < 2 {
+		hubLog.Warnf("Invalid typing frame in envelope from %s: %v", env.Origin, err)
+		return
+	}
+	userID, err := uuid.Parse(packet.Payload[1])
+	if err != nil {
+		hubLog.Warnf("Invalid user ID in typing envelope from %s: %v", env.Origin, err)
+		return
+	}
+	h.broadcast <- &BroadcastMessage{RoomID: env.Target, Message: env.Data, except: userID, transient: true, remote: true}
+}
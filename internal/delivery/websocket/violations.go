@@ -0,0 +1,54 @@
+package websocket
+
+import (
+	"sync"
+	"time"
+
+	"chatservice/internal/encode"
+)
+
+const protocolViolationCode = "protocol_violation"
+
+type violationCounter struct {
+	mu          sync.Mutex
+	count       int
+	windowStart time.Time
+}
+
+func (h *Hub) SetProtocolErrorLimit(limit int, window time.Duration) {
+	h.maxProtocolErrors = limit
+	h.protocolErrorWindow = window
+}
+
+// protocolError counts a malformed packet against the client and reports
+// whether it has just reached the limit. It may run on the read pump, so it
+// never touches the send queue; the hub disconnects the client instead.
+func (c *Client) protocolError(err error) bool {
+	limit, window := c.hub.maxProtocolErrors, c.hub.protocolErrorWindow
+	if limit <= 0 {
+		return false
+	}
+
+	c.violations.mu.Lock()
+	defer c.violations.mu.Unlock()
+	now := time.Now()
+	if now.Sub(c.violations.windowStart) > window {
+		c.violations.windowStart = now
+		c.violations.count = 0
+	}
+	c.violations.count++
+	if c.violations.count != limit {
+		return false
+	}
+	hubLog.Warnf("Disconnecting %s after %d protocol errors within %s, last: %v", c.userID, limit, window, err)
+	return true
+}
+
+func (h *Hub) disconnectViolator(client *Client) {
+	if client.closed {
+		return
+	}
+	client.enqueue(encode.EncodeErrorCode(protocolViolationCode, "Too many malformed packets"))
+	client.closeReason = protocolViolationCode
+	h.dropClient(client)
+}
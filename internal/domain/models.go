@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"bytes"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,9 +13,22 @@ type User struct {
 	Email     string    `json:"email" db:"email"`
 	Username  string    `json:"username" db:"username"`
 	Nickname  string    `json:"nickname" db:"nickname"`
+	Badges    []string  `json:"badges,omitempty" db:"badges"`
 	CreatedAt time.Time `json:"createdAt" db:"created_at"`
 }
 
+type LinkedIdentity struct {
+	Provider string    `json:"provider" db:"provider"`
+	Login    string    `json:"login,omitempty" db:"login"`
+	LinkedAt time.Time `json:"linkedAt" db:"linked_at"`
+}
+
+const (
+	UserBadgeVerified = "verified"
+	UserBadgeBot      = "bot"
+	UserBadgeStaff    = "staff"
+)
+
 type Friendship struct {
 	UserOneID    uuid.UUID `json:"user_one_id" db:"user_one_id"`
 	UserTwoID    uuid.UUID `json:"user_two_id" db:"user_two_id"`
@@ -24,9 +39,12 @@ type Friendship struct {
 }
 
 type Friend struct {
-	ID       uuid.UUID `json:"id"`
-	Nickname string    `json:"nickname"`
-	RoomID   uuid.UUID `json:"roomId"`
+	ID                uuid.UUID  `json:"id"`
+	Nickname          string     `json:"nickname"`
+	RoomID            uuid.UUID  `json:"roomId"`
+	Online            bool       `json:"online"`
+	Badges            []string   `json:"badges,omitempty"`
+	LastInteractionAt *time.Time `json:"lastInteractionAt,omitempty"`
 }
 
 type FriendRequest struct {
@@ -35,10 +53,15 @@ type FriendRequest struct {
 }
 
 
-func NewFriendship(userOneID, userTwoID uuid.UUID, status string, actionUserID uuid.UUID) *Friendship {
-	if userOneID.String() > userTwoID.String() {
-		userOneID, userTwoID = userTwoID, userOneID
+func OrderFriendPair(a, b uuid.UUID) (uuid.UUID, uuid.UUID) {
+	if bytes.Compare(a[:], b[:]) > 0 {
+		return b, a
 	}
+	return a, b
+}
+
+func NewFriendship(userOneID, userTwoID uuid.UUID, status string, actionUserID uuid.UUID) *Friendship {
+	userOneID, userTwoID = OrderFriendPair(userOneID, userTwoID)
 	return &Friendship{
 		UserOneID:    userOneID,
 		UserTwoID:    userTwoID,
@@ -56,6 +79,10 @@ type Room struct {
 	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
 	LastMessageContent    *string    `json:"lastMessageContent,omitempty" db:"last_message_content"`
 	LastMessageCreatedAt *time.Time `json:"lastMessageCreatedAt,omitempty" db:"last_message_created_at"`
+	UnreadMessages       int        `json:"unreadMessages" db:"unread_messages"`
+	UnreadMentions       int        `json:"unreadMentions" db:"unread_mentions"`
+	SnoozedUntil         *time.Time `json:"snoozedUntil,omitempty" db:"snoozed_until"`
+	State                string     `json:"state,omitempty" db:"state"`
 }
 
 type Message struct {
@@ -64,8 +91,479 @@ type Message struct {
 	RoomID           uuid.UUID  `json:"room_id" db:"room_id"`
 	UserID           uuid.UUID  `json:"user_id" db:"user_id"`
 	Content          string     `json:"content" db:"content"`
+	Kind             string     `json:"kind" db:"kind"`
+	ContentType      string     `json:"content_type" db:"content_type"`
+	RichContent      json.RawMessage `json:"rich_content,omitempty" db:"rich_content"`
+	Links            []string   `json:"links,omitempty" db:"links"`
+	Hashtags         []string   `json:"hashtags,omitempty" db:"hashtags"`
+	GroupMentions    []string   `json:"groupMentions,omitempty" db:"group_mentions"`
+	Metadata         json.RawMessage `json:"metadata,omitempty" db:"metadata"`
+	AttachmentID     *uuid.UUID `json:"attachment_id,omitempty" db:"attachment_id"`
 	ReplyToMessageID *int64     `json:"reply_to_message_id,omitempty" db:"reply_to_message_id"`
 	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt        *time.Time `json:"updated_at,omitempty" db:"updated_at"`
 	DeletedAt        *time.Time `json:"-" db:"deleted_at"`
-}
\ No newline at end of file
+	Mentions         []uuid.UUID `json:"mentions,omitempty" db:"-"`
+	SenderBadges     []string   `json:"senderBadges,omitempty" db:"-"`
+	Translations     map[string]string `json:"translations,omitempty" db:"-"`
+}
+
+type MessageAction struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+	Style string `json:"style,omitempty"`
+}
+
+const (
+	MessageKindText       = "text"
+	MessageKindMissedCall = "missed_call"
+	MessageKindAttachment = "attachment"
+	MessageKindAutoReply  = "auto_reply"
+	MessageKindSystem     = "system"
+)
+
+const (
+	MessagePriorityNormal = "normal"
+	MessagePriorityUrgent = "urgent"
+)
+
+func IsUrgentMessage(metadata json.RawMessage) bool {
+	if len(metadata) == 0 {
+		return false
+	}
+	var fields struct {
+		Priority string `json:"priority"`
+	}
+	return json.Unmarshal(metadata, &fields) == nil && fields.Priority == MessagePriorityUrgent
+}
+
+const (
+	MentionEveryone = "everyone"
+	MentionAdmins   = "admins"
+)
+
+const (
+	ContentTypePlain    = "plain"
+	ContentTypeMarkdown = "markdown"
+	ContentTypeRich     = "rich"
+)
+
+const (
+	DeliveryStatusRetrying = "retrying"
+	DeliveryStatusDead     = "dead"
+)
+
+type FailedDelivery struct {
+	ID            uuid.UUID       `json:"id" db:"id"`
+	Kind          string          `json:"kind" db:"kind"`
+	Target        string          `json:"target" db:"target"`
+	Payload       json.RawMessage `json:"payload" db:"payload"`
+	Status        string          `json:"status" db:"status"`
+	Attempts      int             `json:"attempts" db:"attempts"`
+	LastError     string          `json:"lastError" db:"last_error"`
+	NextAttemptAt time.Time       `json:"nextAttemptAt" db:"next_attempt_at"`
+	CreatedAt     time.Time       `json:"createdAt" db:"created_at"`
+	UpdatedAt     time.Time       `json:"updatedAt" db:"updated_at"`
+}
+
+type DailyActivity struct {
+	Day             string `json:"day" db:"day"`
+	MessagesSent    int    `json:"messagesSent" db:"messages_sent"`
+	Responses       int    `json:"-" db:"responses"`
+	ResponseSeconds int64  `json:"-" db:"response_seconds"`
+}
+
+type RoomActivity struct {
+	RoomID                 uuid.UUID `json:"roomId" db:"room_id"`
+	Name                   *string   `json:"name,omitempty" db:"name"`
+	MessagesSent           int       `json:"messagesSent" db:"messages_sent"`
+	Responses              int       `json:"-" db:"responses"`
+	ResponseSeconds        int64     `json:"-" db:"response_seconds"`
+	AverageResponseSeconds *float64  `json:"averageResponseSeconds,omitempty" db:"-"`
+}
+
+type UserInsights struct {
+	Days                   int             `json:"days"`
+	MessagesSent           int             `json:"messagesSent"`
+	AverageResponseSeconds *float64        `json:"averageResponseSeconds,omitempty"`
+	Daily                  []DailyActivity `json:"daily"`
+	TopRooms               []RoomActivity  `json:"topRooms"`
+}
+
+type RoomTag struct {
+	Tag          string    `json:"tag" db:"tag"`
+	MessageCount int       `json:"messageCount" db:"message_count"`
+	LastUsedAt   time.Time `json:"lastUsedAt" db:"last_used_at"`
+}
+
+type JobStatus struct {
+	Name           string     `json:"name" db:"name"`
+	Interval       string     `json:"interval" db:"-"`
+	IntervalSecs   int        `json:"-" db:"interval_seconds"`
+	NextRunAt      time.Time  `json:"nextRunAt" db:"next_run_at"`
+	LockedBy       *string    `json:"lockedBy,omitempty" db:"locked_by"`
+	LockedUntil    *time.Time `json:"lockedUntil,omitempty" db:"locked_until"`
+	LastStartedAt  *time.Time `json:"lastStartedAt,omitempty" db:"last_started_at"`
+	LastFinishedAt *time.Time `json:"lastFinishedAt,omitempty" db:"last_finished_at"`
+	LastStatus     string     `json:"lastStatus" db:"last_status"`
+	LastError      string     `json:"lastError,omitempty" db:"last_error"`
+	RunCount       int64      `json:"runCount" db:"run_count"`
+}
+
+type SentPush struct {
+	NotificationID uuid.UUID `db:"notification_id"`
+	UserID         uuid.UUID `db:"user_id"`
+	RoomID         uuid.UUID `db:"room_id"`
+	MessageID      int64     `db:"message_id"`
+	Preview        string    `db:"preview"`
+	SentAt         time.Time `db:"sent_at"`
+}
+
+type BadgeCounts struct {
+	UnreadMessages int `json:"unreadMessages"`
+	UnreadMentions int `json:"unreadMentions"`
+	MissedCalls    int `json:"missedCalls"`
+}
+
+type UserSettings struct {
+	UserID             uuid.UUID `json:"-" db:"user_id"`
+	EmailNotifications bool      `json:"emailNotifications" db:"email_notifications"`
+	PushPreviews       bool      `json:"pushPreviews" db:"push_previews"`
+	Language           string    `json:"language" db:"language"`
+	SmartReplies       bool      `json:"smartReplies" db:"smart_replies"`
+	UpdatedAt          time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+func DefaultUserSettings(userID uuid.UUID) *UserSettings {
+	return &UserSettings{UserID: userID, EmailNotifications: true, PushPreviews: true}
+}
+
+type CallParticipantState struct {
+	UserID        uuid.UUID `json:"userId"`
+	Muted         bool      `json:"muted"`
+	CameraOn      bool      `json:"cameraOn"`
+	ScreenSharing bool      `json:"screenSharing"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+const (
+	AttachmentKindCallRecording = "call_recording"
+	AttachmentKindUpload        = "upload"
+)
+
+type Attachment struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	RoomID      uuid.UUID `json:"roomId" db:"room_id"`
+	UploaderID  uuid.UUID `json:"uploaderId" db:"uploader_id"`
+	Kind        string    `json:"kind" db:"kind"`
+	StorageURL  string    `json:"url" db:"storage_url"`
+	ContentType string    `json:"contentType" db:"content_type"`
+	SizeBytes   int64     `json:"sizeBytes" db:"size_bytes"`
+	CreatedAt   time.Time `json:"createdAt" db:"created_at"`
+}
+
+type AwayStatus struct {
+	UserID    uuid.UUID  `json:"-" db:"user_id"`
+	Message   string     `json:"message" db:"message"`
+	StartsAt  *time.Time `json:"startsAt,omitempty" db:"starts_at"`
+	EndsAt    *time.Time `json:"endsAt,omitempty" db:"ends_at"`
+	UpdatedAt time.Time  `json:"updatedAt" db:"updated_at"`
+}
+
+type ShareLink struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	RoomID         uuid.UUID  `json:"roomId" db:"room_id"`
+	CreatedBy      uuid.UUID  `json:"createdBy" db:"created_by"`
+	Title          *string    `json:"title,omitempty" db:"title"`
+	FirstMessageID int64      `json:"firstMessageId" db:"first_message_id"`
+	LastMessageID  int64      `json:"lastMessageId" db:"last_message_id"`
+	ExpiresAt      time.Time  `json:"expiresAt" db:"expires_at"`
+	RevokedAt      *time.Time `json:"revokedAt,omitempty" db:"revoked_at"`
+	CreatedAt      time.Time  `json:"createdAt" db:"created_at"`
+	AccessCount    int        `json:"accessCount" db:"access_count"`
+	LastAccessedAt *time.Time `json:"lastAccessedAt,omitempty" db:"last_accessed_at"`
+}
+
+type SharedMessage struct {
+	ID        int64     `json:"id" db:"id"`
+	Author    string    `json:"author" db:"author"`
+	Content   string    `json:"content" db:"content"`
+	Kind      string    `json:"kind" db:"kind"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+type WidgetKey struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	RoomID    uuid.UUID  `json:"roomId" db:"room_id"`
+	Prefix    string     `json:"prefix" db:"key_prefix"`
+	Label     string     `json:"label" db:"label"`
+	CreatedBy uuid.UUID  `json:"createdBy" db:"created_by"`
+	CreatedAt time.Time  `json:"createdAt" db:"created_at"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty" db:"revoked_at"`
+}
+
+type WidgetGuest struct {
+	UserID      uuid.UUID `json:"guestId" db:"user_id"`
+	KeyID       uuid.UUID `json:"-" db:"key_id"`
+	RoomID      uuid.UUID `json:"roomId" db:"room_id"`
+	DisplayName string    `json:"displayName" db:"display_name"`
+	CreatedAt   time.Time `json:"createdAt" db:"created_at"`
+	LastSeenAt  time.Time `json:"lastSeenAt" db:"last_seen_at"`
+}
+
+const (
+	RoomStateOpen     = "open"
+	RoomStateResolved = "resolved"
+	RoomStateClosed   = "closed"
+)
+
+type RoomStateChange struct {
+	RoomID    uuid.UUID `json:"roomId"`
+	State     string    `json:"state"`
+	Previous  string    `json:"previous"`
+	ChangedBy uuid.UUID `json:"changedBy"`
+	ChangedAt time.Time `json:"changedAt"`
+}
+
+type SupportAgent struct {
+	UserID              uuid.UUID `json:"userId" db:"user_id"`
+	Nickname            *string   `json:"nickname,omitempty" db:"nickname"`
+	Available           bool      `json:"available" db:"available"`
+	ActiveConversations int       `json:"activeConversations" db:"active_conversations"`
+	CreatedAt           time.Time `json:"createdAt" db:"created_at"`
+}
+
+type SupportConversation struct {
+	RoomID     uuid.UUID  `json:"roomId" db:"room_id"`
+	CustomerID uuid.UUID  `json:"customerId" db:"customer_id"`
+	AgentID    *uuid.UUID `json:"agentId,omitempty" db:"agent_id"`
+	Status     string     `json:"status" db:"status"`
+	Subject    string     `json:"subject" db:"subject"`
+	CreatedAt  time.Time  `json:"createdAt" db:"created_at"`
+	AssignedAt *time.Time `json:"assignedAt,omitempty" db:"assigned_at"`
+	UpdatedAt  time.Time  `json:"updatedAt" db:"updated_at"`
+
+	FirstResponseAt         *time.Time `json:"firstResponseAt,omitempty" db:"first_response_at"`
+	ResolvedAt              *time.Time `json:"resolvedAt,omitempty" db:"resolved_at"`
+	FirstResponseBreachedAt *time.Time `json:"firstResponseBreachedAt,omitempty" db:"first_response_breached_at"`
+	ResolutionBreachedAt    *time.Time `json:"resolutionBreachedAt,omitempty" db:"resolution_breached_at"`
+}
+
+type SupportSLABreach struct {
+	RoomID     uuid.UUID  `db:"room_id"`
+	AgentID    *uuid.UUID `db:"agent_id"`
+	Kind       string     `db:"kind"`
+	CreatedAt  time.Time  `db:"created_at"`
+	BreachedAt time.Time  `db:"breached_at"`
+}
+
+type SupportSLASummary struct {
+	Since                   time.Time `json:"since" db:"-"`
+	Conversations           int       `json:"conversations" db:"conversations"`
+	Queued                  int       `json:"queued" db:"queued"`
+	Open                    int       `json:"open" db:"open"`
+	Resolved                int       `json:"resolved" db:"resolved"`
+	AvgFirstResponseSeconds *float64  `json:"avgFirstResponseSeconds,omitempty" db:"avg_first_response_seconds"`
+	AvgResolutionSeconds    *float64  `json:"avgResolutionSeconds,omitempty" db:"avg_resolution_seconds"`
+	FirstResponseBreaches   int       `json:"firstResponseBreaches" db:"first_response_breaches"`
+	ResolutionBreaches      int       `json:"resolutionBreaches" db:"resolution_breaches"`
+	FirstResponseTarget     string    `json:"firstResponseTarget,omitempty" db:"-"`
+	ResolutionTarget        string    `json:"resolutionTarget,omitempty" db:"-"`
+}
+
+type SupportNote struct {
+	ID        int64     `json:"id" db:"id"`
+	RoomID    uuid.UUID `json:"roomId" db:"room_id"`
+	AuthorID  uuid.UUID `json:"authorId" db:"author_id"`
+	Content   string    `json:"content" db:"content"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+type CannedResponse struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	Scope     string     `json:"scope" db:"scope"`
+	OwnerID   *uuid.UUID `json:"-" db:"owner_id"`
+	RoomID    *uuid.UUID `json:"roomId,omitempty" db:"room_id"`
+	Shortcut  string     `json:"shortcut" db:"shortcut"`
+	Title     string     `json:"title" db:"title"`
+	Body      string     `json:"body" db:"body"`
+	CreatedBy uuid.UUID  `json:"createdBy" db:"created_by"`
+	CreatedAt time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time  `json:"updatedAt" db:"updated_at"`
+}
+
+const (
+	CannedScopeUser    = "user"
+	CannedScopeRoom    = "room"
+	CannedScopeSupport = "support"
+)
+
+const (
+	SupportStatusQueued   = "queued"
+	SupportStatusAssigned = "assigned"
+	SupportStatusResolved = "resolved"
+
+	SLAKindFirstResponse = "first_response"
+	SLAKindResolution    = "resolution"
+)
+
+type Label struct {
+	ID           uuid.UUID `json:"id" db:"id"`
+	UserID       uuid.UUID `json:"-" db:"user_id"`
+	Name         string    `json:"name" db:"name"`
+	Color        string    `json:"color,omitempty" db:"color"`
+	MessageCount int       `json:"messageCount" db:"message_count"`
+	RoomCount    int       `json:"roomCount" db:"room_count"`
+	CreatedAt    time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt    time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+type QuickSearchResult struct {
+	Kind      string     `json:"kind" db:"kind"`
+	RoomID    *uuid.UUID `json:"roomId,omitempty" db:"room_id"`
+	UserID    *uuid.UUID `json:"userId,omitempty" db:"user_id"`
+	MessageID *int64     `json:"messageId,omitempty" db:"message_id"`
+	Title     string     `json:"title" db:"title"`
+	Snippet   string     `json:"snippet,omitempty" db:"snippet"`
+	At        *time.Time `json:"at,omitempty" db:"at"`
+	Score     float64    `json:"score" db:"score"`
+}
+
+type RoomSnooze struct {
+	RoomID uuid.UUID `db:"room_id"`
+	UserID uuid.UUID `db:"user_id"`
+}
+
+type AllowlistEntry struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	UserID    *uuid.UUID `json:"userId,omitempty" db:"user_id"`
+	Email     *string    `json:"email,omitempty" db:"email"`
+	Note      string     `json:"note" db:"note"`
+	AddedBy   *uuid.UUID `json:"addedBy,omitempty" db:"added_by"`
+	CreatedAt time.Time  `json:"createdAt" db:"created_at"`
+}
+
+const (
+	LegalHoldSubjectUser = "user"
+	LegalHoldSubjectRoom = "room"
+)
+
+type LegalHold struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	SubjectType string     `json:"subjectType" db:"subject_type"`
+	SubjectID   uuid.UUID  `json:"subjectId" db:"subject_id"`
+	Reason      string     `json:"reason" db:"reason"`
+	PlacedBy    *uuid.UUID `json:"placedBy,omitempty" db:"placed_by"`
+	CreatedAt   time.Time  `json:"createdAt" db:"created_at"`
+	ReleasedAt  *time.Time `json:"releasedAt,omitempty" db:"released_at"`
+}
+
+type Instance struct {
+	ID              string    `json:"id" db:"id"`
+	URL             string    `json:"url" db:"url"`
+	StartedAt       time.Time `json:"startedAt" db:"started_at"`
+	LastHeartbeatAt time.Time `json:"lastHeartbeatAt" db:"last_heartbeat_at"`
+	Connections     int       `json:"connections" db:"connections"`
+}
+
+const (
+	EphemeralTyping = "typing"
+	EphemeralFocus  = "focus"
+	EphemeralCall   = "call"
+)
+
+type EphemeralState struct {
+	RoomID    uuid.UUID `json:"roomId" db:"room_id"`
+	UserID    uuid.UUID `json:"userId" db:"user_id"`
+	Kind      string    `json:"kind" db:"kind"`
+	Value     string    `json:"value" db:"value"`
+	ExpiresAt time.Time `json:"expiresAt" db:"expires_at"`
+}
+
+const (
+	RoomMetadataSensitive     = "admin.sensitive"
+	RoomMetadataLanguage      = "admin.language"
+	RoomMetadataAutoTranslate = "admin.auto_translate"
+	RoomMetadataE2EE          = "admin.e2ee"
+	RoomMetadataWidget        = "admin.widget"
+)
+
+type Draft struct {
+	UserID        uuid.UUID   `json:"-" db:"user_id"`
+	RoomID        uuid.UUID   `json:"roomId" db:"room_id"`
+	Content       string      `json:"content" db:"content"`
+	AttachmentIDs []uuid.UUID `json:"attachmentIds" db:"attachment_ids"`
+	UpdatedAt     time.Time   `json:"updatedAt" db:"updated_at"`
+}
+
+type Upload struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	RoomID         uuid.UUID  `json:"roomId" db:"room_id"`
+	UploaderID     uuid.UUID  `json:"uploaderId" db:"uploader_id"`
+	Filename       string     `json:"filename" db:"filename"`
+	ContentType    string     `json:"contentType" db:"content_type"`
+	SizeBytes      int64      `json:"sizeBytes" db:"size_bytes"`
+	OffsetBytes    int64      `json:"offsetBytes" db:"offset_bytes"`
+	ChecksumSHA256 string     `json:"checksumSha256,omitempty" db:"checksum_sha256"`
+	StorageKey     string     `json:"-" db:"storage_key"`
+	ExpiresAt      time.Time  `json:"expiresAt" db:"expires_at"`
+	CreatedAt      time.Time  `json:"createdAt" db:"created_at"`
+	CompletedAt    *time.Time `json:"completedAt,omitempty" db:"completed_at"`
+}
+
+type IdempotencyRecord struct {
+	Fingerprint string    `db:"fingerprint"`
+	StatusCode  int       `db:"status_code"`
+	ContentType string    `db:"content_type"`
+	Body        []byte    `db:"body"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+type GuestMerge struct {
+	ID               uuid.UUID `json:"id" db:"id"`
+	GuestID          uuid.UUID `json:"guestId" db:"guest_id"`
+	UserID           uuid.UUID `json:"userId" db:"user_id"`
+	RoomID           uuid.UUID `json:"roomId" db:"room_id"`
+	GuestDisplayName string    `json:"guestDisplayName" db:"guest_display_name"`
+	MessagesMoved    int       `json:"messagesMoved" db:"messages_moved"`
+	AlreadyMember    bool      `json:"alreadyMember" db:"already_member"`
+	MergedAt         time.Time `json:"mergedAt" db:"merged_at"`
+}
+
+type SummaryLine struct {
+	ID        int64     `db:"id"`
+	UserID    uuid.UUID `db:"user_id"`
+	Author    string    `db:"author"`
+	Content   string    `db:"content"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type MessageReaction struct {
+	RoomID    uuid.UUID `json:"roomId"`
+	MessageID int64     `json:"messageId"`
+	UserID    uuid.UUID `json:"userId"`
+	Emoji     string    `json:"emoji"`
+	Added     bool      `json:"added"`
+}
+
+type RoomHighlight struct {
+	MessageID int64     `json:"messageId" db:"id"`
+	UserID    uuid.UUID `json:"userId" db:"user_id"`
+	Author    string    `json:"author" db:"author"`
+	Content   string    `json:"content" db:"content"`
+	Kind      string    `json:"kind" db:"kind"`
+	Reactions int       `json:"reactions" db:"reactions"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+type RoomSummary struct {
+	RoomID         uuid.UUID `json:"roomId" db:"room_id"`
+	Language       string    `json:"language,omitempty" db:"language"`
+	Since          time.Time `json:"since" db:"-"`
+	FirstMessageID int64     `json:"firstMessageId" db:"first_message_id"`
+	LastMessageID  int64     `json:"lastMessageId" db:"last_message_id"`
+	MessageCount   int       `json:"messageCount" db:"message_count"`
+	Summary        string    `json:"summary" db:"summary"`
+	Cached         bool      `json:"cached" db:"-"`
+	CreatedAt      time.Time `json:"createdAt" db:"created_at"`
+}
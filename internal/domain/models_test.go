@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"bytes"
+	"testing"
+	"testing/quick"
+
+	"github.com/google/uuid"
+)
+
+func ordered(a, b uuid.UUID) bool { return bytes.Compare(a[:], b[:]) <= 0 }
+
+func TestOrderFriendPairIsCanonical(t *testing.T) {
+	property := func(a, b uuid.UUID) bool {
+		one, two := OrderFriendPair(a, b)
+		swappedOne, swappedTwo := OrderFriendPair(b, a)
+		return ordered(one, two) &&
+			one == swappedOne && two == swappedTwo &&
+			((one == a && two == b) || (one == b && two == a))
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNewFriendshipIsCanonical(t *testing.T) {
+	property := func(a, b, actor uuid.UUID) bool {
+		fs := NewFriendship(a, b, "pending", actor)
+		swapped := NewFriendship(b, a, "pending", actor)
+		return ordered(fs.UserOneID, fs.UserTwoID) &&
+			fs.UserOneID == swapped.UserOneID && fs.UserTwoID == swapped.UserTwoID &&
+			fs.ActionUserID == actor
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,332 @@
+package encode
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"chatservice/internal/domain"
+	"chatservice/pkg/wprotocol"
+
+	"github.com/google/uuid"
+)
+
+func EncodeMsgDeliver(msg domain.Message) []byte {
+	return wprotocol.Build(wprotocol.OpMsgDeliver, msgDeliverParams(msg)...)
+}
+
+func EncodeMsgDeliverBatch(roomID uuid.UUID, msgs []domain.Message) []byte {
+	params := []string{roomID.String()}
+	for _, msg := range msgs {
+		fields := slices.Delete(msgDeliverParams(msg), 2, 3)
+		params = append(params, strconv.Itoa(len(fields)))
+		params = append(params, fields...)
+	}
+	return wprotocol.Build(wprotocol.OpMsgDeliverBatch, params...)
+}
+
+func MsgDeliverBatchFields(msg domain.Message) int {
+	return len(msgDeliverParams(msg))
+}
+
+func ExpandMsgDeliverBatch(frame []byte) [][]byte {
+	packet, err := wprotocol.Parse(frame)
+	if err != nil || packet.Op != wprotocol.OpMsgDeliverBatch || len(packet.Payload) < 1 {
+		return nil
+	}
+	roomID, rest := packet.Payload[0], packet.Payload[1:]
+	var frames [][]byte
+	for len(rest) > 0 {
+		n, err := strconv.Atoi(rest[0])
+		if err != nil || n < 2 || n > len(rest)-1 {
+			break
+		}
+		fields := slices.Insert(slices.Clone(rest[1:1+n]), 2, roomID)
+		frames = append(frames, wprotocol.Build(wprotocol.OpMsgDeliver, fields...))
+		rest = rest[1+n:]
+	}
+	return frames
+}
+
+// DeliveredMessage reports the room and highest message ID carried by a
+// msg.deliver or msg.deliver_batch frame.
+func DeliveredMessage(frame []byte) (uuid.UUID, int64, bool) {
+	op, ok := wprotocol.PeekOp(frame)
+	if !ok || (op != wprotocol.OpMsgDeliver && op != wprotocol.OpMsgDeliverBatch) {
+		return uuid.Nil, 0, false
+	}
+	if op == wprotocol.OpMsgDeliverBatch {
+		var roomID uuid.UUID
+		var latest int64
+		for _, single := range ExpandMsgDeliverBatch(frame) {
+			if id, messageID, ok := DeliveredMessage(single); ok && messageID > latest {
+				roomID, latest = id, messageID
+			}
+		}
+		return roomID, latest, latest > 0
+	}
+	packet, err := wprotocol.Parse(frame)
+	if err != nil || len(packet.Payload) < 3 {
+		return uuid.Nil, 0, false
+	}
+	messageID, err := strconv.ParseInt(packet.Payload[0], 10, 64)
+	if err != nil {
+		return uuid.Nil, 0, false
+	}
+	roomID, err := uuid.Parse(packet.Payload[2])
+	if err != nil {
+		return uuid.Nil, 0, false
+	}
+	return roomID, messageID, true
+}
+
+func msgDeliverParams(msg domain.Message) []string {
+	params := []string{
+		strconv.FormatInt(msg.ID, 10),
+		msg.MessageUID.String(),
+		msg.RoomID.String(),
+		msg.UserID.String(),
+		msg.CreatedAt.Format(time.RFC3339Nano),
+		msg.Content,
+		msg.Kind,
+	}
+	var attachmentID, contentType string
+	if msg.AttachmentID != nil {
+		attachmentID = msg.AttachmentID.String()
+	}
+	if msg.ContentType != "" && msg.ContentType != domain.ContentTypePlain {
+		contentType = msg.ContentType
+	}
+	tail := []string{string(msg.Metadata), attachmentID, contentType, string(msg.RichContent)}
+	for len(tail) > 0 && tail[len(tail)-1] == "" {
+		tail = tail[:len(tail)-1]
+	}
+	return append(params, tail...)
+}
+
+func EncodeMsgEdited(messageID int64, roomID uuid.UUID, content string, richContent json.RawMessage) []byte {
+	params := []string{strconv.FormatInt(messageID, 10), roomID.String(), content}
+	if len(richContent) > 0 {
+		params = append(params, string(richContent))
+	}
+	return wprotocol.Build(wprotocol.OpMsgEdited, params...)
+}
+
+func EncodeMsgTranslation(messageID int64, roomID uuid.UUID, language, content string) []byte {
+	return wprotocol.Build(wprotocol.OpMsgTranslation, strconv.FormatInt(messageID, 10), roomID.String(), language, content)
+}
+
+func EncodeMsgDeleted(messageID int64, roomID uuid.UUID) []byte {
+	return wprotocol.Build(
+		wprotocol.OpMsgDeleted,
+		strconv.FormatInt(messageID, 10),
+		roomID.String(),
+	)
+}
+
+func EncodeMsgStatusUpdate(messageID int64, roomID, userID uuid.UUID, status string, at time.Time) []byte {
+	return wprotocol.Build(
+		wprotocol.OpMsgStatusUpdate,
+		strconv.FormatInt(messageID, 10),
+		roomID.String(),
+		userID.String(),
+		status,
+		at.Format(time.RFC3339Nano),
+	)
+}
+
+func EncodeNotifyRoomAdded(room domain.Room) []byte {
+	name := ""
+	if room.Name != nil {
+		name = *room.Name
+	}
+	return wprotocol.Build(wprotocol.OpNotifyRoomAdded, room.ID.String(), room.Type, name)
+}
+
+func EncodeNotifyRoomRemoved(roomID uuid.UUID) []byte {
+	return wprotocol.Build(wprotocol.OpNotifyRoomRemoved, roomID.String())
+}
+
+func EncodeFriendRequestReceived(sender domain.User) []byte {
+	return wprotocol.Build(wprotocol.OpFriendRequestReceived, sender.ID.String(), sender.Nickname)
+}
+
+func EncodeFriendRequestAccepted(accepter domain.User, roomID uuid.UUID) []byte {
+	return wprotocol.Build(
+		wprotocol.OpFriendRequestAccepted,
+		accepter.ID.String(),
+		accepter.Nickname,
+		roomID.String(),
+	)
+}
+
+func EncodeFriendRemoved(userID uuid.UUID) []byte {
+	return wprotocol.Build(wprotocol.OpFriendRemoved, userID.String())
+}
+
+func EncodeWebRTCSignal(senderID, roomID uuid.UUID, signal string) []byte {
+	return wprotocol.Build(wprotocol.OpWebRTCSignal, senderID.String(), roomID.String(), signal)
+}
+
+func EncodeCallParticipant(op wprotocol.OpCode, roomID, userID uuid.UUID, at time.Time) []byte {
+	return wprotocol.Build(op, roomID.String(), userID.String(), at.Format(time.RFC3339Nano))
+}
+
+func EncodeCallEnded(roomID uuid.UUID, at time.Time) []byte {
+	return wprotocol.Build(wprotocol.OpCallEnded, roomID.String(), at.Format(time.RFC3339Nano))
+}
+
+func EncodeCallState(roomID uuid.UUID, state domain.CallParticipantState) []byte {
+	return wprotocol.Build(
+		wprotocol.OpCallState,
+		roomID.String(),
+		state.UserID.String(),
+		encodeBool(state.Muted),
+		encodeBool(state.CameraOn),
+		encodeBool(state.ScreenSharing),
+		state.UpdatedAt.Format(time.RFC3339Nano),
+	)
+}
+
+func EncodeCallRecordingPrompt(roomID, recordingID, requesterID uuid.UUID) []byte {
+	return wprotocol.Build(wprotocol.OpCallRecordingPrompt, roomID.String(), recordingID.String(), requesterID.String())
+}
+
+func EncodeCallRecordingStarted(roomID, recordingID uuid.UUID, at time.Time) []byte {
+	return wprotocol.Build(wprotocol.OpCallRecordingStarted, roomID.String(), recordingID.String(), at.Format(time.RFC3339Nano))
+}
+
+func EncodeCallRecordingStopped(roomID, recordingID uuid.UUID, reason string, attachmentID uuid.UUID) []byte {
+	attachment := ""
+	if attachmentID != uuid.Nil {
+		attachment = attachmentID.String()
+	}
+	return wprotocol.Build(wprotocol.OpCallRecordingStopped, roomID.String(), recordingID.String(), reason, attachment)
+}
+
+func EncodeRoomState(roomID uuid.UUID, states []domain.EphemeralState) []byte {
+	params := make([]string, 0, 1+len(states)*3)
+	params = append(params, roomID.String())
+	for _, state := range states {
+		params = append(params, state.Kind, state.UserID.String(), state.Value)
+	}
+	return wprotocol.Build(wprotocol.OpRoomState, params...)
+}
+
+func EncodeRoomMembersAdded(roomID, addedBy uuid.UUID, userIDs []uuid.UUID) []byte {
+	params := make([]string, 0, 2+len(userIDs))
+	params = append(params, roomID.String(), addedBy.String())
+	for _, userID := range userIDs {
+		params = append(params, userID.String())
+	}
+	return wprotocol.Build(wprotocol.OpRoomMembersAdded, params...)
+}
+
+func EncodeRoomUpdated(roomID uuid.UUID, snoozedUntil *time.Time) []byte {
+	until := ""
+	if snoozedUntil != nil {
+		until = snoozedUntil.Format(time.RFC3339Nano)
+	}
+	return wprotocol.Build(wprotocol.OpRoomUpdated, roomID.String(), until)
+}
+
+func EncodeSupportAssignment(conv domain.SupportConversation) []byte {
+	agentID := ""
+	if conv.AgentID != nil {
+		agentID = conv.AgentID.String()
+	}
+	return wprotocol.Build(wprotocol.OpSupportAssignment, conv.RoomID.String(), agentID, conv.Status)
+}
+
+func EncodeSupportNote(note domain.SupportNote) []byte {
+	return wprotocol.Build(wprotocol.OpSupportNote, note.RoomID.String(), strconv.FormatInt(note.ID, 10), note.AuthorID.String(), note.Content, note.CreatedAt.Format(time.RFC3339Nano))
+}
+
+func EncodeSupportSLABreached(breach domain.SupportSLABreach) []byte {
+	return wprotocol.Build(wprotocol.OpSupportSLABreached, breach.RoomID.String(), breach.Kind, breach.BreachedAt.Format(time.RFC3339Nano))
+}
+
+func EncodeMsgReaction(reaction domain.MessageReaction) []byte {
+	added := "0"
+	if reaction.Added {
+		added = "1"
+	}
+	return wprotocol.Build(wprotocol.OpMsgReaction, reaction.RoomID.String(), strconv.FormatInt(reaction.MessageID, 10), reaction.UserID.String(), reaction.Emoji, added)
+}
+
+func EncodeRoomStateChanged(change domain.RoomStateChange) []byte {
+	return wprotocol.Build(wprotocol.OpRoomStateChanged, change.RoomID.String(), change.State, change.Previous, change.ChangedBy.String(), change.ChangedAt.Format(time.RFC3339Nano))
+}
+
+func EncodeUserProfileUpdated(user domain.User) []byte {
+	return wprotocol.Build(wprotocol.OpUserProfileUpdated, user.ID.String(), user.Nickname, user.Username, strings.Join(user.Badges, ","))
+}
+
+func EncodeStateVersion(version int64) []byte {
+	return wprotocol.Build(wprotocol.OpStateVersion, strconv.FormatInt(version, 10))
+}
+
+func EncodeTyping(roomID, userID uuid.UUID, typing bool) []byte {
+	op := wprotocol.OpPresenceTypingOff
+	if typing {
+		op = wprotocol.OpPresenceTypingOn
+	}
+	return wprotocol.Build(op, roomID.String(), userID.String())
+}
+
+func EncodeSuggestions(roomID uuid.UUID, messageID int64, suggestions []string) []byte {
+	return wprotocol.Build(wprotocol.OpSuggestions, append([]string{roomID.String(), strconv.FormatInt(messageID, 10)}, suggestions...)...)
+}
+
+func encodeBool(v bool) string {
+	if v {
+		return "1"
+	}
+	return "0"
+}
+
+func EncodeSessionToken(token string) []byte {
+	return wprotocol.Build(wprotocol.OpSessionToken, token)
+}
+
+func EncodeSessionResumed(replayed int) []byte {
+	return wprotocol.Build(wprotocol.OpSessionResumed, strconv.Itoa(replayed))
+}
+
+// EncodeSessionResync tells a resuming client its buffered view is
+// incomplete. Each room is followed by the last message ID the session
+// received in it (0 for none), from which the client syncs over REST.
+func EncodeSessionResync(reason string, cursors map[uuid.UUID]int64) []byte {
+	roomIDs := slices.SortedFunc(maps.Keys(cursors), func(a, b uuid.UUID) int { return bytes.Compare(a[:], b[:]) })
+	params := []string{reason}
+	for _, roomID := range roomIDs {
+		params = append(params, roomID.String(), strconv.FormatInt(cursors[roomID], 10))
+	}
+	return wprotocol.Build(wprotocol.OpSessionResync, params...)
+}
+
+func EncodeDeprecated(op wprotocol.OpCode, info wprotocol.OpInfo) []byte {
+	replacement := ""
+	if info.Replacement != 0 {
+		replacement = strconv.Itoa(int(info.Replacement))
+	}
+	return wprotocol.Build(
+		wprotocol.OpDeprecated,
+		strconv.Itoa(int(op)),
+		replacement,
+		strconv.Itoa(info.MaxVersion),
+		"opcode "+op.String()+" is deprecated and will be removed",
+	)
+}
+
+func EncodeError(message string) []byte {
+	return wprotocol.Build(wprotocol.OpError, message)
+}
+
+func EncodeErrorCode(code, message string) []byte {
+	return wprotocol.Build(wprotocol.OpError, message, code)
+}
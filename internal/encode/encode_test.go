@@ -0,0 +1,139 @@
+package encode
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+
+	"chatservice/internal/domain"
+	"chatservice/pkg/wprotocol"
+
+	"github.com/google/uuid"
+)
+
+var (
+	roomID  = uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	userID  = uuid.MustParse("22222222-2222-2222-2222-222222222222")
+	otherID = uuid.MustParse("33333333-3333-3333-3333-333333333333")
+	at      = time.Date(2024, 5, 6, 7, 8, 9, 123456789, time.UTC)
+)
+
+func TestRoundTrip(t *testing.T) {
+	name := "Team"
+	attachmentID := otherID
+	plain := domain.Message{ID: 42, MessageUID: otherID, RoomID: roomID, UserID: userID, CreatedAt: at, Content: "hello, world", Kind: "text"}
+	rich := plain
+	rich.ContentType = domain.ContentTypeRich
+	rich.Metadata = json.RawMessage(`{"priority":"urgent"}`)
+	rich.AttachmentID = &attachmentID
+	rich.RichContent = json.RawMessage(`{"type":"doc"}`)
+	onlyAttachment := plain
+	onlyAttachment.AttachmentID = &attachmentID
+	ts := at.Format(time.RFC3339Nano)
+
+	tests := []struct {
+		name    string
+		frame   []byte
+		op      wprotocol.OpCode
+		payload []string
+	}{
+		{"plain message", EncodeMsgDeliver(plain), wprotocol.OpMsgDeliver,
+			[]string{"42", otherID.String(), roomID.String(), userID.String(), ts, "hello, world", "text"}},
+		{"rich message", EncodeMsgDeliver(rich), wprotocol.OpMsgDeliver,
+			[]string{"42", otherID.String(), roomID.String(), userID.String(), ts, "hello, world", "text", `{"priority":"urgent"}`, otherID.String(), "rich", `{"type":"doc"}`}},
+		{"attachment keeps earlier empty fields", EncodeMsgDeliver(onlyAttachment), wprotocol.OpMsgDeliver,
+			[]string{"42", otherID.String(), roomID.String(), userID.String(), ts, "hello, world", "text", "", otherID.String()}},
+		{"edited", EncodeMsgEdited(42, roomID, "new", nil), wprotocol.OpMsgEdited, []string{"42", roomID.String(), "new"}},
+		{"edited rich", EncodeMsgEdited(42, roomID, "new", json.RawMessage(`{}`)), wprotocol.OpMsgEdited, []string{"42", roomID.String(), "new", "{}"}},
+		{"translation", EncodeMsgTranslation(42, roomID, "de", "hallo"), wprotocol.OpMsgTranslation, []string{"42", roomID.String(), "de", "hallo"}},
+		{"deleted", EncodeMsgDeleted(42, roomID), wprotocol.OpMsgDeleted, []string{"42", roomID.String()}},
+		{"status", EncodeMsgStatusUpdate(42, roomID, userID, "read", at), wprotocol.OpMsgStatusUpdate, []string{"42", roomID.String(), userID.String(), "read", ts}},
+		{"room added", EncodeNotifyRoomAdded(domain.Room{ID: roomID, Type: "group", Name: &name}), wprotocol.OpNotifyRoomAdded, []string{roomID.String(), "group", "Team"}},
+		{"room added without name", EncodeNotifyRoomAdded(domain.Room{ID: roomID, Type: "private"}), wprotocol.OpNotifyRoomAdded, []string{roomID.String(), "private", ""}},
+		{"room removed", EncodeNotifyRoomRemoved(roomID), wprotocol.OpNotifyRoomRemoved, []string{roomID.String()}},
+		{"friend request", EncodeFriendRequestReceived(domain.User{ID: userID, Nickname: "ann"}), wprotocol.OpFriendRequestReceived, []string{userID.String(), "ann"}},
+		{"friend accepted", EncodeFriendRequestAccepted(domain.User{ID: userID, Nickname: "ann"}, roomID), wprotocol.OpFriendRequestAccepted, []string{userID.String(), "ann", roomID.String()}},
+		{"friend removed", EncodeFriendRemoved(userID), wprotocol.OpFriendRemoved, []string{userID.String()}},
+		{"signal", EncodeWebRTCSignal(userID, roomID, "sdp"), wprotocol.OpWebRTCSignal, []string{userID.String(), roomID.String(), "sdp"}},
+		{"call joined", EncodeCallParticipant(wprotocol.OpCallParticipantJoined, roomID, userID, at), wprotocol.OpCallParticipantJoined, []string{roomID.String(), userID.String(), ts}},
+		{"call ended", EncodeCallEnded(roomID, at), wprotocol.OpCallEnded, []string{roomID.String(), ts}},
+		{"call state", EncodeCallState(roomID, domain.CallParticipantState{UserID: userID, Muted: true, ScreenSharing: true, UpdatedAt: at}), wprotocol.OpCallState,
+			[]string{roomID.String(), userID.String(), "1", "0", "1", ts}},
+		{"recording prompt", EncodeCallRecordingPrompt(roomID, otherID, userID), wprotocol.OpCallRecordingPrompt, []string{roomID.String(), otherID.String(), userID.String()}},
+		{"recording started", EncodeCallRecordingStarted(roomID, otherID, at), wprotocol.OpCallRecordingStarted, []string{roomID.String(), otherID.String(), ts}},
+		{"recording stopped", EncodeCallRecordingStopped(roomID, otherID, "done", uuid.Nil), wprotocol.OpCallRecordingStopped, []string{roomID.String(), otherID.String(), "done", ""}},
+		{"room state", EncodeRoomState(roomID, []domain.EphemeralState{{Kind: "typing", UserID: userID, Value: "1"}}), wprotocol.OpRoomState, []string{roomID.String(), "typing", userID.String(), "1"}},
+		{"members added", EncodeRoomMembersAdded(roomID, userID, []uuid.UUID{otherID}), wprotocol.OpRoomMembersAdded, []string{roomID.String(), userID.String(), otherID.String()}},
+		{"room unsnoozed", EncodeRoomUpdated(roomID, nil), wprotocol.OpRoomUpdated, []string{roomID.String(), ""}},
+		{"room snoozed", EncodeRoomUpdated(roomID, &at), wprotocol.OpRoomUpdated, []string{roomID.String(), ts}},
+		{"support queued", EncodeSupportAssignment(domain.SupportConversation{RoomID: roomID, Status: "queued"}), wprotocol.OpSupportAssignment, []string{roomID.String(), "", "queued"}},
+		{"support note", EncodeSupportNote(domain.SupportNote{ID: 7, RoomID: roomID, AuthorID: userID, Content: "note", CreatedAt: at}), wprotocol.OpSupportNote,
+			[]string{roomID.String(), "7", userID.String(), "note", ts}},
+		{"sla breached", EncodeSupportSLABreached(domain.SupportSLABreach{RoomID: roomID, Kind: "first_response", BreachedAt: at}), wprotocol.OpSupportSLABreached,
+			[]string{roomID.String(), "first_response", ts}},
+		{"room state changed", EncodeRoomStateChanged(domain.RoomStateChange{RoomID: roomID, State: "closed", Previous: "open", ChangedBy: userID, ChangedAt: at}), wprotocol.OpRoomStateChanged,
+			[]string{roomID.String(), "closed", "open", userID.String(), ts}},
+		{"profile", EncodeUserProfileUpdated(domain.User{ID: userID, Nickname: "ann", Username: "ann1", Badges: []string{"staff", "bot"}}), wprotocol.OpUserProfileUpdated,
+			[]string{userID.String(), "ann", "ann1", "staff,bot"}},
+		{"state version", EncodeStateVersion(9), wprotocol.OpStateVersion, []string{"9"}},
+		{"reaction", EncodeMsgReaction(domain.MessageReaction{RoomID: roomID, MessageID: 7, UserID: userID, Emoji: "🎉", Added: true}), wprotocol.OpMsgReaction,
+			[]string{roomID.String(), "7", userID.String(), "🎉", "1"}},
+		{"typing on", EncodeTyping(roomID, userID, true), wprotocol.OpPresenceTypingOn, []string{roomID.String(), userID.String()}},
+		{"typing off", EncodeTyping(roomID, userID, false), wprotocol.OpPresenceTypingOff, []string{roomID.String(), userID.String()}},
+		{"suggestions", EncodeSuggestions(roomID, 42, []string{"yes", "no"}), wprotocol.OpSuggestions, []string{roomID.String(), "42", "yes", "no"}},
+		{"session token", EncodeSessionToken("tok"), wprotocol.OpSessionToken, []string{"tok"}},
+		{"session resumed", EncodeSessionResumed(3), wprotocol.OpSessionResumed, []string{"3"}},
+		{"session resync", EncodeSessionResync("restart", map[uuid.UUID]int64{roomID: 42}), wprotocol.OpSessionResync, []string{"restart", roomID.String(), "42"}},
+		{"error", EncodeError("bad"), wprotocol.OpError, []string{"bad"}},
+		{"error code", EncodeErrorCode("busy", "slow down"), wprotocol.OpError, []string{"slow down", "busy"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			packet, err := wprotocol.Parse(tt.frame)
+			if err != nil {
+				t.Fatalf("Parse(%q) failed: %v", tt.frame, err)
+			}
+			if packet.Op != tt.op {
+				t.Errorf("op = %d, want %d", packet.Op, tt.op)
+			}
+			if !slices.Equal(packet.Payload, tt.payload) {
+				t.Errorf("payload = %q, want %q", packet.Payload, tt.payload)
+			}
+		})
+	}
+}
+
+func TestMsgDeliverBatchExpandsToSingleFrames(t *testing.T) {
+	attachmentID := otherID
+	first := domain.Message{ID: 1, MessageUID: userID, RoomID: roomID, UserID: userID, CreatedAt: at, Content: "one", Kind: "text"}
+	second := domain.Message{ID: 2, MessageUID: otherID, RoomID: roomID, UserID: otherID, CreatedAt: at, Content: "two", Kind: "text",
+		ContentType: domain.ContentTypeMarkdown, AttachmentID: &attachmentID}
+
+	frames := ExpandMsgDeliverBatch(EncodeMsgDeliverBatch(roomID, []domain.Message{first, second}))
+	want := [][]byte{EncodeMsgDeliver(first), EncodeMsgDeliver(second)}
+	if len(frames) != len(want) {
+		t.Fatalf("expanded %d frames, want %d", len(frames), len(want))
+	}
+	for i := range want {
+		if string(frames[i]) != string(want[i]) {
+			t.Errorf("frame %d = %q, want %q", i, frames[i], want[i])
+		}
+	}
+	if got := MsgDeliverBatchFields(second); got != 10 {
+		t.Errorf("MsgDeliverBatchFields = %d, want 10", got)
+	}
+}
+
+func TestDeprecatedNamesReplacement(t *testing.T) {
+	packet, err := wprotocol.Parse(EncodeDeprecated(wprotocol.OpMsgDeliver, wprotocol.OpInfo{Replacement: wprotocol.OpMsgDeliverBatch, MaxVersion: 2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if packet.Op != wprotocol.OpDeprecated || len(packet.Payload) != 4 {
+		t.Fatalf("unexpected packet %+v", packet)
+	}
+	if packet.Payload[0] != "2" || packet.Payload[1] != "44" || packet.Payload[2] != "2" {
+		t.Errorf("payload = %q", packet.Payload)
+	}
+}
@@ -0,0 +1,137 @@
+package ephemeral
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+const pruneInterval = time.Minute
+
+type Store interface {
+	Set(ctx context.Context, roomID, userID uuid.UUID, kind, value string, ttl time.Duration) error
+	Clear(ctx context.Context, roomID, userID uuid.UUID, kind string) error
+	Room(ctx context.Context, roomID uuid.UUID) ([]domain.EphemeralState, error)
+}
+
+type entryKey struct {
+	userID uuid.UUID
+	kind   string
+}
+
+type MemoryStore struct {
+	mu    sync.Mutex
+	rooms map[uuid.UUID]map[entryKey]domain.EphemeralState
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{rooms: make(map[uuid.UUID]map[entryKey]domain.EphemeralState)}
+}
+
+func (s *MemoryStore) Set(ctx context.Context, roomID, userID uuid.UUID, kind, value string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	room, ok := s.rooms[roomID]
+	if !ok {
+		room = make(map[entryKey]domain.EphemeralState)
+		s.rooms[roomID] = room
+	}
+	now := time.Now()
+	for key, state := range room {
+		if !state.ExpiresAt.After(now) {
+			delete(room, key)
+		}
+	}
+	room[entryKey{userID, kind}] = domain.EphemeralState{
+		RoomID:    roomID,
+		UserID:    userID,
+		Kind:      kind,
+		Value:     value,
+		ExpiresAt: now.Add(ttl),
+	}
+	return nil
+}
+
+func (s *MemoryStore) Clear(ctx context.Context, roomID, userID uuid.UUID, kind string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if room, ok := s.rooms[roomID]; ok {
+		delete(room, entryKey{userID, kind})
+		if len(room) == 0 {
+			delete(s.rooms, roomID)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) Room(ctx context.Context, roomID uuid.UUID) ([]domain.EphemeralState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	states := []domain.EphemeralState{}
+	for key, state := range s.rooms[roomID] {
+		if !state.ExpiresAt.After(now) {
+			delete(s.rooms[roomID], key)
+			continue
+		}
+		states = append(states, state)
+	}
+	if len(s.rooms[roomID]) == 0 {
+		delete(s.rooms, roomID)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].Kind != states[j].Kind {
+			return states[i].Kind < states[j].Kind
+		}
+		return states[i].UserID.String() < states[j].UserID.String()
+	})
+	return states, nil
+}
+
+type SharedStore struct {
+	repo repository.EphemeralRepository
+}
+
+func NewSharedStore(repo repository.EphemeralRepository) *SharedStore {
+	return &SharedStore{repo: repo}
+}
+
+func (s *SharedStore) Set(ctx context.Context, roomID, userID uuid.UUID, kind, value string, ttl time.Duration) error {
+	return s.repo.SetState(ctx, domain.EphemeralState{
+		RoomID:    roomID,
+		UserID:    userID,
+		Kind:      kind,
+		Value:     value,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+}
+
+func (s *SharedStore) Clear(ctx context.Context, roomID, userID uuid.UUID, kind string) error {
+	return s.repo.ClearState(ctx, roomID, userID, kind)
+}
+
+func (s *SharedStore) Room(ctx context.Context, roomID uuid.UUID) ([]domain.EphemeralState, error) {
+	return s.repo.GetRoomState(ctx, roomID)
+}
+
+func (s *SharedStore) Run(ctx context.Context) {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.repo.PruneExpiredState(ctx); err != nil {
+				log.Printf("Error pruning expired ephemeral state: %v", err)
+			}
+		}
+	}
+}
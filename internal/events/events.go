@@ -0,0 +1,201 @@
+package events
+
+import (
+	"encoding/json"
+	"time"
+
+	"chatservice/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+type Event interface {
+	EventName() string
+}
+
+type MessageCreated struct {
+	Message domain.Message
+}
+
+type MessageEdited struct {
+	MessageID   int64
+	RoomID      uuid.UUID
+	EditorID    uuid.UUID
+	Content     string
+	RichContent json.RawMessage
+}
+
+type MessageDeleted struct {
+	MessageID int64
+	RoomID    uuid.UUID
+	DeleterID uuid.UUID
+}
+
+type MessageRead struct {
+	MessageID int64
+	RoomID    uuid.UUID
+	UserID    uuid.UUID
+	ReadAt    time.Time
+}
+
+type FriendRequestSent struct {
+	Sender   domain.User
+	Receiver domain.User
+}
+
+type FriendshipAccepted struct {
+	Accepter    domain.User
+	RequesterID uuid.UUID
+	Room        domain.Room
+}
+
+type FriendRequestDeclined struct {
+	DeclinerID  uuid.UUID
+	RequesterID uuid.UUID
+}
+
+type RoomMembersAdded struct {
+	Room    domain.Room
+	AddedBy uuid.UUID
+	UserIDs []uuid.UUID
+}
+
+type RoomMembersRemoved struct {
+	RoomID    uuid.UUID
+	RemovedBy uuid.UUID
+	UserIDs   []uuid.UUID
+}
+
+type CallParticipantJoined struct {
+	RoomID uuid.UUID
+	UserID uuid.UUID
+	At     time.Time
+}
+
+type CallParticipantLeft struct {
+	RoomID uuid.UUID
+	UserID uuid.UUID
+	At     time.Time
+}
+
+type CallEnded struct {
+	RoomID uuid.UUID
+	At     time.Time
+}
+
+type CallMissed struct {
+	RoomID    uuid.UUID
+	CallerID  uuid.UUID
+	CalleeIDs []uuid.UUID
+	Message   domain.Message
+	At        time.Time
+}
+
+type CallRecordingRequested struct {
+	RoomID      uuid.UUID
+	RecordingID uuid.UUID
+	RequesterID uuid.UUID
+}
+
+type CallRecordingStarted struct {
+	RoomID      uuid.UUID
+	RecordingID uuid.UUID
+	At          time.Time
+}
+
+type CallRecordingStopped struct {
+	RoomID       uuid.UUID
+	RecordingID  uuid.UUID
+	Reason       string
+	AttachmentID uuid.UUID
+}
+
+type CallStateChanged struct {
+	RoomID uuid.UUID
+	State  domain.CallParticipantState
+}
+
+type CallStateSnapshot struct {
+	RecipientID uuid.UUID
+	RoomID      uuid.UUID
+	States      []domain.CallParticipantState
+}
+
+type RoomUpdated struct {
+	UserID       uuid.UUID
+	RoomID       uuid.UUID
+	SnoozedUntil *time.Time
+}
+
+type SupportAssignmentChanged struct {
+	Conversation    domain.SupportConversation
+	PreviousAgentID *uuid.UUID
+}
+
+type UserProfileUpdated struct {
+	User         domain.User
+	RecipientIDs []uuid.UUID
+}
+
+type GuestMerged struct {
+	Merge domain.GuestMerge
+}
+
+type RoomStateChanged struct {
+	Change domain.RoomStateChange
+}
+
+type SupportSLABreached struct {
+	Breach    domain.SupportSLABreach
+	Threshold time.Duration
+}
+
+type SupportNoteCreated struct {
+	Note         domain.SupportNote
+	RecipientIDs []uuid.UUID
+}
+
+type UserTyping struct {
+	RoomID uuid.UUID
+	UserID uuid.UUID
+	Typing bool
+}
+
+type MessageReactionChanged struct {
+	Reaction domain.MessageReaction
+}
+
+type RoomStateSnapshot struct {
+	RecipientID uuid.UUID
+	RoomID      uuid.UUID
+	States      []domain.EphemeralState
+}
+
+func (MessageCreated) EventName() string           { return "message.created" }
+func (MessageEdited) EventName() string            { return "message.edited" }
+func (MessageDeleted) EventName() string           { return "message.deleted" }
+func (MessageRead) EventName() string              { return "message.read" }
+func (MessageReactionChanged) EventName() string   { return "message.reaction_changed" }
+func (FriendRequestSent) EventName() string        { return "friend_request.sent" }
+func (FriendRequestDeclined) EventName() string    { return "friend_request.declined" }
+func (FriendshipAccepted) EventName() string       { return "friendship.accepted" }
+func (RoomMembersAdded) EventName() string         { return "room.members_added" }
+func (RoomMembersRemoved) EventName() string       { return "room.members_removed" }
+func (RoomUpdated) EventName() string              { return "room.updated" }
+func (CallParticipantJoined) EventName() string    { return "call.participant_joined" }
+func (CallParticipantLeft) EventName() string      { return "call.participant_left" }
+func (CallMissed) EventName() string               { return "call.missed" }
+func (CallRecordingRequested) EventName() string   { return "call.recording_requested" }
+func (CallRecordingStarted) EventName() string     { return "call.recording_started" }
+func (CallRecordingStopped) EventName() string     { return "call.recording_stopped" }
+func (CallStateChanged) EventName() string         { return "call.state_changed" }
+func (CallStateSnapshot) EventName() string        { return "call.state_snapshot" }
+func (CallEnded) EventName() string                { return "call.ended" }
+func (RoomStateSnapshot) EventName() string        { return "room.state_snapshot" }
+func (SupportAssignmentChanged) EventName() string { return "support.assignment_changed" }
+func (SupportNoteCreated) EventName() string       { return "support.note_created" }
+func (SupportSLABreached) EventName() string       { return "support.sla_breached" }
+func (UserProfileUpdated) EventName() string       { return "user.profile_updated" }
+func (GuestMerged) EventName() string              { return "guest.merged" }
+func (RoomStateChanged) EventName() string         { return "room.state_changed" }
+func (UserTyping) EventName() string               { return "user.typing" }
@@ -0,0 +1,112 @@
+package experiments
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"chatservice/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+var ErrUnknownExperiment = errors.New("unknown experiment")
+
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,63}$`)
+
+type Variant struct {
+	Name   string `json:"name"`
+	Weight int    `json:"weight"`
+}
+
+type Experiment struct {
+	Name     string    `json:"name"`
+	Variants []Variant `json:"variants"`
+	Enabled  *bool     `json:"enabled,omitempty"`
+}
+
+type Service struct {
+	experiments map[string]Experiment
+	repo        repository.ExperimentRepository
+}
+
+func Parse(raw string) ([]Experiment, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	var defs []Experiment
+	if err := json.Unmarshal([]byte(raw), &defs); err != nil {
+		return nil, fmt.Errorf("invalid experiment definitions: %w", err)
+	}
+	seen := make(map[string]bool, len(defs))
+	for _, def := range defs {
+		if !namePattern.MatchString(def.Name) || seen[def.Name] {
+			return nil, fmt.Errorf("invalid or duplicate experiment name %q", def.Name)
+		}
+		seen[def.Name] = true
+		total := 0
+		for _, v := range def.Variants {
+			if !namePattern.MatchString(v.Name) || v.Weight < 0 {
+				return nil, fmt.Errorf("experiment %s has an invalid variant %q", def.Name, v.Name)
+			}
+			total += v.Weight
+		}
+		if total <= 0 {
+			return nil, fmt.Errorf("experiment %s needs at least one weighted variant", def.Name)
+		}
+	}
+	return defs, nil
+}
+
+func NewService(defs []Experiment, repo repository.ExperimentRepository) *Service {
+	s := &Service{experiments: make(map[string]Experiment, len(defs)), repo: repo}
+	for _, def := range defs {
+		if def.Enabled == nil || *def.Enabled {
+			s.experiments[def.Name] = def
+		}
+	}
+	return s
+}
+
+func (s *Service) Assign(userID uuid.UUID, name string) (string, bool) {
+	def, ok := s.experiments[name]
+	if !ok {
+		return "", false
+	}
+	total := 0
+	for _, v := range def.Variants {
+		total += v.Weight
+	}
+	sum := sha256.Sum256([]byte(name + ":" + userID.String()))
+	bucket := int(binary.BigEndian.Uint64(sum[:8]) % uint64(total))
+	for _, v := range def.Variants {
+		if bucket < v.Weight {
+			return v.Name, true
+		}
+		bucket -= v.Weight
+	}
+	return def.Variants[len(def.Variants)-1].Name, true
+}
+
+func (s *Service) Assignments(userID uuid.UUID) map[string]string {
+	assignments := make(map[string]string, len(s.experiments))
+	for name := range s.experiments {
+		assignments[name], _ = s.Assign(userID, name)
+	}
+	return assignments
+}
+
+func (s *Service) Expose(ctx context.Context, userID uuid.UUID, name string) (string, error) {
+	variant, ok := s.Assign(userID, name)
+	if !ok {
+		return "", ErrUnknownExperiment
+	}
+	if err := s.repo.RecordExposure(ctx, name, userID, variant); err != nil {
+		return variant, err
+	}
+	return variant, nil
+}
@@ -0,0 +1,72 @@
+package firehose
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/repository"
+)
+
+const (
+	DefaultPageSize = 500
+	MaxPageSize     = 5000
+)
+
+type Record struct {
+	domain.Message
+	Deleted bool `json:"deleted"`
+}
+
+type Page struct {
+	Messages      []Record `json:"messages"`
+	NextCursor    int64    `json:"nextCursor"`
+	HighWatermark int64    `json:"highWatermark"`
+	HasMore       bool     `json:"hasMore"`
+}
+
+type Service struct {
+	repo   repository.FirehoseRepository
+	settle time.Duration
+}
+
+func NewService(repo repository.FirehoseRepository, settle time.Duration) *Service {
+	return &Service{repo: repo, settle: settle}
+}
+
+func (s *Service) Messages(ctx context.Context, after, until int64, limit int) (*Page, error) {
+	if after < 0 || until < 0 {
+		return nil, fmt.Errorf("cursor must not be negative")
+	}
+	if until > 0 && until <= after {
+		return nil, fmt.Errorf("until must be greater than after")
+	}
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	limit = min(limit, MaxPageSize)
+
+	messages, watermark, err := s.repo.GetMessageRange(ctx, after, until, s.settle, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &Page{
+		Messages:      make([]Record, 0, len(messages)),
+		NextCursor:    max(after, watermark),
+		HighWatermark: watermark,
+	}
+	for _, msg := range messages {
+		record := Record{Message: msg, Deleted: msg.DeletedAt != nil}
+		if record.Deleted {
+			record.Content, record.RichContent, record.Metadata = "", nil, nil
+		}
+		page.Messages = append(page.Messages, record)
+	}
+	if len(messages) == limit {
+		page.NextCursor = messages[len(messages)-1].ID
+		page.HasMore = page.NextCursor < watermark
+	}
+	return page, nil
+}
@@ -0,0 +1,51 @@
+package insights
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"chatservice/internal/repository"
+	"chatservice/internal/scheduler"
+)
+
+const (
+	backfillWindow = 90 * 24 * time.Hour
+	rollupWindow   = 24 * time.Hour
+)
+
+type Pipeline struct {
+	repos      map[string]repository.InsightsRepository
+	backfilled bool
+}
+
+func NewPipeline(repos map[string]repository.InsightsRepository) *Pipeline {
+	return &Pipeline{repos: repos}
+}
+
+func (p *Pipeline) Job(interval time.Duration) scheduler.Job {
+	return scheduler.Job{Name: "rollup-activity", Interval: interval, Run: p.rollup}
+}
+
+func (p *Pipeline) rollup(ctx context.Context) error {
+	window := rollupWindow
+	if !p.backfilled {
+		window = backfillWindow
+	}
+	from := time.Now().Add(-window)
+	var errs []error
+	for cluster, repo := range p.repos {
+		rows, err := repo.RollupActivity(ctx, from)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s cluster: %w", cluster, err))
+		} else if rows > 0 {
+			log.Printf("Insights rolled up %d activity rows on %s cluster", rows, cluster)
+		}
+	}
+	if len(errs) == 0 {
+		p.backfilled = true
+	}
+	return errors.Join(errs...)
+}
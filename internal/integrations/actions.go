@@ -0,0 +1,90 @@
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrActionsNotConfigured = errors.New("message actions are not configured")
+
+const SignatureHeader = "X-Chat-Signature"
+
+type Actor struct {
+	ID       uuid.UUID `json:"id"`
+	Nickname string    `json:"nickname,omitempty"`
+}
+
+type ActionCallback struct {
+	MessageID int64     `json:"messageId"`
+	RoomID    uuid.UUID `json:"roomId"`
+	BotID     uuid.UUID `json:"botId"`
+	ActionID  string    `json:"actionId"`
+	Actor     Actor     `json:"actor"`
+	At        time.Time `json:"at"`
+}
+
+type ActionDispatcher struct {
+	secret []byte
+	client *http.Client
+}
+
+func NewActionDispatcher(secret string) *ActionDispatcher {
+	return &ActionDispatcher{
+		secret: []byte(secret),
+		client: NewWebhookClient(5 * time.Second),
+	}
+}
+
+func (d *ActionDispatcher) Dispatch(ctx context.Context, url string, callback ActionCallback) error {
+	if d == nil || len(d.secret) == 0 {
+		return ErrActionsNotConfigured
+	}
+	if !ValidWebhookURL(url) {
+		return fmt.Errorf("%w: %s", ErrForbiddenDestination, url)
+	}
+	body, err := json.Marshal(callback)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid action webhook URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, d.sign(body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error contacting action webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("action webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (d *ActionDispatcher) Retry(ctx context.Context, url string, payload json.RawMessage) error {
+	var callback ActionCallback
+	if err := json.Unmarshal(payload, &callback); err != nil {
+		return fmt.Errorf("malformed action callback: %w", err)
+	}
+	return d.Dispatch(ctx, url, callback)
+}
+
+func (d *ActionDispatcher) sign(body []byte) string {
+	mac := hmac.New(sha256.New, d.secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
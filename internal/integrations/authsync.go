@@ -0,0 +1,86 @@
+package integrations
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrAuthSyncNotConfigured = errors.New("auth service sync is not configured")
+	ErrInvalidAuthSignature  = errors.New("invalid auth event signature")
+	ErrAuthEventExpired      = errors.New("auth event timestamp is outside the accepted window")
+)
+
+const AuthSignatureHeader = "X-Auth-Signature"
+
+// authEventMaxSkew bounds how far an event's signed timestamp may be from
+// now, so a captured body cannot be replayed later.
+const authEventMaxSkew = 5 * time.Minute
+
+const (
+	AuthEventUserUpdated      = "user.updated"
+	AuthEventIdentityLinked   = "identity.linked"
+	AuthEventIdentityUnlinked = "identity.unlinked"
+)
+
+type AuthIdentity struct {
+	Provider string    `json:"provider"`
+	Login    string    `json:"login,omitempty"`
+	LinkedAt time.Time `json:"linkedAt"`
+}
+
+type AuthEvent struct {
+	Event      string         `json:"event"`
+	UserID     uuid.UUID      `json:"userId"`
+	Email      *string        `json:"email,omitempty"`
+	Username   *string        `json:"username,omitempty"`
+	Nickname   *string        `json:"nickname,omitempty"`
+	Identity   *AuthIdentity  `json:"identity,omitempty"`
+	Identities []AuthIdentity `json:"identities,omitempty"`
+	Timestamp  time.Time      `json:"timestamp"`
+}
+
+type AuthSync struct {
+	secret []byte
+}
+
+func NewAuthSync(secret string) *AuthSync {
+	if secret == "" {
+		return nil
+	}
+	return &AuthSync{secret: []byte(secret)}
+}
+
+func (s *AuthSync) Parse(body []byte, signature string) (*AuthEvent, error) {
+	if s == nil {
+		return nil, ErrAuthSyncNotConfigured
+	}
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return nil, ErrInvalidAuthSignature
+	}
+
+	var event AuthEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("invalid auth event payload: %w", err)
+	}
+	if event.UserID == uuid.Nil {
+		return nil, fmt.Errorf("invalid auth event payload: userId is required")
+	}
+	if event.Timestamp.IsZero() {
+		return nil, fmt.Errorf("invalid auth event payload: timestamp is required")
+	}
+	if skew := time.Since(event.Timestamp); skew > authEventMaxSkew || skew < -authEventMaxSkew {
+		return nil, ErrAuthEventExpired
+	}
+	return &event, nil
+}
@@ -0,0 +1,77 @@
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type SuggestionRequest struct {
+	Language string           `json:"language,omitempty"`
+	Context  []SummaryMessage `json:"context"`
+	Message  SummaryMessage   `json:"message"`
+}
+
+type SuggestionProvider interface {
+	Suggest(ctx context.Context, request SuggestionRequest) ([]string, error)
+}
+
+type NoopSuggestions struct{}
+
+func (NoopSuggestions) Suggest(context.Context, SuggestionRequest) ([]string, error) {
+	return nil, nil
+}
+
+type suggestionResponse struct {
+	Suggestions []string `json:"suggestions"`
+}
+
+type SuggestionHook struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func NewSuggestionHook(url, token string) SuggestionProvider {
+	if url == "" {
+		return NoopSuggestions{}
+	}
+	return &SuggestionHook{
+		url:    url,
+		token:  token,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (s *SuggestionHook) Suggest(ctx context.Context, request SuggestionRequest) ([]string, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid suggestion hook URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error contacting suggestion hook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("suggestion hook returned status %d", resp.StatusCode)
+	}
+	var decoded suggestionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("malformed suggestion hook response: %w", err)
+	}
+	return decoded.Suggestions, nil
+}
@@ -0,0 +1,80 @@
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+var ErrSummarizerNotConfigured = errors.New("summary hook is not configured")
+
+type SummaryMessage struct {
+	Author string    `json:"author"`
+	Text   string    `json:"text"`
+	SentAt time.Time `json:"sentAt"`
+}
+
+type SummaryRequest struct {
+	RoomName string           `json:"roomName"`
+	Language string           `json:"language,omitempty"`
+	Messages []SummaryMessage `json:"messages"`
+}
+
+type Summarizer interface {
+	Summarize(ctx context.Context, request SummaryRequest) (string, error)
+}
+
+type summaryResponse struct {
+	Summary string `json:"summary"`
+}
+
+type SummaryHook struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func NewSummaryHook(url, token string) Summarizer {
+	if url == "" {
+		return nil
+	}
+	return &SummaryHook{
+		url:    url,
+		token:  token,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *SummaryHook) Summarize(ctx context.Context, request SummaryRequest) (string, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("invalid summary hook URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error contacting summary hook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("summary hook returned status %d", resp.StatusCode)
+	}
+	var decoded summaryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return "", fmt.Errorf("malformed summary hook response: %w", err)
+	}
+	return decoded.Summary, nil
+}
@@ -0,0 +1,73 @@
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+var ErrTranslationNotConfigured = errors.New("translation hook is not configured")
+
+type TranslationRequest struct {
+	Text    string   `json:"text"`
+	Source  string   `json:"source"`
+	Targets []string `json:"targets"`
+}
+
+type translationResponse struct {
+	Translations map[string]string `json:"translations"`
+}
+
+type Translator struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func NewTranslator(url, token string) *Translator {
+	if url == "" {
+		return nil
+	}
+	return &Translator{
+		url:    url,
+		token:  token,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (t *Translator) Translate(ctx context.Context, request TranslationRequest) (map[string]string, error) {
+	if t == nil {
+		return nil, ErrTranslationNotConfigured
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid translation hook URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if t.token != "" {
+		req.Header.Set("Authorization", "Bearer "+t.token)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error contacting translation hook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("translation hook returned status %d", resp.StatusCode)
+	}
+	var decoded translationResponse
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("malformed translation hook response: %w", err)
+	}
+	return decoded.Translations, nil
+}
@@ -0,0 +1,143 @@
+package janitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"chatservice/internal/repository"
+	"chatservice/internal/scheduler"
+)
+
+const quotaUsageRetentionDays = 2
+
+type Config struct {
+	Interval       time.Duration
+	DraftTTL       time.Duration
+	PushTTL        time.Duration
+	IdempotencyTTL time.Duration
+	SummaryTTL     time.Duration
+	RemoveBlob     func(key string) error
+}
+
+type Janitor struct {
+	cfg   Config
+	repos map[string]repository.MaintenanceRepository
+}
+
+func New(cfg Config, repos map[string]repository.MaintenanceRepository) *Janitor {
+	return &Janitor{cfg: cfg, repos: repos}
+}
+
+func (j *Janitor) Jobs() []scheduler.Job {
+	if j.cfg.Interval <= 0 {
+		return nil
+	}
+	jobs := []scheduler.Job{
+		{Name: "expire-uploads", Interval: j.cfg.Interval, Run: j.expireUploads},
+		{Name: "expire-quota-usage", Interval: j.cfg.Interval, Run: j.expireQuotaUsage},
+	}
+	if j.cfg.DraftTTL > 0 {
+		jobs = append(jobs, scheduler.Job{Name: "expire-drafts", Interval: j.cfg.Interval, Run: j.expireDrafts})
+	}
+	if j.cfg.PushTTL > 0 {
+		jobs = append(jobs, scheduler.Job{Name: "expire-sent-pushes", Interval: j.cfg.Interval, Run: j.expireSentPushes})
+	}
+	if j.cfg.IdempotencyTTL > 0 {
+		jobs = append(jobs, scheduler.Job{Name: "expire-idempotency-keys", Interval: j.cfg.Interval, Run: j.expireIdempotencyKeys})
+	}
+	if j.cfg.SummaryTTL > 0 {
+		jobs = append(jobs, scheduler.Job{Name: "expire-room-summaries", Interval: j.cfg.Interval, Run: j.expireRoomSummaries})
+	}
+	return jobs
+}
+
+func (j *Janitor) expireDrafts(ctx context.Context) error {
+	var errs []error
+	for cluster, repo := range j.repos {
+		expired, err := repo.ExpireDrafts(ctx, time.Now().Add(-j.cfg.DraftTTL))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s cluster: %w", cluster, err))
+		} else if expired > 0 {
+			log.Printf("Janitor expired %d stale drafts on %s cluster", expired, cluster)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (j *Janitor) expireSentPushes(ctx context.Context) error {
+	var errs []error
+	for cluster, repo := range j.repos {
+		expired, err := repo.ExpireSentPushes(ctx, time.Now().Add(-j.cfg.PushTTL))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s cluster: %w", cluster, err))
+		} else if expired > 0 {
+			log.Printf("Janitor expired %d sent push records on %s cluster", expired, cluster)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (j *Janitor) expireIdempotencyKeys(ctx context.Context) error {
+	var errs []error
+	for cluster, repo := range j.repos {
+		expired, err := repo.ExpireIdempotencyKeys(ctx, time.Now().Add(-j.cfg.IdempotencyTTL))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s cluster: %w", cluster, err))
+		} else if expired > 0 {
+			log.Printf("Janitor expired %d idempotency keys on %s cluster", expired, cluster)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (j *Janitor) expireRoomSummaries(ctx context.Context) error {
+	var errs []error
+	for cluster, repo := range j.repos {
+		expired, err := repo.ExpireRoomSummaries(ctx, time.Now().Add(-j.cfg.SummaryTTL))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s cluster: %w", cluster, err))
+		} else if expired > 0 {
+			log.Printf("Janitor expired %d cached room summaries on %s cluster", expired, cluster)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (j *Janitor) expireQuotaUsage(ctx context.Context) error {
+	var errs []error
+	for cluster, repo := range j.repos {
+		expired, err := repo.ExpireQuotaUsage(ctx, time.Now().UTC().AddDate(0, 0, -quotaUsageRetentionDays))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s cluster: %w", cluster, err))
+		} else if expired > 0 {
+			log.Printf("Janitor expired %d daily quota counters on %s cluster", expired, cluster)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (j *Janitor) expireUploads(ctx context.Context) error {
+	var errs []error
+	for cluster, repo := range j.repos {
+		keys, err := repo.ExpireUploads(ctx, time.Now())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s cluster: %w", cluster, err))
+			continue
+		}
+		for _, key := range keys {
+			if j.cfg.RemoveBlob == nil {
+				break
+			}
+			if err := j.cfg.RemoveBlob(key); err != nil {
+				log.Printf("Janitor failed to remove blob of expired upload %s: %v", key, err)
+			}
+		}
+		if len(keys) > 0 {
+			log.Printf("Janitor expired %d abandoned uploads on %s cluster", len(keys), cluster)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,69 @@
+package logbuf
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Entry struct {
+	At   time.Time `json:"at"`
+	Line string    `json:"line"`
+}
+
+type Recent struct {
+	mu      sync.Mutex
+	entries []Entry
+	next    int
+	full    bool
+	partial []byte
+}
+
+func NewRecent(size int) *Recent {
+	return &Recent{entries: make([]Entry, max(size, 1))}
+}
+
+func (r *Recent) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data := append(r.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		r.add(string(data[:i]))
+		data = data[i+1:]
+	}
+	r.partial = append(r.partial[:0], data...)
+	return len(p), nil
+}
+
+func (r *Recent) add(line string) {
+	lower := strings.ToLower(line)
+	if !strings.Contains(lower, "error") && !strings.Contains(lower, "fail") && !strings.Contains(lower, "panic") {
+		return
+	}
+	r.entries[r.next] = Entry{At: time.Now().UTC(), Line: line}
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+func (r *Recent) Entries() []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := r.next
+	if r.full {
+		count = len(r.entries)
+	}
+	entries := make([]Entry, 0, count)
+	for i := 1; i <= count; i++ {
+		entries = append(entries, r.entries[(r.next-i+len(r.entries))%len(r.entries)])
+	}
+	return entries
+}
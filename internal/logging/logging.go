@@ -0,0 +1,113 @@
+package logging
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+type Level int32
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = map[Level]string{LevelDebug: "DEBUG", LevelInfo: "INFO", LevelWarn: "WARN", LevelError: "ERROR"}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+func ParseLevel(name string) (Level, error) {
+	for level, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			return level, nil
+		}
+	}
+	if strings.EqualFold(name, "warning") {
+		return LevelWarn, nil
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q", name)
+}
+
+type Logger struct {
+	module string
+	level  atomic.Int32
+}
+
+var (
+	mu           sync.Mutex
+	loggers      = make(map[string]*Logger)
+	defaultLevel = LevelInfo
+	moduleLevels = make(map[string]Level)
+	redact       atomic.Bool
+)
+
+func init() {
+	redact.Store(true)
+}
+
+func For(module string) *Logger {
+	mu.Lock()
+	defer mu.Unlock()
+	if logger, ok := loggers[module]; ok {
+		return logger
+	}
+	logger := &Logger{module: module}
+	logger.level.Store(int32(levelFor(module)))
+	loggers[module] = logger
+	return logger
+}
+
+func Configure(level string, modules map[string]string) error {
+	def, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	levels := make(map[string]Level, len(modules))
+	for module, name := range modules {
+		if levels[module], err = ParseLevel(name); err != nil {
+			return fmt.Errorf("module %s: %w", module, err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	defaultLevel, moduleLevels = def, levels
+	for module, logger := range loggers {
+		logger.level.Store(int32(levelFor(module)))
+	}
+	return nil
+}
+
+func SetRedaction(enabled bool) {
+	redact.Store(enabled)
+}
+
+func levelFor(module string) Level {
+	if level, ok := moduleLevels[module]; ok {
+		return level
+	}
+	return defaultLevel
+}
+
+func (l *Logger) Enabled(level Level) bool {
+	return level >= Level(l.level.Load())
+}
+
+func (l *Logger) Debugf(format string, args ...any) { l.output(LevelDebug, format, args) }
+func (l *Logger) Infof(format string, args ...any)  { l.output(LevelInfo, format, args) }
+func (l *Logger) Warnf(format string, args ...any)  { l.output(LevelWarn, format, args) }
+func (l *Logger) Errorf(format string, args ...any) { l.output(LevelError, format, args) }
+
+func (l *Logger) output(level Level, format string, args []any) {
+	if !l.Enabled(level) {
+		return
+	}
+	log.Output(3, level.String()+" ["+l.module+"] "+fmt.Sprintf(format, args...))
+}
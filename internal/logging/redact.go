@@ -0,0 +1,34 @@
+package logging
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+var (
+	emailPattern     = regexp.MustCompile(`([A-Za-z0-9._%+\-])[A-Za-z0-9._%+\-]*@([A-Za-z0-9.\-]+\.[A-Za-z]{2,})`)
+	secretPattern    = regexp.MustCompile(`(?i)\b(token|session_token|secret|password|cookie|authorization)(["']?\s*[=:]\s*["']?)(Bearer\s+)?[^\s"'&,;]+`)
+	contentPattern   = regexp.MustCompile(`(?i)("(?:content|text|body|message|preview)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+	longTokenPattern = regexp.MustCompile(`[A-Za-z0-9_\-.=]{32,}`)
+)
+
+func Redact(msg string) string {
+	msg = contentPattern.ReplaceAllString(msg, `$1"[REDACTED]"`)
+	msg = secretPattern.ReplaceAllString(msg, "$1$2[REDACTED]")
+	msg = emailPattern.ReplaceAllString(msg, "$1***@$2")
+	return longTokenPattern.ReplaceAllStringFunc(msg, func(match string) string {
+		if _, err := uuid.Parse(match); err == nil || !strings.ContainsAny(match, "0123456789") {
+			return match
+		}
+		return "[REDACTED]"
+	})
+}
+
+func Scrub(msg string) string {
+	if !redact.Load() {
+		return msg
+	}
+	return Redact(msg)
+}
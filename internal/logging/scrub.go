@@ -0,0 +1,21 @@
+package logging
+
+import "io"
+
+type Scrubber struct {
+	w io.Writer
+}
+
+func NewScrubber(w io.Writer) *Scrubber {
+	return &Scrubber{w: w}
+}
+
+func (s *Scrubber) Write(p []byte) (int, error) {
+	if !redact.Load() {
+		return s.w.Write(p)
+	}
+	if _, err := io.WriteString(s.w, Redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
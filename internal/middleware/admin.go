@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func AdminMiddleware(adminUserIDs []string) gin.HandlerFunc {
+	admins := make(map[uuid.UUID]bool, len(adminUserIDs))
+	for _, raw := range adminUserIDs {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			middlewareLog.Warnf("Ignoring invalid admin user ID %q: %v", raw, err)
+			continue
+		}
+		admins[id] = true
+	}
+
+	return func(c *gin.Context) {
+		userID, ok := c.Get(UserIDKey)
+		if !ok || !admins[userID.(uuid.UUID)] {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			return
+		}
+		c.Next()
+	}
+}
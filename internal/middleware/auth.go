@@ -4,16 +4,19 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"time"
 
+	"chatservice/internal/tenant"
+
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
 const (
 	UserIDKey      = "userID"
+	TenantKey      = "tenant"
+	UserEmailKey   = "userEmail"
 	AuthCookieName = "session_token"
 )
 
@@ -21,6 +24,7 @@ type UserData struct {
 	ID       uuid.UUID `json:"id"`
 	Email    string    `json:"email"`
 	Nickname string    `json:"nickname"`
+	Tenant   string    `json:"tenant"`
 }
 
 type AuthResponse struct {
@@ -35,26 +39,23 @@ func AuthMiddleware(authServiceURL string) gin.HandlerFunc {
 	}
 
 	return func(c *gin.Context) {
-		log.Println("[AUTH-TRACE] Middleware started.")
-
 		sessionToken, err := c.Cookie(AuthCookieName)
 		if err != nil {
-			log.Println("[AUTH-TRACE] FAILED: Could not get session cookie.")
+			authLog.Debugf("Rejecting %s %s: no session cookie", c.Request.Method, c.Request.URL.Path)
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization cookie not found"})
 			return
 		}
 		if sessionToken == "" {
-			log.Println("[AUTH-TRACE] FAILED: Session cookie is empty.")
+			authLog.Debugf("Rejecting %s %s: empty session cookie", c.Request.Method, c.Request.URL.Path)
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization token is missing"})
 			return
 		}
 
 		validationURL := fmt.Sprintf("%s/auth/me", authServiceURL)
-		log.Printf("[AUTH-TRACE] Preparing to call auth service at: %s", validationURL)
 
 		req, err := http.NewRequestWithContext(c.Request.Context(), "GET", validationURL, nil)
 		if err != nil {
-			log.Printf("[AUTH-TRACE] FAILED: Error creating auth request: %v", err)
+			authLog.Errorf("Error creating auth request: %v", err)
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
 			return
 		}
@@ -66,40 +67,38 @@ func AuthMiddleware(authServiceURL string) gin.HandlerFunc {
 
 		resp, err := client.Do(req)
 		if err != nil {
-			log.Printf("[AUTH-TRACE] FAILED: Error contacting auth service: %v", err)
+			authLog.Errorf("Error contacting auth service: %v", err)
 			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Authentication service is unavailable"})
 			return
 		}
 		defer resp.Body.Close()
 
-		log.Printf("[AUTH-TRACE] Auth service responded with status code: %d", resp.StatusCode)
-
 		body, err := io.ReadAll(resp.Body)
 		if err != nil {
-			log.Printf("[AUTH-TRACE] FAILED: Could not read response body: %v", err)
+			authLog.Errorf("Error reading auth service response: %v", err)
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to read auth response"})
 			return
 		}
 
 		if resp.StatusCode != http.StatusOK {
-			log.Printf("[AUTH-TRACE] FAILED: Auth service returned non-200 status. Body: %s", string(body))
+			authLog.Debugf("Auth service rejected session with status %d", resp.StatusCode)
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired session"})
 			return
 		}
 
-		log.Printf("[AUTH-TRACE] Auth service response body: %s", string(body))
-
 		var authResp AuthResponse
 		if err := json.Unmarshal(body, &authResp); err != nil {
-			log.Printf("[AUTH-TRACE] FAILED: Error decoding auth response JSON: %v", err)
+			authLog.Errorf("Error decoding auth service response: %v", err)
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Internal server error on auth response"})
 			return
 		}
 
-		log.Printf("[AUTH-TRACE] SUCCESS: User authenticated. ID: %s", authResp.User.ID)
+		authLog.Debugf("Authenticated user %s for %s %s", authResp.User.ID, c.Request.Method, c.Request.URL.Path)
 		c.Set(UserIDKey, authResp.User.ID)
-		
-		log.Println("[AUTH-TRACE] Middleware finished, calling next handler.")
+		c.Set(TenantKey, authResp.User.Tenant)
+		c.Set(UserEmailKey, authResp.User.Email)
+		c.Request = c.Request.WithContext(tenant.WithTenant(c.Request.Context(), authResp.User.Tenant))
+
 		c.Next()
 	}
 }
\ No newline at end of file
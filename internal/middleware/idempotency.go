@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	"chatservice/internal/domain"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	maxIdempotencyKey    = 255
+	maxIdempotentBody    = 1 << 20
+)
+
+type IdempotencyStore interface {
+	Reserve(ctx context.Context, userID uuid.UUID, key, fingerprint string, expiredBefore time.Time) (*domain.IdempotencyRecord, error)
+	Complete(ctx context.Context, userID uuid.UUID, key string, statusCode int, contentType string, body []byte) error
+	Release(ctx context.Context, userID uuid.UUID, key string) error
+}
+
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+func IdempotencyMiddleware(store IdempotencyStore, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" || (c.Request.Method != http.MethodPost && c.Request.Method != http.MethodPut) {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKey {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key must be at most 255 characters"})
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxIdempotentBody+1))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Could not read request body"})
+			return
+		}
+		if len(body) > maxIdempotentBody {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body is too large for an idempotent request"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		userID := c.MustGet(UserIDKey).(uuid.UUID)
+		ctx := c.Request.Context()
+		fingerprint := requestFingerprint(c.Request.Method, c.Request.URL.RequestURI(), body)
+		record, err := store.Reserve(ctx, userID, key, fingerprint, time.Now().Add(-ttl))
+		if err != nil {
+			middlewareLog.Errorf("Error reserving idempotency key for %s: %v", userID, err)
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Could not process idempotent request"})
+			return
+		}
+		if record != nil {
+			replayIdempotent(c, record, fingerprint)
+			return
+		}
+
+		writer := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		defer func() {
+			if p := recover(); p != nil {
+				if err := store.Release(context.WithoutCancel(ctx), userID, key); err != nil {
+					middlewareLog.Errorf("Error releasing idempotency key for %s after a panic: %v", userID, err)
+				}
+				panic(p)
+			}
+		}()
+		c.Next()
+
+		ctx = context.WithoutCancel(ctx)
+		if status := writer.Status(); status < http.StatusInternalServerError && writer.body.Len() <= maxIdempotentBody {
+			err = store.Complete(ctx, userID, key, status, writer.Header().Get("Content-Type"), writer.body.Bytes())
+		} else {
+			err = store.Release(ctx, userID, key)
+		}
+		if err != nil {
+			middlewareLog.Errorf("Error recording idempotent response for %s: %v", userID, err)
+		}
+	}
+}
+
+func replayIdempotent(c *gin.Context, record *domain.IdempotencyRecord, fingerprint string) {
+	switch {
+	case record.Fingerprint != fingerprint:
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used for a different request", "code": "idempotency_key_reused"})
+	case record.StatusCode == 0:
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is still in progress", "code": "idempotency_key_in_progress"})
+	default:
+		c.Header("Idempotent-Replayed", "true")
+		if record.ContentType == "" {
+			c.AbortWithStatus(record.StatusCode)
+			return
+		}
+		c.Data(record.StatusCode, record.ContentType, record.Body)
+		c.Abort()
+	}
+}
+
+func requestFingerprint(method, uri string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method))
+	hash.Write([]byte{0})
+	hash.Write([]byte(uri))
+	hash.Write([]byte{0})
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type AccessChecker interface {
+	IsAllowed(ctx context.Context, userID uuid.UUID, email string) (bool, error)
+}
+
+func InviteOnlyMiddleware(checker AccessChecker, adminUserIDs []string) gin.HandlerFunc {
+	admins := make(map[uuid.UUID]bool, len(adminUserIDs))
+	for _, raw := range adminUserIDs {
+		if id, err := uuid.Parse(raw); err == nil {
+			admins[id] = true
+		}
+	}
+
+	return func(c *gin.Context) {
+		userID := c.MustGet(UserIDKey).(uuid.UUID)
+		if admins[userID] {
+			c.Next()
+			return
+		}
+		allowed, err := checker.IsAllowed(c.Request.Context(), userID, c.GetString(UserEmailKey))
+		if err != nil {
+			middlewareLog.Errorf("Error checking invite allowlist for %s: %v", userID, err)
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Could not verify access"})
+			return
+		}
+		if !allowed {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "This service is invite-only", "code": "not_invited"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,8 @@
+package middleware
+
+import "chatservice/internal/logging"
+
+var (
+	authLog       = logging.For("auth")
+	middlewareLog = logging.For("middleware")
+)
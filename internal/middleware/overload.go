@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LoadMonitor interface {
+	Overloaded() bool
+	RetryAfter() time.Duration
+}
+
+func ShedHeavyRoutes(monitor LoadMonitor, routes ...string) gin.HandlerFunc {
+	heavy := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		heavy[route] = true
+	}
+
+	return func(c *gin.Context) {
+		if heavy[c.FullPath()] && monitor.Overloaded() {
+			c.Header("Retry-After", strconv.Itoa(max(1, int(monitor.RetryAfter().Seconds()))))
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Service is overloaded, try again later", "code": "overloaded"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,212 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"chatservice/internal/outbox"
+
+	"github.com/google/uuid"
+)
+
+type pendingKey struct {
+	userID uuid.UUID
+	roomID uuid.UUID
+}
+
+type pendingPush struct {
+	ctx           context.Context
+	timer         *time.Timer
+	count         int
+	lastMessageID int64
+	notification  Notification
+}
+
+type Dispatcher struct {
+	pusher  Pusher
+	email   *EmailSender
+	window  time.Duration
+	masker  *Masker
+	tracker PushTracker
+	outbox  *outbox.Service
+	limiter *rateLimiter
+
+	mu      sync.Mutex
+	pending map[pendingKey]*pendingPush
+}
+
+func NewDispatcher(pusher Pusher, email *EmailSender, window time.Duration) *Dispatcher {
+	return &Dispatcher{
+		pusher:  pusher,
+		email:   email,
+		window:  window,
+		pending: make(map[pendingKey]*pendingPush),
+	}
+}
+
+func (d *Dispatcher) Send(ctx context.Context, userID uuid.UUID, n Notification) {
+	if err := d.Deliver(ctx, userID, n); err != nil && !errors.Is(err, ErrNoDevices) {
+		log.Printf("Failed to push notification to %s: %v", userID, err)
+	}
+}
+
+func (d *Dispatcher) SetMasker(masker *Masker) { d.masker = masker }
+
+func (d *Dispatcher) Deliver(ctx context.Context, userID uuid.UUID, n Notification) error {
+	return d.push(ctx, userID, d.redact(n))
+}
+
+func (d *Dispatcher) SetOutbox(failed *outbox.Service) { d.outbox = failed }
+
+func (d *Dispatcher) SetRateLimit(perSecond int) {
+	if perSecond > 0 {
+		d.limiter = newRateLimiter(perSecond)
+	}
+}
+
+type pushDelivery struct {
+	UserID       uuid.UUID    `json:"userId"`
+	Notification Notification `json:"notification"`
+}
+
+func (d *Dispatcher) push(ctx context.Context, userID uuid.UUID, n Notification) error {
+	if err := d.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	err := d.pusher.Push(ctx, userID, n)
+	if err != nil && !errors.Is(err, ErrNoDevices) && d.outbox != nil {
+		d.outbox.Record(ctx, outbox.KindPush, userID.String(), pushDelivery{UserID: userID, Notification: n}, err)
+	}
+	return err
+}
+
+func (d *Dispatcher) RetryPush(ctx context.Context, target string, payload json.RawMessage) error {
+	var delivery pushDelivery
+	if err := json.Unmarshal(payload, &delivery); err != nil {
+		return fmt.Errorf("malformed push delivery: %w", err)
+	}
+	if stale, err := d.stale(ctx, delivery.Notification); err != nil || stale {
+		return err
+	}
+	if err := d.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	err := d.pusher.Push(ctx, delivery.UserID, delivery.Notification)
+	if errors.Is(err, ErrNoDevices) {
+		return nil
+	}
+	return err
+}
+
+func (d *Dispatcher) stale(ctx context.Context, n Notification) (bool, error) {
+	if d.tracker == nil || n.Type != TypeMessage {
+		return false, nil
+	}
+	messageID, err := strconv.ParseInt(n.Data["message_id"], 10, 64)
+	if err != nil {
+		return false, nil
+	}
+	exists, err := d.tracker.MessageExists(ctx, messageID)
+	return !exists, err
+}
+
+func (d *Dispatcher) redact(n Notification) Notification {
+	if n.HidePreview {
+		n.Body = fmt.Sprintf("New message from %s", n.Title)
+		return n
+	}
+	n.Body = shorten(d.masker.Mask(n.Body))
+	return n
+}
+
+func (d *Dispatcher) Email() *EmailSender { return d.email }
+
+func (d *Dispatcher) QueueMessage(ctx context.Context, userID, roomID uuid.UUID, messageID int64, n Notification) {
+	if d.window <= 0 {
+		go d.sendMessage(ctx, userID, roomID, messageID, 1, n)
+		return
+	}
+
+	key := pendingKey{userID: userID, roomID: roomID}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if n.Data["urgent"] == "true" {
+		count := 1
+		if p, ok := d.pending[key]; ok {
+			p.timer.Stop()
+			delete(d.pending, key)
+			count += p.count
+		}
+		go d.sendMessage(ctx, userID, roomID, messageID, count, n)
+		return
+	}
+	if p, ok := d.pending[key]; ok {
+		p.count++
+		p.lastMessageID = messageID
+		if p.notification.Data["mention"] == "true" {
+			n.Data["mention"] = "true"
+			n.Data["priority"] = "high"
+		}
+		p.notification = n
+		return
+	}
+	d.pending[key] = &pendingPush{
+		ctx:           ctx,
+		timer:         time.AfterFunc(d.window, func() { d.flush(key) }),
+		count:         1,
+		lastMessageID: messageID,
+		notification:  n,
+	}
+}
+
+func (d *Dispatcher) CancelRead(userID, roomID uuid.UUID, readMessageID int64) {
+	key := pendingKey{userID: userID, roomID: roomID}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if p, ok := d.pending[key]; ok && readMessageID >= p.lastMessageID {
+		p.timer.Stop()
+		delete(d.pending, key)
+	}
+}
+
+func (d *Dispatcher) Supersede(roomID uuid.UUID, messageID int64, content string, deleted bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for key, p := range d.pending {
+		if key.roomID != roomID || p.lastMessageID != messageID {
+			continue
+		}
+		switch {
+		case !deleted:
+			p.notification.Body = content
+		case p.count > 1:
+			p.count--
+		default:
+			p.timer.Stop()
+			delete(d.pending, key)
+		}
+	}
+}
+
+func (d *Dispatcher) flush(key pendingKey) {
+	d.mu.Lock()
+	p, ok := d.pending[key]
+	delete(d.pending, key)
+	d.mu.Unlock()
+
+	if !ok {
+		return
+	}
+	d.sendMessage(p.ctx, key.userID, key.roomID, p.lastMessageID, p.count, p.notification)
+}
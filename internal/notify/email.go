@@ -0,0 +1,177 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	emailCooldown  = time.Hour
+	emailChangeTTL = 24 * time.Hour
+)
+
+var ErrEmailLinksDisabled = errors.New("email link secret is not configured")
+
+var friendRequestTemplate = template.Must(template.New("friend_request").Parse(
+	`Hi {{.RecipientName}},
+
+{{.SenderName}} sent you a friend request. Sign in to accept or ignore it.
+
+You are receiving this email because you were not online when the request arrived.
+To stop receiving these emails, visit: {{.UnsubscribeURL}}
+`))
+
+var emailChangeTemplate = template.Must(template.New("email_change").Parse(
+	`Hi,
+
+Someone asked to use this address for their chat account. If that was you, confirm the change within 24 hours:
+{{.ConfirmURL}}
+
+If you did not request this, you can ignore this email.
+`))
+
+type friendRequestEmail struct {
+	RecipientName  string
+	SenderName     string
+	UnsubscribeURL string
+}
+
+type EmailSender struct {
+	mailer  Mailer
+	secret  []byte
+	baseURL string
+
+	mu          sync.Mutex
+	lastSent    map[uuid.UUID]time.Time
+	lastChanged map[uuid.UUID]time.Time
+}
+
+func NewEmailSender(mailer Mailer, secret, baseURL string) *EmailSender {
+	return &EmailSender{
+		mailer:      mailer,
+		secret:      []byte(secret),
+		baseURL:     strings.TrimRight(baseURL, "/"),
+		lastSent:    make(map[uuid.UUID]time.Time),
+		lastChanged: make(map[uuid.UUID]time.Time),
+	}
+}
+
+func (e *EmailSender) FriendRequest(ctx context.Context, userID uuid.UUID, to, recipientName, senderName string) error {
+	if len(e.secret) == 0 {
+		return ErrEmailLinksDisabled
+	}
+	if !e.allow(e.lastSent, userID) {
+		return fmt.Errorf("email to %s rate limited", userID)
+	}
+
+	var body strings.Builder
+	err := friendRequestTemplate.Execute(&body, friendRequestEmail{
+		RecipientName:  recipientName,
+		SenderName:     senderName,
+		UnsubscribeURL: e.unsubscribeURL(userID),
+	})
+	if err != nil {
+		return fmt.Errorf("error rendering friend request email: %w", err)
+	}
+	return e.mailer.Send(ctx, to, fmt.Sprintf("%s sent you a friend request", senderName), body.String())
+}
+
+func (e *EmailSender) EmailChange(ctx context.Context, userID uuid.UUID, to string) error {
+	if len(e.secret) == 0 {
+		return ErrEmailLinksDisabled
+	}
+	if !e.allow(e.lastChanged, userID) {
+		return fmt.Errorf("email to %s rate limited", userID)
+	}
+	token := e.EmailChangeToken(userID, to, time.Now().Add(emailChangeTTL))
+	var body strings.Builder
+	err := emailChangeTemplate.Execute(&body, struct{ ConfirmURL string }{
+		ConfirmURL: fmt.Sprintf("%s/email/verify?token=%s", e.baseURL, url.QueryEscape(token)),
+	})
+	if err != nil {
+		return fmt.Errorf("error rendering email change email: %w", err)
+	}
+	return e.mailer.Send(ctx, to, "Confirm your new email address", body.String())
+}
+
+func (e *EmailSender) allow(sent map[uuid.UUID]time.Time, userID uuid.UUID) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+	if last, ok := sent[userID]; ok && now.Sub(last) < emailCooldown {
+		return false
+	}
+	sent[userID] = now
+	return true
+}
+
+func (e *EmailSender) unsubscribeURL(userID uuid.UUID) string {
+	return fmt.Sprintf("%s/unsubscribe?token=%s", e.baseURL, url.QueryEscape(e.UnsubscribeToken(userID)))
+}
+
+func (e *EmailSender) UnsubscribeToken(userID uuid.UUID) string {
+	return userID.String() + "." + e.sign("unsubscribe", userID.String())
+}
+
+func (e *EmailSender) VerifyUnsubscribeToken(token string) (uuid.UUID, bool) {
+	if len(e.secret) == 0 {
+		return uuid.Nil, false
+	}
+	rawID, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(e.sign("unsubscribe", rawID))) {
+		return uuid.Nil, false
+	}
+	userID, err := uuid.Parse(rawID)
+	return userID, err == nil
+}
+
+func (e *EmailSender) EmailChangeToken(userID uuid.UUID, email string, expires time.Time) string {
+	claims := base64.RawURLEncoding.EncodeToString([]byte(userID.String() + "\n" + email + "\n" + strconv.FormatInt(expires.Unix(), 10)))
+	return claims + "." + e.sign("email", claims)
+}
+
+func (e *EmailSender) VerifyEmailChangeToken(token string) (uuid.UUID, string, bool) {
+	if len(e.secret) == 0 {
+		return uuid.Nil, "", false
+	}
+	claims, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(e.sign("email", claims))) {
+		return uuid.Nil, "", false
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(claims)
+	if err != nil {
+		return uuid.Nil, "", false
+	}
+	fields := strings.Split(string(raw), "\n")
+	if len(fields) != 3 {
+		return uuid.Nil, "", false
+	}
+	userID, err := uuid.Parse(fields[0])
+	if err != nil {
+		return uuid.Nil, "", false
+	}
+	expires, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return uuid.Nil, "", false
+	}
+	return userID, fields[1], true
+}
+
+func (e *EmailSender) sign(purpose, value string) string {
+	mac := hmac.New(sha256.New, e.secret)
+	mac.Write([]byte(purpose + ":" + value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
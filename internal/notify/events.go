@@ -0,0 +1,123 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/events"
+
+	"github.com/google/uuid"
+)
+
+type Directory interface {
+	GetRoomMemberIDs(ctx context.Context, roomID uuid.UUID) ([]uuid.UUID, error)
+	GetSnoozedMemberIDs(ctx context.Context, roomID uuid.UUID) ([]uuid.UUID, error)
+	GetUserByID(ctx context.Context, id uuid.UUID) (*domain.User, error)
+	GetUserSettings(ctx context.Context, userID uuid.UUID) (*domain.UserSettings, error)
+	GetUserSettingsBatch(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]*domain.UserSettings, error)
+	GetRoomMetadata(ctx context.Context, roomID uuid.UUID) (map[string]json.RawMessage, error)
+}
+
+type Presence interface {
+	IsOnline(ctx context.Context, userID uuid.UUID) bool
+}
+
+type Subscriber struct {
+	dispatcher *Dispatcher
+	directory  Directory
+	presence   Presence
+	fanOut     chan fanOutJob
+	batchSize  int
+}
+
+func NewSubscriber(dispatcher *Dispatcher, directory Directory, presence Presence) *Subscriber {
+	return &Subscriber{dispatcher: dispatcher, directory: directory, presence: presence}
+}
+
+func (s *Subscriber) HandleEvent(ctx context.Context, event events.Event) {
+	switch e := event.(type) {
+	case events.MessageCreated:
+		if e.Message.Kind != domain.MessageKindText {
+			return
+		}
+		s.queueFanOut(context.WithoutCancel(ctx), e.Message)
+	case events.CallMissed:
+		go s.notifyMissedCall(context.WithoutCancel(ctx), e)
+	case events.MessageEdited:
+		s.dispatcher.Supersede(e.RoomID, e.MessageID, e.Content, false)
+		go s.dispatcher.Retract(context.WithoutCancel(ctx), e.MessageID, e.Content, false)
+	case events.MessageDeleted:
+		s.dispatcher.Supersede(e.RoomID, e.MessageID, "", true)
+		go s.dispatcher.Retract(context.WithoutCancel(ctx), e.MessageID, "", true)
+	case events.MessageRead:
+		s.dispatcher.CancelRead(e.UserID, e.RoomID, e.MessageID)
+	case events.FriendRequestSent:
+		go s.notifyFriendRequestOffline(context.WithoutCancel(ctx), e.Receiver, e.Sender.Nickname)
+	}
+}
+
+func (s *Subscriber) roomIsSensitive(ctx context.Context, roomID uuid.UUID) bool {
+	metadata, err := s.directory.GetRoomMetadata(ctx, roomID)
+	if err != nil {
+		log.Printf("Failed to load metadata of room %s for push: %v", roomID, err)
+		return false
+	}
+	var sensitive bool
+	if raw, ok := metadata[domain.RoomMetadataSensitive]; ok {
+		json.Unmarshal(raw, &sensitive)
+	}
+	return sensitive
+}
+
+func (s *Subscriber) notifyMissedCall(ctx context.Context, e events.CallMissed) {
+	callerName := "Someone"
+	if caller, err := s.directory.GetUserByID(ctx, e.CallerID); err == nil && caller != nil {
+		callerName = caller.Nickname
+	}
+
+	for _, calleeID := range e.CalleeIDs {
+		n := NewNotification("Missed call", fmt.Sprintf("You missed a call from %s", callerName), Payload{
+			Type:      TypeMissedCall,
+			RoomID:    e.RoomID,
+			MessageID: e.Message.ID,
+			DeepLink:  fmt.Sprintf("%srooms/%s/call", deepLinkBase, e.RoomID),
+		})
+		n.Data["action"] = "call_back"
+		n.Data["caller_id"] = e.CallerID.String()
+		err := s.dispatcher.Deliver(ctx, calleeID, n)
+		if err != nil && !errors.Is(err, ErrNoDevices) {
+			log.Printf("Failed to push missed call to %s: %v", calleeID, err)
+		}
+	}
+}
+
+func (s *Subscriber) notifyFriendRequestOffline(ctx context.Context, receiver domain.User, senderName string) {
+	if s.presence.IsOnline(ctx, receiver.ID) {
+		return
+	}
+
+	err := s.dispatcher.Deliver(ctx, receiver.ID, NewNotification("New friend request", fmt.Sprintf("%s sent you a friend request", senderName), Payload{
+		Type:     TypeFriendRequest,
+		DeepLink: deepLinkBase + "friends/requests",
+	}))
+	if err == nil {
+		return
+	}
+	if !errors.Is(err, ErrNoDevices) {
+		log.Printf("Failed to push friend request to %s: %v", receiver.ID, err)
+		return
+	}
+
+	settings, err := s.directory.GetUserSettings(ctx, receiver.ID)
+	if err != nil || !settings.EmailNotifications || receiver.Email == "" {
+		return
+	}
+	err = s.dispatcher.Email().FriendRequest(ctx, receiver.ID, receiver.Email, receiver.Nickname, senderName)
+	if err != nil && !errors.Is(err, ErrMailerDisabled) {
+		log.Printf("Failed to email friend request to %s: %v", receiver.ID, err)
+	}
+}
@@ -0,0 +1,130 @@
+package notify
+
+import (
+	"context"
+	"log"
+	"slices"
+
+	"chatservice/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+const defaultFanOutBatch = 500
+
+type fanOutJob struct {
+	ctx context.Context
+	msg domain.Message
+}
+
+func (s *Subscriber) StartFanOut(ctx context.Context, workers, queueSize, batchSize int) {
+	if workers <= 0 {
+		return
+	}
+	if batchSize <= 0 {
+		batchSize = defaultFanOutBatch
+	}
+	s.batchSize = batchSize
+	s.fanOut = make(chan fanOutJob, queueSize)
+	for range workers {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-s.fanOut:
+					s.notifyOfflineMembers(job.ctx, job.msg)
+				}
+			}
+		}()
+	}
+}
+
+func (s *Subscriber) queueFanOut(ctx context.Context, msg domain.Message) {
+	if s.fanOut == nil {
+		go s.notifyOfflineMembers(ctx, msg)
+		return
+	}
+	job := fanOutJob{ctx: ctx, msg: msg}
+	select {
+	case s.fanOut <- job:
+	default:
+		log.Printf("Push fan-out queue is full, message %d waits for a worker", msg.ID)
+		go func() { s.fanOut <- job }()
+	}
+}
+
+func (s *Subscriber) notifyOfflineMembers(ctx context.Context, msg domain.Message) {
+	memberIDs, err := s.directory.GetRoomMemberIDs(ctx, msg.RoomID)
+	if err != nil {
+		log.Printf("Failed to load members of room %s for push: %v", msg.RoomID, err)
+		return
+	}
+	urgent := domain.IsUrgentMessage(msg.Metadata)
+	if !urgent {
+		memberIDs = s.withoutSnoozed(ctx, msg.RoomID, memberIDs)
+	}
+
+	senderName := "Someone"
+	if sender, err := s.directory.GetUserByID(ctx, msg.UserID); err == nil && sender != nil {
+		senderName = sender.Nickname
+	}
+
+	sensitive := s.roomIsSensitive(ctx, msg.RoomID)
+	batchSize := s.batchSize
+	if batchSize <= 0 {
+		batchSize = defaultFanOutBatch
+	}
+	for batch := range slices.Chunk(memberIDs, batchSize) {
+		s.notifyBatch(ctx, msg, senderName, sensitive, urgent, batch)
+	}
+}
+
+func (s *Subscriber) withoutSnoozed(ctx context.Context, roomID uuid.UUID, memberIDs []uuid.UUID) []uuid.UUID {
+	snoozed, err := s.directory.GetSnoozedMemberIDs(ctx, roomID)
+	if err != nil {
+		log.Printf("Failed to load snoozed members of room %s: %v", roomID, err)
+		return memberIDs
+	}
+	if len(snoozed) == 0 {
+		return memberIDs
+	}
+	return slices.DeleteFunc(memberIDs, func(id uuid.UUID) bool { return slices.Contains(snoozed, id) })
+}
+
+func (s *Subscriber) notifyBatch(ctx context.Context, msg domain.Message, senderName string, sensitive, urgent bool, memberIDs []uuid.UUID) {
+	offline := make([]uuid.UUID, 0, len(memberIDs))
+	for _, memberID := range memberIDs {
+		if memberID != msg.UserID && !s.presence.IsOnline(ctx, memberID) {
+			offline = append(offline, memberID)
+		}
+	}
+	if len(offline) == 0 {
+		return
+	}
+
+	var settings map[uuid.UUID]*domain.UserSettings
+	if !sensitive {
+		var err error
+		if settings, err = s.directory.GetUserSettingsBatch(ctx, offline); err != nil {
+			log.Printf("Failed to load push settings for room %s: %v", msg.RoomID, err)
+		}
+	}
+	for _, memberID := range offline {
+		hidePreview := sensitive
+		if userSettings, ok := settings[memberID]; ok {
+			hidePreview = !userSettings.PushPreviews
+		}
+		n := NewNotification(senderName, msg.Content, MessagePayload(msg.RoomID, msg.ID))
+		n.HidePreview = hidePreview
+		if slices.Contains(msg.Mentions, memberID) {
+			n.Data["mention"] = "true"
+			n.Data["priority"] = "high"
+		}
+		if urgent {
+			n.Data["urgent"] = "true"
+			n.Data["priority"] = "high"
+		}
+		s.dispatcher.QueueMessage(ctx, memberID, msg.RoomID, msg.ID, n)
+	}
+}
@@ -0,0 +1,66 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+var ErrMailerDisabled = errors.New("email delivery is not configured")
+
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+type SMTPConfig struct {
+	Addr     string
+	Username string
+	Password string
+	From     string
+}
+
+type noopMailer struct{}
+
+func (noopMailer) Send(ctx context.Context, to, subject, body string) error {
+	return ErrMailerDisabled
+}
+
+type smtpMailer struct {
+	cfg  SMTPConfig
+	auth smtp.Auth
+}
+
+func NewMailer(cfg SMTPConfig) Mailer {
+	if cfg.Addr == "" {
+		return noopMailer{}
+	}
+	m := &smtpMailer{cfg: cfg}
+	if cfg.Username != "" {
+		host, _, _ := net.SplitHostPort(cfg.Addr)
+		m.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, host)
+	}
+	return m
+}
+
+func (m *smtpMailer) Send(ctx context.Context, to, subject, body string) error {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", m.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", headerValue(to))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", headerValue(subject)))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(body)
+
+	if err := smtp.SendMail(m.cfg.Addr, m.auth, m.cfg.From, []string{to}, []byte(msg.String())); err != nil {
+		return fmt.Errorf("error sending email: %w", err)
+	}
+	return nil
+}
+
+func headerValue(value string) string {
+	return strings.Join(strings.FieldsFunc(value, func(r rune) bool { return r == '\r' || r == '\n' }), " ")
+}
@@ -0,0 +1,67 @@
+package notify
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const maxPreviewRunes = 120
+
+type Masker struct {
+	words map[string]bool
+}
+
+func NewMasker(words []string) *Masker {
+	m := &Masker{words: make(map[string]bool, len(words))}
+	for _, word := range words {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			m.words[word] = true
+		}
+	}
+	return m
+}
+
+func (m *Masker) Mask(text string) string {
+	if m == nil || len(m.words) == 0 {
+		return text
+	}
+	var b strings.Builder
+	b.Grow(len(text))
+	start := -1
+	flush := func(end int) {
+		word := text[start:end]
+		if m.words[strings.ToLower(word)] {
+			first, size := utf8.DecodeRuneInString(word)
+			b.WriteRune(first)
+			b.WriteString(strings.Repeat("*", utf8.RuneCountInString(word[size:])))
+		} else {
+			b.WriteString(word)
+		}
+		start = -1
+	}
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			flush(i)
+		}
+		b.WriteRune(r)
+	}
+	if start >= 0 {
+		flush(len(text))
+	}
+	return b.String()
+}
+
+func shorten(text string) string {
+	if utf8.RuneCountInString(text) <= maxPreviewRunes {
+		return text
+	}
+	runes := []rune(text)
+	return string(runes[:maxPreviewRunes-1]) + "…"
+}
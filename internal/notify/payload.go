@@ -0,0 +1,65 @@
+package notify
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+const deepLinkBase = "chatservice://"
+
+const (
+	TypeMessage       = "message"
+	TypeMissedCall    = "missed_call"
+	TypeFriendRequest = "friend_request"
+	TypeUpdate        = "update"
+	TypeRetract       = "retract"
+)
+
+type Payload struct {
+	Type      string
+	RoomID    uuid.UUID
+	MessageID int64
+	DeepLink  string
+}
+
+func MessagePayload(roomID uuid.UUID, messageID int64) Payload {
+	return Payload{
+		Type:      TypeMessage,
+		RoomID:    roomID,
+		MessageID: messageID,
+		DeepLink:  fmt.Sprintf("%srooms/%s/messages/%d", deepLinkBase, roomID, messageID),
+	}
+}
+
+func (p Payload) CollapseKey() string {
+	if p.RoomID == uuid.Nil {
+		return p.Type
+	}
+	return p.Type + ":" + p.RoomID.String()
+}
+
+func (p Payload) Data() map[string]string {
+	data := map[string]string{"type": p.Type}
+	if p.RoomID != uuid.Nil {
+		data["room_id"] = p.RoomID.String()
+	}
+	if p.MessageID != 0 {
+		data["message_id"] = strconv.FormatInt(p.MessageID, 10)
+	}
+	if p.DeepLink != "" {
+		data["deep_link"] = p.DeepLink
+	}
+	return data
+}
+
+func NewNotification(title, body string, p Payload) Notification {
+	return Notification{
+		Title:       title,
+		Body:        body,
+		Type:        p.Type,
+		CollapseKey: p.CollapseKey(),
+		Data:        p.Data(),
+	}
+}
@@ -0,0 +1,82 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrNoDevices = errors.New("user has no registered push devices")
+
+type Notification struct {
+	ID          string            `json:"id,omitempty"`
+	Title       string            `json:"title"`
+	Body        string            `json:"body"`
+	Type        string            `json:"type"`
+	CollapseKey string            `json:"collapse_key,omitempty"`
+	Data        map[string]string `json:"data,omitempty"`
+
+	HidePreview bool `json:"-"`
+}
+
+type Pusher interface {
+	Push(ctx context.Context, userID uuid.UUID, n Notification) error
+}
+
+type noopPusher struct{}
+
+func (noopPusher) Push(ctx context.Context, userID uuid.UUID, n Notification) error {
+	return ErrNoDevices
+}
+
+type gatewayPusher struct {
+	url    string
+	client *http.Client
+}
+
+func NewPusher(gatewayURL string) Pusher {
+	if gatewayURL == "" {
+		return noopPusher{}
+	}
+	return &gatewayPusher{
+		url:    gatewayURL,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+type gatewayRequest struct {
+	UserID uuid.UUID `json:"user_id"`
+	Notification
+}
+
+func (p *gatewayPusher) Push(ctx context.Context, userID uuid.UUID, n Notification) error {
+	body, err := json.Marshal(gatewayRequest{UserID: userID, Notification: n})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error contacting push gateway: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNoDevices
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("push gateway returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package notify
+
+import (
+	"context"
+	"time"
+)
+
+type rateLimiter struct {
+	tokens chan struct{}
+}
+
+func newRateLimiter(perSecond int) *rateLimiter {
+	l := &rateLimiter{tokens: make(chan struct{}, perSecond)}
+	for range perSecond {
+		l.tokens <- struct{}{}
+	}
+	go func() {
+		ticker := time.NewTicker(time.Second / time.Duration(perSecond))
+		defer ticker.Stop()
+		for range ticker.C {
+			select {
+			case l.tokens <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return l
+}
+
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	select {
+	case <-l.tokens:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,129 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"chatservice/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+const heavyEditRatio = 0.3
+
+type PushTracker interface {
+	RecordSentPush(ctx context.Context, push *domain.SentPush) error
+	GetUnreadSentPushes(ctx context.Context, messageID int64) ([]domain.SentPush, error)
+	DeleteSentPushes(ctx context.Context, messageID int64) ([]domain.SentPush, error)
+	UpdateSentPushPreview(ctx context.Context, notificationID uuid.UUID, preview string) error
+	GetBadgeCounts(ctx context.Context, userID uuid.UUID) (*domain.BadgeCounts, error)
+	MessageExists(ctx context.Context, messageID int64) (bool, error)
+}
+
+func (d *Dispatcher) SetTracker(tracker PushTracker) { d.tracker = tracker }
+
+func (d *Dispatcher) sendMessage(ctx context.Context, userID, roomID uuid.UUID, messageID int64, count int, n Notification) {
+	if count > 1 {
+		n.Body = fmt.Sprintf("%d new messages", count)
+	}
+	n = d.redact(n)
+	n.ID = uuid.NewString()
+	if err := d.push(ctx, userID, n); err != nil {
+		if !errors.Is(err, ErrNoDevices) {
+			log.Printf("Failed to push notification to %s: %v", userID, err)
+		}
+		return
+	}
+	if d.tracker == nil {
+		return
+	}
+
+	push := &domain.SentPush{NotificationID: uuid.MustParse(n.ID), UserID: userID, RoomID: roomID, MessageID: messageID}
+	if count == 1 && !n.HidePreview {
+		push.Preview = n.Body
+	}
+	if err := d.tracker.RecordSentPush(ctx, push); err != nil {
+		log.Printf("Failed to track push %s to %s: %v", n.ID, userID, err)
+	}
+}
+
+func (d *Dispatcher) Retract(ctx context.Context, messageID int64, content string, deleted bool) {
+	if d.tracker == nil {
+		return
+	}
+	var pushes []domain.SentPush
+	var err error
+	if deleted {
+		pushes, err = d.tracker.DeleteSentPushes(ctx, messageID)
+	} else {
+		pushes, err = d.tracker.GetUnreadSentPushes(ctx, messageID)
+	}
+	if err != nil {
+		log.Printf("Failed to load pushes of message %d for retraction: %v", messageID, err)
+		return
+	}
+
+	preview := shorten(d.masker.Mask(content))
+	for _, push := range pushes {
+		n := Notification{ID: push.NotificationID.String(), Type: TypeRetract}
+		if !deleted {
+			if push.Preview == "" || !heavilyEdited(push.Preview, preview) {
+				continue
+			}
+			n.Type = TypeUpdate
+			n.Body = preview
+		}
+		n.CollapseKey = MessagePayload(push.RoomID, push.MessageID).CollapseKey()
+		n.Data = map[string]string{
+			"type":            n.Type,
+			"notification_id": n.ID,
+			"room_id":         push.RoomID.String(),
+			"message_id":      strconv.FormatInt(push.MessageID, 10),
+		}
+		if counts, err := d.tracker.GetBadgeCounts(ctx, push.UserID); err == nil {
+			n.Data["badge"] = strconv.Itoa(counts.UnreadMessages + counts.MissedCalls)
+		}
+
+		if err := d.push(ctx, push.UserID, n); err != nil && !errors.Is(err, ErrNoDevices) {
+			log.Printf("Failed to %s push %s to %s: %v", n.Type, n.ID, push.UserID, err)
+			continue
+		}
+		if !deleted {
+			if err := d.tracker.UpdateSentPushPreview(ctx, push.NotificationID, preview); err != nil {
+				log.Printf("Failed to track updated push %s: %v", push.NotificationID, err)
+			}
+		}
+	}
+}
+
+func heavilyEdited(before, after string) bool {
+	a, b := []rune(before), []rune(after)
+	longest := max(len(a), len(b))
+	if longest == 0 {
+		return false
+	}
+	return float64(editDistance(a, b)) >= heavyEditRatio*float64(longest)
+}
+
+func editDistance(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
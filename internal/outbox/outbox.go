@@ -0,0 +1,161 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/logging"
+	"chatservice/internal/repository"
+	"chatservice/internal/scheduler"
+
+	"github.com/google/uuid"
+)
+
+const (
+	KindPush          = "push"
+	KindActionWebhook = "action_webhook"
+
+	baseBackoff  = 30 * time.Second
+	maxBackoff   = 6 * time.Hour
+	claimLease   = 5 * time.Minute
+	retryBatch   = 50
+	listLimit    = 200
+	retryJobName = "retry-deliveries"
+)
+
+var (
+	ErrDeliveryNotFound = errors.New("delivery not found")
+	ErrUnknownKind      = errors.New("no handler for delivery kind")
+)
+
+type Handler func(ctx context.Context, target string, payload json.RawMessage) error
+
+type Service struct {
+	repo        repository.DeliveryRepository
+	maxAttempts int
+	handlers    map[string]Handler
+}
+
+func NewService(repo repository.DeliveryRepository, maxAttempts int) *Service {
+	if maxAttempts <= 0 {
+		maxAttempts = 8
+	}
+	return &Service{repo: repo, maxAttempts: maxAttempts, handlers: make(map[string]Handler)}
+}
+
+func (s *Service) Handle(kind string, handler Handler) {
+	s.handlers[kind] = handler
+}
+
+func (s *Service) Record(ctx context.Context, kind, target string, payload any, cause error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Could not encode failed %s delivery: %v", kind, err)
+		return
+	}
+	delivery := &domain.FailedDelivery{
+		ID:            uuid.New(),
+		Kind:          kind,
+		Target:        target,
+		Payload:       raw,
+		Status:        domain.DeliveryStatusRetrying,
+		Attempts:      1,
+		LastError:     logging.Scrub(cause.Error()),
+		NextAttemptAt: time.Now().Add(backoff(1)),
+	}
+	if err := s.repo.CreateFailedDelivery(ctx, delivery); err != nil {
+		log.Printf("Could not queue failed %s delivery for retry: %v", kind, err)
+	}
+}
+
+func (s *Service) Job(interval time.Duration) scheduler.Job {
+	return scheduler.Job{Name: retryJobName, Interval: interval, Run: s.retryDue}
+}
+
+func (s *Service) retryDue(ctx context.Context) error {
+	deliveries, err := s.repo.ClaimDueDeliveries(ctx, retryBatch, claimLease)
+	if err != nil {
+		return err
+	}
+	for _, delivery := range deliveries {
+		s.attempt(ctx, delivery)
+	}
+	return nil
+}
+
+func (s *Service) attempt(ctx context.Context, delivery domain.FailedDelivery) error {
+	handler, ok := s.handlers[delivery.Kind]
+	err := ErrUnknownKind
+	if ok {
+		err = handler(ctx, delivery.Target, delivery.Payload)
+	}
+	if err == nil {
+		log.Printf("Delivered %s %s after %d failed attempts", delivery.Kind, delivery.ID, delivery.Attempts)
+		return s.repo.DeleteDelivery(ctx, delivery.ID)
+	}
+
+	attempts := delivery.Attempts + 1
+	dead := attempts >= s.maxAttempts
+	if dead {
+		log.Printf("Dead-lettering %s delivery %s after %d attempts: %v", delivery.Kind, delivery.ID, attempts, err)
+	}
+	if rerr := s.repo.RescheduleDelivery(ctx, delivery.ID, attempts, time.Now().Add(backoff(attempts)), logging.Scrub(err.Error()), dead); rerr != nil {
+		return rerr
+	}
+	return err
+}
+
+func (s *Service) List(ctx context.Context, status string) ([]domain.FailedDelivery, error) {
+	switch status {
+	case "", domain.DeliveryStatusRetrying, domain.DeliveryStatusDead:
+	default:
+		return nil, fmt.Errorf("status must be %q or %q", domain.DeliveryStatusRetrying, domain.DeliveryStatusDead)
+	}
+	return s.repo.ListFailedDeliveries(ctx, status, listLimit)
+}
+
+func (s *Service) Get(ctx context.Context, id uuid.UUID) (*domain.FailedDelivery, error) {
+	delivery, err := s.repo.GetFailedDelivery(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if delivery == nil {
+		return nil, ErrDeliveryNotFound
+	}
+	return delivery, nil
+}
+
+func (s *Service) Requeue(ctx context.Context, id uuid.UUID) error {
+	ok, err := s.repo.RequeueDelivery(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrDeliveryNotFound
+	}
+	return nil
+}
+
+func (s *Service) DeadLetter(ctx context.Context, id uuid.UUID) error {
+	ok, err := s.repo.DeadLetterDelivery(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrDeliveryNotFound
+	}
+	return nil
+}
+
+func backoff(attempts int) time.Duration {
+	delay := baseBackoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxBackoff)
+}
@@ -0,0 +1,89 @@
+package overload
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"chatservice/internal/logging"
+	"chatservice/internal/repository"
+)
+
+var overloadLog = logging.For("overload")
+
+type Config struct {
+	Interval       time.Duration
+	MaxLoopLag     time.Duration
+	MaxAcquireWait time.Duration
+	RetryAfter     time.Duration
+}
+
+type Monitor struct {
+	cfg       Config
+	probeLoop func(ctx context.Context) error
+	poolStats func() map[string]repository.PoolStats
+	last      map[string]repository.PoolStats
+
+	overloaded atomic.Bool
+}
+
+func NewMonitor(cfg Config, probeLoop func(ctx context.Context) error, poolStats func() map[string]repository.PoolStats) *Monitor {
+	return &Monitor{cfg: cfg, probeLoop: probeLoop, poolStats: poolStats}
+}
+
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.sample(ctx)
+		}
+	}
+}
+
+func (m *Monitor) Overloaded() bool { return m.overloaded.Load() }
+
+func (m *Monitor) RetryAfter() time.Duration { return m.cfg.RetryAfter }
+
+func (m *Monitor) sample(ctx context.Context) {
+	lag := m.measureLoopLag(ctx)
+	wait := m.measureAcquireWait()
+	overloaded := lag > m.cfg.MaxLoopLag || wait > m.cfg.MaxAcquireWait
+	if m.overloaded.Swap(overloaded) != overloaded {
+		if overloaded {
+			overloadLog.Warnf("Service is overloaded (loop lag %s, pool acquire wait %s), shedding heavy requests", lag, wait)
+		} else {
+			overloadLog.Infof("Load is back to normal (loop lag %s, pool acquire wait %s)", lag, wait)
+		}
+	}
+}
+
+func (m *Monitor) measureLoopLag(ctx context.Context) time.Duration {
+	ctx, cancel := context.WithTimeout(ctx, 4*m.cfg.MaxLoopLag)
+	defer cancel()
+	start := time.Now()
+	m.probeLoop(ctx)
+	return time.Since(start)
+}
+
+func (m *Monitor) measureAcquireWait() time.Duration {
+	current := m.poolStats()
+	var worst time.Duration
+	for name, stats := range current {
+		prev, ok := m.last[name]
+		if !ok {
+			continue
+		}
+		acquires := stats.AcquireCount - prev.AcquireCount
+		if acquires <= 0 {
+			continue
+		}
+		wait := time.Duration(stats.AcquireDuration-prev.AcquireDuration) * time.Millisecond / time.Duration(acquires)
+		worst = max(worst, wait)
+	}
+	m.last = current
+	return worst
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"chatservice/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var ErrAllowlistDuplicate = errors.New("allowlist entry already exists")
+
+type AccessRepository interface {
+	IsAllowlisted(ctx context.Context, userID uuid.UUID, email string) (bool, error)
+	ListAllowlist(ctx context.Context) ([]domain.AllowlistEntry, error)
+	AddAllowlistEntry(ctx context.Context, entry *domain.AllowlistEntry) error
+	RemoveAllowlistEntry(ctx context.Context, id uuid.UUID) (bool, error)
+}
+
+type postgresAccessRepository struct {
+	db *ClusterResolver
+}
+
+func NewAccessRepository(db *ClusterResolver) AccessRepository {
+	return &postgresAccessRepository{db: db}
+}
+
+func (r *postgresAccessRepository) IsAllowlisted(ctx context.Context, userID uuid.UUID, email string) (bool, error) {
+	var allowed bool
+	query := `SELECT EXISTS(SELECT 1 FROM access_allowlist WHERE user_id = $1 OR (email IS NOT NULL AND email = NULLIF($2, '')))`
+	if err := r.db.Pool(ctx).QueryRow(ctx, query, userID, email).Scan(&allowed); err != nil {
+		return false, fmt.Errorf("error checking allowlist for %s: %w", userID, err)
+	}
+	return allowed, nil
+}
+
+func (r *postgresAccessRepository) ListAllowlist(ctx context.Context) ([]domain.AllowlistEntry, error) {
+	rows, err := r.db.Pool(ctx).Query(ctx, `SELECT id, user_id, email, note, added_by, created_at FROM access_allowlist ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("error listing allowlist: %w", err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[domain.AllowlistEntry])
+}
+
+func (r *postgresAccessRepository) AddAllowlistEntry(ctx context.Context, entry *domain.AllowlistEntry) error {
+	query := `INSERT INTO access_allowlist (user_id, email, note, added_by) VALUES ($1, $2, $3, $4) RETURNING id, created_at`
+	err := r.db.Pool(ctx).QueryRow(ctx, query, entry.UserID, entry.Email, entry.Note, entry.AddedBy).Scan(&entry.ID, &entry.CreatedAt)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrAllowlistDuplicate
+	}
+	if err != nil {
+		return fmt.Errorf("error adding allowlist entry: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresAccessRepository) RemoveAllowlistEntry(ctx context.Context, id uuid.UUID) (bool, error) {
+	cmdTag, err := r.db.Pool(ctx).Exec(ctx, `DELETE FROM access_allowlist WHERE id = $1`, id)
+	if err != nil {
+		return false, fmt.Errorf("error removing allowlist entry: %w", err)
+	}
+	return cmdTag.RowsAffected() > 0, nil
+}
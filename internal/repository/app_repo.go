@@ -2,61 +2,365 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"strings"
 	"time"
 
 	"chatservice/internal/domain"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
+var ErrCannedShortcutTaken = errors.New("canned response shortcut already exists")
+
+var ErrLabelNameTaken = errors.New("a label with this name already exists")
+
 type AppRepository interface {
-	UpsertUser(ctx context.Context, id uuid.UUID, email *string, nickname *string) error
+	UpsertUser(ctx context.Context, id uuid.UUID, email, username, nickname *string) error
+	SetUserBadges(ctx context.Context, userID uuid.UUID, badges []string) (bool, error)
+	GetUserBadges(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID][]string, error)
+	BumpStateVersions(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]int64, error)
+	GetStateVersions(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]int64, error)
+	GetContactIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error)
+	UpsertUserIdentity(ctx context.Context, userID uuid.UUID, identity domain.LinkedIdentity) error
+	DeleteUserIdentity(ctx context.Context, userID uuid.UUID, provider string) (bool, error)
+	ReplaceUserIdentities(ctx context.Context, userID uuid.UUID, identities []domain.LinkedIdentity) error
+	GetUserIdentities(ctx context.Context, userID uuid.UUID) ([]domain.LinkedIdentity, error)
+	AdvanceAuthEventCursor(ctx context.Context, userID uuid.UUID, at time.Time) (bool, error)
+	ConsumeDailyQuota(ctx context.Context, userID uuid.UUID, kind string, limit int) (bool, error)
 	GetUserByEmail(ctx context.Context, email string) (*domain.User, error)
 	GetUserByID(ctx context.Context, id uuid.UUID) (*domain.User, error)
+	GetUserSettings(ctx context.Context, userID uuid.UUID) (*domain.UserSettings, error)
+	GetUserSettingsBatch(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]*domain.UserSettings, error)
+	UpsertUserSettings(ctx context.Context, settings *domain.UserSettings) error
 	CreateFriendship(ctx context.Context, fs *domain.Friendship) error
 	UpdateFriendshipStatus(ctx context.Context, tx pgx.Tx, fs *domain.Friendship) error
 	GetFriendship(ctx context.Context, userOneID, userTwoID uuid.UUID) (*domain.Friendship, error)
 	GetFriendshipsForUser(ctx context.Context, userID uuid.UUID, status string) ([]domain.Friendship, error)
+	GetFriends(ctx context.Context, userID uuid.UUID, sortBy string) ([]domain.Friend, error)
 	DeleteFriendship(ctx context.Context, userOneID, userTwoID uuid.UUID) error
 	IsUserInRoom(ctx context.Context, userID, roomID uuid.UUID) (bool, error)
+	GetRoomMemberIDs(ctx context.Context, roomID uuid.UUID) ([]uuid.UUID, error)
+	GetRoomAdminIDs(ctx context.Context, roomID uuid.UUID) ([]uuid.UUID, error)
+	GetRoomRole(ctx context.Context, userID, roomID uuid.UUID) (string, error)
+	GetRoomMetadata(ctx context.Context, roomID uuid.UUID) (map[string]json.RawMessage, error)
+	UpdateRoomMetadata(ctx context.Context, roomID uuid.UUID, set map[string]json.RawMessage, remove []string, maxBytes, maxKeys int) (map[string]json.RawMessage, bool, error)
 	GetRoomByID(ctx context.Context, roomID uuid.UUID) (*domain.Room, error)
 	CreateRoom(ctx context.Context, tx pgx.Tx, room *domain.Room) (*domain.Room, error)
+	AddUserToRoomWithRole(ctx context.Context, tx pgx.Tx, userID, roomID uuid.UUID, role string) error
+	CopyRecentMessages(ctx context.Context, tx pgx.Tx, fromRoomID, toRoomID, authorID uuid.UUID, limit int) (int64, error)
+	CopyRoomMetadata(ctx context.Context, tx pgx.Tx, fromRoomID, toRoomID uuid.UUID) error
+	CopyRoomMembers(ctx context.Context, tx pgx.Tx, fromRoomID, toRoomID, excludeUserID uuid.UUID) ([]uuid.UUID, error)
 	AddUserToRoom(ctx context.Context, tx pgx.Tx, userID, roomID uuid.UUID) error
 	GetRoomsForUser(ctx context.Context, userID uuid.UUID) ([]domain.Room, error)
-	GetMessagesForRoom(ctx context.Context, roomID uuid.UUID, limit, offset int) ([]domain.Message, error)
+	GetRoomsChangeToken(ctx context.Context, userID uuid.UUID) (string, error)
+	GetMessagesForRoom(ctx context.Context, roomID uuid.UUID, tag string, limit, offset int) ([]domain.Message, error)
+	GetRoomTags(ctx context.Context, roomID uuid.UUID, limit int) ([]domain.RoomTag, error)
+	SearchMessages(ctx context.Context, roomIDs []uuid.UUID, query string, since time.Time, limit int) ([]domain.Message, error)
+	GetMessagesAfter(ctx context.Context, afterID int64, limit int) ([]domain.Message, error)
+	GetMessagesByIDs(ctx context.Context, messageIDs []int64) ([]domain.Message, error)
+	GetRoomIDsForUser(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error)
+	GetAwayStatus(ctx context.Context, userID uuid.UUID) (*domain.AwayStatus, error)
+	UpsertAwayStatus(ctx context.Context, status *domain.AwayStatus) error
+	DeleteAwayStatus(ctx context.Context, userID uuid.UUID) error
+	GetAwayResponder(ctx context.Context, roomID, senderID uuid.UUID) (*domain.AwayStatus, error)
+	ClaimAwayReply(ctx context.Context, userID, senderID uuid.UUID, cooldown time.Duration) (bool, error)
+	SetRoomSnooze(ctx context.Context, userID, roomID uuid.UUID, until *time.Time) (bool, error)
+	ExpireRoomSnoozes(ctx context.Context) ([]domain.RoomSnooze, error)
+	GetSnoozedMemberIDs(ctx context.Context, roomID uuid.UUID) ([]uuid.UUID, error)
+	CountShareableMessages(ctx context.Context, roomID uuid.UUID, firstID, lastID int64) (endpoints, total int, err error)
+	CreateShareLink(ctx context.Context, link *domain.ShareLink, tokenHash string) error
+	ListShareLinks(ctx context.Context, roomID uuid.UUID) ([]domain.ShareLink, error)
+	RevokeShareLink(ctx context.Context, roomID, linkID uuid.UUID) (bool, error)
+	GetShareLinkByTokenHash(ctx context.Context, tokenHash string) (*domain.ShareLink, error)
+	GetSharedMessages(ctx context.Context, roomID uuid.UUID, firstID, lastID int64) ([]domain.SharedMessage, error)
+	LogShareLinkAccess(ctx context.Context, linkID uuid.UUID, ipAddress, userAgent string) error
+	CreateWidgetKey(ctx context.Context, key *domain.WidgetKey, keyHash string) error
+	ListWidgetKeys(ctx context.Context, roomID uuid.UUID) ([]domain.WidgetKey, error)
+	RevokeWidgetKey(ctx context.Context, roomID, keyID uuid.UUID) (bool, error)
+	GetWidgetKeyByHash(ctx context.Context, keyHash string) (*domain.WidgetKey, error)
+	CreateWidgetGuest(ctx context.Context, tx pgx.Tx, guest *domain.WidgetGuest, tokenHash string) error
+	TouchWidgetGuest(ctx context.Context, tokenHash string) (*domain.WidgetGuest, error)
+	LockWidgetGuest(ctx context.Context, tx pgx.Tx, tokenHash string) (*domain.WidgetGuest, error)
+	MergeWidgetGuest(ctx context.Context, tx pgx.Tx, guest *domain.WidgetGuest, userID uuid.UUID) (*domain.GuestMerge, error)
+	GetGuestMessages(ctx context.Context, roomID uuid.UUID, since time.Time, afterID int64, limit int) ([]domain.SharedMessage, error)
+	UpsertSupportAgent(ctx context.Context, userID uuid.UUID, available bool) error
+	RemoveSupportAgent(ctx context.Context, userID uuid.UUID) (bool, error)
+	SetSupportAgentAvailability(ctx context.Context, userID uuid.UUID, available bool) (bool, error)
+	IsSupportAgent(ctx context.Context, userID uuid.UUID) (bool, error)
+	ListSupportAgents(ctx context.Context) ([]domain.SupportAgent, error)
+	PickSupportAgent(ctx context.Context, tx pgx.Tx) (*uuid.UUID, error)
+	CreateSupportConversation(ctx context.Context, tx pgx.Tx, conv *domain.SupportConversation) error
+	GetSupportConversation(ctx context.Context, roomID uuid.UUID) (*domain.SupportConversation, error)
+	ListSupportQueue(ctx context.Context, limit int) ([]domain.SupportConversation, error)
+	ListAgentConversations(ctx context.Context, agentID uuid.UUID) ([]domain.SupportConversation, error)
+	AssignSupportConversation(ctx context.Context, roomID uuid.UUID, fromAgentID, toAgentID *uuid.UUID) (*domain.SupportConversation, error)
+	EnsureRoomParticipant(ctx context.Context, userID, roomID uuid.UUID) (bool, error)
+	GetRoomAgentIDs(ctx context.Context, roomID uuid.UUID) ([]uuid.UUID, error)
+	CreateSupportNote(ctx context.Context, note *domain.SupportNote) error
+	ListSupportNotes(ctx context.Context, roomID uuid.UUID, limit int) ([]domain.SupportNote, error)
+	TrackSupportMessage(ctx context.Context, roomID, senderID uuid.UUID, at time.Time) error
+	SetSupportResolved(ctx context.Context, roomID uuid.UUID, resolved bool) error
+	SetRoomState(ctx context.Context, roomID, actorID uuid.UUID, state string) (*domain.RoomStateChange, error)
+	MarkSupportSLABreaches(ctx context.Context, kind string, threshold time.Duration) ([]domain.SupportSLABreach, error)
+	GetSupportSLASummary(ctx context.Context, since time.Time, firstResponse, resolution time.Duration) (*domain.SupportSLASummary, error)
+	GetRoomCounts(ctx context.Context, roomID uuid.UUID) (members, messages int, err error)
+	CreateCannedResponse(ctx context.Context, resp *domain.CannedResponse) error
+	UpdateCannedResponse(ctx context.Context, resp *domain.CannedResponse) (bool, error)
+	DeleteCannedResponse(ctx context.Context, id uuid.UUID) (bool, error)
+	GetCannedResponse(ctx context.Context, id uuid.UUID) (*domain.CannedResponse, error)
+	FindCannedResponses(ctx context.Context, userID uuid.UUID, roomID *uuid.UUID, includeSupport bool, prefix string, limit int) ([]domain.CannedResponse, error)
+	CreateLabel(ctx context.Context, label *domain.Label) error
+	UpdateLabel(ctx context.Context, label *domain.Label) (bool, error)
+	DeleteLabel(ctx context.Context, userID, labelID uuid.UUID) (bool, error)
+	GetLabel(ctx context.Context, userID, labelID uuid.UUID) (*domain.Label, error)
+	ListLabels(ctx context.Context, userID uuid.UUID) ([]domain.Label, error)
+	CountLabels(ctx context.Context, userID uuid.UUID) (int, error)
+	AddMessageLabel(ctx context.Context, labelID uuid.UUID, messageID int64) error
+	RemoveMessageLabel(ctx context.Context, labelID uuid.UUID, messageID int64) (bool, error)
+	AddRoomLabel(ctx context.Context, labelID, roomID uuid.UUID) error
+	RemoveRoomLabel(ctx context.Context, labelID, roomID uuid.UUID) (bool, error)
+	GetLabeledMessages(ctx context.Context, userID, labelID uuid.UUID, includeSupport bool, limit int) ([]domain.Message, error)
+	GetLabeledRoomIDs(ctx context.Context, labelID uuid.UUID) ([]uuid.UUID, error)
+	QuickSearch(ctx context.Context, userID uuid.UUID, query string, perType int) ([]domain.QuickSearchResult, error)
+	GetDailyActivity(ctx context.Context, userID uuid.UUID, since time.Time) ([]domain.DailyActivity, error)
+	GetRoomActivity(ctx context.Context, userID uuid.UUID, since time.Time, limit int) ([]domain.RoomActivity, error)
+	GetMessageByID(ctx context.Context, messageID int64) (*domain.Message, error)
+	MessageExists(ctx context.Context, messageID int64) (bool, error)
 	CreateMessage(ctx context.Context, msg *domain.Message) (*domain.Message, error)
 	MarkMessageAsRead(ctx context.Context, messageID int64, userID uuid.UUID) (*time.Time, error)
+	GetBadgeCounts(ctx context.Context, userID uuid.UUID) (*domain.BadgeCounts, error)
+	CreateRestrictedAttachment(ctx context.Context, att *domain.Attachment, allowedUserIDs []uuid.UUID) error
+	GetAttachmentsForUser(ctx context.Context, roomID, userID uuid.UUID, kind string) ([]domain.Attachment, error)
+	GetAttachmentForUser(ctx context.Context, attachmentID, userID uuid.UUID) (*domain.Attachment, error)
+	CreateUpload(ctx context.Context, upload *domain.Upload) error
+	GetUpload(ctx context.Context, uploadID uuid.UUID) (*domain.Upload, error)
+	LockUpload(ctx context.Context, tx pgx.Tx, uploadID uuid.UUID) (*domain.Upload, error)
+	SetUploadOffset(ctx context.Context, tx pgx.Tx, uploadID uuid.UUID, offset int64) error
+	CompleteUpload(ctx context.Context, uploadID uuid.UUID) (bool, error)
+	ReopenUpload(ctx context.Context, uploadID uuid.UUID) error
+	DeleteUpload(ctx context.Context, uploadID uuid.UUID) error
+	GetDraft(ctx context.Context, userID, roomID uuid.UUID) (*domain.Draft, error)
+	GetDraftsForUser(ctx context.Context, userID uuid.UUID) ([]domain.Draft, error)
+	SaveDraft(ctx context.Context, draft *domain.Draft) (*domain.Draft, error)
+	DeleteDraft(ctx context.Context, userID, roomID uuid.UUID) error
+	RecordMentions(ctx context.Context, messageID int64, roomID, senderID uuid.UUID, usernames []string) ([]uuid.UUID, error)
+	MarkRoomReadUpTo(ctx context.Context, userID, roomID uuid.UUID, upToMessageID int64) (int64, error)
+	GetMemberLanguages(ctx context.Context, roomID uuid.UUID) (map[uuid.UUID]string, error)
+	SaveMessageTranslations(ctx context.Context, messageID int64, translations map[string]string) error
+	GetMessageTranslations(ctx context.Context, messageIDs []int64, language string) (map[int64]string, error)
+	GetLastReadAt(ctx context.Context, userID, roomID uuid.UUID) (*time.Time, error)
+	GetSummaryLines(ctx context.Context, roomID uuid.UUID, since time.Time, limit int) ([]domain.SummaryLine, error)
+	AddMessageReaction(ctx context.Context, messageID int64, userID uuid.UUID, emoji string) (bool, error)
+	RemoveMessageReaction(ctx context.Context, messageID int64, userID uuid.UUID, emoji string) (bool, error)
+	GetRoomHighlights(ctx context.Context, roomID uuid.UUID, since time.Time, limit int) ([]domain.RoomHighlight, error)
+	GetRoomSummary(ctx context.Context, roomID uuid.UUID, language string, firstMessageID, lastMessageID int64) (*domain.RoomSummary, error)
+	SaveRoomSummary(ctx context.Context, summary *domain.RoomSummary) error
+	RecordSentPush(ctx context.Context, push *domain.SentPush) error
+	GetUnreadSentPushes(ctx context.Context, messageID int64) ([]domain.SentPush, error)
+	DeleteSentPushes(ctx context.Context, messageID int64) ([]domain.SentPush, error)
+	UpdateSentPushPreview(ctx context.Context, notificationID uuid.UUID, preview string) error
 	FindPrivateRoomByParticipants(ctx context.Context, userOneID, userTwoID uuid.UUID) (uuid.UUID, error)
 	SearchUsersByNickname(ctx context.Context, query string, selfID uuid.UUID, limit int) ([]domain.User, error)
-	UpdateMessage(ctx context.Context, messageID int64, userID uuid.UUID, newContent string) error
+	UpdateMessage(ctx context.Context, messageID int64, userID uuid.UUID, newContent string, richContent json.RawMessage, links, hashtags []string) error
 	DeleteMessage(ctx context.Context, messageID int64, userID uuid.UUID) error	
 }
 
 type postgresAppRepository struct {
-	db *pgxpool.Pool
+	db *ClusterResolver
 }
 
-func NewAppRepository(db *pgxpool.Pool) AppRepository {
+func NewAppRepository(db *ClusterResolver) AppRepository {
 	return &postgresAppRepository{db: db}
 }
 
-func (r *postgresAppRepository) UpsertUser(ctx context.Context, id uuid.UUID, email *string, nickname *string) error {	query := `INSERT INTO users (id, email) VALUES ($1, $2) ON CONFLICT (id) DO UPDATE SET email = COALESCE(users.email, $2)`
-	_, err := r.db.Exec(ctx, query, id, email)
-	return err
+func (r *postgresAppRepository) UpsertUser(ctx context.Context, id uuid.UUID, email, username, nickname *string) error {
+	query := `
+		INSERT INTO users (id, email, username, nickname)
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), NULLIF($4, ''))
+		ON CONFLICT (id) DO UPDATE SET
+			email = COALESCE(NULLIF($2, ''), users.email),
+			username = COALESCE(NULLIF($3, ''), users.username),
+			nickname = COALESCE(NULLIF($4, ''), users.nickname)
+	`
+	_, err := r.db.Pool(ctx).Exec(ctx, query, id, email, username, nickname)
+	if err != nil {
+		return fmt.Errorf("error upserting user %s: %w", id, err)
+	}
+	return nil
+}
+
+func (r *postgresAppRepository) SetUserBadges(ctx context.Context, userID uuid.UUID, badges []string) (bool, error) {
+	tag, err := r.db.Pool(ctx).Exec(ctx, `UPDATE users SET badges = $2 WHERE id = $1`, userID, badges)
+	if err != nil {
+		return false, fmt.Errorf("error setting badges for %s: %w", userID, err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *postgresAppRepository) GetUserBadges(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID][]string, error) {
+	rows, err := r.db.Pool(ctx).Query(ctx, `SELECT id, badges FROM users WHERE id = ANY($1) AND badges <> '{}'`, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error getting user badges: %w", err)
+	}
+	defer rows.Close()
+
+	badges := make(map[uuid.UUID][]string)
+	for rows.Next() {
+		var id uuid.UUID
+		var userBadges []string
+		if err := rows.Scan(&id, &userBadges); err != nil {
+			return nil, fmt.Errorf("error scanning user badges: %w", err)
+		}
+		badges[id] = userBadges
+	}
+	return badges, rows.Err()
+}
+
+func (r *postgresAppRepository) BumpStateVersions(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]int64, error) {
+	rows, err := r.db.Pool(ctx).Query(ctx, `UPDATE users SET state_version = state_version + 1 WHERE id = ANY($1) RETURNING id, state_version`, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error bumping state versions: %w", err)
+	}
+	return collectStateVersions(rows)
+}
+
+func (r *postgresAppRepository) GetStateVersions(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]int64, error) {
+	rows, err := r.db.Pool(ctx).Query(ctx, `SELECT id, state_version FROM users WHERE id = ANY($1)`, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error getting state versions: %w", err)
+	}
+	return collectStateVersions(rows)
+}
+
+func collectStateVersions(rows pgx.Rows) (map[uuid.UUID]int64, error) {
+	defer rows.Close()
+	versions := make(map[uuid.UUID]int64)
+	for rows.Next() {
+		var id uuid.UUID
+		var version int64
+		if err := rows.Scan(&id, &version); err != nil {
+			return nil, fmt.Errorf("error scanning state version: %w", err)
+		}
+		versions[id] = version
+	}
+	return versions, rows.Err()
+}
+
+func (r *postgresAppRepository) ConsumeDailyQuota(ctx context.Context, userID uuid.UUID, kind string, limit int) (bool, error) {
+	query := `
+		INSERT INTO user_quota_usage (user_id, kind, day, used)
+		VALUES ($1, $2, (NOW() AT TIME ZONE 'UTC')::date, 1)
+		ON CONFLICT (user_id, kind, day) DO UPDATE SET used = user_quota_usage.used + 1
+		WHERE user_quota_usage.used < $3
+		RETURNING used
+	`
+	var used int
+	err := r.db.Pool(ctx).QueryRow(ctx, query, userID, kind, limit).Scan(&used)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error consuming %s quota for %s: %w", kind, userID, err)
+	}
+	return true, nil
+}
+
+func (r *postgresAppRepository) UpsertUserIdentity(ctx context.Context, userID uuid.UUID, identity domain.LinkedIdentity) error {
+	query := `
+		INSERT INTO user_identities (user_id, provider, login, linked_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, provider) DO UPDATE SET login = $3, linked_at = $4
+	`
+	if _, err := r.db.Pool(ctx).Exec(ctx, query, userID, identity.Provider, identity.Login, identity.LinkedAt); err != nil {
+		return fmt.Errorf("error linking %s identity for %s: %w", identity.Provider, userID, err)
+	}
+	return nil
+}
+
+func (r *postgresAppRepository) DeleteUserIdentity(ctx context.Context, userID uuid.UUID, provider string) (bool, error) {
+	tag, err := r.db.Pool(ctx).Exec(ctx, `DELETE FROM user_identities WHERE user_id = $1 AND provider = $2`, userID, provider)
+	if err != nil {
+		return false, fmt.Errorf("error unlinking %s identity for %s: %w", provider, userID, err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *postgresAppRepository) ReplaceUserIdentities(ctx context.Context, userID uuid.UUID, identities []domain.LinkedIdentity) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting identity transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM user_identities WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("error clearing identities for %s: %w", userID, err)
+	}
+	for _, identity := range identities {
+		_, err := tx.Exec(ctx, `INSERT INTO user_identities (user_id, provider, login, linked_at) VALUES ($1, $2, $3, $4)`, userID, identity.Provider, identity.Login, identity.LinkedAt)
+		if err != nil {
+			return fmt.Errorf("error linking %s identity for %s: %w", identity.Provider, userID, err)
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *postgresAppRepository) GetUserIdentities(ctx context.Context, userID uuid.UUID) ([]domain.LinkedIdentity, error) {
+	rows, err := r.db.Pool(ctx).Query(ctx, `SELECT provider, login, linked_at FROM user_identities WHERE user_id = $1 ORDER BY linked_at`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting identities for %s: %w", userID, err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[domain.LinkedIdentity])
+}
+
+// AdvanceAuthEventCursor records at as the newest auth event applied for the
+// user and reports false when a later event was already applied.
+func (r *postgresAppRepository) AdvanceAuthEventCursor(ctx context.Context, userID uuid.UUID, at time.Time) (bool, error) {
+	query := `
+		INSERT INTO auth_event_cursors (user_id, applied_at) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET applied_at = EXCLUDED.applied_at
+		WHERE auth_event_cursors.applied_at <= EXCLUDED.applied_at
+	`
+	tag, err := r.db.Pool(ctx).Exec(ctx, query, userID, at)
+	if err != nil {
+		return false, fmt.Errorf("error advancing auth event cursor for %s: %w", userID, err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *postgresAppRepository) GetContactIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	query := `
+		SELECT CASE WHEN user_one_id = $1 THEN user_two_id ELSE user_one_id END
+		FROM friendships
+		WHERE (user_one_id = $1 OR user_two_id = $1) AND status = 'accepted'
+		UNION
+		SELECT other.user_id
+		FROM room_participants mine
+		JOIN room_participants other ON other.room_id = mine.room_id AND other.user_id <> $1
+		WHERE mine.user_id = $1
+	`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting contacts for %s: %w", userID, err)
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
 }
 
-func (r *postgresAppRepository) UpdateMessage(ctx context.Context, messageID int64, userID uuid.UUID, newContent string) error {
+func (r *postgresAppRepository) UpdateMessage(ctx context.Context, messageID int64, userID uuid.UUID, newContent string, richContent json.RawMessage, links, hashtags []string) error {
+	var rich *string
+	if len(richContent) > 0 {
+		raw := string(richContent)
+		rich = &raw
+	}
 	query := `
 		UPDATE messages
-		SET content = $1, updated_at = $2
+		SET content = $1, rich_content = $5::jsonb, links = COALESCE($6, '{}'), hashtags = COALESCE($7, '{}'), updated_at = $2
 		WHERE id = $3 AND user_id = $4
 	`
-	cmdTag, err := r.db.Exec(ctx, query, newContent, time.Now(), messageID, userID)
+	cmdTag, err := r.db.Pool(ctx).Exec(ctx, query, newContent, time.Now(), messageID, userID, rich, links, hashtags)
 	if err != nil {
 		return fmt.Errorf("error executing update message query: %w", err)
 	}
@@ -69,11 +373,31 @@ func (r *postgresAppRepository) UpdateMessage(ctx context.Context, messageID int
 }
 
 func (r *postgresAppRepository) DeleteMessage(ctx context.Context, messageID int64, userID uuid.UUID) error {
+	var held bool
+	heldQuery := `
+		SELECT EXISTS(
+			SELECT 1 FROM messages m
+			JOIN legal_holds h ON h.released_at IS NULL AND (
+				(h.subject_type = 'user' AND h.subject_id = m.user_id) OR
+				(h.subject_type = 'room' AND h.subject_id = m.room_id))
+			WHERE m.id = $1
+		)
+	`
+	if err := r.db.Pool(ctx).QueryRow(ctx, heldQuery, messageID).Scan(&held); err != nil {
+		return fmt.Errorf("error checking legal hold: %w", err)
+	}
+
 	query := `
 		DELETE FROM messages
 		WHERE id = $1 AND user_id = $2
 	`
-	cmdTag, err := r.db.Exec(ctx, query, messageID, userID)
+	if held {
+		query = `
+		UPDATE messages SET deleted_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+	`
+	}
+	cmdTag, err := r.db.Pool(ctx).Exec(ctx, query, messageID, userID)
 	if err != nil {
 		return fmt.Errorf("error executing delete message query: %w", err)
 	}
@@ -86,8 +410,8 @@ func (r *postgresAppRepository) DeleteMessage(ctx context.Context, messageID int
 }
 
 func (r *postgresAppRepository) GetUserByEmail(ctx context.Context, email string) (*domain.User, error) {
-	query := `SELECT id, email, nickname, username, created_at FROM users WHERE email = $1`
-	rows, err := r.db.Query(ctx, query, email)
+	query := `SELECT id, COALESCE(email, '') AS email, COALESCE(nickname, '') AS nickname, COALESCE(username, '') AS username, badges, created_at FROM users WHERE email = $1`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, email)
 	if err != nil { return nil, err }
 	user, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.User])
 	if errors.Is(err, pgx.ErrNoRows) { return nil, nil }
@@ -96,14 +420,14 @@ func (r *postgresAppRepository) GetUserByEmail(ctx context.Context, email string
 
 func (r *postgresAppRepository) SearchUsersByNickname(ctx context.Context, query string, selfID uuid.UUID, limit int) ([]domain.User, error) {
 	sqlQuery := `
-		SELECT id, email, nickname, username, created_at 
+		SELECT id, COALESCE(email, '') AS email, COALESCE(nickname, '') AS nickname, COALESCE(username, '') AS username, badges, created_at 
 		FROM users 
 		WHERE nickname ILIKE $1 
 		  AND id != $2
 		LIMIT $3
 	`
 	
-	rows, err := r.db.Query(ctx, sqlQuery, "%"+query+"%", selfID, limit)
+	rows, err := r.db.Pool(ctx).Query(ctx, sqlQuery, "%"+query+"%", selfID, limit)
 	if err != nil {
 		return nil, fmt.Errorf("error searching users: %w", err)
 	}
@@ -118,14 +442,56 @@ func (r *postgresAppRepository) SearchUsersByNickname(ctx context.Context, query
 }
 
 func (r *postgresAppRepository) GetUserByID(ctx context.Context, id uuid.UUID) (*domain.User, error) {
-	query := `SELECT id, email, nickname, username, created_at FROM users WHERE id = $1`
-	rows, err := r.db.Query(ctx, query, id)
+	query := `SELECT id, COALESCE(email, '') AS email, COALESCE(nickname, '') AS nickname, COALESCE(username, '') AS username, badges, created_at FROM users WHERE id = $1`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, id)
 	if err != nil { return nil, err }
 	user, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.User])
 	if errors.Is(err, pgx.ErrNoRows) { return nil, nil }
 	return &user, err
 }
 
+func (r *postgresAppRepository) GetUserSettings(ctx context.Context, userID uuid.UUID) (*domain.UserSettings, error) {
+	query := `SELECT user_id, email_notifications, push_previews, language, smart_replies, updated_at FROM user_settings WHERE user_id = $1`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, userID)
+	if err != nil { return nil, err }
+	settings, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.UserSettings])
+	if errors.Is(err, pgx.ErrNoRows) { return domain.DefaultUserSettings(userID), nil }
+	return &settings, err
+}
+
+func (r *postgresAppRepository) GetUserSettingsBatch(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]*domain.UserSettings, error) {
+	query := `SELECT user_id, email_notifications, push_previews, language, smart_replies, updated_at FROM user_settings WHERE user_id = ANY($1)`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error getting user settings: %w", err)
+	}
+	stored, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[domain.UserSettings])
+	if err != nil {
+		return nil, fmt.Errorf("error getting user settings: %w", err)
+	}
+	settings := make(map[uuid.UUID]*domain.UserSettings, len(userIDs))
+	for _, userID := range userIDs {
+		settings[userID] = domain.DefaultUserSettings(userID)
+	}
+	for _, s := range stored {
+		settings[s.UserID] = s
+	}
+	return settings, nil
+}
+
+func (r *postgresAppRepository) UpsertUserSettings(ctx context.Context, settings *domain.UserSettings) error {
+	query := `
+		INSERT INTO user_settings (user_id, email_notifications, push_previews, language, smart_replies, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		ON CONFLICT (user_id) DO UPDATE SET email_notifications = $2, push_previews = $3, language = $4, smart_replies = $5, updated_at = NOW()
+	`
+	_, err := r.db.Pool(ctx).Exec(ctx, query, settings.UserID, settings.EmailNotifications, settings.PushPreviews, settings.Language, settings.SmartReplies)
+	if err != nil {
+		return fmt.Errorf("error saving user settings: %w", err)
+	}
+	return nil
+}
+
 func (r *postgresAppRepository) FindPrivateRoomByParticipants(ctx context.Context, userOneID, userTwoID uuid.UUID) (uuid.UUID, error) {
 	var roomID uuid.UUID
 	query := `
@@ -143,7 +509,7 @@ func (r *postgresAppRepository) FindPrivateRoomByParticipants(ctx context.Contex
 		  ) = 2
 	`
 
-	err := r.db.QueryRow(ctx, query, userOneID, userTwoID).Scan(&roomID)
+	err := r.db.Pool(ctx).QueryRow(ctx, query, userOneID, userTwoID).Scan(&roomID)
 	
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -157,21 +523,23 @@ func (r *postgresAppRepository) FindPrivateRoomByParticipants(ctx context.Contex
 }
 
 func (r *postgresAppRepository) CreateFriendship(ctx context.Context, fs *domain.Friendship) error {
+	fs.UserOneID, fs.UserTwoID = domain.OrderFriendPair(fs.UserOneID, fs.UserTwoID)
 	query := `INSERT INTO friendships (user_one_id, user_two_id, status, action_user_id) VALUES ($1, $2, $3, $4)`
-	_, err := r.db.Exec(ctx, query, fs.UserOneID, fs.UserTwoID, fs.Status, fs.ActionUserID)
+	_, err := r.db.Pool(ctx).Exec(ctx, query, fs.UserOneID, fs.UserTwoID, fs.Status, fs.ActionUserID)
 	return err
 }
 
 func (r *postgresAppRepository) UpdateFriendshipStatus(ctx context.Context, tx pgx.Tx, fs *domain.Friendship) error {
+	fs.UserOneID, fs.UserTwoID = domain.OrderFriendPair(fs.UserOneID, fs.UserTwoID)
 	query := `UPDATE friendships SET status = $3, action_user_id = $4, updated_at = NOW() WHERE user_one_id = $1 AND user_two_id = $2`
 	_, err := tx.Exec(ctx, query, fs.UserOneID, fs.UserTwoID, fs.Status, fs.ActionUserID)
 	return err
 }
 
 func (r *postgresAppRepository) GetFriendship(ctx context.Context, userOneID, userTwoID uuid.UUID) (*domain.Friendship, error) {
-	if userOneID.String() > userTwoID.String() { userOneID, userTwoID = userTwoID, userOneID }
+	userOneID, userTwoID = domain.OrderFriendPair(userOneID, userTwoID)
 	query := `SELECT user_one_id, user_two_id, status, action_user_id, created_at, updated_at FROM friendships WHERE user_one_id = $1 AND user_two_id = $2`
-	rows, err := r.db.Query(ctx, query, userOneID, userTwoID)
+	rows, err := r.db.Pool(ctx).Query(ctx, query, userOneID, userTwoID)
 	if err != nil { return nil, err }
 	fs, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.Friendship])
 	if errors.Is(err, pgx.ErrNoRows) { return nil, nil }
@@ -180,30 +548,97 @@ func (r *postgresAppRepository) GetFriendship(ctx context.Context, userOneID, us
 
 func (r *postgresAppRepository) GetFriendshipsForUser(ctx context.Context, userID uuid.UUID, status string) ([]domain.Friendship, error) {
 	query := `SELECT user_one_id, user_two_id, status, action_user_id, created_at, updated_at FROM friendships WHERE (user_one_id = $1 OR user_two_id = $1) AND status = $2`
-	rows, err := r.db.Query(ctx, query, userID, status)
+	rows, err := r.db.Pool(ctx).Query(ctx, query, userID, status)
 	if err != nil { return nil, err }
 	return pgx.CollectRows(rows, pgx.RowToStructByName[domain.Friendship])
 }
 
 func (r *postgresAppRepository) DeleteFriendship(ctx context.Context, userOneID, userTwoID uuid.UUID) error {
-	if userOneID.String() > userTwoID.String() { userOneID, userTwoID = userTwoID, userOneID }
+	userOneID, userTwoID = domain.OrderFriendPair(userOneID, userTwoID)
 	query := `DELETE FROM friendships WHERE user_one_id = $1 AND user_two_id = $2`
-	_, err := r.db.Exec(ctx, query, userOneID, userTwoID)
+	_, err := r.db.Pool(ctx).Exec(ctx, query, userOneID, userTwoID)
 	return err
 }
 
 func (r *postgresAppRepository) IsUserInRoom(ctx context.Context, userID, roomID uuid.UUID) (bool, error) {
 	var exists bool
 	query := `SELECT EXISTS(SELECT 1 FROM room_participants WHERE user_id = $1 AND room_id = $2 AND is_blocked = false)`
-	err := r.db.QueryRow(ctx, query, userID, roomID).Scan(&exists)
+	err := r.db.Pool(ctx).QueryRow(ctx, query, userID, roomID).Scan(&exists)
 	return exists, err
 }
 
+func (r *postgresAppRepository) GetRoomRole(ctx context.Context, userID, roomID uuid.UUID) (string, error) {
+	var role string
+	query := `SELECT role FROM room_participants WHERE user_id = $1 AND room_id = $2 AND is_blocked = false`
+	err := r.db.Pool(ctx).QueryRow(ctx, query, userID, roomID).Scan(&role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	return role, err
+}
+
+func (r *postgresAppRepository) GetRoomMetadata(ctx context.Context, roomID uuid.UUID) (map[string]json.RawMessage, error) {
+	var metadata map[string]json.RawMessage
+	err := r.db.Pool(ctx).QueryRow(ctx, `SELECT metadata FROM rooms WHERE id = $1`, roomID).Scan(&metadata)
+	if err != nil {
+		return nil, fmt.Errorf("error getting metadata for room %s: %w", roomID, err)
+	}
+	return metadata, nil
+}
+
+func (r *postgresAppRepository) UpdateRoomMetadata(ctx context.Context, roomID uuid.UUID, set map[string]json.RawMessage, remove []string, maxBytes, maxKeys int) (map[string]json.RawMessage, bool, error) {
+	patch, err := json.Marshal(set)
+	if err != nil {
+		return nil, false, fmt.Errorf("error encoding room metadata: %w", err)
+	}
+	if remove == nil {
+		remove = []string{}
+	}
+	query := `
+		WITH merged AS (
+			SELECT id, (metadata || $2::jsonb) - $3::text[] AS metadata FROM rooms WHERE id = $1 FOR UPDATE
+		)
+		UPDATE rooms r SET metadata = m.metadata
+		FROM merged m
+		WHERE r.id = m.id
+			AND octet_length(m.metadata::text) <= $4
+			AND (SELECT COUNT(*) FROM jsonb_object_keys(m.metadata)) <= $5
+		RETURNING r.metadata
+	`
+	var metadata map[string]json.RawMessage
+	err = r.db.Pool(ctx).QueryRow(ctx, query, roomID, string(patch), remove, maxBytes, maxKeys).Scan(&metadata)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("error updating metadata for room %s: %w", roomID, err)
+	}
+	return metadata, true, nil
+}
+
+func (r *postgresAppRepository) GetRoomMemberIDs(ctx context.Context, roomID uuid.UUID) ([]uuid.UUID, error) {
+	query := `SELECT user_id FROM room_participants WHERE room_id = $1 AND is_blocked = false`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting room members: %w", err)
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+}
+
+func (r *postgresAppRepository) GetRoomAdminIDs(ctx context.Context, roomID uuid.UUID) ([]uuid.UUID, error) {
+	query := `SELECT user_id FROM room_participants WHERE room_id = $1 AND is_blocked = false AND role IN ('owner', 'admin')`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting room admins: %w", err)
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+}
+
 func (r *postgresAppRepository) GetRoomByID(ctx context.Context, roomID uuid.UUID) (*domain.Room, error) {
-	query := `SELECT id, type, name, owner_id, created_at, updated_at FROM rooms WHERE id = $1`
-	rows, err := r.db.Query(ctx, query, roomID)
+	query := `SELECT id, type, name, owner_id, created_at, updated_at, state FROM rooms WHERE id = $1`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, roomID)
 	if err != nil { return nil, err }
-	room, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.Room])
+	room, err := pgx.CollectOneRow(rows, pgx.RowToStructByNameLax[domain.Room])
 	if errors.Is(err, pgx.ErrNoRows) { return nil, fmt.Errorf("room not found") }
 	return &room, err
 }
@@ -220,6 +655,66 @@ func (r *postgresAppRepository) AddUserToRoom(ctx context.Context, tx pgx.Tx, us
 	return err
 }
 
+func (r *postgresAppRepository) AddUserToRoomWithRole(ctx context.Context, tx pgx.Tx, userID, roomID uuid.UUID, role string) error {
+	query := `INSERT INTO room_participants (user_id, room_id, role) VALUES ($1, $2, $3)`
+	_, err := tx.Exec(ctx, query, userID, roomID, role)
+	return err
+}
+
+func (r *postgresAppRepository) CopyRoomMetadata(ctx context.Context, tx pgx.Tx, fromRoomID, toRoomID uuid.UUID) error {
+	query := `UPDATE rooms SET metadata = (SELECT metadata FROM rooms WHERE id = $1) WHERE id = $2`
+	if _, err := tx.Exec(ctx, query, fromRoomID, toRoomID); err != nil {
+		return fmt.Errorf("error copying room metadata: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresAppRepository) CopyRoomMembers(ctx context.Context, tx pgx.Tx, fromRoomID, toRoomID, excludeUserID uuid.UUID) ([]uuid.UUID, error) {
+	query := `
+		INSERT INTO room_participants (user_id, room_id, role)
+		SELECT user_id, $2, CASE WHEN role = 'owner' THEN 'admin' ELSE role END
+		FROM room_participants
+		WHERE room_id = $1 AND user_id <> $3 AND is_blocked = false AND role <> 'guest'
+		ON CONFLICT DO NOTHING
+		RETURNING user_id
+	`
+	rows, err := tx.Query(ctx, query, fromRoomID, toRoomID, excludeUserID)
+	if err != nil {
+		return nil, fmt.Errorf("error copying room members: %w", err)
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+}
+
+func (r *postgresAppRepository) CopyRecentMessages(ctx context.Context, tx pgx.Tx, fromRoomID, toRoomID, authorID uuid.UUID, limit int) (int64, error) {
+	query := `
+		WITH recent AS (
+			SELECT user_id, content, content_type, rich_content, links, hashtags, metadata, created_at
+			FROM messages
+			WHERE room_id = $1 AND user_id = $4 AND kind = 'text' AND deleted_at IS NULL
+			ORDER BY created_at DESC
+			LIMIT $3
+		), copied AS (
+			INSERT INTO messages (message_uid, room_id, user_id, content, kind, content_type, rich_content, links, hashtags, metadata, created_at)
+			SELECT uuid_generate_v4(), $2, user_id, content, 'text', content_type, rich_content, links, hashtags, metadata, created_at
+			FROM recent
+			ORDER BY created_at
+			RETURNING created_at
+		)
+		UPDATE rooms SET last_message_at = (SELECT MAX(created_at) FROM copied)
+		WHERE id = $2 AND EXISTS (SELECT 1 FROM copied)
+		RETURNING (SELECT COUNT(*) FROM copied)
+	`
+	var copied int64
+	err := tx.QueryRow(ctx, query, fromRoomID, toRoomID, limit, authorID).Scan(&copied)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error importing history into room %s: %w", toRoomID, err)
+	}
+	return copied, nil
+}
+
 func (r *postgresAppRepository) GetRoomsForUser(ctx context.Context, userID uuid.UUID) ([]domain.Room, error) {
 	query := `
 		WITH ranked_messages AS (
@@ -235,19 +730,33 @@ func (r *postgresAppRepository) GetRoomsForUser(ctx context.Context, userID uuid
 			r.type,
 			r.name,
 			lm.content as last_message_content,
-			lm.created_at as last_message_created_at
+			lm.created_at as last_message_created_at,
+			unread.messages as unread_messages,
+			unread.mentions as unread_mentions,
+			rp.snoozed_until,
+			r.state
 		FROM 
 			rooms r
 		JOIN 
 			room_participants rp ON r.id = rp.room_id
 		LEFT JOIN 
 			ranked_messages lm ON r.id = lm.room_id AND lm.rn = 1
+		CROSS JOIN LATERAL (
+			SELECT
+				COUNT(*) AS messages,
+				COUNT(*) FILTER (WHERE EXISTS (SELECT 1 FROM message_mentions mm WHERE mm.message_id = m.id AND mm.user_id = $1) OR 'everyone' = ANY(m.group_mentions) OR ('admins' = ANY(m.group_mentions) AND rp.role IN ('owner', 'admin'))) AS mentions
+			FROM messages m
+			WHERE m.room_id = r.id
+				AND m.user_id <> $1
+				AND m.deleted_at IS NULL
+				AND NOT EXISTS (SELECT 1 FROM message_read_status rs WHERE rs.message_id = m.id AND rs.user_id = $1)
+		) unread
 		WHERE 
 			rp.user_id = $1
 		ORDER BY
 			COALESCE(lm.created_at, r.created_at) DESC
 	`
-		rows, err := r.db.Query(ctx, query, userID)
+		rows, err := r.db.Pool(ctx).Query(ctx, query, userID)
 	if err != nil {
 		return nil, fmt.Errorf("error getting rooms for user: %w", err)
 	}
@@ -262,9 +771,13 @@ func (r *postgresAppRepository) GetRoomsForUser(ctx context.Context, userID uuid
 			&room.Name,
 			&room.LastMessageContent,
 			&room.LastMessageCreatedAt,
+			&room.UnreadMessages,
+			&room.UnreadMentions,
+			&room.SnoozedUntil,
+			&room.State,
 		)
 		if err != nil {
-			log.Printf("Warning: Error scanning room row: %v", err)
+			repoLog.Warnf("Error scanning room row: %v", err)
 			continue 
 		}
 		rooms = append(rooms, room)
@@ -273,9 +786,14 @@ func (r *postgresAppRepository) GetRoomsForUser(ctx context.Context, userID uuid
 	return rooms, nil
 }
 
-func (r *postgresAppRepository) GetMessagesForRoom(ctx context.Context, roomID uuid.UUID, limit, offset int) ([]domain.Message, error) {
-	query := `SELECT id, message_uid, room_id, user_id, content, reply_to_message_id, created_at, updated_at, deleted_at FROM messages WHERE room_id = $1 AND deleted_at IS NULL ORDER BY created_at DESC LIMIT $2 OFFSET $3`
-	rows, err := r.db.Query(ctx, query, roomID, limit, offset)
+func (r *postgresAppRepository) GetMessagesForRoom(ctx context.Context, roomID uuid.UUID, tag string, limit, offset int) ([]domain.Message, error) {
+	query := `SELECT id, message_uid, room_id, user_id, content, kind, content_type, rich_content, links, hashtags, group_mentions, metadata, attachment_id, reply_to_message_id, created_at, updated_at, deleted_at FROM messages WHERE room_id = $1 AND deleted_at IS NULL ORDER BY created_at DESC LIMIT $2 OFFSET $3`
+	args := []any{roomID, limit, offset}
+	if tag != "" {
+		query = `SELECT id, message_uid, room_id, user_id, content, kind, content_type, rich_content, links, hashtags, group_mentions, metadata, attachment_id, reply_to_message_id, created_at, updated_at, deleted_at FROM messages WHERE room_id = $1 AND hashtags @> ARRAY[$4]::text[] AND deleted_at IS NULL ORDER BY created_at DESC LIMIT $2 OFFSET $3`
+		args = append(args, tag)
+	}
+	rows, err := r.db.Pool(ctx).Query(ctx, query, args...)
 	if err != nil { return nil, err }
 	messages, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.Message])
 	if err != nil { return nil, err }
@@ -285,15 +803,1606 @@ func (r *postgresAppRepository) GetMessagesForRoom(ctx context.Context, roomID u
 	return messages, nil
 }
 
-func (r *postgresAppRepository) CreateMessage(ctx context.Context, msg *domain.Message) (*domain.Message, error) {
-	query := `INSERT INTO messages (message_uid, room_id, user_id, content, reply_to_message_id) VALUES (COALESCE($1, uuid_generate_v4()), $2, $3, $4, $5) RETURNING id, message_uid, created_at`
-	err := r.db.QueryRow(ctx, query, msg.MessageUID, msg.RoomID, msg.UserID, msg.Content, msg.ReplyToMessageID).Scan(&msg.ID, &msg.MessageUID, &msg.CreatedAt)
-	return msg, err
+func (r *postgresAppRepository) GetRoomTags(ctx context.Context, roomID uuid.UUID, limit int) ([]domain.RoomTag, error) {
+	query := `
+		SELECT tag, COUNT(*) AS message_count, MAX(m.created_at) AS last_used_at
+		FROM messages m, UNNEST(m.hashtags) AS tag
+		WHERE m.room_id = $1 AND m.deleted_at IS NULL AND m.hashtags <> '{}'
+		GROUP BY tag
+		ORDER BY message_count DESC, last_used_at DESC
+		LIMIT $2
+	`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, roomID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error getting tags for room %s: %w", roomID, err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[domain.RoomTag])
 }
 
-func (r *postgresAppRepository) MarkMessageAsRead(ctx context.Context, messageID int64, userID uuid.UUID) (*time.Time, error) {
-	var readAt time.Time
-	query := `INSERT INTO message_read_status (message_id, user_id, read_at) VALUES ($1, $2, NOW()) ON CONFLICT (message_id, user_id) DO UPDATE SET read_at = NOW() RETURNING read_at`
-	err := r.db.QueryRow(ctx, query, messageID, userID).Scan(&readAt)
-	return &readAt, err
-}
\ No newline at end of file
+func (r *postgresAppRepository) SearchMessages(ctx context.Context, roomIDs []uuid.UUID, query string, since time.Time, limit int) ([]domain.Message, error) {
+	sql := `
+		SELECT id, message_uid, room_id, user_id, content, kind, content_type, rich_content, links, hashtags, group_mentions, metadata, attachment_id, reply_to_message_id, created_at, updated_at, deleted_at
+		FROM messages
+		WHERE room_id = ANY($1) AND deleted_at IS NULL AND created_at >= $4 AND search_vector @@ websearch_to_tsquery('simple', $2)
+		ORDER BY ts_rank(search_vector, websearch_to_tsquery('simple', $2)) DESC, created_at DESC
+		LIMIT $3
+	`
+	rows, err := r.db.Pool(ctx).Query(ctx, sql, roomIDs, query, limit, since)
+	if err != nil {
+		return nil, fmt.Errorf("error searching messages: %w", err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[domain.Message])
+}
+
+func (r *postgresAppRepository) GetMessagesAfter(ctx context.Context, afterID int64, limit int) ([]domain.Message, error) {
+	query := `SELECT id, message_uid, room_id, user_id, content, kind, content_type, rich_content, links, hashtags, group_mentions, metadata, attachment_id, reply_to_message_id, created_at, updated_at, deleted_at FROM messages WHERE id > $1 AND deleted_at IS NULL ORDER BY id LIMIT $2`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error getting messages after %d: %w", afterID, err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[domain.Message])
+}
+
+func (r *postgresAppRepository) GetMessagesByIDs(ctx context.Context, messageIDs []int64) ([]domain.Message, error) {
+	query := `SELECT id, message_uid, room_id, user_id, content, kind, content_type, rich_content, links, hashtags, group_mentions, metadata, attachment_id, reply_to_message_id, created_at, updated_at, deleted_at FROM messages WHERE id = ANY($1) AND deleted_at IS NULL`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, messageIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error getting messages by ID: %w", err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[domain.Message])
+}
+
+func (r *postgresAppRepository) GetRoomIDsForUser(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := r.db.Pool(ctx).Query(ctx, `SELECT room_id FROM room_participants WHERE user_id = $1 AND is_blocked = false`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting rooms for user %s: %w", userID, err)
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+}
+
+func (r *postgresAppRepository) GetAwayStatus(ctx context.Context, userID uuid.UUID) (*domain.AwayStatus, error) {
+	rows, err := r.db.Pool(ctx).Query(ctx, `SELECT user_id, message, starts_at, ends_at, updated_at FROM user_away WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting away status for %s: %w", userID, err)
+	}
+	status, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.AwayStatus])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting away status for %s: %w", userID, err)
+	}
+	return &status, nil
+}
+
+func (r *postgresAppRepository) UpsertAwayStatus(ctx context.Context, status *domain.AwayStatus) error {
+	query := `
+		INSERT INTO user_away (user_id, message, starts_at, ends_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE
+		SET message = EXCLUDED.message, starts_at = EXCLUDED.starts_at, ends_at = EXCLUDED.ends_at, updated_at = NOW()
+		RETURNING updated_at
+	`
+	if err := r.db.Pool(ctx).QueryRow(ctx, query, status.UserID, status.Message, status.StartsAt, status.EndsAt).Scan(&status.UpdatedAt); err != nil {
+		return fmt.Errorf("error saving away status for %s: %w", status.UserID, err)
+	}
+	_, err := r.db.Pool(ctx).Exec(ctx, `DELETE FROM away_replies WHERE user_id = $1`, status.UserID)
+	return err
+}
+
+func (r *postgresAppRepository) DeleteAwayStatus(ctx context.Context, userID uuid.UUID) error {
+	if _, err := r.db.Pool(ctx).Exec(ctx, `DELETE FROM user_away WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("error clearing away status for %s: %w", userID, err)
+	}
+	return nil
+}
+
+func (r *postgresAppRepository) GetAwayResponder(ctx context.Context, roomID, senderID uuid.UUID) (*domain.AwayStatus, error) {
+	query := `
+		SELECT a.user_id, a.message, a.starts_at, a.ends_at, a.updated_at
+		FROM user_away a
+		JOIN room_participants rp ON rp.user_id = a.user_id AND rp.room_id = $1
+		JOIN rooms r ON r.id = rp.room_id AND r.type = 'private'
+		WHERE a.user_id <> $2
+			AND (a.starts_at IS NULL OR a.starts_at <= NOW())
+			AND (a.ends_at IS NULL OR a.ends_at > NOW())
+	`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, roomID, senderID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting away responder in room %s: %w", roomID, err)
+	}
+	status, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.AwayStatus])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting away responder in room %s: %w", roomID, err)
+	}
+	return &status, nil
+}
+
+func (r *postgresAppRepository) SetRoomSnooze(ctx context.Context, userID, roomID uuid.UUID, until *time.Time) (bool, error) {
+	query := `UPDATE room_participants SET snoozed_until = $3 WHERE user_id = $1 AND room_id = $2`
+	cmdTag, err := r.db.Pool(ctx).Exec(ctx, query, userID, roomID, until)
+	if err != nil {
+		return false, fmt.Errorf("error snoozing room %s for %s: %w", roomID, userID, err)
+	}
+	return cmdTag.RowsAffected() > 0, nil
+}
+
+func (r *postgresAppRepository) ExpireRoomSnoozes(ctx context.Context) ([]domain.RoomSnooze, error) {
+	query := `
+		UPDATE room_participants SET snoozed_until = NULL
+		WHERE snoozed_until IS NOT NULL AND snoozed_until <= NOW()
+		RETURNING room_id, user_id
+	`
+	rows, err := r.db.Pool(ctx).Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error expiring room snoozes: %w", err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[domain.RoomSnooze])
+}
+
+func (r *postgresAppRepository) GetSnoozedMemberIDs(ctx context.Context, roomID uuid.UUID) ([]uuid.UUID, error) {
+	query := `SELECT user_id FROM room_participants WHERE room_id = $1 AND snoozed_until > NOW()`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting snoozed members of room %s: %w", roomID, err)
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+}
+
+func (r *postgresAppRepository) CountShareableMessages(ctx context.Context, roomID uuid.UUID, firstID, lastID int64) (int, int, error) {
+	query := `
+		SELECT COUNT(*) FILTER (WHERE id IN ($2, $3)), COUNT(*)
+		FROM messages
+		WHERE room_id = $1 AND id BETWEEN $2 AND $3 AND deleted_at IS NULL
+	`
+	var endpoints, total int
+	if err := r.db.Pool(ctx).QueryRow(ctx, query, roomID, firstID, lastID).Scan(&endpoints, &total); err != nil {
+		return 0, 0, fmt.Errorf("error counting messages %d-%d in room %s: %w", firstID, lastID, roomID, err)
+	}
+	return endpoints, total, nil
+}
+
+func (r *postgresAppRepository) CreateShareLink(ctx context.Context, link *domain.ShareLink, tokenHash string) error {
+	query := `
+		INSERT INTO room_share_links (id, token_hash, room_id, created_by, title, first_message_id, last_message_id, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING created_at
+	`
+	err := r.db.Pool(ctx).QueryRow(ctx, query, link.ID, tokenHash, link.RoomID, link.CreatedBy, link.Title, link.FirstMessageID, link.LastMessageID, link.ExpiresAt).Scan(&link.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating share link for room %s: %w", link.RoomID, err)
+	}
+	return nil
+}
+
+const shareLinkColumns = `
+	l.id, l.room_id, l.created_by, l.title, l.first_message_id, l.last_message_id, l.expires_at, l.revoked_at, l.created_at,
+	(SELECT COUNT(*) FROM share_link_access a WHERE a.link_id = l.id) AS access_count,
+	(SELECT MAX(a.accessed_at) FROM share_link_access a WHERE a.link_id = l.id) AS last_accessed_at
+`
+
+func (r *postgresAppRepository) ListShareLinks(ctx context.Context, roomID uuid.UUID) ([]domain.ShareLink, error) {
+	query := `SELECT ` + shareLinkColumns + ` FROM room_share_links l WHERE l.room_id = $1 ORDER BY l.created_at DESC`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing share links for room %s: %w", roomID, err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[domain.ShareLink])
+}
+
+func (r *postgresAppRepository) RevokeShareLink(ctx context.Context, roomID, linkID uuid.UUID) (bool, error) {
+	query := `UPDATE room_share_links SET revoked_at = NOW() WHERE id = $1 AND room_id = $2 AND revoked_at IS NULL`
+	cmdTag, err := r.db.Pool(ctx).Exec(ctx, query, linkID, roomID)
+	if err != nil {
+		return false, fmt.Errorf("error revoking share link %s: %w", linkID, err)
+	}
+	return cmdTag.RowsAffected() > 0, nil
+}
+
+func (r *postgresAppRepository) GetShareLinkByTokenHash(ctx context.Context, tokenHash string) (*domain.ShareLink, error) {
+	query := `SELECT ` + shareLinkColumns + ` FROM room_share_links l WHERE l.token_hash = $1`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, tokenHash)
+	if err != nil {
+		return nil, fmt.Errorf("error getting share link: %w", err)
+	}
+	link, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.ShareLink])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting share link: %w", err)
+	}
+	return &link, nil
+}
+
+func (r *postgresAppRepository) GetSharedMessages(ctx context.Context, roomID uuid.UUID, firstID, lastID int64) ([]domain.SharedMessage, error) {
+	query := `
+		SELECT m.id, COALESCE(u.nickname, 'Unknown') AS author, m.content, m.kind, m.created_at
+		FROM messages m
+		LEFT JOIN users u ON u.id = m.user_id
+		WHERE m.room_id = $1 AND m.id BETWEEN $2 AND $3 AND m.deleted_at IS NULL
+		ORDER BY m.id
+	`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, roomID, firstID, lastID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting shared messages for room %s: %w", roomID, err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[domain.SharedMessage])
+}
+
+func (r *postgresAppRepository) LogShareLinkAccess(ctx context.Context, linkID uuid.UUID, ipAddress, userAgent string) error {
+	query := `INSERT INTO share_link_access (link_id, ip_address, user_agent) VALUES ($1, $2, $3)`
+	if _, err := r.db.Pool(ctx).Exec(ctx, query, linkID, ipAddress, userAgent); err != nil {
+		return fmt.Errorf("error logging access to share link %s: %w", linkID, err)
+	}
+	return nil
+}
+
+func (r *postgresAppRepository) CreateWidgetKey(ctx context.Context, key *domain.WidgetKey, keyHash string) error {
+	query := `
+		INSERT INTO room_widget_keys (id, room_id, key_hash, key_prefix, label, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at
+	`
+	err := r.db.Pool(ctx).QueryRow(ctx, query, key.ID, key.RoomID, keyHash, key.Prefix, key.Label, key.CreatedBy).Scan(&key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating widget key for room %s: %w", key.RoomID, err)
+	}
+	return nil
+}
+
+func (r *postgresAppRepository) ListWidgetKeys(ctx context.Context, roomID uuid.UUID) ([]domain.WidgetKey, error) {
+	query := `
+		SELECT id, room_id, key_prefix, label, created_by, created_at, revoked_at
+		FROM room_widget_keys WHERE room_id = $1 ORDER BY created_at DESC
+	`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing widget keys for room %s: %w", roomID, err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[domain.WidgetKey])
+}
+
+func (r *postgresAppRepository) RevokeWidgetKey(ctx context.Context, roomID, keyID uuid.UUID) (bool, error) {
+	query := `UPDATE room_widget_keys SET revoked_at = NOW() WHERE id = $1 AND room_id = $2 AND revoked_at IS NULL`
+	cmdTag, err := r.db.Pool(ctx).Exec(ctx, query, keyID, roomID)
+	if err != nil {
+		return false, fmt.Errorf("error revoking widget key %s: %w", keyID, err)
+	}
+	return cmdTag.RowsAffected() > 0, nil
+}
+
+func (r *postgresAppRepository) GetWidgetKeyByHash(ctx context.Context, keyHash string) (*domain.WidgetKey, error) {
+	query := `
+		SELECT id, room_id, key_prefix, label, created_by, created_at, revoked_at
+		FROM room_widget_keys WHERE key_hash = $1 AND revoked_at IS NULL
+	`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, keyHash)
+	if err != nil {
+		return nil, fmt.Errorf("error getting widget key: %w", err)
+	}
+	key, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.WidgetKey])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting widget key: %w", err)
+	}
+	return &key, nil
+}
+
+func (r *postgresAppRepository) CreateWidgetGuest(ctx context.Context, tx pgx.Tx, guest *domain.WidgetGuest, tokenHash string) error {
+	if _, err := tx.Exec(ctx, `INSERT INTO users (id, nickname) VALUES ($1, $2)`, guest.UserID, guest.DisplayName); err != nil {
+		return fmt.Errorf("error creating guest user %s: %w", guest.UserID, err)
+	}
+	query := `
+		INSERT INTO widget_guests (user_id, key_id, room_id, token_hash, display_name)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at, last_seen_at
+	`
+	err := tx.QueryRow(ctx, query, guest.UserID, guest.KeyID, guest.RoomID, tokenHash, guest.DisplayName).Scan(&guest.CreatedAt, &guest.LastSeenAt)
+	if err != nil {
+		return fmt.Errorf("error creating widget guest %s: %w", guest.UserID, err)
+	}
+	return nil
+}
+
+func (r *postgresAppRepository) LockWidgetGuest(ctx context.Context, tx pgx.Tx, tokenHash string) (*domain.WidgetGuest, error) {
+	query := `SELECT user_id, key_id, room_id, display_name, created_at, last_seen_at FROM widget_guests WHERE token_hash = $1 FOR UPDATE`
+	rows, err := tx.Query(ctx, query, tokenHash)
+	if err != nil {
+		return nil, fmt.Errorf("error locking widget guest: %w", err)
+	}
+	guest, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.WidgetGuest])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error locking widget guest: %w", err)
+	}
+	return &guest, nil
+}
+
+func (r *postgresAppRepository) MergeWidgetGuest(ctx context.Context, tx pgx.Tx, guest *domain.WidgetGuest, userID uuid.UUID) (*domain.GuestMerge, error) {
+	merge := &domain.GuestMerge{ID: uuid.New(), GuestID: guest.UserID, UserID: userID, RoomID: guest.RoomID, GuestDisplayName: guest.DisplayName}
+
+	tag, err := tx.Exec(ctx, `INSERT INTO room_participants (user_id, room_id, role) VALUES ($1, $2, 'member') ON CONFLICT (room_id, user_id) DO NOTHING`, userID, guest.RoomID)
+	if err != nil {
+		return nil, fmt.Errorf("error adding %s to room %s: %w", userID, guest.RoomID, err)
+	}
+	merge.AlreadyMember = tag.RowsAffected() == 0
+
+	tag, err = tx.Exec(ctx, `UPDATE messages SET user_id = $2 WHERE user_id = $1`, guest.UserID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error moving guest messages: %w", err)
+	}
+	merge.MessagesMoved = int(tag.RowsAffected())
+
+	statements := []string{
+		`INSERT INTO message_read_status (message_id, user_id, read_at) SELECT message_id, $2, read_at FROM message_read_status WHERE user_id = $1 ON CONFLICT DO NOTHING`,
+		`INSERT INTO message_mentions (message_id, user_id) SELECT message_id, $2 FROM message_mentions WHERE user_id = $1 ON CONFLICT DO NOTHING`,
+		`INSERT INTO attachment_access (attachment_id, user_id) SELECT attachment_id, $2 FROM attachment_access WHERE user_id = $1 ON CONFLICT DO NOTHING`,
+		`UPDATE room_attachments SET uploader_id = $2 WHERE uploader_id = $1`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(ctx, statement, guest.UserID, userID); err != nil {
+			return nil, fmt.Errorf("error merging guest %s into %s: %w", guest.UserID, userID, err)
+		}
+	}
+	for _, statement := range []string{
+		`DELETE FROM room_participants WHERE user_id = $1`,
+		`DELETE FROM widget_guests WHERE user_id = $1`,
+	} {
+		if _, err := tx.Exec(ctx, statement, guest.UserID); err != nil {
+			return nil, fmt.Errorf("error removing guest %s: %w", guest.UserID, err)
+		}
+	}
+	tag, err = tx.Exec(ctx, `DELETE FROM users WHERE id = $1`, guest.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("error removing guest user %s: %w", guest.UserID, err)
+	}
+	if tag.RowsAffected() != 1 {
+		return nil, fmt.Errorf("guest user %s not found while merging into %s", guest.UserID, userID)
+	}
+
+	query := `
+		INSERT INTO guest_merges (id, guest_id, user_id, room_id, guest_display_name, messages_moved, already_member)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING merged_at
+	`
+	err = tx.QueryRow(ctx, query, merge.ID, merge.GuestID, merge.UserID, merge.RoomID, merge.GuestDisplayName, merge.MessagesMoved, merge.AlreadyMember).Scan(&merge.MergedAt)
+	if err != nil {
+		return nil, fmt.Errorf("error recording guest merge: %w", err)
+	}
+	return merge, nil
+}
+
+func (r *postgresAppRepository) TouchWidgetGuest(ctx context.Context, tokenHash string) (*domain.WidgetGuest, error) {
+	query := `
+		UPDATE widget_guests g SET last_seen_at = NOW()
+		FROM room_widget_keys k
+		WHERE g.token_hash = $1 AND k.id = g.key_id AND k.revoked_at IS NULL
+		RETURNING g.user_id, g.key_id, g.room_id, g.display_name, g.created_at, g.last_seen_at
+	`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, tokenHash)
+	if err != nil {
+		return nil, fmt.Errorf("error authenticating widget guest: %w", err)
+	}
+	guest, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.WidgetGuest])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error authenticating widget guest: %w", err)
+	}
+	return &guest, nil
+}
+
+func (r *postgresAppRepository) GetGuestMessages(ctx context.Context, roomID uuid.UUID, since time.Time, afterID int64, limit int) ([]domain.SharedMessage, error) {
+	query := `
+		SELECT * FROM (
+			SELECT m.id, COALESCE(u.nickname, 'Unknown') AS author, m.content, m.kind, m.created_at
+			FROM messages m
+			LEFT JOIN users u ON u.id = m.user_id
+			WHERE m.room_id = $1 AND m.id > $2 AND m.created_at >= $4 AND m.deleted_at IS NULL
+			ORDER BY CASE WHEN $2 = 0 THEN -m.id ELSE m.id END
+			LIMIT $3
+		) page ORDER BY id
+	`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, roomID, afterID, limit, since)
+	if err != nil {
+		return nil, fmt.Errorf("error getting guest messages for room %s: %w", roomID, err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[domain.SharedMessage])
+}
+
+const supportConversationColumns = `room_id, customer_id, agent_id, CASE WHEN resolved_at IS NOT NULL THEN 'resolved' WHEN agent_id IS NULL THEN 'queued' ELSE 'assigned' END AS status, subject, created_at, assigned_at, updated_at, first_response_at, resolved_at, first_response_breached_at, resolution_breached_at`
+
+func (r *postgresAppRepository) UpsertSupportAgent(ctx context.Context, userID uuid.UUID, available bool) error {
+	query := `
+		INSERT INTO support_agents (user_id, available) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET available = EXCLUDED.available
+	`
+	if _, err := r.db.Pool(ctx).Exec(ctx, query, userID, available); err != nil {
+		return fmt.Errorf("error saving support agent %s: %w", userID, err)
+	}
+	return nil
+}
+
+func (r *postgresAppRepository) RemoveSupportAgent(ctx context.Context, userID uuid.UUID) (bool, error) {
+	query := `
+		WITH requeued AS (
+			UPDATE support_conversations SET agent_id = NULL, assigned_at = NULL, updated_at = NOW()
+			WHERE agent_id = $1
+		)
+		DELETE FROM support_agents WHERE user_id = $1
+	`
+	tag, err := r.db.Pool(ctx).Exec(ctx, query, userID)
+	if err != nil {
+		return false, fmt.Errorf("error removing support agent %s: %w", userID, err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *postgresAppRepository) SetSupportAgentAvailability(ctx context.Context, userID uuid.UUID, available bool) (bool, error) {
+	tag, err := r.db.Pool(ctx).Exec(ctx, `UPDATE support_agents SET available = $2 WHERE user_id = $1`, userID, available)
+	if err != nil {
+		return false, fmt.Errorf("error updating availability for agent %s: %w", userID, err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *postgresAppRepository) IsSupportAgent(ctx context.Context, userID uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.db.Pool(ctx).QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM support_agents WHERE user_id = $1)`, userID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("error checking support agent %s: %w", userID, err)
+	}
+	return exists, nil
+}
+
+func (r *postgresAppRepository) ListSupportAgents(ctx context.Context) ([]domain.SupportAgent, error) {
+	query := `
+		SELECT a.user_id, u.nickname, a.available, COUNT(c.room_id)::int AS active_conversations, a.created_at
+		FROM support_agents a
+		JOIN users u ON u.id = a.user_id
+		LEFT JOIN support_conversations c ON c.agent_id = a.user_id AND c.resolved_at IS NULL
+		GROUP BY a.user_id, u.nickname, a.available, a.created_at
+		ORDER BY a.created_at
+	`
+	rows, err := r.db.Pool(ctx).Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error listing support agents: %w", err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[domain.SupportAgent])
+}
+
+func (r *postgresAppRepository) PickSupportAgent(ctx context.Context, tx pgx.Tx) (*uuid.UUID, error) {
+	query := `
+		SELECT a.user_id
+		FROM support_agents a
+		LEFT JOIN support_conversations c ON c.agent_id = a.user_id AND c.resolved_at IS NULL
+		WHERE a.available
+		GROUP BY a.user_id, a.created_at
+		ORDER BY COUNT(c.room_id), a.created_at
+		LIMIT 1
+	`
+	var agentID uuid.UUID
+	err := tx.QueryRow(ctx, query).Scan(&agentID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error picking support agent: %w", err)
+	}
+	return &agentID, nil
+}
+
+func (r *postgresAppRepository) CreateSupportConversation(ctx context.Context, tx pgx.Tx, conv *domain.SupportConversation) error {
+	query := `
+		INSERT INTO support_conversations (room_id, customer_id, agent_id, subject, assigned_at)
+		VALUES ($1, $2, $3, $4, CASE WHEN $3::uuid IS NULL THEN NULL ELSE NOW() END)
+		RETURNING ` + supportConversationColumns
+	rows, err := tx.Query(ctx, query, conv.RoomID, conv.CustomerID, conv.AgentID, conv.Subject)
+	if err != nil {
+		return fmt.Errorf("error creating support conversation %s: %w", conv.RoomID, err)
+	}
+	created, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.SupportConversation])
+	if err != nil {
+		return fmt.Errorf("error creating support conversation %s: %w", conv.RoomID, err)
+	}
+	*conv = created
+	return nil
+}
+
+func (r *postgresAppRepository) GetSupportConversation(ctx context.Context, roomID uuid.UUID) (*domain.SupportConversation, error) {
+	rows, err := r.db.Pool(ctx).Query(ctx, `SELECT `+supportConversationColumns+` FROM support_conversations WHERE room_id = $1`, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting support conversation %s: %w", roomID, err)
+	}
+	conv, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.SupportConversation])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting support conversation %s: %w", roomID, err)
+	}
+	return &conv, nil
+}
+
+func (r *postgresAppRepository) ListSupportQueue(ctx context.Context, limit int) ([]domain.SupportConversation, error) {
+	query := `SELECT ` + supportConversationColumns + ` FROM support_conversations WHERE agent_id IS NULL AND resolved_at IS NULL ORDER BY created_at LIMIT $1`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error listing support queue: %w", err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[domain.SupportConversation])
+}
+
+func (r *postgresAppRepository) ListAgentConversations(ctx context.Context, agentID uuid.UUID) ([]domain.SupportConversation, error) {
+	query := `SELECT ` + supportConversationColumns + ` FROM support_conversations WHERE agent_id = $1 ORDER BY updated_at DESC`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, agentID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing conversations for agent %s: %w", agentID, err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[domain.SupportConversation])
+}
+
+func (r *postgresAppRepository) AssignSupportConversation(ctx context.Context, roomID uuid.UUID, fromAgentID, toAgentID *uuid.UUID) (*domain.SupportConversation, error) {
+	query := `
+		UPDATE support_conversations
+		SET agent_id = $3, assigned_at = CASE WHEN $3::uuid IS NULL THEN NULL ELSE NOW() END, updated_at = NOW()
+		WHERE room_id = $1 AND agent_id IS NOT DISTINCT FROM $2
+		RETURNING ` + supportConversationColumns
+	rows, err := r.db.Pool(ctx).Query(ctx, query, roomID, fromAgentID, toAgentID)
+	if err != nil {
+		return nil, fmt.Errorf("error assigning support conversation %s: %w", roomID, err)
+	}
+	conv, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.SupportConversation])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error assigning support conversation %s: %w", roomID, err)
+	}
+	return &conv, nil
+}
+
+func (r *postgresAppRepository) EnsureRoomParticipant(ctx context.Context, userID, roomID uuid.UUID) (bool, error) {
+	query := `INSERT INTO room_participants (user_id, room_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+	tag, err := r.db.Pool(ctx).Exec(ctx, query, userID, roomID)
+	if err != nil {
+		return false, fmt.Errorf("error adding %s to room %s: %w", userID, roomID, err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *postgresAppRepository) GetRoomAgentIDs(ctx context.Context, roomID uuid.UUID) ([]uuid.UUID, error) {
+	query := `
+		SELECT rp.user_id
+		FROM room_participants rp
+		JOIN support_agents a ON a.user_id = rp.user_id
+		JOIN support_conversations c ON c.room_id = rp.room_id
+		WHERE rp.room_id = $1 AND rp.user_id <> c.customer_id
+	`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting agents in room %s: %w", roomID, err)
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+}
+
+func (r *postgresAppRepository) CreateSupportNote(ctx context.Context, note *domain.SupportNote) error {
+	query := `INSERT INTO support_notes (room_id, author_id, content) VALUES ($1, $2, $3) RETURNING id, created_at`
+	err := r.db.Pool(ctx).QueryRow(ctx, query, note.RoomID, note.AuthorID, note.Content).Scan(&note.ID, &note.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating support note in room %s: %w", note.RoomID, err)
+	}
+	return nil
+}
+
+func (r *postgresAppRepository) ListSupportNotes(ctx context.Context, roomID uuid.UUID, limit int) ([]domain.SupportNote, error) {
+	query := `
+		SELECT * FROM (
+			SELECT id, room_id, author_id, content, created_at
+			FROM support_notes WHERE room_id = $1
+			ORDER BY id DESC LIMIT $2
+		) page ORDER BY id
+	`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, roomID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error listing support notes for room %s: %w", roomID, err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[domain.SupportNote])
+}
+
+func (r *postgresAppRepository) TrackSupportMessage(ctx context.Context, roomID, senderID uuid.UUID, at time.Time) error {
+	query := `
+		UPDATE support_conversations SET first_response_at = $3, updated_at = NOW()
+		WHERE room_id = $1 AND customer_id <> $2 AND first_response_at IS NULL
+			AND EXISTS (SELECT 1 FROM support_agents WHERE user_id = $2)
+	`
+	if _, err := r.db.Pool(ctx).Exec(ctx, query, roomID, senderID, at); err != nil {
+		return fmt.Errorf("error tracking support message in room %s: %w", roomID, err)
+	}
+	return nil
+}
+
+func (r *postgresAppRepository) SetSupportResolved(ctx context.Context, roomID uuid.UUID, resolved bool) error {
+	query := `
+		UPDATE support_conversations
+		SET resolved_at = CASE WHEN $2 THEN COALESCE(resolved_at, NOW()) END, updated_at = NOW()
+		WHERE room_id = $1
+	`
+	if _, err := r.db.Pool(ctx).Exec(ctx, query, roomID, resolved); err != nil {
+		return fmt.Errorf("error updating resolution of support conversation %s: %w", roomID, err)
+	}
+	return nil
+}
+
+func (r *postgresAppRepository) SetRoomState(ctx context.Context, roomID, actorID uuid.UUID, state string) (*domain.RoomStateChange, error) {
+	query := `
+		UPDATE rooms r SET state = $3, state_changed_at = NOW(), state_changed_by = $2, updated_at = NOW()
+		FROM (SELECT id, state FROM rooms WHERE id = $1 FOR UPDATE) previous
+		WHERE r.id = previous.id AND previous.state <> $3
+		RETURNING previous.state, r.state_changed_at
+	`
+	change := domain.RoomStateChange{RoomID: roomID, State: state, ChangedBy: actorID}
+	err := r.db.Pool(ctx).QueryRow(ctx, query, roomID, actorID, state).Scan(&change.Previous, &change.ChangedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error changing state of room %s: %w", roomID, err)
+	}
+	return &change, nil
+}
+
+func (r *postgresAppRepository) MarkSupportSLABreaches(ctx context.Context, kind string, threshold time.Duration) ([]domain.SupportSLABreach, error) {
+	var query string
+	switch kind {
+	case domain.SLAKindFirstResponse:
+		query = `
+			UPDATE support_conversations SET first_response_breached_at = NOW()
+			WHERE first_response_breached_at IS NULL
+				AND created_at <= NOW() - make_interval(secs => $1)
+				AND (first_response_at IS NULL OR first_response_at > created_at + make_interval(secs => $1))
+			RETURNING room_id, agent_id, 'first_response' AS kind, created_at, first_response_breached_at AS breached_at
+		`
+	case domain.SLAKindResolution:
+		query = `
+			UPDATE support_conversations SET resolution_breached_at = NOW()
+			WHERE resolution_breached_at IS NULL
+				AND created_at <= NOW() - make_interval(secs => $1)
+				AND (resolved_at IS NULL OR resolved_at > created_at + make_interval(secs => $1))
+			RETURNING room_id, agent_id, 'resolution' AS kind, created_at, resolution_breached_at AS breached_at
+		`
+	default:
+		return nil, fmt.Errorf("unknown SLA kind %q", kind)
+	}
+	rows, err := r.db.Pool(ctx).Query(ctx, query, threshold.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("error marking %s SLA breaches: %w", kind, err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[domain.SupportSLABreach])
+}
+
+func (r *postgresAppRepository) GetSupportSLASummary(ctx context.Context, since time.Time, firstResponse, resolution time.Duration) (*domain.SupportSLASummary, error) {
+	query := `
+		SELECT
+			COUNT(*)::int AS conversations,
+			COUNT(*) FILTER (WHERE agent_id IS NULL AND resolved_at IS NULL)::int AS queued,
+			COUNT(*) FILTER (WHERE resolved_at IS NULL)::int AS open,
+			COUNT(*) FILTER (WHERE resolved_at IS NOT NULL)::int AS resolved,
+			AVG(EXTRACT(EPOCH FROM first_response_at - created_at))::float8 AS avg_first_response_seconds,
+			AVG(EXTRACT(EPOCH FROM resolved_at - created_at))::float8 AS avg_resolution_seconds,
+			COUNT(*) FILTER (WHERE $2 > 0 AND (first_response_breached_at IS NOT NULL OR first_response_at > created_at + make_interval(secs => $2)))::int AS first_response_breaches,
+			COUNT(*) FILTER (WHERE $3 > 0 AND (resolution_breached_at IS NOT NULL OR resolved_at > created_at + make_interval(secs => $3)))::int AS resolution_breaches
+		FROM support_conversations
+		WHERE created_at >= $1
+	`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, since, firstResponse.Seconds(), resolution.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("error summarizing support SLAs: %w", err)
+	}
+	summary, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.SupportSLASummary])
+	if err != nil {
+		return nil, fmt.Errorf("error summarizing support SLAs: %w", err)
+	}
+	summary.Since = since
+	return &summary, nil
+}
+
+func (r *postgresAppRepository) GetRoomCounts(ctx context.Context, roomID uuid.UUID) (int, int, error) {
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM room_participants WHERE room_id = $1)::int,
+			(SELECT COUNT(*) FROM messages WHERE room_id = $1 AND deleted_at IS NULL)::int
+	`
+	var members, messages int
+	if err := r.db.Pool(ctx).QueryRow(ctx, query, roomID).Scan(&members, &messages); err != nil {
+		return 0, 0, fmt.Errorf("error counting room %s: %w", roomID, err)
+	}
+	return members, messages, nil
+}
+
+const cannedResponseColumns = `id, scope, owner_id, room_id, shortcut, title, body, created_by, created_at, updated_at`
+
+func (r *postgresAppRepository) CreateCannedResponse(ctx context.Context, resp *domain.CannedResponse) error {
+	query := `
+		INSERT INTO canned_responses (id, scope, owner_id, room_id, shortcut, title, body, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING created_at, updated_at
+	`
+	err := r.db.Pool(ctx).QueryRow(ctx, query, resp.ID, resp.Scope, resp.OwnerID, resp.RoomID, resp.Shortcut, resp.Title, resp.Body, resp.CreatedBy).Scan(&resp.CreatedAt, &resp.UpdatedAt)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrCannedShortcutTaken
+	}
+	if err != nil {
+		return fmt.Errorf("error creating canned response: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresAppRepository) UpdateCannedResponse(ctx context.Context, resp *domain.CannedResponse) (bool, error) {
+	query := `
+		UPDATE canned_responses SET shortcut = $2, title = $3, body = $4, updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at
+	`
+	err := r.db.Pool(ctx).QueryRow(ctx, query, resp.ID, resp.Shortcut, resp.Title, resp.Body).Scan(&resp.UpdatedAt)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return false, ErrCannedShortcutTaken
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error updating canned response %s: %w", resp.ID, err)
+	}
+	return true, nil
+}
+
+func (r *postgresAppRepository) DeleteCannedResponse(ctx context.Context, id uuid.UUID) (bool, error) {
+	tag, err := r.db.Pool(ctx).Exec(ctx, `DELETE FROM canned_responses WHERE id = $1`, id)
+	if err != nil {
+		return false, fmt.Errorf("error deleting canned response %s: %w", id, err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *postgresAppRepository) GetCannedResponse(ctx context.Context, id uuid.UUID) (*domain.CannedResponse, error) {
+	rows, err := r.db.Pool(ctx).Query(ctx, `SELECT `+cannedResponseColumns+` FROM canned_responses WHERE id = $1`, id)
+	if err != nil {
+		return nil, fmt.Errorf("error getting canned response %s: %w", id, err)
+	}
+	resp, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.CannedResponse])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting canned response %s: %w", id, err)
+	}
+	return &resp, nil
+}
+
+func (r *postgresAppRepository) FindCannedResponses(ctx context.Context, userID uuid.UUID, roomID *uuid.UUID, includeSupport bool, prefix string, limit int) ([]domain.CannedResponse, error) {
+	query := `
+		SELECT ` + cannedResponseColumns + `
+		FROM canned_responses
+		WHERE ((scope = 'user' AND owner_id = $1) OR (scope = 'room' AND room_id = $2) OR (scope = 'support' AND $3))
+			AND starts_with(shortcut, $4)
+		ORDER BY shortcut, CASE scope WHEN 'room' THEN 0 WHEN 'support' THEN 1 ELSE 2 END
+		LIMIT $5
+	`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, userID, roomID, includeSupport, prefix, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error finding canned responses for %s: %w", userID, err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[domain.CannedResponse])
+}
+
+const labelColumns = `l.id, l.user_id, l.name, l.color, l.created_at, l.updated_at,
+	(SELECT COUNT(*) FROM message_labels ml WHERE ml.label_id = l.id) AS message_count,
+	(SELECT COUNT(*) FROM room_labels rl WHERE rl.label_id = l.id) AS room_count`
+
+func (r *postgresAppRepository) CreateLabel(ctx context.Context, label *domain.Label) error {
+	query := `
+		INSERT INTO labels (id, user_id, name, color) VALUES ($1, $2, $3, $4)
+		RETURNING created_at, updated_at
+	`
+	err := r.db.Pool(ctx).QueryRow(ctx, query, label.ID, label.UserID, label.Name, label.Color).Scan(&label.CreatedAt, &label.UpdatedAt)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrLabelNameTaken
+	}
+	if err != nil {
+		return fmt.Errorf("error creating label: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresAppRepository) UpdateLabel(ctx context.Context, label *domain.Label) (bool, error) {
+	query := `
+		UPDATE labels SET name = $3, color = $4, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+		RETURNING updated_at
+	`
+	err := r.db.Pool(ctx).QueryRow(ctx, query, label.ID, label.UserID, label.Name, label.Color).Scan(&label.UpdatedAt)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return false, ErrLabelNameTaken
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error updating label %s: %w", label.ID, err)
+	}
+	return true, nil
+}
+
+func (r *postgresAppRepository) DeleteLabel(ctx context.Context, userID, labelID uuid.UUID) (bool, error) {
+	tag, err := r.db.Pool(ctx).Exec(ctx, `DELETE FROM labels WHERE id = $1 AND user_id = $2`, labelID, userID)
+	if err != nil {
+		return false, fmt.Errorf("error deleting label %s: %w", labelID, err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *postgresAppRepository) GetLabel(ctx context.Context, userID, labelID uuid.UUID) (*domain.Label, error) {
+	rows, err := r.db.Pool(ctx).Query(ctx, `SELECT `+labelColumns+` FROM labels l WHERE l.id = $1 AND l.user_id = $2`, labelID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting label %s: %w", labelID, err)
+	}
+	label, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.Label])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting label %s: %w", labelID, err)
+	}
+	return &label, nil
+}
+
+func (r *postgresAppRepository) ListLabels(ctx context.Context, userID uuid.UUID) ([]domain.Label, error) {
+	rows, err := r.db.Pool(ctx).Query(ctx, `SELECT `+labelColumns+` FROM labels l WHERE l.user_id = $1 ORDER BY l.name`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing labels for %s: %w", userID, err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[domain.Label])
+}
+
+func (r *postgresAppRepository) CountLabels(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	if err := r.db.Pool(ctx).QueryRow(ctx, `SELECT COUNT(*) FROM labels WHERE user_id = $1`, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("error counting labels for %s: %w", userID, err)
+	}
+	return count, nil
+}
+
+func (r *postgresAppRepository) AddMessageLabel(ctx context.Context, labelID uuid.UUID, messageID int64) error {
+	_, err := r.db.Pool(ctx).Exec(ctx, `INSERT INTO message_labels (label_id, message_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, labelID, messageID)
+	if err != nil {
+		return fmt.Errorf("error labelling message %d: %w", messageID, err)
+	}
+	return nil
+}
+
+func (r *postgresAppRepository) RemoveMessageLabel(ctx context.Context, labelID uuid.UUID, messageID int64) (bool, error) {
+	tag, err := r.db.Pool(ctx).Exec(ctx, `DELETE FROM message_labels WHERE label_id = $1 AND message_id = $2`, labelID, messageID)
+	if err != nil {
+		return false, fmt.Errorf("error unlabelling message %d: %w", messageID, err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *postgresAppRepository) AddRoomLabel(ctx context.Context, labelID, roomID uuid.UUID) error {
+	_, err := r.db.Pool(ctx).Exec(ctx, `INSERT INTO room_labels (label_id, room_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, labelID, roomID)
+	if err != nil {
+		return fmt.Errorf("error labelling room %s: %w", roomID, err)
+	}
+	return nil
+}
+
+func (r *postgresAppRepository) RemoveRoomLabel(ctx context.Context, labelID, roomID uuid.UUID) (bool, error) {
+	tag, err := r.db.Pool(ctx).Exec(ctx, `DELETE FROM room_labels WHERE label_id = $1 AND room_id = $2`, labelID, roomID)
+	if err != nil {
+		return false, fmt.Errorf("error unlabelling room %s: %w", roomID, err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *postgresAppRepository) GetLabeledMessages(ctx context.Context, userID, labelID uuid.UUID, includeSupport bool, limit int) ([]domain.Message, error) {
+	query := `
+		SELECT m.id, m.message_uid, m.room_id, m.user_id, m.content, m.kind, m.content_type, m.rich_content, m.links, m.hashtags, m.group_mentions, m.metadata, m.attachment_id, m.reply_to_message_id, m.created_at, m.updated_at, m.deleted_at
+		FROM message_labels ml
+		JOIN messages m ON m.id = ml.message_id
+		WHERE ml.label_id = $1 AND m.deleted_at IS NULL
+			AND (EXISTS (SELECT 1 FROM room_participants rp WHERE rp.room_id = m.room_id AND rp.user_id = $2 AND rp.is_blocked = false)
+				OR ($3 AND EXISTS (SELECT 1 FROM support_conversations sc WHERE sc.room_id = m.room_id)))
+		ORDER BY ml.created_at DESC
+		LIMIT $4
+	`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, labelID, userID, includeSupport, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error getting messages for label %s: %w", labelID, err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[domain.Message])
+}
+
+func (r *postgresAppRepository) GetLabeledRoomIDs(ctx context.Context, labelID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := r.db.Pool(ctx).Query(ctx, `SELECT room_id FROM room_labels WHERE label_id = $1 ORDER BY created_at DESC`, labelID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting rooms for label %s: %w", labelID, err)
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (r *postgresAppRepository) QuickSearch(ctx context.Context, userID uuid.UUID, query string, perType int) ([]domain.QuickSearchResult, error) {
+	sql := `
+		WITH my_rooms AS (
+			SELECT r.id, r.type, COALESCE(r.name, other.nickname, '') AS title, COALESCE(r.last_message_at, r.created_at) AS at
+			FROM room_participants rp
+			JOIN rooms r ON r.id = rp.room_id
+			LEFT JOIN LATERAL (
+				SELECT u.nickname FROM room_participants op JOIN users u ON u.id = op.user_id
+				WHERE op.room_id = r.id AND op.user_id <> $1
+				LIMIT 1
+			) other ON r.type = 'private'
+			WHERE rp.user_id = $1 AND rp.is_blocked = false
+		)
+		(
+			SELECT 'room' AS kind, id AS room_id, NULL::uuid AS user_id, NULL::bigint AS message_id, title, '' AS snippet, at,
+				(CASE WHEN LOWER(title) = $2 THEN 3 WHEN starts_with(LOWER(title), $2) THEN 2 ELSE 1 END)::float8 AS score
+			FROM my_rooms
+			WHERE title ILIKE $3
+			ORDER BY score DESC, at DESC
+			LIMIT $4
+		)
+		UNION ALL
+		(
+			SELECT 'friend', pr.room_id, u.id, NULL::bigint, COALESCE(u.nickname, ''), COALESCE(u.username, ''), pr.at,
+				(CASE WHEN LOWER(u.nickname) = $2 OR LOWER(u.username) = $2 THEN 3
+					WHEN starts_with(LOWER(u.nickname), $2) OR starts_with(LOWER(u.username), $2) THEN 2 ELSE 1 END)::float8 AS score
+			FROM friendships f
+			JOIN users u ON u.id = CASE WHEN f.user_one_id = $1 THEN f.user_two_id ELSE f.user_one_id END
+			LEFT JOIN LATERAL (
+				SELECT mr.id AS room_id, mr.at FROM my_rooms mr
+				JOIN room_participants p2 ON p2.room_id = mr.id AND p2.user_id = u.id
+				WHERE mr.type = 'private'
+				LIMIT 1
+			) pr ON TRUE
+			WHERE (f.user_one_id = $1 OR f.user_two_id = $1) AND f.status = 'accepted'
+				AND (u.nickname ILIKE $3 OR u.username ILIKE $3)
+			ORDER BY score DESC, pr.at DESC NULLS LAST
+			LIMIT $4
+		)
+		UNION ALL
+		(
+			SELECT 'message', m.room_id, m.user_id, m.id, mr.title, LEFT(m.content, 140), m.created_at, 0.5::float8
+			FROM messages m
+			JOIN my_rooms mr ON mr.id = m.room_id
+			WHERE m.deleted_at IS NULL AND m.search_vector @@ websearch_to_tsquery('simple', $2)
+			ORDER BY m.created_at DESC
+			LIMIT $4
+		)
+		ORDER BY score DESC, at DESC NULLS LAST
+	`
+	pattern := "%" + likeEscaper.Replace(query) + "%"
+	rows, err := r.db.Pool(ctx).Query(ctx, sql, userID, query, pattern, perType)
+	if err != nil {
+		return nil, fmt.Errorf("error running quick search for %s: %w", userID, err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[domain.QuickSearchResult])
+}
+
+func (r *postgresAppRepository) ClaimAwayReply(ctx context.Context, userID, senderID uuid.UUID, cooldown time.Duration) (bool, error) {
+	query := `
+		INSERT INTO away_replies (user_id, sender_id) VALUES ($1, $2)
+		ON CONFLICT (user_id, sender_id) DO UPDATE SET sent_at = NOW()
+		WHERE away_replies.sent_at <= NOW() - make_interval(secs => $3)
+	`
+	cmdTag, err := r.db.Pool(ctx).Exec(ctx, query, userID, senderID, cooldown.Seconds())
+	if err != nil {
+		return false, fmt.Errorf("error claiming away reply for %s: %w", userID, err)
+	}
+	return cmdTag.RowsAffected() > 0, nil
+}
+
+func (r *postgresAppRepository) GetDailyActivity(ctx context.Context, userID uuid.UUID, since time.Time) ([]domain.DailyActivity, error) {
+	query := `
+		SELECT TO_CHAR(day, 'YYYY-MM-DD') AS day, SUM(messages_sent)::int AS messages_sent,
+			SUM(responses)::int AS responses, SUM(response_seconds)::bigint AS response_seconds
+		FROM user_activity_daily
+		WHERE user_id = $1 AND day >= $2::date
+		GROUP BY day
+		ORDER BY day
+	`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, userID, since.UTC().Format(time.DateOnly))
+	if err != nil {
+		return nil, fmt.Errorf("error getting daily activity for user %s: %w", userID, err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[domain.DailyActivity])
+}
+
+func (r *postgresAppRepository) GetRoomActivity(ctx context.Context, userID uuid.UUID, since time.Time, limit int) ([]domain.RoomActivity, error) {
+	query := `
+		SELECT a.room_id, r.name, SUM(a.messages_sent)::int AS messages_sent,
+			SUM(a.responses)::int AS responses, SUM(a.response_seconds)::bigint AS response_seconds
+		FROM user_activity_daily a
+		JOIN rooms r ON r.id = a.room_id
+		WHERE a.user_id = $1 AND a.day >= $2::date
+		GROUP BY a.room_id, r.name
+		ORDER BY messages_sent DESC, a.room_id
+		LIMIT $3
+	`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, userID, since.UTC().Format(time.DateOnly), limit)
+	if err != nil {
+		return nil, fmt.Errorf("error getting room activity for user %s: %w", userID, err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[domain.RoomActivity])
+}
+
+func (r *postgresAppRepository) GetMessageByID(ctx context.Context, messageID int64) (*domain.Message, error) {
+	query := `SELECT id, message_uid, room_id, user_id, content, kind, content_type, rich_content, links, hashtags, group_mentions, metadata, attachment_id, reply_to_message_id, created_at, updated_at, deleted_at FROM messages WHERE id = $1 AND deleted_at IS NULL`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting message %d: %w", messageID, err)
+	}
+	msg, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[domain.Message])
+	if err != nil {
+		return nil, fmt.Errorf("error getting message %d: %w", messageID, err)
+	}
+	return &msg, nil
+}
+
+func (r *postgresAppRepository) MessageExists(ctx context.Context, messageID int64) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS(SELECT 1 FROM messages WHERE id = $1 AND deleted_at IS NULL)`
+	if err := r.db.Pool(ctx).QueryRow(ctx, query, messageID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("error checking message %d: %w", messageID, err)
+	}
+	return exists, nil
+}
+
+func (r *postgresAppRepository) CreateMessage(ctx context.Context, msg *domain.Message) (*domain.Message, error) {
+	query := `
+		WITH inserted AS (
+			INSERT INTO messages (message_uid, room_id, user_id, content, kind, reply_to_message_id, metadata, attachment_id, content_type, rich_content, links, hashtags, group_mentions)
+			VALUES (COALESCE($1, uuid_generate_v4()), $2, $3, $4, COALESCE(NULLIF($5, ''), 'text'), $6, $7::jsonb, $8, COALESCE(NULLIF($9, ''), 'plain'), $10::jsonb, COALESCE($11, '{}'), COALESCE($12, '{}'), COALESCE($13, '{}'))
+			RETURNING id, message_uid, room_id, kind, content_type, created_at
+		), touched AS (
+			UPDATE rooms SET last_message_at = inserted.created_at
+			FROM inserted
+			WHERE rooms.id = inserted.room_id AND (rooms.last_message_at IS NULL OR rooms.last_message_at < inserted.created_at)
+		)
+		SELECT id, message_uid, kind, content_type, created_at FROM inserted
+	`
+	var metadata, rich *string
+	if len(msg.Metadata) > 0 {
+		raw := string(msg.Metadata)
+		metadata = &raw
+	}
+	if len(msg.RichContent) > 0 {
+		raw := string(msg.RichContent)
+		rich = &raw
+	}
+	err := r.db.Pool(ctx).QueryRow(ctx, query, msg.MessageUID, msg.RoomID, msg.UserID, msg.Content, msg.Kind, msg.ReplyToMessageID, metadata, msg.AttachmentID, msg.ContentType, rich, msg.Links, msg.Hashtags, msg.GroupMentions).Scan(&msg.ID, &msg.MessageUID, &msg.Kind, &msg.ContentType, &msg.CreatedAt)
+	return msg, err
+}
+
+func (r *postgresAppRepository) MarkMessageAsRead(ctx context.Context, messageID int64, userID uuid.UUID) (*time.Time, error) {
+	var readAt time.Time
+	query := `INSERT INTO message_read_status (message_id, user_id, read_at) VALUES ($1, $2, NOW()) ON CONFLICT (message_id, user_id) DO UPDATE SET read_at = NOW() RETURNING read_at`
+	err := r.db.Pool(ctx).QueryRow(ctx, query, messageID, userID).Scan(&readAt)
+	return &readAt, err
+}
+
+func (r *postgresAppRepository) GetBadgeCounts(ctx context.Context, userID uuid.UUID) (*domain.BadgeCounts, error) {
+	query := `
+		SELECT
+			COUNT(*) FILTER (WHERE m.kind = 'text'),
+			COUNT(*) FILTER (WHERE EXISTS (SELECT 1 FROM message_mentions mm WHERE mm.message_id = m.id AND mm.user_id = $1) OR 'everyone' = ANY(m.group_mentions) OR ('admins' = ANY(m.group_mentions) AND rp.role IN ('owner', 'admin'))),
+			COUNT(*) FILTER (WHERE m.kind = 'missed_call')
+		FROM messages m
+		JOIN room_participants rp ON rp.room_id = m.room_id AND rp.user_id = $1 AND rp.is_blocked = FALSE
+		WHERE m.user_id <> $1
+			AND m.deleted_at IS NULL
+			AND NOT EXISTS (SELECT 1 FROM message_read_status rs WHERE rs.message_id = m.id AND rs.user_id = $1)`
+	counts := &domain.BadgeCounts{}
+	err := r.db.Pool(ctx).QueryRow(ctx, query, userID).Scan(&counts.UnreadMessages, &counts.UnreadMentions, &counts.MissedCalls)
+	if err != nil {
+		return nil, fmt.Errorf("error counting badge items for user %s: %w", userID, err)
+	}
+	return counts, nil
+}
+
+func (r *postgresAppRepository) CreateRestrictedAttachment(ctx context.Context, att *domain.Attachment, allowedUserIDs []uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting attachment transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `INSERT INTO room_attachments (id, room_id, uploader_id, kind, storage_url, content_type, size_bytes) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING created_at`
+	err = tx.QueryRow(ctx, query, att.ID, att.RoomID, att.UploaderID, att.Kind, att.StorageURL, att.ContentType, att.SizeBytes).Scan(&att.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating attachment: %w", err)
+	}
+	for _, userID := range allowedUserIDs {
+		if _, err := tx.Exec(ctx, `INSERT INTO attachment_access (attachment_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, att.ID, userID); err != nil {
+			return fmt.Errorf("error granting attachment access: %w", err)
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *postgresAppRepository) GetAttachmentsForUser(ctx context.Context, roomID, userID uuid.UUID, kind string) ([]domain.Attachment, error) {
+	query := `
+		SELECT a.id, a.room_id, a.uploader_id, a.kind, a.storage_url, a.content_type, a.size_bytes, a.created_at
+		FROM room_attachments a
+		JOIN attachment_access aa ON aa.attachment_id = a.id AND aa.user_id = $2
+		WHERE a.room_id = $1 AND a.kind = $3
+		ORDER BY a.created_at DESC`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, roomID, userID, kind)
+	if err != nil {
+		return nil, fmt.Errorf("error getting attachments: %w", err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[domain.Attachment])
+}
+
+func (r *postgresAppRepository) GetAttachmentForUser(ctx context.Context, attachmentID, userID uuid.UUID) (*domain.Attachment, error) {
+	query := `
+		SELECT a.id, a.room_id, a.uploader_id, a.kind, a.storage_url, a.content_type, a.size_bytes, a.created_at
+		FROM room_attachments a
+		JOIN attachment_access aa ON aa.attachment_id = a.id AND aa.user_id = $2
+		WHERE a.id = $1`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, attachmentID, userID)
+	if err != nil {
+		return nil, err
+	}
+	att, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.Attachment])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("attachment not found")
+	}
+	return &att, err
+}
+
+const uploadColumns = `id, room_id, uploader_id, filename, content_type, size_bytes, offset_bytes, checksum_sha256, storage_key, expires_at, created_at, completed_at`
+
+func (r *postgresAppRepository) CreateUpload(ctx context.Context, upload *domain.Upload) error {
+	query := `
+		INSERT INTO uploads (id, room_id, uploader_id, filename, content_type, size_bytes, checksum_sha256, storage_key, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING created_at
+	`
+	err := r.db.Pool(ctx).QueryRow(ctx, query, upload.ID, upload.RoomID, upload.UploaderID, upload.Filename, upload.ContentType, upload.SizeBytes, upload.ChecksumSHA256, upload.StorageKey, upload.ExpiresAt).Scan(&upload.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating upload: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresAppRepository) GetUpload(ctx context.Context, uploadID uuid.UUID) (*domain.Upload, error) {
+	return collectUpload(r.db.Pool(ctx).Query(ctx, `SELECT `+uploadColumns+` FROM uploads WHERE id = $1`, uploadID))
+}
+
+func (r *postgresAppRepository) LockUpload(ctx context.Context, tx pgx.Tx, uploadID uuid.UUID) (*domain.Upload, error) {
+	return collectUpload(tx.Query(ctx, `SELECT `+uploadColumns+` FROM uploads WHERE id = $1 FOR UPDATE`, uploadID))
+}
+
+func collectUpload(rows pgx.Rows, err error) (*domain.Upload, error) {
+	if err != nil {
+		return nil, fmt.Errorf("error getting upload: %w", err)
+	}
+	upload, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.Upload])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting upload: %w", err)
+	}
+	return &upload, nil
+}
+
+func (r *postgresAppRepository) SetUploadOffset(ctx context.Context, tx pgx.Tx, uploadID uuid.UUID, offset int64) error {
+	_, err := tx.Exec(ctx, `UPDATE uploads SET offset_bytes = $2 WHERE id = $1`, uploadID, offset)
+	if err != nil {
+		return fmt.Errorf("error updating upload offset: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresAppRepository) CompleteUpload(ctx context.Context, uploadID uuid.UUID) (bool, error) {
+	query := `UPDATE uploads SET completed_at = NOW() WHERE id = $1 AND completed_at IS NULL AND offset_bytes = size_bytes`
+	tag, err := r.db.Pool(ctx).Exec(ctx, query, uploadID)
+	if err != nil {
+		return false, fmt.Errorf("error completing upload: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// ReopenUpload undoes a CompleteUpload whose attachment message could not be
+// posted, removing the attachment row if it was created so a retry can
+// finalize the upload again.
+func (r *postgresAppRepository) ReopenUpload(ctx context.Context, uploadID uuid.UUID) error {
+	query := `
+		WITH removed AS (
+			DELETE FROM room_attachments a
+			WHERE a.id = $1 AND NOT EXISTS (SELECT 1 FROM messages m WHERE m.attachment_id = a.id)
+		)
+		UPDATE uploads SET completed_at = NULL WHERE id = $1
+	`
+	if _, err := r.db.Pool(ctx).Exec(ctx, query, uploadID); err != nil {
+		return fmt.Errorf("error reopening upload %s: %w", uploadID, err)
+	}
+	return nil
+}
+
+func (r *postgresAppRepository) DeleteUpload(ctx context.Context, uploadID uuid.UUID) error {
+	_, err := r.db.Pool(ctx).Exec(ctx, `DELETE FROM uploads WHERE id = $1`, uploadID)
+	return err
+}
+
+func (r *postgresAppRepository) GetDraft(ctx context.Context, userID, roomID uuid.UUID) (*domain.Draft, error) {
+	query := `SELECT user_id, room_id, content, attachment_ids, updated_at FROM message_drafts WHERE user_id = $1 AND room_id = $2`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, userID, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting draft: %w", err)
+	}
+	draft, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.Draft])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting draft: %w", err)
+	}
+	return &draft, nil
+}
+
+func (r *postgresAppRepository) GetDraftsForUser(ctx context.Context, userID uuid.UUID) ([]domain.Draft, error) {
+	query := `
+		SELECT d.user_id, d.room_id, d.content, d.attachment_ids, d.updated_at
+		FROM message_drafts d
+		JOIN room_participants rp ON rp.room_id = d.room_id AND rp.user_id = d.user_id AND rp.is_blocked = FALSE
+		WHERE d.user_id = $1
+		ORDER BY d.updated_at DESC
+	`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting drafts: %w", err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[domain.Draft])
+}
+
+func (r *postgresAppRepository) SaveDraft(ctx context.Context, draft *domain.Draft) (*domain.Draft, error) {
+	query := `
+		INSERT INTO message_drafts (user_id, room_id, content, attachment_ids, updated_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (user_id, room_id) DO UPDATE SET content = $3, attachment_ids = $4, updated_at = NOW()
+		RETURNING updated_at
+	`
+	if draft.AttachmentIDs == nil {
+		draft.AttachmentIDs = []uuid.UUID{}
+	}
+	err := r.db.Pool(ctx).QueryRow(ctx, query, draft.UserID, draft.RoomID, draft.Content, draft.AttachmentIDs).Scan(&draft.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("error saving draft: %w", err)
+	}
+	return draft, nil
+}
+
+func (r *postgresAppRepository) DeleteDraft(ctx context.Context, userID, roomID uuid.UUID) error {
+	_, err := r.db.Pool(ctx).Exec(ctx, `DELETE FROM message_drafts WHERE user_id = $1 AND room_id = $2`, userID, roomID)
+	return err
+}
+
+const (
+	FriendSortNickname = "nickname"
+	FriendSortRecent   = "recent"
+)
+
+func (r *postgresAppRepository) GetFriends(ctx context.Context, userID uuid.UUID, sortBy string) ([]domain.Friend, error) {
+	orderBy := "LOWER(COALESCE(u.nickname, '')), u.id"
+	if sortBy == FriendSortRecent {
+		orderBy = "pr.last_interaction_at DESC NULLS LAST, LOWER(COALESCE(u.nickname, '')), u.id"
+	}
+	query := `
+		SELECT u.id, COALESCE(u.nickname, ''), u.badges, COALESCE(pr.room_id, '00000000-0000-0000-0000-000000000000'::uuid), pr.last_interaction_at
+		FROM friendships f
+		JOIN users u ON u.id = CASE WHEN f.user_one_id = $1 THEN f.user_two_id ELSE f.user_one_id END
+		LEFT JOIN LATERAL (
+			SELECT p1.room_id, rm.last_message_at AS last_interaction_at
+			FROM room_participants p1
+			JOIN room_participants p2 ON p2.room_id = p1.room_id AND p2.user_id = u.id
+			JOIN rooms rm ON rm.id = p1.room_id AND rm.type = 'private'
+			WHERE p1.user_id = $1
+			LIMIT 1
+		) pr ON TRUE
+		WHERE (f.user_one_id = $1 OR f.user_two_id = $1) AND f.status = 'accepted'
+		ORDER BY ` + orderBy
+	rows, err := r.db.Pool(ctx).Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting friends: %w", err)
+	}
+	defer rows.Close()
+
+	var friends []domain.Friend
+	for rows.Next() {
+		var friend domain.Friend
+		if err := rows.Scan(&friend.ID, &friend.Nickname, &friend.Badges, &friend.RoomID, &friend.LastInteractionAt); err != nil {
+			return nil, fmt.Errorf("error scanning friend row: %w", err)
+		}
+		friends = append(friends, friend)
+	}
+	return friends, rows.Err()
+}
+
+func (r *postgresAppRepository) GetRoomsChangeToken(ctx context.Context, userID uuid.UUID) (string, error) {
+	query := `
+		SELECT md5(COALESCE(string_agg(
+			r.id::text || ':' || rp.is_blocked::text || ':' || COALESCE(r.last_message_at, r.created_at)::text || ':' || r.updated_at::text || ':' || COALESCE(rp.snoozed_until::text, ''),
+			',' ORDER BY r.id
+		), '') || COALESCE((SELECT MAX(read_at)::text FROM message_read_status WHERE user_id = $1), ''))
+		FROM room_participants rp
+		JOIN rooms r ON r.id = rp.room_id
+		WHERE rp.user_id = $1
+	`
+	var token string
+	if err := r.db.Pool(ctx).QueryRow(ctx, query, userID).Scan(&token); err != nil {
+		return "", fmt.Errorf("error computing rooms change token: %w", err)
+	}
+	return token, nil
+}
+
+func (r *postgresAppRepository) RecordSentPush(ctx context.Context, push *domain.SentPush) error {
+	query := `INSERT INTO sent_pushes (notification_id, user_id, room_id, message_id, preview) VALUES ($1, $2, $3, $4, $5)`
+	_, err := r.db.Pool(ctx).Exec(ctx, query, push.NotificationID, push.UserID, push.RoomID, push.MessageID, push.Preview)
+	if err != nil {
+		return fmt.Errorf("error recording push for message %d: %w", push.MessageID, err)
+	}
+	return nil
+}
+
+func (r *postgresAppRepository) GetUnreadSentPushes(ctx context.Context, messageID int64) ([]domain.SentPush, error) {
+	query := `
+		SELECT sp.notification_id, sp.user_id, sp.room_id, sp.message_id, sp.preview, sp.sent_at
+		FROM sent_pushes sp
+		WHERE sp.message_id = $1
+			AND NOT EXISTS (SELECT 1 FROM message_read_status rs WHERE rs.message_id = sp.message_id AND rs.user_id = sp.user_id)`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("error loading pushes for message %d: %w", messageID, err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[domain.SentPush])
+}
+
+func (r *postgresAppRepository) DeleteSentPushes(ctx context.Context, messageID int64) ([]domain.SentPush, error) {
+	query := `
+		DELETE FROM sent_pushes sp
+		WHERE sp.message_id = $1
+		RETURNING sp.notification_id, sp.user_id, sp.room_id, sp.message_id, sp.preview, sp.sent_at,
+			EXISTS (SELECT 1 FROM message_read_status rs WHERE rs.message_id = sp.message_id AND rs.user_id = sp.user_id)`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("error deleting pushes for message %d: %w", messageID, err)
+	}
+	defer rows.Close()
+
+	var unread []domain.SentPush
+	for rows.Next() {
+		var push domain.SentPush
+		var read bool
+		if err := rows.Scan(&push.NotificationID, &push.UserID, &push.RoomID, &push.MessageID, &push.Preview, &push.SentAt, &read); err != nil {
+			return nil, err
+		}
+		if !read {
+			unread = append(unread, push)
+		}
+	}
+	return unread, rows.Err()
+}
+
+func (r *postgresAppRepository) UpdateSentPushPreview(ctx context.Context, notificationID uuid.UUID, preview string) error {
+	_, err := r.db.Pool(ctx).Exec(ctx, `UPDATE sent_pushes SET preview = $2 WHERE notification_id = $1`, notificationID, preview)
+	if err != nil {
+		return fmt.Errorf("error updating push %s: %w", notificationID, err)
+	}
+	return nil
+}
+
+func (r *postgresAppRepository) RecordMentions(ctx context.Context, messageID int64, roomID, senderID uuid.UUID, usernames []string) ([]uuid.UUID, error) {
+	query := `
+		INSERT INTO message_mentions (message_id, user_id)
+		SELECT $1, u.id
+		FROM users u
+		JOIN room_participants rp ON rp.user_id = u.id AND rp.room_id = $2 AND rp.is_blocked = FALSE
+		WHERE LOWER(u.username) = ANY($4) AND u.id <> $3
+		ON CONFLICT DO NOTHING
+		RETURNING user_id`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, messageID, roomID, senderID, usernames)
+	if err != nil {
+		return nil, fmt.Errorf("error recording mentions for message %d: %w", messageID, err)
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+}
+
+func (r *postgresAppRepository) MarkRoomReadUpTo(ctx context.Context, userID, roomID uuid.UUID, upToMessageID int64) (int64, error) {
+	query := `
+		INSERT INTO message_read_status (message_id, user_id, read_at)
+		SELECT m.id, $1, NOW()
+		FROM messages m
+		WHERE m.room_id = $2 AND m.id <= $3 AND m.user_id <> $1 AND m.deleted_at IS NULL
+		ON CONFLICT (message_id, user_id) DO NOTHING`
+	tag, err := r.db.Pool(ctx).Exec(ctx, query, userID, roomID, upToMessageID)
+	if err != nil {
+		return 0, fmt.Errorf("error marking room %s read for user %s: %w", roomID, userID, err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (r *postgresAppRepository) GetMemberLanguages(ctx context.Context, roomID uuid.UUID) (map[uuid.UUID]string, error) {
+	query := `
+		SELECT rp.user_id, us.language
+		FROM room_participants rp
+		JOIN user_settings us ON us.user_id = rp.user_id
+		WHERE rp.room_id = $1 AND rp.is_blocked = FALSE AND us.language <> ''`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting member languages for room %s: %w", roomID, err)
+	}
+	defer rows.Close()
+
+	languages := make(map[uuid.UUID]string)
+	for rows.Next() {
+		var userID uuid.UUID
+		var language string
+		if err := rows.Scan(&userID, &language); err != nil {
+			return nil, err
+		}
+		languages[userID] = language
+	}
+	return languages, rows.Err()
+}
+
+func (r *postgresAppRepository) SaveMessageTranslations(ctx context.Context, messageID int64, translations map[string]string) error {
+	batch := &pgx.Batch{}
+	for language, content := range translations {
+		batch.Queue(`INSERT INTO message_translations (message_id, language, content) VALUES ($1, $2, $3)
+			ON CONFLICT (message_id, language) DO UPDATE SET content = $3, created_at = NOW()`, messageID, language, content)
+	}
+	if err := r.db.Pool(ctx).SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("error saving translations of message %d: %w", messageID, err)
+	}
+	return nil
+}
+
+func (r *postgresAppRepository) GetMessageTranslations(ctx context.Context, messageIDs []int64, language string) (map[int64]string, error) {
+	query := `SELECT message_id, content FROM message_translations WHERE message_id = ANY($1) AND language = $2`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, messageIDs, language)
+	if err != nil {
+		return nil, fmt.Errorf("error getting message translations: %w", err)
+	}
+	defer rows.Close()
+
+	translations := make(map[int64]string)
+	for rows.Next() {
+		var messageID int64
+		var content string
+		if err := rows.Scan(&messageID, &content); err != nil {
+			return nil, err
+		}
+		translations[messageID] = content
+	}
+	return translations, rows.Err()
+}
+
+func (r *postgresAppRepository) GetLastReadAt(ctx context.Context, userID, roomID uuid.UUID) (*time.Time, error) {
+	query := `
+		SELECT MAX(m.created_at)
+		FROM message_read_status rs
+		JOIN messages m ON m.id = rs.message_id
+		WHERE rs.user_id = $1 AND m.room_id = $2
+	`
+	var readAt *time.Time
+	if err := r.db.Pool(ctx).QueryRow(ctx, query, userID, roomID).Scan(&readAt); err != nil {
+		return nil, fmt.Errorf("error getting last read message of room %s: %w", roomID, err)
+	}
+	return readAt, nil
+}
+
+func (r *postgresAppRepository) GetSummaryLines(ctx context.Context, roomID uuid.UUID, since time.Time, limit int) ([]domain.SummaryLine, error) {
+	query := `
+		SELECT id, user_id, author, content, created_at FROM (
+			SELECT m.id, m.user_id, COALESCE(u.nickname, 'Unknown') AS author, m.content, m.created_at
+			FROM messages m
+			LEFT JOIN users u ON u.id = m.user_id
+			WHERE m.room_id = $1 AND m.kind = 'text' AND m.deleted_at IS NULL AND m.created_at > $2
+			ORDER BY m.id DESC
+			LIMIT $3
+		) recent
+		ORDER BY id
+	`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, roomID, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error getting messages to summarize: %w", err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[domain.SummaryLine])
+}
+
+func (r *postgresAppRepository) AddMessageReaction(ctx context.Context, messageID int64, userID uuid.UUID, emoji string) (bool, error) {
+	tag, err := r.db.Pool(ctx).Exec(ctx, `INSERT INTO message_reactions (message_id, user_id, emoji) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`, messageID, userID, emoji)
+	if err != nil {
+		return false, fmt.Errorf("error reacting to message %d: %w", messageID, err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *postgresAppRepository) RemoveMessageReaction(ctx context.Context, messageID int64, userID uuid.UUID, emoji string) (bool, error) {
+	tag, err := r.db.Pool(ctx).Exec(ctx, `DELETE FROM message_reactions WHERE message_id = $1 AND user_id = $2 AND emoji = $3`, messageID, userID, emoji)
+	if err != nil {
+		return false, fmt.Errorf("error removing reaction from message %d: %w", messageID, err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *postgresAppRepository) GetRoomHighlights(ctx context.Context, roomID uuid.UUID, since time.Time, limit int) ([]domain.RoomHighlight, error) {
+	query := `
+		SELECT m.id, m.user_id, COALESCE(u.nickname, 'Unknown') AS author, m.content, m.kind, COUNT(*) AS reactions, m.created_at
+		FROM message_reactions mr
+		JOIN messages m ON m.id = mr.message_id
+		LEFT JOIN users u ON u.id = m.user_id
+		WHERE m.room_id = $1 AND m.created_at > $2 AND m.deleted_at IS NULL
+		GROUP BY m.id, u.nickname
+		ORDER BY reactions DESC, m.id DESC
+		LIMIT $3
+	`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, roomID, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error getting highlights for room %s: %w", roomID, err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[domain.RoomHighlight])
+}
+
+func (r *postgresAppRepository) GetRoomSummary(ctx context.Context, roomID uuid.UUID, language string, firstMessageID, lastMessageID int64) (*domain.RoomSummary, error) {
+	query := `
+		SELECT room_id, language, first_message_id, last_message_id, message_count, summary, created_at
+		FROM room_summaries
+		WHERE room_id = $1 AND language = $2 AND first_message_id = $3 AND last_message_id = $4
+	`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, roomID, language, firstMessageID, lastMessageID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting room summary: %w", err)
+	}
+	summary, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.RoomSummary])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting room summary: %w", err)
+	}
+	return &summary, nil
+}
+
+func (r *postgresAppRepository) SaveRoomSummary(ctx context.Context, summary *domain.RoomSummary) error {
+	query := `
+		INSERT INTO room_summaries (room_id, language, first_message_id, last_message_id, message_count, summary)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (room_id, language, first_message_id, last_message_id)
+		DO UPDATE SET summary = EXCLUDED.summary, message_count = EXCLUDED.message_count, created_at = NOW()
+		RETURNING created_at
+	`
+	err := r.db.Pool(ctx).QueryRow(ctx, query, summary.RoomID, summary.Language, summary.FirstMessageID, summary.LastMessageID, summary.MessageCount, summary.Summary).Scan(&summary.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error saving room summary: %w", err)
+	}
+	return nil
+}
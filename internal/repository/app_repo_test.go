@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"testing"
+	"testing/quick"
+
+	"chatservice/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+func testRepository(t *testing.T) AppRepository {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	pool, err := NewDBPool(url, 0)
+	if err != nil {
+		t.Fatalf("could not connect to test database: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	resolver, err := NewClusterResolver(pool, 0, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewAppRepository(resolver)
+}
+
+func ptr(s string) *string { return &s }
+
+func TestUpsertUserRoundTrip(t *testing.T) {
+	repo := testRepository(t)
+	ctx := context.Background()
+	id := uuid.New()
+	suffix := id.String()[:8]
+	email, username, nickname := "rt-"+suffix+"@example.com", "rt_"+suffix, "Round Trip"
+
+	tests := []struct {
+		name                          string
+		email, username, nickname     *string
+		wantEmail, wantUser, wantNick string
+	}{
+		{"insert writes every column", &email, &username, &nickname, email, username, nickname},
+		{"nil fields keep stored values", nil, nil, nil, email, username, nickname},
+		{"empty strings keep stored values", ptr(""), ptr(""), ptr(""), email, username, nickname},
+		{"nickname only", nil, nil, ptr("Renamed"), email, username, "Renamed"},
+		{"username only", nil, ptr("rt2_" + suffix), nil, email, "rt2_" + suffix, "Renamed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := repo.UpsertUser(ctx, id, tt.email, tt.username, tt.nickname); err != nil {
+				t.Fatalf("UpsertUser: %v", err)
+			}
+			byID, err := repo.GetUserByID(ctx, id)
+			if err != nil || byID == nil {
+				t.Fatalf("GetUserByID = %v, %v", byID, err)
+			}
+			if byID.Email != tt.wantEmail || byID.Username != tt.wantUser || byID.Nickname != tt.wantNick {
+				t.Errorf("got (%q, %q, %q), want (%q, %q, %q)", byID.Email, byID.Username, byID.Nickname, tt.wantEmail, tt.wantUser, tt.wantNick)
+			}
+			byEmail, err := repo.GetUserByEmail(ctx, tt.wantEmail)
+			if err != nil || byEmail == nil || byEmail.ID != id {
+				t.Fatalf("GetUserByEmail = %v, %v", byEmail, err)
+			}
+			if byEmail.Username != tt.wantUser || byEmail.Nickname != tt.wantNick {
+				t.Errorf("GetUserByEmail returned (%q, %q), want (%q, %q)", byEmail.Username, byEmail.Nickname, tt.wantUser, tt.wantNick)
+			}
+		})
+	}
+}
+
+func TestUpsertUserWithoutProfile(t *testing.T) {
+	repo := testRepository(t)
+	ctx := context.Background()
+	id := uuid.New()
+	if err := repo.UpsertUser(ctx, id, nil, nil, nil); err != nil {
+		t.Fatalf("UpsertUser: %v", err)
+	}
+	user, err := repo.GetUserByID(ctx, id)
+	if err != nil || user == nil {
+		t.Fatalf("GetUserByID = %v, %v", user, err)
+	}
+	if user.Email != "" || user.Username != "" || user.Nickname != "" {
+		t.Errorf("expected empty profile, got %+v", user)
+	}
+}
+
+func TestFriendshipOrderingProperty(t *testing.T) {
+	repo := testRepository(t)
+	ctx := context.Background()
+	property := func(a, b uuid.UUID) bool {
+		if a == b {
+			return true
+		}
+		for _, id := range []uuid.UUID{a, b} {
+			if err := repo.UpsertUser(ctx, id, nil, nil, nil); err != nil {
+				t.Fatalf("UpsertUser: %v", err)
+			}
+		}
+		if err := repo.CreateFriendship(ctx, domain.NewFriendship(b, a, "pending", b)); err != nil {
+			t.Fatalf("CreateFriendship: %v", err)
+		}
+		one, two := domain.OrderFriendPair(a, b)
+		for _, pair := range [][2]uuid.UUID{{a, b}, {b, a}} {
+			fs, err := repo.GetFriendship(ctx, pair[0], pair[1])
+			if err != nil || fs == nil || fs.UserOneID != one || fs.UserTwoID != two {
+				t.Logf("GetFriendship(%s, %s) = %+v, %v", pair[0], pair[1], fs, err)
+				return false
+			}
+		}
+		if err := repo.DeleteFriendship(ctx, a, b); err != nil {
+			t.Fatalf("DeleteFriendship: %v", err)
+		}
+		fs, err := repo.GetFriendship(ctx, b, a)
+		return err == nil && fs == nil
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 20}); err != nil {
+		t.Error(err)
+	}
+}
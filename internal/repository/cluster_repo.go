@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"chatservice/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ClusterRepository interface {
+	Heartbeat(ctx context.Context, instance *domain.Instance) error
+	RemoveInstance(ctx context.Context, instanceID string) error
+	PruneInstances(ctx context.Context, olderThan time.Time) error
+	GetLiveInstances(ctx context.Context, since time.Time) ([]domain.Instance, error)
+	TrackConnection(ctx context.Context, userID uuid.UUID, instanceID string) error
+	UntrackConnection(ctx context.Context, userID uuid.UUID, instanceID string) error
+	GetInstancesForUser(ctx context.Context, userID uuid.UUID, since time.Time) ([]string, error)
+	StoreEnvelope(ctx context.Context, payload []byte) (int64, error)
+	LoadEnvelope(ctx context.Context, id int64) ([]byte, error)
+	PruneEnvelopes(ctx context.Context, olderThan time.Time) error
+}
+
+type postgresClusterRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewClusterRepository(db *pgxpool.Pool) ClusterRepository {
+	return &postgresClusterRepository{db: db}
+}
+
+func (r *postgresClusterRepository) Heartbeat(ctx context.Context, instance *domain.Instance) error {
+	query := `
+		INSERT INTO chat_instances (id, url, started_at, last_heartbeat_at, connections)
+		VALUES ($1, $2, $3, NOW(), $4)
+		ON CONFLICT (id) DO UPDATE SET url = $2, last_heartbeat_at = NOW(), connections = $4
+	`
+	_, err := r.db.Exec(ctx, query, instance.ID, instance.URL, instance.StartedAt, instance.Connections)
+	if err != nil {
+		return fmt.Errorf("error recording instance heartbeat: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresClusterRepository) RemoveInstance(ctx context.Context, instanceID string) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM chat_instances WHERE id = $1`, instanceID)
+	return err
+}
+
+func (r *postgresClusterRepository) PruneInstances(ctx context.Context, olderThan time.Time) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM chat_instances WHERE last_heartbeat_at < $1`, olderThan)
+	return err
+}
+
+func (r *postgresClusterRepository) GetLiveInstances(ctx context.Context, since time.Time) ([]domain.Instance, error) {
+	query := `SELECT id, url, started_at, last_heartbeat_at, connections FROM chat_instances WHERE last_heartbeat_at >= $1 ORDER BY started_at`
+	rows, err := r.db.Query(ctx, query, since)
+	if err != nil {
+		return nil, fmt.Errorf("error getting live instances: %w", err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[domain.Instance])
+}
+
+func (r *postgresClusterRepository) TrackConnection(ctx context.Context, userID uuid.UUID, instanceID string) error {
+	query := `INSERT INTO user_connections (user_id, instance_id) VALUES ($1, $2) ON CONFLICT (user_id, instance_id) DO UPDATE SET connected_at = NOW()`
+	_, err := r.db.Exec(ctx, query, userID, instanceID)
+	return err
+}
+
+func (r *postgresClusterRepository) UntrackConnection(ctx context.Context, userID uuid.UUID, instanceID string) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM user_connections WHERE user_id = $1 AND instance_id = $2`, userID, instanceID)
+	return err
+}
+
+func (r *postgresClusterRepository) GetInstancesForUser(ctx context.Context, userID uuid.UUID, since time.Time) ([]string, error) {
+	query := `
+		SELECT uc.instance_id
+		FROM user_connections uc
+		JOIN chat_instances ci ON ci.id = uc.instance_id
+		WHERE uc.user_id = $1 AND ci.last_heartbeat_at >= $2
+	`
+	rows, err := r.db.Query(ctx, query, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("error locating user connections: %w", err)
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+func (r *postgresClusterRepository) StoreEnvelope(ctx context.Context, payload []byte) (int64, error) {
+	var id int64
+	err := r.db.QueryRow(ctx, `INSERT INTO backplane_envelopes (payload) VALUES ($1) RETURNING id`, string(payload)).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("error storing backplane envelope: %w", err)
+	}
+	return id, nil
+}
+
+func (r *postgresClusterRepository) LoadEnvelope(ctx context.Context, id int64) ([]byte, error) {
+	var payload string
+	err := r.db.QueryRow(ctx, `SELECT payload FROM backplane_envelopes WHERE id = $1`, id).Scan(&payload)
+	if err != nil {
+		return nil, fmt.Errorf("error loading backplane envelope %d: %w", id, err)
+	}
+	return []byte(payload), nil
+}
+
+func (r *postgresClusterRepository) PruneEnvelopes(ctx context.Context, olderThan time.Time) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM backplane_envelopes WHERE created_at < $1`, olderThan)
+	return err
+}
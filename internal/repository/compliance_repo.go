@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"chatservice/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type ComplianceRepository interface {
+	CreateLegalHold(ctx context.Context, hold *domain.LegalHold) error
+	ReleaseLegalHold(ctx context.Context, holdID uuid.UUID) error
+	GetActiveLegalHolds(ctx context.Context) ([]domain.LegalHold, error)
+	IsUnderLegalHold(ctx context.Context, subjectType string, subjectID uuid.UUID) (bool, error)
+	GetMessagesForExport(ctx context.Context, userIDs, roomIDs []uuid.UUID, from, to time.Time) ([]domain.Message, error)
+	GetAttachmentsForExport(ctx context.Context, attachmentIDs, roomIDs []uuid.UUID, from, to time.Time) ([]domain.Attachment, error)
+}
+
+type postgresComplianceRepository struct {
+	db *ClusterResolver
+}
+
+func NewComplianceRepository(db *ClusterResolver) ComplianceRepository {
+	return &postgresComplianceRepository{db: db}
+}
+
+func (r *postgresComplianceRepository) CreateLegalHold(ctx context.Context, hold *domain.LegalHold) error {
+	query := `INSERT INTO legal_holds (subject_type, subject_id, reason, placed_by) VALUES ($1, $2, $3, $4) RETURNING id, created_at`
+	err := r.db.Pool(ctx).QueryRow(ctx, query, hold.SubjectType, hold.SubjectID, hold.Reason, hold.PlacedBy).Scan(&hold.ID, &hold.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating legal hold: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresComplianceRepository) ReleaseLegalHold(ctx context.Context, holdID uuid.UUID) error {
+	cmdTag, err := r.db.Pool(ctx).Exec(ctx, `UPDATE legal_holds SET released_at = NOW() WHERE id = $1 AND released_at IS NULL`, holdID)
+	if err != nil {
+		return fmt.Errorf("error releasing legal hold: %w", err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return fmt.Errorf("legal hold not found or already released")
+	}
+	return nil
+}
+
+func (r *postgresComplianceRepository) GetActiveLegalHolds(ctx context.Context) ([]domain.LegalHold, error) {
+	query := `SELECT id, subject_type, subject_id, reason, placed_by, created_at, released_at FROM legal_holds WHERE released_at IS NULL ORDER BY created_at DESC`
+	rows, err := r.db.Pool(ctx).Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error getting legal holds: %w", err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[domain.LegalHold])
+}
+
+func (r *postgresComplianceRepository) IsUnderLegalHold(ctx context.Context, subjectType string, subjectID uuid.UUID) (bool, error) {
+	var held bool
+	query := `SELECT EXISTS(SELECT 1 FROM legal_holds WHERE subject_type = $1 AND subject_id = $2 AND released_at IS NULL)`
+	err := r.db.Pool(ctx).QueryRow(ctx, query, subjectType, subjectID).Scan(&held)
+	return held, err
+}
+
+func (r *postgresComplianceRepository) GetMessagesForExport(ctx context.Context, userIDs, roomIDs []uuid.UUID, from, to time.Time) ([]domain.Message, error) {
+	query := `
+		SELECT m.id, m.message_uid, m.room_id, m.user_id, m.content, m.kind, m.content_type, m.rich_content, m.links, m.hashtags, m.group_mentions, m.metadata, m.attachment_id, m.reply_to_message_id, m.created_at, m.updated_at, m.deleted_at
+		FROM messages m
+		WHERE m.created_at >= $2 AND m.created_at < $3
+			AND (m.user_id = ANY($1) OR m.room_id IN (SELECT room_id FROM room_participants WHERE user_id = ANY($1)) OR m.room_id = ANY($4))
+		ORDER BY m.id
+	`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, userIDs, from, to, roomIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error getting messages for export: %w", err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[domain.Message])
+}
+
+func (r *postgresComplianceRepository) GetAttachmentsForExport(ctx context.Context, attachmentIDs, roomIDs []uuid.UUID, from, to time.Time) ([]domain.Attachment, error) {
+	query := `
+		SELECT id, room_id, COALESCE(uploader_id, uuid_nil()) AS uploader_id, kind, storage_url, content_type, size_bytes, created_at
+		FROM room_attachments
+		WHERE id = ANY($1) OR (room_id = ANY($2) AND created_at >= $3 AND created_at < $4)
+		ORDER BY created_at, id
+	`
+	rows, err := r.db.Pool(ctx).Query(ctx, query, attachmentIDs, roomIDs, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("error getting attachments for export: %w", err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[domain.Attachment])
+}
@@ -2,13 +2,24 @@ package repository
 
 import (
 	"context"
-	"log"
+	"strings"
 
+	"chatservice/internal/logging"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func NewDBPool(connString string) (*pgxpool.Pool, error) {
-	pool, err := pgxpool.New(context.Background(), connString)
+var repoLog = logging.For("repo")
+
+func NewDBPool(connString string, statementCacheCapacity int) (*pgxpool.Pool, error) {
+	cfg, err := poolConfig(connString, statementCacheCapacity)
+	if err != nil {
+		return nil, err
+	}
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -17,6 +28,27 @@ func NewDBPool(connString string) (*pgxpool.Pool, error) {
 		return nil, err
 	}
 
-	log.Println("Successfully connected to PostgreSQL database.")
+	repoLog.Infof("Successfully connected to PostgreSQL database.")
 	return pool, nil
-}
\ No newline at end of file
+}
+
+func poolConfig(connString string, statementCacheCapacity int) (*pgxpool.Config, error) {
+	cfg, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.Contains(connString, "default_query_exec_mode") {
+		cfg.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeExec
+		if statementCacheCapacity > 0 {
+			cfg.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeCacheStatement
+		}
+	}
+	if statementCacheCapacity > 0 && !strings.Contains(connString, "statement_cache_capacity") {
+		cfg.ConnConfig.StatementCacheCapacity = statementCacheCapacity
+	}
+	cfg.ConnConfig.Tracer = statementStats
+	if len(cfg.ConnConfig.Fallbacks) > 0 && !strings.Contains(connString, "target_session_attrs") {
+		cfg.ConnConfig.ValidateConnect = pgconn.ValidateConnectTargetSessionAttrsReadWrite
+	}
+	return cfg, nil
+}
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"chatservice/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestPoolConfigStatementCache(t *testing.T) {
+	const dsn = "postgres://chat@localhost:5432/chat"
+	tests := []struct {
+		name     string
+		connStr  string
+		capacity int
+		mode     pgx.QueryExecMode
+		cache    int
+	}{
+		{"cache enabled", dsn, 256, pgx.QueryExecModeCacheStatement, 256},
+		{"cache disabled", dsn, 0, pgx.QueryExecModeExec, 512},
+		{"dsn exec mode wins", dsn + "?default_query_exec_mode=exec", 256, pgx.QueryExecModeExec, 256},
+		{"dsn capacity wins", dsn + "?statement_cache_capacity=16", 256, pgx.QueryExecModeCacheStatement, 16},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := poolConfig(tt.connStr, tt.capacity)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.ConnConfig.DefaultQueryExecMode != tt.mode {
+				t.Errorf("exec mode = %v, want %v", cfg.ConnConfig.DefaultQueryExecMode, tt.mode)
+			}
+			if cfg.ConnConfig.StatementCacheCapacity != tt.cache {
+				t.Errorf("statement cache capacity = %d, want %d", cfg.ConnConfig.StatementCacheCapacity, tt.cache)
+			}
+			if cfg.ConnConfig.Tracer != statementStats {
+				t.Error("statement tracer not installed")
+			}
+		})
+	}
+}
+
+const (
+	benchRooms        = 50
+	benchParticipants = 8
+	benchMessages     = 20
+)
+
+// seedRoomsForUser gives a fresh user benchRooms group rooms, each shared with
+// other participants and holding a short message history, and removes them
+// when the benchmark finishes.
+func seedRoomsForUser(b *testing.B, url string) uuid.UUID {
+	b.Helper()
+	pool, err := NewDBPool(url, 0)
+	if err != nil {
+		b.Fatalf("could not connect to test database: %v", err)
+	}
+	b.Cleanup(pool.Close)
+	resolver, err := NewClusterResolver(pool, 0, nil, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	repo := NewAppRepository(resolver)
+	ctx := context.Background()
+
+	users := make([]uuid.UUID, benchParticipants)
+	for i := range users {
+		users[i] = uuid.New()
+		if err := repo.UpsertUser(ctx, users[i], nil, nil, nil); err != nil {
+			b.Fatalf("UpsertUser: %v", err)
+		}
+	}
+	rooms := make([]uuid.UUID, 0, benchRooms)
+	b.Cleanup(func() {
+		ctx := context.Background()
+		if _, err := pool.Exec(ctx, `DELETE FROM rooms WHERE id = ANY($1)`, rooms); err != nil {
+			b.Errorf("could not remove seeded rooms: %v", err)
+		}
+		if _, err := pool.Exec(ctx, `DELETE FROM users WHERE id = ANY($1)`, users); err != nil {
+			b.Errorf("could not remove seeded users: %v", err)
+		}
+	})
+
+	for range benchRooms {
+		tx, err := pool.Begin(ctx)
+		if err != nil {
+			b.Fatal(err)
+		}
+		room, err := repo.CreateRoom(ctx, tx, &domain.Room{Type: "group", OwnerID: &users[0]})
+		if err != nil {
+			tx.Rollback(ctx)
+			b.Fatalf("CreateRoom: %v", err)
+		}
+		rooms = append(rooms, room.ID)
+		for _, userID := range users {
+			if err := repo.AddUserToRoom(ctx, tx, userID, room.ID); err != nil {
+				tx.Rollback(ctx)
+				b.Fatalf("AddUserToRoom: %v", err)
+			}
+		}
+		if err := tx.Commit(ctx); err != nil {
+			b.Fatal(err)
+		}
+		for i := range benchMessages {
+			msg := &domain.Message{MessageUID: uuid.New(), RoomID: room.ID, UserID: users[i%len(users)], Content: "benchmark message"}
+			if _, err := repo.CreateMessage(ctx, msg); err != nil {
+				b.Fatalf("CreateMessage: %v", err)
+			}
+		}
+	}
+	return users[0]
+}
+
+func BenchmarkGetRoomsForUser(b *testing.B) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		b.Skip("TEST_DATABASE_URL is not set")
+	}
+	userID := seedRoomsForUser(b, url)
+	for _, bench := range []struct {
+		name     string
+		capacity int
+	}{{"exec", 0}, {"cache_statement", 512}} {
+		b.Run(bench.name, func(b *testing.B) {
+			cfg, err := poolConfig(url, bench.capacity)
+			if err != nil {
+				b.Fatal(err)
+			}
+			cfg.MaxConns = 1
+			pool, err := pgxpool.NewWithConfig(context.Background(), cfg)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer pool.Close()
+			resolver, err := NewClusterResolver(pool, bench.capacity, nil, nil)
+			if err != nil {
+				b.Fatal(err)
+			}
+			repo := NewAppRepository(resolver)
+			ctx := context.Background()
+
+			b.ResetTimer()
+			for range b.N {
+				if _, err := repo.GetRoomsForUser(ctx, userID); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"chatservice/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const failedDeliveryColumns = `id, kind, target, payload, status, attempts, last_error, next_attempt_at, created_at, updated_at`
+
+type DeliveryRepository interface {
+	CreateFailedDelivery(ctx context.Context, delivery *domain.FailedDelivery) error
+	ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]domain.FailedDelivery, error)
+	DeleteDelivery(ctx context.Context, id uuid.UUID) error
+	RescheduleDelivery(ctx context.Context, id uuid.UUID, attempts int, nextAttemptAt time.Time, lastError string, dead bool) error
+	ListFailedDeliveries(ctx context.Context, status string, limit int) ([]domain.FailedDelivery, error)
+	GetFailedDelivery(ctx context.Context, id uuid.UUID) (*domain.FailedDelivery, error)
+	RequeueDelivery(ctx context.Context, id uuid.UUID) (bool, error)
+	DeadLetterDelivery(ctx context.Context, id uuid.UUID) (bool, error)
+}
+
+type postgresDeliveryRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewDeliveryRepository(db *pgxpool.Pool) DeliveryRepository {
+	return &postgresDeliveryRepository{db: db}
+}
+
+func (r *postgresDeliveryRepository) CreateFailedDelivery(ctx context.Context, delivery *domain.FailedDelivery) error {
+	query := `
+		INSERT INTO failed_deliveries (id, kind, target, payload, status, attempts, last_error, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING created_at, updated_at
+	`
+	err := r.db.QueryRow(ctx, query, delivery.ID, delivery.Kind, delivery.Target, string(delivery.Payload), delivery.Status,
+		delivery.Attempts, delivery.LastError, delivery.NextAttemptAt).Scan(&delivery.CreatedAt, &delivery.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("error recording failed %s delivery: %w", delivery.Kind, err)
+	}
+	return nil
+}
+
+func (r *postgresDeliveryRepository) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]domain.FailedDelivery, error) {
+	query := `
+		UPDATE failed_deliveries
+		SET next_attempt_at = NOW() + $2 * INTERVAL '1 second', updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM failed_deliveries
+			WHERE status = 'retrying' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + failedDeliveryColumns
+	rows, err := r.db.Query(ctx, query, limit, int(lease.Seconds()))
+	if err != nil {
+		return nil, fmt.Errorf("error claiming due deliveries: %w", err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[domain.FailedDelivery])
+}
+
+func (r *postgresDeliveryRepository) DeleteDelivery(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM failed_deliveries WHERE id = $1`, id)
+	return err
+}
+
+func (r *postgresDeliveryRepository) RescheduleDelivery(ctx context.Context, id uuid.UUID, attempts int, nextAttemptAt time.Time, lastError string, dead bool) error {
+	status := domain.DeliveryStatusRetrying
+	if dead {
+		status = domain.DeliveryStatusDead
+	}
+	query := `
+		UPDATE failed_deliveries
+		SET attempts = $2, next_attempt_at = $3, last_error = $4, status = $5, updated_at = NOW()
+		WHERE id = $1
+	`
+	if _, err := r.db.Exec(ctx, query, id, attempts, nextAttemptAt, lastError, status); err != nil {
+		return fmt.Errorf("error rescheduling delivery %s: %w", id, err)
+	}
+	return nil
+}
+
+func (r *postgresDeliveryRepository) ListFailedDeliveries(ctx context.Context, status string, limit int) ([]domain.FailedDelivery, error) {
+	query := `
+		SELECT ` + failedDeliveryColumns + `
+		FROM failed_deliveries
+		WHERE $1 = '' OR status = $1
+		ORDER BY updated_at DESC
+		LIMIT $2
+	`
+	rows, err := r.db.Query(ctx, query, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error listing failed deliveries: %w", err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[domain.FailedDelivery])
+}
+
+func (r *postgresDeliveryRepository) GetFailedDelivery(ctx context.Context, id uuid.UUID) (*domain.FailedDelivery, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+failedDeliveryColumns+` FROM failed_deliveries WHERE id = $1`, id)
+	if err != nil {
+		return nil, fmt.Errorf("error getting delivery %s: %w", id, err)
+	}
+	delivery, err := pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByName[domain.FailedDelivery])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return delivery, err
+}
+
+func (r *postgresDeliveryRepository) RequeueDelivery(ctx context.Context, id uuid.UUID) (bool, error) {
+	query := `
+		UPDATE failed_deliveries
+		SET status = 'retrying', next_attempt_at = NOW(), updated_at = NOW()
+		WHERE id = $1
+	`
+	tag, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("error requeueing delivery %s: %w", id, err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *postgresDeliveryRepository) DeadLetterDelivery(ctx context.Context, id uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `UPDATE failed_deliveries SET status = 'dead', updated_at = NOW() WHERE id = $1`, id)
+	if err != nil {
+		return false, fmt.Errorf("error dead-lettering delivery %s: %w", id, err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
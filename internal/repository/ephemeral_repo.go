@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"chatservice/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type EphemeralRepository interface {
+	SetState(ctx context.Context, state domain.EphemeralState) error
+	ClearState(ctx context.Context, roomID, userID uuid.UUID, kind string) error
+	GetRoomState(ctx context.Context, roomID uuid.UUID) ([]domain.EphemeralState, error)
+	PruneExpiredState(ctx context.Context) error
+}
+
+type postgresEphemeralRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewEphemeralRepository(db *pgxpool.Pool) EphemeralRepository {
+	return &postgresEphemeralRepository{db: db}
+}
+
+func (r *postgresEphemeralRepository) SetState(ctx context.Context, state domain.EphemeralState) error {
+	query := `
+		INSERT INTO room_ephemeral_state (room_id, user_id, kind, value, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (room_id, user_id, kind) DO UPDATE SET value = $4, expires_at = $5
+	`
+	_, err := r.db.Exec(ctx, query, state.RoomID, state.UserID, state.Kind, state.Value, state.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("error saving ephemeral state: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresEphemeralRepository) ClearState(ctx context.Context, roomID, userID uuid.UUID, kind string) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM room_ephemeral_state WHERE room_id = $1 AND user_id = $2 AND kind = $3`, roomID, userID, kind)
+	return err
+}
+
+func (r *postgresEphemeralRepository) GetRoomState(ctx context.Context, roomID uuid.UUID) ([]domain.EphemeralState, error) {
+	query := `
+		SELECT room_id, user_id, kind, value, expires_at
+		FROM room_ephemeral_state
+		WHERE room_id = $1 AND expires_at > NOW()
+		ORDER BY kind, user_id
+	`
+	rows, err := r.db.Query(ctx, query, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting ephemeral state for room: %w", err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[domain.EphemeralState])
+}
+
+func (r *postgresEphemeralRepository) PruneExpiredState(ctx context.Context) error {
+	_, err := WithAdvisoryLock(ctx, r.db, "ephemeral:prune", func(ctx context.Context) error {
+		_, err := r.db.Exec(ctx, `DELETE FROM room_ephemeral_state WHERE expires_at <= NOW()`)
+		return err
+	})
+	return err
+}
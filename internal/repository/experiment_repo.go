@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+type ExperimentRepository interface {
+	RecordExposure(ctx context.Context, experiment string, userID uuid.UUID, variant string) error
+}
+
+type postgresExperimentRepository struct {
+	db *ClusterResolver
+}
+
+func NewExperimentRepository(db *ClusterResolver) ExperimentRepository {
+	return &postgresExperimentRepository{db: db}
+}
+
+func (r *postgresExperimentRepository) RecordExposure(ctx context.Context, experiment string, userID uuid.UUID, variant string) error {
+	query := `
+		INSERT INTO experiment_exposures (experiment, user_id, variant)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (experiment, user_id) DO UPDATE
+		SET variant = EXCLUDED.variant, last_exposed_at = NOW(), exposures = experiment_exposures.exposures + 1
+	`
+	if _, err := r.db.Pool(ctx).Exec(ctx, query, experiment, userID, variant); err != nil {
+		return fmt.Errorf("error recording exposure to %s for %s: %w", experiment, userID, err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"chatservice/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type FirehoseRepository interface {
+	GetMessageRange(ctx context.Context, afterID, untilID int64, settle time.Duration, limit int) ([]domain.Message, int64, error)
+}
+
+type postgresFirehoseRepository struct {
+	db *ClusterResolver
+}
+
+func NewFirehoseRepository(db *ClusterResolver) FirehoseRepository {
+	return &postgresFirehoseRepository{db: db}
+}
+
+func (r *postgresFirehoseRepository) GetMessageRange(ctx context.Context, afterID, untilID int64, settle time.Duration, limit int) ([]domain.Message, int64, error) {
+	tx, err := r.db.Pool(ctx).BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, 0, fmt.Errorf("error starting firehose snapshot: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var watermark int64
+	watermarkQuery := `
+		SELECT COALESCE(
+			(SELECT MIN(id) - 1 FROM messages WHERE id > $1 AND created_at > NOW() - make_interval(secs => $2)),
+			(SELECT MAX(id) FROM messages),
+			0
+		)
+	`
+	if err := tx.QueryRow(ctx, watermarkQuery, afterID, settle.Seconds()).Scan(&watermark); err != nil {
+		return nil, 0, fmt.Errorf("error getting firehose watermark: %w", err)
+	}
+	if untilID > 0 && untilID < watermark {
+		watermark = untilID
+	}
+
+	query := `
+		SELECT id, message_uid, room_id, user_id, content, kind, content_type, rich_content, links, hashtags, group_mentions, metadata, attachment_id, reply_to_message_id, created_at, updated_at, deleted_at
+		FROM messages
+		WHERE id > $1 AND id <= $2
+		ORDER BY id
+		LIMIT $3
+	`
+	rows, err := tx.Query(ctx, query, afterID, watermark, limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error getting firehose messages: %w", err)
+	}
+	messages, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.Message])
+	if err != nil {
+		return nil, 0, fmt.Errorf("error scanning firehose messages: %w", err)
+	}
+	return messages, watermark, nil
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	defaultHealthInterval = 5 * time.Second
+	unhealthyAfter        = 2
+)
+
+var errStandby = errors.New("connected to a standby in recovery")
+
+type PoolHealth struct {
+	Healthy   bool      `json:"healthy"`
+	Failures  int       `json:"failures"`
+	LastError string    `json:"lastError,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+type HealthProbe struct {
+	pools    map[string]*pgxpool.Pool
+	interval time.Duration
+	ready    atomic.Bool
+
+	mu     sync.Mutex
+	status map[string]PoolHealth
+}
+
+func NewHealthProbe(pools map[string]*pgxpool.Pool, interval time.Duration) *HealthProbe {
+	if interval <= 0 {
+		interval = defaultHealthInterval
+	}
+	p := &HealthProbe{pools: pools, interval: interval, status: make(map[string]PoolHealth, len(pools))}
+	for name := range pools {
+		p.status[name] = PoolHealth{Healthy: true, CheckedAt: time.Now().UTC()}
+	}
+	p.ready.Store(true)
+	return p
+}
+
+func (p *HealthProbe) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.check(ctx)
+		}
+	}
+}
+
+func (p *HealthProbe) Ready() bool {
+	return p.ready.Load()
+}
+
+func (p *HealthProbe) Status() map[string]PoolHealth {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	status := make(map[string]PoolHealth, len(p.status))
+	for name, health := range p.status {
+		status[name] = health
+	}
+	return status
+}
+
+func (p *HealthProbe) check(ctx context.Context) {
+	ready := true
+	for name, pool := range p.pools {
+		err := probe(ctx, pool, p.interval)
+
+		p.mu.Lock()
+		health := p.status[name]
+		health.CheckedAt = time.Now().UTC()
+		if err == nil {
+			if !health.Healthy {
+				repoLog.Infof("Database cluster %s is healthy again", name)
+			}
+			health = PoolHealth{Healthy: true, CheckedAt: health.CheckedAt}
+		} else {
+			health.Failures++
+			health.LastError = err.Error()
+			if health.Healthy && health.Failures >= unhealthyAfter {
+				health.Healthy = false
+				repoLog.Errorf("Database cluster %s is unhealthy after %d failed probes: %v", name, health.Failures, err)
+			}
+		}
+		p.status[name] = health
+		p.mu.Unlock()
+
+		if err != nil {
+			pool.Reset()
+		}
+		ready = ready && health.Healthy
+	}
+	if p.ready.Swap(ready) != ready {
+		repoLog.Warnf("Database readiness changed to %t", ready)
+	}
+}
+
+func probe(ctx context.Context, pool *pgxpool.Pool, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var inRecovery bool
+	if err := pool.QueryRow(ctx, "SELECT pg_is_in_recovery()").Scan(&inRecovery); err != nil {
+		return err
+	}
+	if inRecovery {
+		return errStandby
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"chatservice/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type IdempotencyRepository interface {
+	Reserve(ctx context.Context, userID uuid.UUID, key, fingerprint string, expiredBefore time.Time) (*domain.IdempotencyRecord, error)
+	Complete(ctx context.Context, userID uuid.UUID, key string, statusCode int, contentType string, body []byte) error
+	Release(ctx context.Context, userID uuid.UUID, key string) error
+}
+
+type postgresIdempotencyRepository struct {
+	db *ClusterResolver
+}
+
+func NewIdempotencyRepository(db *ClusterResolver) IdempotencyRepository {
+	return &postgresIdempotencyRepository{db: db}
+}
+
+func (r *postgresIdempotencyRepository) Reserve(ctx context.Context, userID uuid.UUID, key, fingerprint string, expiredBefore time.Time) (*domain.IdempotencyRecord, error) {
+	pool := r.db.Pool(ctx)
+	if _, err := pool.Exec(ctx, `DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND created_at < $3`, userID, key, expiredBefore); err != nil {
+		return nil, fmt.Errorf("error clearing expired idempotency key: %w", err)
+	}
+	tag, err := pool.Exec(ctx, `INSERT INTO idempotency_keys (user_id, key, fingerprint) VALUES ($1, $2, $3) ON CONFLICT (user_id, key) DO NOTHING`, userID, key, fingerprint)
+	if err != nil {
+		return nil, fmt.Errorf("error reserving idempotency key: %w", err)
+	}
+	if tag.RowsAffected() == 1 {
+		return nil, nil
+	}
+
+	rows, err := pool.Query(ctx, `SELECT fingerprint, status_code, content_type, COALESCE(body, '') AS body, created_at FROM idempotency_keys WHERE user_id = $1 AND key = $2`, userID, key)
+	if err != nil {
+		return nil, fmt.Errorf("error loading idempotency key: %w", err)
+	}
+	record, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.IdempotencyRecord])
+	if err != nil {
+		return nil, fmt.Errorf("error loading idempotency key: %w", err)
+	}
+	return &record, nil
+}
+
+func (r *postgresIdempotencyRepository) Complete(ctx context.Context, userID uuid.UUID, key string, statusCode int, contentType string, body []byte) error {
+	query := `UPDATE idempotency_keys SET status_code = $3, content_type = $4, body = $5 WHERE user_id = $1 AND key = $2`
+	if _, err := r.db.Pool(ctx).Exec(ctx, query, userID, key, statusCode, contentType, body); err != nil {
+		return fmt.Errorf("error storing idempotent response: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresIdempotencyRepository) Release(ctx context.Context, userID uuid.UUID, key string) error {
+	if _, err := r.db.Pool(ctx).Exec(ctx, `DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND status_code = 0`, userID, key); err != nil {
+		return fmt.Errorf("error releasing idempotency key: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type InsightsRepository interface {
+	RollupActivity(ctx context.Context, from time.Time) (int64, error)
+}
+
+type postgresInsightsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewInsightsRepository(db *pgxpool.Pool) InsightsRepository {
+	return &postgresInsightsRepository{db: db}
+}
+
+func (r *postgresInsightsRepository) RollupActivity(ctx context.Context, from time.Time) (int64, error) {
+	query := `
+		WITH ordered AS (
+			SELECT m.user_id, m.room_id, m.created_at,
+				LAG(m.user_id) OVER w AS previous_user_id,
+				LAG(m.created_at) OVER w AS previous_at
+			FROM messages m
+			WHERE m.created_at >= $1::timestamptz - INTERVAL '1 day' AND m.deleted_at IS NULL
+			WINDOW w AS (PARTITION BY m.room_id ORDER BY m.created_at, m.id)
+		), replies AS (
+			SELECT user_id, room_id, (created_at AT TIME ZONE 'UTC')::date AS day, created_at - previous_at AS delay,
+				previous_user_id IS NOT NULL AND previous_user_id <> user_id AND created_at - previous_at <= INTERVAL '24 hours' AS is_response
+			FROM ordered
+			WHERE user_id IS NOT NULL AND created_at >= $1
+		)
+		INSERT INTO user_activity_daily (user_id, room_id, day, messages_sent, responses, response_seconds)
+		SELECT user_id, room_id, day, COUNT(*),
+			COUNT(*) FILTER (WHERE is_response),
+			COALESCE(SUM(EXTRACT(EPOCH FROM delay)) FILTER (WHERE is_response), 0)::bigint
+		FROM replies
+		GROUP BY user_id, room_id, day
+		ON CONFLICT (user_id, day, room_id) DO UPDATE SET
+			messages_sent = EXCLUDED.messages_sent,
+			responses = EXCLUDED.responses,
+			response_seconds = EXCLUDED.response_seconds
+	`
+	tag, err := r.db.Exec(ctx, query, from.UTC().Truncate(24*time.Hour))
+	if err != nil {
+		return 0, fmt.Errorf("error rolling up activity: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/logging"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type JobRepository interface {
+	RegisterJob(ctx context.Context, name string, interval time.Duration) error
+	ClaimJob(ctx context.Context, name, owner string, lease time.Duration) (bool, error)
+	FinishJob(ctx context.Context, name, owner string, interval time.Duration, runErr error) error
+	ListJobs(ctx context.Context) ([]domain.JobStatus, error)
+	TryLock(ctx context.Context, name string) (func(), bool, error)
+}
+
+type postgresJobRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewJobRepository(db *pgxpool.Pool) JobRepository {
+	return &postgresJobRepository{db: db}
+}
+
+func (r *postgresJobRepository) RegisterJob(ctx context.Context, name string, interval time.Duration) error {
+	query := `
+		INSERT INTO scheduled_jobs (name, interval_seconds)
+		VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE SET interval_seconds = $2
+	`
+	if _, err := r.db.Exec(ctx, query, name, int(interval.Seconds())); err != nil {
+		return fmt.Errorf("error registering job %s: %w", name, err)
+	}
+	return nil
+}
+
+func (r *postgresJobRepository) ClaimJob(ctx context.Context, name, owner string, lease time.Duration) (bool, error) {
+	query := `
+		UPDATE scheduled_jobs
+		SET locked_by = $2, locked_until = NOW() + $3 * INTERVAL '1 second', last_started_at = NOW(), last_status = 'running'
+		WHERE name = $1 AND next_run_at <= NOW() AND (locked_until IS NULL OR locked_until < NOW())
+		RETURNING name
+	`
+	var claimed string
+	err := r.db.QueryRow(ctx, query, name, owner, int(lease.Seconds())).Scan(&claimed)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error claiming job %s: %w", name, err)
+	}
+	return true, nil
+}
+
+func (r *postgresJobRepository) FinishJob(ctx context.Context, name, owner string, interval time.Duration, runErr error) error {
+	status, message := "succeeded", ""
+	if runErr != nil {
+		status, message = "failed", logging.Scrub(runErr.Error())
+	}
+	query := `
+		UPDATE scheduled_jobs
+		SET locked_by = NULL, locked_until = NULL, last_finished_at = NOW(), last_status = $3, last_error = $4,
+			next_run_at = NOW() + $5 * INTERVAL '1 second', run_count = run_count + 1
+		WHERE name = $1 AND locked_by = $2
+	`
+	if _, err := r.db.Exec(ctx, query, name, owner, status, message, int(interval.Seconds())); err != nil {
+		return fmt.Errorf("error finishing job %s: %w", name, err)
+	}
+	return nil
+}
+
+func (r *postgresJobRepository) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	return TryAdvisoryLock(ctx, r.db, "job:"+name)
+}
+
+func (r *postgresJobRepository) ListJobs(ctx context.Context) ([]domain.JobStatus, error) {
+	query := `
+		SELECT name, interval_seconds, next_run_at, locked_by, locked_until, last_started_at, last_finished_at, last_status, last_error, run_count
+		FROM scheduled_jobs ORDER BY name
+	`
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error listing jobs: %w", err)
+	}
+	jobs, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.JobStatus])
+	if err != nil {
+		return nil, err
+	}
+	for i := range jobs {
+		jobs[i].Interval = (time.Duration(jobs[i].IntervalSecs) * time.Second).String()
+	}
+	return jobs, nil
+}
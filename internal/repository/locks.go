@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TryAdvisoryLock(ctx context.Context, db *pgxpool.Pool, name string) (func(), bool, error) {
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("error acquiring connection for lock %s: %w", name, err)
+	}
+	var locked bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock(hashtext($1))`, name).Scan(&locked); err != nil {
+		conn.Release()
+		return nil, false, fmt.Errorf("error taking lock %s: %w", name, err)
+	}
+	if !locked {
+		conn.Release()
+		return nil, false, nil
+	}
+	release := func() {
+		if _, err := conn.Exec(context.Background(), `SELECT pg_advisory_unlock(hashtext($1))`, name); err != nil {
+			repoLog.Errorf("Failed to release lock %s, closing its connection: %v", name, err)
+			conn.Conn().Close(context.Background())
+		}
+		conn.Release()
+	}
+	return release, true, nil
+}
+
+func WithAdvisoryLock(ctx context.Context, db *pgxpool.Pool, name string, fn func(ctx context.Context) error) (bool, error) {
+	release, locked, err := TryAdvisoryLock(ctx, db, name)
+	if err != nil || !locked {
+		return false, err
+	}
+	defer release()
+	return true, fn(ctx)
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type MaintenanceRepository interface {
+	ExpireDrafts(ctx context.Context, updatedBefore time.Time) (int64, error)
+	ExpireUploads(ctx context.Context, now time.Time) ([]string, error)
+	ExpireSentPushes(ctx context.Context, sentBefore time.Time) (int64, error)
+	ExpireQuotaUsage(ctx context.Context, before time.Time) (int64, error)
+	ExpireIdempotencyKeys(ctx context.Context, createdBefore time.Time) (int64, error)
+	ExpireRoomSummaries(ctx context.Context, createdBefore time.Time) (int64, error)
+}
+
+type postgresMaintenanceRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewMaintenanceRepository(db *pgxpool.Pool) MaintenanceRepository {
+	return &postgresMaintenanceRepository{db: db}
+}
+
+func (r *postgresMaintenanceRepository) ExpireDrafts(ctx context.Context, updatedBefore time.Time) (int64, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM message_drafts WHERE updated_at < $1`, updatedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("error expiring drafts: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (r *postgresMaintenanceRepository) ExpireUploads(ctx context.Context, now time.Time) ([]string, error) {
+	rows, err := r.db.Query(ctx, `DELETE FROM uploads WHERE completed_at IS NULL AND expires_at < $1 RETURNING storage_key`, now)
+	if err != nil {
+		return nil, fmt.Errorf("error expiring uploads: %w", err)
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+func (r *postgresMaintenanceRepository) ExpireSentPushes(ctx context.Context, sentBefore time.Time) (int64, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM sent_pushes WHERE sent_at < $1`, sentBefore)
+	if err != nil {
+		return 0, fmt.Errorf("error expiring sent pushes: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (r *postgresMaintenanceRepository) ExpireQuotaUsage(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM user_quota_usage WHERE day < $1::date`, before)
+	if err != nil {
+		return 0, fmt.Errorf("error expiring quota usage: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (r *postgresMaintenanceRepository) ExpireIdempotencyKeys(ctx context.Context, createdBefore time.Time) (int64, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM idempotency_keys WHERE created_at < $1`, createdBefore)
+	if err != nil {
+		return 0, fmt.Errorf("error expiring idempotency keys: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (r *postgresMaintenanceRepository) ExpireRoomSummaries(ctx context.Context, createdBefore time.Time) (int64, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM room_summaries WHERE created_at < $1`, createdBefore)
+	if err != nil {
+		return 0, fmt.Errorf("error expiring room summaries: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
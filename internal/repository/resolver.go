@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"chatservice/internal/tenant"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ClusterResolver struct {
+	primary *pgxpool.Pool
+	regions map[string]*pgxpool.Pool
+	tenants map[string]string
+	retry   retryPolicy
+}
+
+func NewClusterResolver(primary *pgxpool.Pool, statementCacheCapacity int, regionURLs map[string]string, tenantRegions map[string]string) (*ClusterResolver, error) {
+	r := &ClusterResolver{
+		primary: primary,
+		regions: make(map[string]*pgxpool.Pool),
+		tenants: tenantRegions,
+		retry:   retryPolicy{attempts: defaultRetryAttempts, baseDelay: defaultRetryBaseDelay},
+	}
+	for region, url := range regionURLs {
+		pool, err := NewDBPool(url, statementCacheCapacity)
+		if err != nil {
+			r.Close()
+			return nil, fmt.Errorf("could not connect to %s database cluster: %w", region, err)
+		}
+		r.regions[region] = pool
+		repoLog.Infof("Registered %s database cluster", region)
+	}
+	for tenantID, region := range tenantRegions {
+		if _, ok := r.regions[region]; !ok {
+			r.Close()
+			return nil, fmt.Errorf("tenant %s is routed to unknown region %q", tenantID, region)
+		}
+	}
+	return r, nil
+}
+
+func (r *ClusterResolver) SetRetryPolicy(attempts int, baseDelay time.Duration) {
+	r.retry = retryPolicy{attempts: max(attempts, 1), baseDelay: max(baseDelay, time.Millisecond)}
+}
+
+func (r *ClusterResolver) Pool(ctx context.Context) RetryPool {
+	return RetryPool{Pool: r.pool(ctx), policy: &r.retry}
+}
+
+func (r *ClusterResolver) pool(ctx context.Context) *pgxpool.Pool {
+	if region, ok := r.tenants[tenant.FromContext(ctx)]; ok {
+		return r.regions[region]
+	}
+	return r.primary
+}
+
+func (r *ClusterResolver) Begin(ctx context.Context) (pgx.Tx, error) {
+	return r.pool(ctx).Begin(ctx)
+}
+
+func (r *ClusterResolver) Validate(ctx context.Context) error {
+	if err := ValidateSchema(ctx, r.primary); err != nil {
+		return fmt.Errorf("primary cluster: %w", err)
+	}
+	for region, pool := range r.regions {
+		if err := ValidateSchema(ctx, pool); err != nil {
+			return fmt.Errorf("%s cluster: %w", region, err)
+		}
+	}
+	return nil
+}
+
+func (r *ClusterResolver) Pools() map[string]*pgxpool.Pool {
+	pools := map[string]*pgxpool.Pool{"primary": r.primary}
+	for region, pool := range r.regions {
+		pools[region] = pool
+	}
+	return pools
+}
+
+func (r *ClusterResolver) Stats() map[string]PoolStats {
+	stats := map[string]PoolStats{"primary": poolStats(r.primary)}
+	for region, pool := range r.regions {
+		stats[region] = poolStats(pool)
+	}
+	return stats
+}
+
+func (r *ClusterResolver) Close() {
+	for _, pool := range r.regions {
+		pool.Close()
+	}
+}
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	defaultRetryAttempts  = 3
+	defaultRetryBaseDelay = 25 * time.Millisecond
+	maxRetryDelay         = time.Second
+)
+
+var retryStats = &retryCounters{}
+
+type retryCounters struct {
+	serialization atomic.Int64
+	connection    atomic.Int64
+	recovered     atomic.Int64
+	exhausted     atomic.Int64
+}
+
+type RetryStats struct {
+	SerializationRetries int64 `json:"serializationRetries"`
+	ConnectionRetries    int64 `json:"connectionRetries"`
+	Recovered            int64 `json:"recovered"`
+	Exhausted            int64 `json:"exhausted"`
+}
+
+func TransientRetryStats() RetryStats {
+	return RetryStats{
+		SerializationRetries: retryStats.serialization.Load(),
+		ConnectionRetries:    retryStats.connection.Load(),
+		Recovered:            retryStats.recovered.Load(),
+		Exhausted:            retryStats.exhausted.Load(),
+	}
+}
+
+type retryPolicy struct {
+	attempts  int
+	baseDelay time.Duration
+}
+
+type RetryPool struct {
+	*pgxpool.Pool
+	policy *retryPolicy
+}
+
+func (p RetryPool) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	var tag pgconn.CommandTag
+	err := p.policy.do(ctx, func() error {
+		var err error
+		tag, err = p.Pool.Exec(ctx, sql, args...)
+		return err
+	})
+	return tag, err
+}
+
+func (p RetryPool) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	var rows pgx.Rows
+	err := p.policy.do(ctx, func() error {
+		var err error
+		rows, err = p.Pool.Query(ctx, sql, args...)
+		return err
+	})
+	return rows, err
+}
+
+func (p RetryPool) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return retryRow{pool: p, ctx: ctx, sql: sql, args: args}
+}
+
+type retryRow struct {
+	pool RetryPool
+	ctx  context.Context
+	sql  string
+	args []any
+}
+
+func (r retryRow) Scan(dest ...any) error {
+	return r.pool.policy.do(r.ctx, func() error {
+		return r.pool.Pool.QueryRow(r.ctx, r.sql, r.args...).Scan(dest...)
+	})
+}
+
+func (p *retryPolicy) do(ctx context.Context, op func() error) error {
+	attempts := 1
+	if p != nil {
+		attempts = max(p.attempts, 1)
+	}
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil {
+			if attempt > 1 {
+				retryStats.recovered.Add(1)
+			}
+			return nil
+		}
+		kind := transientKind(err)
+		if kind == "" {
+			return err
+		}
+		if attempt >= attempts {
+			retryStats.exhausted.Add(1)
+			return err
+		}
+		if kind == "serialization" {
+			retryStats.serialization.Add(1)
+		} else {
+			retryStats.connection.Add(1)
+		}
+
+		timer := time.NewTimer(p.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+func (p *retryPolicy) backoff(attempt int) time.Duration {
+	ceiling := min(p.baseDelay<<(attempt-1), maxRetryDelay)
+	return ceiling/2 + rand.N(ceiling/2+1)
+}
+
+func transientKind(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "40001", "40P01":
+			return "serialization"
+		case "57P01", "57P02", "57P03", "08001", "08004", "25006":
+			return "connection"
+		}
+		return ""
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return ""
+	}
+	if pgconn.SafeToRetry(err) {
+		return "connection"
+	}
+	return ""
+}
@@ -0,0 +1,267 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const ExpectedSchemaVersion = 43
+
+var requiredColumns = map[string][]string{
+	"users":                   {"id", "email", "username", "nickname", "created_at", "badges", "state_version"},
+	"friendships":             {"user_one_id", "user_two_id", "status", "action_user_id", "created_at", "updated_at"},
+	"rooms":                   {"id", "type", "name", "owner_id", "created_at", "updated_at", "last_message_at", "metadata", "state", "state_changed_at", "state_changed_by"},
+	"room_participants":       {"room_id", "user_id", "role", "joined_at", "is_blocked", "snoozed_until"},
+	"messages":                {"id", "message_uid", "room_id", "user_id", "content", "kind", "content_type", "rich_content", "links", "hashtags", "group_mentions", "metadata", "attachment_id", "reply_to_message_id", "created_at", "updated_at", "deleted_at", "search_vector"},
+	"message_mentions":        {"message_id", "user_id"},
+	"message_translations":    {"message_id", "language", "content", "created_at"},
+	"message_read_status":     {"message_id", "user_id", "read_at"},
+	"user_activity_daily":     {"user_id", "room_id", "day", "messages_sent", "responses", "response_seconds"},
+	"user_settings":           {"user_id", "email_notifications", "push_previews", "language", "smart_replies", "updated_at"},
+	"chat_instances":          {"id", "url", "started_at", "last_heartbeat_at", "connections"},
+	"user_connections":        {"user_id", "instance_id", "connected_at"},
+	"backplane_envelopes":     {"id", "payload", "created_at"},
+	"experiment_exposures":    {"experiment", "user_id", "variant", "first_exposed_at", "last_exposed_at", "exposures"},
+	"user_away":               {"user_id", "message", "starts_at", "ends_at", "updated_at"},
+	"away_replies":            {"user_id", "sender_id", "sent_at"},
+	"access_allowlist":        {"id", "user_id", "email", "note", "added_by", "created_at"},
+	"room_attachments":        {"id", "room_id", "uploader_id", "kind", "storage_url", "content_type", "size_bytes", "created_at"},
+	"attachment_access":       {"attachment_id", "user_id"},
+	"failed_deliveries":       {"id", "kind", "target", "payload", "status", "attempts", "last_error", "next_attempt_at", "created_at", "updated_at"},
+	"legal_holds":             {"id", "subject_type", "subject_id", "reason", "placed_by", "created_at", "released_at"},
+	"schema_migrations":       {"version", "applied_at"},
+	"room_ephemeral_state":    {"room_id", "user_id", "kind", "value", "expires_at"},
+	"message_drafts":          {"user_id", "room_id", "content", "attachment_ids", "updated_at"},
+	"sent_pushes":             {"notification_id", "user_id", "room_id", "message_id", "preview", "sent_at"},
+	"scheduled_jobs":          {"name", "interval_seconds", "next_run_at", "locked_by", "locked_until", "last_started_at", "last_finished_at", "last_status", "last_error", "run_count"},
+	"room_share_links":        {"id", "token_hash", "room_id", "created_by", "title", "first_message_id", "last_message_id", "expires_at", "revoked_at", "created_at"},
+	"share_link_access":       {"id", "link_id", "ip_address", "user_agent", "accessed_at"},
+	"room_widget_keys":        {"id", "room_id", "key_hash", "key_prefix", "label", "created_by", "created_at", "revoked_at"},
+	"widget_guests":           {"user_id", "key_id", "room_id", "token_hash", "display_name", "created_at", "last_seen_at"},
+	"support_agents":          {"user_id", "available", "created_at"},
+	"support_conversations":   {"room_id", "customer_id", "agent_id", "subject", "created_at", "assigned_at", "updated_at", "first_response_at", "resolved_at", "first_response_breached_at", "resolution_breached_at"},
+	"support_notes":           {"id", "room_id", "author_id", "content", "created_at"},
+	"canned_responses":        {"id", "scope", "owner_id", "room_id", "shortcut", "title", "body", "created_by", "created_at", "updated_at"},
+	"labels":                  {"id", "user_id", "name", "color", "created_at", "updated_at"},
+	"message_labels":          {"label_id", "message_id", "created_at"},
+	"message_reactions":       {"message_id", "user_id", "emoji", "created_at"},
+	"room_labels":             {"label_id", "room_id", "created_at"},
+	"user_identities":         {"user_id", "provider", "login", "linked_at"},
+	"auth_event_cursors":      {"user_id", "applied_at"},
+	"user_quota_usage":        {"user_id", "kind", "day", "used"},
+	"room_message_counters":   {"room_id", "message_count", "updated_at"},
+	"search_backfill_cursors": {"tenant", "last_message_id", "updated_at"},
+	"room_summaries":          {"room_id", "language", "first_message_id", "last_message_id", "message_count", "summary", "created_at"},
+	"guest_merges":            {"id", "guest_id", "user_id", "room_id", "guest_display_name", "messages_moved", "already_member", "merged_at"},
+	"idempotency_keys":        {"user_id", "key", "fingerprint", "status_code", "content_type", "body", "created_at"},
+	"uploads":                 {"id", "room_id", "uploader_id", "filename", "content_type", "size_bytes", "offset_bytes", "checksum_sha256", "storage_key", "expires_at", "created_at", "completed_at"},
+}
+
+var requiredIndexes = []struct {
+	table   string
+	columns []string
+}{
+	{"friendships", []string{"user_one_id", "status"}},
+	{"friendships", []string{"user_two_id", "status"}},
+	{"room_participants", []string{"user_id"}},
+	{"room_participants", []string{"snoozed_until"}},
+	{"messages", []string{"room_id", "created_at"}},
+	{"messages", []string{"hashtags"}},
+	{"messages", []string{"search_vector"}},
+	{"message_read_status", []string{"user_id"}},
+	{"message_mentions", []string{"message_id", "user_id"}},
+	{"message_mentions", []string{"user_id"}},
+	{"message_translations", []string{"message_id", "language"}},
+	{"chat_instances", []string{"last_heartbeat_at"}},
+	{"backplane_envelopes", []string{"created_at"}},
+	{"room_attachments", []string{"room_id", "created_at"}},
+	{"attachment_access", []string{"user_id"}},
+	{"legal_holds", []string{"subject_type", "subject_id"}},
+	{"room_ephemeral_state", []string{"room_id", "user_id", "kind"}},
+	{"room_ephemeral_state", []string{"expires_at"}},
+	{"message_drafts", []string{"user_id", "room_id"}},
+	{"message_drafts", []string{"updated_at"}},
+	{"uploads", []string{"expires_at"}},
+	{"room_share_links", []string{"room_id"}},
+	{"room_share_links", []string{"token_hash"}},
+	{"share_link_access", []string{"link_id", "accessed_at"}},
+	{"room_widget_keys", []string{"room_id"}},
+	{"room_widget_keys", []string{"key_hash"}},
+	{"widget_guests", []string{"key_id"}},
+	{"widget_guests", []string{"token_hash"}},
+	{"support_conversations", []string{"agent_id"}},
+	{"support_conversations", []string{"created_at"}},
+	{"support_conversations", []string{"resolved_at"}},
+	{"support_notes", []string{"room_id", "id"}},
+	{"canned_responses", []string{"owner_id", "shortcut"}},
+	{"canned_responses", []string{"room_id", "shortcut"}},
+	{"canned_responses", []string{"shortcut"}},
+	{"labels", []string{"user_id", "name"}},
+	{"message_labels", []string{"message_id"}},
+	{"room_labels", []string{"room_id"}},
+	{"access_allowlist", []string{"user_id"}},
+	{"experiment_exposures", []string{"experiment", "variant"}},
+	{"access_allowlist", []string{"email"}},
+	{"sent_pushes", []string{"message_id"}},
+	{"failed_deliveries", []string{"status", "next_attempt_at"}},
+	{"sent_pushes", []string{"sent_at"}},
+	{"user_activity_daily", []string{"user_id", "day", "room_id"}},
+	{"user_activity_daily", []string{"day"}},
+	{"user_quota_usage", []string{"day"}},
+	{"idempotency_keys", []string{"created_at"}},
+	{"guest_merges", []string{"user_id"}},
+	{"guest_merges", []string{"guest_id"}},
+	{"room_summaries", []string{"created_at"}},
+}
+
+type SchemaReport struct {
+	Version         int
+	ExpectedVersion int
+	MissingTables   []string
+	MissingColumns  []string
+	MissingIndexes  []string
+}
+
+func (r *SchemaReport) OK() bool {
+	return r.Version == r.ExpectedVersion && len(r.MissingTables) == 0 && len(r.MissingColumns) == 0 && len(r.MissingIndexes) == 0
+}
+
+func (r *SchemaReport) Error() string {
+	var b strings.Builder
+	b.WriteString("database schema does not match this build:")
+	if r.Version != r.ExpectedVersion {
+		fmt.Fprintf(&b, "\n  schema version is %d, expected %d", r.Version, r.ExpectedVersion)
+	}
+	for _, table := range r.MissingTables {
+		fmt.Fprintf(&b, "\n  missing table %s", table)
+	}
+	for _, column := range r.MissingColumns {
+		fmt.Fprintf(&b, "\n  missing column %s", column)
+	}
+	for _, index := range r.MissingIndexes {
+		fmt.Fprintf(&b, "\n  missing index on %s", index)
+	}
+	return b.String()
+}
+
+func ValidateSchema(ctx context.Context, db *pgxpool.Pool) error {
+	report, err := InspectSchema(ctx, db)
+	if err != nil {
+		return err
+	}
+	if !report.OK() {
+		return report
+	}
+	return nil
+}
+
+func InspectSchema(ctx context.Context, db *pgxpool.Pool) (*SchemaReport, error) {
+	report := &SchemaReport{ExpectedVersion: ExpectedSchemaVersion}
+
+	columns, err := loadColumns(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	tables := make([]string, 0, len(requiredColumns))
+	for table := range requiredColumns {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	for _, table := range tables {
+		existing, ok := columns[table]
+		if !ok {
+			report.MissingTables = append(report.MissingTables, table)
+			continue
+		}
+		for _, column := range requiredColumns[table] {
+			if !existing[column] {
+				report.MissingColumns = append(report.MissingColumns, table+"."+column)
+			}
+		}
+	}
+
+	for _, idx := range requiredIndexes {
+		if _, ok := columns[idx.table]; !ok {
+			continue
+		}
+		found, err := hasIndex(ctx, db, idx.table, idx.columns)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			report.MissingIndexes = append(report.MissingIndexes, fmt.Sprintf("%s(%s)", idx.table, strings.Join(idx.columns, ", ")))
+		}
+	}
+
+	if _, ok := columns["schema_migrations"]; ok {
+		err := db.QueryRow(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&report.Version)
+		if err != nil {
+			return nil, fmt.Errorf("error reading schema version: %w", err)
+		}
+	}
+	return report, nil
+}
+
+func loadColumns(ctx context.Context, db *pgxpool.Pool) (map[string]map[string]bool, error) {
+	rows, err := db.Query(ctx, `SELECT table_name, column_name FROM information_schema.columns WHERE table_schema = current_schema()`)
+	if err != nil {
+		return nil, fmt.Errorf("error reading schema columns: %w", err)
+	}
+	defer rows.Close()
+
+	columns := make(map[string]map[string]bool)
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			return nil, fmt.Errorf("error scanning schema column: %w", err)
+		}
+		if _, ok := columns[table]; !ok {
+			columns[table] = make(map[string]bool)
+		}
+		columns[table][column] = true
+	}
+	return columns, rows.Err()
+}
+
+func hasIndex(ctx context.Context, db *pgxpool.Pool, table string, want []string) (bool, error) {
+	query := `
+		SELECT array_agg(a.attname::text ORDER BY k.ord)
+		FROM pg_index i
+		JOIN pg_class t ON t.oid = i.indrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		CROSS JOIN LATERAL unnest(i.indkey) WITH ORDINALITY AS k(attnum, ord)
+		JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+		WHERE t.relname = $1 AND n.nspname = current_schema()
+		GROUP BY i.indexrelid
+	`
+	rows, err := db.Query(ctx, query, table)
+	if err != nil {
+		return false, fmt.Errorf("error reading indexes for %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var columns []string
+		if err := rows.Scan(&columns); err != nil {
+			return false, fmt.Errorf("error scanning index for %s: %w", table, err)
+		}
+		if len(columns) < len(want) {
+			continue
+		}
+		match := true
+		for i, column := range want {
+			if columns[i] != column {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
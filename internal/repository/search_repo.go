@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SearchBackfillRepository interface {
+	GetSearchCursor(ctx context.Context, tenant string) (int64, error)
+	SaveSearchCursor(ctx context.Context, tenant string, lastMessageID int64) error
+}
+
+type postgresSearchBackfillRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewSearchBackfillRepository(db *pgxpool.Pool) SearchBackfillRepository {
+	return &postgresSearchBackfillRepository{db: db}
+}
+
+func (r *postgresSearchBackfillRepository) GetSearchCursor(ctx context.Context, tenant string) (int64, error) {
+	var lastMessageID int64
+	err := r.db.QueryRow(ctx, `SELECT last_message_id FROM search_backfill_cursors WHERE tenant = $1`, tenant).Scan(&lastMessageID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error getting search backfill cursor for tenant %q: %w", tenant, err)
+	}
+	return lastMessageID, nil
+}
+
+func (r *postgresSearchBackfillRepository) SaveSearchCursor(ctx context.Context, tenant string, lastMessageID int64) error {
+	query := `
+		INSERT INTO search_backfill_cursors (tenant, last_message_id) VALUES ($1, $2)
+		ON CONFLICT (tenant) DO UPDATE SET last_message_id = EXCLUDED.last_message_id, updated_at = NOW()
+	`
+	if _, err := r.db.Exec(ctx, query, tenant, lastMessageID); err != nil {
+		return fmt.Errorf("error saving search backfill cursor for tenant %q: %w", tenant, err)
+	}
+	return nil
+}
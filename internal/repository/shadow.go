@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync/atomic"
+
+	"chatservice/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type ShadowMode string
+
+const (
+	ShadowOff    ShadowMode = "off"
+	ShadowWrite  ShadowMode = "write"
+	ShadowVerify ShadowMode = "verify"
+)
+
+const ShadowRoomMessageCounters = "room_message_counters"
+
+var shadowStats = map[string]*shadowCounters{
+	ShadowRoomMessageCounters: {},
+}
+
+type shadowCounters struct {
+	mode        atomic.Value
+	writes      atomic.Int64
+	writeErrors atomic.Int64
+	reads       atomic.Int64
+	readErrors  atomic.Int64
+	missing     atomic.Int64
+	mismatches  atomic.Int64
+}
+
+type ShadowTargetStats struct {
+	Mode        ShadowMode `json:"mode"`
+	Writes      int64      `json:"writes"`
+	WriteErrors int64      `json:"writeErrors"`
+	Reads       int64      `json:"reads"`
+	ReadErrors  int64      `json:"readErrors"`
+	Missing     int64      `json:"missing"`
+	Mismatches  int64      `json:"mismatches"`
+	Divergence  float64    `json:"divergence"`
+}
+
+func ShadowStats() map[string]ShadowTargetStats {
+	stats := make(map[string]ShadowTargetStats, len(shadowStats))
+	for target, counters := range shadowStats {
+		mode, _ := counters.mode.Load().(ShadowMode)
+		if mode == "" {
+			mode = ShadowOff
+		}
+		s := ShadowTargetStats{
+			Mode:        mode,
+			Writes:      counters.writes.Load(),
+			WriteErrors: counters.writeErrors.Load(),
+			Reads:       counters.reads.Load(),
+			ReadErrors:  counters.readErrors.Load(),
+			Missing:     counters.missing.Load(),
+			Mismatches:  counters.mismatches.Load(),
+		}
+		if s.Reads > 0 {
+			s.Divergence = float64(s.Mismatches) / float64(s.Reads)
+		}
+		stats[target] = s
+	}
+	return stats
+}
+
+type ShadowRepository struct {
+	AppRepository
+
+	db    *ClusterResolver
+	modes map[string]ShadowMode
+}
+
+func NewShadowRepository(repo AppRepository, db *ClusterResolver, modes map[string]string) (*ShadowRepository, error) {
+	s := &ShadowRepository{AppRepository: repo, db: db, modes: make(map[string]ShadowMode, len(modes))}
+	for target, value := range modes {
+		counters, ok := shadowStats[target]
+		if !ok {
+			return nil, fmt.Errorf("unknown shadow target %q", target)
+		}
+		mode := ShadowMode(value)
+		if !slices.Contains([]ShadowMode{ShadowOff, ShadowWrite, ShadowVerify}, mode) {
+			return nil, fmt.Errorf("shadow target %s has invalid mode %q", target, value)
+		}
+		s.modes[target] = mode
+		counters.mode.Store(mode)
+	}
+	return s, nil
+}
+
+func (s *ShadowRepository) writes(target string) bool {
+	return s.modes[target] == ShadowWrite || s.modes[target] == ShadowVerify
+}
+
+func (s *ShadowRepository) verifies(target string) bool {
+	return s.modes[target] == ShadowVerify
+}
+
+func (s *ShadowRepository) shadowWrite(target string, err error) {
+	counters := shadowStats[target]
+	counters.writes.Add(1)
+	if err != nil {
+		counters.writeErrors.Add(1)
+		repoLog.Errorf("Shadow write to %s failed: %v", target, err)
+	}
+}
+
+func (s *ShadowRepository) shadowRead(target, key string, primary, shadow int64, found bool, err error) {
+	counters := shadowStats[target]
+	counters.reads.Add(1)
+	switch {
+	case err != nil:
+		counters.readErrors.Add(1)
+		repoLog.Errorf("Shadow read from %s failed for %s: %v", target, key, err)
+	case !found:
+		counters.missing.Add(1)
+	case primary != shadow:
+		counters.mismatches.Add(1)
+		repoLog.Warnf("Shadow divergence in %s for %s: primary=%d shadow=%d", target, key, primary, shadow)
+	}
+}
+
+func (s *ShadowRepository) CreateMessage(ctx context.Context, msg *domain.Message) (*domain.Message, error) {
+	created, err := s.AppRepository.CreateMessage(ctx, msg)
+	if err == nil && s.writes(ShadowRoomMessageCounters) {
+		s.shadowWrite(ShadowRoomMessageCounters, adjustMessageCounter(ctx, s.db.Pool(ctx), created.RoomID, 1))
+	}
+	return created, err
+}
+
+func (s *ShadowRepository) DeleteMessage(ctx context.Context, messageID int64, userID uuid.UUID) error {
+	if !s.writes(ShadowRoomMessageCounters) {
+		return s.AppRepository.DeleteMessage(ctx, messageID, userID)
+	}
+	var roomID uuid.UUID
+	query := `SELECT room_id FROM messages WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`
+	lookupErr := s.db.Pool(ctx).QueryRow(ctx, query, messageID, userID).Scan(&roomID)
+	if err := s.AppRepository.DeleteMessage(ctx, messageID, userID); err != nil {
+		return err
+	}
+	if lookupErr != nil {
+		s.shadowWrite(ShadowRoomMessageCounters, fmt.Errorf("could not resolve room of message %d: %w", messageID, lookupErr))
+		return nil
+	}
+	s.shadowWrite(ShadowRoomMessageCounters, adjustMessageCounter(ctx, s.db.Pool(ctx), roomID, -1))
+	return nil
+}
+
+func (s *ShadowRepository) CopyRecentMessages(ctx context.Context, tx pgx.Tx, fromRoomID, toRoomID, authorID uuid.UUID, limit int) (int64, error) {
+	copied, err := s.AppRepository.CopyRecentMessages(ctx, tx, fromRoomID, toRoomID, authorID, limit)
+	if err != nil || copied == 0 || !s.writes(ShadowRoomMessageCounters) {
+		return copied, err
+	}
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		s.shadowWrite(ShadowRoomMessageCounters, err)
+		return copied, nil
+	}
+	if err := adjustMessageCounter(ctx, savepoint, toRoomID, copied); err != nil {
+		savepoint.Rollback(ctx)
+		s.shadowWrite(ShadowRoomMessageCounters, err)
+		return copied, nil
+	}
+	s.shadowWrite(ShadowRoomMessageCounters, savepoint.Commit(ctx))
+	return copied, nil
+}
+
+func (s *ShadowRepository) GetRoomCounts(ctx context.Context, roomID uuid.UUID) (int, int, error) {
+	members, messages, err := s.AppRepository.GetRoomCounts(ctx, roomID)
+	if err != nil || !s.verifies(ShadowRoomMessageCounters) {
+		return members, messages, err
+	}
+	var shadow int64
+	readErr := s.db.Pool(ctx).QueryRow(ctx, `SELECT message_count FROM room_message_counters WHERE room_id = $1`, roomID).Scan(&shadow)
+	found := readErr == nil
+	if errors.Is(readErr, pgx.ErrNoRows) {
+		readErr = nil
+	}
+	s.shadowRead(ShadowRoomMessageCounters, roomID.String(), int64(messages), shadow, found, readErr)
+	return members, messages, nil
+}
+
+type shadowExecer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+func adjustMessageCounter(ctx context.Context, db shadowExecer, roomID uuid.UUID, delta int64) error {
+	query := `UPDATE room_message_counters SET message_count = message_count + $2, updated_at = NOW() WHERE room_id = $1`
+	tag, err := db.Exec(ctx, query, roomID, delta)
+	if err != nil {
+		return fmt.Errorf("error adjusting message counter of room %s: %w", roomID, err)
+	}
+	if tag.RowsAffected() > 0 {
+		return nil
+	}
+	seed := `
+		INSERT INTO room_message_counters (room_id, message_count)
+		SELECT $1, COUNT(*) FROM messages WHERE room_id = $1 AND deleted_at IS NULL
+		ON CONFLICT (room_id) DO NOTHING
+	`
+	if _, err := db.Exec(ctx, seed, roomID); err != nil {
+		return fmt.Errorf("error seeding message counter of room %s: %w", roomID, err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var statementStats = &statementTracer{}
+
+type statementTracer struct {
+	queries  atomic.Int64
+	prepares atomic.Int64
+}
+
+func (t *statementTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryStartData) context.Context {
+	t.queries.Add(1)
+	return ctx
+}
+
+func (t *statementTracer) TraceQueryEnd(context.Context, *pgx.Conn, pgx.TraceQueryEndData) {}
+
+func (t *statementTracer) TracePrepareStart(ctx context.Context, _ *pgx.Conn, _ pgx.TracePrepareStartData) context.Context {
+	return ctx
+}
+
+func (t *statementTracer) TracePrepareEnd(_ context.Context, _ *pgx.Conn, data pgx.TracePrepareEndData) {
+	if data.Err == nil && !data.AlreadyPrepared {
+		t.prepares.Add(1)
+	}
+}
+
+type StatementCacheStats struct {
+	Queries   int64   `json:"queries"`
+	Prepares  int64   `json:"prepares"`
+	CacheHits int64   `json:"cacheHits"`
+	HitRatio  float64 `json:"hitRatio"`
+}
+
+type PoolStats struct {
+	TotalConns      int32 `json:"totalConns"`
+	IdleConns       int32 `json:"idleConns"`
+	AcquiredConns   int32 `json:"acquiredConns"`
+	MaxConns        int32 `json:"maxConns"`
+	AcquireCount    int64 `json:"acquireCount"`
+	EmptyAcquires   int64 `json:"emptyAcquireCount"`
+	AcquireDuration int64 `json:"acquireDurationMs"`
+}
+
+func StatementStats() StatementCacheStats {
+	queries, prepares := statementStats.queries.Load(), statementStats.prepares.Load()
+	stats := StatementCacheStats{Queries: queries, Prepares: prepares}
+	if hits := queries - prepares; hits > 0 {
+		stats.CacheHits = hits
+		stats.HitRatio = float64(hits) / float64(queries)
+	}
+	return stats
+}
+
+func poolStats(pool *pgxpool.Pool) PoolStats {
+	s := pool.Stat()
+	return PoolStats{
+		TotalConns:      s.TotalConns(),
+		IdleConns:       s.IdleConns(),
+		AcquiredConns:   s.AcquiredConns(),
+		MaxConns:        s.MaxConns(),
+		AcquireCount:    s.AcquireCount(),
+		EmptyAcquires:   s.EmptyAcquireCount(),
+		AcquireDuration: s.AcquireDuration().Milliseconds(),
+	}
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"container/list"
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/events"
+
+	"github.com/google/uuid"
+)
+
+var userCacheStats = &userCacheCounters{}
+
+type userCacheCounters struct {
+	hits          atomic.Int64
+	misses        atomic.Int64
+	invalidations atomic.Int64
+	size          atomic.Int64
+}
+
+type UserCacheStats struct {
+	Size          int64   `json:"size"`
+	Hits          int64   `json:"hits"`
+	Misses        int64   `json:"misses"`
+	Invalidations int64   `json:"invalidations"`
+	HitRatio      float64 `json:"hitRatio"`
+}
+
+func UserLookupStats() UserCacheStats {
+	stats := UserCacheStats{
+		Size:          userCacheStats.size.Load(),
+		Hits:          userCacheStats.hits.Load(),
+		Misses:        userCacheStats.misses.Load(),
+		Invalidations: userCacheStats.invalidations.Load(),
+	}
+	if lookups := stats.Hits + stats.Misses; lookups > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(lookups)
+	}
+	return stats
+}
+
+type cachedUser struct {
+	user      domain.User
+	expiresAt time.Time
+}
+
+type UserCache struct {
+	AppRepository
+
+	capacity int
+	ttl      time.Duration
+
+	mu         sync.Mutex
+	entries    map[uuid.UUID]*list.Element
+	order      *list.List
+	generation uint64
+}
+
+func NewUserCache(repo AppRepository, capacity int, ttl time.Duration) *UserCache {
+	return &UserCache{
+		AppRepository: repo,
+		capacity:      capacity,
+		ttl:           ttl,
+		entries:       make(map[uuid.UUID]*list.Element),
+		order:         list.New(),
+	}
+}
+
+func (c *UserCache) GetUserByID(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+	c.mu.Lock()
+	if elem, ok := c.entries[id]; ok {
+		entry := elem.Value.(*cachedUser)
+		if time.Now().Before(entry.expiresAt) {
+			c.order.MoveToFront(elem)
+			user := cloneUser(entry.user)
+			c.mu.Unlock()
+			userCacheStats.hits.Add(1)
+			return &user, nil
+		}
+		c.remove(elem)
+	}
+	generation := c.generation
+	c.mu.Unlock()
+	userCacheStats.misses.Add(1)
+
+	user, err := c.AppRepository.GetUserByID(ctx, id)
+	if err != nil || user == nil {
+		return user, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation == generation {
+		c.store(*user)
+	}
+	return user, nil
+}
+
+func (c *UserCache) UpsertUser(ctx context.Context, id uuid.UUID, email, username, nickname *string) error {
+	defer c.Invalidate(id)
+	return c.AppRepository.UpsertUser(ctx, id, email, username, nickname)
+}
+
+func (c *UserCache) SetUserBadges(ctx context.Context, userID uuid.UUID, badges []string) (bool, error) {
+	defer c.Invalidate(userID)
+	return c.AppRepository.SetUserBadges(ctx, userID, badges)
+}
+
+func (c *UserCache) HandleEvent(_ context.Context, event events.Event) {
+	switch e := event.(type) {
+	case events.UserProfileUpdated:
+		c.Invalidate(e.User.ID)
+	case events.GuestMerged:
+		// The guest's user row is gone once the merge commits.
+		c.Invalidate(e.Merge.GuestID)
+	}
+}
+
+func (c *UserCache) Invalidate(id uuid.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	if elem, ok := c.entries[id]; ok {
+		c.remove(elem)
+		userCacheStats.invalidations.Add(1)
+	}
+}
+
+func (c *UserCache) store(user domain.User) {
+	entry := &cachedUser{user: cloneUser(user), expiresAt: time.Now().Add(c.ttl)}
+	if elem, ok := c.entries[user.ID]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[user.ID] = c.order.PushFront(entry)
+	userCacheStats.size.Add(1)
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *UserCache) remove(elem *list.Element) {
+	delete(c.entries, elem.Value.(*cachedUser).user.ID)
+	c.order.Remove(elem)
+	userCacheStats.size.Add(-1)
+}
+
+func cloneUser(user domain.User) domain.User {
+	user.Badges = slices.Clone(user.Badges)
+	return user
+}
@@ -0,0 +1,129 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+const minLease = time.Minute
+
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Timeout  time.Duration
+	Run      func(ctx context.Context) error
+}
+
+type Scheduler struct {
+	repo  repository.JobRepository
+	owner string
+	poll  time.Duration
+
+	mu   sync.Mutex
+	jobs []Job
+}
+
+func New(repo repository.JobRepository, owner string, poll time.Duration) *Scheduler {
+	if owner == "" {
+		owner = uuid.NewString()
+	}
+	if poll <= 0 {
+		poll = 10 * time.Second
+	}
+	return &Scheduler{repo: repo, owner: owner, poll: poll}
+}
+
+func (s *Scheduler) Register(job Job) {
+	if job.Interval < time.Second {
+		log.Printf("Scheduler ignoring job %s with interval %s", job.Name, job.Interval)
+		return
+	}
+	if job.Timeout <= 0 {
+		job.Timeout = max(job.Interval, minLease)
+	}
+	s.mu.Lock()
+	s.jobs = append(s.jobs, job)
+	s.mu.Unlock()
+}
+
+func (s *Scheduler) Jobs(ctx context.Context) ([]domain.JobStatus, error) {
+	return s.repo.ListJobs(ctx)
+}
+
+func (s *Scheduler) Run(ctx context.Context) {
+	s.mu.Lock()
+	jobs := append([]Job(nil), s.jobs...)
+	s.mu.Unlock()
+
+	for _, job := range jobs {
+		if err := s.repo.RegisterJob(ctx, job.Name, job.Interval); err != nil {
+			log.Printf("Scheduler could not register job %s: %v", job.Name, err)
+		}
+	}
+
+	ticker := time.NewTicker(s.poll)
+	defer ticker.Stop()
+	for {
+		for _, job := range jobs {
+			s.tryRun(ctx, job)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) tryRun(ctx context.Context, job Job) {
+	claimed, err := s.repo.ClaimJob(ctx, job.Name, s.owner, job.Timeout)
+	if err != nil {
+		log.Printf("Scheduler could not claim job %s: %v", job.Name, err)
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	release, locked, err := s.repo.TryLock(ctx, job.Name)
+	if err != nil || !locked {
+		runErr := err
+		if runErr == nil {
+			runErr = fmt.Errorf("job %s is still running elsewhere", job.Name)
+		}
+		log.Printf("Scheduler skipped job %s: %v", job.Name, runErr)
+		if err := s.repo.FinishJob(context.WithoutCancel(ctx), job.Name, s.owner, job.Interval, runErr); err != nil {
+			log.Printf("Scheduler could not record run of job %s: %v", job.Name, err)
+		}
+		return
+	}
+	defer release()
+
+	started := time.Now()
+	runErr := s.execute(ctx, job)
+	if runErr != nil {
+		log.Printf("Scheduled job %s failed after %s: %v", job.Name, time.Since(started).Round(time.Millisecond), runErr)
+	}
+	if err := s.repo.FinishJob(context.WithoutCancel(ctx), job.Name, s.owner, job.Interval, runErr); err != nil {
+		log.Printf("Scheduler could not record run of job %s: %v", job.Name, err)
+	}
+}
+
+func (s *Scheduler) execute(ctx context.Context, job Job) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, job.Timeout)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return job.Run(ctx)
+}
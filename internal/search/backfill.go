@@ -0,0 +1,71 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/repository"
+	"chatservice/internal/scheduler"
+	"chatservice/internal/tenant"
+)
+
+const backfillBatch = 500
+
+type MessageSource interface {
+	GetMessagesAfter(ctx context.Context, afterID int64, limit int) ([]domain.Message, error)
+}
+
+type Backfill struct {
+	indexer *Indexer
+	source  MessageSource
+	cursors repository.SearchBackfillRepository
+	tenants []string
+}
+
+func NewBackfill(indexer *Indexer, source MessageSource, cursors repository.SearchBackfillRepository, tenants []string) *Backfill {
+	return &Backfill{indexer: indexer, source: source, cursors: cursors, tenants: tenants}
+}
+
+func (b *Backfill) Job(interval time.Duration) scheduler.Job {
+	return scheduler.Job{Name: "search-backfill", Interval: interval, Run: b.run}
+}
+
+func (b *Backfill) run(ctx context.Context) error {
+	var errs []error
+	for _, tenantID := range b.tenants {
+		if err := b.backfill(tenant.WithTenant(ctx, tenantID), tenantID); err != nil {
+			errs = append(errs, fmt.Errorf("tenant %q: %w", tenantID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (b *Backfill) backfill(ctx context.Context, tenantID string) error {
+	cursor, err := b.cursors.GetSearchCursor(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	if missed, ok := b.indexer.takeMissed(tenantID); ok && missed <= cursor {
+		cursor = missed - 1
+	}
+	messages, err := b.source.GetMessagesAfter(ctx, cursor, backfillBatch)
+	if err != nil {
+		return err
+	}
+	indexed := cursor
+	var indexErr error
+	for _, msg := range messages {
+		if indexErr = b.indexer.backend.Index(ctx, document(tenantID, msg)); indexErr != nil {
+			break
+		}
+		indexed = msg.ID
+	}
+	if indexed != cursor {
+		log.Printf("Search backfill indexed messages up to %d for tenant %q", indexed, tenantID)
+	}
+	return errors.Join(indexErr, b.cursors.SaveSearchCursor(ctx, tenantID, indexed))
+}
@@ -0,0 +1,106 @@
+package search
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/events"
+	"chatservice/internal/tenant"
+)
+
+type indexJob struct {
+	name      string
+	tenant    string
+	messageID int64
+	run       func(ctx context.Context) error
+}
+
+type Indexer struct {
+	backend Backend
+	queue   chan indexJob
+
+	mu     sync.Mutex
+	missed map[string]int64
+}
+
+func NewIndexer(backend Backend, queueSize int) *Indexer {
+	return &Indexer{backend: backend, queue: make(chan indexJob, queueSize), missed: make(map[string]int64)}
+}
+
+func (ix *Indexer) Start(ctx context.Context, workers int) {
+	for range max(workers, 1) {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-ix.queue:
+					if err := job.run(ctx); err != nil {
+						log.Printf("Search indexing failed for %s: %v", job.name, err)
+						ix.miss(job)
+					}
+				}
+			}
+		}()
+	}
+}
+
+func (ix *Indexer) HandleEvent(ctx context.Context, event events.Event) {
+	tenantID := tenant.FromContext(ctx)
+	switch e := event.(type) {
+	case events.MessageCreated:
+		doc := document(tenantID, e.Message)
+		ix.enqueue(indexJob{name: event.EventName(), tenant: tenantID, messageID: e.Message.ID, run: func(ctx context.Context) error {
+			return ix.backend.Index(ctx, doc)
+		}})
+	case events.MessageEdited:
+		ix.enqueue(indexJob{name: event.EventName(), run: func(ctx context.Context) error {
+			return ix.backend.UpdateContent(ctx, tenantID, e.MessageID, e.Content)
+		}})
+	case events.MessageDeleted:
+		ix.enqueue(indexJob{name: event.EventName(), run: func(ctx context.Context) error {
+			return ix.backend.Delete(ctx, tenantID, e.MessageID)
+		}})
+	}
+}
+
+func (ix *Indexer) enqueue(job indexJob) {
+	select {
+	case ix.queue <- job:
+	default:
+		log.Printf("Search index queue full, dropping %s", job.name)
+		ix.miss(job)
+	}
+}
+
+func (ix *Indexer) miss(job indexJob) {
+	if job.messageID == 0 {
+		return
+	}
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if earliest, ok := ix.missed[job.tenant]; !ok || job.messageID < earliest {
+		ix.missed[job.tenant] = job.messageID
+	}
+}
+
+func (ix *Indexer) takeMissed(tenantID string) (int64, bool) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	earliest, ok := ix.missed[tenantID]
+	delete(ix.missed, tenantID)
+	return earliest, ok
+}
+
+func document(tenantID string, msg domain.Message) Document {
+	return Document{
+		Tenant:    tenantID,
+		MessageID: msg.ID,
+		RoomID:    msg.RoomID,
+		UserID:    msg.UserID,
+		Content:   msg.Content,
+		CreatedAt: msg.CreatedAt,
+	}
+}
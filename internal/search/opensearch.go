@@ -0,0 +1,181 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var indexMapping = map[string]any{
+	"mappings": map[string]any{
+		"properties": map[string]any{
+			"tenant":     map[string]any{"type": "keyword"},
+			"message_id": map[string]any{"type": "long"},
+			"room_id":    map[string]any{"type": "keyword"},
+			"user_id":    map[string]any{"type": "keyword"},
+			"content":    map[string]any{"type": "text"},
+			"created_at": map[string]any{"type": "date"},
+		},
+	},
+}
+
+type OpenSearch struct {
+	baseURL  string
+	index    string
+	username string
+	password string
+	client   *http.Client
+}
+
+func NewOpenSearch(baseURL, index, username, password string) *OpenSearch {
+	if baseURL == "" {
+		return nil
+	}
+	return &OpenSearch{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		index:    index,
+		username: username,
+		password: password,
+		client:   &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (o *OpenSearch) EnsureIndex(ctx context.Context) error {
+	status, _, err := o.do(ctx, http.MethodHead, "", nil)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusOK {
+		return nil
+	}
+	status, body, err := o.do(ctx, http.MethodPut, "", indexMapping)
+	if err != nil {
+		return err
+	}
+	if status >= 300 && !bytes.Contains(body, []byte("resource_already_exists_exception")) {
+		return fmt.Errorf("opensearch returned status %d creating index %s", status, o.index)
+	}
+	return nil
+}
+
+func (o *OpenSearch) Index(ctx context.Context, doc Document) error {
+	return o.expect(o.do(ctx, http.MethodPut, "/_doc/"+documentID(doc.Tenant, doc.MessageID), doc))
+}
+
+func (o *OpenSearch) UpdateContent(ctx context.Context, tenant string, messageID int64, content string) error {
+	update := map[string]any{"doc": map[string]any{"content": content}}
+	status, body, err := o.do(ctx, http.MethodPost, "/_update/"+documentID(tenant, messageID), update)
+	if status == http.StatusNotFound {
+		return nil
+	}
+	return o.expect(status, body, err)
+}
+
+func (o *OpenSearch) Delete(ctx context.Context, tenant string, messageID int64) error {
+	status, body, err := o.do(ctx, http.MethodDelete, "/_doc/"+documentID(tenant, messageID), nil)
+	if status == http.StatusNotFound {
+		return nil
+	}
+	return o.expect(status, body, err)
+}
+
+func (o *OpenSearch) Search(ctx context.Context, query Query) ([]int64, error) {
+	rooms := make([]string, len(query.RoomIDs))
+	for i, id := range query.RoomIDs {
+		rooms[i] = id.String()
+	}
+	request := map[string]any{
+		"size":    query.Limit,
+		"_source": []string{"message_id"},
+		"query": map[string]any{
+			"bool": map[string]any{
+				"must": map[string]any{
+					"match": map[string]any{"content": map[string]any{"query": query.Text, "operator": "and"}},
+				},
+				"filter": []any{
+					map[string]any{"term": map[string]any{"tenant": query.Tenant}},
+					map[string]any{"terms": map[string]any{"room_id": rooms}},
+				},
+			},
+		},
+	}
+	status, body, err := o.do(ctx, http.MethodPost, "/_search", request)
+	if err := o.expect(status, body, err); err != nil {
+		return nil, err
+	}
+
+	var decoded struct {
+		Hits struct {
+			Hits []struct {
+				Source struct {
+					MessageID int64 `json:"message_id"`
+				} `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		return nil, fmt.Errorf("malformed opensearch response: %w", err)
+	}
+	ids := make([]int64, 0, len(decoded.Hits.Hits))
+	for _, hit := range decoded.Hits.Hits {
+		ids = append(ids, hit.Source.MessageID)
+	}
+	return ids, nil
+}
+
+func (o *OpenSearch) do(ctx context.Context, method, path string, payload any) (int, []byte, error) {
+	var reader io.Reader
+	if payload != nil {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return 0, nil, err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, o.baseURL+"/"+url.PathEscape(o.index)+path, reader)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid opensearch URL: %w", err)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if o.username != "" {
+		req.SetBasicAuth(o.username, o.password)
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("error contacting opensearch: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return resp.StatusCode, nil, fmt.Errorf("error reading opensearch response: %w", err)
+	}
+	return resp.StatusCode, body, nil
+}
+
+func (o *OpenSearch) expect(status int, body []byte, err error) error {
+	if err != nil {
+		return err
+	}
+	if status >= 300 {
+		return fmt.Errorf("opensearch returned status %d: %s", status, bytes.TrimSpace(body[:min(len(body), 256)]))
+	}
+	return nil
+}
+
+func documentID(tenant string, messageID int64) string {
+	id := strconv.FormatInt(messageID, 10)
+	if tenant == "" {
+		return id
+	}
+	return url.PathEscape(tenant) + "_" + id
+}
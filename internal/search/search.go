@@ -0,0 +1,31 @@
+package search
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Document struct {
+	Tenant    string    `json:"tenant"`
+	MessageID int64     `json:"message_id"`
+	RoomID    uuid.UUID `json:"room_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Query struct {
+	Tenant  string
+	RoomIDs []uuid.UUID
+	Text    string
+	Limit   int
+}
+
+type Backend interface {
+	Index(ctx context.Context, doc Document) error
+	UpdateContent(ctx context.Context, tenant string, messageID int64, content string) error
+	Delete(ctx context.Context, tenant string, messageID int64) error
+	Search(ctx context.Context, query Query) ([]int64, error)
+}
@@ -0,0 +1,204 @@
+package sfu
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotConfigured    = errors.New("SFU integration is not configured")
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrNoRecordingAPI   = errors.New("SFU recording API is not configured")
+)
+
+const (
+	EventParticipantJoined = "participant_joined"
+	EventParticipantLeft   = "participant_left"
+	EventRoomFinished      = "room_finished"
+	EventRecordingStarted  = "recording_started"
+	EventRecordingFinished = "recording_finished"
+)
+
+type Config struct {
+	URL           string
+	APIKey        string
+	APISecret     string
+	WebhookSecret string
+	TokenTTL      time.Duration
+	APIURL        string
+}
+
+type Client struct {
+	cfg        Config
+	httpClient *http.Client
+}
+
+type JoinToken struct {
+	Token     string    `json:"token"`
+	URL       string    `json:"url"`
+	Room      string    `json:"room"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type Recording struct {
+	ID          uuid.UUID `json:"id"`
+	URL         string    `json:"url"`
+	ContentType string    `json:"contentType"`
+	Size        int64     `json:"size"`
+}
+
+type WebhookEvent struct {
+	Event       string     `json:"event"`
+	Room        uuid.UUID  `json:"room"`
+	Participant uuid.UUID  `json:"participant"`
+	Timestamp   time.Time  `json:"timestamp"`
+	Recording   *Recording `json:"recording,omitempty"`
+}
+
+func NewClient(cfg Config) *Client {
+	if cfg.URL == "" || cfg.APIKey == "" || cfg.APISecret == "" {
+		return nil
+	}
+	if cfg.TokenTTL <= 0 {
+		cfg.TokenTTL = time.Hour
+	}
+	return &Client{cfg: cfg, httpClient: &http.Client{Timeout: 10 * time.Second}}
+}
+
+type videoGrant struct {
+	Room         string `json:"room"`
+	RoomJoin     bool   `json:"roomJoin"`
+	CanPublish   bool   `json:"canPublish"`
+	CanSubscribe bool   `json:"canSubscribe"`
+	RoomRecord   bool   `json:"roomRecord,omitempty"`
+}
+
+type claims struct {
+	Issuer    string     `json:"iss"`
+	Subject   string     `json:"sub"`
+	Name      string     `json:"name,omitempty"`
+	NotBefore int64      `json:"nbf"`
+	ExpiresAt int64      `json:"exp"`
+	Video     videoGrant `json:"video"`
+}
+
+func (c *Client) MintJoinToken(userID uuid.UUID, displayName string, roomID uuid.UUID) (*JoinToken, error) {
+	if c == nil {
+		return nil, ErrNotConfigured
+	}
+	now := time.Now()
+	expiresAt := now.Add(c.cfg.TokenTTL)
+
+	token, err := c.sign(claims{
+		Issuer:    c.cfg.APIKey,
+		Subject:   userID.String(),
+		Name:      displayName,
+		NotBefore: now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+		Video: videoGrant{
+			Room:         roomID.String(),
+			RoomJoin:     true,
+			CanPublish:   true,
+			CanSubscribe: true,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &JoinToken{Token: token, URL: c.cfg.URL, Room: roomID.String(), ExpiresAt: expiresAt}, nil
+}
+
+func (c *Client) sign(cl claims) (string, error) {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	payload, err := json.Marshal(cl)
+	if err != nil {
+		return "", err
+	}
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	mac := hmac.New(sha256.New, []byte(c.cfg.APISecret))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+func (c *Client) ParseWebhook(body []byte, signature string) (*WebhookEvent, error) {
+	if c == nil || c.cfg.WebhookSecret == "" {
+		return nil, ErrNotConfigured
+	}
+	mac := hmac.New(sha256.New, []byte(c.cfg.WebhookSecret))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return nil, ErrInvalidSignature
+	}
+
+	var event WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("invalid webhook payload: %w", err)
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	return &event, nil
+}
+
+func (c *Client) StartRecording(ctx context.Context, roomID, recordingID uuid.UUID) error {
+	body, err := json.Marshal(map[string]string{"id": recordingID.String()})
+	if err != nil {
+		return err
+	}
+	return c.recordingRequest(ctx, http.MethodPost, fmt.Sprintf("/rooms/%s/recordings", roomID), roomID, body)
+}
+
+func (c *Client) StopRecording(ctx context.Context, roomID, recordingID uuid.UUID) error {
+	return c.recordingRequest(ctx, http.MethodDelete, fmt.Sprintf("/rooms/%s/recordings/%s", roomID, recordingID), roomID, nil)
+}
+
+func (c *Client) recordingRequest(ctx context.Context, method, path string, roomID uuid.UUID, body []byte) error {
+	if c == nil {
+		return ErrNotConfigured
+	}
+	if c.cfg.APIURL == "" {
+		return ErrNoRecordingAPI
+	}
+
+	now := time.Now()
+	token, err := c.sign(claims{
+		Issuer:    c.cfg.APIKey,
+		NotBefore: now.Unix(),
+		ExpiresAt: now.Add(time.Minute).Unix(),
+		Video:     videoGrant{Room: roomID.String(), RoomRecord: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(c.cfg.APIURL, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("SFU recording request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("SFU recording request returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+package tenant
+
+import "context"
+
+type contextKey struct{}
+
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	if tenantID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, contextKey{}, tenantID)
+}
+
+func FromContext(ctx context.Context) string {
+	tenantID, _ := ctx.Value(contextKey{}).(string)
+	return tenantID
+}
@@ -0,0 +1,97 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"chatservice/internal/encode"
+	"chatservice/internal/integrations"
+	"chatservice/internal/outbox"
+
+	"github.com/google/uuid"
+)
+
+const actionWebhookMetadataKey = adminRoomMetadataPrefix + "action_webhook"
+
+func (uc *AppUsecase) SetActionDispatcher(dispatcher *integrations.ActionDispatcher) {
+	uc.actions = dispatcher
+}
+
+func (uc *AppUsecase) SetOutbox(failed *outbox.Service) {
+	uc.outbox = failed
+}
+
+func (uc *AppUsecase) handleMessageAction(ctx context.Context, actorID, roomID uuid.UUID, messageID int64, actionID string) {
+	msg, err := uc.repo.GetMessageByID(ctx, messageID)
+	if err != nil || msg.RoomID != roomID {
+		uc.bcast.SendToUser(actorID, encode.EncodeError("Message not found"))
+		return
+	}
+	found := false
+	for _, action := range messageActions(msg) {
+		if action.ID == actionID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		uc.bcast.SendToUser(actorID, encode.EncodeError("Unknown message action"))
+		return
+	}
+
+	webhookURL, err := uc.actionWebhook(ctx, roomID)
+	if err != nil || webhookURL == "" {
+		uc.bcast.SendToUser(actorID, encode.EncodeError("No integration handles actions in this room"))
+		return
+	}
+
+	callback := integrations.ActionCallback{
+		MessageID: msg.ID,
+		RoomID:    roomID,
+		BotID:     msg.UserID,
+		ActionID:  actionID,
+		Actor:     integrations.Actor{ID: actorID},
+		At:        time.Now(),
+	}
+	if user, err := uc.repo.GetUserByID(ctx, actorID); err == nil && user != nil {
+		callback.Actor.Nickname = user.Nickname
+	}
+
+	go func(ctx context.Context) {
+		err := uc.actions.Dispatch(ctx, webhookURL, callback)
+		if err == nil {
+			return
+		}
+		ucLog.Errorf("Failed to deliver action %q on message %d: %v", actionID, messageID, err)
+		if uc.outbox != nil && !errors.Is(err, integrations.ErrActionsNotConfigured) {
+			uc.outbox.Record(ctx, outbox.KindActionWebhook, webhookURL, callback, err)
+			uc.bcast.SendToUser(actorID, encode.EncodeError("Action delivery is delayed and will be retried"))
+			return
+		}
+		uc.bcast.SendToUser(actorID, encode.EncodeError("Action could not be delivered"))
+	}(context.WithoutCancel(ctx))
+}
+
+func (uc *AppUsecase) actionWebhook(ctx context.Context, roomID uuid.UUID) (string, error) {
+	metadata, err := uc.repo.GetRoomMetadata(ctx, roomID)
+	if err != nil {
+		return "", err
+	}
+	var webhookURL string
+	if raw, ok := metadata[actionWebhookMetadataKey]; ok {
+		if err := json.Unmarshal(raw, &webhookURL); err != nil {
+			return "", err
+		}
+	}
+	return webhookURL, nil
+}
+
+func validWebhookURL(raw json.RawMessage) bool {
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return false
+	}
+	return integrations.ValidWebhookURL(value)
+}
@@ -2,65 +2,278 @@ package usecase
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"sort"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"chatservice/internal/attachments"
 	"chatservice/internal/domain"
+	"chatservice/internal/encode"
+	"chatservice/internal/ephemeral"
+	"chatservice/internal/events"
+	"chatservice/internal/experiments"
+	"chatservice/internal/integrations"
+	"chatservice/internal/logging"
+	"chatservice/internal/notify"
+	"chatservice/internal/outbox"
 	"chatservice/internal/repository"
+	"chatservice/internal/search"
+	"chatservice/internal/sfu"
 	"chatservice/pkg/wprotocol"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5"
 )
 
+var ucLog = logging.For("usecase")
+
+const (
+	maxBulkFriendRequests  = 100
+	defaultFriendsPageSize = 50
+	maxFriendsPageSize     = 200
+	maxSignalTargets       = 32
+)
+
+var (
+	ErrEmailTaken        = errors.New("email address is already in use")
+	ErrInvalidEmailToken = errors.New("email confirmation link is invalid or has expired")
+)
+
+type FriendRequestResult struct {
+	RequesterID uuid.UUID `json:"requesterId"`
+	Status      string    `json:"status"`
+	Error       string    `json:"error,omitempty"`
+}
+
 type FriendsList struct {
 	Friends  []domain.Friend  `json:"friends"`
 	Requests []domain.FriendRequest `json:"requests"`
+	TotalFriends int `json:"totalFriends"`
+	Limit        int `json:"limit"`
+	Offset       int `json:"offset"`
+}
+
+type MessagePage struct {
+	Messages []domain.Message `json:"messages"`
+	Limit    int              `json:"limit"`
+	Offset   int              `json:"offset"`
+}
+
+type FriendListOptions struct {
+	Sort        string
+	OnlineFirst bool
+	Limit       int
+	Offset      int
+}
+
+type Presence interface {
+	IsOnline(ctx context.Context, userID uuid.UUID) bool
 }
 
 
 type AppUsecaseInterface interface {
-	UpdateUser(ctx context.Context, id uuid.UUID, email *string, nickname *string) error
+	UpdateUser(ctx context.Context, id uuid.UUID, username, nickname *string) error
+	RequestEmailChange(ctx context.Context, userID uuid.UUID, email string) error
+	ConfirmEmailChange(ctx context.Context, token string) error
 	SendFriendRequest(ctx context.Context, senderID uuid.UUID, receiverEmail string) error
 	AcceptFriendRequest(ctx context.Context, accepterID, requesterID uuid.UUID) error
+	DeclineFriendRequest(ctx context.Context, declinerID, requesterID uuid.UUID) error
+	BulkRespondToFriendRequests(ctx context.Context, userID uuid.UUID, action string, requesterIDs []uuid.UUID) ([]FriendRequestResult, error)
 	GetRoomsForUser(ctx context.Context, userID uuid.UUID) ([]domain.Room, error)
-	GetMessagesForRoom(ctx context.Context, userID, roomID uuid.UUID, limit, offset int) ([]domain.Message, error)
+	GetRoomsChangeToken(ctx context.Context, userID uuid.UUID) (string, error)
+	GetRoomList(ctx context.Context, userID uuid.UUID, state string) (*RoomList, error)
+	SnoozeRoom(ctx context.Context, userID, roomID uuid.UUID, duration time.Duration) (time.Time, error)
+	UnsnoozeRoom(ctx context.Context, userID, roomID uuid.UUID) error
+	CreateShareLink(ctx context.Context, userID, roomID uuid.UUID, firstID, lastID int64, title string, ttl time.Duration) (*CreatedShareLink, error)
+	ListShareLinks(ctx context.Context, userID, roomID uuid.UUID) ([]domain.ShareLink, error)
+	RevokeShareLink(ctx context.Context, userID, roomID, linkID uuid.UUID) error
+	ViewSharedHistory(ctx context.Context, token, ipAddress, userAgent string) (*SharedHistory, error)
+	CreateWidgetKey(ctx context.Context, userID, roomID uuid.UUID, label string) (*CreatedWidgetKey, error)
+	ListWidgetKeys(ctx context.Context, userID, roomID uuid.UUID) ([]domain.WidgetKey, error)
+	RevokeWidgetKey(ctx context.Context, userID, roomID, keyID uuid.UUID) error
+	StartGuestSession(ctx context.Context, key, displayName, ipAddress string) (*GuestSession, error)
+	GetGuestMessages(ctx context.Context, token string, afterID int64, limit int) ([]domain.SharedMessage, error)
+	SendGuestMessage(ctx context.Context, token, content string) (*domain.Message, error)
+	MergeGuestIdentity(ctx context.Context, userID uuid.UUID, token string) (*domain.GuestMerge, error)
+	OpenSupportConversation(ctx context.Context, customerID uuid.UUID, subject string) (*domain.SupportConversation, error)
+	ListSupportQueue(ctx context.Context, agentID uuid.UUID) ([]domain.SupportConversation, error)
+	ListAgentConversations(ctx context.Context, agentID uuid.UUID) ([]domain.SupportConversation, error)
+	SetSupportAvailability(ctx context.Context, agentID uuid.UUID, available bool) error
+	ClaimSupportConversation(ctx context.Context, agentID, roomID uuid.UUID) (*domain.SupportConversation, error)
+	ReleaseSupportConversation(ctx context.Context, agentID, roomID uuid.UUID) (*domain.SupportConversation, error)
+	TransferSupportConversation(ctx context.Context, agentID, roomID, targetID uuid.UUID) (*domain.SupportConversation, error)
+	AddSupportNote(ctx context.Context, agentID, roomID uuid.UUID, content string) (*domain.SupportNote, error)
+	ListSupportNotes(ctx context.Context, agentID, roomID uuid.UUID) ([]domain.SupportNote, error)
+	ResolveSupportConversation(ctx context.Context, agentID, roomID uuid.UUID) (*domain.SupportConversation, error)
+	GetRoomStats(ctx context.Context, userID, roomID uuid.UUID) (*RoomStats, error)
+	ChangeRoomState(ctx context.Context, userID, roomID uuid.UUID, state string) (*domain.RoomStateChange, error)
+	ListLabels(ctx context.Context, userID uuid.UUID) ([]domain.Label, error)
+	CreateLabel(ctx context.Context, userID uuid.UUID, input LabelInput) (*domain.Label, error)
+	UpdateLabel(ctx context.Context, userID, labelID uuid.UUID, input LabelInput) (*domain.Label, error)
+	DeleteLabel(ctx context.Context, userID, labelID uuid.UUID) error
+	LabelMessage(ctx context.Context, userID, labelID uuid.UUID, messageID int64) error
+	UnlabelMessage(ctx context.Context, userID, labelID uuid.UUID, messageID int64) error
+	LabelRoom(ctx context.Context, userID, labelID, roomID uuid.UUID) error
+	UnlabelRoom(ctx context.Context, userID, labelID, roomID uuid.UUID) error
+	GetLabeledMessages(ctx context.Context, userID, labelID uuid.UUID, limit int) ([]domain.Message, error)
+	GetLabeledRooms(ctx context.Context, userID, labelID uuid.UUID) ([]domain.Room, error)
+	QuickSearch(ctx context.Context, userID uuid.UUID, query string, perType int) ([]domain.QuickSearchResult, error)
+	SetUserBadges(ctx context.Context, userID uuid.UUID, badges []string) (*domain.User, error)
+	HandleAuthEvent(ctx context.Context, body []byte, signature string) error
+	GetUserIdentities(ctx context.Context, viewerID, userID uuid.UUID) ([]domain.LinkedIdentity, error)
+	GetSupportSLASummary(ctx context.Context, days int) (*domain.SupportSLASummary, error)
+	ListSupportAgents(ctx context.Context) ([]domain.SupportAgent, error)
+	AddSupportAgent(ctx context.Context, userID uuid.UUID, available bool) error
+	RemoveSupportAgent(ctx context.Context, userID uuid.UUID) error
+	CreateCannedResponse(ctx context.Context, userID uuid.UUID, input CannedResponseInput) (*domain.CannedResponse, error)
+	UpdateCannedResponse(ctx context.Context, userID, id uuid.UUID, input CannedResponseInput) (*domain.CannedResponse, error)
+	DeleteCannedResponse(ctx context.Context, userID, id uuid.UUID) error
+	LookupCannedResponses(ctx context.Context, userID uuid.UUID, roomID *uuid.UUID, query string, limit int) ([]domain.CannedResponse, error)
+	RenderCannedResponse(ctx context.Context, userID, id uuid.UUID, roomID *uuid.UUID, vars map[string]string) (string, error)
+	SendCannedSupportReply(ctx context.Context, agentID, roomID, responseID uuid.UUID, vars map[string]string) (*domain.Message, error)
+	GetMessagesForRoom(ctx context.Context, userID, roomID uuid.UUID, tag string, limit, offset int) (*MessagePage, error)
+	GetRoomTags(ctx context.Context, userID, roomID uuid.UUID, limit int) ([]domain.RoomTag, error)
+	SearchMessages(ctx context.Context, userID, roomID uuid.UUID, query string, limit int) ([]domain.Message, error)
+	ModerateDeleteMessage(ctx context.Context, adminID uuid.UUID, messageID int64) error
+	GetBootstrap(ctx context.Context, userID uuid.UUID) (*Bootstrap, error)
+	GetAwayStatus(ctx context.Context, userID uuid.UUID) (*domain.AwayStatus, error)
+	SetAwayStatus(ctx context.Context, userID uuid.UUID, message string, startsAt, endsAt *time.Time) (*domain.AwayStatus, error)
+	ClearAwayStatus(ctx context.Context, userID uuid.UUID) error
+	RecordExperimentExposure(ctx context.Context, userID uuid.UUID, experiment string) (string, error)
 	ProcessIncomingPacket(ctx context.Context, senderID uuid.UUID, packet *wprotocol.Packet)
-	GetFriendsAndRequests(ctx context.Context, userID uuid.UUID) (*FriendsList, error)
+	GetFriendsAndRequests(ctx context.Context, userID uuid.UUID, opts FriendListOptions) (*FriendsList, error)
 	SearchUsers(ctx context.Context, query string, selfID uuid.UUID) ([]domain.User, error)
+	GetUserSettings(ctx context.Context, userID uuid.UUID) (*domain.UserSettings, error)
+	UpdateUserSettings(ctx context.Context, userID uuid.UUID, emailNotifications, pushPreviews, smartReplies *bool, language *string) (*domain.UserSettings, error)
+	UnsubscribeEmail(ctx context.Context, token string) error
+	CreateCallToken(ctx context.Context, userID, roomID uuid.UUID) (*sfu.JoinToken, error)
+	HandleSFUWebhook(ctx context.Context, body []byte, signature string) error
+	GetBadgeCounts(ctx context.Context, userID uuid.UUID) (*domain.BadgeCounts, error)
+	GetUserInsights(ctx context.Context, userID uuid.UUID, days int) (*domain.UserInsights, error)
+	CreateGroupFromPrivate(ctx context.Context, userID, privateRoomID uuid.UUID, name string, extraMemberIDs []uuid.UUID, importHistory int) (*GroupUpgrade, error)
+	CloneRoom(ctx context.Context, userID, roomID uuid.UUID, name string, includeMembers bool) (*RoomClone, error)
+	AddRoomMembers(ctx context.Context, inviterID, roomID uuid.UUID, userIDs []uuid.UUID) ([]MemberAddResult, error)
+	MarkRoomsRead(ctx context.Context, userID uuid.UUID, markers []RoomReadMarker) ([]RoomReadResult, error)
+	ListCallRecordings(ctx context.Context, userID, roomID uuid.UUID) ([]domain.Attachment, error)
+	GetCallRecording(ctx context.Context, userID, roomID, recordingID uuid.UUID) (*domain.Attachment, error)
+	GetRoomMetadata(ctx context.Context, userID, roomID uuid.UUID) (map[string]json.RawMessage, error)
+	GetRoomSummary(ctx context.Context, userID, roomID uuid.UUID, since *time.Time) (*domain.RoomSummary, error)
+	ReactToMessage(ctx context.Context, userID, roomID uuid.UUID, messageID int64, emoji string, add bool) error
+	GetRoomHighlights(ctx context.Context, userID, roomID uuid.UUID, days int) ([]domain.RoomHighlight, error)
+	UpdateRoomMetadata(ctx context.Context, userID, roomID uuid.UUID, changes map[string]json.RawMessage) (map[string]json.RawMessage, error)
+	GetDrafts(ctx context.Context, userID uuid.UUID) ([]domain.Draft, error)
+	GetDraft(ctx context.Context, userID, roomID uuid.UUID) (*domain.Draft, error)
+	SaveDraft(ctx context.Context, userID, roomID uuid.UUID, content string, attachmentIDs []uuid.UUID) (*domain.Draft, error)
+	DeleteDraft(ctx context.Context, userID, roomID uuid.UUID) error
+	CreateUpload(ctx context.Context, userID, roomID uuid.UUID, filename, contentType string, size int64, checksum string) (*domain.Upload, error)
+	GetUpload(ctx context.Context, userID, uploadID uuid.UUID) (*domain.Upload, error)
+	AppendUpload(ctx context.Context, userID, uploadID uuid.UUID, offset int64, chunkChecksum string, body io.Reader) (*UploadProgress, error)
+	DeleteUpload(ctx context.Context, userID, uploadID uuid.UUID) error
+	OpenAttachment(ctx context.Context, userID, attachmentID uuid.UUID) (*domain.Attachment, io.ReadSeekCloser, error)
+}
+
+type TxBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
 }
 
 type Broadcaster interface {
 	BroadcastToRoom(roomID uuid.UUID, message []byte)
 	SendToUser(userID uuid.UUID, message []byte)
 	Subscribe(clientUserID uuid.UUID, roomID uuid.UUID)
+	SetStateVersion(userID uuid.UUID, version int64)
 }
 
 type AppUsecase struct {
-	repo  repository.AppRepository
-	bcast Broadcaster
-	db    *pgxpool.Pool 
+	repo     repository.AppRepository
+	bcast    Broadcaster
+	db       TxBeginner
+	notifier *notify.Dispatcher
+	events   *events.Bus
+	sfu      *sfu.Client
+
+	presence    Presence
+	callStates  *callStateStore
+	ringTimeout time.Duration
+	ephemeral   ephemeral.Store
+	actions     *integrations.ActionDispatcher
+	authSync    *integrations.AuthSync
+	outbox      *outbox.Service
+	translator  *integrations.Translator
+	summarizer  integrations.Summarizer
+	suggestions integrations.SuggestionProvider
+	typing      typingRelay
+	guestStarts guestStartLimiter
+	pageLimits  pageLimits
+	experiments *experiments.Service
+	search      search.Backend
+	searchDown  atomic.Int64
+
+	awayCooldown time.Duration
+	shareBaseURL string
+	supportSLA   supportSLA
+	quotas       DailyQuotas
+
+	storage       *attachments.DiskStorage
+	maxUploadSize int64
+	uploadTTL     time.Duration
 }
 
-func NewAppUsecase(repo repository.AppRepository, bcast Broadcaster, db *pgxpool.Pool) AppUsecaseInterface {
+func NewAppUsecase(repo repository.AppRepository, bcast Broadcaster, db TxBeginner, notifier *notify.Dispatcher, bus *events.Bus) AppUsecaseInterface {
 	return &AppUsecase{
-		repo:  repo,
-		bcast: bcast,
-		db:    db,
+		repo:     repo,
+		bcast:    bcast,
+		db:       db,
+		notifier: notifier,
+		events:   bus,
+
+		callStates:  newCallStateStore(),
+		ringTimeout: defaultRingTimeout,
+		ephemeral:   ephemeral.NewMemoryStore(),
+		suggestions: integrations.NoopSuggestions{},
+		pageLimits:  pageLimits{defaultLimit: defaultPageLimit, maxLimit: maxPageLimit},
+
+		awayCooldown: defaultAwayReplyCooldown,
+
+		maxUploadSize: defaultMaxUploadSize,
+		uploadTTL:     defaultUploadTTL,
 	}
 }
 
 
 
-func (uc *AppUsecase) UpdateUser(ctx context.Context, id uuid.UUID, email *string, nickname *string) error {
-	return uc.repo.UpsertUser(ctx, id, email, nickname)
+func (uc *AppUsecase) UpdateUser(ctx context.Context, id uuid.UUID, username, nickname *string) error {
+	if err := uc.repo.UpsertUser(ctx, id, nil, username, nickname); err != nil {
+		return err
+	}
+	if username != nil || nickname != nil {
+		if _, err := uc.publishProfileUpdated(ctx, id); err != nil {
+			ucLog.Errorf("Failed to announce profile update for %s: %v", id, err)
+		}
+	}
+	return nil
 }
 
 
-func (uc *AppUsecase) GetFriendsAndRequests(ctx context.Context, userID uuid.UUID) (*FriendsList, error) {
-	acceptedFriendships, err := uc.repo.GetFriendshipsForUser(ctx, userID, "accepted")
+func (uc *AppUsecase) SetPresence(presence Presence) { uc.presence = presence }
+
+func (uc *AppUsecase) GetFriendsAndRequests(ctx context.Context, userID uuid.UUID, opts FriendListOptions) (*FriendsList, error) {
+	if opts.Sort != repository.FriendSortRecent {
+		opts.Sort = repository.FriendSortNickname
+	}
+	if opts.Limit <= 0 || opts.Limit > maxFriendsPageSize {
+		opts.Limit = defaultFriendsPageSize
+	}
+	if opts.Offset < 0 {
+		opts.Offset = 0
+	}
+
+	friends, err := uc.repo.GetFriends(ctx, userID, opts.Sort)
 	if err != nil {
 		return nil, fmt.Errorf("could not fetch friends: %w", err)
 	}
@@ -71,37 +284,28 @@ func (uc *AppUsecase) GetFriendsAndRequests(ctx context.Context, userID uuid.UUI
 	}
 
 	response := &FriendsList{
-		Friends:  []domain.Friend{},
-		Requests: []domain.FriendRequest{},
+		Friends:      []domain.Friend{},
+		Requests:     []domain.FriendRequest{},
+		TotalFriends: len(friends),
+		Limit:        opts.Limit,
+		Offset:       opts.Offset,
 	}
 
-	for _, fs := range acceptedFriendships {
-		var friendID uuid.UUID
-		if fs.UserOneID == userID {
-			friendID = fs.UserTwoID
-		} else {
-			friendID = fs.UserOneID
+	if opts.OnlineFirst && uc.presence != nil {
+		for i := range friends {
+			friends[i].Online = uc.presence.IsOnline(ctx, friends[i].ID)
 		}
+		sort.SliceStable(friends, func(i, j int) bool { return friends[i].Online && !friends[j].Online })
+	}
 
-		friendUser, err := uc.repo.GetUserByID(ctx, friendID)
-		if err != nil || friendUser == nil {
-			log.Printf("Warning: could not find user data for friend ID %s", friendID)
-			continue
-		}
-		
-		sharedRoomID, err := uc.repo.FindPrivateRoomByParticipants(ctx, userID, friendID)
-		if err != nil {
-			log.Printf("Error finding shared room for users %s and %s: %v", userID, friendID, err)
-		}
-		if sharedRoomID == uuid.Nil {
-			log.Printf("Warning: no shared private room found for users %s and %s", userID, friendID)
+	if opts.Offset < len(friends) {
+		page := friends[opts.Offset:min(opts.Offset+opts.Limit, len(friends))]
+		if !opts.OnlineFirst && uc.presence != nil {
+			for i := range page {
+				page[i].Online = uc.presence.IsOnline(ctx, page[i].ID)
+			}
 		}
-		
-		response.Friends = append(response.Friends, domain.Friend{
-			ID:       friendUser.ID,
-			Nickname: friendUser.Nickname,
-			RoomID:   sharedRoomID, 
-		})
+		response.Friends = append(response.Friends, page...)
 	}
 
 	for _, fs := range pendingFriendships {
@@ -110,7 +314,7 @@ func (uc *AppUsecase) GetFriendsAndRequests(ctx context.Context, userID uuid.UUI
 			
 			requester, err := uc.repo.GetUserByID(ctx, requesterID)
 			if err != nil || requester == nil {
-				log.Printf("Warning: could not find user data for requester ID %s", requesterID)
+				ucLog.Warnf("Could not find user data for requester ID %s", requesterID)
 				continue
 			}
 		
@@ -125,6 +329,66 @@ func (uc *AppUsecase) GetFriendsAndRequests(ctx context.Context, userID uuid.UUI
 	return response, nil
 }
 
+func (uc *AppUsecase) GetUserSettings(ctx context.Context, userID uuid.UUID) (*domain.UserSettings, error) {
+	return uc.repo.GetUserSettings(ctx, userID)
+}
+
+func (uc *AppUsecase) GetBadgeCounts(ctx context.Context, userID uuid.UUID) (*domain.BadgeCounts, error) {
+	return uc.repo.GetBadgeCounts(ctx, userID)
+}
+
+func (uc *AppUsecase) UpdateUserSettings(ctx context.Context, userID uuid.UUID, emailNotifications, pushPreviews, smartReplies *bool, language *string) (*domain.UserSettings, error) {
+	if language != nil && *language != "" && !validLanguage(*language) {
+		return nil, ErrInvalidLanguage
+	}
+	settings, err := uc.repo.GetUserSettings(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("could not load settings: %w", err)
+	}
+	if emailNotifications != nil {
+		settings.EmailNotifications = *emailNotifications
+	}
+	if pushPreviews != nil {
+		settings.PushPreviews = *pushPreviews
+	}
+	if smartReplies != nil {
+		settings.SmartReplies = *smartReplies
+	}
+	if language != nil {
+		settings.Language = *language
+	}
+	if err := uc.repo.UpsertUserSettings(ctx, settings); err != nil {
+		return nil, err
+	}
+	uc.bumpStateVersions(ctx, userID)
+	return settings, nil
+}
+
+func (uc *AppUsecase) RequestEmailChange(ctx context.Context, userID uuid.UUID, email string) error {
+	if existing, err := uc.repo.GetUserByEmail(ctx, email); err == nil && existing != nil && existing.ID != userID {
+		return ErrEmailTaken
+	}
+	return uc.notifier.Email().EmailChange(ctx, userID, email)
+}
+
+func (uc *AppUsecase) ConfirmEmailChange(ctx context.Context, token string) error {
+	userID, email, ok := uc.notifier.Email().VerifyEmailChangeToken(token)
+	if !ok {
+		return ErrInvalidEmailToken
+	}
+	return uc.repo.UpsertUser(ctx, userID, &email, nil, nil)
+}
+
+func (uc *AppUsecase) UnsubscribeEmail(ctx context.Context, token string) error {
+	userID, ok := uc.notifier.Email().VerifyUnsubscribeToken(token)
+	if !ok {
+		return fmt.Errorf("invalid unsubscribe token")
+	}
+	disabled := false
+	_, err := uc.UpdateUserSettings(ctx, userID, &disabled, nil, nil, nil)
+	return err
+}
+
 func (uc *AppUsecase) SearchUsers(ctx context.Context, query string, selfID uuid.UUID) ([]domain.User, error) {
 	if len(query) < 2 {
 		return []domain.User{}, nil 
@@ -154,18 +418,18 @@ func (uc *AppUsecase) SendFriendRequest(ctx context.Context, senderID uuid.UUID,
 	if existingFs != nil {
 		return fmt.Errorf("a friendship or pending request already exists with this user")
 	}
+	if err := uc.consumeQuota(ctx, senderID, quotaFriendRequests); err != nil {
+		return err
+	}
 
 	fs := domain.NewFriendship(senderID, receiver.ID, "pending", senderID)
 	if err := uc.repo.CreateFriendship(ctx, fs); err != nil {
 		return fmt.Errorf("failed to create friend request: %w", err)
 	}
 
-	senderName := sender.Nickname
-
-	notification := wprotocol.Build(wprotocol.OpFriendRequestReceived, senderID.String(), senderName)
-	uc.bcast.SendToUser(receiver.ID, notification)
+	uc.events.Publish(ctx, events.FriendRequestSent{Sender: *sender, Receiver: *receiver})
 
-	log.Printf("User %s sent friend request to user %s", senderID, receiver.ID)
+	ucLog.Infof("User %s sent friend request to user %s", senderID, receiver.ID)
 	return nil
 }
 
@@ -207,37 +471,84 @@ func (uc *AppUsecase) AcceptFriendRequest(ctx context.Context, accepterID, reque
 		return fmt.Errorf("transaction commit failed: %w", err)
 	}
 
-	accepter, _ := uc.repo.GetUserByID(ctx, accepterID)
-	accepterName := accepter.Nickname
-
-	notificationToRequester := wprotocol.Build(
-		wprotocol.OpFriendRequestAccepted,
-		accepterID.String(),
-		accepterName,
-		createdRoom.ID.String(),
-	)
-	uc.bcast.SendToUser(requesterID, notificationToRequester)
-	uc.bcast.Subscribe(requesterID, createdRoom.ID) 
-
-	notificationToAccepter := wprotocol.Build(
-		wprotocol.OpNotifyRoomAdded,
-		createdRoom.ID.String(),
-		createdRoom.Type,
-		"",
-	)
-	uc.bcast.SendToUser(accepterID, notificationToAccepter)
-	uc.bcast.Subscribe(accepterID, createdRoom.ID)
-
-	log.Printf("User %s accepted friend request from %s. Private room %s created.", accepterID, requesterID, createdRoom.ID)
+	accepter, err := uc.repo.GetUserByID(ctx, accepterID)
+	if err != nil || accepter == nil {
+		accepter = &domain.User{ID: accepterID}
+	}
+	uc.events.Publish(ctx, events.FriendshipAccepted{Accepter: *accepter, RequesterID: requesterID, Room: *createdRoom})
+
+	ucLog.Infof("User %s accepted friend request from %s. Private room %s created.", accepterID, requesterID, createdRoom.ID)
 	return nil
 }
 
+func (uc *AppUsecase) DeclineFriendRequest(ctx context.Context, declinerID, requesterID uuid.UUID) error {
+	fs, err := uc.repo.GetFriendship(ctx, declinerID, requesterID)
+	if err != nil || fs == nil {
+		return fmt.Errorf("no pending friend request found")
+	}
+	if fs.Status != "pending" || fs.ActionUserID == declinerID {
+		return fmt.Errorf("invalid friend request state")
+	}
+
+	if err := uc.repo.DeleteFriendship(ctx, declinerID, requesterID); err != nil {
+		return fmt.Errorf("failed to decline friend request: %w", err)
+	}
+
+	uc.events.Publish(ctx, events.FriendRequestDeclined{DeclinerID: declinerID, RequesterID: requesterID})
+
+	ucLog.Infof("User %s declined friend request from %s", declinerID, requesterID)
+	return nil
+}
+
+func (uc *AppUsecase) BulkRespondToFriendRequests(ctx context.Context, userID uuid.UUID, action string, requesterIDs []uuid.UUID) ([]FriendRequestResult, error) {
+	var respond func(context.Context, uuid.UUID, uuid.UUID) error
+	var done string
+	switch action {
+	case "accept":
+		respond, done = uc.AcceptFriendRequest, "accepted"
+	case "decline":
+		respond, done = uc.DeclineFriendRequest, "declined"
+	default:
+		return nil, fmt.Errorf("action must be \"accept\" or \"decline\"")
+	}
+	if len(requesterIDs) > maxBulkFriendRequests {
+		return nil, fmt.Errorf("at most %d requests can be processed at once", maxBulkFriendRequests)
+	}
+
+	results := make([]FriendRequestResult, 0, len(requesterIDs))
+	seen := make(map[uuid.UUID]bool, len(requesterIDs))
+	for _, requesterID := range requesterIDs {
+		if seen[requesterID] {
+			continue
+		}
+		seen[requesterID] = true
+
+		result := FriendRequestResult{RequesterID: requesterID, Status: done}
+		if err := respond(ctx, userID, requesterID); err != nil {
+			result.Status = "failed"
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
 
 func (uc *AppUsecase) GetRoomsForUser(ctx context.Context, userID uuid.UUID) ([]domain.Room, error) {
 	return uc.repo.GetRoomsForUser(ctx, userID)
 }
 
-func (uc *AppUsecase) GetMessagesForRoom(ctx context.Context, userID, roomID uuid.UUID, limit, offset int) ([]domain.Message, error) {
+func (uc *AppUsecase) GetRoomsChangeToken(ctx context.Context, userID uuid.UUID) (string, error) {
+	return uc.repo.GetRoomsChangeToken(ctx, userID)
+}
+
+func (uc *AppUsecase) GetMessagesForRoom(ctx context.Context, userID, roomID uuid.UUID, tag string, limit, offset int) (*MessagePage, error) {
+	if tag != "" {
+		normalized, err := normalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		tag = normalized
+	}
 	isMember, err := uc.repo.IsUserInRoom(ctx, userID, roomID)
 	if err != nil {
 		return nil, fmt.Errorf("could not verify room membership: %w", err)
@@ -245,19 +556,26 @@ func (uc *AppUsecase) GetMessagesForRoom(ctx context.Context, userID, roomID uui
 	if !isMember {
 		return nil, fmt.Errorf("user not authorized to access this room")
 	}
-	return uc.repo.GetMessagesForRoom(ctx, roomID, limit, offset)
+	limit, offset = uc.pageLimits.apply(limit, offset)
+	messages, err := uc.repo.GetMessagesForRoom(ctx, roomID, tag, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	uc.attachTranslations(ctx, userID, messages)
+	uc.attachSenderBadges(ctx, messages)
+	return &MessagePage{Messages: messages, Limit: limit, Offset: offset}, nil
 }
 
 func (uc *AppUsecase) ProcessIncomingPacket(ctx context.Context, senderID uuid.UUID, packet *wprotocol.Packet) {
 	checkMembership := func(roomID uuid.UUID) bool {
 		isMember, err := uc.repo.IsUserInRoom(ctx, senderID, roomID)
 		if err != nil {
-			log.Printf("Error checking membership for user %s in room %s: %v", senderID, roomID, err)
+			ucLog.Errorf("Error checking membership for user %s in room %s: %v", senderID, roomID, err)
 			return false
 		}
 		if !isMember {
-			log.Printf("AuthZ Error: User %s not in room %s", senderID, roomID)
-			uc.bcast.SendToUser(senderID, wprotocol.Build(wprotocol.OpError, "Not a member of this room"))
+			ucLog.Warnf("AuthZ Error: User %s not in room %s", senderID, roomID)
+			uc.bcast.SendToUser(senderID, encode.EncodeError("Not a member of this room"))
 			return false
 		}
 		return true
@@ -266,12 +584,36 @@ func (uc *AppUsecase) ProcessIncomingPacket(ctx context.Context, senderID uuid.U
 	switch packet.Op {
 	case wprotocol.OpMsgSend:
 		if len(packet.Payload) < 3 { return }
-		roomID, _ := uuid.Parse(packet.Payload[0])
-		clientMsgUID, _ := uuid.Parse(packet.Payload[1])
+		roomID, err := uuid.Parse(packet.Payload[0])
+		if err != nil { return }
+		clientMsgUID, err := uuid.Parse(packet.Payload[1])
+		if err != nil || clientMsgUID == uuid.Nil { clientMsgUID = uuid.New() }
 		content := packet.Payload[2]
+		var metadata json.RawMessage
+		if len(packet.Payload) > 3 && packet.Payload[3] != "" {
+			validated, err := validateMessageMetadata(packet.Payload[3])
+			if err != nil {
+				uc.bcast.SendToUser(senderID, encode.EncodeError(err.Error()))
+				return
+			}
+			metadata = validated
+		}
+		var contentType string
+		if len(packet.Payload) > 4 {
+			contentType = packet.Payload[4]
+		}
 		
 		if !checkMembership(roomID) { return }
-		uc.handleSendMessage(ctx, senderID, roomID, clientMsgUID, content)
+		uc.handleSendMessage(ctx, senderID, roomID, clientMsgUID, content, contentType, metadata)
+
+	case wprotocol.OpMsgAction:
+		if len(packet.Payload) < 3 { return }
+		msgID, err := strconv.ParseInt(packet.Payload[0], 10, 64)
+		if err != nil { return }
+		roomID, err := uuid.Parse(packet.Payload[1])
+		if err != nil { return }
+		if !checkMembership(roomID) { return }
+		uc.handleMessageAction(ctx, senderID, roomID, msgID, packet.Payload[2])
 
 	case wprotocol.OpMsgEdit:
 		if len(packet.Payload) < 3 { return }
@@ -296,122 +638,223 @@ func (uc *AppUsecase) ProcessIncomingPacket(ctx context.Context, senderID uuid.U
 
 	case wprotocol.OpMsgRead:
 		if len(packet.Payload) < 2 { return }
-		msgID, _ := strconv.ParseInt(packet.Payload[0], 10, 64)
-		roomID, _ := uuid.Parse(packet.Payload[1])
+		msgID, err := strconv.ParseInt(packet.Payload[0], 10, 64)
+		if err != nil { return }
+		roomID, err := uuid.Parse(packet.Payload[1])
+		if err != nil { return }
 		if !checkMembership(roomID) { return }
 		uc.handleReadMessage(ctx, msgID, senderID, roomID)
 
-    case wprotocol.OpWebRTCSignal:
-		    if len(packet.Payload) < 2 {
-        log.Printf("Invalid WebRTC signal packet from %s: insufficient payload", senderID)
-        return
-    }
-
-    roomIDStr := packet.Payload[0]
-    roomID, err := uuid.Parse(roomIDStr)
-    if err != nil {
-        log.Printf("Invalid roomID in WebRTC signal from %s: %v", senderID, err)
-        return
-    }
-
-    signalDataString := packet.Payload[1]
-
-    isMember, err := uc.repo.IsUserInRoom(ctx, senderID, roomID)
-    if err != nil || !isMember {
-        log.Printf("AuthZ Error: User %s tried to send signal to room %s without being a member", senderID, roomID)
-        return
-    }
-
-    forwardPacket := wprotocol.Build(
-        wprotocol.OpWebRTCSignal,
-        senderID.String(), 
-        roomIDStr,         
-        signalDataString,  
-    )
-
-    uc.bcast.BroadcastToRoom(roomID, forwardPacket)
+	case wprotocol.OpWebRTCSignal:
+		if len(packet.Payload) < 2 {
+			ucLog.Warnf("Invalid WebRTC signal packet from %s: insufficient payload", senderID)
+			return
+		}
+		roomID, err := uuid.Parse(packet.Payload[0])
+		if err != nil {
+			ucLog.Warnf("Invalid roomID in WebRTC signal from %s: %v", senderID, err)
+			return
+		}
+		var targets []uuid.UUID
+		if len(packet.Payload) >= 3 && packet.Payload[2] != "" {
+			if strings.Count(packet.Payload[2], ",") >= maxSignalTargets {
+				uc.bcast.SendToUser(senderID, encode.EncodeError("Too many signal targets"))
+				return
+			}
+			for _, raw := range strings.Split(packet.Payload[2], ",") {
+				targetID, err := uuid.Parse(raw)
+				if err != nil {
+					ucLog.Warnf("Invalid target in WebRTC signal from %s: %v", senderID, err)
+					return
+				}
+				targets = append(targets, targetID)
+			}
+		}
+
+		if !checkMembership(roomID) { return }
+		uc.handleWebRTCSignal(ctx, senderID, roomID, packet.Payload[1], targets)
+
+	case wprotocol.OpCallStateUpdate:
+		if len(packet.Payload) < 4 { return }
+		roomID, err := uuid.Parse(packet.Payload[0])
+		if err != nil { return }
+		if !checkMembership(roomID) { return }
+		uc.handleCallStateUpdate(ctx, senderID, roomID, packet.Payload[1] == "1", packet.Payload[2] == "1", packet.Payload[3] == "1")
+
+	case wprotocol.OpCallStateSync:
+		if len(packet.Payload) < 1 { return }
+		roomID, err := uuid.Parse(packet.Payload[0])
+		if err != nil { return }
+		if !checkMembership(roomID) { return }
+		uc.sendCallSnapshot(ctx, senderID, roomID)
+
+	case wprotocol.OpPresenceTypingOn, wprotocol.OpPresenceTypingOff:
+		if len(packet.Payload) < 1 { return }
+		roomID, err := uuid.Parse(packet.Payload[0])
+		if err != nil { return }
+		if !checkMembership(roomID) { return }
+		uc.setTyping(ctx, senderID, roomID, packet.Op == wprotocol.OpPresenceTypingOn)
+
+	case wprotocol.OpRoomFocus:
+		if len(packet.Payload) < 1 { return }
+		roomID, err := uuid.Parse(packet.Payload[0])
+		if err != nil { return }
+		if !checkMembership(roomID) { return }
+		uc.focusRoom(ctx, senderID, roomID)
+
+	case wprotocol.OpCallRecordingRequest:
+		if len(packet.Payload) < 1 { return }
+		roomID, err := uuid.Parse(packet.Payload[0])
+		if err != nil { return }
+		if !checkMembership(roomID) { return }
+		uc.handleRecordingRequest(ctx, senderID, roomID)
+
+	case wprotocol.OpCallRecordingConsent:
+		if len(packet.Payload) < 3 { return }
+		roomID, err1 := uuid.Parse(packet.Payload[0])
+		recordingID, err2 := uuid.Parse(packet.Payload[1])
+		if err1 != nil || err2 != nil { return }
+		if !checkMembership(roomID) { return }
+		uc.handleRecordingConsent(ctx, senderID, recordingID, packet.Payload[2] == "1")
+
+	case wprotocol.OpCallRecordingStop:
+		if len(packet.Payload) < 2 { return }
+		roomID, err1 := uuid.Parse(packet.Payload[0])
+		recordingID, err2 := uuid.Parse(packet.Payload[1])
+		if err1 != nil || err2 != nil { return }
+		if !checkMembership(roomID) { return }
+		uc.handleRecordingStop(ctx, senderID, roomID, recordingID)
+
 	default:
-		log.Printf("Unknown or unhandled opcode received: %d", packet.Op)
+		ucLog.Warnf("Unknown or unhandled opcode received: %d", packet.Op)
+	}
+}
+
+func (uc *AppUsecase) handleWebRTCSignal(ctx context.Context, senderID, roomID uuid.UUID, signal string, targets []uuid.UUID) {
+	memberIDs, err := uc.repo.GetRoomMemberIDs(ctx, roomID)
+	if err != nil {
+		ucLog.Errorf("Failed to load members of room %s for WebRTC signal: %v", roomID, err)
+		return
+	}
+	members := make(map[uuid.UUID]bool, len(memberIDs))
+	for _, memberID := range memberIDs {
+		members[memberID] = true
+	}
+
+	if len(targets) == 0 {
+		targets = memberIDs
+	}
+
+	forwardPacket := encode.EncodeWebRTCSignal(senderID, roomID, signal)
+	for _, targetID := range targets {
+		if targetID == senderID {
+			continue
+		}
+		if !members[targetID] {
+			ucLog.Warnf("AuthZ Error: User %s targeted non-member %s with a signal in room %s", senderID, targetID, roomID)
+			uc.bcast.SendToUser(senderID, encode.EncodeError("Signal target is not a member of this room"))
+			continue
+		}
+		uc.bcast.SendToUser(targetID, forwardPacket)
 	}
 }
 
 func (uc *AppUsecase) handleEditMessage(ctx context.Context, senderID uuid.UUID, msgID int64, roomID uuid.UUID, newContent string) {
-	err := uc.repo.UpdateMessage(ctx, msgID, senderID, newContent)
+	existing, err := uc.repo.GetMessageByID(ctx, msgID)
+	if err != nil || existing.RoomID != roomID {
+		uc.bcast.SendToUser(senderID, encode.EncodeError("Message not found"))
+		return
+	}
+	processed, err := processMessage(existing.ContentType, newContent)
 	if err != nil {
-		log.Printf("Failed to edit message %d by user %s: %v", msgID, senderID, err)
-		uc.bcast.SendToUser(senderID, wprotocol.Build(wprotocol.OpError, "Failed to edit message"))
+		uc.bcast.SendToUser(senderID, encode.EncodeError(err.Error()))
+		return
+	}
+	err = uc.repo.UpdateMessage(ctx, msgID, senderID, processed.Content, processed.RichContent, processed.Links, processed.Hashtags)
+	if err != nil {
+		ucLog.Errorf("Failed to edit message %d by user %s: %v", msgID, senderID, err)
+		uc.bcast.SendToUser(senderID, encode.EncodeError("Failed to edit message"))
 		return
 	}
 
-	msg := wprotocol.Build(
-		wprotocol.OpMsgEdited,
-		strconv.FormatInt(msgID, 10),
-		roomID.String(),
-		newContent,
-	)
-	uc.bcast.BroadcastToRoom(roomID, msg)
-	log.Printf("User %s edited message %d in room %s", senderID, msgID, roomID)
+	mentions, _ := splitGroupMentions(processed.Mentions)
+	uc.recordMentions(ctx, existing, mentions)
+
+	uc.events.Publish(ctx, events.MessageEdited{MessageID: msgID, RoomID: roomID, EditorID: senderID, Content: processed.Content, RichContent: processed.RichContent})
+	ucLog.Infof("User %s edited message %d in room %s", senderID, msgID, roomID)
 }
 
 
 func (uc *AppUsecase) handleDeleteMessage(ctx context.Context, senderID uuid.UUID, msgID int64, roomID uuid.UUID) {
 	err := uc.repo.DeleteMessage(ctx, msgID, senderID)
 	if err != nil {
-		log.Printf("Failed to delete message %d by user %s: %v", msgID, senderID, err)
-		uc.bcast.SendToUser(senderID, wprotocol.Build(wprotocol.OpError, "Failed to delete message"))
+		ucLog.Errorf("Failed to delete message %d by user %s: %v", msgID, senderID, err)
+		uc.bcast.SendToUser(senderID, encode.EncodeError("Failed to delete message"))
 		return
 	}
 
-	msg := wprotocol.Build(
-		wprotocol.OpMsgDeleted,
-		strconv.FormatInt(msgID, 10),
-		roomID.String(),
-	)
-	uc.bcast.BroadcastToRoom(roomID, msg)
-	log.Printf("User %s deleted message %d in room %s", senderID, msgID, roomID)
+	uc.events.Publish(ctx, events.MessageDeleted{MessageID: msgID, RoomID: roomID, DeleterID: senderID})
+	ucLog.Infof("User %s deleted message %d in room %s", senderID, msgID, roomID)
 }
 
 
-func (uc *AppUsecase) handleSendMessage(ctx context.Context, senderID, roomID, clientMsgUID uuid.UUID, content string) {
+func (uc *AppUsecase) handleSendMessage(ctx context.Context, senderID, roomID, clientMsgUID uuid.UUID, content, contentType string, metadata json.RawMessage) {
+	if err := uc.ensureRoomWritable(ctx, senderID, roomID); err != nil {
+		uc.bcast.SendToUser(senderID, encode.EncodeError(err.Error()))
+		return
+	}
+	processed, err := processMessage(contentType, content)
+	if err != nil {
+		uc.bcast.SendToUser(senderID, encode.EncodeError(err.Error()))
+		return
+	}
+	mentions, groups := splitGroupMentions(processed.Mentions)
+	if err := uc.authorizeGroupMentions(ctx, senderID, roomID, groups); err != nil {
+		uc.bcast.SendToUser(senderID, encode.EncodeError(err.Error()))
+		return
+	}
+	if domain.IsUrgentMessage(metadata) {
+		if err := uc.authorizeUrgentMessage(ctx, senderID, roomID); err != nil {
+			uc.bcast.SendToUser(senderID, encode.EncodeError(err.Error()))
+			return
+		}
+	}
+	if err := uc.consumeQuota(ctx, senderID, quotaMessages); err != nil {
+		uc.bcast.SendToUser(senderID, encode.EncodeError(err.Error()))
+		return
+	}
 	dbMsg := &domain.Message{
-		MessageUID: clientMsgUID,
-		RoomID:     roomID,
-		UserID:     senderID,
-		Content:    content,
+		MessageUID:    clientMsgUID,
+		RoomID:        roomID,
+		UserID:        senderID,
+		Content:       processed.Content,
+		ContentType:   contentType,
+		RichContent:   processed.RichContent,
+		Links:         processed.Links,
+		Hashtags:      processed.Hashtags,
+		GroupMentions: groups,
+		Metadata:      metadata,
 	}
 
 	createdMsg, err := uc.repo.CreateMessage(ctx, dbMsg)
 	if err != nil {
-		log.Printf("Failed to save message: %v", err)
+		ucLog.Errorf("Failed to save message: %v", err)
 		return
 	}
+	uc.recordMentions(ctx, createdMsg, mentions)
+	uc.expandGroupMentions(ctx, createdMsg)
 
-	msg := wprotocol.Build(
-		wprotocol.OpMsgDeliver,
-		strconv.FormatInt(createdMsg.ID, 10),
-		createdMsg.MessageUID.String(),
-		createdMsg.RoomID.String(),
-		createdMsg.UserID.String(),
-		createdMsg.CreatedAt.Format(time.RFC3339Nano),
-		createdMsg.Content,
-	)
-	uc.bcast.BroadcastToRoom(roomID, msg)
+	uc.events.Publish(ctx, events.MessageCreated{Message: *createdMsg})
+	uc.setTyping(ctx, senderID, roomID, false)
+	go uc.translateMessage(context.WithoutCancel(ctx), *createdMsg)
 }
 
 func (uc *AppUsecase) handleReadMessage(ctx context.Context, msgID int64, userID, roomID uuid.UUID) {
 	readAt, err := uc.repo.MarkMessageAsRead(ctx, msgID, userID)
 	if err != nil {
-		log.Printf("Failed to mark message as read: %v", err)
+		ucLog.Errorf("Failed to mark message as read: %v", err)
 		return
 	}
 
-	msg := wprotocol.Build(
-		wprotocol.OpMsgStatusUpdate,
-		strconv.FormatInt(msgID, 10),
-		roomID.String(),
-		userID.String(),
-		"read",
-		readAt.Format(time.RFC3339Nano),
-	)
-	uc.bcast.BroadcastToRoom(roomID, msg)
+	uc.events.Publish(ctx, events.MessageRead{MessageID: msgID, RoomID: roomID, UserID: userID, ReadAt: *readAt})
 }
\ No newline at end of file
@@ -0,0 +1,81 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/events"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultAwayReplyCooldown = 12 * time.Hour
+	maxAwayMessageLength     = 1000
+)
+
+var ErrInvalidAwayStatus = errors.New("away message must be 1-1000 characters and its window must end after it starts")
+
+func (uc *AppUsecase) SetAwayReplyCooldown(cooldown time.Duration) {
+	if cooldown > 0 {
+		uc.awayCooldown = cooldown
+	}
+}
+
+func (uc *AppUsecase) GetAwayStatus(ctx context.Context, userID uuid.UUID) (*domain.AwayStatus, error) {
+	return uc.repo.GetAwayStatus(ctx, userID)
+}
+
+func (uc *AppUsecase) SetAwayStatus(ctx context.Context, userID uuid.UUID, message string, startsAt, endsAt *time.Time) (*domain.AwayStatus, error) {
+	message = strings.TrimSpace(message)
+	if length := utf8.RuneCountInString(message); length == 0 || length > maxAwayMessageLength {
+		return nil, ErrInvalidAwayStatus
+	}
+	if endsAt != nil && (!endsAt.After(time.Now()) || (startsAt != nil && !endsAt.After(*startsAt))) {
+		return nil, ErrInvalidAwayStatus
+	}
+	status := &domain.AwayStatus{UserID: userID, Message: message, StartsAt: startsAt, EndsAt: endsAt}
+	if err := uc.repo.UpsertAwayStatus(ctx, status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+func (uc *AppUsecase) ClearAwayStatus(ctx context.Context, userID uuid.UUID) error {
+	return uc.repo.DeleteAwayStatus(ctx, userID)
+}
+
+func (uc *AppUsecase) autoReply(ctx context.Context, msg domain.Message) {
+	responder, err := uc.repo.GetAwayResponder(ctx, msg.RoomID, msg.UserID)
+	if err != nil {
+		ucLog.Errorf("Failed to check away status in room %s: %v", msg.RoomID, err)
+		return
+	}
+	if responder == nil {
+		return
+	}
+	claimed, err := uc.repo.ClaimAwayReply(ctx, responder.UserID, msg.UserID, uc.awayCooldown)
+	if err != nil || !claimed {
+		if err != nil {
+			ucLog.Errorf("Failed to claim away reply for %s: %v", responder.UserID, err)
+		}
+		return
+	}
+
+	reply, err := uc.repo.CreateMessage(ctx, &domain.Message{
+		MessageUID: uuid.New(),
+		RoomID:     msg.RoomID,
+		UserID:     responder.UserID,
+		Content:    responder.Message,
+		Kind:       domain.MessageKindAutoReply,
+	})
+	if err != nil {
+		ucLog.Errorf("Failed to post away reply for %s in room %s: %v", responder.UserID, msg.RoomID, err)
+		return
+	}
+	uc.events.Publish(ctx, events.MessageCreated{Message: *reply})
+}
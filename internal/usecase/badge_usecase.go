@@ -0,0 +1,89 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/events"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidBadge = fmt.Errorf("badges must be any of %q, %q or %q", domain.UserBadgeVerified, domain.UserBadgeBot, domain.UserBadgeStaff)
+	ErrUserNotFound = errors.New("user not found")
+)
+
+func normalizeBadges(badges []string) ([]string, error) {
+	normalized := []string{}
+	for _, badge := range badges {
+		switch badge {
+		case domain.UserBadgeVerified, domain.UserBadgeBot, domain.UserBadgeStaff:
+		default:
+			return nil, ErrInvalidBadge
+		}
+		if !slices.Contains(normalized, badge) {
+			normalized = append(normalized, badge)
+		}
+	}
+	slices.Sort(normalized)
+	return normalized, nil
+}
+
+func (uc *AppUsecase) SetUserBadges(ctx context.Context, userID uuid.UUID, badges []string) (*domain.User, error) {
+	normalized, err := normalizeBadges(badges)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := uc.repo.SetUserBadges(ctx, userID, normalized)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, ErrUserNotFound
+	}
+	user, err := uc.publishProfileUpdated(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (uc *AppUsecase) publishProfileUpdated(ctx context.Context, userID uuid.UUID) (*domain.User, error) {
+	user, err := uc.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	contactIDs, err := uc.repo.GetContactIDs(ctx, userID)
+	if err != nil {
+		ucLog.Errorf("Failed to load contacts for profile update of %s: %v", userID, err)
+	}
+	uc.events.Publish(ctx, events.UserProfileUpdated{User: *user, RecipientIDs: contactIDs})
+	return user, nil
+}
+
+func (uc *AppUsecase) attachSenderBadges(ctx context.Context, messages []domain.Message) {
+	if len(messages) == 0 {
+		return
+	}
+	var senderIDs []uuid.UUID
+	for _, msg := range messages {
+		if !slices.Contains(senderIDs, msg.UserID) {
+			senderIDs = append(senderIDs, msg.UserID)
+		}
+	}
+	badges, err := uc.repo.GetUserBadges(ctx, senderIDs)
+	if err != nil {
+		ucLog.Errorf("Failed to load sender badges: %v", err)
+		return
+	}
+	for i := range messages {
+		messages[i].SenderBadges = badges[messages[i].UserID]
+	}
+}
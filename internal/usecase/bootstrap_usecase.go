@@ -0,0 +1,53 @@
+package usecase
+
+import (
+	"context"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/experiments"
+
+	"github.com/google/uuid"
+)
+
+type Bootstrap struct {
+	UserID       uuid.UUID            `json:"userId"`
+	StateVersion int64                `json:"stateVersion"`
+	Settings     *domain.UserSettings `json:"settings"`
+	Experiments  map[string]string    `json:"experiments"`
+}
+
+func (uc *AppUsecase) bumpStateVersions(ctx context.Context, userIDs ...uuid.UUID) {
+	versions, err := uc.repo.BumpStateVersions(ctx, userIDs)
+	if err != nil {
+		ucLog.Errorf("Failed to bump state versions for %v: %v", userIDs, err)
+		return
+	}
+	for userID, version := range versions {
+		uc.bcast.SetStateVersion(userID, version)
+	}
+}
+
+func (uc *AppUsecase) SetExperiments(service *experiments.Service) { uc.experiments = service }
+
+func (uc *AppUsecase) GetBootstrap(ctx context.Context, userID uuid.UUID) (*Bootstrap, error) {
+	versions, err := uc.repo.GetStateVersions(ctx, []uuid.UUID{userID})
+	if err != nil {
+		return nil, err
+	}
+	settings, err := uc.repo.GetUserSettings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	bootstrap := &Bootstrap{UserID: userID, StateVersion: versions[userID], Settings: settings, Experiments: map[string]string{}}
+	if uc.experiments != nil {
+		bootstrap.Experiments = uc.experiments.Assignments(userID)
+	}
+	return bootstrap, nil
+}
+
+func (uc *AppUsecase) RecordExperimentExposure(ctx context.Context, userID uuid.UUID, experiment string) (string, error) {
+	if uc.experiments == nil {
+		return "", experiments.ErrUnknownExperiment
+	}
+	return uc.experiments.Expose(ctx, userID, experiment)
+}
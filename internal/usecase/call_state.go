@@ -0,0 +1,253 @@
+package usecase
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"chatservice/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+var (
+	errNoActiveCall        = errors.New("no active call in this room")
+	errNotInCall           = errors.New("you are not in this call")
+	errRecordingInProgress = errors.New("a recording is already pending or in progress")
+	errUnknownRecording    = errors.New("unknown recording")
+	errRecordingNotStarted = errors.New("recording has not started")
+)
+
+type activeCall struct {
+	initiator    uuid.UUID
+	startedAt    time.Time
+	answered     bool
+	missed       bool
+	ringTimer    *time.Timer
+	participants map[uuid.UUID]bool
+	recordingID  uuid.UUID
+}
+
+type callRecording struct {
+	id           uuid.UUID
+	roomID       uuid.UUID
+	requester    uuid.UUID
+	consents     map[uuid.UUID]bool
+	participants map[uuid.UUID]bool
+	started      bool
+}
+
+type callStateStore struct {
+	mu         sync.RWMutex
+	rooms      map[uuid.UUID]map[uuid.UUID]domain.CallParticipantState
+	calls      map[uuid.UUID]*activeCall
+	recordings map[uuid.UUID]*callRecording
+}
+
+func newCallStateStore() *callStateStore {
+	return &callStateStore{
+		rooms:      make(map[uuid.UUID]map[uuid.UUID]domain.CallParticipantState),
+		calls:      make(map[uuid.UUID]*activeCall),
+		recordings: make(map[uuid.UUID]*callRecording),
+	}
+}
+
+func (s *callStateStore) join(roomID, userID uuid.UUID) (*activeCall, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if call, ok := s.calls[roomID]; ok {
+		call.participants[userID] = true
+		if rec, ok := s.recordings[call.recordingID]; ok && rec.started {
+			rec.participants[userID] = true
+		}
+		if userID != call.initiator && !call.answered {
+			call.answered = true
+			if call.ringTimer != nil {
+				call.ringTimer.Stop()
+			}
+		}
+		return call, false
+	}
+	call := &activeCall{
+		initiator:    userID,
+		startedAt:    time.Now(),
+		participants: map[uuid.UUID]bool{userID: true},
+	}
+	s.calls[roomID] = call
+	return call, true
+}
+
+func (s *callStateStore) setRingTimer(call *activeCall, timer *time.Timer) {
+	s.mu.Lock()
+	call.ringTimer = timer
+	s.mu.Unlock()
+}
+
+func (s *callStateStore) markMissed(call *activeCall) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if call.answered || call.missed {
+		return false
+	}
+	call.missed = true
+	if call.ringTimer != nil {
+		call.ringTimer.Stop()
+	}
+	return true
+}
+
+func (s *callStateStore) end(roomID uuid.UUID) *activeCall {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	call := s.calls[roomID]
+	if call != nil {
+		if rec, ok := s.recordings[call.recordingID]; ok && !rec.started {
+			delete(s.recordings, rec.id)
+		}
+	}
+	delete(s.calls, roomID)
+	delete(s.rooms, roomID)
+	return call
+}
+
+func (s *callStateStore) leave(roomID, userID uuid.UUID) *callRecording {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if room, ok := s.rooms[roomID]; ok {
+		delete(room, userID)
+		if len(room) == 0 {
+			delete(s.rooms, roomID)
+		}
+	}
+	call, ok := s.calls[roomID]
+	if !ok {
+		return nil
+	}
+	delete(call.participants, userID)
+	if rec, ok := s.recordings[call.recordingID]; ok && !rec.started && s.consentComplete(call, rec) {
+		return rec
+	}
+	return nil
+}
+
+func (s *callStateStore) requestRecording(roomID, requester uuid.UUID) (*callRecording, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	call, ok := s.calls[roomID]
+	if !ok {
+		return nil, false, errNoActiveCall
+	}
+	if !call.participants[requester] {
+		return nil, false, errNotInCall
+	}
+	if _, ok := s.recordings[call.recordingID]; ok {
+		return nil, false, errRecordingInProgress
+	}
+	rec := &callRecording{
+		id:           uuid.New(),
+		roomID:       roomID,
+		requester:    requester,
+		consents:     map[uuid.UUID]bool{requester: true},
+		participants: make(map[uuid.UUID]bool),
+	}
+	call.recordingID = rec.id
+	s.recordings[rec.id] = rec
+	return rec, s.consentComplete(call, rec), nil
+}
+
+func (s *callStateStore) consent(recordingID, userID uuid.UUID, agree bool) (*callRecording, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, ok := s.recordings[recordingID]
+	if !ok || rec.started {
+		return nil, false, errUnknownRecording
+	}
+	call, ok := s.calls[rec.roomID]
+	if !ok || call.recordingID != recordingID {
+		return nil, false, errUnknownRecording
+	}
+	if !call.participants[userID] {
+		return nil, false, errNotInCall
+	}
+	if !agree {
+		delete(s.recordings, recordingID)
+		call.recordingID = uuid.Nil
+		return rec, false, nil
+	}
+	rec.consents[userID] = true
+	return rec, s.consentComplete(call, rec), nil
+}
+
+func (s *callStateStore) consentComplete(call *activeCall, rec *callRecording) bool {
+	for userID := range call.participants {
+		if !rec.consents[userID] {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *callStateStore) markRecordingStarted(recordingID uuid.UUID) (*callRecording, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, ok := s.recordings[recordingID]
+	if !ok || rec.started {
+		return nil, false
+	}
+	rec.started = true
+	if call, ok := s.calls[rec.roomID]; ok {
+		for userID := range call.participants {
+			rec.participants[userID] = true
+		}
+	}
+	return rec, true
+}
+
+func (s *callStateStore) activeRecording(roomID, userID, recordingID uuid.UUID) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	rec, ok := s.recordings[recordingID]
+	if !ok || rec.roomID != roomID {
+		return errUnknownRecording
+	}
+	if !rec.started {
+		return errRecordingNotStarted
+	}
+	if call, ok := s.calls[roomID]; !ok || !call.participants[userID] {
+		return errNotInCall
+	}
+	return nil
+}
+
+func (s *callStateStore) takeRecording(recordingID uuid.UUID) *callRecording {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, ok := s.recordings[recordingID]
+	if !ok {
+		return nil
+	}
+	delete(s.recordings, recordingID)
+	if call, ok := s.calls[rec.roomID]; ok && call.recordingID == recordingID {
+		call.recordingID = uuid.Nil
+	}
+	return rec
+}
+
+func (s *callStateStore) set(roomID uuid.UUID, state domain.CallParticipantState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.rooms[roomID]; !ok {
+		s.rooms[roomID] = make(map[uuid.UUID]domain.CallParticipantState)
+	}
+	s.rooms[roomID][state.UserID] = state
+}
+
+func (s *callStateStore) snapshot(roomID uuid.UUID) []domain.CallParticipantState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	states := make([]domain.CallParticipantState, 0, len(s.rooms[roomID]))
+	for _, state := range s.rooms[roomID] {
+		states = append(states, state)
+	}
+	return states
+}
@@ -0,0 +1,158 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/events"
+	"chatservice/internal/sfu"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultRingTimeout = 45 * time.Second
+	missedCallContent  = "Missed call"
+)
+
+func (uc *AppUsecase) SetSFU(client *sfu.Client) { uc.sfu = client }
+
+func (uc *AppUsecase) SetCallRingTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		uc.ringTimeout = timeout
+	}
+}
+
+func (uc *AppUsecase) CreateCallToken(ctx context.Context, userID, roomID uuid.UUID) (*sfu.JoinToken, error) {
+	isMember, err := uc.repo.IsUserInRoom(ctx, userID, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("could not verify room membership: %w", err)
+	}
+	if !isMember {
+		return nil, fmt.Errorf("user not authorized to access this room")
+	}
+
+	displayName := ""
+	if user, err := uc.repo.GetUserByID(ctx, userID); err == nil && user != nil {
+		displayName = user.Nickname
+	}
+	return uc.sfu.MintJoinToken(userID, displayName, roomID)
+}
+
+func (uc *AppUsecase) HandleSFUWebhook(ctx context.Context, body []byte, signature string) error {
+	event, err := uc.sfu.ParseWebhook(body, signature)
+	if err != nil {
+		return err
+	}
+
+	switch event.Event {
+	case sfu.EventParticipantJoined:
+		isMember, err := uc.repo.IsUserInRoom(ctx, event.Participant, event.Room)
+		if err != nil || !isMember {
+			ucLog.Warnf("SFU reported non-member %s joining call in room %s", event.Participant, event.Room)
+			return nil
+		}
+		if call, started := uc.callStates.join(event.Room, event.Participant); started {
+			roomID, timerCtx := event.Room, context.WithoutCancel(ctx)
+			uc.callStates.setRingTimer(call, time.AfterFunc(uc.ringTimeout, func() {
+				uc.recordMissedCall(timerCtx, roomID, call)
+			}))
+		}
+		uc.setEphemeral(ctx, event.Room, event.Participant, domain.EphemeralCall, callStateTTL)
+		uc.events.Publish(ctx, events.CallParticipantJoined{RoomID: event.Room, UserID: event.Participant, At: event.Timestamp})
+		uc.sendCallSnapshot(ctx, event.Participant, event.Room)
+	case sfu.EventParticipantLeft:
+		ready := uc.callStates.leave(event.Room, event.Participant)
+		uc.clearEphemeral(ctx, event.Room, event.Participant, domain.EphemeralCall)
+		uc.events.Publish(ctx, events.CallParticipantLeft{RoomID: event.Room, UserID: event.Participant, At: event.Timestamp})
+		if ready != nil {
+			uc.startRecording(ctx, ready)
+		}
+	case sfu.EventRoomFinished:
+		if call := uc.callStates.end(event.Room); call != nil {
+			uc.recordMissedCall(ctx, event.Room, call)
+		}
+		uc.clearRoomEphemeral(ctx, event.Room, domain.EphemeralCall)
+		uc.events.Publish(ctx, events.CallEnded{RoomID: event.Room, At: event.Timestamp})
+	case sfu.EventRecordingStarted:
+		if event.Recording == nil {
+			return fmt.Errorf("recording event without recording details")
+		}
+		if _, ok := uc.callStates.markRecordingStarted(event.Recording.ID); ok {
+			uc.events.Publish(ctx, events.CallRecordingStarted{RoomID: event.Room, RecordingID: event.Recording.ID, At: event.Timestamp})
+		}
+	case sfu.EventRecordingFinished:
+		if event.Recording == nil {
+			return fmt.Errorf("recording event without recording details")
+		}
+		return uc.storeRecording(ctx, event.Room, event.Recording)
+	default:
+		ucLog.Warnf("Ignoring unknown SFU webhook event %q", event.Event)
+	}
+	return nil
+}
+
+func (uc *AppUsecase) handleCallStateUpdate(ctx context.Context, userID, roomID uuid.UUID, muted, cameraOn, screenSharing bool) {
+	state := domain.CallParticipantState{
+		UserID:        userID,
+		Muted:         muted,
+		CameraOn:      cameraOn,
+		ScreenSharing: screenSharing,
+		UpdatedAt:     time.Now(),
+	}
+	uc.callStates.set(roomID, state)
+	uc.events.Publish(ctx, events.CallStateChanged{RoomID: roomID, State: state})
+}
+
+func (uc *AppUsecase) sendCallSnapshot(ctx context.Context, userID, roomID uuid.UUID) {
+	states := uc.callStates.snapshot(roomID)
+	if len(states) == 0 {
+		return
+	}
+	uc.events.Publish(ctx, events.CallStateSnapshot{RecipientID: userID, RoomID: roomID, States: states})
+}
+
+func (uc *AppUsecase) recordMissedCall(ctx context.Context, roomID uuid.UUID, call *activeCall) {
+	if !uc.callStates.markMissed(call) {
+		return
+	}
+
+	memberIDs, err := uc.repo.GetRoomMemberIDs(ctx, roomID)
+	if err != nil {
+		ucLog.Errorf("Failed to load members of room %s for missed call: %v", roomID, err)
+		return
+	}
+	calleeIDs := make([]uuid.UUID, 0, len(memberIDs))
+	for _, memberID := range memberIDs {
+		if memberID != call.initiator {
+			calleeIDs = append(calleeIDs, memberID)
+		}
+	}
+	if len(calleeIDs) == 0 {
+		return
+	}
+
+	msg, err := uc.repo.CreateMessage(ctx, &domain.Message{
+		MessageUID: uuid.New(),
+		RoomID:     roomID,
+		UserID:     call.initiator,
+		Content:    missedCallContent,
+		Kind:       domain.MessageKindMissedCall,
+	})
+	if err != nil {
+		ucLog.Errorf("Failed to save missed call message in room %s: %v", roomID, err)
+		return
+	}
+
+	uc.events.Publish(ctx, events.MessageCreated{Message: *msg})
+	uc.events.Publish(ctx, events.CallMissed{
+		RoomID:    roomID,
+		CallerID:  call.initiator,
+		CalleeIDs: calleeIDs,
+		Message:   *msg,
+		At:        call.startedAt,
+	})
+	ucLog.Infof("Call from %s in room %s rang out unanswered", call.initiator, roomID)
+}
@@ -0,0 +1,295 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxCannedTitleLength  = 100
+	maxCannedBodyBytes    = 4 * 1024
+	defaultCannedLookup   = 20
+	maxCannedLookup       = 50
+	maxCannedVariableSize = 500
+)
+
+var (
+	ErrCannedResponseNotFound  = errors.New("canned response not found")
+	ErrCannedResponseForbidden = errors.New("only room owners and admins may manage room canned responses")
+	ErrInvalidCannedResponse   = errors.New("invalid canned response")
+	ErrCannedShortcutTaken     = errors.New("a canned response with this shortcut already exists")
+	ErrMissingCannedVariable   = errors.New("canned response has unfilled variables")
+
+	cannedShortcutPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+	cannedVariablePattern = regexp.MustCompile(`\{\{\s*([a-zA-Z0-9_.]+)\s*\}\}`)
+)
+
+type CannedResponseInput struct {
+	Scope    string
+	RoomID   *uuid.UUID
+	Shortcut string
+	Title    string
+	Body     string
+}
+
+func (uc *AppUsecase) CreateCannedResponse(ctx context.Context, userID uuid.UUID, input CannedResponseInput) (*domain.CannedResponse, error) {
+	if input.Scope == "" {
+		input.Scope = domain.CannedScopeUser
+	}
+	resp := &domain.CannedResponse{ID: uuid.New(), Scope: input.Scope, CreatedBy: userID}
+	switch input.Scope {
+	case domain.CannedScopeUser:
+		resp.OwnerID = &userID
+	case domain.CannedScopeRoom:
+		if input.RoomID == nil {
+			return nil, fmt.Errorf("%w: roomId is required for room canned responses", ErrInvalidCannedResponse)
+		}
+		resp.RoomID = input.RoomID
+	case domain.CannedScopeSupport:
+	default:
+		return nil, fmt.Errorf("%w: scope must be %q, %q or %q", ErrInvalidCannedResponse, domain.CannedScopeUser, domain.CannedScopeRoom, domain.CannedScopeSupport)
+	}
+	if err := applyCannedInput(resp, input); err != nil {
+		return nil, err
+	}
+	if err := uc.authorizeCannedManage(ctx, userID, resp); err != nil {
+		return nil, err
+	}
+	err := uc.repo.CreateCannedResponse(ctx, resp)
+	if errors.Is(err, repository.ErrCannedShortcutTaken) {
+		return nil, ErrCannedShortcutTaken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (uc *AppUsecase) UpdateCannedResponse(ctx context.Context, userID, id uuid.UUID, input CannedResponseInput) (*domain.CannedResponse, error) {
+	resp, err := uc.loadCannedResponse(ctx, userID, id, uc.authorizeCannedManage)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyCannedInput(resp, input); err != nil {
+		return nil, err
+	}
+	updated, err := uc.repo.UpdateCannedResponse(ctx, resp)
+	if errors.Is(err, repository.ErrCannedShortcutTaken) {
+		return nil, ErrCannedShortcutTaken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, ErrCannedResponseNotFound
+	}
+	return resp, nil
+}
+
+func (uc *AppUsecase) DeleteCannedResponse(ctx context.Context, userID, id uuid.UUID) error {
+	if _, err := uc.loadCannedResponse(ctx, userID, id, uc.authorizeCannedManage); err != nil {
+		return err
+	}
+	deleted, err := uc.repo.DeleteCannedResponse(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrCannedResponseNotFound
+	}
+	return nil
+}
+
+func (uc *AppUsecase) LookupCannedResponses(ctx context.Context, userID uuid.UUID, roomID *uuid.UUID, query string, limit int) ([]domain.CannedResponse, error) {
+	if roomID != nil {
+		isMember, err := uc.repo.IsUserInRoom(ctx, userID, *roomID)
+		if err != nil {
+			return nil, fmt.Errorf("could not verify room membership: %w", err)
+		}
+		if !isMember {
+			return nil, ErrNotRoomMember
+		}
+	}
+	isAgent, err := uc.repo.IsSupportAgent(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultCannedLookup
+	}
+	prefix := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(query), "/"))
+	return uc.repo.FindCannedResponses(ctx, userID, roomID, isAgent, prefix, min(limit, maxCannedLookup))
+}
+
+func (uc *AppUsecase) RenderCannedResponse(ctx context.Context, userID, id uuid.UUID, roomID *uuid.UUID, vars map[string]string) (string, error) {
+	resp, err := uc.loadCannedResponse(ctx, userID, id, uc.authorizeCannedUse)
+	if err != nil {
+		return "", err
+	}
+	if roomID != nil {
+		isMember, err := uc.repo.IsUserInRoom(ctx, userID, *roomID)
+		if err != nil {
+			return "", fmt.Errorf("could not verify room membership: %w", err)
+		}
+		if !isMember {
+			return "", ErrNotRoomMember
+		}
+	}
+	return uc.renderCannedResponse(ctx, userID, resp, roomID, vars)
+}
+
+func (uc *AppUsecase) SendCannedSupportReply(ctx context.Context, agentID, roomID, responseID uuid.UUID, vars map[string]string) (*domain.Message, error) {
+	if err := uc.requireSupportAgent(ctx, agentID); err != nil {
+		return nil, err
+	}
+	conv, err := uc.repo.GetSupportConversation(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if conv == nil {
+		return nil, ErrSupportConversationNotFound
+	}
+	isMember, err := uc.repo.IsUserInRoom(ctx, agentID, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("could not verify room membership: %w", err)
+	}
+	if !isMember {
+		return nil, ErrNotRoomMember
+	}
+	resp, err := uc.loadCannedResponse(ctx, agentID, responseID, uc.authorizeCannedUse)
+	if err != nil {
+		return nil, err
+	}
+	content, err := uc.renderCannedResponse(ctx, agentID, resp, &roomID, vars)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := uc.postTextMessage(ctx, agentID, roomID, content)
+	if err != nil {
+		return nil, err
+	}
+	ucLog.Infof("Agent %s replied with canned response %s in room %s", agentID, resp.ID, roomID)
+	return msg, nil
+}
+
+func (uc *AppUsecase) loadCannedResponse(ctx context.Context, userID, id uuid.UUID, authorize func(context.Context, uuid.UUID, *domain.CannedResponse) error) (*domain.CannedResponse, error) {
+	resp, err := uc.repo.GetCannedResponse(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, ErrCannedResponseNotFound
+	}
+	if err := authorize(ctx, userID, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (uc *AppUsecase) authorizeCannedManage(ctx context.Context, userID uuid.UUID, resp *domain.CannedResponse) error {
+	switch resp.Scope {
+	case domain.CannedScopeRoom:
+		return uc.requireRoomAdmin(ctx, userID, *resp.RoomID, ErrCannedResponseForbidden)
+	case domain.CannedScopeSupport:
+		return uc.requireSupportAgent(ctx, userID)
+	default:
+		if resp.OwnerID == nil || *resp.OwnerID != userID {
+			return ErrCannedResponseNotFound
+		}
+		return nil
+	}
+}
+
+func (uc *AppUsecase) authorizeCannedUse(ctx context.Context, userID uuid.UUID, resp *domain.CannedResponse) error {
+	switch resp.Scope {
+	case domain.CannedScopeRoom:
+		isMember, err := uc.repo.IsUserInRoom(ctx, userID, *resp.RoomID)
+		if err != nil {
+			return fmt.Errorf("could not verify room membership: %w", err)
+		}
+		if !isMember {
+			return ErrCannedResponseNotFound
+		}
+		return nil
+	case domain.CannedScopeSupport:
+		return uc.requireSupportAgent(ctx, userID)
+	default:
+		if resp.OwnerID == nil || *resp.OwnerID != userID {
+			return ErrCannedResponseNotFound
+		}
+		return nil
+	}
+}
+
+func (uc *AppUsecase) renderCannedResponse(ctx context.Context, userID uuid.UUID, resp *domain.CannedResponse, roomID *uuid.UUID, vars map[string]string) (string, error) {
+	values := map[string]string{
+		"date": time.Now().UTC().Format(time.DateOnly),
+	}
+	if user, err := uc.repo.GetUserByID(ctx, userID); err == nil && user != nil {
+		values["agent.name"] = user.Nickname
+		values["sender.name"] = user.Nickname
+	}
+	if roomID != nil {
+		if room, err := uc.repo.GetRoomByID(ctx, *roomID); err == nil && room.Name != nil {
+			values["room.name"] = *room.Name
+		}
+		if conv, err := uc.repo.GetSupportConversation(ctx, *roomID); err == nil && conv != nil {
+			values["conversation.subject"] = conv.Subject
+			if customer, err := uc.repo.GetUserByID(ctx, conv.CustomerID); err == nil && customer != nil {
+				values["customer.name"] = customer.Nickname
+			}
+		}
+	}
+	for name, value := range vars {
+		if utf8.RuneCountInString(value) > maxCannedVariableSize {
+			return "", fmt.Errorf("%w: variable %q is longer than %d characters", ErrInvalidCannedResponse, name, maxCannedVariableSize)
+		}
+		values[name] = value
+	}
+
+	var missing []string
+	rendered := cannedVariablePattern.ReplaceAllStringFunc(resp.Body, func(match string) string {
+		name := cannedVariablePattern.FindStringSubmatch(match)[1]
+		value, ok := values[name]
+		if !ok {
+			if !slices.Contains(missing, name) {
+				missing = append(missing, name)
+			}
+			return match
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("%w: %s", ErrMissingCannedVariable, strings.Join(missing, ", "))
+	}
+	return rendered, nil
+}
+
+func applyCannedInput(resp *domain.CannedResponse, input CannedResponseInput) error {
+	shortcut := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(input.Shortcut), "/"))
+	if !cannedShortcutPattern.MatchString(shortcut) {
+		return fmt.Errorf("%w: shortcut must be 1-32 lowercase letters, digits, dashes or underscores", ErrInvalidCannedResponse)
+	}
+	title := strings.TrimSpace(input.Title)
+	if utf8.RuneCountInString(title) > maxCannedTitleLength {
+		return fmt.Errorf("%w: title must be at most %d characters", ErrInvalidCannedResponse, maxCannedTitleLength)
+	}
+	if strings.TrimSpace(input.Body) == "" || len(input.Body) > maxCannedBodyBytes {
+		return fmt.Errorf("%w: body must be between 1 and %d bytes", ErrInvalidCannedResponse, maxCannedBodyBytes)
+	}
+	resp.Shortcut = shortcut
+	resp.Title = title
+	resp.Body = input.Body
+	return nil
+}
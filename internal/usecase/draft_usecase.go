@@ -0,0 +1,69 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"chatservice/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxDraftLength      = 4000
+	maxDraftAttachments = 10
+)
+
+var ErrInvalidDraft = errors.New("invalid draft")
+
+func (uc *AppUsecase) GetDrafts(ctx context.Context, userID uuid.UUID) ([]domain.Draft, error) {
+	return uc.repo.GetDraftsForUser(ctx, userID)
+}
+
+func (uc *AppUsecase) GetDraft(ctx context.Context, userID, roomID uuid.UUID) (*domain.Draft, error) {
+	isMember, err := uc.repo.IsUserInRoom(ctx, userID, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("could not verify room membership: %w", err)
+	}
+	if !isMember {
+		return nil, ErrNotRoomMember
+	}
+	return uc.repo.GetDraft(ctx, userID, roomID)
+}
+
+func (uc *AppUsecase) SaveDraft(ctx context.Context, userID, roomID uuid.UUID, content string, attachmentIDs []uuid.UUID) (*domain.Draft, error) {
+	isMember, err := uc.repo.IsUserInRoom(ctx, userID, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("could not verify room membership: %w", err)
+	}
+	if !isMember {
+		return nil, ErrNotRoomMember
+	}
+	if utf8.RuneCountInString(content) > maxDraftLength {
+		return nil, fmt.Errorf("%w: content exceeds %d characters", ErrInvalidDraft, maxDraftLength)
+	}
+	if len(attachmentIDs) > maxDraftAttachments {
+		return nil, fmt.Errorf("%w: at most %d attachments", ErrInvalidDraft, maxDraftAttachments)
+	}
+	seen := make(map[uuid.UUID]bool, len(attachmentIDs))
+	for _, attachmentID := range attachmentIDs {
+		if seen[attachmentID] {
+			return nil, fmt.Errorf("%w: attachment %s listed twice", ErrInvalidDraft, attachmentID)
+		}
+		seen[attachmentID] = true
+		att, err := uc.repo.GetAttachmentForUser(ctx, attachmentID, userID)
+		if err != nil || att.RoomID != roomID {
+			return nil, fmt.Errorf("%w: attachment %s is not available in this room", ErrInvalidDraft, attachmentID)
+		}
+	}
+	if content == "" && len(attachmentIDs) == 0 {
+		return nil, uc.repo.DeleteDraft(ctx, userID, roomID)
+	}
+	return uc.repo.SaveDraft(ctx, &domain.Draft{UserID: userID, RoomID: roomID, Content: content, AttachmentIDs: attachmentIDs})
+}
+
+func (uc *AppUsecase) DeleteDraft(ctx context.Context, userID, roomID uuid.UUID) error {
+	return uc.repo.DeleteDraft(ctx, userID, roomID)
+}
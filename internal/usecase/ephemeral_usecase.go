@@ -0,0 +1,108 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/ephemeral"
+	"chatservice/internal/events"
+
+	"github.com/google/uuid"
+)
+
+const (
+	typingStateTTL = 10 * time.Second
+	focusStateTTL  = 2 * time.Minute
+	callStateTTL   = 12 * time.Hour
+
+	typingRelayInterval = 3 * time.Second
+	maxTypingRelays     = 10000
+)
+
+type typingKey struct {
+	userID uuid.UUID
+	roomID uuid.UUID
+}
+
+type typingRelay struct {
+	mu   sync.Mutex
+	last map[typingKey]time.Time
+}
+
+func (r *typingRelay) allow(key typingKey, typing bool, now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	last, relayed := r.last[key]
+	if !typing {
+		delete(r.last, key)
+		return relayed
+	}
+	if relayed && now.Sub(last) < typingRelayInterval {
+		return false
+	}
+	if r.last == nil {
+		r.last = make(map[typingKey]time.Time)
+	}
+	if len(r.last) >= maxTypingRelays {
+		for k, at := range r.last {
+			if now.Sub(at) > typingStateTTL {
+				delete(r.last, k)
+			}
+		}
+	}
+	r.last[key] = now
+	return true
+}
+
+func (uc *AppUsecase) SetEphemeralStore(store ephemeral.Store) { uc.ephemeral = store }
+
+func (uc *AppUsecase) setTyping(ctx context.Context, userID, roomID uuid.UUID, typing bool) {
+	if typing {
+		uc.setEphemeral(ctx, roomID, userID, domain.EphemeralTyping, typingStateTTL)
+	} else {
+		uc.clearEphemeral(ctx, roomID, userID, domain.EphemeralTyping)
+	}
+	if uc.typing.allow(typingKey{userID: userID, roomID: roomID}, typing, time.Now()) {
+		uc.events.Publish(ctx, events.UserTyping{RoomID: roomID, UserID: userID, Typing: typing})
+	}
+}
+
+func (uc *AppUsecase) focusRoom(ctx context.Context, userID, roomID uuid.UUID) {
+	uc.setEphemeral(ctx, roomID, userID, domain.EphemeralFocus, focusStateTTL)
+
+	states, err := uc.ephemeral.Room(ctx, roomID)
+	if err != nil {
+		ucLog.Errorf("Error loading ephemeral state for room %s: %v", roomID, err)
+		return
+	}
+	uc.events.Publish(ctx, events.RoomStateSnapshot{RecipientID: userID, RoomID: roomID, States: states})
+	uc.sendCallSnapshot(ctx, userID, roomID)
+	go uc.suggestReplies(context.WithoutCancel(ctx), userID, roomID)
+}
+
+func (uc *AppUsecase) setEphemeral(ctx context.Context, roomID, userID uuid.UUID, kind string, ttl time.Duration) {
+	if err := uc.ephemeral.Set(ctx, roomID, userID, kind, "", ttl); err != nil {
+		ucLog.Errorf("Error setting %s state for %s in room %s: %v", kind, userID, roomID, err)
+	}
+}
+
+func (uc *AppUsecase) clearEphemeral(ctx context.Context, roomID, userID uuid.UUID, kind string) {
+	if err := uc.ephemeral.Clear(ctx, roomID, userID, kind); err != nil {
+		ucLog.Errorf("Error clearing %s state for %s in room %s: %v", kind, userID, roomID, err)
+	}
+}
+
+func (uc *AppUsecase) clearRoomEphemeral(ctx context.Context, roomID uuid.UUID, kind string) {
+	states, err := uc.ephemeral.Room(ctx, roomID)
+	if err != nil {
+		ucLog.Errorf("Error loading ephemeral state for room %s: %v", roomID, err)
+		return
+	}
+	for _, state := range states {
+		if state.Kind == kind {
+			uc.clearEphemeral(ctx, roomID, state.UserID, kind)
+		}
+	}
+}
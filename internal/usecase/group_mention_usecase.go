@@ -0,0 +1,93 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"chatservice/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+const (
+	everyoneMentionMetadataKey = adminRoomMetadataPrefix + "everyone_mentions"
+
+	everyoneMentionsAll    = "all"
+	everyoneMentionsAdmins = "admins"
+	everyoneMentionsNobody = "nobody"
+)
+
+var ErrGroupMentionForbidden = errors.New("you are not allowed to mention @everyone in this room")
+
+func validEveryoneMentions(value json.RawMessage) bool {
+	var policy string
+	if err := json.Unmarshal(value, &policy); err != nil {
+		return false
+	}
+	return policy == everyoneMentionsAll || policy == everyoneMentionsAdmins || policy == everyoneMentionsNobody
+}
+
+func splitGroupMentions(usernames []string) ([]string, []string) {
+	var users, groups []string
+	for _, username := range usernames {
+		if username == domain.MentionEveryone || username == domain.MentionAdmins {
+			groups = append(groups, username)
+			continue
+		}
+		users = append(users, username)
+	}
+	return users, groups
+}
+
+func (uc *AppUsecase) authorizeGroupMentions(ctx context.Context, senderID, roomID uuid.UUID, groups []string) error {
+	if !slices.Contains(groups, domain.MentionEveryone) {
+		return nil
+	}
+	metadata, err := uc.repo.GetRoomMetadata(ctx, roomID)
+	if err != nil {
+		return fmt.Errorf("could not load room settings: %w", err)
+	}
+	policy := everyoneMentionsAdmins
+	if raw, ok := metadata[everyoneMentionMetadataKey]; ok {
+		json.Unmarshal(raw, &policy)
+	}
+	switch policy {
+	case everyoneMentionsAll:
+		return nil
+	case everyoneMentionsNobody:
+		return ErrGroupMentionForbidden
+	}
+	role, err := uc.repo.GetRoomRole(ctx, senderID, roomID)
+	if err != nil {
+		return fmt.Errorf("could not verify room role: %w", err)
+	}
+	if role != "owner" && role != "admin" {
+		return ErrGroupMentionForbidden
+	}
+	return nil
+}
+
+func (uc *AppUsecase) expandGroupMentions(ctx context.Context, msg *domain.Message) {
+	for _, group := range msg.GroupMentions {
+		var memberIDs []uuid.UUID
+		var err error
+		switch group {
+		case domain.MentionEveryone:
+			memberIDs, err = uc.repo.GetRoomMemberIDs(ctx, msg.RoomID)
+		case domain.MentionAdmins:
+			memberIDs, err = uc.repo.GetRoomAdminIDs(ctx, msg.RoomID)
+		}
+		if err != nil {
+			ucLog.Errorf("Failed to expand @%s in message %d: %v", group, msg.ID, err)
+			continue
+		}
+		for _, memberID := range memberIDs {
+			if memberID != msg.UserID && !slices.Contains(msg.Mentions, memberID) {
+				msg.Mentions = append(msg.Mentions, memberID)
+			}
+		}
+	}
+}
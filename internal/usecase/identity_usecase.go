@@ -0,0 +1,106 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/integrations"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidIdentityProvider = errors.New("identity provider must be 1-32 lowercase letters, digits, '_' or '-'")
+
+var identityProviderPattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+func (uc *AppUsecase) SetAuthSync(sync *integrations.AuthSync) { uc.authSync = sync }
+
+func (uc *AppUsecase) HandleAuthEvent(ctx context.Context, body []byte, signature string) error {
+	event, err := uc.authSync.Parse(body, signature)
+	if err != nil {
+		return err
+	}
+	newest, err := uc.repo.AdvanceAuthEventCursor(ctx, event.UserID, event.Timestamp)
+	if err != nil {
+		return err
+	}
+	if !newest {
+		ucLog.Infof("Ignoring %s event for %s older than the last one applied", event.Event, event.UserID)
+		return nil
+	}
+
+	switch event.Event {
+	case integrations.AuthEventUserUpdated:
+		if err := uc.repo.UpsertUser(ctx, event.UserID, event.Email, event.Username, event.Nickname); err != nil {
+			return err
+		}
+		if event.Identities != nil {
+			identities := make([]domain.LinkedIdentity, 0, len(event.Identities))
+			for _, identity := range event.Identities {
+				linked, err := linkedIdentity(identity, event)
+				if err != nil {
+					return err
+				}
+				identities = append(identities, linked)
+			}
+			if err := uc.repo.ReplaceUserIdentities(ctx, event.UserID, identities); err != nil {
+				return err
+			}
+		}
+	case integrations.AuthEventIdentityLinked, integrations.AuthEventIdentityUnlinked:
+		if event.Identity == nil {
+			return fmt.Errorf("%s event without identity details", event.Event)
+		}
+		linked, err := linkedIdentity(*event.Identity, event)
+		if err != nil {
+			return err
+		}
+		if event.Event == integrations.AuthEventIdentityUnlinked {
+			if _, err := uc.repo.DeleteUserIdentity(ctx, event.UserID, linked.Provider); err != nil {
+				return err
+			}
+			break
+		}
+		if err := uc.repo.UpsertUser(ctx, event.UserID, nil, nil, nil); err != nil {
+			return err
+		}
+		if err := uc.repo.UpsertUserIdentity(ctx, event.UserID, linked); err != nil {
+			return err
+		}
+	default:
+		ucLog.Warnf("Ignoring unknown auth event %q for %s", event.Event, event.UserID)
+		return nil
+	}
+
+	if _, err := uc.publishProfileUpdated(ctx, event.UserID); err != nil {
+		ucLog.Errorf("Failed to announce profile update for %s: %v", event.UserID, err)
+	}
+	return nil
+}
+
+func (uc *AppUsecase) GetUserIdentities(ctx context.Context, viewerID, userID uuid.UUID) ([]domain.LinkedIdentity, error) {
+	identities, err := uc.repo.GetUserIdentities(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if viewerID != userID {
+		for i := range identities {
+			identities[i].Login = ""
+		}
+	}
+	return identities, nil
+}
+
+func linkedIdentity(identity integrations.AuthIdentity, event *integrations.AuthEvent) (domain.LinkedIdentity, error) {
+	if !identityProviderPattern.MatchString(identity.Provider) {
+		return domain.LinkedIdentity{}, ErrInvalidIdentityProvider
+	}
+	linkedAt := identity.LinkedAt
+	if linkedAt.IsZero() {
+		linkedAt = event.Timestamp
+	}
+	return domain.LinkedIdentity{Provider: identity.Provider, Login: identity.Login, LinkedAt: linkedAt}, nil
+}
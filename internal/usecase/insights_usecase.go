@@ -0,0 +1,58 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"chatservice/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultInsightsDays = 30
+	maxInsightsDays     = 90
+	insightsTopRooms    = 5
+)
+
+func (uc *AppUsecase) GetUserInsights(ctx context.Context, userID uuid.UUID, days int) (*domain.UserInsights, error) {
+	if days <= 0 {
+		days = defaultInsightsDays
+	}
+	if days > maxInsightsDays {
+		days = maxInsightsDays
+	}
+	since := time.Now().UTC().AddDate(0, 0, -(days - 1))
+
+	daily, err := uc.repo.GetDailyActivity(ctx, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	rooms, err := uc.repo.GetRoomActivity(ctx, userID, since, insightsTopRooms)
+	if err != nil {
+		return nil, err
+	}
+
+	insights := &domain.UserInsights{Days: days, Daily: daily, TopRooms: rooms}
+	var responses int
+	var responseSeconds int64
+	for _, day := range daily {
+		insights.MessagesSent += day.MessagesSent
+		responses += day.Responses
+		responseSeconds += day.ResponseSeconds
+	}
+	insights.AverageResponseSeconds = averageResponse(responses, responseSeconds)
+	for i := range insights.TopRooms {
+		room := &insights.TopRooms[i]
+		room.AverageResponseSeconds = averageResponse(room.Responses, room.ResponseSeconds)
+	}
+	return insights, nil
+}
+
+func averageResponse(responses int, seconds int64) *float64 {
+	if responses == 0 {
+		return nil
+	}
+	average := float64(seconds) / float64(responses)
+	return &average
+}
@@ -0,0 +1,245 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxLabelsPerUser     = 100
+	maxLabelNameLength   = 64
+	defaultLabeledLookup = 50
+	maxLabeledLookup     = 200
+)
+
+var (
+	ErrLabelNotFound     = errors.New("label not found")
+	ErrInvalidLabel      = errors.New("invalid label")
+	ErrLabelNameTaken    = errors.New("a label with this name already exists")
+	ErrTooManyLabels     = fmt.Errorf("at most %d labels are allowed", maxLabelsPerUser)
+	ErrLabelTargetAccess = errors.New("you do not have access to this conversation")
+
+	labelColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+)
+
+type LabelInput struct {
+	Name  string
+	Color string
+}
+
+func (input LabelInput) apply(label *domain.Label) error {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxLabelNameLength {
+		return fmt.Errorf("%w: name must be 1-%d characters", ErrInvalidLabel, maxLabelNameLength)
+	}
+	if input.Color != "" && !labelColorPattern.MatchString(input.Color) {
+		return fmt.Errorf("%w: color must look like #rrggbb", ErrInvalidLabel)
+	}
+	label.Name = name
+	label.Color = strings.ToLower(input.Color)
+	return nil
+}
+
+func (uc *AppUsecase) ListLabels(ctx context.Context, userID uuid.UUID) ([]domain.Label, error) {
+	return uc.repo.ListLabels(ctx, userID)
+}
+
+func (uc *AppUsecase) CreateLabel(ctx context.Context, userID uuid.UUID, input LabelInput) (*domain.Label, error) {
+	label := &domain.Label{ID: uuid.New(), UserID: userID}
+	if err := input.apply(label); err != nil {
+		return nil, err
+	}
+	count, err := uc.repo.CountLabels(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxLabelsPerUser {
+		return nil, ErrTooManyLabels
+	}
+	err = uc.repo.CreateLabel(ctx, label)
+	if errors.Is(err, repository.ErrLabelNameTaken) {
+		return nil, ErrLabelNameTaken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return label, nil
+}
+
+func (uc *AppUsecase) UpdateLabel(ctx context.Context, userID, labelID uuid.UUID, input LabelInput) (*domain.Label, error) {
+	label, err := uc.getOwnLabel(ctx, userID, labelID)
+	if err != nil {
+		return nil, err
+	}
+	if err := input.apply(label); err != nil {
+		return nil, err
+	}
+	updated, err := uc.repo.UpdateLabel(ctx, label)
+	if errors.Is(err, repository.ErrLabelNameTaken) {
+		return nil, ErrLabelNameTaken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, ErrLabelNotFound
+	}
+	return label, nil
+}
+
+func (uc *AppUsecase) DeleteLabel(ctx context.Context, userID, labelID uuid.UUID) error {
+	deleted, err := uc.repo.DeleteLabel(ctx, userID, labelID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrLabelNotFound
+	}
+	return nil
+}
+
+func (uc *AppUsecase) LabelMessage(ctx context.Context, userID, labelID uuid.UUID, messageID int64) error {
+	if _, err := uc.getOwnLabel(ctx, userID, labelID); err != nil {
+		return err
+	}
+	msg, err := uc.repo.GetMessageByID(ctx, messageID)
+	if err != nil {
+		return ErrMessageNotFound
+	}
+	if err := uc.requireLabelAccess(ctx, userID, msg.RoomID); err != nil {
+		return err
+	}
+	return uc.repo.AddMessageLabel(ctx, labelID, messageID)
+}
+
+func (uc *AppUsecase) UnlabelMessage(ctx context.Context, userID, labelID uuid.UUID, messageID int64) error {
+	if _, err := uc.getOwnLabel(ctx, userID, labelID); err != nil {
+		return err
+	}
+	removed, err := uc.repo.RemoveMessageLabel(ctx, labelID, messageID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrMessageNotFound
+	}
+	return nil
+}
+
+func (uc *AppUsecase) LabelRoom(ctx context.Context, userID, labelID, roomID uuid.UUID) error {
+	if _, err := uc.getOwnLabel(ctx, userID, labelID); err != nil {
+		return err
+	}
+	if err := uc.requireLabelAccess(ctx, userID, roomID); err != nil {
+		return err
+	}
+	return uc.repo.AddRoomLabel(ctx, labelID, roomID)
+}
+
+func (uc *AppUsecase) UnlabelRoom(ctx context.Context, userID, labelID, roomID uuid.UUID) error {
+	if _, err := uc.getOwnLabel(ctx, userID, labelID); err != nil {
+		return err
+	}
+	removed, err := uc.repo.RemoveRoomLabel(ctx, labelID, roomID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrRoomNotFound
+	}
+	return nil
+}
+
+func (uc *AppUsecase) GetLabeledMessages(ctx context.Context, userID, labelID uuid.UUID, limit int) ([]domain.Message, error) {
+	if _, err := uc.getOwnLabel(ctx, userID, labelID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultLabeledLookup
+	}
+	if limit > maxLabeledLookup {
+		limit = maxLabeledLookup
+	}
+	isAgent, err := uc.repo.IsSupportAgent(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return uc.repo.GetLabeledMessages(ctx, userID, labelID, isAgent, limit)
+}
+
+func (uc *AppUsecase) GetLabeledRooms(ctx context.Context, userID, labelID uuid.UUID) ([]domain.Room, error) {
+	if _, err := uc.getOwnLabel(ctx, userID, labelID); err != nil {
+		return nil, err
+	}
+	roomIDs, err := uc.repo.GetLabeledRoomIDs(ctx, labelID)
+	if err != nil {
+		return nil, err
+	}
+	rooms, err := uc.repo.GetRoomsForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]domain.Room, len(rooms))
+	for _, room := range rooms {
+		byID[room.ID] = room
+	}
+	labeled := []domain.Room{}
+	for _, id := range roomIDs {
+		if room, ok := byID[id]; ok {
+			labeled = append(labeled, room)
+			continue
+		}
+		if uc.requireLabelAccess(ctx, userID, id) != nil {
+			continue
+		}
+		if room, err := uc.repo.GetRoomByID(ctx, id); err == nil {
+			labeled = append(labeled, *room)
+		}
+	}
+	return labeled, nil
+}
+
+func (uc *AppUsecase) getOwnLabel(ctx context.Context, userID, labelID uuid.UUID) (*domain.Label, error) {
+	label, err := uc.repo.GetLabel(ctx, userID, labelID)
+	if err != nil {
+		return nil, err
+	}
+	if label == nil {
+		return nil, ErrLabelNotFound
+	}
+	return label, nil
+}
+
+func (uc *AppUsecase) requireLabelAccess(ctx context.Context, userID, roomID uuid.UUID) error {
+	isMember, err := uc.repo.IsUserInRoom(ctx, userID, roomID)
+	if err != nil {
+		return err
+	}
+	if isMember {
+		return nil
+	}
+	conv, err := uc.repo.GetSupportConversation(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	if conv == nil {
+		return ErrLabelTargetAccess
+	}
+	isAgent, err := uc.repo.IsSupportAgent(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !isAgent {
+		return ErrLabelTargetAccess
+	}
+	return nil
+}
@@ -0,0 +1,306 @@
+package usecase
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"chatservice/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+const (
+	maxRichContentBytes   = 16 * 1024
+	maxRichNodes          = 500
+	maxRichDepth          = 8
+	maxLinksPerMessage    = 20
+	maxHashtagsPerMessage = 20
+)
+
+var ErrInvalidContent = errors.New("invalid message content")
+
+var (
+	htmlTagPattern = regexp.MustCompile(`(?s)<!--.*?-->|</?[A-Za-z][^>]*>`)
+	linkPattern    = regexp.MustCompile(`(?i)\b(?:https?://|mailto:)[^\s<>()\[\]"']+`)
+	hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_#&/])#([\p{L}\p{N}_]{1,64})`)
+	letterPattern  = regexp.MustCompile(`\p{L}`)
+
+	richNodeTypes = map[string]bool{
+		"paragraph": true, "text": true, "link": true, "mention": true, "code_block": true,
+		"quote": true, "bullet_list": true, "ordered_list": true, "list_item": true, "hard_break": true,
+	}
+	richMarks = map[string]bool{"bold": true, "italic": true, "strike": true, "code": true}
+
+	markdown     = goldmark.New()
+	markdownText = bluemonday.StrictPolicy()
+)
+
+type processedContent struct {
+	Content     string
+	RichContent json.RawMessage
+	Mentions    []string
+	Links       []string
+	Hashtags    []string
+}
+
+type richNode struct {
+	Type    string     `json:"type"`
+	Text    string     `json:"text,omitempty"`
+	Href    string     `json:"href,omitempty"`
+	UserID  string     `json:"userId,omitempty"`
+	Marks   []string   `json:"marks,omitempty"`
+	Content []richNode `json:"content,omitempty"`
+}
+
+func processMessage(contentType, raw string) (processedContent, error) {
+	content, rich, err := processContent(contentType, canonicalText(raw))
+	if err != nil {
+		return processedContent{}, err
+	}
+	links := extractLinks(content)
+	if len(rich) > 0 {
+		var doc richNode
+		if err := json.Unmarshal(rich, &doc); err == nil {
+			links = appendRichLinks(links, doc.Content)
+		}
+	}
+	if len(links) > maxLinksPerMessage {
+		links = links[:maxLinksPerMessage]
+	}
+	return processedContent{
+		Content:     content,
+		RichContent: rich,
+		Mentions:    parseMentions(content),
+		Links:       links,
+		Hashtags:    parseHashtags(content),
+	}, nil
+}
+
+func canonicalText(raw string) string {
+	raw = strings.ReplaceAll(raw, "\r\n", "\n")
+	raw = strings.ToValidUTF8(raw, "")
+	clean := strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n' || r == '\t':
+			return r
+		case r == '\r':
+			return '\n'
+		case unicode.IsControl(r), r == '\u200b', r == '\u200e', r == '\u200f', r == '\u202e', r == '\ufeff':
+			return -1
+		}
+		return r
+	}, raw)
+	return strings.TrimSpace(clean)
+}
+
+func extractLinks(content string) []string {
+	var links []string
+	seen := make(map[string]bool)
+	for _, link := range linkPattern.FindAllString(content, -1) {
+		link = strings.TrimRight(link, ".,;:!?")
+		if seen[link] {
+			continue
+		}
+		seen[link] = true
+		links = append(links, link)
+	}
+	return links
+}
+
+func appendRichLinks(links []string, nodes []richNode) []string {
+	for _, node := range nodes {
+		if node.Type == "link" && !slices.Contains(links, node.Href) {
+			links = append(links, node.Href)
+		}
+		links = appendRichLinks(links, node.Content)
+	}
+	return links
+}
+
+func parseHashtags(content string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, match := range hashtagPattern.FindAllStringSubmatch(content, -1) {
+		tag := strings.ToLower(match[1])
+		if !letterPattern.MatchString(tag) || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+		if len(tags) == maxHashtagsPerMessage {
+			break
+		}
+	}
+	return tags
+}
+
+func processContent(contentType, raw string) (string, json.RawMessage, error) {
+	switch contentType {
+	case "", domain.ContentTypePlain:
+		return raw, nil, nil
+	case domain.ContentTypeMarkdown:
+		clean := htmlTagPattern.ReplaceAllString(raw, "")
+		if !markdownSafe(clean) {
+			return "", nil, fmt.Errorf("%w: markdown may only contain http, https or mailto links and no raw HTML", ErrInvalidContent)
+		}
+		return clean, nil, nil
+	case domain.ContentTypeRich:
+		return sanitizeRich(raw)
+	default:
+		return "", nil, fmt.Errorf("%w: content type must be %q, %q or %q", ErrInvalidContent, domain.ContentTypePlain, domain.ContentTypeMarkdown, domain.ContentTypeRich)
+	}
+}
+
+func safeLink(href string) bool {
+	href = strings.TrimSpace(html.UnescapeString(href))
+	if strings.ContainsFunc(href, unicode.IsControl) {
+		return false
+	}
+	// Browsers treat backslashes like slashes, so `\\host` and `/\host` are
+	// protocol-relative too.
+	if len(href) >= 2 && strings.ContainsRune(`/\`, rune(href[0])) && strings.ContainsRune(`/\`, rune(href[1])) {
+		return false
+	}
+	u, err := url.Parse(href)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "", "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+func sanitizeMarkdown(raw string) string {
+	clean := htmlTagPattern.ReplaceAllString(raw, "")
+	if markdownSafe(clean) {
+		return clean
+	}
+	var rendered bytes.Buffer
+	if err := markdown.Convert([]byte(clean), &rendered); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(html.UnescapeString(markdownText.Sanitize(rendered.String())))
+}
+
+func markdownSafe(source string) bool {
+	src := []byte(source)
+	safe := true
+	ast.Walk(markdown.Parser().Parse(text.NewReader(src)), func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := n.(type) {
+		case *ast.Link:
+			safe = safeLink(string(node.Destination))
+		case *ast.Image:
+			safe = safeLink(string(node.Destination))
+		case *ast.AutoLink:
+			safe = safeLink(string(node.URL(src)))
+		case *ast.RawHTML, *ast.HTMLBlock:
+			safe = false
+		}
+		if !safe {
+			return ast.WalkStop, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return safe
+}
+
+func sanitizeRich(raw string) (string, json.RawMessage, error) {
+	if len(raw) > maxRichContentBytes {
+		return "", nil, fmt.Errorf("%w: rich content exceeds %d bytes", ErrInvalidContent, maxRichContentBytes)
+	}
+	var doc richNode
+	if err := json.Unmarshal([]byte(raw), &doc); err != nil || doc.Type != "doc" {
+		return "", nil, fmt.Errorf("%w: rich content must be a JSON document of type \"doc\"", ErrInvalidContent)
+	}
+	count := 0
+	if err := sanitizeRichNodes(doc.Content, 1, &count); err != nil {
+		return "", nil, err
+	}
+	doc = richNode{Type: "doc", Content: doc.Content}
+	canonical, err := json.Marshal(doc)
+	if err != nil {
+		return "", nil, err
+	}
+	var plain strings.Builder
+	writeRichText(&plain, doc.Content)
+	return strings.TrimSpace(plain.String()), canonical, nil
+}
+
+func sanitizeRichNodes(nodes []richNode, depth int, count *int) error {
+	if len(nodes) > 0 && depth > maxRichDepth {
+		return fmt.Errorf("%w: rich content is nested more than %d levels", ErrInvalidContent, maxRichDepth)
+	}
+	for i := range nodes {
+		node := &nodes[i]
+		*count++
+		if *count > maxRichNodes {
+			return fmt.Errorf("%w: rich content has more than %d nodes", ErrInvalidContent, maxRichNodes)
+		}
+		if !richNodeTypes[node.Type] {
+			return fmt.Errorf("%w: unsupported rich node type %q", ErrInvalidContent, node.Type)
+		}
+		for _, mark := range node.Marks {
+			if !richMarks[mark] {
+				return fmt.Errorf("%w: unsupported rich mark %q", ErrInvalidContent, mark)
+			}
+		}
+		switch node.Type {
+		case "link":
+			if !safeLink(node.Href) {
+				return fmt.Errorf("%w: links must use http, https or mailto", ErrInvalidContent)
+			}
+		case "mention":
+			if _, err := uuid.Parse(node.UserID); err != nil {
+				return fmt.Errorf("%w: mentions must reference a user ID", ErrInvalidContent)
+			}
+		default:
+			node.Href, node.UserID = "", ""
+		}
+		if node.Type == "text" || node.Type == "hard_break" {
+			node.Content = nil
+		}
+		if node.Type != "text" && node.Type != "link" && node.Type != "mention" {
+			node.Text = ""
+		}
+		node.Text = htmlTagPattern.ReplaceAllString(node.Text, "")
+		if err := sanitizeRichNodes(node.Content, depth+1, count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeRichText(b *strings.Builder, nodes []richNode) {
+	for _, node := range nodes {
+		switch node.Type {
+		case "hard_break":
+			b.WriteString("\n")
+		case "mention":
+			b.WriteString("@" + node.Text)
+		default:
+			b.WriteString(node.Text)
+		}
+		writeRichText(b, node.Content)
+		switch node.Type {
+		case "paragraph", "code_block", "quote", "list_item":
+			b.WriteString("\n")
+		}
+	}
+}
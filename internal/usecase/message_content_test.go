@@ -0,0 +1,200 @@
+package usecase
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"chatservice/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+func TestSafeLink(t *testing.T) {
+	tests := []struct {
+		href string
+		safe bool
+	}{
+		{"https://example.com/a?b=c", true},
+		{"http://example.com", true},
+		{"mailto:ann@example.com", true},
+		{"/rooms/123", true},
+		{"#section", true},
+		{"javascript:alert(1)", false},
+		{"JavaScript:alert(1)", false},
+		{"  javascript:alert(1)", false},
+		{"vbscript:msgbox(1)", false},
+		{"data:text/html;base64,PHNjcmlwdD4=", false},
+		{"&#106;avascript:alert(1)", false},
+		{"&#x6A;avascript:alert(1)", false},
+		{"java&#x09;script:alert(1)", false},
+		{"java\tscript:alert(1)", false},
+		{"&#100;ata:text/html,x", false},
+		{"//evil.example", false},
+		{" //evil.example", false},
+		{`\\evil.example`, false},
+		{`/\evil.example`, false},
+		{`\/evil.example`, false},
+		{"&#47;&#47;evil.example", false},
+	}
+	for _, tt := range tests {
+		if got := safeLink(tt.href); got != tt.safe {
+			t.Errorf("safeLink(%q) = %v, want %v", tt.href, got, tt.safe)
+		}
+	}
+}
+
+func TestMarkdownSafe(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		safe   bool
+	}{
+		{"plain", "**bold** and _italic_", true},
+		{"http link", "[docs](https://example.com)", true},
+		{"autolink", "<https://example.com>", true},
+		{"javascript link", "[x](javascript:alert(1))", false},
+		{"entity-encoded link", "[x](&#106;avascript:alert(1))", false},
+		{"data image", "![x](data:image/svg+xml;base64,PHN2Zz4=)", false},
+		{"protocol-relative link", "[x](//evil.example)", false},
+		{"reference link", "[x][1]\n\n[1]: javascript:alert(1)", false},
+		{"inline html", "hello <b>there</b>", false},
+		{"html block", "<div>\nhi\n</div>", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := markdownSafe(tt.source); got != tt.safe {
+				t.Errorf("markdownSafe(%q) = %v, want %v", tt.source, got, tt.safe)
+			}
+		})
+	}
+}
+
+func TestProcessMessageMarkdown(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		content string
+		invalid bool
+	}{
+		{"strips raw html", "hi <script>alert(1)</script>there", "hi alert(1)there", false},
+		{"strips comments", "a<!-- hidden -->b", "ab", false},
+		{"strips event handlers", `<img src=x onerror="alert(1)">hi`, "hi", false},
+		{"rejects javascript link", "[x](javascript:alert(1))", "", true},
+		{"rejects protocol-relative link", "[x](//evil.example)", "", true},
+		{"drops control characters", "a\u202eb\u200bc", "abc", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := processMessage(domain.ContentTypeMarkdown, tt.raw)
+			if tt.invalid {
+				if !errors.Is(err, ErrInvalidContent) {
+					t.Fatalf("processMessage(%q) error = %v, want ErrInvalidContent", tt.raw, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("processMessage(%q) failed: %v", tt.raw, err)
+			}
+			if got.Content != tt.content {
+				t.Errorf("content = %q, want %q", got.Content, tt.content)
+			}
+		})
+	}
+}
+
+func TestProcessMessageMentions(t *testing.T) {
+	got, err := processMessage(domain.ContentTypePlain, "hi @Ann and @bob. also ann@example.com, @ann again")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"ann", "bob"}; !slices.Equal(got.Mentions, want) {
+		t.Errorf("mentions = %q, want %q", got.Mentions, want)
+	}
+}
+
+func richDoc(nodes ...map[string]any) string {
+	data, _ := json.Marshal(map[string]any{"type": "doc", "content": nodes})
+	return string(data)
+}
+
+func paragraph(children ...map[string]any) map[string]any {
+	return map[string]any{"type": "paragraph", "content": children}
+}
+
+func nested(depth int) map[string]any {
+	node := map[string]any{"type": "text", "text": "deep"}
+	for range depth - 1 {
+		node = map[string]any{"type": "quote", "content": []map[string]any{node}}
+	}
+	return node
+}
+
+func TestSanitizeRich(t *testing.T) {
+	userID := uuid.New()
+	many := make([]map[string]any, maxRichNodes)
+	for i := range many {
+		many[i] = map[string]any{"type": "text", "text": "x"}
+	}
+
+	tests := []struct {
+		name    string
+		raw     string
+		plain   string
+		invalid bool
+	}{
+		{"text and marks", richDoc(paragraph(map[string]any{"type": "text", "text": "hi", "marks": []string{"bold"}})), "hi", false},
+		{"safe link", richDoc(paragraph(map[string]any{"type": "link", "text": "docs", "href": "https://example.com"})), "docs", false},
+		{"valid mention", richDoc(paragraph(map[string]any{"type": "mention", "text": "ann", "userId": userID.String()})), "@ann", false},
+		{"strips html from text", richDoc(paragraph(map[string]any{"type": "text", "text": "<b>hi</b>"})), "hi", false},
+		{"max depth", richDoc(nested(maxRichDepth)), "deep", false},
+		{"javascript link", richDoc(paragraph(map[string]any{"type": "link", "text": "x", "href": "javascript:alert(1)"})), "", true},
+		{"entity-encoded link", richDoc(paragraph(map[string]any{"type": "link", "text": "x", "href": "&#106;avascript:alert(1)"})), "", true},
+		{"data link", richDoc(paragraph(map[string]any{"type": "link", "text": "x", "href": "data:text/html,x"})), "", true},
+		{"protocol-relative link", richDoc(paragraph(map[string]any{"type": "link", "text": "x", "href": "//evil.example"})), "", true},
+		{"mention without user ID", richDoc(paragraph(map[string]any{"type": "mention", "text": "ann", "userId": "ann"})), "", true},
+		{"unknown node type", richDoc(map[string]any{"type": "html", "text": "<script>"}), "", true},
+		{"unknown mark", richDoc(paragraph(map[string]any{"type": "text", "text": "x", "marks": []string{"onclick"}})), "", true},
+		{"too deep", richDoc(nested(maxRichDepth + 1)), "", true},
+		{"too many nodes", richDoc(paragraph(many...)), "", true},
+		{"not a doc", `{"type":"paragraph"}`, "", true},
+		{"too large", richDoc(paragraph(map[string]any{"type": "text", "text": strings.Repeat("x", maxRichContentBytes)})), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plain, canonical, err := sanitizeRich(tt.raw)
+			if tt.invalid {
+				if !errors.Is(err, ErrInvalidContent) {
+					t.Fatalf("sanitizeRich error = %v, want ErrInvalidContent", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("sanitizeRich failed: %v", err)
+			}
+			if plain != tt.plain {
+				t.Errorf("plain text = %q, want %q", plain, tt.plain)
+			}
+			if strings.Contains(string(canonical), "<") {
+				t.Errorf("canonical document kept markup: %s", canonical)
+			}
+		})
+	}
+}
+
+func TestSanitizeRichDropsStrayFields(t *testing.T) {
+	raw := `{"type":"doc","extra":1,"content":[{"type":"paragraph","text":"hidden","href":"https://example.com","content":[{"type":"text","text":"shown","userId":"x","content":[{"type":"text","text":"child"}]}]}]}`
+	plain, canonical, err := sanitizeRich(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain != "shown" {
+		t.Errorf("plain text = %q, want %q", plain, "shown")
+	}
+	want := `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"shown"}]}]}`
+	if string(canonical) != want {
+		t.Errorf("canonical = %s, want %s", canonical, want)
+	}
+}
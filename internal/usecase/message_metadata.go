@@ -0,0 +1,77 @@
+package usecase
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"chatservice/internal/domain"
+)
+
+const (
+	maxMessageMetadataBytes = 2 * 1024
+	maxMessageActions       = 5
+	maxActionIDLength       = 64
+	maxActionLabelLength    = 80
+)
+
+func validateMessageMetadata(raw string) (json.RawMessage, error) {
+	if len(raw) > maxMessageMetadataBytes {
+		return nil, fmt.Errorf("message metadata exceeds %d bytes", maxMessageMetadataBytes)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(raw), &fields); err != nil || fields == nil {
+		return nil, fmt.Errorf("message metadata must be a JSON object")
+	}
+	if rawPriority, ok := fields["priority"]; ok {
+		var priority string
+		if json.Unmarshal(rawPriority, &priority) != nil || (priority != domain.MessagePriorityNormal && priority != domain.MessagePriorityUrgent) {
+			return nil, fmt.Errorf("message priority must be \"normal\" or \"urgent\"")
+		}
+	}
+	if rawActions, ok := fields["actions"]; ok {
+		if _, err := parseMessageActions(rawActions); err != nil {
+			return nil, err
+		}
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, []byte(raw)); err != nil {
+		return nil, fmt.Errorf("message metadata must be a JSON object")
+	}
+	return compact.Bytes(), nil
+}
+
+func parseMessageActions(raw json.RawMessage) ([]domain.MessageAction, error) {
+	var actions []domain.MessageAction
+	if err := json.Unmarshal(raw, &actions); err != nil {
+		return nil, fmt.Errorf("message actions must be a list of {id, label} objects")
+	}
+	if len(actions) > maxMessageActions {
+		return nil, fmt.Errorf("a message may have at most %d actions", maxMessageActions)
+	}
+	seen := make(map[string]bool, len(actions))
+	for _, action := range actions {
+		if action.ID == "" || len(action.ID) > maxActionIDLength || seen[action.ID] {
+			return nil, fmt.Errorf("action ids must be unique and 1-%d characters", maxActionIDLength)
+		}
+		if action.Label == "" || len(action.Label) > maxActionLabelLength {
+			return nil, fmt.Errorf("action labels must be 1-%d characters", maxActionLabelLength)
+		}
+		seen[action.ID] = true
+	}
+	return actions, nil
+}
+
+func messageActions(msg *domain.Message) []domain.MessageAction {
+	if len(msg.Metadata) == 0 {
+		return nil
+	}
+	var fields struct {
+		Actions json.RawMessage `json:"actions"`
+	}
+	if err := json.Unmarshal(msg.Metadata, &fields); err != nil || len(fields.Actions) == 0 {
+		return nil
+	}
+	actions, _ := parseMessageActions(fields.Actions)
+	return actions
+}
@@ -0,0 +1,25 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+
+	"chatservice/internal/events"
+
+	"github.com/google/uuid"
+)
+
+var ErrMessageNotFound = errors.New("message not found")
+
+func (uc *AppUsecase) ModerateDeleteMessage(ctx context.Context, adminID uuid.UUID, messageID int64) error {
+	msg, err := uc.repo.GetMessageByID(ctx, messageID)
+	if err != nil {
+		return ErrMessageNotFound
+	}
+	if err := uc.repo.DeleteMessage(ctx, msg.ID, msg.UserID); err != nil {
+		return err
+	}
+	uc.events.Publish(ctx, events.MessageDeleted{MessageID: msg.ID, RoomID: msg.RoomID, DeleterID: adminID})
+	ucLog.Infof("Admin %s removed message %d by %s in room %s", adminID, msg.ID, msg.UserID, msg.RoomID)
+	return nil
+}
@@ -0,0 +1,28 @@
+package usecase
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+type pageLimits struct {
+	defaultLimit int
+	maxLimit     int
+}
+
+func (uc *AppUsecase) SetPageLimits(defaultLimit, maxLimit int) {
+	if maxLimit <= 0 {
+		maxLimit = maxPageLimit
+	}
+	if defaultLimit <= 0 || defaultLimit > maxLimit {
+		defaultLimit = min(defaultPageLimit, maxLimit)
+	}
+	uc.pageLimits = pageLimits{defaultLimit: defaultLimit, maxLimit: maxLimit}
+}
+
+func (p pageLimits) apply(limit, offset int) (int, int) {
+	if limit <= 0 {
+		limit = p.defaultLimit
+	}
+	return min(limit, p.maxLimit), max(offset, 0)
+}
@@ -0,0 +1,59 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+const (
+	quotaMessages       = "messages"
+	quotaFriendRequests = "friend_requests"
+	quotaRoomsCreated   = "rooms_created"
+	quotaUrgentMessages = "urgent_messages"
+)
+
+var (
+	ErrQuotaExceeded              = errors.New("daily quota exceeded")
+	ErrMessageQuotaExceeded       = fmt.Errorf("%w: too many messages sent today", ErrQuotaExceeded)
+	ErrFriendRequestQuotaExceeded = fmt.Errorf("%w: too many friend requests sent today", ErrQuotaExceeded)
+	ErrRoomQuotaExceeded          = fmt.Errorf("%w: too many rooms created today", ErrQuotaExceeded)
+	ErrUrgentQuotaExceeded        = fmt.Errorf("%w: too many urgent messages sent today", ErrQuotaExceeded)
+)
+
+type DailyQuotas struct {
+	Messages       int
+	FriendRequests int
+	RoomsCreated   int
+	UrgentMessages int
+}
+
+func (uc *AppUsecase) SetDailyQuotas(quotas DailyQuotas) { uc.quotas = quotas }
+
+func (uc *AppUsecase) consumeQuota(ctx context.Context, userID uuid.UUID, kind string) error {
+	var limit int
+	var exceeded error
+	switch kind {
+	case quotaMessages:
+		limit, exceeded = uc.quotas.Messages, ErrMessageQuotaExceeded
+	case quotaFriendRequests:
+		limit, exceeded = uc.quotas.FriendRequests, ErrFriendRequestQuotaExceeded
+	case quotaRoomsCreated:
+		limit, exceeded = uc.quotas.RoomsCreated, ErrRoomQuotaExceeded
+	case quotaUrgentMessages:
+		limit, exceeded = uc.quotas.UrgentMessages, ErrUrgentQuotaExceeded
+	}
+	if limit <= 0 {
+		return nil
+	}
+	ok, err := uc.repo.ConsumeDailyQuota(ctx, userID, kind, limit)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return exceeded
+	}
+	return nil
+}
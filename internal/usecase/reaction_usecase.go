@@ -0,0 +1,84 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"unicode"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/events"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxReactionLength    = 32
+	maxRoomHighlights    = 10
+	maxRoomHighlightDays = 31
+)
+
+var ErrInvalidReaction = errors.New("reaction must be 1-32 bytes without spaces or control characters")
+
+func validReaction(emoji string) bool {
+	if emoji == "" || len(emoji) > maxReactionLength {
+		return false
+	}
+	for _, r := range emoji {
+		if r == unicode.ReplacementChar || unicode.IsSpace(r) || unicode.IsControl(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func (uc *AppUsecase) ReactToMessage(ctx context.Context, userID, roomID uuid.UUID, messageID int64, emoji string, add bool) error {
+	if !validReaction(emoji) {
+		return ErrInvalidReaction
+	}
+	isMember, err := uc.repo.IsUserInRoom(ctx, userID, roomID)
+	if err != nil {
+		return fmt.Errorf("could not verify room membership: %w", err)
+	}
+	if !isMember {
+		return ErrNotRoomMember
+	}
+	msg, err := uc.repo.GetMessageByID(ctx, messageID)
+	if err != nil || msg.RoomID != roomID {
+		return ErrMessageNotFound
+	}
+
+	var changed bool
+	if add {
+		changed, err = uc.repo.AddMessageReaction(ctx, messageID, userID, emoji)
+	} else {
+		changed, err = uc.repo.RemoveMessageReaction(ctx, messageID, userID, emoji)
+	}
+	if err != nil || !changed {
+		return err
+	}
+	uc.events.Publish(ctx, events.MessageReactionChanged{Reaction: domain.MessageReaction{
+		RoomID:    roomID,
+		MessageID: messageID,
+		UserID:    userID,
+		Emoji:     emoji,
+		Added:     add,
+	}})
+	return nil
+}
+
+// GetRoomHighlights returns the most-reacted messages of the last days days.
+func (uc *AppUsecase) GetRoomHighlights(ctx context.Context, userID, roomID uuid.UUID, days int) ([]domain.RoomHighlight, error) {
+	if days <= 0 || days > maxRoomHighlightDays {
+		days = 7
+	}
+	isMember, err := uc.repo.IsUserInRoom(ctx, userID, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("could not verify room membership: %w", err)
+	}
+	if !isMember {
+		return nil, ErrNotRoomMember
+	}
+	return uc.repo.GetRoomHighlights(ctx, roomID, time.Now().AddDate(0, 0, -days), maxRoomHighlights)
+}
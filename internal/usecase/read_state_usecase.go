@@ -0,0 +1,103 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/events"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxBulkReadRooms      = 100
+	maxMentionsPerMessage = 20
+)
+
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([A-Za-z0-9_.-]{1,64})`)
+
+type RoomReadMarker struct {
+	RoomID        uuid.UUID `json:"roomId" binding:"required"`
+	LastMessageID int64     `json:"lastMessageId" binding:"required,min=1"`
+}
+
+type RoomReadResult struct {
+	RoomID uuid.UUID `json:"roomId"`
+	Status string    `json:"status"`
+	Marked int64     `json:"marked"`
+	Error  string    `json:"error,omitempty"`
+}
+
+func parseMentions(content string) []string {
+	var usernames []string
+	seen := make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		username := strings.ToLower(strings.TrimRight(match[1], "."))
+		if username == "" || seen[username] {
+			continue
+		}
+		seen[username] = true
+		usernames = append(usernames, username)
+		if len(usernames) == maxMentionsPerMessage {
+			break
+		}
+	}
+	return usernames
+}
+
+func (uc *AppUsecase) recordMentions(ctx context.Context, msg *domain.Message, usernames []string) {
+	if len(usernames) == 0 {
+		return
+	}
+	mentioned, err := uc.repo.RecordMentions(ctx, msg.ID, msg.RoomID, msg.UserID, usernames)
+	if err != nil {
+		ucLog.Errorf("Failed to record mentions of message %d: %v", msg.ID, err)
+		return
+	}
+	msg.Mentions = mentioned
+}
+
+func (uc *AppUsecase) MarkRoomsRead(ctx context.Context, userID uuid.UUID, markers []RoomReadMarker) ([]RoomReadResult, error) {
+	if len(markers) > maxBulkReadRooms {
+		return nil, fmt.Errorf("at most %d rooms can be marked read at once", maxBulkReadRooms)
+	}
+
+	results := make([]RoomReadResult, 0, len(markers))
+	seen := make(map[uuid.UUID]bool, len(markers))
+	for _, marker := range markers {
+		if seen[marker.RoomID] {
+			continue
+		}
+		seen[marker.RoomID] = true
+
+		result := RoomReadResult{RoomID: marker.RoomID, Status: "read"}
+		marked, err := uc.markRoomRead(ctx, userID, marker)
+		if err != nil {
+			result.Status = "failed"
+			result.Error = err.Error()
+		}
+		result.Marked = marked
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (uc *AppUsecase) markRoomRead(ctx context.Context, userID uuid.UUID, marker RoomReadMarker) (int64, error) {
+	isMember, err := uc.repo.IsUserInRoom(ctx, userID, marker.RoomID)
+	if err != nil {
+		return 0, fmt.Errorf("could not verify room membership: %w", err)
+	}
+	if !isMember {
+		return 0, ErrNotRoomMember
+	}
+	marked, err := uc.repo.MarkRoomReadUpTo(ctx, userID, marker.RoomID, marker.LastMessageID)
+	if err != nil {
+		return 0, err
+	}
+	uc.events.Publish(ctx, events.MessageRead{MessageID: marker.LastMessageID, RoomID: marker.RoomID, UserID: userID, ReadAt: time.Now()})
+	return marked, nil
+}
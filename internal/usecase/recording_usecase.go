@@ -0,0 +1,118 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/encode"
+	"chatservice/internal/events"
+	"chatservice/internal/sfu"
+
+	"github.com/google/uuid"
+)
+
+const (
+	recordingStopDeclined = "declined"
+	recordingStopFailed   = "failed"
+	recordingStopFinished = "finished"
+)
+
+func (uc *AppUsecase) ListCallRecordings(ctx context.Context, userID, roomID uuid.UUID) ([]domain.Attachment, error) {
+	isMember, err := uc.repo.IsUserInRoom(ctx, userID, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("could not verify room membership: %w", err)
+	}
+	if !isMember {
+		return nil, fmt.Errorf("user not authorized to access this room")
+	}
+	return uc.repo.GetAttachmentsForUser(ctx, roomID, userID, domain.AttachmentKindCallRecording)
+}
+
+func (uc *AppUsecase) GetCallRecording(ctx context.Context, userID, roomID, recordingID uuid.UUID) (*domain.Attachment, error) {
+	att, err := uc.repo.GetAttachmentForUser(ctx, recordingID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if att.RoomID != roomID || att.Kind != domain.AttachmentKindCallRecording {
+		return nil, fmt.Errorf("attachment not found")
+	}
+	return att, nil
+}
+
+func (uc *AppUsecase) handleRecordingRequest(ctx context.Context, userID, roomID uuid.UUID) {
+	rec, ready, err := uc.callStates.requestRecording(roomID, userID)
+	if err != nil {
+		uc.bcast.SendToUser(userID, encode.EncodeError(err.Error()))
+		return
+	}
+	uc.events.Publish(ctx, events.CallRecordingRequested{RoomID: roomID, RecordingID: rec.id, RequesterID: userID})
+	if ready {
+		uc.startRecording(ctx, rec)
+	}
+}
+
+func (uc *AppUsecase) handleRecordingConsent(ctx context.Context, userID, recordingID uuid.UUID, agree bool) {
+	rec, ready, err := uc.callStates.consent(recordingID, userID, agree)
+	if err != nil {
+		uc.bcast.SendToUser(userID, encode.EncodeError(err.Error()))
+		return
+	}
+	if !agree {
+		ucLog.Infof("User %s declined recording %s in room %s", userID, recordingID, rec.roomID)
+		uc.events.Publish(ctx, events.CallRecordingStopped{RoomID: rec.roomID, RecordingID: rec.id, Reason: recordingStopDeclined})
+		return
+	}
+	if ready {
+		uc.startRecording(ctx, rec)
+	}
+}
+
+func (uc *AppUsecase) handleRecordingStop(ctx context.Context, userID, roomID, recordingID uuid.UUID) {
+	if err := uc.callStates.activeRecording(roomID, userID, recordingID); err != nil {
+		uc.bcast.SendToUser(userID, encode.EncodeError(err.Error()))
+		return
+	}
+	if err := uc.sfu.StopRecording(ctx, roomID, recordingID); err != nil {
+		ucLog.Errorf("Failed to stop recording %s in room %s: %v", recordingID, roomID, err)
+		uc.bcast.SendToUser(userID, encode.EncodeError("Could not stop recording"))
+	}
+}
+
+func (uc *AppUsecase) startRecording(ctx context.Context, rec *callRecording) {
+	if err := uc.sfu.StartRecording(ctx, rec.roomID, rec.id); err != nil {
+		ucLog.Errorf("Failed to start recording %s in room %s: %v", rec.id, rec.roomID, err)
+		uc.callStates.takeRecording(rec.id)
+		uc.events.Publish(ctx, events.CallRecordingStopped{RoomID: rec.roomID, RecordingID: rec.id, Reason: recordingStopFailed})
+	}
+}
+
+func (uc *AppUsecase) storeRecording(ctx context.Context, roomID uuid.UUID, artifact *sfu.Recording) error {
+	rec := uc.callStates.takeRecording(artifact.ID)
+	if rec == nil || rec.roomID != roomID {
+		return fmt.Errorf("unknown recording %s", artifact.ID)
+	}
+
+	allowed := []uuid.UUID{rec.requester}
+	for userID := range rec.participants {
+		if userID != rec.requester {
+			allowed = append(allowed, userID)
+		}
+	}
+
+	att := &domain.Attachment{
+		ID:          rec.id,
+		RoomID:      roomID,
+		UploaderID:  rec.requester,
+		Kind:        domain.AttachmentKindCallRecording,
+		StorageURL:  artifact.URL,
+		ContentType: artifact.ContentType,
+		SizeBytes:   artifact.Size,
+	}
+	if err := uc.repo.CreateRestrictedAttachment(ctx, att, allowed); err != nil {
+		return fmt.Errorf("could not store recording %s: %w", rec.id, err)
+	}
+
+	uc.events.Publish(ctx, events.CallRecordingStopped{RoomID: roomID, RecordingID: rec.id, Reason: recordingStopFinished, AttachmentID: att.ID})
+	return nil
+}
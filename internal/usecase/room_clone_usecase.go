@@ -0,0 +1,79 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/events"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrRoomNotCloneable   = errors.New("only group rooms can be cloned")
+	ErrRoomCloneForbidden = errors.New("only room owners and admins may clone this room")
+	ErrInvalidRoomClone   = errors.New("invalid room clone")
+)
+
+type RoomClone struct {
+	Room      *domain.Room `json:"room"`
+	SourceID  uuid.UUID    `json:"sourceId"`
+	MemberIDs []uuid.UUID  `json:"memberIds"`
+}
+
+func (uc *AppUsecase) CloneRoom(ctx context.Context, userID, roomID uuid.UUID, name string, includeMembers bool) (*RoomClone, error) {
+	if err := uc.requireRoomAdmin(ctx, userID, roomID, ErrRoomCloneForbidden); err != nil {
+		return nil, err
+	}
+	source, err := uc.repo.GetRoomByID(ctx, roomID)
+	if err != nil {
+		return nil, ErrRoomNotFound
+	}
+	if source.Type != "group" {
+		return nil, ErrRoomNotCloneable
+	}
+	name = strings.TrimSpace(name)
+	if name == "" && source.Name != nil {
+		name = *source.Name
+	}
+	if name == "" || utf8.RuneCountInString(name) > maxRoomNameLength {
+		return nil, fmt.Errorf("%w: name must be 1-%d characters", ErrInvalidRoomClone, maxRoomNameLength)
+	}
+	if err := uc.consumeQuota(ctx, userID, quotaRoomsCreated); err != nil {
+		return nil, err
+	}
+
+	tx, err := uc.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	room, err := uc.repo.CreateRoom(ctx, tx, &domain.Room{Type: "group", Name: &name, OwnerID: &userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cloned room: %w", err)
+	}
+	if err := uc.repo.CopyRoomMetadata(ctx, tx, roomID, room.ID); err != nil {
+		return nil, err
+	}
+	if err := uc.repo.AddUserToRoomWithRole(ctx, tx, userID, room.ID, "owner"); err != nil {
+		return nil, fmt.Errorf("failed to add owner to cloned room: %w", err)
+	}
+	clone := &RoomClone{Room: room, SourceID: roomID, MemberIDs: []uuid.UUID{}}
+	if includeMembers {
+		if clone.MemberIDs, err = uc.repo.CopyRoomMembers(ctx, tx, roomID, room.ID, userID); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("transaction commit failed: %w", err)
+	}
+
+	uc.events.Publish(ctx, events.RoomMembersAdded{Room: *room, AddedBy: userID, UserIDs: append([]uuid.UUID{userID}, clone.MemberIDs...)})
+	ucLog.Infof("User %s cloned room %s into %s with %d members", userID, roomID, room.ID, len(clone.MemberIDs)+1)
+	return clone, nil
+}
@@ -0,0 +1,250 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/events"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxBulkRoomMembers      = 50
+	memberPolicyMetadataKey = adminRoomMetadataPrefix + "member_policy"
+
+	memberPolicyFriends = "friends"
+	memberPolicyAdmins  = "admins"
+	memberPolicyOpen    = "open"
+
+	maxRoomNameLength  = 255
+	maxImportedHistory = 200
+)
+
+var (
+	ErrRoomNotFound         = errors.New("room not found")
+	ErrRoomNotGroup         = errors.New("members can only be added to group rooms")
+	ErrRoomNotPrivate       = errors.New("only private rooms can be upgraded to groups")
+	ErrInvalidGroupUpgrade  = errors.New("invalid group upgrade")
+	ErrRoomMembersForbidden = errors.New("only room owners and admins may add members to this room")
+)
+
+type GroupUpgrade struct {
+	Room             *domain.Room      `json:"room"`
+	Results          []MemberAddResult `json:"results"`
+	ImportedMessages int64             `json:"importedMessages"`
+}
+
+type MemberAddResult struct {
+	UserID uuid.UUID `json:"userId"`
+	Status string    `json:"status"`
+	Error  string    `json:"error,omitempty"`
+}
+
+func validMemberPolicy(value json.RawMessage) bool {
+	var policy string
+	if err := json.Unmarshal(value, &policy); err != nil {
+		return false
+	}
+	return policy == memberPolicyFriends || policy == memberPolicyAdmins || policy == memberPolicyOpen
+}
+
+func (uc *AppUsecase) memberPolicy(ctx context.Context, roomID uuid.UUID) (string, error) {
+	metadata, err := uc.repo.GetRoomMetadata(ctx, roomID)
+	if err != nil {
+		return "", err
+	}
+	policy := memberPolicyFriends
+	if raw, ok := metadata[memberPolicyMetadataKey]; ok {
+		json.Unmarshal(raw, &policy)
+	}
+	return policy, nil
+}
+
+func (uc *AppUsecase) AddRoomMembers(ctx context.Context, inviterID, roomID uuid.UUID, userIDs []uuid.UUID) ([]MemberAddResult, error) {
+	if len(userIDs) > maxBulkRoomMembers {
+		return nil, fmt.Errorf("at most %d members can be added at once", maxBulkRoomMembers)
+	}
+	role, err := uc.repo.GetRoomRole(ctx, inviterID, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("could not verify room membership: %w", err)
+	}
+	if role == "" {
+		return nil, ErrNotRoomMember
+	}
+	room, err := uc.repo.GetRoomByID(ctx, roomID)
+	if err != nil {
+		return nil, ErrRoomNotFound
+	}
+	if room.Type != "group" {
+		return nil, ErrRoomNotGroup
+	}
+	policy, err := uc.memberPolicy(ctx, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("could not load room policy: %w", err)
+	}
+	if policy == memberPolicyAdmins && role != "owner" && role != "admin" {
+		return nil, ErrRoomMembersForbidden
+	}
+	memberIDs, err := uc.repo.GetRoomMemberIDs(ctx, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("could not load room members: %w", err)
+	}
+	members := make(map[uuid.UUID]bool, len(memberIDs))
+	for _, memberID := range memberIDs {
+		members[memberID] = true
+	}
+
+	results := make([]MemberAddResult, 0, len(userIDs))
+	var toAdd []uuid.UUID
+	for _, userID := range userIDs {
+		if members[userID] {
+			if userID != inviterID {
+				results = append(results, MemberAddResult{UserID: userID, Status: "already_member"})
+			}
+			continue
+		}
+		members[userID] = true
+
+		if reason := uc.memberAddRejection(ctx, inviterID, userID, policy); reason != "" {
+			results = append(results, MemberAddResult{UserID: userID, Status: "rejected", Error: reason})
+			continue
+		}
+		toAdd = append(toAdd, userID)
+	}
+	if len(toAdd) == 0 {
+		return results, nil
+	}
+
+	tx, err := uc.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, userID := range toAdd {
+		if err := uc.repo.AddUserToRoom(ctx, tx, userID, roomID); err != nil {
+			return nil, fmt.Errorf("failed to add %s to room: %w", userID, err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("transaction commit failed: %w", err)
+	}
+	for _, userID := range toAdd {
+		results = append(results, MemberAddResult{UserID: userID, Status: "added"})
+	}
+
+	uc.events.Publish(ctx, events.RoomMembersAdded{Room: *room, AddedBy: inviterID, UserIDs: toAdd})
+	ucLog.Infof("User %s added %d members to room %s", inviterID, len(toAdd), roomID)
+	return results, nil
+}
+
+func (uc *AppUsecase) memberAddRejection(ctx context.Context, inviterID, userID uuid.UUID, policy string) string {
+	if policy == memberPolicyFriends {
+		fs, err := uc.repo.GetFriendship(ctx, inviterID, userID)
+		if err != nil || fs == nil || fs.Status != "accepted" {
+			return "user is not your friend"
+		}
+		return ""
+	}
+	user, err := uc.repo.GetUserByID(ctx, userID)
+	if err != nil || user == nil {
+		return "user not found"
+	}
+	return ""
+}
+
+func (uc *AppUsecase) CreateGroupFromPrivate(ctx context.Context, userID, privateRoomID uuid.UUID, name string, extraMemberIDs []uuid.UUID, importHistory int) (*GroupUpgrade, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxRoomNameLength {
+		return nil, fmt.Errorf("%w: name must be 1-%d characters", ErrInvalidGroupUpgrade, maxRoomNameLength)
+	}
+	if importHistory < 0 || importHistory > maxImportedHistory {
+		return nil, fmt.Errorf("%w: importHistory must be between 0 and %d", ErrInvalidGroupUpgrade, maxImportedHistory)
+	}
+	if len(extraMemberIDs) > maxBulkRoomMembers {
+		return nil, fmt.Errorf("%w: at most %d additional members can be added", ErrInvalidGroupUpgrade, maxBulkRoomMembers)
+	}
+	isMember, err := uc.repo.IsUserInRoom(ctx, userID, privateRoomID)
+	if err != nil {
+		return nil, fmt.Errorf("could not verify room membership: %w", err)
+	}
+	if !isMember {
+		return nil, ErrNotRoomMember
+	}
+	private, err := uc.repo.GetRoomByID(ctx, privateRoomID)
+	if err != nil {
+		return nil, ErrRoomNotFound
+	}
+	if private.Type != "private" {
+		return nil, ErrRoomNotPrivate
+	}
+	if err := uc.consumeQuota(ctx, userID, quotaRoomsCreated); err != nil {
+		return nil, err
+	}
+	memberIDs, err := uc.repo.GetRoomMemberIDs(ctx, privateRoomID)
+	if err != nil {
+		return nil, fmt.Errorf("could not load room members: %w", err)
+	}
+
+	upgrade := &GroupUpgrade{Results: []MemberAddResult{}}
+	seen := map[uuid.UUID]bool{userID: true}
+	var members []uuid.UUID
+	for _, memberID := range memberIDs {
+		if !seen[memberID] {
+			members = append(members, memberID)
+		}
+		seen[memberID] = true
+	}
+	for _, memberID := range extraMemberIDs {
+		if seen[memberID] {
+			continue
+		}
+		seen[memberID] = true
+		if reason := uc.memberAddRejection(ctx, userID, memberID, memberPolicyFriends); reason != "" {
+			upgrade.Results = append(upgrade.Results, MemberAddResult{UserID: memberID, Status: "rejected", Error: reason})
+			continue
+		}
+		members = append(members, memberID)
+	}
+
+	tx, err := uc.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	room, err := uc.repo.CreateRoom(ctx, tx, &domain.Room{Type: "group", Name: &name, OwnerID: &userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create group room: %w", err)
+	}
+	if err := uc.repo.AddUserToRoomWithRole(ctx, tx, userID, room.ID, "owner"); err != nil {
+		return nil, fmt.Errorf("failed to add owner to group room: %w", err)
+	}
+	for _, memberID := range members {
+		if err := uc.repo.AddUserToRoom(ctx, tx, memberID, room.ID); err != nil {
+			return nil, fmt.Errorf("failed to add %s to group room: %w", memberID, err)
+		}
+		upgrade.Results = append(upgrade.Results, MemberAddResult{UserID: memberID, Status: "added"})
+	}
+	// Only the requester's own messages are imported: the other participant
+	// never agreed to share their side of the conversation with new members.
+	if importHistory > 0 {
+		if upgrade.ImportedMessages, err = uc.repo.CopyRecentMessages(ctx, tx, privateRoomID, room.ID, userID, importHistory); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("transaction commit failed: %w", err)
+	}
+	upgrade.Room = room
+
+	uc.events.Publish(ctx, events.RoomMembersAdded{Room: *room, AddedBy: userID, UserIDs: append([]uuid.UUID{userID}, members...)})
+	ucLog.Infof("User %s upgraded private room %s into group %s with %d members", userID, privateRoomID, room.ID, len(members)+1)
+	return upgrade, nil
+}
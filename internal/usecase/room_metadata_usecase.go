@@ -0,0 +1,109 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"chatservice/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxRoomMetadataBytes    = 8 * 1024
+	maxRoomMetadataKeys     = 64
+	maxRoomMetadataValue    = 2 * 1024
+	adminRoomMetadataPrefix = "admin."
+)
+
+var (
+	ErrNotRoomMember         = errors.New("user not authorized to access this room")
+	ErrInvalidRoomMetadata   = errors.New("invalid room metadata")
+	ErrRoomMetadataForbidden = errors.New("only room owners and admins may change admin.* metadata keys")
+	ErrRoomMetadataTooLarge  = fmt.Errorf("room metadata may hold at most %d keys and %d bytes", maxRoomMetadataKeys, maxRoomMetadataBytes)
+)
+
+var roomMetadataKeyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,63}$`)
+
+func (uc *AppUsecase) GetRoomMetadata(ctx context.Context, userID, roomID uuid.UUID) (map[string]json.RawMessage, error) {
+	isMember, err := uc.repo.IsUserInRoom(ctx, userID, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("could not verify room membership: %w", err)
+	}
+	if !isMember {
+		return nil, ErrNotRoomMember
+	}
+	return uc.repo.GetRoomMetadata(ctx, roomID)
+}
+
+func (uc *AppUsecase) UpdateRoomMetadata(ctx context.Context, userID, roomID uuid.UUID, changes map[string]json.RawMessage) (map[string]json.RawMessage, error) {
+	role, err := uc.repo.GetRoomRole(ctx, userID, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("could not verify room membership: %w", err)
+	}
+	if role == "" {
+		return nil, ErrNotRoomMember
+	}
+	if len(changes) == 0 {
+		return nil, fmt.Errorf("%w: no keys given", ErrInvalidRoomMetadata)
+	}
+
+	set := make(map[string]json.RawMessage, len(changes))
+	var remove []string
+	for key, value := range changes {
+		if !roomMetadataKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("%w: key %q must be 1-64 lowercase letters, digits, '.', '_' or '-'", ErrInvalidRoomMetadata, key)
+		}
+		if strings.HasPrefix(key, adminRoomMetadataPrefix) && role != "owner" && role != "admin" {
+			return nil, ErrRoomMetadataForbidden
+		}
+		if string(value) == "null" {
+			remove = append(remove, key)
+			continue
+		}
+		if key == actionWebhookMetadataKey && !validWebhookURL(value) {
+			return nil, fmt.Errorf("%w: %s must be a public https URL", ErrInvalidRoomMetadata, key)
+		}
+		if key == domain.RoomMetadataLanguage && !validLanguageValue(value) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidRoomMetadata, ErrInvalidLanguage)
+		}
+		if (key == domain.RoomMetadataAutoTranslate || key == domain.RoomMetadataE2EE || key == domain.RoomMetadataWidget || key == smartRepliesMetadataKey) && !validBoolValue(value) {
+			return nil, fmt.Errorf("%w: %s must be a boolean", ErrInvalidRoomMetadata, key)
+		}
+		if key == memberPolicyMetadataKey && !validMemberPolicy(value) {
+			return nil, fmt.Errorf("%w: %s must be one of \"friends\", \"admins\" or \"open\"", ErrInvalidRoomMetadata, key)
+		}
+		if key == welcomeMessageMetadataKey && !validWelcomeMessage(value) {
+			return nil, fmt.Errorf("%w: %s must be a string of 1-%d characters", ErrInvalidRoomMetadata, key, maxWelcomeMessageLength)
+		}
+		if key == welcomeDeliveryMetadataKey && !validWelcomeDelivery(value) {
+			return nil, fmt.Errorf("%w: %s must be \"room\" or \"dm\"", ErrInvalidRoomMetadata, key)
+		}
+		if key == roomModeMetadataKey && !validRoomMode(value) {
+			return nil, fmt.Errorf("%w: %s must be \"standard\" or \"read_only\"", ErrInvalidRoomMetadata, key)
+		}
+		if key == everyoneMentionMetadataKey && !validEveryoneMentions(value) {
+			return nil, fmt.Errorf("%w: %s must be one of \"all\", \"admins\" or \"nobody\"", ErrInvalidRoomMetadata, key)
+		}
+		if key == urgentMessagesMetadataKey && !validUrgentMessages(value) {
+			return nil, fmt.Errorf("%w: %s must be one of \"all\", \"admins\" or \"nobody\"", ErrInvalidRoomMetadata, key)
+		}
+		if len(value) > maxRoomMetadataValue {
+			return nil, fmt.Errorf("%w: value of %q exceeds %d bytes", ErrInvalidRoomMetadata, key, maxRoomMetadataValue)
+		}
+		set[key] = value
+	}
+
+	metadata, ok, err := uc.repo.UpdateRoomMetadata(ctx, roomID, set, remove, maxRoomMetadataBytes, maxRoomMetadataKeys)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrRoomMetadataTooLarge
+	}
+	return metadata, nil
+}
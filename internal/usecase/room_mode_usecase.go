@@ -0,0 +1,57 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+const (
+	roomModeMetadataKey = adminRoomMetadataPrefix + "mode"
+
+	roomModeStandard = "standard"
+	// roomModeReadOnly is for announcement channels: only owners and admins
+	// post, while members can still react to messages.
+	roomModeReadOnly = "read_only"
+)
+
+var ErrRoomPostingRestricted = errors.New("only room owners and admins may post in this room")
+
+var roomPostPermissions = map[string]map[string]bool{
+	roomModeStandard: {"owner": true, "admin": true, "member": true, "guest": true},
+	roomModeReadOnly: {"owner": true, "admin": true},
+}
+
+func validRoomMode(value json.RawMessage) bool {
+	var mode string
+	if err := json.Unmarshal(value, &mode); err != nil {
+		return false
+	}
+	_, ok := roomPostPermissions[mode]
+	return ok
+}
+
+func (uc *AppUsecase) authorizeRoomPost(ctx context.Context, senderID, roomID uuid.UUID) error {
+	metadata, err := uc.repo.GetRoomMetadata(ctx, roomID)
+	if err != nil {
+		return fmt.Errorf("could not load room settings: %w", err)
+	}
+	mode := roomModeStandard
+	if raw, ok := metadata[roomModeMetadataKey]; ok {
+		json.Unmarshal(raw, &mode)
+	}
+	if mode == roomModeStandard {
+		return nil
+	}
+	role, err := uc.repo.GetRoomRole(ctx, senderID, roomID)
+	if err != nil {
+		return fmt.Errorf("could not verify room role: %w", err)
+	}
+	if !roomPostPermissions[mode][role] {
+		return ErrRoomPostingRestricted
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/events"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidRoomState     = errors.New("state must be open, resolved or closed")
+	ErrRoomStateUnsupported = errors.New("only group and support rooms can be resolved or closed")
+	ErrRoomStateForbidden   = errors.New("only room owners and admins may change the room state")
+	ErrRoomClosed           = errors.New("this conversation is closed")
+)
+
+func validRoomState(state string) bool {
+	return state == domain.RoomStateOpen || state == domain.RoomStateResolved || state == domain.RoomStateClosed
+}
+
+func (uc *AppUsecase) ChangeRoomState(ctx context.Context, userID, roomID uuid.UUID, state string) (*domain.RoomStateChange, error) {
+	if !validRoomState(state) {
+		return nil, ErrInvalidRoomState
+	}
+	room, err := uc.repo.GetRoomByID(ctx, roomID)
+	if err != nil {
+		return nil, ErrRoomNotFound
+	}
+	switch room.Type {
+	case "group":
+		if err := uc.requireRoomAdmin(ctx, userID, roomID, ErrRoomStateForbidden); err != nil {
+			return nil, err
+		}
+	case "support":
+		if err := uc.requireSupportAgent(ctx, userID); err != nil {
+			return nil, err
+		}
+		conv, err := uc.repo.GetSupportConversation(ctx, roomID)
+		if err != nil {
+			return nil, err
+		}
+		if conv == nil {
+			return nil, ErrSupportConversationNotFound
+		}
+		if conv.CustomerID == userID {
+			return nil, ErrNotSupportAgent
+		}
+	default:
+		return nil, ErrRoomStateUnsupported
+	}
+	return uc.applyRoomState(ctx, room, userID, state, false)
+}
+
+func (uc *AppUsecase) applyRoomState(ctx context.Context, room *domain.Room, actorID uuid.UUID, state string, byMessage bool) (*domain.RoomStateChange, error) {
+	change, err := uc.repo.SetRoomState(ctx, room.ID, actorID, state)
+	if err != nil {
+		return nil, err
+	}
+	if change == nil {
+		return nil, nil
+	}
+	if room.Type == "support" {
+		if err := uc.repo.SetSupportResolved(ctx, room.ID, state != domain.RoomStateOpen); err != nil {
+			return nil, err
+		}
+	}
+
+	name := "Someone"
+	if user, err := uc.repo.GetUserByID(ctx, actorID); err == nil && user != nil && user.Nickname != "" {
+		name = user.Nickname
+	}
+	var content string
+	switch {
+	case byMessage:
+		content = fmt.Sprintf("Conversation reopened by a new message from %s", name)
+	case state == domain.RoomStateOpen:
+		content = fmt.Sprintf("%s reopened this conversation", name)
+	default:
+		content = fmt.Sprintf("%s marked this conversation as %s", name, state)
+	}
+	msg, err := uc.repo.CreateMessage(ctx, &domain.Message{
+		MessageUID: uuid.New(),
+		RoomID:     room.ID,
+		UserID:     actorID,
+		Content:    content,
+		Kind:       domain.MessageKindSystem,
+	})
+	if err != nil {
+		ucLog.Errorf("Failed to post state change message in room %s: %v", room.ID, err)
+	} else {
+		uc.events.Publish(ctx, events.MessageCreated{Message: *msg})
+	}
+
+	uc.events.Publish(ctx, events.RoomStateChanged{Change: *change})
+	if room.Type == "support" {
+		if conv, err := uc.repo.GetSupportConversation(ctx, room.ID); err == nil && conv != nil {
+			uc.events.Publish(ctx, events.SupportAssignmentChanged{Conversation: *conv})
+		}
+	}
+	ucLog.Infof("Room %s moved from %s to %s by %s", room.ID, change.Previous, change.State, actorID)
+	return change, nil
+}
+
+func (uc *AppUsecase) ensureRoomWritable(ctx context.Context, senderID, roomID uuid.UUID) error {
+	room, err := uc.repo.GetRoomByID(ctx, roomID)
+	if err != nil {
+		return ErrRoomNotFound
+	}
+	if room.State == domain.RoomStateClosed {
+		return ErrRoomClosed
+	}
+	return uc.authorizeRoomPost(ctx, senderID, roomID)
+}
+
+func (uc *AppUsecase) reopenOnCustomerMessage(ctx context.Context, msg domain.Message) {
+	room, err := uc.repo.GetRoomByID(ctx, msg.RoomID)
+	if err != nil || room.State != domain.RoomStateResolved {
+		return
+	}
+	switch room.Type {
+	case "support":
+		conv, err := uc.repo.GetSupportConversation(ctx, room.ID)
+		if err != nil || conv == nil || conv.CustomerID != msg.UserID {
+			return
+		}
+	case "group":
+		role, err := uc.repo.GetRoomRole(ctx, msg.UserID, room.ID)
+		if err != nil || role == "owner" || role == "admin" {
+			return
+		}
+	default:
+		return
+	}
+	if _, err := uc.applyRoomState(ctx, room, msg.UserID, domain.RoomStateOpen, true); err != nil {
+		ucLog.Errorf("Failed to reopen room %s after a new message: %v", room.ID, err)
+	}
+}
@@ -0,0 +1,134 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/search"
+	"chatservice/internal/tenant"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	maxSearchQueryLen  = 256
+
+	defaultQuickSearchPerType = 5
+	maxQuickSearchPerType     = 10
+
+	searchBackendCooldown = 30 * time.Second
+	degradedSearchWindow  = 30 * 24 * time.Hour
+)
+
+var ErrInvalidSearchQuery = errors.New("search query must be 1-256 characters")
+
+func (uc *AppUsecase) SetSearch(backend search.Backend) { uc.search = backend }
+
+func (uc *AppUsecase) SearchMessages(ctx context.Context, userID, roomID uuid.UUID, query string, limit int) ([]domain.Message, error) {
+	query = strings.TrimSpace(query)
+	if query == "" || utf8.RuneCountInString(query) > maxSearchQueryLen {
+		return nil, ErrInvalidSearchQuery
+	}
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	limit = min(limit, maxSearchLimit)
+
+	var roomIDs []uuid.UUID
+	if roomID != uuid.Nil {
+		isMember, err := uc.repo.IsUserInRoom(ctx, userID, roomID)
+		if err != nil {
+			return nil, err
+		}
+		if !isMember {
+			return nil, ErrNotRoomMember
+		}
+		roomIDs = []uuid.UUID{roomID}
+	} else {
+		var err error
+		if roomIDs, err = uc.repo.GetRoomIDsForUser(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+	if len(roomIDs) == 0 {
+		return []domain.Message{}, nil
+	}
+
+	var since time.Time
+	if uc.search != nil {
+		if time.Now().UnixNano() >= uc.searchDown.Load() {
+			messages, err := uc.searchIndex(ctx, roomIDs, query, limit)
+			if err == nil {
+				uc.attachSenderBadges(ctx, messages)
+				return messages, nil
+			}
+			ucLog.Warnf("Search backend failed, serving degraded Postgres results for %s: %v", searchBackendCooldown, err)
+			uc.searchDown.Store(time.Now().Add(searchBackendCooldown).UnixNano())
+		}
+		since = time.Now().Add(-degradedSearchWindow)
+		limit = min(limit, defaultSearchLimit)
+	}
+	messages, err := uc.repo.SearchMessages(ctx, roomIDs, query, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	uc.attachSenderBadges(ctx, messages)
+	return messages, nil
+}
+
+func (uc *AppUsecase) searchIndex(ctx context.Context, roomIDs []uuid.UUID, query string, limit int) ([]domain.Message, error) {
+	ids, err := uc.search.Search(ctx, search.Query{Tenant: tenant.FromContext(ctx), RoomIDs: roomIDs, Text: query, Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return []domain.Message{}, nil
+	}
+	found, err := uc.repo.GetMessagesByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := make(map[uuid.UUID]bool, len(roomIDs))
+	for _, id := range roomIDs {
+		allowed[id] = true
+	}
+	byID := make(map[int64]domain.Message, len(found))
+	for _, msg := range found {
+		if allowed[msg.RoomID] {
+			byID[msg.ID] = msg
+		}
+	}
+	messages := make([]domain.Message, 0, len(byID))
+	for _, id := range ids {
+		if msg, ok := byID[id]; ok {
+			messages = append(messages, msg)
+		}
+	}
+	return messages, nil
+}
+
+func (uc *AppUsecase) QuickSearch(ctx context.Context, userID uuid.UUID, query string, perType int) ([]domain.QuickSearchResult, error) {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" || utf8.RuneCountInString(query) > maxSearchQueryLen {
+		return nil, ErrInvalidSearchQuery
+	}
+	if perType <= 0 {
+		perType = defaultQuickSearchPerType
+	}
+	perType = min(perType, maxQuickSearchPerType)
+	results, err := uc.repo.QuickSearch(ctx, userID, query, perType)
+	if err != nil {
+		return nil, err
+	}
+	if results == nil {
+		results = []domain.QuickSearchResult{}
+	}
+	return results, nil
+}
@@ -0,0 +1,181 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"chatservice/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultShareLinkTTL = 7 * 24 * time.Hour
+	maxShareLinkTTL     = 90 * 24 * time.Hour
+	maxSharedMessages   = 500
+	maxShareTitleLength = 200
+	maxShareUserAgent   = 512
+)
+
+var (
+	ErrShareLinkForbidden = errors.New("only room owners and admins may manage share links")
+	ErrInvalidShareRange  = errors.New("share range must start and end on messages in this room and cover at most 500 messages")
+	ErrInvalidShareLink   = errors.New("share link title must be at most 200 characters and its lifetime between 1 minute and 90 days")
+	ErrShareLinkNotFound  = errors.New("share link not found")
+	ErrShareLinkExpired   = errors.New("share link has expired or been revoked")
+)
+
+type CreatedShareLink struct {
+	domain.ShareLink
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+type SharedHistory struct {
+	Title     *string                `json:"title,omitempty"`
+	ExpiresAt time.Time              `json:"expiresAt"`
+	Messages  []domain.SharedMessage `json:"messages"`
+}
+
+func (uc *AppUsecase) SetShareBaseURL(baseURL string) {
+	uc.shareBaseURL = strings.TrimRight(baseURL, "/")
+}
+
+func (uc *AppUsecase) CreateShareLink(ctx context.Context, userID, roomID uuid.UUID, firstID, lastID int64, title string, ttl time.Duration) (*CreatedShareLink, error) {
+	if err := uc.requireRoomAdmin(ctx, userID, roomID, ErrShareLinkForbidden); err != nil {
+		return nil, err
+	}
+	if ttl == 0 {
+		ttl = defaultShareLinkTTL
+	}
+	title = strings.TrimSpace(title)
+	if ttl < time.Minute || ttl > maxShareLinkTTL || utf8.RuneCountInString(title) > maxShareTitleLength {
+		return nil, ErrInvalidShareLink
+	}
+	if firstID <= 0 || lastID < firstID {
+		return nil, ErrInvalidShareRange
+	}
+	endpoints, total, err := uc.repo.CountShareableMessages(ctx, roomID, firstID, lastID)
+	if err != nil {
+		return nil, err
+	}
+	wantEndpoints := 2
+	if firstID == lastID {
+		wantEndpoints = 1
+	}
+	if endpoints != wantEndpoints || total > maxSharedMessages {
+		return nil, ErrInvalidShareRange
+	}
+
+	token, tokenHash, err := newShareToken()
+	if err != nil {
+		return nil, err
+	}
+	link := domain.ShareLink{
+		ID:             uuid.New(),
+		RoomID:         roomID,
+		CreatedBy:      userID,
+		FirstMessageID: firstID,
+		LastMessageID:  lastID,
+		ExpiresAt:      time.Now().Add(ttl).UTC(),
+	}
+	if title != "" {
+		link.Title = &title
+	}
+	if err := uc.repo.CreateShareLink(ctx, &link, tokenHash); err != nil {
+		return nil, err
+	}
+	return &CreatedShareLink{ShareLink: link, Token: token, URL: uc.shareBaseURL + "/shared/" + token}, nil
+}
+
+func (uc *AppUsecase) ListShareLinks(ctx context.Context, userID, roomID uuid.UUID) ([]domain.ShareLink, error) {
+	if err := uc.requireRoomAdmin(ctx, userID, roomID, ErrShareLinkForbidden); err != nil {
+		return nil, err
+	}
+	return uc.repo.ListShareLinks(ctx, roomID)
+}
+
+func (uc *AppUsecase) RevokeShareLink(ctx context.Context, userID, roomID, linkID uuid.UUID) error {
+	if err := uc.requireRoomAdmin(ctx, userID, roomID, ErrShareLinkForbidden); err != nil {
+		return err
+	}
+	revoked, err := uc.repo.RevokeShareLink(ctx, roomID, linkID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrShareLinkNotFound
+	}
+	return nil
+}
+
+func (uc *AppUsecase) ViewSharedHistory(ctx context.Context, token, ipAddress, userAgent string) (*SharedHistory, error) {
+	link, err := uc.repo.GetShareLinkByTokenHash(ctx, hashShareToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if link == nil {
+		return nil, ErrShareLinkNotFound
+	}
+	if link.RevokedAt != nil || !link.ExpiresAt.After(time.Now()) {
+		return nil, ErrShareLinkExpired
+	}
+	if len(userAgent) > maxShareUserAgent {
+		userAgent = strings.ToValidUTF8(userAgent[:maxShareUserAgent], "")
+	}
+	if err := uc.repo.LogShareLinkAccess(ctx, link.ID, ipAddress, userAgent); err != nil {
+		ucLog.Errorf("Failed to log access to share link %s: %v", link.ID, err)
+	}
+
+	messages, err := uc.repo.GetSharedMessages(ctx, link.RoomID, link.FirstMessageID, link.LastMessageID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range messages {
+		messages[i].Content = sanitizeMarkdown(messages[i].Content)
+	}
+	return &SharedHistory{Title: link.Title, ExpiresAt: link.ExpiresAt, Messages: messages}, nil
+}
+
+func (uc *AppUsecase) requireRoomAdmin(ctx context.Context, userID, roomID uuid.UUID, forbidden error) error {
+	role, err := uc.repo.GetRoomRole(ctx, userID, roomID)
+	if err != nil {
+		return fmt.Errorf("could not verify room membership: %w", err)
+	}
+	if role == "" {
+		return ErrNotRoomMember
+	}
+	if role != "owner" && role != "admin" {
+		return forbidden
+	}
+	return nil
+}
+
+func newShareToken() (string, string, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", "", fmt.Errorf("could not generate share token: %w", err)
+	}
+	return token, hashShareToken(token), nil
+}
+
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashShareToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,83 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/events"
+
+	"github.com/google/uuid"
+)
+
+const maxSnoozeDuration = 30 * 24 * time.Hour
+
+var ErrInvalidSnooze = errors.New("snooze duration must be between 1 minute and 30 days")
+
+type RoomList struct {
+	Rooms   []domain.Room `json:"rooms"`
+	Snoozed []domain.Room `json:"snoozed"`
+}
+
+func (uc *AppUsecase) GetRoomList(ctx context.Context, userID uuid.UUID, state string) (*RoomList, error) {
+	if state != "" && !validRoomState(state) {
+		return nil, ErrInvalidRoomState
+	}
+	rooms, err := uc.repo.GetRoomsForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	list := &RoomList{Rooms: []domain.Room{}, Snoozed: []domain.Room{}}
+	now := time.Now()
+	for _, room := range rooms {
+		if state != "" && room.State != state {
+			continue
+		}
+		if room.SnoozedUntil != nil && room.SnoozedUntil.After(now) {
+			list.Snoozed = append(list.Snoozed, room)
+			continue
+		}
+		room.SnoozedUntil = nil
+		list.Rooms = append(list.Rooms, room)
+	}
+	return list, nil
+}
+
+func (uc *AppUsecase) SnoozeRoom(ctx context.Context, userID, roomID uuid.UUID, duration time.Duration) (time.Time, error) {
+	if duration < time.Minute || duration > maxSnoozeDuration {
+		return time.Time{}, ErrInvalidSnooze
+	}
+	until := time.Now().Add(duration).UTC()
+	if err := uc.setRoomSnooze(ctx, userID, roomID, &until); err != nil {
+		return time.Time{}, err
+	}
+	return until, nil
+}
+
+func (uc *AppUsecase) UnsnoozeRoom(ctx context.Context, userID, roomID uuid.UUID) error {
+	return uc.setRoomSnooze(ctx, userID, roomID, nil)
+}
+
+func (uc *AppUsecase) setRoomSnooze(ctx context.Context, userID, roomID uuid.UUID, until *time.Time) error {
+	updated, err := uc.repo.SetRoomSnooze(ctx, userID, roomID, until)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return ErrNotRoomMember
+	}
+	uc.events.Publish(ctx, events.RoomUpdated{UserID: userID, RoomID: roomID, SnoozedUntil: until})
+	return nil
+}
+
+func (uc *AppUsecase) ExpireRoomSnoozes(ctx context.Context) error {
+	expired, err := uc.repo.ExpireRoomSnoozes(ctx)
+	if err != nil {
+		return err
+	}
+	for _, snooze := range expired {
+		uc.events.Publish(ctx, events.RoomUpdated{UserID: snooze.UserID, RoomID: snooze.RoomID})
+	}
+	return nil
+}
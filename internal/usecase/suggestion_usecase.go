@@ -0,0 +1,98 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/encode"
+	"chatservice/internal/integrations"
+
+	"github.com/google/uuid"
+)
+
+const (
+	smartRepliesMetadataKey = adminRoomMetadataPrefix + "smart_replies"
+
+	maxSuggestions          = 3
+	maxSuggestionLength     = 80
+	suggestionContextLength = 5
+	suggestionTimeout       = 5 * time.Second
+)
+
+func (uc *AppUsecase) SetSuggestionProvider(provider integrations.SuggestionProvider) {
+	uc.suggestions = provider
+}
+
+func (uc *AppUsecase) suggestReplies(ctx context.Context, userID, roomID uuid.UUID) {
+	if _, noop := uc.suggestions.(integrations.NoopSuggestions); noop || uc.suggestions == nil {
+		return
+	}
+	settings, err := uc.repo.GetUserSettings(ctx, userID)
+	if err != nil || !settings.SmartReplies {
+		return
+	}
+	if !uc.roomAllowsSuggestions(ctx, roomID) {
+		return
+	}
+	lines, err := uc.repo.GetSummaryLines(ctx, roomID, time.Time{}, suggestionContextLength)
+	if err != nil {
+		ucLog.Errorf("Failed to load messages for reply suggestions in room %s: %v", roomID, err)
+		return
+	}
+	if len(lines) == 0 || lines[len(lines)-1].UserID == userID {
+		return
+	}
+
+	request := integrations.SuggestionRequest{Language: settings.Language, Context: make([]integrations.SummaryMessage, 0, len(lines)-1)}
+	for i, line := range lines {
+		message := integrations.SummaryMessage{Author: line.Author, Text: line.Content, SentAt: line.CreatedAt}
+		if i == len(lines)-1 {
+			request.Message = message
+			continue
+		}
+		request.Context = append(request.Context, message)
+	}
+	ctx, cancel := context.WithTimeout(ctx, suggestionTimeout)
+	defer cancel()
+	candidates, err := uc.suggestions.Suggest(ctx, request)
+	if err != nil {
+		ucLog.Errorf("Failed to get reply suggestions for room %s: %v", roomID, err)
+		return
+	}
+	if suggestions := cleanSuggestions(candidates); len(suggestions) > 0 {
+		uc.bcast.SendToUser(userID, encode.EncodeSuggestions(roomID, lines[len(lines)-1].ID, suggestions))
+	}
+}
+
+func (uc *AppUsecase) roomAllowsSuggestions(ctx context.Context, roomID uuid.UUID) bool {
+	metadata, err := uc.repo.GetRoomMetadata(ctx, roomID)
+	if err != nil {
+		ucLog.Errorf("Failed to load suggestion settings of room %s: %v", roomID, err)
+		return false
+	}
+	enabled := true
+	var encrypted, sensitive bool
+	json.Unmarshal(metadata[smartRepliesMetadataKey], &enabled)
+	json.Unmarshal(metadata[domain.RoomMetadataE2EE], &encrypted)
+	json.Unmarshal(metadata[domain.RoomMetadataSensitive], &sensitive)
+	return enabled && !encrypted && !sensitive
+}
+
+func cleanSuggestions(candidates []string) []string {
+	suggestions := make([]string, 0, maxSuggestions)
+	for _, candidate := range candidates {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "" || utf8.RuneCountInString(candidate) > maxSuggestionLength {
+			continue
+		}
+		suggestions = append(suggestions, candidate)
+		if len(suggestions) == maxSuggestions {
+			break
+		}
+	}
+	return suggestions
+}
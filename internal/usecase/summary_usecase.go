@@ -0,0 +1,125 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/integrations"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxSummaryMessages   = 500
+	defaultSummaryWindow = 24 * time.Hour
+)
+
+var (
+	ErrSummariesDisabled   = errors.New("summaries are disabled for end-to-end encrypted rooms")
+	ErrSummaryUnavailable  = errors.New("could not generate room summary")
+	ErrInvalidSummarySince = errors.New("since must be in the past")
+)
+
+func (uc *AppUsecase) SetSummarizer(summarizer integrations.Summarizer) {
+	uc.summarizer = summarizer
+}
+
+func (uc *AppUsecase) GetRoomSummary(ctx context.Context, userID, roomID uuid.UUID, since *time.Time) (*domain.RoomSummary, error) {
+	isMember, err := uc.repo.IsUserInRoom(ctx, userID, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("could not verify room membership: %w", err)
+	}
+	if !isMember {
+		return nil, ErrNotRoomMember
+	}
+	room, err := uc.repo.GetRoomByID(ctx, roomID)
+	if err != nil {
+		return nil, ErrRoomNotFound
+	}
+	metadata, err := uc.repo.GetRoomMetadata(ctx, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("could not load room settings: %w", err)
+	}
+	var encrypted bool
+	json.Unmarshal(metadata[domain.RoomMetadataE2EE], &encrypted)
+	if encrypted {
+		return nil, ErrSummariesDisabled
+	}
+
+	from, err := uc.summaryStart(ctx, userID, roomID, since)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := uc.repo.GetSummaryLines(ctx, roomID, from, maxSummaryMessages)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return &domain.RoomSummary{RoomID: roomID, Since: from}, nil
+	}
+
+	var language string
+	if settings, err := uc.repo.GetUserSettings(ctx, userID); err == nil {
+		language = settings.Language
+	}
+	firstID, lastID := lines[0].ID, lines[len(lines)-1].ID
+	cached, err := uc.repo.GetRoomSummary(ctx, roomID, language, firstID, lastID)
+	if err != nil {
+		return nil, err
+	}
+	if cached != nil {
+		cached.Since, cached.Cached = from, true
+		return cached, nil
+	}
+	if uc.summarizer == nil {
+		return nil, integrations.ErrSummarizerNotConfigured
+	}
+
+	request := integrations.SummaryRequest{Language: language, Messages: make([]integrations.SummaryMessage, len(lines))}
+	if room.Name != nil {
+		request.RoomName = *room.Name
+	}
+	for i, line := range lines {
+		request.Messages[i] = integrations.SummaryMessage{Author: line.Author, Text: line.Content, SentAt: line.CreatedAt}
+	}
+	text, err := uc.summarizer.Summarize(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSummaryUnavailable, err)
+	}
+
+	summary := &domain.RoomSummary{
+		RoomID:         roomID,
+		Language:       language,
+		Since:          from,
+		FirstMessageID: firstID,
+		LastMessageID:  lastID,
+		MessageCount:   len(lines),
+		Summary:        text,
+		CreatedAt:      time.Now(),
+	}
+	if err := uc.repo.SaveRoomSummary(ctx, summary); err != nil {
+		ucLog.Errorf("Failed to cache summary of room %s: %v", roomID, err)
+	}
+	return summary, nil
+}
+
+func (uc *AppUsecase) summaryStart(ctx context.Context, userID, roomID uuid.UUID, since *time.Time) (time.Time, error) {
+	if since != nil {
+		if since.After(time.Now()) {
+			return time.Time{}, ErrInvalidSummarySince
+		}
+		return *since, nil
+	}
+	readAt, err := uc.repo.GetLastReadAt(ctx, userID, roomID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if readAt == nil {
+		return time.Now().Add(-defaultSummaryWindow), nil
+	}
+	return *readAt, nil
+}
@@ -0,0 +1,163 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/events"
+
+	"github.com/google/uuid"
+)
+
+const maxSupportSLADays = 90
+
+type supportSLA struct {
+	firstResponse time.Duration
+	resolution    time.Duration
+}
+
+type RoomStats struct {
+	RoomID   uuid.UUID             `json:"roomId"`
+	Type     string                `json:"type"`
+	Members  int                   `json:"members"`
+	Messages int                   `json:"messages"`
+	Support  *SupportResponseTimes `json:"support,omitempty"`
+}
+
+type SupportResponseTimes struct {
+	Status                string   `json:"status"`
+	FirstResponseSeconds  *float64 `json:"firstResponseSeconds,omitempty"`
+	ResolutionSeconds     *float64 `json:"resolutionSeconds,omitempty"`
+	FirstResponseBreached bool     `json:"firstResponseBreached"`
+	ResolutionBreached    bool     `json:"resolutionBreached"`
+}
+
+func (uc *AppUsecase) SetSupportSLA(firstResponse, resolution time.Duration) {
+	uc.supportSLA = supportSLA{firstResponse: max(firstResponse, 0), resolution: max(resolution, 0)}
+}
+
+func (uc *AppUsecase) ResolveSupportConversation(ctx context.Context, agentID, roomID uuid.UUID) (*domain.SupportConversation, error) {
+	conv, err := uc.repo.GetSupportConversation(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if conv == nil {
+		return nil, ErrSupportConversationNotFound
+	}
+	if _, err := uc.ChangeRoomState(ctx, agentID, roomID, domain.RoomStateResolved); err != nil {
+		return nil, err
+	}
+	return uc.repo.GetSupportConversation(ctx, roomID)
+}
+
+func (uc *AppUsecase) GetRoomStats(ctx context.Context, userID, roomID uuid.UUID) (*RoomStats, error) {
+	isMember, err := uc.repo.IsUserInRoom(ctx, userID, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("could not verify room membership: %w", err)
+	}
+	if !isMember {
+		return nil, ErrNotRoomMember
+	}
+	room, err := uc.repo.GetRoomByID(ctx, roomID)
+	if err != nil {
+		return nil, ErrRoomNotFound
+	}
+	members, messages, err := uc.repo.GetRoomCounts(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	stats := &RoomStats{RoomID: roomID, Type: room.Type, Members: members, Messages: messages}
+	if room.Type != "support" {
+		return stats, nil
+	}
+	conv, err := uc.repo.GetSupportConversation(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if conv != nil {
+		stats.Support = uc.supportResponseTimes(conv, time.Now())
+	}
+	return stats, nil
+}
+
+func (uc *AppUsecase) GetSupportSLASummary(ctx context.Context, days int) (*domain.SupportSLASummary, error) {
+	if days <= 0 || days > maxSupportSLADays {
+		days = 7
+	}
+	since := time.Now().AddDate(0, 0, -days)
+	summary, err := uc.repo.GetSupportSLASummary(ctx, since, uc.supportSLA.firstResponse, uc.supportSLA.resolution)
+	if err != nil {
+		return nil, err
+	}
+	if uc.supportSLA.firstResponse > 0 {
+		summary.FirstResponseTarget = uc.supportSLA.firstResponse.String()
+	}
+	if uc.supportSLA.resolution > 0 {
+		summary.ResolutionTarget = uc.supportSLA.resolution.String()
+	}
+	return summary, nil
+}
+
+func (uc *AppUsecase) CheckSupportSLAs(ctx context.Context) error {
+	thresholds := []struct {
+		kind      string
+		threshold time.Duration
+	}{
+		{domain.SLAKindFirstResponse, uc.supportSLA.firstResponse},
+		{domain.SLAKindResolution, uc.supportSLA.resolution},
+	}
+	for _, t := range thresholds {
+		if t.threshold <= 0 {
+			continue
+		}
+		breaches, err := uc.repo.MarkSupportSLABreaches(ctx, t.kind, t.threshold)
+		if err != nil {
+			return err
+		}
+		for _, breach := range breaches {
+			ucLog.Warnf("Support conversation %s breached its %s SLA of %s", breach.RoomID, breach.Kind, t.threshold)
+			uc.events.Publish(ctx, events.SupportSLABreached{Breach: breach, Threshold: t.threshold})
+		}
+	}
+	return nil
+}
+
+func (uc *AppUsecase) trackSupportMessage(ctx context.Context, msg domain.Message) {
+	if err := uc.repo.TrackSupportMessage(ctx, msg.RoomID, msg.UserID, msg.CreatedAt); err != nil {
+		ucLog.Errorf("Failed to track support response times for room %s: %v", msg.RoomID, err)
+	}
+	uc.reopenOnCustomerMessage(ctx, msg)
+}
+
+func (uc *AppUsecase) supportResponseTimes(conv *domain.SupportConversation, now time.Time) *SupportResponseTimes {
+	times := &SupportResponseTimes{
+		Status:                conv.Status,
+		FirstResponseBreached: conv.FirstResponseBreachedAt != nil,
+		ResolutionBreached:    conv.ResolutionBreachedAt != nil,
+	}
+	if conv.FirstResponseAt != nil {
+		seconds := conv.FirstResponseAt.Sub(conv.CreatedAt).Seconds()
+		times.FirstResponseSeconds = &seconds
+	}
+	if conv.ResolvedAt != nil {
+		seconds := conv.ResolvedAt.Sub(conv.CreatedAt).Seconds()
+		times.ResolutionSeconds = &seconds
+	}
+	if limit := uc.supportSLA.firstResponse; limit > 0 {
+		responded := now
+		if conv.FirstResponseAt != nil {
+			responded = *conv.FirstResponseAt
+		}
+		times.FirstResponseBreached = times.FirstResponseBreached || responded.Sub(conv.CreatedAt) > limit
+	}
+	if limit := uc.supportSLA.resolution; limit > 0 {
+		resolved := now
+		if conv.ResolvedAt != nil {
+			resolved = *conv.ResolvedAt
+		}
+		times.ResolutionBreached = times.ResolutionBreached || resolved.Sub(conv.CreatedAt) > limit
+	}
+	return times
+}
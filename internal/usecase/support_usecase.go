@@ -0,0 +1,258 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/events"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxSupportSubjectLength = 200
+	maxSupportNoteBytes     = 4 * 1024
+	supportQueueLimit       = 100
+	supportNotesLimit       = 200
+	defaultSupportRoomName  = "Support"
+)
+
+var (
+	ErrNotSupportAgent              = errors.New("only support agents may do this")
+	ErrSupportAgentNotFound         = errors.New("support agent not found")
+	ErrSupportConversationNotFound  = errors.New("support conversation not found")
+	ErrSupportConversationAssigned  = errors.New("support conversation is already assigned")
+	ErrNotAssignedAgent             = errors.New("support conversation is not assigned to you")
+	ErrInvalidSupportSubject        = errors.New("subject must be at most 200 characters")
+	ErrInvalidSupportNote           = errors.New("note must be between 1 and 4096 bytes")
+	ErrInvalidSupportTransferTarget = errors.New("conversation cannot be transferred to this agent")
+)
+
+func (uc *AppUsecase) OpenSupportConversation(ctx context.Context, customerID uuid.UUID, subject string) (*domain.SupportConversation, error) {
+	subject = strings.TrimSpace(subject)
+	if utf8.RuneCountInString(subject) > maxSupportSubjectLength {
+		return nil, ErrInvalidSupportSubject
+	}
+	name := subject
+	if name == "" {
+		name = defaultSupportRoomName
+	}
+	if err := uc.consumeQuota(ctx, customerID, quotaRoomsCreated); err != nil {
+		return nil, err
+	}
+
+	tx, err := uc.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	room, err := uc.repo.CreateRoom(ctx, tx, &domain.Room{Type: "support", Name: &name})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create support room: %w", err)
+	}
+	if err := uc.repo.AddUserToRoom(ctx, tx, customerID, room.ID); err != nil {
+		return nil, fmt.Errorf("failed to add customer to support room: %w", err)
+	}
+	agentID, err := uc.repo.PickSupportAgent(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	members := []uuid.UUID{customerID}
+	if agentID != nil && *agentID != customerID {
+		if err := uc.repo.AddUserToRoom(ctx, tx, *agentID, room.ID); err != nil {
+			return nil, fmt.Errorf("failed to add agent to support room: %w", err)
+		}
+		members = append(members, *agentID)
+	} else {
+		agentID = nil
+	}
+	conv := domain.SupportConversation{RoomID: room.ID, CustomerID: customerID, AgentID: agentID, Subject: subject}
+	if err := uc.repo.CreateSupportConversation(ctx, tx, &conv); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("could not commit support conversation: %w", err)
+	}
+
+	uc.events.Publish(ctx, events.RoomMembersAdded{Room: *room, AddedBy: customerID, UserIDs: members})
+	if conv.AgentID != nil {
+		uc.events.Publish(ctx, events.SupportAssignmentChanged{Conversation: conv})
+	}
+	ucLog.Infof("Support conversation %s opened by %s (%s)", conv.RoomID, customerID, conv.Status)
+	return &conv, nil
+}
+
+func (uc *AppUsecase) ListSupportQueue(ctx context.Context, agentID uuid.UUID) ([]domain.SupportConversation, error) {
+	if err := uc.requireSupportAgent(ctx, agentID); err != nil {
+		return nil, err
+	}
+	return uc.repo.ListSupportQueue(ctx, supportQueueLimit)
+}
+
+func (uc *AppUsecase) ListAgentConversations(ctx context.Context, agentID uuid.UUID) ([]domain.SupportConversation, error) {
+	if err := uc.requireSupportAgent(ctx, agentID); err != nil {
+		return nil, err
+	}
+	return uc.repo.ListAgentConversations(ctx, agentID)
+}
+
+func (uc *AppUsecase) SetSupportAvailability(ctx context.Context, agentID uuid.UUID, available bool) error {
+	updated, err := uc.repo.SetSupportAgentAvailability(ctx, agentID, available)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return ErrNotSupportAgent
+	}
+	return nil
+}
+
+func (uc *AppUsecase) ClaimSupportConversation(ctx context.Context, agentID, roomID uuid.UUID) (*domain.SupportConversation, error) {
+	if err := uc.requireSupportAgent(ctx, agentID); err != nil {
+		return nil, err
+	}
+	return uc.reassignSupportConversation(ctx, roomID, nil, &agentID, ErrSupportConversationAssigned)
+}
+
+func (uc *AppUsecase) ReleaseSupportConversation(ctx context.Context, agentID, roomID uuid.UUID) (*domain.SupportConversation, error) {
+	if err := uc.requireSupportAgent(ctx, agentID); err != nil {
+		return nil, err
+	}
+	return uc.reassignSupportConversation(ctx, roomID, &agentID, nil, ErrNotAssignedAgent)
+}
+
+func (uc *AppUsecase) TransferSupportConversation(ctx context.Context, agentID, roomID, targetID uuid.UUID) (*domain.SupportConversation, error) {
+	if err := uc.requireSupportAgent(ctx, agentID); err != nil {
+		return nil, err
+	}
+	if targetID == agentID {
+		return nil, ErrInvalidSupportTransferTarget
+	}
+	isAgent, err := uc.repo.IsSupportAgent(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if !isAgent {
+		return nil, ErrSupportAgentNotFound
+	}
+	conv, err := uc.repo.GetSupportConversation(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if conv != nil && conv.CustomerID == targetID {
+		return nil, ErrInvalidSupportTransferTarget
+	}
+	return uc.reassignSupportConversation(ctx, roomID, &agentID, &targetID, ErrNotAssignedAgent)
+}
+
+func (uc *AppUsecase) reassignSupportConversation(ctx context.Context, roomID uuid.UUID, from, to *uuid.UUID, conflict error) (*domain.SupportConversation, error) {
+	conv, err := uc.repo.AssignSupportConversation(ctx, roomID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if conv == nil {
+		existing, err := uc.repo.GetSupportConversation(ctx, roomID)
+		if err != nil {
+			return nil, err
+		}
+		if existing == nil {
+			return nil, ErrSupportConversationNotFound
+		}
+		return nil, conflict
+	}
+	if to != nil {
+		added, err := uc.repo.EnsureRoomParticipant(ctx, *to, roomID)
+		if err != nil {
+			return nil, err
+		}
+		if added {
+			if room, err := uc.repo.GetRoomByID(ctx, roomID); err == nil {
+				uc.events.Publish(ctx, events.RoomMembersAdded{Room: *room, AddedBy: *to, UserIDs: []uuid.UUID{*to}})
+			}
+		}
+	}
+	uc.events.Publish(ctx, events.SupportAssignmentChanged{Conversation: *conv, PreviousAgentID: from})
+	ucLog.Infof("Support conversation %s reassigned (%s)", roomID, conv.Status)
+	return conv, nil
+}
+
+func (uc *AppUsecase) AddSupportNote(ctx context.Context, agentID, roomID uuid.UUID, content string) (*domain.SupportNote, error) {
+	if err := uc.requireSupportAgent(ctx, agentID); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(content) == "" || len(content) > maxSupportNoteBytes {
+		return nil, ErrInvalidSupportNote
+	}
+	conv, err := uc.repo.GetSupportConversation(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if conv == nil {
+		return nil, ErrSupportConversationNotFound
+	}
+	if conv.CustomerID == agentID {
+		return nil, ErrNotSupportAgent
+	}
+	note := domain.SupportNote{RoomID: roomID, AuthorID: agentID, Content: content}
+	if err := uc.repo.CreateSupportNote(ctx, &note); err != nil {
+		return nil, err
+	}
+	recipients, err := uc.repo.GetRoomAgentIDs(ctx, roomID)
+	if err != nil {
+		ucLog.Errorf("Could not load agents for support note in room %s: %v", roomID, err)
+	}
+	uc.events.Publish(ctx, events.SupportNoteCreated{Note: note, RecipientIDs: recipients})
+	return &note, nil
+}
+
+func (uc *AppUsecase) ListSupportNotes(ctx context.Context, agentID, roomID uuid.UUID) ([]domain.SupportNote, error) {
+	if err := uc.requireSupportAgent(ctx, agentID); err != nil {
+		return nil, err
+	}
+	conv, err := uc.repo.GetSupportConversation(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if conv == nil {
+		return nil, ErrSupportConversationNotFound
+	}
+	if conv.CustomerID == agentID {
+		return nil, ErrNotSupportAgent
+	}
+	return uc.repo.ListSupportNotes(ctx, roomID, supportNotesLimit)
+}
+
+func (uc *AppUsecase) ListSupportAgents(ctx context.Context) ([]domain.SupportAgent, error) {
+	return uc.repo.ListSupportAgents(ctx)
+}
+
+func (uc *AppUsecase) AddSupportAgent(ctx context.Context, userID uuid.UUID, available bool) error {
+	return uc.repo.UpsertSupportAgent(ctx, userID, available)
+}
+
+func (uc *AppUsecase) RemoveSupportAgent(ctx context.Context, userID uuid.UUID) error {
+	removed, err := uc.repo.RemoveSupportAgent(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrSupportAgentNotFound
+	}
+	return nil
+}
+
+func (uc *AppUsecase) requireSupportAgent(ctx context.Context, userID uuid.UUID) error {
+	isAgent, err := uc.repo.IsSupportAgent(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !isAgent {
+		return ErrNotSupportAgent
+	}
+	return nil
+}
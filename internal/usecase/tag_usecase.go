@@ -0,0 +1,38 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"chatservice/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+const maxRoomTags = 200
+
+var ErrInvalidTag = errors.New("tag must be 1-64 letters, digits or underscores")
+
+func normalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+	tags := parseHashtags("#" + tag)
+	if len(tags) != 1 || tags[0] != tag {
+		return "", ErrInvalidTag
+	}
+	return tags[0], nil
+}
+
+func (uc *AppUsecase) GetRoomTags(ctx context.Context, userID, roomID uuid.UUID, limit int) ([]domain.RoomTag, error) {
+	isMember, err := uc.repo.IsUserInRoom(ctx, userID, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, ErrNotRoomMember
+	}
+	if limit <= 0 || limit > maxRoomTags {
+		limit = maxRoomTags
+	}
+	return uc.repo.GetRoomTags(ctx, roomID, limit)
+}
@@ -0,0 +1,128 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/encode"
+	"chatservice/internal/integrations"
+
+	"github.com/google/uuid"
+)
+
+const maxTranslatedMessageLength = 4000
+
+var (
+	ErrInvalidLanguage = errors.New("language must be a BCP 47 tag such as \"en\" or \"pt-BR\"")
+
+	languagePattern = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+)
+
+func (uc *AppUsecase) SetTranslator(translator *integrations.Translator) {
+	uc.translator = translator
+}
+
+func validLanguage(language string) bool {
+	return len(language) <= 35 && languagePattern.MatchString(language)
+}
+
+func validLanguageValue(value json.RawMessage) bool {
+	var language string
+	return json.Unmarshal(value, &language) == nil && validLanguage(language)
+}
+
+func validBoolValue(value json.RawMessage) bool {
+	var b bool
+	return json.Unmarshal(value, &b) == nil
+}
+
+func (uc *AppUsecase) roomTranslation(ctx context.Context, roomID uuid.UUID) (string, bool) {
+	metadata, err := uc.repo.GetRoomMetadata(ctx, roomID)
+	if err != nil {
+		ucLog.Errorf("Failed to load translation settings of room %s: %v", roomID, err)
+		return "", false
+	}
+	var language string
+	var enabled bool
+	json.Unmarshal(metadata[domain.RoomMetadataLanguage], &language)
+	json.Unmarshal(metadata[domain.RoomMetadataAutoTranslate], &enabled)
+	return language, enabled && language != ""
+}
+
+func (uc *AppUsecase) translateMessage(ctx context.Context, msg domain.Message) {
+	if uc.translator == nil || msg.Content == "" || len(msg.Content) > maxTranslatedMessageLength {
+		return
+	}
+	source, enabled := uc.roomTranslation(ctx, msg.RoomID)
+	if !enabled {
+		return
+	}
+	languages, err := uc.repo.GetMemberLanguages(ctx, msg.RoomID)
+	if err != nil {
+		ucLog.Errorf("Failed to load member languages for message %d: %v", msg.ID, err)
+		return
+	}
+
+	seen := make(map[string]bool)
+	var targets []string
+	for userID, language := range languages {
+		if userID == msg.UserID || language == source || seen[language] {
+			continue
+		}
+		seen[language] = true
+		targets = append(targets, language)
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	translations, err := uc.translator.Translate(ctx, integrations.TranslationRequest{Text: msg.Content, Source: source, Targets: targets})
+	if err != nil {
+		ucLog.Errorf("Failed to translate message %d: %v", msg.ID, err)
+		return
+	}
+	for language := range translations {
+		if !seen[language] {
+			delete(translations, language)
+		}
+	}
+	if len(translations) == 0 {
+		return
+	}
+	if err := uc.repo.SaveMessageTranslations(ctx, msg.ID, translations); err != nil {
+		ucLog.Errorf("Failed to store translations of message %d: %v", msg.ID, err)
+	}
+
+	for userID, language := range languages {
+		if text, ok := translations[language]; ok && userID != msg.UserID {
+			uc.bcast.SendToUser(userID, encode.EncodeMsgTranslation(msg.ID, msg.RoomID, language, text))
+		}
+	}
+}
+
+func (uc *AppUsecase) attachTranslations(ctx context.Context, userID uuid.UUID, messages []domain.Message) {
+	if len(messages) == 0 {
+		return
+	}
+	settings, err := uc.repo.GetUserSettings(ctx, userID)
+	if err != nil || settings.Language == "" {
+		return
+	}
+	ids := make([]int64, len(messages))
+	for i, msg := range messages {
+		ids[i] = msg.ID
+	}
+	translations, err := uc.repo.GetMessageTranslations(ctx, ids, settings.Language)
+	if err != nil {
+		ucLog.Errorf("Failed to load translations for user %s: %v", userID, err)
+		return
+	}
+	for i := range messages {
+		if text, ok := translations[messages[i].ID]; ok {
+			messages[i].Translations = map[string]string{settings.Language: text}
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+	"time"
+
+	"chatservice/internal/attachments"
+	"chatservice/internal/domain"
+	"chatservice/internal/events"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultMaxUploadSize = 100 * 1024 * 1024
+	defaultUploadTTL     = 24 * time.Hour
+	maxUploadFilename    = 255
+)
+
+var (
+	ErrUploadsDisabled        = errors.New("uploads are not configured")
+	ErrInvalidUpload          = errors.New("invalid upload")
+	ErrUploadNotFound         = errors.New("upload not found")
+	ErrUploadExpired          = errors.New("upload has expired")
+	ErrUploadTooLarge         = errors.New("upload exceeds the maximum size")
+	ErrUploadOffsetMismatch   = errors.New("upload offset does not match")
+	ErrUploadChecksumMismatch = errors.New("upload checksum does not match")
+	ErrAttachmentNotFound     = errors.New("attachment not found")
+)
+
+type UploadProgress struct {
+	Upload     *domain.Upload
+	Message    *domain.Message
+	Attachment *domain.Attachment
+}
+
+func (uc *AppUsecase) SetUploads(storage *attachments.DiskStorage, maxSize int64, ttl time.Duration) {
+	uc.storage = storage
+	if maxSize > 0 {
+		uc.maxUploadSize = maxSize
+	}
+	if ttl > 0 {
+		uc.uploadTTL = ttl
+	}
+}
+
+func (uc *AppUsecase) CreateUpload(ctx context.Context, userID, roomID uuid.UUID, filename, contentType string, size int64, checksum string) (*domain.Upload, error) {
+	if uc.storage == nil {
+		return nil, ErrUploadsDisabled
+	}
+	isMember, err := uc.repo.IsUserInRoom(ctx, userID, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("could not verify room membership: %w", err)
+	}
+	if !isMember {
+		return nil, ErrNotRoomMember
+	}
+	if err := uc.authorizeRoomPost(ctx, userID, roomID); err != nil {
+		return nil, err
+	}
+	if size <= 0 {
+		return nil, fmt.Errorf("%w: size must be positive", ErrInvalidUpload)
+	}
+	if size > uc.maxUploadSize {
+		return nil, ErrUploadTooLarge
+	}
+	if filename == "" || len(filename) > maxUploadFilename || strings.ContainsAny(filename, "/\\\x00") {
+		return nil, fmt.Errorf("%w: filename must be 1-%d characters without path separators", ErrInvalidUpload, maxUploadFilename)
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	checksum = strings.ToLower(checksum)
+	if checksum != "" {
+		if decoded, err := hex.DecodeString(checksum); err != nil || len(decoded) != sha256.Size {
+			return nil, fmt.Errorf("%w: checksum must be a hex sha256 digest", ErrInvalidUpload)
+		}
+	}
+	if err := uc.consumeQuota(ctx, userID, quotaMessages); err != nil {
+		return nil, err
+	}
+
+	upload := &domain.Upload{
+		ID:             uuid.New(),
+		RoomID:         roomID,
+		UploaderID:     userID,
+		Filename:       filename,
+		ContentType:    contentType,
+		SizeBytes:      size,
+		ChecksumSHA256: checksum,
+		ExpiresAt:      time.Now().Add(uc.uploadTTL),
+	}
+	upload.StorageKey = upload.ID.String()
+	if err := uc.storage.Create(upload.StorageKey); err != nil {
+		return nil, err
+	}
+	if err := uc.repo.CreateUpload(ctx, upload); err != nil {
+		uc.storage.Remove(upload.StorageKey)
+		return nil, err
+	}
+	return upload, nil
+}
+
+func (uc *AppUsecase) GetUpload(ctx context.Context, userID, uploadID uuid.UUID) (*domain.Upload, error) {
+	upload, err := uc.repo.GetUpload(ctx, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if upload == nil || upload.UploaderID != userID {
+		return nil, ErrUploadNotFound
+	}
+	if upload.CompletedAt == nil && time.Now().After(upload.ExpiresAt) {
+		return nil, ErrUploadExpired
+	}
+	return upload, nil
+}
+
+func (uc *AppUsecase) AppendUpload(ctx context.Context, userID, uploadID uuid.UUID, offset int64, chunkChecksum string, body io.Reader) (*UploadProgress, error) {
+	if uc.storage == nil {
+		return nil, ErrUploadsDisabled
+	}
+	verify, expected, err := parseChunkChecksum(chunkChecksum)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := uc.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not start upload transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	upload, err := uc.repo.LockUpload(ctx, tx, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if upload == nil || upload.UploaderID != userID {
+		return nil, ErrUploadNotFound
+	}
+	if upload.CompletedAt != nil {
+		return &UploadProgress{Upload: upload}, nil
+	}
+	if time.Now().After(upload.ExpiresAt) {
+		return nil, ErrUploadExpired
+	}
+	if offset != upload.OffsetBytes {
+		return nil, ErrUploadOffsetMismatch
+	}
+
+	reader := io.LimitReader(body, upload.SizeBytes-offset+1)
+	if verify != nil {
+		reader = io.TeeReader(reader, verify)
+	}
+	written, err := uc.storage.Append(upload.StorageKey, offset, reader)
+	if err != nil {
+		uc.storage.Truncate(upload.StorageKey, offset)
+		return nil, err
+	}
+	if offset+written > upload.SizeBytes {
+		uc.storage.Truncate(upload.StorageKey, offset)
+		return nil, ErrUploadTooLarge
+	}
+	if verify != nil && string(verify.Sum(nil)) != string(expected) {
+		uc.storage.Truncate(upload.StorageKey, offset)
+		return nil, ErrUploadChecksumMismatch
+	}
+	upload.OffsetBytes = offset + written
+	if err := uc.repo.SetUploadOffset(ctx, tx, upload.ID, upload.OffsetBytes); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("could not commit upload progress: %w", err)
+	}
+
+	progress := &UploadProgress{Upload: upload}
+	if upload.OffsetBytes == upload.SizeBytes {
+		if err := uc.finalizeUpload(ctx, progress); err != nil {
+			return nil, err
+		}
+	}
+	return progress, nil
+}
+
+func (uc *AppUsecase) DeleteUpload(ctx context.Context, userID, uploadID uuid.UUID) error {
+	upload, err := uc.repo.GetUpload(ctx, uploadID)
+	if err != nil {
+		return err
+	}
+	if upload == nil || upload.UploaderID != userID || upload.CompletedAt != nil {
+		return ErrUploadNotFound
+	}
+	if err := uc.repo.DeleteUpload(ctx, uploadID); err != nil {
+		return err
+	}
+	return uc.storage.Remove(upload.StorageKey)
+}
+
+func (uc *AppUsecase) finalizeUpload(ctx context.Context, progress *UploadProgress) (err error) {
+	upload := progress.Upload
+	claimed, err := uc.repo.CompleteUpload(ctx, upload.ID)
+	if err != nil || !claimed {
+		return err
+	}
+	// The attachment and message are written after the claim, so a failure
+	// must reopen the upload or it stays completed with nothing posted.
+	defer func() {
+		if err == nil || errors.Is(err, ErrUploadChecksumMismatch) {
+			return
+		}
+		if reopenErr := uc.repo.ReopenUpload(context.WithoutCancel(ctx), upload.ID); reopenErr != nil {
+			ucLog.Errorf("Error reopening upload %s after a failed finalize: %v", upload.ID, reopenErr)
+		}
+	}()
+
+	if upload.ChecksumSHA256 != "" {
+		sum, err := uc.storage.Checksum(upload.StorageKey)
+		if err != nil {
+			return err
+		}
+		if sum != upload.ChecksumSHA256 {
+			uc.repo.DeleteUpload(ctx, upload.ID)
+			uc.storage.Remove(upload.StorageKey)
+			return ErrUploadChecksumMismatch
+		}
+	}
+
+	memberIDs, err := uc.repo.GetRoomMemberIDs(ctx, upload.RoomID)
+	if err != nil {
+		return fmt.Errorf("could not load room members: %w", err)
+	}
+	att := &domain.Attachment{
+		ID:          upload.ID,
+		RoomID:      upload.RoomID,
+		UploaderID:  upload.UploaderID,
+		Kind:        domain.AttachmentKindUpload,
+		StorageURL:  uc.storage.URL(upload.StorageKey),
+		ContentType: upload.ContentType,
+		SizeBytes:   upload.SizeBytes,
+	}
+	if err := uc.repo.CreateRestrictedAttachment(ctx, att, memberIDs); err != nil {
+		return err
+	}
+
+	msg, err := uc.repo.CreateMessage(ctx, &domain.Message{
+		MessageUID:   uuid.New(),
+		RoomID:       upload.RoomID,
+		UserID:       upload.UploaderID,
+		Content:      upload.Filename,
+		Kind:         domain.MessageKindAttachment,
+		AttachmentID: &att.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("could not post attachment message: %w", err)
+	}
+	now := time.Now()
+	upload.CompletedAt = &now
+	progress.Attachment = att
+	progress.Message = msg
+
+	uc.events.Publish(ctx, events.MessageCreated{Message: *msg})
+	ucLog.Infof("Upload %s finalized into message %d in room %s", upload.ID, msg.ID, upload.RoomID)
+	return nil
+}
+
+func parseChunkChecksum(header string) (hash.Hash, []byte, error) {
+	if header == "" {
+		return nil, nil, nil
+	}
+	algorithm, encoded, ok := strings.Cut(header, " ")
+	if !ok || algorithm != "sha256" {
+		return nil, nil, fmt.Errorf("%w: only sha256 chunk checksums are supported", ErrInvalidUpload)
+	}
+	expected, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(expected) != sha256.Size {
+		return nil, nil, fmt.Errorf("%w: malformed chunk checksum", ErrInvalidUpload)
+	}
+	return sha256.New(), expected, nil
+}
+
+func (uc *AppUsecase) OpenAttachment(ctx context.Context, userID, attachmentID uuid.UUID) (*domain.Attachment, io.ReadSeekCloser, error) {
+	att, err := uc.repo.GetAttachmentForUser(ctx, attachmentID, userID)
+	if err != nil {
+		return nil, nil, ErrAttachmentNotFound
+	}
+	key, onDisk := attachments.KeyFromURL(att.StorageURL)
+	if !onDisk {
+		return att, nil, nil
+	}
+	if uc.storage == nil {
+		return nil, nil, ErrUploadsDisabled
+	}
+	f, err := uc.storage.Open(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not open attachment %s: %w", attachmentID, err)
+	}
+	return att, f, nil
+}
@@ -0,0 +1,53 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+const (
+	urgentMessagesMetadataKey = adminRoomMetadataPrefix + "urgent_messages"
+
+	urgentMessagesAll    = "all"
+	urgentMessagesAdmins = "admins"
+	urgentMessagesNobody = "nobody"
+)
+
+var ErrUrgentMessageForbidden = errors.New("you are not allowed to send urgent messages in this room")
+
+func validUrgentMessages(value json.RawMessage) bool {
+	var policy string
+	if err := json.Unmarshal(value, &policy); err != nil {
+		return false
+	}
+	return policy == urgentMessagesAll || policy == urgentMessagesAdmins || policy == urgentMessagesNobody
+}
+
+func (uc *AppUsecase) authorizeUrgentMessage(ctx context.Context, senderID, roomID uuid.UUID) error {
+	metadata, err := uc.repo.GetRoomMetadata(ctx, roomID)
+	if err != nil {
+		return fmt.Errorf("could not load room settings: %w", err)
+	}
+	policy := urgentMessagesAdmins
+	if raw, ok := metadata[urgentMessagesMetadataKey]; ok {
+		json.Unmarshal(raw, &policy)
+	}
+	switch policy {
+	case urgentMessagesAll:
+	case urgentMessagesNobody:
+		return ErrUrgentMessageForbidden
+	default:
+		role, err := uc.repo.GetRoomRole(ctx, senderID, roomID)
+		if err != nil {
+			return fmt.Errorf("could not verify room role: %w", err)
+		}
+		if role != "owner" && role != "admin" {
+			return ErrUrgentMessageForbidden
+		}
+	}
+	return uc.consumeQuota(ctx, senderID, quotaUrgentMessages)
+}
@@ -0,0 +1,123 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/events"
+
+	"github.com/google/uuid"
+)
+
+const (
+	welcomeMessageMetadataKey  = adminRoomMetadataPrefix + "welcome_message"
+	welcomeDeliveryMetadataKey = adminRoomMetadataPrefix + "welcome_delivery"
+
+	welcomeDeliveryRoom = "room"
+	welcomeDeliveryDM   = "dm"
+
+	maxWelcomeMessageLength = 1000
+)
+
+func validWelcomeMessage(value json.RawMessage) bool {
+	var template string
+	if err := json.Unmarshal(value, &template); err != nil {
+		return false
+	}
+	length := utf8.RuneCountInString(strings.TrimSpace(template))
+	return length > 0 && length <= maxWelcomeMessageLength
+}
+
+func validWelcomeDelivery(value json.RawMessage) bool {
+	var delivery string
+	if err := json.Unmarshal(value, &delivery); err != nil {
+		return false
+	}
+	return delivery == welcomeDeliveryRoom || delivery == welcomeDeliveryDM
+}
+
+func (uc *AppUsecase) HandleEvent(ctx context.Context, event events.Event) {
+	switch e := event.(type) {
+	case events.RoomMembersAdded:
+		uc.welcomeMembers(ctx, e)
+		uc.bumpStateVersions(ctx, e.UserIDs...)
+	case events.RoomMembersRemoved:
+		uc.bumpStateVersions(ctx, e.UserIDs...)
+	case events.RoomUpdated:
+		uc.bumpStateVersions(ctx, e.UserID)
+	case events.FriendRequestSent:
+		uc.bumpStateVersions(ctx, e.Sender.ID, e.Receiver.ID)
+	case events.FriendshipAccepted:
+		uc.bumpStateVersions(ctx, e.Accepter.ID, e.RequesterID)
+	case events.FriendRequestDeclined:
+		uc.bumpStateVersions(ctx, e.DeclinerID, e.RequesterID)
+	case events.MessageCreated:
+		if e.Message.Kind == domain.MessageKindText {
+			go uc.autoReply(context.WithoutCancel(ctx), e.Message)
+			go uc.trackSupportMessage(context.WithoutCancel(ctx), e.Message)
+		}
+	}
+}
+
+func (uc *AppUsecase) welcomeMembers(ctx context.Context, e events.RoomMembersAdded) {
+	if e.Room.Type != "group" {
+		return
+	}
+	metadata, err := uc.repo.GetRoomMetadata(ctx, e.Room.ID)
+	if err != nil {
+		ucLog.Errorf("Failed to load welcome template of room %s: %v", e.Room.ID, err)
+		return
+	}
+	var template string
+	if raw, ok := metadata[welcomeMessageMetadataKey]; !ok || json.Unmarshal(raw, &template) != nil || strings.TrimSpace(template) == "" {
+		return
+	}
+	delivery := welcomeDeliveryRoom
+	if raw, ok := metadata[welcomeDeliveryMetadataKey]; ok {
+		json.Unmarshal(raw, &delivery)
+	}
+
+	var mentions []string
+	for _, userID := range e.UserIDs {
+		if userID == e.AddedBy {
+			continue
+		}
+		user, err := uc.repo.GetUserByID(ctx, userID)
+		if err != nil || user == nil {
+			continue
+		}
+		if delivery == welcomeDeliveryDM {
+			dmRoomID, err := uc.repo.FindPrivateRoomByParticipants(ctx, e.AddedBy, userID)
+			if err == nil && dmRoomID != uuid.Nil {
+				uc.handleSendMessage(ctx, e.AddedBy, dmRoomID, uuid.New(), renderWelcome(template, e.Room, mentionOf(*user)), "", nil)
+				continue
+			}
+		}
+		mentions = append(mentions, mentionOf(*user))
+	}
+	if len(mentions) > 0 {
+		uc.handleSendMessage(ctx, e.AddedBy, e.Room.ID, uuid.New(), renderWelcome(template, e.Room, strings.Join(mentions, ", ")), "", nil)
+	}
+}
+
+func mentionOf(user domain.User) string {
+	if user.Username != "" {
+		return "@" + user.Username
+	}
+	return user.Nickname
+}
+
+func renderWelcome(template string, room domain.Room, members string) string {
+	roomName := "the room"
+	if room.Name != nil && *room.Name != "" {
+		roomName = *room.Name
+	}
+	rendered := strings.NewReplacer("{{user}}", members, "{{room}}", roomName).Replace(template)
+	if !strings.Contains(template, "{{user}}") {
+		rendered = members + " " + rendered
+	}
+	return rendered
+}
@@ -0,0 +1,357 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"chatservice/internal/domain"
+	"chatservice/internal/events"
+	"chatservice/internal/tenant"
+
+	"github.com/google/uuid"
+)
+
+const (
+	widgetKeyPrefix       = "wk_"
+	guestTokenPrefix      = "wg_"
+	maxWidgetLabelLength  = 100
+	maxGuestNameLength    = 64
+	defaultGuestPageLimit = 50
+	maxGuestPageLimit     = 200
+	maxPostedMessageBytes = 4 * 1024
+
+	guestStartWindow      = time.Minute
+	maxGuestStartsPerIP   = 5
+	maxGuestStartsPerKey  = 60
+	maxGuestStartCounters = 10000
+)
+
+var (
+	ErrWidgetKeyForbidden   = errors.New("only room owners and admins may manage widget keys")
+	ErrWidgetRoomNotGroup   = errors.New("widget keys can only be issued for group rooms")
+	ErrInvalidWidgetLabel   = errors.New("widget key label must be at most 100 characters")
+	ErrWidgetKeyNotFound    = errors.New("widget key not found")
+	ErrInvalidWidgetKey     = errors.New("widget key is invalid or has been revoked")
+	ErrInvalidGuestToken    = errors.New("guest session is invalid or its widget key has been revoked")
+	ErrInvalidGuestName     = errors.New("display name must be 1-64 characters")
+	ErrWidgetRoomNotPublic  = errors.New("widget keys require the room's " + domain.RoomMetadataWidget + " flag to be enabled")
+	ErrGuestSessionsLimited = errors.New("too many guest sessions started, try again later")
+	ErrGuestTenantMismatch  = errors.New("guest session belongs to a different tenant")
+)
+
+type guestStartCount struct {
+	count       int
+	windowStart time.Time
+}
+
+type guestStartLimiter struct {
+	mu     sync.Mutex
+	counts map[string]*guestStartCount
+}
+
+func (l *guestStartLimiter) allow(now time.Time, ipAddress, keyHash string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.counts == nil {
+		l.counts = make(map[string]*guestStartCount)
+	}
+	if len(l.counts) >= maxGuestStartCounters {
+		for k, c := range l.counts {
+			if now.Sub(c.windowStart) > guestStartWindow {
+				delete(l.counts, k)
+			}
+		}
+	}
+	ipCount, keyCount := l.count(now, "ip:"+ipAddress), l.count(now, "key:"+keyHash)
+	if ipCount.count >= maxGuestStartsPerIP || keyCount.count >= maxGuestStartsPerKey {
+		return false
+	}
+	ipCount.count++
+	keyCount.count++
+	return true
+}
+
+func (l *guestStartLimiter) count(now time.Time, key string) *guestStartCount {
+	c, ok := l.counts[key]
+	if !ok || now.Sub(c.windowStart) > guestStartWindow {
+		c = &guestStartCount{windowStart: now}
+		l.counts[key] = c
+	}
+	return c
+}
+
+type CreatedWidgetKey struct {
+	domain.WidgetKey
+	Key string `json:"key"`
+}
+
+type GuestSession struct {
+	domain.WidgetGuest
+	Token string `json:"token"`
+}
+
+func (uc *AppUsecase) CreateWidgetKey(ctx context.Context, userID, roomID uuid.UUID, label string) (*CreatedWidgetKey, error) {
+	if err := uc.requireRoomAdmin(ctx, userID, roomID, ErrWidgetKeyForbidden); err != nil {
+		return nil, err
+	}
+	label = strings.TrimSpace(label)
+	if utf8.RuneCountInString(label) > maxWidgetLabelLength {
+		return nil, ErrInvalidWidgetLabel
+	}
+	room, err := uc.repo.GetRoomByID(ctx, roomID)
+	if err != nil {
+		return nil, ErrRoomNotFound
+	}
+	if room.Type != "group" {
+		return nil, ErrWidgetRoomNotGroup
+	}
+	if !uc.widgetEnabled(ctx, roomID) {
+		return nil, ErrWidgetRoomNotPublic
+	}
+
+	key, err := newScopedToken(ctx, widgetKeyPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("could not generate widget key: %w", err)
+	}
+	widgetKey := domain.WidgetKey{
+		ID:        uuid.New(),
+		RoomID:    roomID,
+		Prefix:    key[:len(widgetKeyPrefix)+8],
+		Label:     label,
+		CreatedBy: userID,
+	}
+	if err := uc.repo.CreateWidgetKey(ctx, &widgetKey, hashShareToken(key)); err != nil {
+		return nil, err
+	}
+	return &CreatedWidgetKey{WidgetKey: widgetKey, Key: key}, nil
+}
+
+func (uc *AppUsecase) ListWidgetKeys(ctx context.Context, userID, roomID uuid.UUID) ([]domain.WidgetKey, error) {
+	if err := uc.requireRoomAdmin(ctx, userID, roomID, ErrWidgetKeyForbidden); err != nil {
+		return nil, err
+	}
+	return uc.repo.ListWidgetKeys(ctx, roomID)
+}
+
+func (uc *AppUsecase) RevokeWidgetKey(ctx context.Context, userID, roomID, keyID uuid.UUID) error {
+	if err := uc.requireRoomAdmin(ctx, userID, roomID, ErrWidgetKeyForbidden); err != nil {
+		return err
+	}
+	revoked, err := uc.repo.RevokeWidgetKey(ctx, roomID, keyID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrWidgetKeyNotFound
+	}
+	return nil
+}
+
+func (uc *AppUsecase) StartGuestSession(ctx context.Context, key, displayName, ipAddress string) (*GuestSession, error) {
+	ctx, ok := scopedTokenContext(ctx, key, widgetKeyPrefix)
+	if !ok {
+		return nil, ErrInvalidWidgetKey
+	}
+	displayName = strings.TrimSpace(displayName)
+	if length := utf8.RuneCountInString(displayName); length == 0 || length > maxGuestNameLength {
+		return nil, ErrInvalidGuestName
+	}
+	keyHash := hashShareToken(key)
+	if !uc.guestStarts.allow(time.Now(), ipAddress, keyHash) {
+		return nil, ErrGuestSessionsLimited
+	}
+	widgetKey, err := uc.repo.GetWidgetKeyByHash(ctx, keyHash)
+	if err != nil {
+		return nil, err
+	}
+	if widgetKey == nil || !uc.widgetEnabled(ctx, widgetKey.RoomID) {
+		return nil, ErrInvalidWidgetKey
+	}
+
+	token, err := newScopedToken(ctx, guestTokenPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("could not generate guest token: %w", err)
+	}
+	guest := domain.WidgetGuest{
+		UserID:      uuid.New(),
+		KeyID:       widgetKey.ID,
+		RoomID:      widgetKey.RoomID,
+		DisplayName: displayName,
+	}
+	tx, err := uc.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	if err := uc.repo.CreateWidgetGuest(ctx, tx, &guest, hashShareToken(token)); err != nil {
+		return nil, err
+	}
+	if err := uc.repo.AddUserToRoomWithRole(ctx, tx, guest.UserID, guest.RoomID, "guest"); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("could not commit guest session: %w", err)
+	}
+	uc.bcast.Subscribe(guest.UserID, guest.RoomID)
+	return &GuestSession{WidgetGuest: guest, Token: token}, nil
+}
+
+func (uc *AppUsecase) GetGuestMessages(ctx context.Context, token string, afterID int64, limit int) ([]domain.SharedMessage, error) {
+	ctx, guest, err := uc.authenticateGuest(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultGuestPageLimit
+	}
+	messages, err := uc.repo.GetGuestMessages(ctx, guest.RoomID, guest.CreatedAt, max(afterID, 0), min(limit, maxGuestPageLimit))
+	if err != nil {
+		return nil, err
+	}
+	for i := range messages {
+		messages[i].Content = sanitizeMarkdown(messages[i].Content)
+	}
+	return messages, nil
+}
+
+func (uc *AppUsecase) SendGuestMessage(ctx context.Context, token, content string) (*domain.Message, error) {
+	ctx, guest, err := uc.authenticateGuest(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := uc.postTextMessage(ctx, guest.UserID, guest.RoomID, content)
+	if err != nil {
+		return nil, err
+	}
+	ucLog.Infof("Guest %s posted message %d in room %s", guest.UserID, msg.ID, guest.RoomID)
+	return msg, nil
+}
+
+func (uc *AppUsecase) MergeGuestIdentity(ctx context.Context, userID uuid.UUID, token string) (*domain.GuestMerge, error) {
+	callerTenant := tenant.FromContext(ctx)
+	ctx, ok := scopedTokenContext(ctx, token, guestTokenPrefix)
+	if !ok {
+		return nil, ErrInvalidGuestToken
+	}
+	if tenant.FromContext(ctx) != callerTenant {
+		return nil, ErrGuestTenantMismatch
+	}
+	tx, err := uc.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	guest, err := uc.repo.LockWidgetGuest(ctx, tx, hashShareToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if guest == nil {
+		return nil, ErrInvalidGuestToken
+	}
+	merge, err := uc.repo.MergeWidgetGuest(ctx, tx, guest, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("could not commit guest merge: %w", err)
+	}
+	ucLog.Infof("Merged guest %s into %s, moving %d messages in room %s", guest.UserID, userID, merge.MessagesMoved, guest.RoomID)
+
+	uc.events.Publish(ctx, events.GuestMerged{Merge: *merge})
+	uc.events.Publish(ctx, events.RoomMembersRemoved{RoomID: guest.RoomID, RemovedBy: userID, UserIDs: []uuid.UUID{guest.UserID}})
+	if !merge.AlreadyMember {
+		if room, err := uc.repo.GetRoomByID(ctx, guest.RoomID); err == nil {
+			uc.events.Publish(ctx, events.RoomMembersAdded{Room: *room, AddedBy: userID, UserIDs: []uuid.UUID{userID}})
+		} else {
+			ucLog.Errorf("Failed to load room %s after guest merge: %v", guest.RoomID, err)
+		}
+	}
+	return merge, nil
+}
+
+func (uc *AppUsecase) postTextMessage(ctx context.Context, senderID, roomID uuid.UUID, content string) (*domain.Message, error) {
+	if strings.TrimSpace(content) == "" || len(content) > maxPostedMessageBytes {
+		return nil, fmt.Errorf("%w: message must be between 1 and %d bytes", ErrInvalidContent, maxPostedMessageBytes)
+	}
+	if err := uc.ensureRoomWritable(ctx, senderID, roomID); err != nil {
+		return nil, err
+	}
+	processed, err := processMessage("", content)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.consumeQuota(ctx, senderID, quotaMessages); err != nil {
+		return nil, err
+	}
+	mentions, _ := splitGroupMentions(processed.Mentions)
+	msg, err := uc.repo.CreateMessage(ctx, &domain.Message{
+		MessageUID: uuid.New(),
+		RoomID:     roomID,
+		UserID:     senderID,
+		Content:    processed.Content,
+		Links:      processed.Links,
+		Hashtags:   processed.Hashtags,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not save message: %w", err)
+	}
+	uc.recordMentions(ctx, msg, mentions)
+	uc.events.Publish(ctx, events.MessageCreated{Message: *msg})
+	go uc.translateMessage(context.WithoutCancel(ctx), *msg)
+	return msg, nil
+}
+
+func (uc *AppUsecase) authenticateGuest(ctx context.Context, token string) (context.Context, *domain.WidgetGuest, error) {
+	ctx, ok := scopedTokenContext(ctx, token, guestTokenPrefix)
+	if !ok {
+		return ctx, nil, ErrInvalidGuestToken
+	}
+	guest, err := uc.repo.TouchWidgetGuest(ctx, hashShareToken(token))
+	if err != nil {
+		return ctx, nil, err
+	}
+	if guest == nil || !uc.widgetEnabled(ctx, guest.RoomID) {
+		return ctx, nil, ErrInvalidGuestToken
+	}
+	return ctx, guest, nil
+}
+
+func (uc *AppUsecase) widgetEnabled(ctx context.Context, roomID uuid.UUID) bool {
+	metadata, err := uc.repo.GetRoomMetadata(ctx, roomID)
+	if err != nil {
+		ucLog.Errorf("Failed to load widget settings of room %s: %v", roomID, err)
+		return false
+	}
+	var enabled bool
+	json.Unmarshal(metadata[domain.RoomMetadataWidget], &enabled)
+	return enabled
+}
+
+func newScopedToken(ctx context.Context, prefix string) (string, error) {
+	secret, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	if tenantID := tenant.FromContext(ctx); tenantID != "" {
+		return prefix + tenantID + "." + secret, nil
+	}
+	return prefix + secret, nil
+}
+
+func scopedTokenContext(ctx context.Context, token, prefix string) (context.Context, bool) {
+	body, ok := strings.CutPrefix(token, prefix)
+	if !ok || body == "" {
+		return ctx, false
+	}
+	if tenantID, _, scoped := strings.Cut(body, "."); scoped {
+		return tenant.WithTenant(ctx, tenantID), true
+	}
+	return ctx, true
+}
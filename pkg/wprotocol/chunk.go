@@ -0,0 +1,143 @@
+package wprotocol
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"time"
+)
+
+const (
+	chunkStreamTimeout  = 30 * time.Second
+	maxChunkIDLength    = 64
+	chunkBufferPrealloc = 64 * 1024
+)
+
+var (
+	ErrChunkTooLarge   = errors.New("chunked payload exceeds size limit")
+	ErrChunkSequence   = errors.New("chunk received out of sequence")
+	ErrTooManyChunks   = errors.New("too many concurrent chunk streams")
+	ErrUnknownChunkID  = errors.New("unknown chunk stream")
+	ErrChunkIncomplete = errors.New("chunk stream ended before all parts arrived")
+)
+
+func IsChunkOp(op OpCode) bool {
+	return op == OpChunkStart || op == OpChunkPart || op == OpChunkEnd
+}
+
+func Split(frame []byte, partSize int, id string) [][]byte {
+	if partSize <= 0 {
+		partSize = len(frame)
+	}
+	parts := (len(frame) + partSize - 1) / partSize
+
+	frames := make([][]byte, 0, parts+2)
+	frames = append(frames, Build(OpChunkStart, id, strconv.Itoa(len(frame)), strconv.Itoa(parts)))
+	for i := 0; i < parts; i++ {
+		end := min((i+1)*partSize, len(frame))
+		data := base64.StdEncoding.EncodeToString(frame[i*partSize : end])
+		frames = append(frames, Build(OpChunkPart, id, strconv.Itoa(i), data))
+	}
+	frames = append(frames, Build(OpChunkEnd, id))
+	return frames
+}
+
+type chunkStream struct {
+	total   int
+	parts   int
+	next    int
+	buf     []byte
+	started time.Time
+}
+
+type Assembler struct {
+	maxSize    int
+	maxStreams int
+	streams    map[string]*chunkStream
+}
+
+func NewAssembler(maxSize, maxStreams int) *Assembler {
+	return &Assembler{
+		maxSize:    maxSize,
+		maxStreams: maxStreams,
+		streams:    make(map[string]*chunkStream),
+	}
+}
+
+func (a *Assembler) Feed(p *Packet) ([]byte, bool, error) {
+	if len(p.Payload) < 1 {
+		return nil, false, ErrInvalidPacket
+	}
+	id := p.Payload[0]
+	if id == "" || len(id) > maxChunkIDLength {
+		return nil, false, ErrInvalidPacket
+	}
+
+	switch p.Op {
+	case OpChunkStart:
+		if len(p.Payload) < 3 {
+			return nil, false, ErrInvalidPacket
+		}
+		total, err1 := strconv.Atoi(p.Payload[1])
+		parts, err2 := strconv.Atoi(p.Payload[2])
+		if err1 != nil || err2 != nil || total < 0 || parts < 0 || parts > total || (total > 0 && parts == 0) {
+			return nil, false, ErrInvalidPacket
+		}
+		if total > a.maxSize {
+			return nil, false, ErrChunkTooLarge
+		}
+		a.evictStale(time.Now())
+		if len(a.streams) >= a.maxStreams {
+			return nil, false, ErrTooManyChunks
+		}
+		a.streams[id] = &chunkStream{total: total, parts: parts, buf: make([]byte, 0, min(total, chunkBufferPrealloc)), started: time.Now()}
+		return nil, false, nil
+
+	case OpChunkPart:
+		stream, ok := a.streams[id]
+		if !ok {
+			return nil, false, ErrUnknownChunkID
+		}
+		if len(p.Payload) < 3 {
+			delete(a.streams, id)
+			return nil, false, ErrInvalidPacket
+		}
+		index, err := strconv.Atoi(p.Payload[1])
+		if err != nil || index != stream.next {
+			delete(a.streams, id)
+			return nil, false, ErrChunkSequence
+		}
+		data, err := base64.StdEncoding.DecodeString(p.Payload[2])
+		if err != nil {
+			delete(a.streams, id)
+			return nil, false, ErrInvalidPacket
+		}
+		if len(stream.buf)+len(data) > stream.total || stream.next >= stream.parts {
+			delete(a.streams, id)
+			return nil, false, ErrChunkTooLarge
+		}
+		stream.buf = append(stream.buf, data...)
+		stream.next++
+		return nil, false, nil
+
+	case OpChunkEnd:
+		stream, ok := a.streams[id]
+		if !ok {
+			return nil, false, ErrUnknownChunkID
+		}
+		delete(a.streams, id)
+		if stream.next != stream.parts || len(stream.buf) != stream.total {
+			return nil, false, ErrChunkIncomplete
+		}
+		return stream.buf, true, nil
+	}
+	return nil, false, ErrInvalidPacket
+}
+
+func (a *Assembler) evictStale(now time.Time) {
+	for id, stream := range a.streams {
+		if now.Sub(stream.started) > chunkStreamTimeout {
+			delete(a.streams, id)
+		}
+	}
+}
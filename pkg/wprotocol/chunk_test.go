@@ -0,0 +1,71 @@
+package wprotocol
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const (
+	fuzzMaxChunked = 4096
+	fuzzMaxStreams = 2
+)
+
+func FuzzAssembler(f *testing.F) {
+	join := func(frames [][]byte) []byte { return bytes.Join(frames, []byte{'\n'}) }
+	f.Add(join(Split([]byte("1\x1froom\x1euid\x1ehello"), 5, "1")))
+	f.Add(join(Split(bytes.Repeat([]byte("x"), fuzzMaxChunked), 1000, "big")))
+	f.Add(join(Split(bytes.Repeat([]byte("x"), fuzzMaxChunked+1), 1000, "big")))
+	f.Add([]byte("24\x1fid\x1e" + strconv.Itoa(fuzzMaxChunked) + "\x1e" + strconv.Itoa(MaxPacketFields)))
+	f.Add([]byte("24\x1fid\x1e10\x1e11"))
+	f.Add([]byte("24\x1fid\x1e4\x1e1\n25\x1fid\x1e0\x1eAAAAAAAA\n26\x1fid"))
+	f.Add([]byte("24\x1f" + strings.Repeat("i", maxChunkIDLength+1) + "\x1e1\x1e1"))
+	f.Add([]byte("24\x1fa\x1e1\x1e1\n24\x1fb\x1e1\x1e1\n24\x1fc\x1e1\x1e1"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		a := NewAssembler(fuzzMaxChunked, fuzzMaxStreams)
+		for _, frame := range bytes.Split(data, []byte{'\n'}) {
+			packet, err := Parse(frame)
+			if err != nil || !IsChunkOp(packet.Op) {
+				continue
+			}
+			assembled, done, err := a.Feed(packet)
+			if len(a.streams) > fuzzMaxStreams {
+				t.Fatalf("%d open streams, limit is %d", len(a.streams), fuzzMaxStreams)
+			}
+			for id, stream := range a.streams {
+				if len(stream.buf) > stream.total || stream.total > fuzzMaxChunked {
+					t.Fatalf("stream %q buffered %d of %d bytes", id, len(stream.buf), stream.total)
+				}
+			}
+			if err != nil && done {
+				t.Fatalf("Feed reported completion with error %v", err)
+			}
+			if done && len(assembled) > fuzzMaxChunked {
+				t.Fatalf("assembled %d bytes, limit is %d", len(assembled), fuzzMaxChunked)
+			}
+		}
+	})
+}
+
+func TestSplitReassembles(t *testing.T) {
+	frame := Build(OpMsgSend, "room", "uid", strings.Repeat("hello ", 100))
+	a := NewAssembler(len(frame), 1)
+	var got []byte
+	for _, part := range Split(frame, 64, "7") {
+		packet, err := Parse(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assembled, done, err := a.Feed(packet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if done {
+			got = assembled
+		}
+	}
+	if !bytes.Equal(got, frame) {
+		t.Fatalf("reassembled %q, want %q", got, frame)
+	}
+}
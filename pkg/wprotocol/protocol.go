@@ -3,6 +3,7 @@ package wprotocol
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"strconv"
 	"strings"
 )
@@ -12,7 +13,17 @@ const (
 	RecordSeparator = '\x1e'
 )
 
-var ErrInvalidPacket = errors.New("invalid packet format")
+const (
+	MaxPacketFields = 64
+	MaxFieldLength  = 64 * 1024
+	maxOpDigits     = 3
+)
+
+var (
+	ErrInvalidPacket = errors.New("invalid packet format")
+	ErrTooManyFields = fmt.Errorf("%w: more than %d fields", ErrInvalidPacket, MaxPacketFields)
+	ErrFieldTooLong  = fmt.Errorf("%w: field longer than %d bytes", ErrInvalidPacket, MaxFieldLength)
+)
 
 type OpCode uint8
 
@@ -34,6 +45,40 @@ const (
 	OpFriendRequestAccepted OpCode = 16
 	OpFriendRemoved         OpCode = 17
 	OpWebRTCSignal          OpCode = 20
+	OpSessionToken          OpCode = 21
+	OpSessionResumed        OpCode = 22
+	OpDeprecated            OpCode = 23
+	OpChunkStart            OpCode = 24
+	OpChunkPart             OpCode = 25
+	OpChunkEnd              OpCode = 26
+	OpCallParticipantJoined OpCode = 27
+	OpCallParticipantLeft   OpCode = 28
+	OpCallEnded             OpCode = 29
+	OpCallStateUpdate       OpCode = 30
+	OpCallState             OpCode = 31
+	OpCallStateSync         OpCode = 32
+	OpCallRecordingRequest  OpCode = 33
+	OpCallRecordingPrompt   OpCode = 34
+	OpCallRecordingConsent  OpCode = 35
+	OpCallRecordingStarted  OpCode = 36
+	OpCallRecordingStop     OpCode = 37
+	OpCallRecordingStopped  OpCode = 38
+	OpRoomFocus             OpCode = 39
+	OpRoomState             OpCode = 40
+	OpMsgAction             OpCode = 41
+	OpRoomMembersAdded      OpCode = 42
+	OpMsgTranslation        OpCode = 43
+	OpMsgDeliverBatch       OpCode = 44
+	OpRoomUpdated           OpCode = 45
+	OpSupportAssignment     OpCode = 46
+	OpSupportNote           OpCode = 47
+	OpSupportSLABreached    OpCode = 48
+	OpRoomStateChanged      OpCode = 49
+	OpUserProfileUpdated    OpCode = 50
+	OpStateVersion          OpCode = 51
+	OpSuggestions           OpCode = 52
+	OpSessionResync         OpCode = 53
+	OpMsgReaction           OpCode = 54
 	OpError                 OpCode = 255
 )
 
@@ -44,7 +89,7 @@ type Packet struct {
 
 func Parse(data []byte) (*Packet, error) {
 	parts := bytes.SplitN(data, []byte{UnitSeparator}, 2)
-	if len(parts) < 1 || len(parts[0]) == 0 {
+	if len(parts) < 1 || len(parts[0]) == 0 || len(parts[0]) > maxOpDigits {
 		return nil, ErrInvalidPacket
 	}
 	op, err := strconv.ParseUint(string(parts[0]), 10, 8)
@@ -53,7 +98,15 @@ func Parse(data []byte) (*Packet, error) {
 	}
 	var payload []string
 	if len(parts) == 2 {
+		if bytes.Count(parts[1], []byte{RecordSeparator}) >= MaxPacketFields {
+			return nil, ErrTooManyFields
+		}
 		payload = strings.Split(string(parts[1]), string(RecordSeparator))
+		for _, field := range payload {
+			if len(field) > MaxFieldLength {
+				return nil, ErrFieldTooLong
+			}
+		}
 	}
 	return &Packet{Op: OpCode(op), Payload: payload}, nil
 }
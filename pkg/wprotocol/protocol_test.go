@@ -0,0 +1,89 @@
+package wprotocol
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func addParseSeeds(f *testing.F) {
+	fields := func(n int) string { return strings.Repeat("a"+string(RecordSeparator), n-1) + "a" }
+	f.Add([]byte("1\x1froom\x1euid\x1ehello"))
+	f.Add([]byte("255"))
+	f.Add([]byte("255\x1f"))
+	f.Add([]byte("256\x1fx"))
+	f.Add([]byte("0001\x1fx"))
+	f.Add([]byte("\x1fx"))
+	f.Add([]byte("1\x1f" + fields(MaxPacketFields)))
+	f.Add([]byte("1\x1f" + fields(MaxPacketFields+1)))
+	f.Add([]byte("1\x1f" + strings.Repeat("x", MaxFieldLength)))
+	f.Add([]byte("1\x1f" + strings.Repeat("x", MaxFieldLength+1)))
+	f.Add([]byte("24\x1fid\x1e10\x1e1"))
+}
+
+func FuzzParse(f *testing.F) {
+	addParseSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		p, err := Parse(data)
+		if err != nil {
+			return
+		}
+		if len(p.Payload) > MaxPacketFields {
+			t.Fatalf("parsed %d fields, limit is %d", len(p.Payload), MaxPacketFields)
+		}
+		for _, field := range p.Payload {
+			if len(field) > MaxFieldLength {
+				t.Fatalf("parsed a %d byte field, limit is %d", len(field), MaxFieldLength)
+			}
+		}
+		if p.Payload == nil {
+			return
+		}
+		again, err := Parse(Build(p.Op, p.Payload...))
+		if err != nil {
+			t.Fatalf("rebuilt packet does not parse: %v", err)
+		}
+		if again.Op != p.Op || !slices.Equal(again.Payload, p.Payload) {
+			t.Fatalf("round trip changed packet: %+v != %+v", again, p)
+		}
+	})
+}
+
+// FuzzDispatch follows a frame the way the websocket read path does: peek
+// the opcode, reassemble chunk frames, then parse and look up the result.
+func FuzzDispatch(f *testing.F) {
+	addParseSeeds(f)
+	for _, part := range Split(Build(OpMsgSend, "room", "uid", "hello"), 4, "1") {
+		f.Add(part)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		assembler := NewAssembler(1024, 2)
+		for _, frame := range bytes.Split(data, []byte{'\n'}) {
+			if op, ok := PeekOp(frame); ok && IsChunkOp(op) {
+				packet, err := Parse(frame)
+				if err != nil {
+					continue
+				}
+				assembled, done, err := assembler.Feed(packet)
+				if err != nil || !done {
+					continue
+				}
+				frame = assembled
+			}
+			packet, err := Parse(frame)
+			if err != nil {
+				continue
+			}
+			if op, ok := PeekOp(frame); !ok || op != packet.Op {
+				t.Fatalf("PeekOp = %v, %v; Parse found %v", op, ok, packet.Op)
+			}
+			if info, ok := Lookup(packet.Op); ok {
+				info.SupportedBy(1)
+			}
+			if packet.Op.String() == "" {
+				t.Fatalf("opcode %d has no name", packet.Op)
+			}
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package record
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"chatservice/pkg/wprotocol"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DirectionIn  = "in"
+	DirectionOut = "out"
+)
+
+var tokenPattern = regexp.MustCompile(`^[a-z][a-z0-9_.-]{0,31}$`)
+
+type Frame struct {
+	OffsetMs  int64    `json:"offsetMs"`
+	Direction string   `json:"dir"`
+	Op        uint8    `json:"op"`
+	Name      string   `json:"name,omitempty"`
+	Payload   []string `json:"payload,omitempty"`
+}
+
+func (f Frame) Bytes() []byte {
+	return wprotocol.Build(wprotocol.OpCode(f.Op), f.Payload...)
+}
+
+type Recorder struct {
+	mu      sync.Mutex
+	out     io.WriteCloser
+	enc     *json.Encoder
+	started time.Time
+}
+
+func NewRecorder(out io.WriteCloser) *Recorder {
+	return &Recorder{out: out, enc: json.NewEncoder(out), started: time.Now()}
+}
+
+func (r *Recorder) Record(direction string, data []byte) {
+	if r == nil {
+		return
+	}
+	packet, err := wprotocol.Parse(data)
+	if err != nil {
+		return
+	}
+	frame := Frame{
+		OffsetMs:  time.Since(r.started).Milliseconds(),
+		Direction: direction,
+		Op:        uint8(packet.Op),
+		Name:      packet.Op.String(),
+		Payload:   Redact(packet.Payload),
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.enc.Encode(frame)
+}
+
+func (r *Recorder) Close() error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.out.Close()
+}
+
+func Redact(payload []string) []string {
+	redacted := make([]string, len(payload))
+	for i, value := range payload {
+		if keep(value) {
+			redacted[i] = value
+			continue
+		}
+		redacted[i] = fmt.Sprintf("<redacted:%d>", len(value))
+	}
+	return redacted
+}
+
+func keep(value string) bool {
+	if value == "" || tokenPattern.MatchString(value) {
+		return true
+	}
+	if _, err := uuid.Parse(value); err == nil {
+		return true
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return true
+	}
+	if _, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return true
+	}
+	return false
+}
+
+func ReadFrames(in io.Reader) ([]Frame, error) {
+	var frames []Frame
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var frame Frame
+		if err := json.Unmarshal(scanner.Bytes(), &frame); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		frames = append(frames, frame)
+	}
+	return frames, scanner.Err()
+}
@@ -0,0 +1,116 @@
+package wprotocol
+
+import "strconv"
+
+const (
+	ProtocolVersion     = 2
+	MinProtocolVersion  = 1
+	unboundedMaxVersion = 0
+)
+
+type Direction uint8
+
+const (
+	ClientToServer Direction = 1 << iota
+	ServerToClient
+	Bidirectional = ClientToServer | ServerToClient
+)
+
+type OpInfo struct {
+	Name        string
+	Direction   Direction
+	MinVersion  int
+	MaxVersion  int
+	Deprecated  bool
+	Replacement OpCode
+	Tracking    bool
+}
+
+func (i OpInfo) SupportedBy(version int) bool {
+	if version < i.MinVersion {
+		return false
+	}
+	return i.MaxVersion == unboundedMaxVersion || version <= i.MaxVersion
+}
+
+var registry = map[OpCode]OpInfo{
+	OpMsgSend:               {Name: "msg.send", Direction: ClientToServer, MinVersion: 1},
+	OpMsgDeliver:            {Name: "msg.deliver", Direction: ServerToClient, MinVersion: 1},
+	OpMsgEdit:               {Name: "msg.edit", Direction: ClientToServer, MinVersion: 1},
+	OpMsgEdited:             {Name: "msg.edited", Direction: ServerToClient, MinVersion: 1},
+	OpMsgDelete:             {Name: "msg.delete", Direction: ClientToServer, MinVersion: 1},
+	OpMsgDeleted:            {Name: "msg.deleted", Direction: ServerToClient, MinVersion: 1},
+	OpMsgRead:               {Name: "msg.read", Direction: ClientToServer, MinVersion: 1},
+	OpMsgStatusUpdate:       {Name: "msg.status_update", Direction: ServerToClient, MinVersion: 1, Tracking: true},
+	OpPresenceTypingOn:      {Name: "presence.typing_on", Direction: Bidirectional, MinVersion: 1, Tracking: true},
+	OpPresenceTypingOff:     {Name: "presence.typing_off", Direction: Bidirectional, MinVersion: 1, Tracking: true},
+	OpPresenceUpdate:        {Name: "presence.update", Direction: ServerToClient, MinVersion: 1, Tracking: true},
+	OpNotifyRoomAdded:       {Name: "notify.room_added", Direction: ServerToClient, MinVersion: 1},
+	OpNotifyRoomRemoved:     {Name: "notify.room_removed", Direction: ServerToClient, MinVersion: 1},
+	OpFriendRequestReceived: {Name: "friend.request_received", Direction: ServerToClient, MinVersion: 1},
+	OpFriendRequestAccepted: {Name: "friend.request_accepted", Direction: ServerToClient, MinVersion: 1},
+	OpFriendRemoved:         {Name: "friend.removed", Direction: ServerToClient, MinVersion: 1},
+	OpWebRTCSignal:          {Name: "webrtc.signal", Direction: Bidirectional, MinVersion: 1},
+	OpSessionToken:          {Name: "session.token", Direction: ServerToClient, MinVersion: 1},
+	OpSessionResumed:        {Name: "session.resumed", Direction: ServerToClient, MinVersion: 1},
+	OpDeprecated:            {Name: "protocol.deprecated", Direction: ServerToClient, MinVersion: 1},
+	OpChunkStart:            {Name: "chunk.start", Direction: Bidirectional, MinVersion: 2},
+	OpChunkPart:             {Name: "chunk.part", Direction: Bidirectional, MinVersion: 2},
+	OpChunkEnd:              {Name: "chunk.end", Direction: Bidirectional, MinVersion: 2},
+	OpCallParticipantJoined: {Name: "call.participant_joined", Direction: ServerToClient, MinVersion: 1},
+	OpCallParticipantLeft:   {Name: "call.participant_left", Direction: ServerToClient, MinVersion: 1},
+	OpCallEnded:             {Name: "call.ended", Direction: ServerToClient, MinVersion: 1},
+	OpCallStateUpdate:       {Name: "call.state_update", Direction: ClientToServer, MinVersion: 1},
+	OpCallState:             {Name: "call.state", Direction: ServerToClient, MinVersion: 1},
+	OpCallStateSync:         {Name: "call.state_sync", Direction: ClientToServer, MinVersion: 1},
+	OpCallRecordingRequest:  {Name: "call.recording_request", Direction: ClientToServer, MinVersion: 1},
+	OpCallRecordingPrompt:   {Name: "call.recording_prompt", Direction: ServerToClient, MinVersion: 1},
+	OpCallRecordingConsent:  {Name: "call.recording_consent", Direction: ClientToServer, MinVersion: 1},
+	OpCallRecordingStarted:  {Name: "call.recording_started", Direction: ServerToClient, MinVersion: 1},
+	OpCallRecordingStop:     {Name: "call.recording_stop", Direction: ClientToServer, MinVersion: 1},
+	OpCallRecordingStopped:  {Name: "call.recording_stopped", Direction: ServerToClient, MinVersion: 1},
+	OpRoomFocus:             {Name: "room.focus", Direction: ClientToServer, MinVersion: 1},
+	OpRoomState:             {Name: "room.state", Direction: ServerToClient, MinVersion: 1},
+	OpMsgAction:             {Name: "msg.action", Direction: ClientToServer, MinVersion: 1},
+	OpRoomMembersAdded:      {Name: "room.members_added", Direction: ServerToClient, MinVersion: 1},
+	OpMsgTranslation:        {Name: "msg.translation", Direction: ServerToClient, MinVersion: 1},
+	OpMsgDeliverBatch:       {Name: "msg.deliver_batch", Direction: ServerToClient, MinVersion: 2},
+	OpRoomUpdated:           {Name: "room.updated", Direction: ServerToClient, MinVersion: 1},
+	OpSupportAssignment:     {Name: "support.assignment", Direction: ServerToClient, MinVersion: 1},
+	OpSupportNote:           {Name: "support.note", Direction: ServerToClient, MinVersion: 1},
+	OpSupportSLABreached:    {Name: "support.sla_breached", Direction: ServerToClient, MinVersion: 1},
+	OpRoomStateChanged:      {Name: "room.state_changed", Direction: ServerToClient, MinVersion: 1},
+	OpUserProfileUpdated:    {Name: "user.profile_updated", Direction: ServerToClient, MinVersion: 1},
+	OpStateVersion:          {Name: "state.version", Direction: ServerToClient, MinVersion: 1},
+	OpSuggestions:           {Name: "msg.suggestions", Direction: ServerToClient, MinVersion: 1},
+	OpSessionResync:         {Name: "session.resync", Direction: ServerToClient, MinVersion: 1},
+	OpMsgReaction:           {Name: "msg.reaction", Direction: ServerToClient, MinVersion: 1},
+	OpError:                 {Name: "error", Direction: ServerToClient, MinVersion: 1},
+}
+
+func Lookup(op OpCode) (OpInfo, bool) {
+	info, ok := registry[op]
+	return info, ok
+}
+
+func (op OpCode) String() string {
+	if info, ok := registry[op]; ok {
+		return info.Name
+	}
+	return "op(" + strconv.Itoa(int(op)) + ")"
+}
+
+func PeekOp(frame []byte) (OpCode, bool) {
+	end := 0
+	for end < len(frame) && frame[end] != UnitSeparator {
+		end++
+		if end > maxOpDigits {
+			return 0, false
+		}
+	}
+	op, err := strconv.ParseUint(string(frame[:end]), 10, 8)
+	if err != nil {
+		return 0, false
+	}
+	return OpCode(op), true
+}